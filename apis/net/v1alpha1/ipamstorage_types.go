@@ -53,6 +53,15 @@ type EndpointMapping struct {
 	ClusterMappings map[string]ClusterMapping `json:"clusterMappings"`
 }
 
+// OrganizationBlock describes a contiguous network reserved for the clusters of an organization,
+// from which the networks remapping their subnets are allocated.
+type OrganizationBlock struct {
+	// Network reserved for the organization.
+	Network string `json:"network"`
+	// Set of clusters belonging to the organization.
+	Clusters []string `json:"clusters,omitempty"`
+}
+
 // IpamSpec defines the desired state of Ipam.
 type IpamSpec struct {
 	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
@@ -80,6 +89,10 @@ type IpamSpec struct {
 	PodCIDR string `json:"podCIDR"`
 	// ServiceCIDR
 	ServiceCIDR string `json:"serviceCIDR"`
+	// Map used to keep track of the networks reserved for organizations. Key is the organization name, value is
+	// the reserved network, along with the clusters belonging to the organization.
+	// +kubebuilder:validation:Optional
+	OrganizationBlocks map[string]OrganizationBlock `json:"organizationBlocks,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -59,8 +59,36 @@ type NetworkConfigStatus struct {
 	// The new subnet used to NAT the externalCIDR of the remote cluster. The original ExternalCIDR may have been mapped
 	// to this network by the remote cluster.
 	ExternalCIDRNAT string `json:"externalCIDRNAT,omitempty"`
+	// The sub-ranges of its PodCIDR actually used by the remote cluster (e.g., those assigned to its nodes), if advertised.
+	// They allow to detect the overlaps with the local networks at a finer granularity than the whole PodCIDR.
+	UsedPodCIDRs []string `json:"usedPodCIDRs,omitempty"`
+	// Human-readable message explaining why this network config cannot be processed, if any (e.g., if no networks
+	// are available to remap the CIDRs of the remote cluster).
+	StatusMessage string `json:"statusMessage,omitempty"`
+	// Indicates whether the CIDRs of the remote cluster have been remapped ("true" or "false"), or "error" in case
+	// the NAT configuration could not be determined (e.g., if no networks are available to remap them).
+	NATEnabled NATEnabledStatus `json:"natEnabled,omitempty"`
+	// Conditions detailing the processing of this network config.
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
+// NATEnabledStatus indicates whether the CIDRs of the remote cluster have been remapped.
+// +kubebuilder:validation:Enum="true";"false";"error"
+type NATEnabledStatus string
+
+const (
+	// NATEnabledTrue indicates that at least one CIDR of the remote cluster has been remapped.
+	NATEnabledTrue NATEnabledStatus = "true"
+	// NATEnabledFalse indicates that no CIDRs of the remote cluster have been remapped.
+	NATEnabledFalse NATEnabledStatus = "false"
+	// NATEnabledError indicates that the NAT configuration could not be determined.
+	NATEnabledError NATEnabledStatus = "error"
+
+	// NetworkConfigPoolsExhaustedCondition is the condition type set when the CIDRs of the remote cluster
+	// cannot be remapped, as no networks are available in the network pools.
+	NetworkConfigPoolsExhaustedCondition = "PoolsExhausted"
+)
+
 // +kubebuilder:object:root=true
 // +kubebuilder:resource:categories=liqo
 // +kubebuilder:subresource:status
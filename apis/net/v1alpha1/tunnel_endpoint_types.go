@@ -74,6 +74,8 @@ type TunnelEndpointStatus struct {
 	VethIP           string     `json:"vethIP,omitempty"`
 	GatewayIP        string     `json:"gatewayIP,omitempty"`
 	Connection       Connection `json:"connection,omitempty"`
+	// MTU is the MTU in use towards the remote cluster, taking into account the possible per-cluster override.
+	MTU int `json:"mtu,omitempty"`
 }
 
 // ConnectionLatency represents the latency between two clusters.
@@ -40,6 +40,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 	"sigs.k8s.io/sig-storage-lib-external-provisioner/v7/controller"
 
@@ -56,6 +57,7 @@ import (
 	resourceRequestOperator "github.com/liqotech/liqo/pkg/liqo-controller-manager/resource-request-controller"
 	resourcemonitors "github.com/liqotech/liqo/pkg/liqo-controller-manager/resource-request-controller/resource-monitors"
 	resourceoffercontroller "github.com/liqotech/liqo/pkg/liqo-controller-manager/resourceoffer-controller"
+	"github.com/liqotech/liqo/pkg/liqo-controller-manager/servicereaper"
 	shadowpodctrl "github.com/liqotech/liqo/pkg/liqo-controller-manager/shadowpod-controller"
 	liqostorageprovisioner "github.com/liqotech/liqo/pkg/liqo-controller-manager/storageprovisioner"
 	virtualNodectrl "github.com/liqotech/liqo/pkg/liqo-controller-manager/virtualNode-controller"
@@ -63,11 +65,13 @@ import (
 	nsoffwh "github.com/liqotech/liqo/pkg/liqo-controller-manager/webhooks/namespaceoffloading"
 	podwh "github.com/liqotech/liqo/pkg/liqo-controller-manager/webhooks/pod"
 	shadowpodswh "github.com/liqotech/liqo/pkg/liqo-controller-manager/webhooks/shadowpod"
+	tepwh "github.com/liqotech/liqo/pkg/liqo-controller-manager/webhooks/tunnelendpoint"
 	peeringroles "github.com/liqotech/liqo/pkg/peering-roles"
 	tenantnamespace "github.com/liqotech/liqo/pkg/tenantNamespace"
 	argsutils "github.com/liqotech/liqo/pkg/utils/args"
 	"github.com/liqotech/liqo/pkg/utils/csr"
 	liqoerrors "github.com/liqotech/liqo/pkg/utils/errors"
+	foreigncluster "github.com/liqotech/liqo/pkg/utils/foreignCluster"
 	"github.com/liqotech/liqo/pkg/utils/mapper"
 	"github.com/liqotech/liqo/pkg/utils/restcfg"
 	"github.com/liqotech/liqo/pkg/vkMachinery/forge"
@@ -105,6 +109,10 @@ func main() {
 	refreshInterval := flag.Duration("resource-validator-refresh-interval",
 		5*time.Minute, "The interval at which the resource validator cache is refreshed")
 
+	// Reflected services reaper
+	reflectedServicesReapInterval := flag.Duration("reflected-services-reap-interval", 1*time.Minute,
+		"The interval at which the reflected services no longer refreshed within their TTL are removed (0 to disable)")
+
 	// Leader election
 	leaderElection := flag.Bool("enable-leader-election", false, "Enable leader election for controller manager")
 
@@ -210,6 +218,7 @@ func main() {
 	mgr.GetWebhookServer().Register("/validate/shadowpods", &webhook.Admission{Handler: spv})
 	mgr.GetWebhookServer().Register("/validate/namespace-offloading", nsoffwh.New())
 	mgr.GetWebhookServer().Register("/mutate/pod", podwh.New(mgr.GetClient()))
+	mgr.GetWebhookServer().Register("/validate/tunnel-endpoint", tepwh.NewValidator())
 
 	clientset := kubernetes.NewForConfigOrDie(config)
 
@@ -251,6 +260,12 @@ func main() {
 		klog.Fatal(err)
 	}
 
+	peeringTransitions := foreigncluster.NewPeeringTransitionWatcher()
+	if err = peeringTransitions.Watch(ctx, mgr.GetCache()); err != nil {
+		klog.Fatal(err)
+	}
+	metrics.Registry.MustRegister(peeringTransitions)
+
 	var resourceRequestReconciler *resourceRequestOperator.ResourceRequestReconciler
 	var monitor resourcemonitors.ResourceReader
 	if *resourcePluginAddress != "" {
@@ -355,6 +370,13 @@ func main() {
 		os.Exit(1)
 	}
 
+	if *reflectedServicesReapInterval > 0 {
+		if err := mgr.Add(manager.RunnableFunc(servicereaper.NewServiceReaper(clientset, *reflectedServicesReapInterval).Start)); err != nil {
+			klog.Errorf("Unable to set up the reflected services reaper: %v", err)
+			os.Exit(1)
+		}
+	}
+
 	if *enableStorage {
 		liqoProvisioner, err := liqostorageprovisioner.NewLiqoLocalStorageProvisioner(ctx, mgr.GetClient(),
 			*virtualStorageClassName, *storageNamespace, *realStorageClassName)
@@ -15,15 +15,25 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
+	"fmt"
+	"net/http"
 	"os"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/klog/v2"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
 
 	"github.com/liqotech/liqo/internal/liqonet/network-manager/netcfgcreator"
 	"github.com/liqotech/liqo/internal/liqonet/network-manager/tunnelendpointcreator"
@@ -39,17 +49,115 @@ type networkManagerFlags struct {
 	podCIDR     args.CIDR
 	serviceCIDR args.CIDR
 
+	pools           args.CIDRList
 	additionalPools args.CIDRList
 	reservedPools   args.CIDRList
+	poolsPriority   args.CIDRList
+	usedPodCIDRs    args.CIDRList
+
+	poolUtilizationThreshold args.Percentage
+	allocationPolicy         *args.StringEnum
+	remapHeadroom            uint
+	logAllocations           bool
+	staticIPOverrides        args.StringMap
+	overlapGranularity       *args.StringEnum
+
+	clusterID               string
+	tunnelEndpointNamespace string
+
+	replicationRequestedLabel   string
+	replicationDestinationLabel string
+
+	unprocessedRequeueInterval time.Duration
+	queueDepthThreshold        uint
+	maxConcurrentReconciles    int
+	persistConfig              bool
+	staleTunnelThreshold       time.Duration
+	natDecisionEvents          bool
+	releaseGracePeriod         time.Duration
+	finalizerConflictRequeue   time.Duration
+	adminAddress               string
+	sweepOrphanTunnelEndpoints bool
 }
 
+const (
+	// poolUtilizationCheckInterval is the interval between two consecutive checks of the network pools utilization.
+	poolUtilizationCheckInterval = 1 * time.Minute
+	// orphanSweepInterval is the interval between two consecutive sweeps of the orphaned TunnelEndpoints.
+	orphanSweepInterval = 10 * time.Minute
+	// tunnelEndpointsVerificationInterval is the interval between two consecutive verifications of the TunnelEndpoints.
+	tunnelEndpointsVerificationInterval = 10 * time.Minute
+	// ipamConsistencyCheckInterval is the interval between two consecutive consistency checks of the IPAM state.
+	ipamConsistencyCheckInterval = 10 * time.Minute
+	// queueDepthCheckInterval is the interval between two consecutive checks of the NetworkConfigs reconcile backlog.
+	queueDepthCheckInterval = 30 * time.Second
+	// missingNetworkConfigsCheckInterval is the interval between two consecutive checks for missing local NetworkConfigs.
+	missingNetworkConfigsCheckInterval = 5 * time.Minute
+	// staleTunnelsCheckInterval is the interval between two consecutive checks for TunnelEndpoints stuck in a pending phase.
+	staleTunnelsCheckInterval = 1 * time.Minute
+)
+
 func addNetworkManagerFlags(managerFlags *networkManagerFlags) {
 	flag.Var(&managerFlags.podCIDR, "manager.pod-cidr", "The subnet used by the cluster for the pods, in CIDR notation")
-	flag.Var(&managerFlags.serviceCIDR, "manager.service-cidr", "The subnet used by the cluster for the pods, in services notation")
+	flag.Var(&managerFlags.serviceCIDR, "manager.service-cidr", "The subnet used by the cluster for the services, in CIDR notation (required)")
 	flag.Var(&managerFlags.reservedPools, "manager.reserved-pools",
 		"Private CIDRs slices used by the Kubernetes infrastructure, in addition to the pod and service CIDR (e.g., the node subnet).")
+	flag.Var(&managerFlags.pools, "manager.pools",
+		"Network pools used to map a cluster network into another one in order to prevent conflicts, replacing the standard private CIDRs "+
+			"(e.g., as colliding with existing networks). Pools can be added upon restarts, while removing the existing ones is not supported.")
 	flag.Var(&managerFlags.additionalPools, "manager.additional-pools",
 		"Network pools used to map a cluster network into another one in order to prevent conflicts, in addition to standard private CIDRs.")
+	flag.Var(&managerFlags.poolsPriority, "manager.pools-priority",
+		"Network pools to be used first when remapping a network, in priority order. The other pools are used only once these are exhausted")
+	managerFlags.poolUtilizationThreshold.Val = 80
+	flag.Var(&managerFlags.poolUtilizationThreshold, "manager.pool-utilization-threshold",
+		"The utilization percentage of a network pool above which a warning is raised, to give lead time before its exhaustion")
+	managerFlags.allocationPolicy = args.NewEnum([]string{string(liqonetIpam.FirstFitAllocationPolicy),
+		string(liqonetIpam.BestFitAllocationPolicy)}, string(liqonetIpam.FirstFitAllocationPolicy))
+	flag.Var(managerFlags.allocationPolicy, "manager.allocation-policy",
+		"The policy used to select the networks allocated from the pools in case of remapping, among FirstFit and BestFit")
+	managerFlags.overlapGranularity = args.NewEnum([]string{string(tunnelendpointcreator.NetworkOverlapGranularity),
+		string(tunnelendpointcreator.HostOverlapGranularity)}, string(tunnelendpointcreator.NetworkOverlapGranularity))
+	flag.Var(managerFlags.overlapGranularity, "manager.overlap-granularity",
+		"The granularity at which the overlaps of the remote pod CIDRs are detected, among Network and Host (i.e., only the used sub-ranges)")
+	flag.Var(&managerFlags.usedPodCIDRs, "manager.used-pod-cidrs",
+		"The sub-ranges of the pod CIDR actually used (e.g., those assigned to the nodes), advertised to the peers for the Host overlap granularity")
+	flag.StringVar(&managerFlags.clusterID, "manager.cluster-id", "",
+		"The cluster ID of the local cluster, used to ignore the NetworkConfigs referring to the cluster itself")
+	flag.StringVar(&managerFlags.tunnelEndpointNamespace, "manager.tunnelendpoint-namespace", "",
+		"The namespace hosting the TunnelEndpoints (default: the tenant namespace of the corresponding NetworkConfigs)")
+	flag.UintVar(&managerFlags.remapHeadroom, "manager.remap-headroom", 0,
+		"The number of additional bits reserved when remapping a network, to allocate larger networks leaving room for future growth")
+	flag.Var(&managerFlags.staticIPOverrides, "manager.static-ip-overrides",
+		"The remote IPs (e.g., DNS servers and gateways) mapped to fixed home addresses rather than remapped (e.g., 10.96.0.10=10.200.0.10)")
+	flag.BoolVar(&managerFlags.logAllocations, "manager.log-allocations", false,
+		"Emit a structured log entry for each network allocation decision (i.e., whether a network has been remapped), for audit purposes")
+	flag.StringVar(&managerFlags.replicationRequestedLabel, "manager.replication-requested-label", liqoconst.ReplicationRequestedLabel,
+		"The key of the label marking the NetworkConfigs to be replicated by the CRD replicator")
+	flag.StringVar(&managerFlags.replicationDestinationLabel, "manager.replication-destination-label", liqoconst.ReplicationDestinationLabel,
+		"The key of the label identifying the cluster the NetworkConfigs are replicated to by the CRD replicator")
+	flag.DurationVar(&managerFlags.unprocessedRequeueInterval, "manager.unprocessed-requeue-interval",
+		tunnelendpointcreator.DefaultUnprocessedRequeueInterval,
+		"The interval after which a local NetworkConfig not yet processed by the remote cluster is checked again")
+	flag.UintVar(&managerFlags.queueDepthThreshold, "manager.queue-depth-threshold", 0,
+		"The number of pending NetworkConfig reconciliations above which a warning is raised, to tune the concurrency (0 to disable)")
+	flag.IntVar(&managerFlags.maxConcurrentReconciles, "manager.max-concurrent-reconciles", tunnelendpointcreator.DefaultMaxConcurrentReconciles,
+		"The maximum number of NetworkConfigs reconciled concurrently, those referring to the same cluster being anyway processed sequentially")
+	flag.BoolVar(&managerFlags.persistConfig, "manager.persist-config", false,
+		"Persist the last-known-good configuration to a ConfigMap, to bootstrap upon restart without waiting for the gateway to be configured again")
+	flag.DurationVar(&managerFlags.staleTunnelThreshold, "manager.stale-tunnel-threshold", tunnelendpointcreator.DefaultStaleTunnelThreshold,
+		"The time after which a TunnelEndpoint stuck in a pending phase causes the corresponding NetworkConfig to be reconciled again (0 to disable)")
+	flag.BoolVar(&managerFlags.natDecisionEvents, "manager.nat-decision-events", false,
+		"Record an event on the remote NetworkConfigs whenever their CIDRs are found to overlap (or not) with the reserved networks")
+	flag.DurationVar(&managerFlags.releaseGracePeriod, "manager.release-grace-period", 0,
+		"The time the subnets reserved for a remote cluster are retained after the deletion of its NetworkConfig, to be reused if quickly recreated")
+	flag.DurationVar(&managerFlags.finalizerConflictRequeue, "manager.finalizer-conflict-requeue-interval",
+		tunnelendpointcreator.DefaultFinalizerConflictRequeueInterval,
+		"The interval after which a NetworkConfig is reconciled again if adding the finalizer conflicted (0 to wait for the next event)")
+	flag.StringVar(&managerFlags.adminAddress, "manager.admin-address", "",
+		"The address the read-only admin endpoints dumping the allocation decisions for debugging purposes bind to (empty to disable)")
+	flag.BoolVar(&managerFlags.sweepOrphanTunnelEndpoints, "manager.sweep-orphan-tunnelendpoints", false,
+		"Periodically delete the TunnelEndpoints whose NetworkConfigs no longer exist, reclaiming the subnets reserved for the corresponding clusters")
 }
 
 func runNetworkManager(commonFlags *liqonetCommonFlags, managerFlags *networkManagerFlags) {
@@ -88,10 +196,100 @@ func runNetworkManager(commonFlags *liqonetCommonFlags, managerFlags *networkMan
 		os.Exit(1)
 	}
 
+	replicationLabels := netcfgcreator.ReplicationLabels{
+		Requested:   managerFlags.replicationRequestedLabel,
+		Destination: managerFlags.replicationDestinationLabel,
+	}
+
 	tec := &tunnelendpointcreator.TunnelEndpointCreator{
 		Client:    mgr.GetClient(),
 		Scheme:    mgr.GetScheme(),
 		IPManager: ipam,
+
+		LocalClusterID:          managerFlags.clusterID,
+		TunnelEndpointNamespace: managerFlags.tunnelEndpointNamespace,
+		ReplicationLabels:       replicationLabels,
+
+		UnprocessedRequeueInterval: managerFlags.unprocessedRequeueInterval,
+		EventRecorder:              mgr.GetEventRecorderFor(liqoconst.LiqoNetworkManagerName),
+		MaxConcurrentReconciles:    managerFlags.maxConcurrentReconciles,
+		OverlapGranularity:         tunnelendpointcreator.OverlapGranularity(managerFlags.overlapGranularity.Value),
+		UsedPodCIDRs:               managerFlags.usedPodCIDRs.StringList.StringList,
+		NATDecisionEvents:          managerFlags.natDecisionEvents,
+		ReleaseGracePeriod:         managerFlags.releaseGracePeriod,
+
+		FinalizerConflictRequeueInterval: managerFlags.finalizerConflictRequeue,
+	}
+
+	// Monitor the utilization of the network pools, exposing the corresponding metrics.
+	poolUtilization := liqonetIpam.NewPoolUtilizationCollector(ipam,
+		float64(managerFlags.poolUtilizationThreshold.Val)/100, tec.IPAMLocker())
+	metrics.Registry.MustRegister(poolUtilization)
+	utilruntime.Must(mgr.Add(manager.RunnableFunc(func(ctx context.Context) error {
+		wait.UntilWithContext(ctx, func(ctx context.Context) {
+			if _, err := poolUtilization.Check(); err != nil {
+				klog.Error(err)
+			}
+		}, poolUtilizationCheckInterval)
+		return nil
+	})))
+
+	// Periodically sweep the TunnelEndpoints left behind by vanished NetworkConfigs, reclaiming the corresponding subnets.
+	if managerFlags.sweepOrphanTunnelEndpoints {
+		utilruntime.Must(mgr.Add(manager.RunnableFunc(func(ctx context.Context) error {
+			wait.UntilWithContext(ctx, func(ctx context.Context) {
+				if _, err := tec.SweepOrphanTunnelEndpoints(ctx); err != nil {
+					klog.Errorf("Failed to sweep the orphaned TunnelEndpoints: %v", err)
+				}
+			}, orphanSweepInterval)
+			return nil
+		})))
+	}
+
+	// Periodically verify the TunnelEndpoints against the NetworkConfigs, correcting the drifts which would otherwise go unnoticed.
+	utilruntime.Must(mgr.Add(manager.RunnableFunc(func(ctx context.Context) error {
+		wait.UntilWithContext(ctx, func(ctx context.Context) {
+			if _, err := tec.VerifyTunnelEndpoints(ctx); err != nil {
+				klog.Errorf("Failed to verify the TunnelEndpoints: %v", err)
+			}
+		}, tunnelEndpointsVerificationInterval)
+		return nil
+	})))
+
+	// Periodically check the IPAM state for internal consistency, reporting the detected problems.
+	utilruntime.Must(mgr.Add(manager.RunnableFunc(func(ctx context.Context) error {
+		wait.UntilWithContext(ctx, func(ctx context.Context) {
+			if _, err := tec.CheckIPAMConsistency(ctx); err != nil {
+				klog.Errorf("Failed to check the consistency of the IPAM state: %v", err)
+			}
+		}, ipamConsistencyCheckInterval)
+		return nil
+	})))
+
+	// Monitor the NetworkConfigs reconcile backlog, whose depth is exposed by controller-runtime through the workqueue metrics.
+	if managerFlags.queueDepthThreshold > 0 {
+		queueDepth := tunnelendpointcreator.NewQueueDepthMonitor(metrics.Registry, tunnelendpointcreator.ControllerName,
+			managerFlags.queueDepthThreshold)
+		utilruntime.Must(mgr.Add(manager.RunnableFunc(func(ctx context.Context) error {
+			wait.UntilWithContext(ctx, func(ctx context.Context) {
+				if _, err := queueDepth.Check(); err != nil {
+					klog.V(4).Infof("Failed to check the NetworkConfigs reconcile backlog: %v", err)
+				}
+			}, queueDepthCheckInterval)
+			return nil
+		})))
+	}
+
+	// Periodically enqueue the NetworkConfigs whose TunnelEndpoints are stuck in a pending phase, e.g., due to a missed status update.
+	if managerFlags.staleTunnelThreshold > 0 {
+		utilruntime.Must(mgr.Add(manager.RunnableFunc(func(ctx context.Context) error {
+			wait.UntilWithContext(ctx, func(ctx context.Context) {
+				if _, err := tec.RequeueStaleTunnelEndpoints(ctx, managerFlags.staleTunnelThreshold); err != nil {
+					klog.Errorf("Failed to check for stale TunnelEndpoints: %v", err)
+				}
+			}, staleTunnelsCheckInterval)
+			return nil
+		})))
 	}
 
 	ncc := &netcfgcreator.NetworkConfigCreator{
@@ -100,6 +298,15 @@ func runNetworkManager(commonFlags *liqonetCommonFlags, managerFlags *networkMan
 
 		PodCIDR:      managerFlags.podCIDR.String(),
 		ExternalCIDR: externalCIDR,
+
+		ReplicationLabels: replicationLabels,
+	}
+	if managerFlags.persistConfig {
+		ncc.ConfigStore = &netcfgcreator.ConfigStore{
+			Reader:    mgr.GetAPIReader(),
+			Writer:    mgr.GetClient(),
+			ConfigMap: types.NamespacedName{Namespace: podNamespace, Name: netcfgcreator.ConfigStoreName},
+		}
 	}
 
 	if err = tec.SetupWithManager(mgr); err != nil {
@@ -112,6 +319,23 @@ func runNetworkManager(commonFlags *liqonetCommonFlags, managerFlags *networkMan
 		os.Exit(1)
 	}
 
+	// Periodically recreate the local NetworkConfigs accidentally deleted while the corresponding peering is active.
+	utilruntime.Must(mgr.Add(manager.RunnableFunc(func(ctx context.Context) error {
+		wait.UntilWithContext(ctx, func(ctx context.Context) {
+			if _, err := ncc.RecreateMissingNetworkConfigs(ctx); err != nil {
+				klog.Errorf("Failed to recreate the missing NetworkConfigs: %v", err)
+			}
+		}, missingNetworkConfigsCheckInterval)
+		return nil
+	})))
+
+	// Optionally expose the read-only admin endpoints, dumping the current allocation decisions for debugging purposes.
+	if managerFlags.adminAddress != "" {
+		utilruntime.Must(mgr.Add(manager.RunnableFunc(func(ctx context.Context) error {
+			return runAdminServer(ctx, managerFlags.adminAddress, tec.AdminHandler())
+		})))
+	}
+
 	klog.Info("starting manager as liqo-network-manager")
 	if err := mgr.Start(tec.SetupSignalHandlerForTunEndCreator()); err != nil {
 		klog.Errorf("an error occurred while starting manager: %s", err)
@@ -119,24 +343,75 @@ func runNetworkManager(commonFlags *liqonetCommonFlags, managerFlags *networkMan
 	}
 }
 
+// runAdminServer serves the given admin handler on the given address, until the context is canceled.
+func runAdminServer(ctx context.Context, address string, handler http.Handler) error {
+	server := &http.Server{
+		Addr:              address,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second, // Required to limit the effects of the Slowloris attack.
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			klog.Errorf("Failed to shutdown the admin server: %v", err)
+		}
+	}()
+
+	klog.Infof("Starting the admin server listening on %q", address)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("failed to start the admin server: %w", err)
+	}
+	return nil
+}
+
 func initializeIPAM(client dynamic.Interface, managerFlags *networkManagerFlags) (*liqonetIpam.IPAM, error) {
-	ipam := liqonetIpam.NewIPAM()
+	// Fail early in case the local networks are not configured, or overlap with the reserved ones.
+	if err := liqonetIpam.CheckReservedSubnets(managerFlags.podCIDR.String(), managerFlags.serviceCIDR.String(),
+		managerFlags.reservedPools.StringList.StringList); err != nil {
+		return nil, err
+	}
 
-	if err := ipam.Init(liqonetIpam.Pools, client, liqoconst.NetworkManagerIpamPort); err != nil {
+	ipam := liqonetIpam.NewIPAM()
+	if err := ipam.SetAllocationPolicy(liqonetIpam.AllocationPolicy(managerFlags.allocationPolicy.Value)); err != nil {
 		return nil, err
 	}
+	if managerFlags.remapHeadroom > 32 {
+		return nil, fmt.Errorf("invalid remap headroom of %d bits", managerFlags.remapHeadroom)
+	}
+	if err := ipam.SetRemapHeadroom(uint8(managerFlags.remapHeadroom)); err != nil {
+		return nil, err
+	}
+	if err := ipam.SetStaticIPOverrides(managerFlags.staticIPOverrides.StringMap); err != nil {
+		return nil, err
+	}
+	if managerFlags.logAllocations {
+		ipam.SetAllocationRecorder(liqonetIpam.LoggingAllocationRecorder{})
+	}
 
-	if err := ipam.SetPodCIDR(managerFlags.podCIDR.String()); err != nil {
+	pools := liqonetIpam.Pools
+	if len(managerFlags.pools.StringList.StringList) > 0 {
+		pools = managerFlags.pools.StringList.StringList
+	}
+	// The additional pools are configured together with the base ones, to be reconciled with the stored ones upon restarts.
+	pools = append(append([]string{}, pools...), managerFlags.additionalPools.StringList.StringList...)
+	if err := ipam.Init(pools, client, liqoconst.NetworkManagerIpamPort); err != nil {
 		return nil, err
 	}
-	if err := ipam.SetServiceCIDR(managerFlags.serviceCIDR.String()); err != nil {
+
+	// Acquire the local networks, re-allocating the remote ones conflicting with them in case the configuration changed.
+	reallocated, err := ipam.ReconcileLocalNetworks(managerFlags.podCIDR.String(), managerFlags.serviceCIDR.String())
+	if err != nil {
 		return nil, err
 	}
+	if len(reallocated) > 0 {
+		klog.Warningf("The networks of clusters %v conflicted with the local ones, and have been re-allocated", reallocated)
+	}
 
-	for _, pool := range managerFlags.additionalPools.StringList.StringList {
-		if err := ipam.AddNetworkPool(pool); err != nil {
-			return nil, err
-		}
+	if err := ipam.SetPoolsPriority(managerFlags.poolsPriority.StringList.StringList); err != nil {
+		return nil, err
 	}
 
 	if err := ipam.SetReservedSubnets(managerFlags.reservedPools.StringList.StringList); err != nil {
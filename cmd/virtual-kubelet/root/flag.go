@@ -52,6 +52,53 @@ func InstallFlags(flags *pflag.FlagSet, o *Opts) {
 		"The number of service account reflection workers (applies only if API server support is enabled in token API mode)")
 	flags.UintVar(&o.PersistentVolumeClaimWorkers, "persistentvolumeclaim-reflection-workers", o.PersistentVolumeClaimWorkers,
 		"The number of persistentvolumeclaim reflection workers")
+	flags.Var(&o.ServiceReflectionAllowedNamespaces, "service-reflection-allowed-namespaces",
+		"The local namespaces whose services are allowed to be reflected towards the remote cluster (default: all)")
+	flags.Var(&o.ServiceReflectionExcludedNamespaces, "service-reflection-excluded-namespaces",
+		"The local namespaces whose services are never reflected towards the remote cluster, set to empty to disable the exclusion "+
+			"(default: kube-system,kube-public,kube-node-lease,liqo)")
+	flags.Var(&o.ServiceReflectionAllowedTypes, "service-reflection-allowed-types",
+		"The types of the services allowed to be reflected towards the remote cluster, among ClusterIP, NodePort, LoadBalancer, ExternalName (default: all)")
+	flags.BoolVar(&o.ServiceReflectionCreateRemoteNamespace, "service-reflection-create-remote-namespace", false,
+		"Create the remote namespace when reflecting services, if it does not already exist (disable if namespaces are managed externally)")
+	flags.Var(&o.ServiceReflectionRemoteNamespaceLabels, "service-reflection-remote-namespace-labels",
+		"The additional labels (e.g., pod-security.kubernetes.io/enforce=baseline) set on the remote namespaces created when reflecting services")
+	flags.Var(&o.ServiceReflectionRemoteIPFamilies, "service-reflection-remote-ip-families",
+		"The IP families supported by the remote cluster, among IPv4 and IPv6, used to normalize those of the reflected services (default: unchanged)")
+	flags.Var(o.ServiceReflectionExternalIPsPolicy, "service-reflection-external-ips-policy",
+		"The policy used to handle the external IPs of the reflected services, among Strip, Retain and Remap")
+	flags.Var(&o.ServiceReflectionExternalIPsMapping, "service-reflection-external-ips-mapping",
+		"The mapping between local and remote external IPs (e.g., 10.0.0.1=10.1.0.1), in case of the Remap policy")
+	flags.DurationVar(&o.ServiceReflectionTTL, "service-reflection-ttl", 0,
+		"The duration after which the reflected services no longer refreshed (e.g., if the local cluster goes offline) are removed "+
+			"by the remote cluster (default: disabled)")
+	flags.StringVar(&o.ServiceReflectionHubKubeconfig, "service-reflection-hub-kubeconfig", "",
+		"The kubeconfig of a hub cluster the services and their endpointslices are reflected to, in place of the remote one (default: disabled)")
+	flags.StringVar(&o.ServiceReflectionNamePrefix, "service-reflection-name-prefix", "",
+		"The prefix prepended to the name of the reflected services and endpointslices (default: names preserved)")
+	flags.DurationVar(&o.ServiceReflectionResyncInterval, "service-reflection-resync-interval", 0,
+		"The interval all services are periodically reconciled with, to recover from missed events (default: disabled)")
+	flags.UintVar(&o.ServiceReflectionMaxAnnotationSize, "service-reflection-max-annotation-size", 0,
+		"The maximum size in bytes (key plus value) of the annotations of the reflected services, while the larger ones are pruned (default: disabled)")
+	flags.Var(&o.ServiceReflectionDeniedPorts, "service-reflection-denied-ports",
+		"The ports (e.g., 22 or 161/UDP) never exposed towards the remote cluster, dropped from the reflected services and endpointslices")
+	flags.StringVar(&o.ServiceReflectionClusterDomain, "service-reflection-cluster-domain", o.ServiceReflectionClusterDomain,
+		"The domain of the local cluster, used to annotate the reflected services with the FQDN of the local ones")
+	flags.UintVar(&o.ServiceReflectionMutationRetries, "service-reflection-mutation-retries", o.ServiceReflectionMutationRetries,
+		"The number of times the remote mutations failed due to transient errors (e.g., timeouts and conflicts) are retried (0 to disable)")
+	flags.StringVar(&o.ServiceReflectionTeardownFinalizer, "service-reflection-teardown-finalizer", "",
+		"The finalizer (e.g., liqo.io/service-teardown) added to the reflected services, suffixed with the remote cluster ID, "+
+			"to delay their deletion until the remote ones are removed (default: disabled)")
+	flags.UintVar(&o.EndpointSliceReflectionWeight, "endpointslice-reflection-weight", o.EndpointSliceReflectionWeight,
+		"The percentage (0-100) of local endpoints reflected towards the remote cluster, to gradually shift the traffic across clusters")
+	flags.BoolVar(&o.EndpointSliceReflectionReadyOnly, "endpointslice-reflection-ready-only", false,
+		"Reflect only the ready endpoints towards the remote cluster")
+	flags.DurationVar(&o.EndpointSliceReflectionNotReadyGracePeriod, "endpointslice-reflection-not-ready-grace-period", 0,
+		"The time an endpoint is still reflected after becoming not ready, in case of ready-only reflection, to prevent flapping")
+	flags.BoolVar(&o.EndpointSliceReflectionAggregate, "endpointslice-reflection-aggregate", false,
+		"Merge the endpoints of each service into a single remote endpointslice, shared with the other clusters reflecting the same service")
+	flags.BoolVar(&o.EndpointSliceReflectionWaitTunnelReady, "endpointslice-reflection-wait-tunnel-ready", false,
+		"Reflect the endpoints only while the tunnel towards the remote cluster is ready, as otherwise unreachable")
 
 	flags.DurationVar(&o.NodeLeaseDuration, "node-lease-duration", o.NodeLeaseDuration, "The duration of the node leases")
 	flags.DurationVar(&o.NodePingInterval, "node-ping-interval", o.NodePingInterval,
@@ -25,6 +25,8 @@ import (
 	discoveryv1alpha1 "github.com/liqotech/liqo/apis/discovery/v1alpha1"
 	"github.com/liqotech/liqo/pkg/consts"
 	argsutils "github.com/liqotech/liqo/pkg/utils/args"
+	"github.com/liqotech/liqo/pkg/virtualKubelet/forge"
+	"github.com/liqotech/liqo/pkg/virtualKubelet/reflection/exposition"
 )
 
 const (
@@ -84,6 +86,50 @@ type Opts struct {
 	ServiceAccountWorkers        uint
 	PersistentVolumeClaimWorkers uint
 
+	// The namespaces whose Services are allowed to be reflected towards the remote cluster (all if empty)
+	ServiceReflectionAllowedNamespaces argsutils.StringList
+	// The namespaces whose Services are never reflected towards the remote cluster (defaults if unset)
+	ServiceReflectionExcludedNamespaces argsutils.StringList
+	// The types of the Services allowed to be reflected towards the remote cluster (all if empty)
+	ServiceReflectionAllowedTypes argsutils.StringList
+	// Whether to create the remote namespace when reflecting Services, in case it does not already exist
+	ServiceReflectionCreateRemoteNamespace bool
+	// The additional labels set on the remote namespaces created when reflecting Services
+	ServiceReflectionRemoteNamespaceLabels argsutils.StringMap
+	// The IP families supported by the remote cluster, to normalize those of the reflected Services (verbatim if empty)
+	ServiceReflectionRemoteIPFamilies argsutils.StringList
+	// The policy used to handle the external IPs of the reflected Services
+	ServiceReflectionExternalIPsPolicy *argsutils.StringEnum
+	// The mapping between local and remote external IPs, in case of the Remap policy
+	ServiceReflectionExternalIPsMapping argsutils.StringMap
+	// The duration after which the reflected Services not refreshed are removed by the remote cluster (disabled if zero)
+	ServiceReflectionTTL time.Duration
+	// The kubeconfig of the hub cluster the Services and EndpointSlices are reflected to, in place of the remote one (hub-and-spoke mode)
+	ServiceReflectionHubKubeconfig string
+	// The prefix prepended to the name of the reflected Services and EndpointSlices (names preserved if empty)
+	ServiceReflectionNamePrefix string
+	// The interval the Services are periodically reconciled with, regardless of the received events (disabled if zero)
+	ServiceReflectionResyncInterval time.Duration
+	// The maximum size of the annotations of the reflected Services, while the larger ones are pruned (disabled if zero)
+	ServiceReflectionMaxAnnotationSize uint
+	// The ports never exposed towards the remote cluster, dropped from the reflected Services and EndpointSlices
+	ServiceReflectionDeniedPorts argsutils.StringList
+	// The domain of the local cluster, used to annotate the reflected Services with the FQDN of the local ones
+	ServiceReflectionClusterDomain string
+	// The number of times the remote mutations of the Service reflection failed due to transient errors are retried
+	ServiceReflectionMutationRetries uint
+	// The finalizer added to the reflected Services, to delay their deletion until the remote ones are removed (disabled if empty)
+	ServiceReflectionTeardownFinalizer string
+	// The percentage of local endpoints reflected towards the remote cluster
+	EndpointSliceReflectionWeight uint
+	// Whether to reflect the ready endpoints only, and the grace period before removing those no longer ready
+	EndpointSliceReflectionReadyOnly           bool
+	EndpointSliceReflectionNotReadyGracePeriod time.Duration
+	// Whether to merge the endpoints of each Service into a single remote EndpointSlice (aggregation mode)
+	EndpointSliceReflectionAggregate bool
+	// Whether to pause the reflection of the endpoints while the tunnel towards the remote cluster is not ready
+	EndpointSliceReflectionWaitTunnelReady bool
+
 	NodeLeaseDuration time.Duration
 	NodePingInterval  time.Duration
 	NodePingTimeout   time.Duration
@@ -120,6 +166,12 @@ func NewOpts() *Opts {
 		ServiceAccountWorkers:        DefaultServiceAccountWorkers,
 		PersistentVolumeClaimWorkers: DefaultPersistenVolumeClaimWorkers,
 
+		ServiceReflectionExternalIPsPolicy: argsutils.NewEnum([]string{string(forge.ExternalIPsPolicyStrip),
+			string(forge.ExternalIPsPolicyRetain), string(forge.ExternalIPsPolicyRemap)}, string(forge.ExternalIPsPolicyStrip)),
+		ServiceReflectionClusterDomain:   forge.DefaultClusterDomain,
+		ServiceReflectionMutationRetries: exposition.DefaultMutationRetries,
+		EndpointSliceReflectionWeight:    forge.EndpointWeightMax,
+
 		NodeLeaseDuration: node.DefaultLeaseDuration * time.Second,
 		NodePingInterval:  node.DefaultPingInterval,
 		NodePingTimeout:   DefaultNodePingTimeout,
@@ -17,6 +17,8 @@ package root
 
 import (
 	"context"
+	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
@@ -24,10 +26,13 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	corev1clients "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/klog/v2"
 
@@ -35,12 +40,18 @@ import (
 	tenantnamespace "github.com/liqotech/liqo/pkg/tenantNamespace"
 	"github.com/liqotech/liqo/pkg/utils"
 	"github.com/liqotech/liqo/pkg/utils/restcfg"
+	"github.com/liqotech/liqo/pkg/virtualKubelet/forge"
 	nodeprovider "github.com/liqotech/liqo/pkg/virtualKubelet/liqoNodeProvider"
 	podprovider "github.com/liqotech/liqo/pkg/virtualKubelet/provider"
+	"github.com/liqotech/liqo/pkg/virtualKubelet/reflection/exposition"
+	"github.com/liqotech/liqo/pkg/virtualKubelet/reflection/generic"
 )
 
 const defaultVersion = "v1.25.0" // This should follow the version of k8s.io/kubernetes we are importing
 
+// teardownTimeout is the maximum time granted to release the resources associated with the local objects upon termination.
+const teardownTimeout = 30 * time.Second
+
 // NewCommand creates a new top-level command.
 // This command is used to start the virtual-kubelet daemon.
 func NewCommand(ctx context.Context, name string, c *Opts) *cobra.Command {
@@ -82,10 +93,42 @@ func runRootCommand(ctx context.Context, c *Opts) error {
 
 	restcfg.SetRateLimiter(remoteConfig)
 
+	allowedServiceTypes, err := parseServiceTypes(c.ServiceReflectionAllowedTypes.StringList)
+	if err != nil {
+		return err
+	}
+	remoteIPFamilies, err := parseIPFamilies(c.ServiceReflectionRemoteIPFamilies.StringList)
+	if err != nil {
+		return err
+	}
+	var hubConfig *rest.Config
+	if c.ServiceReflectionHubKubeconfig != "" {
+		if hubConfig, err = clientcmd.BuildConfigFromFlags("", c.ServiceReflectionHubKubeconfig); err != nil {
+			return errors.Wrap(err, "failed to load the hub cluster kubeconfig")
+		}
+		restcfg.SetRateLimiter(hubConfig)
+	}
+	if c.EndpointSliceReflectionWeight > forge.EndpointWeightMax {
+		return errors.Errorf("invalid endpointslice reflection weight %d, expected in the range [0, %d]",
+			c.EndpointSliceReflectionWeight, forge.EndpointWeightMax)
+	}
+	if err := validateLabels(c.ServiceReflectionRemoteNamespaceLabels.StringMap); err != nil {
+		return errors.Wrap(err, "invalid remote namespace labels")
+	}
+	deniedPorts, err := forge.ParseDeniedPorts(c.ServiceReflectionDeniedPorts.StringList)
+	if err != nil {
+		return err
+	}
+	var nameMapper forge.NameMapper
+	if c.ServiceReflectionNamePrefix != "" {
+		nameMapper = forge.PrefixNameMapper(c.ServiceReflectionNamePrefix)
+	}
+
 	// Initialize the pod provider
 	podcfg := podprovider.InitConfig{
 		LocalConfig:   localConfig,
 		RemoteConfig:  remoteConfig,
+		HubConfig:     hubConfig,
 		LocalCluster:  c.HomeCluster,
 		RemoteCluster: c.ForeignCluster,
 
@@ -105,12 +148,51 @@ func runRootCommand(ctx context.Context, c *Opts) error {
 		ServiceAccountWorkers:       c.ServiceAccountWorkers,
 		PersistenVolumeClaimWorkers: c.PersistentVolumeClaimWorkers,
 
+		ServiceReflection: exposition.ServiceReflectorConfig{
+			AllowedNamespaces:     c.ServiceReflectionAllowedNamespaces.StringList,
+			ExcludedNamespaces:    c.ServiceReflectionExcludedNamespaces.StringList,
+			AllowedTypes:          allowedServiceTypes,
+			CreateRemoteNamespace: c.ServiceReflectionCreateRemoteNamespace,
+			RemoteNamespaceLabels: c.ServiceReflectionRemoteNamespaceLabels.StringMap,
+			RemoteIPFamilies:      remoteIPFamilies,
+			ExternalIPsPolicy:     forge.ExternalIPsPolicy(c.ServiceReflectionExternalIPsPolicy.Value),
+			ExternalIPsMapping:    c.ServiceReflectionExternalIPsMapping.StringMap,
+			TTL:                   c.ServiceReflectionTTL,
+			NameMapper:            nameMapper,
+			ResyncInterval:        c.ServiceReflectionResyncInterval,
+			MaxAnnotationSize:     int(c.ServiceReflectionMaxAnnotationSize),
+			DeniedPorts:           deniedPorts,
+			ClusterDomain:         c.ServiceReflectionClusterDomain,
+			MutationRetries:       c.ServiceReflectionMutationRetries,
+			TeardownFinalizer:     c.ServiceReflectionTeardownFinalizer,
+		},
+		EndpointSliceReflection: exposition.EndpointSliceReflectorConfig{
+			Weight:              c.EndpointSliceReflectionWeight,
+			ReadyOnly:           c.EndpointSliceReflectionReadyOnly,
+			NotReadyGracePeriod: c.EndpointSliceReflectionNotReadyGracePeriod,
+			Aggregate:           c.EndpointSliceReflectionAggregate,
+			NameMapper:          nameMapper,
+			DeniedPorts:         deniedPorts,
+		},
+
 		EnableAPIServerSupport:     c.EnableAPIServerSupport,
 		EnableStorage:              c.EnableStorage,
 		VirtualStorageClassName:    c.VirtualStorageClassName,
 		RemoteRealStorageClassName: c.RemoteRealStorageClassName,
 	}
 
+	// Re-translate and re-push the reflected endpoints whenever the remapping of the local networks in the remote cluster changes.
+	podcfg.EndpointSliceReflection.Pauser = generic.NewReflectionPauser()
+	dynClient := dynamic.NewForConfigOrDie(localConfig)
+	watcher := exposition.NewTunnelRemapWatcher(podcfg.EndpointSliceReflection.Pauser, c.ForeignCluster.ClusterID)
+	watcher.Start(ctx, dynClient, c.TenantNamespace, c.InformerResyncPeriod)
+
+	// Pause the reflection of the endpoints while the tunnel towards the remote cluster is not ready, as otherwise unreachable.
+	if c.EndpointSliceReflectionWaitTunnelReady {
+		gate := exposition.NewTunnelReadinessGate(podcfg.EndpointSliceReflection.Pauser, c.ForeignCluster.ClusterID)
+		gate.Start(ctx, dynClient, c.TenantNamespace, c.InformerResyncPeriod)
+	}
+
 	eb := record.NewBroadcaster()
 	eb.StartRecordingToSink(&corev1clients.EventSinkImpl{Interface: localClient.CoreV1().Events(corev1.NamespaceAll)})
 
@@ -201,6 +283,14 @@ func runRootCommand(ctx context.Context, c *Opts) error {
 	klog.Info("Setup ended")
 	close(nodeReady)
 	<-ctx.Done()
+
+	// Release the teardown finalizers of the reflected Services, since they would otherwise prevent the deletion of the local
+	// ones (and of their namespaces) once the virtual kubelet terminates. They are added back in case it is restarted.
+	teardownCtx, cancel := context.WithTimeout(context.Background(), teardownTimeout)
+	defer cancel()
+	if err := podcfg.ServiceReflection.ReleaseTeardownFinalizers(teardownCtx, localClient.CoreV1()); err != nil {
+		klog.Errorf("Failed to release the teardown finalizers of the reflected Services: %v", err)
+	}
 	return nil
 }
 
@@ -219,3 +309,43 @@ func getVersion(config *rest.Config) string {
 
 	return version.GitVersion
 }
+
+// parseServiceTypes converts the given strings into the corresponding service types, returning an error if invalid.
+func parseServiceTypes(types []string) ([]corev1.ServiceType, error) {
+	var svcTypes []corev1.ServiceType
+	for _, t := range types {
+		switch svcType := corev1.ServiceType(t); svcType {
+		case corev1.ServiceTypeClusterIP, corev1.ServiceTypeNodePort, corev1.ServiceTypeLoadBalancer, corev1.ServiceTypeExternalName:
+			svcTypes = append(svcTypes, svcType)
+		default:
+			return nil, errors.Errorf("invalid service type %q", t)
+		}
+	}
+	return svcTypes, nil
+}
+
+// validateLabels returns an error if any of the given labels is not a valid label key-value pair.
+func validateLabels(lbls map[string]string) error {
+	for key, value := range lbls {
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return errors.Errorf("invalid label key %q: %s", key, strings.Join(errs, "; "))
+		}
+		if errs := validation.IsValidLabelValue(value); len(errs) > 0 {
+			return errors.Errorf("invalid label value %q: %s", value, strings.Join(errs, "; "))
+		}
+	}
+	return nil
+}
+
+func parseIPFamilies(families []string) ([]corev1.IPFamily, error) {
+	var ipFamilies []corev1.IPFamily
+	for _, f := range families {
+		switch family := corev1.IPFamily(f); family {
+		case corev1.IPv4Protocol, corev1.IPv6Protocol:
+			ipFamilies = append(ipFamilies, family)
+		default:
+			return nil, errors.Errorf("invalid IP family %q", f)
+		}
+	}
+	return ipFamilies, nil
+}
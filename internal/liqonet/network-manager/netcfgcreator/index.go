@@ -0,0 +1,55 @@
+// Copyright 2019-2023 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package netcfgcreator
+
+import (
+	"context"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+	"github.com/liqotech/liqo/pkg/consts"
+)
+
+// RemoteClusterIDIndex is the name of the field index mapping each NetworkConfig to the ID of the remote cluster it refers to,
+// to retrieve the NetworkConfigs of a given cluster through an indexed lookup, rather than filtering all those in the namespace.
+const RemoteClusterIDIndex = "networkconfig.remoteClusterID"
+
+// RemoteClusterIDIndexFunc returns the function extracting the ID of the remote cluster a NetworkConfig refers to, that is
+// the origin cluster in case of remote NetworkConfigs, and the destination one (according to the labels) in case of local ones.
+func (rl *ReplicationLabels) RemoteClusterIDIndexFunc() client.IndexerFunc {
+	return func(obj client.Object) []string {
+		labels := obj.GetLabels()
+		if origin := labels[consts.ReplicationOriginLabel]; origin != "" {
+			return []string{origin}
+		}
+		if destination := labels[rl.DestinationKey()]; destination != "" {
+			return []string{destination}
+		}
+		return nil
+	}
+}
+
+// IndexNetworkConfigs registers the RemoteClusterIDIndex field index on the NetworkConfigs.
+func (rl *ReplicationLabels) IndexNetworkConfigs(ctx context.Context, indexer client.FieldIndexer) error {
+	return indexer.IndexField(ctx, &netv1alpha1.NetworkConfig{}, RemoteClusterIDIndex, rl.RemoteClusterIDIndexFunc())
+}
+
+// MatchingRemoteClusterID returns the list option selecting the NetworkConfigs referring to the given remote cluster through
+// the RemoteClusterIDIndex field index, which shall be registered in the cache the client reads from. Since the index does not
+// distinguish between local and remote NetworkConfigs, it is meant to complement the label selectors, rather than replacing them.
+func MatchingRemoteClusterID(clusterID string) client.ListOption {
+	return client.MatchingFields{RemoteClusterIDIndex: clusterID}
+}
@@ -0,0 +1,176 @@
+// Copyright 2019-2023 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package netcfgcreator
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8slabels "k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+	"github.com/liqotech/liqo/pkg/consts"
+)
+
+// indexedClient is a client serving the NetworkConfigs from a thread safe store indexed through the RemoteClusterIDIndex,
+// mimicking the behavior of the cache backed clients (which, differently from the fake one, honor the field selectors).
+type indexedClient struct {
+	client.Client
+	store cache.Indexer
+}
+
+func newIndexedClient(rl *ReplicationLabels, netcfgs ...*netv1alpha1.NetworkConfig) *indexedClient {
+	indexFunc := rl.RemoteClusterIDIndexFunc()
+	store := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{
+		RemoteClusterIDIndex: func(obj interface{}) ([]string, error) { return indexFunc(obj.(client.Object)), nil },
+	})
+	for _, netcfg := range netcfgs {
+		Expect(store.Add(netcfg)).To(Succeed())
+	}
+	return &indexedClient{Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).Build(), store: store}
+}
+
+func (ic *indexedClient) List(_ context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	options := client.ListOptions{}
+	options.ApplyOptions(opts)
+
+	var objs []interface{}
+	if options.FieldSelector == nil {
+		objs = ic.store.List()
+	} else {
+		value, found := options.FieldSelector.RequiresExactMatch(RemoteClusterIDIndex)
+		if !found {
+			return fmt.Errorf("unsupported field selector %v", options.FieldSelector)
+		}
+
+		var err error
+		if objs, err = ic.store.ByIndex(RemoteClusterIDIndex, value); err != nil {
+			return err
+		}
+	}
+
+	netcfgs := list.(*netv1alpha1.NetworkConfigList)
+	for _, obj := range objs {
+		netcfg := obj.(*netv1alpha1.NetworkConfig)
+		if options.Namespace != "" && netcfg.GetNamespace() != options.Namespace {
+			continue
+		}
+		if options.LabelSelector != nil && !options.LabelSelector.Matches(k8slabels.Set(netcfg.GetLabels())) {
+			continue
+		}
+		netcfgs.Items = append(netcfgs.Items, *netcfg.DeepCopy())
+	}
+	return nil
+}
+
+func remoteNetworkConfigs(count int) []*netv1alpha1.NetworkConfig {
+	netcfgs := make([]*netv1alpha1.NetworkConfig, count)
+	for i := range netcfgs {
+		netcfgs[i] = &netv1alpha1.NetworkConfig{ObjectMeta: metav1.ObjectMeta{
+			Name: fmt.Sprintf("netcfg-%d", i), Namespace: "liqo",
+			Labels: map[string]string{consts.ReplicationOriginLabel: fmt.Sprintf("cluster-%d", i)},
+		}}
+	}
+	return netcfgs
+}
+
+var _ = Describe("The RemoteClusterIDIndex", func() {
+	var rl ReplicationLabels
+
+	BeforeEach(func() { rl = ReplicationLabels{} })
+
+	Describe("The RemoteClusterIDIndexFunc function", func() {
+		DescribeTable("should return the expected cluster IDs",
+			func(labels map[string]string, expected []string) {
+				netcfg := &netv1alpha1.NetworkConfig{ObjectMeta: metav1.ObjectMeta{Name: "foo", Labels: labels}}
+				Expect(rl.RemoteClusterIDIndexFunc()(netcfg)).To(Equal(expected))
+			},
+			Entry("remote NetworkConfig", map[string]string{consts.ReplicationOriginLabel: "origin"}, []string{"origin"}),
+			Entry("local NetworkConfig", map[string]string{consts.ReplicationDestinationLabel: "destination"}, []string{"destination"}),
+			Entry("unlabeled NetworkConfig", map[string]string{}, nil),
+		)
+	})
+
+	Describe("The indexed lookup of the remote NetworkConfig", func() {
+		var (
+			netcfgs []*netv1alpha1.NetworkConfig
+			netcfg  *netv1alpha1.NetworkConfig
+			err     error
+		)
+
+		BeforeEach(func() { netcfgs = remoteNetworkConfigs(10) })
+
+		JustBeforeEach(func() {
+			c := newIndexedClient(&rl, netcfgs...)
+			netcfg, err = GetRemoteNetworkConfig(context.Background(), c, "cluster-5", "liqo", MatchingRemoteClusterID("cluster-5"))
+		})
+
+		When("a single NetworkConfig refers to the given cluster", func() {
+			It("should succeed", func() { Expect(err).ToNot(HaveOccurred()) })
+			It("should return the expected NetworkConfig", func() { Expect(netcfg.GetName()).To(Equal("netcfg-5")) })
+		})
+
+		When("no NetworkConfig refers to the given cluster", func() {
+			BeforeEach(func() { netcfgs = netcfgs[:5] })
+			It("should return a not found error", func() { Expect(kerrors.IsNotFound(err)).To(BeTrue()) })
+		})
+
+		When("multiple NetworkConfigs refer to the given cluster", func() {
+			BeforeEach(func() {
+				duplicate := netcfgs[5].DeepCopy()
+				duplicate.SetName("duplicate")
+				netcfgs = append(netcfgs, duplicate)
+			})
+
+			It("should fail with the multiple instances error", func() {
+				Expect(err).To(MatchError(ContainSubstring("found multiple instances of remote NetworkConfigs")))
+			})
+			It("should return a nil NetworkConfig", func() { Expect(netcfg).To(BeNil()) })
+		})
+	})
+})
+
+// BenchmarkGetRemoteNetworkConfig compares the retrieval of the remote NetworkConfig of a given cluster
+// among 500 ones, filtering them through the label selector only, and leveraging the RemoteClusterIDIndex.
+func BenchmarkGetRemoteNetworkConfig(b *testing.B) {
+	RegisterTestingT(b)
+	c := newIndexedClient(&ReplicationLabels{}, remoteNetworkConfigs(500)...)
+	ctx := context.Background()
+
+	b.Run("LabelSelector", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := GetRemoteNetworkConfig(ctx, c, "cluster-250", "liqo"); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("FieldIndex", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := GetRemoteNetworkConfig(ctx, c, "cluster-250", "liqo", MatchingRemoteClusterID("cluster-250")); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
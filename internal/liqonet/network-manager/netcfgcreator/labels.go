@@ -0,0 +1,78 @@
+// Copyright 2019-2023 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package netcfgcreator
+
+import (
+	"context"
+	"strconv"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+	"github.com/liqotech/liqo/pkg/consts"
+)
+
+// ReplicationLabels contains the keys of the labels used to select the local NetworkConfigs to be replicated by the
+// CRD replicator, in case they are customized. Unset keys default to the ones used by the CRD replicator.
+type ReplicationLabels struct {
+	// Requested is the key of the label marking the resources to be replicated.
+	Requested string
+	// Destination is the key of the label identifying the cluster the resources are replicated to.
+	Destination string
+}
+
+// RequestedKey returns the key of the label marking the resources to be replicated.
+func (rl *ReplicationLabels) RequestedKey() string {
+	if rl == nil || rl.Requested == "" {
+		return consts.ReplicationRequestedLabel
+	}
+	return rl.Requested
+}
+
+// DestinationKey returns the key of the label identifying the cluster the resources are replicated to.
+func (rl *ReplicationLabels) DestinationKey() string {
+	if rl == nil || rl.Destination == "" {
+		return consts.ReplicationDestinationLabel
+	}
+	return rl.Destination
+}
+
+// IsLocal returns whether the given NetworkConfig is a local one, i.e., to be replicated to the remote cluster.
+func (rl *ReplicationLabels) IsLocal(netcfg *netv1alpha1.NetworkConfig) bool {
+	return netcfg.GetLabels()[rl.RequestedKey()] == strconv.FormatBool(true)
+}
+
+// LocalSelector returns the label selector matching the local NetworkConfigs to be replicated.
+func (rl *ReplicationLabels) LocalSelector() metav1.LabelSelector {
+	return metav1.LabelSelector{
+		MatchExpressions: []metav1.LabelSelectorRequirement{
+			{Key: rl.RequestedKey(), Operator: metav1.LabelSelectorOpIn, Values: []string{strconv.FormatBool(true)}},
+			{Key: rl.DestinationKey(), Operator: metav1.LabelSelectorOpExists},
+		},
+	}
+}
+
+// GetLocalNetworkConfig returns the local NetworkConfig associated with a given label selector and a clusterID,
+// identified through the configured label keys. In case more than one NetworkConfig is found, all but the oldest are deleted.
+// The additional list options, if any, further restrict the lookup (e.g., leveraging a field index).
+func (rl *ReplicationLabels) GetLocalNetworkConfig(ctx context.Context, c client.Client, labels client.MatchingLabels,
+	clusterID, namespace string, opts ...client.ListOption) (*netv1alpha1.NetworkConfig, error) {
+	if labels == nil {
+		labels = client.MatchingLabels{}
+	}
+	labels[rl.DestinationKey()] = clusterID
+	return getLocalNetworkConfig(ctx, c, labels, clusterID, namespace, opts...)
+}
@@ -0,0 +1,79 @@
+// Copyright 2019-2023 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package netcfgcreator
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	discoveryv1alpha1 "github.com/liqotech/liqo/apis/discovery/v1alpha1"
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+	"github.com/liqotech/liqo/pkg/consts"
+	foreignclusterutils "github.com/liqotech/liqo/pkg/utils/foreignCluster"
+)
+
+// RestoreNetworkConfigLabels restores the labels of the local NetworkConfig associated with the given ForeignCluster, in case
+// they have been stripped (e.g., by a misbehaving controller), as the NetworkConfig would otherwise be neither replicated to
+// the remote cluster, nor retrieved through the label selectors. Since the labels cannot be trusted, the NetworkConfig is
+// identified by its name, and it is considered only if controlled by the given ForeignCluster. It returns whether the labels
+// have been restored.
+func (ncc *NetworkConfigCreator) RestoreNetworkConfigLabels(ctx context.Context, fc *discoveryv1alpha1.ForeignCluster) (bool, error) {
+	var netcfg netv1alpha1.NetworkConfig
+	key := client.ObjectKey{Name: foreignclusterutils.UniqueName(&fc.Spec.ClusterIdentity), Namespace: fc.Status.TenantNamespace.Local}
+	if err := ncc.Get(ctx, key, &netcfg); err != nil {
+		return false, client.IgnoreNotFound(err)
+	}
+
+	if !metav1.IsControlledBy(&netcfg, fc) {
+		klog.V(4).Infof("NetworkConfig %q is not controlled by ForeignCluster %q, skipping the labels check", key, fc.GetName())
+		return false, nil
+	}
+
+	expected := map[string]string{
+		ncc.ReplicationLabels.RequestedKey():   strconv.FormatBool(true),
+		consts.LocalResourceOwnership:          componentName,
+		ncc.ReplicationLabels.DestinationKey(): fc.Spec.ClusterIdentity.ClusterID,
+	}
+
+	var drifted []string
+	for label, value := range expected {
+		if netcfg.Labels[label] != value {
+			drifted = append(drifted, label)
+		}
+	}
+	if len(drifted) == 0 {
+		return false, nil
+	}
+	sort.Strings(drifted)
+
+	if netcfg.Labels == nil {
+		netcfg.Labels = map[string]string{}
+	}
+	for label, value := range expected {
+		netcfg.Labels[label] = value
+	}
+	if err := ncc.Update(ctx, &netcfg); err != nil {
+		return false, fmt.Errorf("failed to restore the labels of NetworkConfig %q: %w", key, err)
+	}
+
+	klog.Warningf("Restored the labels %v of NetworkConfig %q, which drifted from the expected values", drifted, key)
+	return true, nil
+}
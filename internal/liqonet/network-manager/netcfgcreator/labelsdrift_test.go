@@ -0,0 +1,138 @@
+// Copyright 2019-2023 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package netcfgcreator
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	discoveryv1alpha1 "github.com/liqotech/liqo/apis/discovery/v1alpha1"
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+	"github.com/liqotech/liqo/pkg/consts"
+	foreignclusterutils "github.com/liqotech/liqo/pkg/utils/foreignCluster"
+	peeringconditionsutils "github.com/liqotech/liqo/pkg/utils/peeringConditions"
+)
+
+var _ = Describe("NetworkConfig labels drift", func() {
+	const (
+		clusterID = "remote-cluster-id"
+		namespace = "liqo-tenant-remote"
+	)
+
+	var (
+		ctx context.Context
+		fc  *discoveryv1alpha1.ForeignCluster
+		ncc *NetworkConfigCreator
+		key client.ObjectKey
+	)
+
+	StripLabels := func() {
+		var netcfg netv1alpha1.NetworkConfig
+		ExpectWithOffset(1, ncc.Get(ctx, key, &netcfg)).To(Succeed())
+		netcfg.Labels = map[string]string{"other": "label"}
+		ExpectWithOffset(1, ncc.Update(ctx, &netcfg)).To(Succeed())
+	}
+
+	ExpectLabelsRestored := func() {
+		var netcfg netv1alpha1.NetworkConfig
+		ExpectWithOffset(1, ncc.Get(ctx, key, &netcfg)).To(Succeed())
+		ExpectWithOffset(1, netcfg.Labels).To(Equal(map[string]string{
+			consts.ReplicationRequestedLabel:   "true",
+			consts.ReplicationDestinationLabel: clusterID,
+			consts.LocalResourceOwnership:      componentName,
+			"other":                            "label",
+		}))
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		fc = &discoveryv1alpha1.ForeignCluster{
+			TypeMeta:   metav1.TypeMeta{APIVersion: discoveryv1alpha1.GroupVersion.String(), Kind: "ForeignCluster"},
+			ObjectMeta: metav1.ObjectMeta{Name: "remote", UID: "8a402261-9cf4-402e-89e8-4d743fb315fb"},
+			Spec: discoveryv1alpha1.ForeignClusterSpec{
+				ClusterIdentity: discoveryv1alpha1.ClusterIdentity{ClusterID: clusterID, ClusterName: "remote"},
+				PeeringType:     discoveryv1alpha1.PeeringTypeOutOfBand,
+			},
+			Status: discoveryv1alpha1.ForeignClusterStatus{
+				TenantNamespace: discoveryv1alpha1.TenantNamespaceType{Local: namespace},
+			},
+		}
+		peeringconditionsutils.EnsureStatus(fc, discoveryv1alpha1.OutgoingPeeringCondition,
+			discoveryv1alpha1.PeeringConditionStatusEstablished, "", "")
+		key = client.ObjectKey{Name: foreignclusterutils.UniqueName(&fc.Spec.ClusterIdentity), Namespace: namespace}
+	})
+
+	JustBeforeEach(func() {
+		ncc = &NetworkConfigCreator{
+			Client:       fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(fc).Build(),
+			Scheme:       scheme.Scheme,
+			PodCIDR:      "192.168.0.0/24",
+			ExternalCIDR: "192.168.1.0/24",
+
+			secretWatcher:  &SecretWatcher{wiregardPublicKey: "public-key", configured: true},
+			serviceWatcher: &ServiceWatcher{endpointIP: "1.1.1.1", endpointPort: "9999", configured: true},
+		}
+		Expect(ncc.EnforceNetworkConfigPresence(ctx, fc)).To(Succeed())
+	})
+
+	When("the labels are in place", func() {
+		It("should not restore anything", func() {
+			Expect(ncc.RestoreNetworkConfigLabels(ctx, fc)).To(BeFalse())
+		})
+	})
+
+	When("the labels have been stripped", func() {
+		JustBeforeEach(StripLabels)
+
+		It("should restore the labels", func() {
+			Expect(ncc.RestoreNetworkConfigLabels(ctx, fc)).To(BeTrue())
+			ExpectLabelsRestored()
+		})
+
+		It("should restore the labels when enforcing the NetworkConfig", func() {
+			Expect(ncc.EnforceNetworkConfigPresence(ctx, fc)).To(Succeed())
+			ExpectLabelsRestored()
+		})
+
+		It("should restore the labels when checking for missing NetworkConfigs", func() {
+			Expect(ncc.RecreateMissingNetworkConfigs(ctx)).To(Equal(1))
+			ExpectLabelsRestored()
+			Expect(ncc.RecreateMissingNetworkConfigs(ctx)).To(BeZero())
+		})
+	})
+
+	When("the NetworkConfig is not controlled by the ForeignCluster", func() {
+		JustBeforeEach(func() {
+			StripLabels()
+			var netcfg netv1alpha1.NetworkConfig
+			Expect(ncc.Get(ctx, key, &netcfg)).To(Succeed())
+			netcfg.OwnerReferences = nil
+			Expect(ncc.Update(ctx, &netcfg)).To(Succeed())
+		})
+
+		It("should not restore the labels", func() {
+			Expect(ncc.RestoreNetworkConfigLabels(ctx, fc)).To(BeFalse())
+			var netcfg netv1alpha1.NetworkConfig
+			Expect(ncc.Get(ctx, key, &netcfg)).To(Succeed())
+			Expect(netcfg.Labels).To(Equal(map[string]string{"other": "label"}))
+		})
+	})
+})
@@ -0,0 +1,141 @@
+// Copyright 2019-2023 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package netcfgcreator
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// ConfigStoreName is the default name of the ConfigMap the last-known-good configuration is persisted to.
+	ConfigStoreName = "liqo-network-manager-config"
+
+	configStorePublicKey    = "wireguardPublicKey"
+	configStoreEndpointIP   = "wireguardEndpointIP"
+	configStoreEndpointPort = "wireguardEndpointPort"
+)
+
+// LastKnownGoodConfig is the configuration retrieved by the watchers, required to forge the NetworkConfigs.
+type LastKnownGoodConfig struct {
+	PublicKey    string
+	EndpointIP   string
+	EndpointPort string
+}
+
+// ConfigStore persists the last-known-good configuration to a ConfigMap, so that the NetworkConfigCreator can bootstrap
+// itself upon restart, without waiting for the Secret and Service watchers to be configured again.
+type ConfigStore struct {
+	// Reader retrieves the ConfigMap, and is expected to bypass the cache (which does not include ConfigMaps).
+	Reader client.Reader
+	// Writer creates and updates the ConfigMap.
+	Writer client.Writer
+	// ConfigMap identifies the ConfigMap the configuration is persisted to.
+	ConfigMap types.NamespacedName
+
+	mutex  sync.Mutex
+	stored *LastKnownGoodConfig
+}
+
+// Load retrieves the last-known-good configuration, returning nil in case it has not been persisted yet.
+func (cs *ConfigStore) Load(ctx context.Context) (*LastKnownGoodConfig, error) {
+	var cm corev1.ConfigMap
+	if err := cs.Reader.Get(ctx, cs.ConfigMap, &cm); err != nil {
+		if kerrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to retrieve ConfigMap %q: %w", cs.ConfigMap, err)
+	}
+
+	cfg := &LastKnownGoodConfig{
+		PublicKey:    cm.Data[configStorePublicKey],
+		EndpointIP:   cm.Data[configStoreEndpointIP],
+		EndpointPort: cm.Data[configStoreEndpointPort],
+	}
+
+	cs.mutex.Lock()
+	defer cs.mutex.Unlock()
+	cs.stored = cfg
+	return cfg, nil
+}
+
+// Store persists the given configuration, in case it differs from the one previously stored.
+func (cs *ConfigStore) Store(ctx context.Context, cfg *LastKnownGoodConfig) error {
+	cs.mutex.Lock()
+	defer cs.mutex.Unlock()
+
+	if reflect.DeepEqual(cs.stored, cfg) {
+		return nil
+	}
+
+	cm := corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: cs.ConfigMap.Name, Namespace: cs.ConfigMap.Namespace},
+		Data: map[string]string{
+			configStorePublicKey:    cfg.PublicKey,
+			configStoreEndpointIP:   cfg.EndpointIP,
+			configStoreEndpointPort: cfg.EndpointPort,
+		},
+	}
+
+	err := cs.Writer.Update(ctx, &cm)
+	if kerrors.IsNotFound(err) {
+		err = cs.Writer.Create(ctx, &cm)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to persist the configuration to ConfigMap %q: %w", cs.ConfigMap, err)
+	}
+
+	klog.V(4).Infof("Last-known-good configuration persisted to ConfigMap %q", cs.ConfigMap)
+	stored := *cfg
+	cs.stored = &stored
+	return nil
+}
+
+// bootstrap initializes the watchers with the last-known-good configuration, if persisted.
+func (ncc *NetworkConfigCreator) bootstrap(ctx context.Context) error {
+	cfg, err := ncc.ConfigStore.Load(ctx)
+	if err != nil || cfg == nil {
+		return err
+	}
+
+	klog.Infof("Bootstrapping from the last-known-good configuration persisted to ConfigMap %q", ncc.ConfigStore.ConfigMap)
+	ncc.secretWatcher.bootstrap(cfg.PublicKey)
+	ncc.serviceWatcher.bootstrap(cfg.EndpointIP, cfg.EndpointPort)
+	return nil
+}
+
+// persistConfiguration persists the current configuration as the last-known-good one, if the store is configured.
+// It is expected to be invoked once both watchers are configured.
+func (ncc *NetworkConfigCreator) persistConfiguration(ctx context.Context) {
+	if ncc.ConfigStore == nil {
+		return
+	}
+
+	ip, port := ncc.serviceWatcher.WiregardEndpoint()
+	cfg := LastKnownGoodConfig{PublicKey: ncc.secretWatcher.WiregardPublicKey(), EndpointIP: ip, EndpointPort: port}
+	if err := ncc.ConfigStore.Store(ctx, &cfg); err != nil {
+		// The failure is not fatal, as only preventing a faster bootstrap upon restart.
+		klog.Warningf("Failed to persist the last-known-good configuration: %v", err)
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright 2019-2023 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package netcfgcreator
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gstruct"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Last-known-good configuration", func() {
+	var (
+		ctx   context.Context
+		cl    client.Client
+		store *ConfigStore
+		ncc   *NetworkConfigCreator
+		cfg   LastKnownGoodConfig
+	)
+
+	key := types.NamespacedName{Namespace: "liqo", Name: ConfigStoreName}
+	newStore := func() *ConfigStore { return &ConfigStore{Reader: cl, Writer: cl, ConfigMap: key} }
+	newNetworkConfigCreator := func() *NetworkConfigCreator {
+		enqueuefn := func(rli workqueue.RateLimitingInterface) {}
+		return &NetworkConfigCreator{
+			secretWatcher: NewSecretWatcher(enqueuefn), serviceWatcher: NewServiceWatcher(enqueuefn), ConfigStore: newStore(),
+		}
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		cl = fake.NewClientBuilder().WithScheme(scheme.Scheme).Build()
+		store = newStore()
+		cfg = LastKnownGoodConfig{PublicKey: "public-key", EndpointIP: "1.1.1.1", EndpointPort: "5871"}
+	})
+
+	Describe("The ConfigStore", func() {
+		It("should return a nil configuration if not yet persisted", func() {
+			Expect(store.Load(ctx)).To(BeNil())
+		})
+
+		It("should persist and load back the configuration", func() {
+			Expect(store.Store(ctx, &cfg)).To(Succeed())
+			Expect(newStore().Load(ctx)).To(PointTo(Equal(cfg)))
+		})
+
+		It("should update the persisted configuration", func() {
+			Expect(store.Store(ctx, &cfg)).To(Succeed())
+			cfg.EndpointPort = "5872"
+			Expect(store.Store(ctx, &cfg)).To(Succeed())
+
+			var cm corev1.ConfigMap
+			Expect(cl.Get(ctx, key, &cm)).To(Succeed())
+			Expect(cm.Data).To(HaveKeyWithValue(configStoreEndpointPort, "5872"))
+		})
+	})
+
+	Describe("Restarting the NetworkConfigCreator", func() {
+		var (
+			waitCtx context.Context
+			cancel  context.CancelFunc
+		)
+
+		BeforeEach(func() {
+			waitCtx, cancel = context.WithTimeout(ctx, 50*time.Millisecond)
+			ncc = newNetworkConfigCreator()
+		})
+		AfterEach(func() { cancel() })
+
+		When("a configuration has been persisted", func() {
+			BeforeEach(func() {
+				// Simulate the previous run, which persisted the configuration once configured.
+				previous := newNetworkConfigCreator()
+				previous.secretWatcher.bootstrap(cfg.PublicKey)
+				previous.serviceWatcher.bootstrap(cfg.EndpointIP, cfg.EndpointPort)
+				previous.persistConfiguration(ctx)
+
+				Expect(ncc.bootstrap(ctx)).To(Succeed())
+			})
+
+			It("should be configured immediately", func() {
+				start := time.Now()
+				Expect(ncc.secretWatcher.WaitForConfigured(waitCtx)).To(BeTrue())
+				Expect(ncc.serviceWatcher.WaitForConfigured(waitCtx)).To(BeTrue())
+				Expect(time.Now()).To(BeTemporally("~", start, time.Millisecond))
+			})
+			It("should restore the persisted configuration", func() {
+				Expect(ncc.secretWatcher.WiregardPublicKey()).To(Equal(cfg.PublicKey))
+				ip, port := ncc.serviceWatcher.WiregardEndpoint()
+				Expect(ip).To(Equal(cfg.EndpointIP))
+				Expect(port).To(Equal(cfg.EndpointPort))
+			})
+		})
+
+		When("no configuration has been persisted", func() {
+			BeforeEach(func() { Expect(ncc.bootstrap(ctx)).To(Succeed()) })
+
+			It("should wait for the watchers to be configured", func() {
+				Expect(ncc.secretWatcher.WaitForConfigured(waitCtx)).To(BeFalse())
+			})
+		})
+	})
+})
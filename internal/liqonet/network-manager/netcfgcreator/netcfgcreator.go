@@ -33,7 +33,6 @@ import (
 
 	discoveryv1alpha1 "github.com/liqotech/liqo/apis/discovery/v1alpha1"
 	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
-	"github.com/liqotech/liqo/internal/crdReplicator/reflection"
 	foreigncluster "github.com/liqotech/liqo/pkg/utils/foreignCluster"
 	"github.com/liqotech/liqo/pkg/utils/syncset"
 	traceutils "github.com/liqotech/liqo/pkg/utils/trace"
@@ -52,6 +51,12 @@ type NetworkConfigCreator struct {
 
 	PodCIDR      string
 	ExternalCIDR string
+
+	// ReplicationLabels are the keys of the labels stamped on the NetworkConfigs to be replicated.
+	ReplicationLabels ReplicationLabels
+
+	// ConfigStore, if set, persists the last-known-good configuration, to bootstrap upon restart without waiting for the watchers.
+	ConfigStore *ConfigStore
 }
 
 // cluster-roles
@@ -60,6 +65,7 @@ type NetworkConfigCreator struct {
 // roles
 // +kubebuilder:rbac:groups=core,namespace="do-not-care",resources=secrets,verbs=get;list;watch
 // +kubebuilder:rbac:groups=core,namespace="do-not-care",resources=services,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,namespace="do-not-care",resources=configmaps,verbs=get;create;update
 
 // Reconcile reconciles the state of ForeignCluster resources to enforce the respective NetworkConfigs.
 func (ncc *NetworkConfigCreator) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
@@ -67,6 +73,7 @@ func (ncc *NetworkConfigCreator) Reconcile(ctx context.Context, req ctrl.Request
 	if !ncc.secretWatcher.WaitForConfigured(ctx) || !ncc.serviceWatcher.WaitForConfigured(ctx) {
 		return ctrl.Result{}, errors.New("context expired before initialization completed")
 	}
+	ncc.persistConfiguration(ctx)
 
 	klog.V(4).Infof("Reconciling ForeignCluster %q", req.Name)
 	tracer := trace.New("Reconcile", trace.Field{Key: "ForeignCluster", Value: req.Name})
@@ -100,8 +107,7 @@ func (ncc *NetworkConfigCreator) Reconcile(ctx context.Context, req ctrl.Request
 	ncc.foreignClusters.Add(req.NamespacedName.Name)
 
 	// A peering is (being) established and networking is enabled, hence we need to ensure the network interconnection.
-	if fc.GetDeletionTimestamp().IsZero() && foreigncluster.IsNetworkingEnabled(&fc) &&
-		(foreigncluster.IsIncomingJoined(&fc) || foreigncluster.IsOutgoingJoined(&fc)) {
+	if requiresNetworkConfig(&fc) {
 		return ctrl.Result{}, ncc.EnforceNetworkConfigPresence(ctx, &fc)
 	}
 
@@ -109,20 +115,35 @@ func (ncc *NetworkConfigCreator) Reconcile(ctx context.Context, req ctrl.Request
 	return ctrl.Result{}, ncc.EnforceNetworkConfigAbsence(ctx, &fc)
 }
 
+// enqueueForeignClusters enqueues all the known ForeignClusters, to update the respective NetworkConfigs (e.g., upon changes
+// of the Wireguard configuration). The Secret and Service events are processed by the watchers in the event handlers,
+// while the queue deduplicates the keys: hence, a storm of events results at most in one pending item per ForeignCluster.
+func (ncc *NetworkConfigCreator) enqueueForeignClusters(rli workqueue.RateLimitingInterface) {
+	ncc.foreignClusters.ForEach(func(fc string) { rli.Add(fc) })
+}
+
+// ControllerName is the name of the NetworkConfigCreator controller, which identifies its work queue.
+const ControllerName = "networkconfigcreator"
+
 // SetupWithManager registers a new controller for ForeignCluster resources.
+// The controller relies on a dedicated work queue (and workers), hence the creation of NetworkConfigs
+// is not slowed down by the reconciliation of the existing ones, and vice versa.
 func (ncc *NetworkConfigCreator) SetupWithManager(mgr ctrl.Manager) error {
-	enqueuefn := func(rli workqueue.RateLimitingInterface) {
-		ncc.foreignClusters.ForEach(func(fc string) { rli.Add(fc) })
-	}
-
 	ncc.foreignClusters = syncset.New()
-	ncc.secretWatcher = NewSecretWatcher(enqueuefn)
-	ncc.serviceWatcher = NewServiceWatcher(enqueuefn)
+	ncc.secretWatcher = NewSecretWatcher(ncc.enqueueForeignClusters)
+	ncc.serviceWatcher = NewServiceWatcher(ncc.enqueueForeignClusters)
+
+	if ncc.ConfigStore != nil {
+		if err := ncc.bootstrap(context.Background()); err != nil {
+			// The failure is not fatal, as the configuration is anyway retrieved by the watchers.
+			klog.Warningf("Failed to bootstrap from the last-known-good configuration: %v", err)
+		}
+	}
 
-	localNetcfg, err := predicate.LabelSelectorPredicate(reflection.LocalResourcesLabelSelector())
+	localNetcfg, err := predicate.LabelSelectorPredicate(ncc.ReplicationLabels.LocalSelector())
 	utilruntime.Must(err)
 
-	return ctrl.NewControllerManagedBy(mgr).
+	return ctrl.NewControllerManagedBy(mgr).Named(ControllerName).
 		For(&discoveryv1alpha1.ForeignCluster{}).
 		Owns(&netv1alpha1.NetworkConfig{}, builder.WithPredicates(
 			predicate.Or(predicate.GenerationChangedPredicate{}, predicate.LabelChangedPredicate{}), localNetcfg)).
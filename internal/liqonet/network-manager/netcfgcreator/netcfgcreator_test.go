@@ -16,6 +16,8 @@ package netcfgcreator
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -23,9 +25,12 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/util/workqueue"
 	"k8s.io/utils/pointer"
 	controllerruntime "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 
 	discoveryv1alpha1 "github.com/liqotech/liqo/apis/discovery/v1alpha1"
 	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
@@ -290,3 +295,149 @@ var _ = Describe("NetworkConfigCreator Controller", func() {
 		}))
 	})
 })
+
+var _ = Describe("The processing of a storm of Secret and Service events", func() {
+	const (
+		namespace = "liqo"
+		clusters  = 50
+		events    = 5000
+		bound     = time.Second
+	)
+
+	var (
+		ctx    context.Context
+		cancel context.CancelFunc
+
+		ncc   *NetworkConfigCreator
+		queue workqueue.RateLimitingInterface
+
+		// pending is the maximum number of items observed in the queue during the storm.
+		pending int
+	)
+
+	forgeForeignCluster := func(i int) *discoveryv1alpha1.ForeignCluster {
+		fc := &discoveryv1alpha1.ForeignCluster{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("foreign-cluster-%d", i)},
+			Spec: discoveryv1alpha1.ForeignClusterSpec{
+				ClusterIdentity: discoveryv1alpha1.ClusterIdentity{ClusterID: fmt.Sprintf("cluster-%d", i), ClusterName: "remote"},
+				PeeringType:     discoveryv1alpha1.PeeringTypeOutOfBand,
+			},
+			Status: discoveryv1alpha1.ForeignClusterStatus{
+				TenantNamespace: discoveryv1alpha1.TenantNamespaceType{Local: namespace},
+			},
+		}
+		peeringconditionsutils.EnsureStatus(fc, discoveryv1alpha1.OutgoingPeeringCondition,
+			discoveryv1alpha1.PeeringConditionStatusEstablished, "", "")
+		return fc
+	}
+
+	// storm feeds the watchers with events alternatively changing the Wireguard key and endpoint, as during a flapping.
+	storm := func() {
+		keys := []string{"cHVibGljLWtleS1vZi10aGUtY29ycmVjdC1sZW5ndGg=", "YW5vdGhlci1wdWJsaWMta2V5LW9mLTMyLWJ5dGVzISE="}
+		secrets, services := ncc.secretWatcher.Handlers(), ncc.serviceWatcher.Handlers()
+
+		for i := 0; i < events; i++ {
+			secret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "gateway", Namespace: namespace},
+				Data:       map[string][]byte{consts.PublicKey: []byte(keys[i%2])},
+			}
+			secrets.Update(event.UpdateEvent{ObjectOld: secret, ObjectNew: secret}, queue)
+
+			service := &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{Name: "gateway", Namespace: namespace,
+					Annotations: map[string]string{"net.liqo.io/gatewayNodeIP": fmt.Sprintf("1.1.1.%d", i%2+1)}},
+				Spec: corev1.ServiceSpec{Type: corev1.ServiceTypeNodePort,
+					Ports: []corev1.ServicePort{{Name: "wireguard", NodePort: 9999}}},
+			}
+			services.Update(event.UpdateEvent{ObjectOld: service, ObjectNew: service}, queue)
+
+			if queue.Len() > pending {
+				pending = queue.Len()
+			}
+		}
+	}
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithCancel(context.Background())
+		pending = 0
+
+		queue = workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), ControllerName)
+
+		ncc = &NetworkConfigCreator{
+			Client:       fake.NewClientBuilder().WithScheme(scheme.Scheme).Build(),
+			Scheme:       scheme.Scheme,
+			PodCIDR:      "192.168.0.0/24",
+			ExternalCIDR: "192.168.1.0/24",
+
+			foreignClusters: syncset.New(),
+		}
+		ncc.secretWatcher = NewSecretWatcher(ncc.enqueueForeignClusters)
+		ncc.serviceWatcher = NewServiceWatcher(ncc.enqueueForeignClusters)
+
+		for i := 0; i < clusters; i++ {
+			fc := forgeForeignCluster(i)
+			Expect(ncc.Create(ctx, fc)).To(Succeed())
+			ncc.foreignClusters.Add(fc.GetName())
+		}
+
+		// The worker processes the queue as the controller does.
+		go func() {
+			for {
+				item, shutdown := queue.Get()
+				if shutdown {
+					return
+				}
+				req := controllerruntime.Request{NamespacedName: types.NamespacedName{Name: item.(string)}}
+				if _, err := ncc.Reconcile(ctx, req); err != nil {
+					queue.AddRateLimited(item)
+				} else {
+					queue.Forget(item)
+				}
+				queue.Done(item)
+			}
+		}()
+	})
+
+	AfterEach(func() {
+		queue.ShutDown()
+		cancel()
+	})
+
+	It("should not delay the creation of the NetworkConfig of a new ForeignCluster beyond a bound", func() {
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			storm()
+		}()
+		Eventually(ncc.secretWatcher.WiregardPublicKey).ShouldNot(BeEmpty())
+
+		// Enqueue the new ForeignCluster while the storm is in progress, as the ForeignCluster watch does.
+		fc := forgeForeignCluster(clusters)
+		Expect(ncc.Create(ctx, fc)).To(Succeed())
+		queue.Add(fc.GetName())
+
+		Eventually(func() error {
+			_, err := GetLocalNetworkConfig(ctx, ncc.Client, nil, fc.Spec.ClusterIdentity.ClusterID, namespace)
+			return err
+		}).WithTimeout(bound).WithPolling(time.Millisecond).Should(Succeed())
+		Eventually(done).WithTimeout(time.Minute).Should(BeClosed())
+	})
+
+	It("should keep at most one pending item per ForeignCluster", func() {
+		storm()
+		Expect(pending).To(BeNumerically("<=", clusters))
+	})
+
+	It("should eventually converge to the last Wireguard configuration", func() {
+		storm()
+		Eventually(func() []string {
+			var netcfgs netv1alpha1.NetworkConfigList
+			Expect(ncc.List(ctx, &netcfgs)).To(Succeed())
+			var keys []string
+			for i := range netcfgs.Items {
+				keys = append(keys, netcfgs.Items[i].Spec.BackendConfig[consts.PublicKey])
+			}
+			return keys
+		}).Should(And(HaveLen(clusters), HaveEach("YW5vdGhlci1wdWJsaWMta2V5LW9mLTMyLWJ5dGVzISE=")))
+	})
+})
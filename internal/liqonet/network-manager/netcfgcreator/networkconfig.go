@@ -34,21 +34,21 @@ import (
 	foreignclusterutils "github.com/liqotech/liqo/pkg/utils/foreignCluster"
 )
 
-// GetLocalNetworkConfig returns the local NetworkConfig associated with a given label selector and a clusterID.
-// In case more than one NetworkConfig is found, all but the oldest are deleted.
+// GetLocalNetworkConfig returns the local NetworkConfig associated with a given label selector and a clusterID,
+// identified through the default replication label keys. In case more than one NetworkConfig is found, all but the oldest are deleted.
+// The additional list options, if any, further restrict the lookup (e.g., leveraging a field index).
 func GetLocalNetworkConfig(ctx context.Context, c client.Client, labels client.MatchingLabels,
-	clusterID, namespace string) (*netv1alpha1.NetworkConfig, error) {
-	networkConfigList := &netv1alpha1.NetworkConfigList{}
+	clusterID, namespace string, opts ...client.ListOption) (*netv1alpha1.NetworkConfig, error) {
+	return (&ReplicationLabels{}).GetLocalNetworkConfig(ctx, c, labels, clusterID, namespace, opts...)
+}
 
-	if labels == nil {
-		labels = client.MatchingLabels{
-			consts.ReplicationDestinationLabel: clusterID,
-		}
-	} else {
-		labels[consts.ReplicationDestinationLabel] = clusterID
-	}
+// getLocalNetworkConfig returns the local NetworkConfig matching the given labels (already including the destination cluster).
+func getLocalNetworkConfig(ctx context.Context, c client.Client, labels client.MatchingLabels,
+	clusterID, namespace string, opts ...client.ListOption) (*netv1alpha1.NetworkConfig, error) {
+	networkConfigList := &netv1alpha1.NetworkConfigList{}
 
-	if err := c.List(ctx, networkConfigList, labels, client.InNamespace(namespace)); err != nil {
+	opts = append([]client.ListOption{labels, client.InNamespace(namespace)}, opts...)
+	if err := c.List(ctx, networkConfigList, opts...); err != nil {
 		klog.Errorf("An error occurred while listing NetworkConfigs: %v", err)
 		return nil, err
 	}
@@ -77,11 +77,14 @@ func GetLocalNetworkConfig(ctx context.Context, c client.Client, labels client.M
 }
 
 // GetRemoteNetworkConfig returns the remote NetworkConfig associated with a given cluster ID.
-func GetRemoteNetworkConfig(ctx context.Context, c client.Client, clusterID, namespace string) (*netv1alpha1.NetworkConfig, error) {
+// The additional list options, if any, further restrict the lookup (e.g., leveraging a field index).
+func GetRemoteNetworkConfig(ctx context.Context, c client.Client, clusterID, namespace string,
+	opts ...client.ListOption) (*netv1alpha1.NetworkConfig, error) {
 	networkConfigList := &netv1alpha1.NetworkConfigList{}
 	labels := client.MatchingLabels{consts.ReplicationOriginLabel: clusterID}
 
-	if err := c.List(ctx, networkConfigList, labels, client.InNamespace(namespace)); err != nil {
+	opts = append([]client.ListOption{labels, client.InNamespace(namespace)}, opts...)
+	if err := c.List(ctx, networkConfigList, opts...); err != nil {
 		klog.Errorf("An error occurred while listing NetworkConfigs: %v", err)
 		return nil, err
 	}
@@ -120,13 +123,18 @@ func (ncc *NetworkConfigCreator) EnforceNetworkConfigPresence(ctx context.Contex
 	}
 
 	// Check if the resource for the remote cluster already exists
-	netcfg, err := GetLocalNetworkConfig(ctx, ncc.Client, labels, clusterID, fc.Status.TenantNamespace.Local)
+	netcfg, err := ncc.ReplicationLabels.GetLocalNetworkConfig(ctx, ncc.Client, labels, clusterID, fc.Status.TenantNamespace.Local)
 	if client.IgnoreNotFound(err) != nil {
 		return err
 	}
 
-	// Create the resource if not already present (if the error is not nil, then at this point is a not found one)
+	// Create the resource if not already present (if the error is not nil, then at this point is a not found one),
+	// unless it exists but its labels have been stripped, in which case they are restored.
 	if err != nil {
+		restored, err := ncc.RestoreNetworkConfigLabels(ctx, fc)
+		if err != nil || restored {
+			return err
+		}
 		return ncc.createNetworkConfig(ctx, fc)
 	}
 
@@ -182,9 +190,9 @@ func (ncc *NetworkConfigCreator) populateNetworkConfig(netcfg *netv1alpha1.Netwo
 	if netcfg.Labels == nil {
 		netcfg.Labels = map[string]string{}
 	}
-	netcfg.Labels[consts.ReplicationRequestedLabel] = strconv.FormatBool(true)
+	netcfg.Labels[ncc.ReplicationLabels.RequestedKey()] = strconv.FormatBool(true)
 	netcfg.Labels[consts.LocalResourceOwnership] = componentName
-	netcfg.Labels[consts.ReplicationDestinationLabel] = clusterIdentity.ClusterID
+	netcfg.Labels[ncc.ReplicationLabels.DestinationKey()] = clusterIdentity.ClusterID
 
 	wgEndpointIP, wgEndpointPort := ncc.serviceWatcher.WiregardEndpoint()
 
@@ -207,8 +215,8 @@ func (ncc *NetworkConfigCreator) populateNetworkConfig(netcfg *netv1alpha1.Netwo
 func (ncc *NetworkConfigCreator) EnforceNetworkConfigAbsence(ctx context.Context, fc *discoveryv1alpha1.ForeignCluster) error {
 	clusterIdentity := fc.Spec.ClusterIdentity
 	labels := client.MatchingLabels{
-		consts.ReplicationDestinationLabel: clusterIdentity.ClusterID,
-		consts.LocalResourceOwnership:      componentName,
+		ncc.ReplicationLabels.DestinationKey(): clusterIdentity.ClusterID,
+		consts.LocalResourceOwnership:          componentName,
 	}
 
 	// Let perform a cached list first, to prevent unnecessary interactions with the API server.
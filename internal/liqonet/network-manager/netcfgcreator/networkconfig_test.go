@@ -22,6 +22,7 @@ import (
 	. "github.com/onsi/gomega"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8slabels "k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes/scheme"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -44,6 +45,7 @@ var _ = Describe("Network config functions", func() {
 		ctx           context.Context
 		clientBuilder fake.ClientBuilder
 		fcw           *NetworkConfigCreator
+		rl            ReplicationLabels
 		labels        = client.MatchingLabels{
 			consts.LocalResourceOwnership: componentName,
 		}
@@ -52,6 +54,7 @@ var _ = Describe("Network config functions", func() {
 	BeforeEach(func() {
 		ctx = context.Background()
 		clientBuilder = *fake.NewClientBuilder().WithScheme(scheme.Scheme)
+		rl = ReplicationLabels{}
 	})
 
 	JustBeforeEach(func() {
@@ -62,6 +65,8 @@ var _ = Describe("Network config functions", func() {
 			PodCIDR:      "192.168.0.0/24",
 			ExternalCIDR: "192.168.1.0/24",
 
+			ReplicationLabels: rl,
+
 			secretWatcher:  &SecretWatcher{wiregardPublicKey: "public-key"},
 			serviceWatcher: &ServiceWatcher{endpointIP: "1.1.1.1", endpointPort: "9999"},
 		}
@@ -314,6 +319,48 @@ var _ = Describe("Network config functions", func() {
 					AssertNetworkConfigSpec(netcfg)
 				})
 			})
+
+			When("the gateway endpoint IP changes after the network config has been created", func() {
+				JustBeforeEach(func() {
+					Expect(err).ToNot(HaveOccurred())
+					fcw.serviceWatcher.endpointIP = "2.2.2.2"
+					err = fcw.EnforceNetworkConfigPresence(ctx, fc)
+				})
+
+				It("should succeed", func() { Expect(err).ToNot(HaveOccurred()) })
+				It("the network config should carry the new endpoint IP", func() {
+					netcfg, err := GetLocalNetworkConfig(ctx, fcw.Client, labels, clusterID, namespace)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(netcfg.Spec.EndpointIP).To(BeIdenticalTo("2.2.2.2"))
+				})
+			})
+
+			When("custom replication label keys are configured", func() {
+				BeforeEach(func() {
+					rl = ReplicationLabels{Requested: "example.com/replicate", Destination: "example.com/destination"}
+				})
+
+				It("should succeed", func() { Expect(err).ToNot(HaveOccurred()) })
+				It("the network config should carry the custom labels", func() {
+					netcfg, err := rl.GetLocalNetworkConfig(ctx, fcw.Client, nil, clusterID, namespace)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(netcfg.Labels).To(HaveKeyWithValue("example.com/replicate", "true"))
+					Expect(netcfg.Labels).To(HaveKeyWithValue("example.com/destination", clusterID))
+					Expect(netcfg.Labels).ToNot(HaveKey(consts.ReplicationRequestedLabel))
+				})
+				It("the network config should be matched by the replication selector", func() {
+					netcfg, err := rl.GetLocalNetworkConfig(ctx, fcw.Client, nil, clusterID, namespace)
+					Expect(err).ToNot(HaveOccurred())
+					ls := rl.LocalSelector()
+					selector, err := metav1.LabelSelectorAsSelector(&ls)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(selector.Matches(k8slabels.Set(netcfg.Labels))).To(BeTrue())
+				})
+				It("should not be retrieved through the default label keys", func() {
+					_, err := GetLocalNetworkConfig(ctx, fcw.Client, nil, clusterID, namespace)
+					Expect(kerrors.IsNotFound(err)).To(BeTrue())
+				})
+			})
 		})
 
 		Describe("The EnforceNetworkConfigAbsence function", func() {
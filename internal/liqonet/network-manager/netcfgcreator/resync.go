@@ -0,0 +1,91 @@
+// Copyright 2019-2023 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package netcfgcreator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	discoveryv1alpha1 "github.com/liqotech/liqo/apis/discovery/v1alpha1"
+	"github.com/liqotech/liqo/pkg/consts"
+	foreignclusterutils "github.com/liqotech/liqo/pkg/utils/foreignCluster"
+)
+
+// RecreateMissingNetworkConfigs ensures that every ForeignCluster with an active peering (and networking enabled) has
+// the corresponding local NetworkConfig, recreating those which are missing. The NetworkConfigs are otherwise enforced
+// only upon ForeignCluster events, which might not fire again in case a NetworkConfig is accidentally deleted while the
+// peering is active. It additionally restores the labels of the NetworkConfigs which have been stripped. It returns
+// the number of NetworkConfigs which have been recreated (or whose labels have been restored).
+func (ncc *NetworkConfigCreator) RecreateMissingNetworkConfigs(ctx context.Context) (int, error) {
+	// Wait, in case the configuration has not completed yet.
+	if !ncc.secretWatcher.WaitForConfigured(ctx) || !ncc.serviceWatcher.WaitForConfigured(ctx) {
+		return 0, errors.New("context expired before initialization completed")
+	}
+
+	var foreignClusters discoveryv1alpha1.ForeignClusterList
+	if err := ncc.List(ctx, &foreignClusters); err != nil {
+		return 0, fmt.Errorf("failed to list ForeignClusters: %w", err)
+	}
+
+	labels := client.MatchingLabels{consts.LocalResourceOwnership: componentName}
+
+	recreated := 0
+	for i := range foreignClusters.Items {
+		fc := &foreignClusters.Items[i]
+		if !requiresNetworkConfig(fc) {
+			continue
+		}
+
+		clusterID := fc.Spec.ClusterIdentity.ClusterID
+		_, err := ncc.ReplicationLabels.GetLocalNetworkConfig(ctx, ncc.Client, labels, clusterID, fc.Status.TenantNamespace.Local)
+		if err == nil {
+			continue
+		}
+		if !kerrors.IsNotFound(err) {
+			return recreated, fmt.Errorf("failed to retrieve the NetworkConfig for cluster %s: %w", fc.Spec.ClusterIdentity, err)
+		}
+
+		// The NetworkConfig might still exist, but with the labels stripped: in that case, it is sufficient to restore them.
+		restored, err := ncc.RestoreNetworkConfigLabels(ctx, fc)
+		if err != nil {
+			return recreated, err
+		}
+		if restored {
+			recreated++
+			continue
+		}
+
+		klog.Warningf("NetworkConfig for cluster %s is missing, although the peering is active: recreating it", fc.Spec.ClusterIdentity)
+		if err := ncc.createNetworkConfig(ctx, fc); err != nil {
+			return recreated, fmt.Errorf("failed to recreate the NetworkConfig for cluster %s: %w", fc.Spec.ClusterIdentity, err)
+		}
+		recreated++
+	}
+
+	return recreated, nil
+}
+
+// requiresNetworkConfig returns whether a local NetworkConfig is expected to exist for the given ForeignCluster,
+// that is, a peering is (being) established and networking is enabled.
+func requiresNetworkConfig(fc *discoveryv1alpha1.ForeignCluster) bool {
+	return fc.Spec.ClusterIdentity.ClusterID != "" && fc.GetDeletionTimestamp().IsZero() &&
+		foreignclusterutils.IsNetworkingEnabled(fc) &&
+		(foreignclusterutils.IsIncomingJoined(fc) || foreignclusterutils.IsOutgoingJoined(fc))
+}
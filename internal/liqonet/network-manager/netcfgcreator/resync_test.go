@@ -0,0 +1,129 @@
+// Copyright 2019-2023 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package netcfgcreator
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	discoveryv1alpha1 "github.com/liqotech/liqo/apis/discovery/v1alpha1"
+	peeringconditionsutils "github.com/liqotech/liqo/pkg/utils/peeringConditions"
+)
+
+var _ = Describe("Missing NetworkConfigs recreation", func() {
+	const (
+		clusterID = "remote-cluster-id"
+		namespace = "liqo-tenant-remote"
+	)
+
+	var (
+		ctx context.Context
+		fc  *discoveryv1alpha1.ForeignCluster
+		ncc *NetworkConfigCreator
+
+		recreated int
+		err       error
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		fc = &discoveryv1alpha1.ForeignCluster{
+			TypeMeta:   metav1.TypeMeta{APIVersion: discoveryv1alpha1.GroupVersion.String(), Kind: "ForeignCluster"},
+			ObjectMeta: metav1.ObjectMeta{Name: "remote", UID: "8a402261-9cf4-402e-89e8-4d743fb315fb"},
+			Spec: discoveryv1alpha1.ForeignClusterSpec{
+				ClusterIdentity: discoveryv1alpha1.ClusterIdentity{ClusterID: clusterID, ClusterName: "remote"},
+				PeeringType:     discoveryv1alpha1.PeeringTypeOutOfBand,
+			},
+			Status: discoveryv1alpha1.ForeignClusterStatus{
+				TenantNamespace: discoveryv1alpha1.TenantNamespaceType{Local: namespace},
+			},
+		}
+		peeringconditionsutils.EnsureStatus(fc, discoveryv1alpha1.OutgoingPeeringCondition,
+			discoveryv1alpha1.PeeringConditionStatusEstablished, "", "")
+	})
+
+	JustBeforeEach(func() {
+		ncc = &NetworkConfigCreator{
+			Client:       fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(fc).Build(),
+			Scheme:       scheme.Scheme,
+			PodCIDR:      "192.168.0.0/24",
+			ExternalCIDR: "192.168.1.0/24",
+
+			secretWatcher:  &SecretWatcher{wiregardPublicKey: "public-key", configured: true},
+			serviceWatcher: &ServiceWatcher{endpointIP: "1.1.1.1", endpointPort: "9999", configured: true},
+		}
+	})
+
+	When("the NetworkConfig of an active peering has been deleted", func() {
+		JustBeforeEach(func() {
+			Expect(ncc.EnforceNetworkConfigPresence(ctx, fc)).To(Succeed())
+			netcfg, err := GetLocalNetworkConfig(ctx, ncc.Client, nil, clusterID, namespace)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ncc.Delete(ctx, netcfg)).To(Succeed())
+
+			recreated, err = ncc.RecreateMissingNetworkConfigs(ctx)
+		})
+
+		It("should succeed", func() { Expect(err).ToNot(HaveOccurred()) })
+		It("should report the recreation", func() { Expect(recreated).To(Equal(1)) })
+		It("should recreate the NetworkConfig", func() {
+			netcfg, err := GetLocalNetworkConfig(ctx, ncc.Client, nil, clusterID, namespace)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(netcfg.Spec.RemoteCluster.ClusterID).To(Equal(clusterID))
+			Expect(metav1.GetControllerOf(netcfg).UID).To(Equal(fc.GetUID()))
+		})
+	})
+
+	When("the NetworkConfig of an active peering exists", func() {
+		JustBeforeEach(func() {
+			Expect(ncc.EnforceNetworkConfigPresence(ctx, fc)).To(Succeed())
+			recreated, err = ncc.RecreateMissingNetworkConfigs(ctx)
+		})
+
+		It("should succeed", func() { Expect(err).ToNot(HaveOccurred()) })
+		It("should not recreate anything", func() { Expect(recreated).To(BeZero()) })
+	})
+
+	When("the peering is not active", func() {
+		BeforeEach(func() {
+			peeringconditionsutils.EnsureStatus(fc, discoveryv1alpha1.OutgoingPeeringCondition,
+				discoveryv1alpha1.PeeringConditionStatusNone, "", "")
+		})
+
+		JustBeforeEach(func() { recreated, err = ncc.RecreateMissingNetworkConfigs(ctx) })
+
+		It("should succeed", func() { Expect(err).ToNot(HaveOccurred()) })
+		It("should not create the NetworkConfig", func() {
+			Expect(recreated).To(BeZero())
+			_, err := GetLocalNetworkConfig(ctx, ncc.Client, nil, clusterID, namespace)
+			Expect(kerrors.IsNotFound(err)).To(BeTrue())
+		})
+	})
+
+	When("networking is disabled for the peering", func() {
+		BeforeEach(func() { fc.Spec.PeeringType = discoveryv1alpha1.PeeringTypeInBand })
+
+		JustBeforeEach(func() { recreated, err = ncc.RecreateMissingNetworkConfigs(ctx) })
+
+		It("should succeed", func() { Expect(err).ToNot(HaveOccurred()) })
+		It("should not create the NetworkConfig", func() { Expect(recreated).To(BeZero()) })
+	})
+})
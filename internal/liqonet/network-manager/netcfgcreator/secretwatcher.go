@@ -82,6 +82,22 @@ func (sw *SecretWatcher) WaitForConfigured(ctx context.Context) bool {
 	return true
 }
 
+// bootstrap initializes the watcher with the given public key (e.g., the last-known-good one), in case it is not yet
+// configured. The key is anyway replaced once retrieved from the corresponding Secret, if different.
+func (sw *SecretWatcher) bootstrap(key string) {
+	sw.Lock()
+	defer sw.Unlock()
+
+	if sw.configured || key == "" {
+		return
+	}
+
+	klog.Infof("Wiregard public key bootstrapped from the last-known-good configuration")
+	sw.wiregardPublicKey = key
+	close(sw.wait)
+	sw.configured = true
+}
+
 // Handlers returns the set of handlers used for the Watch configuration.
 func (sw *SecretWatcher) Handlers() handler.EventHandler {
 	return handler.Funcs{
@@ -83,6 +83,23 @@ func (sw *ServiceWatcher) WaitForConfigured(ctx context.Context) bool {
 	return true
 }
 
+// bootstrap initializes the watcher with the given endpoint (e.g., the last-known-good one), in case it is not yet
+// configured. The endpoint is anyway replaced once retrieved from the corresponding Service, if different.
+func (sw *ServiceWatcher) bootstrap(ip, port string) {
+	sw.Lock()
+	defer sw.Unlock()
+
+	if sw.configured || ip == "" || port == "" {
+		return
+	}
+
+	klog.Infof("Wiregard endpoint bootstrapped from the last-known-good configuration: %s:%s", ip, port)
+	sw.endpointIP = ip
+	sw.endpointPort = port
+	close(sw.wait)
+	sw.configured = true
+}
+
 // Handlers returns the set of handlers used for the Watch configuration.
 func (sw *ServiceWatcher) Handlers() handler.EventHandler {
 	return handler.Funcs{
@@ -59,6 +59,25 @@ var _ = Describe("Service Watcher functions", func() {
 				It("should be initialized", func() { Expect(sw.configured).To(BeTrue()) })
 			})
 
+			When("the gateway IP changes (e.g., due to a failover)", func() {
+				BeforeEach(func() {
+					sw.endpointIP, sw.endpointPort, sw.configured = "2.2.2.2", "9999", true
+				})
+				It("should retrieve the new endpoint", func() {
+					ip, port := sw.WiregardEndpoint()
+					Expect(ip).To(BeIdenticalTo("1.1.1.1"))
+					Expect(port).To(BeIdenticalTo("9999"))
+				})
+				It("should enqueue the foreign clusters to update the network configs", func() { Expect(handled).To(BeClosed()) })
+			})
+
+			When("the gateway endpoint did not change", func() {
+				BeforeEach(func() {
+					sw.endpointIP, sw.endpointPort, sw.configured = "1.1.1.1", "9999", true
+				})
+				It("should not enqueue the foreign clusters", func() { Expect(handled).ToNot(BeClosed()) })
+			})
+
 			When("given an invalid service (missing the annotation)", func() {
 				BeforeEach(func() { service.Annotations = nil })
 				It("should not execute the handle function", func() { Expect(handled).ToNot(BeClosed()) })
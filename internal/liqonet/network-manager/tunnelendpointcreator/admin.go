@@ -0,0 +1,155 @@
+// Copyright 2019-2023 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tunnelendpointcreator
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+)
+
+const (
+	// ReservationsDebugPath is the path of the admin endpoint exposing the IPAM reservations and the per-cluster subnets.
+	ReservationsDebugPath = "/debug/reservations"
+	// TunnelsDebugPath is the path of the admin endpoint exposing the TunnelEndpoints and the corresponding phases.
+	TunnelsDebugPath = "/debug/tunnels"
+)
+
+// TunnelInfo summarizes the configuration and the phase of a TunnelEndpoint, as exposed by the admin endpoint.
+type TunnelInfo struct {
+	Name        string `json:"name"`
+	Namespace   string `json:"namespace"`
+	ClusterID   string `json:"clusterID"`
+	ClusterName string `json:"clusterName,omitempty"`
+
+	RemotePodCIDR         string `json:"remotePodCIDR"`
+	RemoteNATPodCIDR      string `json:"remoteNATPodCIDR"`
+	RemoteExternalCIDR    string `json:"remoteExternalCIDR"`
+	RemoteNATExternalCIDR string `json:"remoteNATExternalCIDR"`
+	LocalNATPodCIDR       string `json:"localNATPodCIDR"`
+	LocalNATExternalCIDR  string `json:"localNATExternalCIDR"`
+
+	Phase         netv1alpha1.ConnectionStatus `json:"phase,omitempty"`
+	StatusMessage string                       `json:"statusMessage,omitempty"`
+	Terminating   bool                         `json:"terminating,omitempty"`
+}
+
+// AdminHandler returns the handler of the read-only admin endpoints, dumping the current allocation decisions as JSON
+// for debugging purposes. It is meant to be served separately from the metrics, and only when explicitly enabled.
+func (tec *TunnelEndpointCreator) AdminHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(ReservationsDebugPath, tec.serveReservations)
+	mux.HandleFunc(TunnelsDebugPath, tec.serveTunnels)
+	return mux
+}
+
+// serveReservations writes the whole IPAM state, including the reserved networks and the subnets of each remote cluster.
+func (tec *TunnelEndpointCreator) serveReservations(w http.ResponseWriter, r *http.Request) {
+	if !allowReadOnly(w, r) {
+		return
+	}
+
+	ipManager, err := tec.ipManager()
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+
+	unlock := tec.lockIPAM()
+	state, err := ipManager.MarshalState()
+	unlock()
+	if err != nil {
+		writeAdminError(w, fmt.Errorf("failed to retrieve the IPAM state: %w", err))
+		return
+	}
+	writeAdminJSON(w, state)
+}
+
+// serveTunnels writes the summary of the existing TunnelEndpoints, sorted by cluster ID.
+func (tec *TunnelEndpointCreator) serveTunnels(w http.ResponseWriter, r *http.Request) {
+	if !allowReadOnly(w, r) {
+		return
+	}
+
+	var teps netv1alpha1.TunnelEndpointList
+	var opts []client.ListOption
+	if tec.TunnelEndpointNamespace != "" {
+		opts = append(opts, client.InNamespace(tec.TunnelEndpointNamespace))
+	}
+	if err := tec.List(r.Context(), &teps, opts...); err != nil {
+		writeAdminError(w, fmt.Errorf("failed to list TunnelEndpoints: %w", err))
+		return
+	}
+
+	tunnels := make([]TunnelInfo, 0, len(teps.Items))
+	for i := range teps.Items {
+		tep := &teps.Items[i]
+		tunnels = append(tunnels, TunnelInfo{
+			Name:        tep.GetName(),
+			Namespace:   tep.GetNamespace(),
+			ClusterID:   tep.Spec.ClusterIdentity.ClusterID,
+			ClusterName: tep.Spec.ClusterIdentity.ClusterName,
+
+			RemotePodCIDR:         tep.Spec.RemotePodCIDR,
+			RemoteNATPodCIDR:      tep.Spec.RemoteNATPodCIDR,
+			RemoteExternalCIDR:    tep.Spec.RemoteExternalCIDR,
+			RemoteNATExternalCIDR: tep.Spec.RemoteNATExternalCIDR,
+			LocalNATPodCIDR:       tep.Spec.LocalNATPodCIDR,
+			LocalNATExternalCIDR:  tep.Spec.LocalNATExternalCIDR,
+
+			Phase:         tep.Status.Connection.Status,
+			StatusMessage: tep.Status.Connection.StatusMessage,
+			Terminating:   !tep.GetDeletionTimestamp().IsZero(),
+		})
+	}
+	sort.SliceStable(tunnels, func(i, j int) bool { return tunnels[i].ClusterID < tunnels[j].ClusterID })
+
+	data, err := json.Marshal(tunnels)
+	if err != nil {
+		writeAdminError(w, fmt.Errorf("failed to serialize the TunnelEndpoints: %w", err))
+		return
+	}
+	writeAdminJSON(w, data)
+}
+
+// allowReadOnly rejects the requests with methods other than GET and HEAD, returning whether the request shall be served.
+func allowReadOnly(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		return true
+	}
+	w.Header().Set("Allow", "GET, HEAD")
+	http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	return false
+}
+
+// writeAdminJSON writes the given JSON document as response.
+func writeAdminJSON(w http.ResponseWriter, data []byte) {
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(data); err != nil {
+		klog.Errorf("Failed to write the admin endpoint response: %v", err)
+	}
+}
+
+// writeAdminError logs the given error and writes it as response.
+func writeAdminError(w http.ResponseWriter, err error) {
+	klog.Errorf("Admin endpoint request failed: %v", err)
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
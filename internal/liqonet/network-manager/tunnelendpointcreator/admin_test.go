@@ -0,0 +1,168 @@
+// Copyright 2019-2023 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tunnelendpointcreator
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	discoveryv1alpha1 "github.com/liqotech/liqo/apis/discovery/v1alpha1"
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+)
+
+// statefulIPAM is a liqonetIpam.Ipam implementation which tracks the subnets of the remote clusters, and exposes them as its state.
+type statefulIPAM struct {
+	fakeIPAM
+	subnets map[string]netv1alpha1.Subnets
+}
+
+func (si *statefulIPAM) GetSubnetsPerCluster(podCIDR, externalCIDR, clusterID string) (mappedPodCIDR, mappedExternalCIDR string, err error) {
+	si.subnets[clusterID] = netv1alpha1.Subnets{RemotePodCIDR: podCIDR, RemoteExternalCIDR: externalCIDR,
+		LocalNATPodCIDR: "None", LocalNATExternalCIDR: "None"}
+	return podCIDR, externalCIDR, nil
+}
+
+func (si *statefulIPAM) RemoveClusterConfig(clusterID string) error {
+	delete(si.subnets, clusterID)
+	return nil
+}
+
+func (si *statefulIPAM) MarshalState() ([]byte, error) {
+	return json.Marshal(netv1alpha1.IpamStorage{Spec: netv1alpha1.IpamSpec{ClusterSubnets: si.subnets}})
+}
+
+var _ = Describe("Admin endpoints", func() {
+	const otherClusterID = "other-cluster-id"
+
+	var (
+		cl     client.Client
+		ipam   *statefulIPAM
+		tec    *TunnelEndpointCreator
+		server *httptest.Server
+	)
+
+	tunnelEndpoint := func(name, clusterID string, status netv1alpha1.ConnectionStatus) *netv1alpha1.TunnelEndpoint {
+		return &netv1alpha1.TunnelEndpoint{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Spec: netv1alpha1.TunnelEndpointSpec{
+				ClusterIdentity: discoveryv1alpha1.ClusterIdentity{ClusterID: clusterID, ClusterName: clusterID + "-name"},
+				RemotePodCIDR:   "10.50.0.0/16", RemoteNATPodCIDR: "10.60.0.0/16",
+				RemoteExternalCIDR: "10.51.0.0/16", RemoteNATExternalCIDR: "None",
+				LocalNATPodCIDR: "None", LocalNATExternalCIDR: "None",
+			},
+			Status: netv1alpha1.TunnelEndpointStatus{Connection: netv1alpha1.Connection{Status: status}},
+		}
+	}
+
+	get := func(path string, target interface{}) {
+		resp, err := http.Get(server.URL + path)
+		ExpectWithOffset(1, err).ToNot(HaveOccurred())
+		defer resp.Body.Close()
+		ExpectWithOffset(1, resp.StatusCode).To(Equal(http.StatusOK))
+		ExpectWithOffset(1, resp.Header.Get("Content-Type")).To(Equal("application/json"))
+		ExpectWithOffset(1, json.NewDecoder(resp.Body).Decode(target)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		cl = fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(
+			tunnelEndpoint("tep-other", otherClusterID, netv1alpha1.Connecting),
+			tunnelEndpoint("tep", clusterID, netv1alpha1.Connected),
+		).Build()
+		ipam = &statefulIPAM{subnets: map[string]netv1alpha1.Subnets{}}
+		tec = &TunnelEndpointCreator{Client: cl, Scheme: scheme.Scheme, IPManager: ipam}
+		server = httptest.NewServer(tec.AdminHandler())
+	})
+
+	AfterEach(func() { server.Close() })
+
+	Describe("the reservations endpoint", func() {
+		var storage netv1alpha1.IpamStorage
+
+		BeforeEach(func() { storage = netv1alpha1.IpamStorage{} })
+
+		BeforeEach(func() {
+			_, _, err := ipam.GetSubnetsPerCluster("10.50.0.0/16", "10.51.0.0/16", clusterID)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("should expose the subnets reserved for the remote clusters", func() {
+			get(ReservationsDebugPath, &storage)
+			Expect(storage.Spec.ClusterSubnets).To(HaveLen(1))
+			Expect(storage.Spec.ClusterSubnets).To(HaveKeyWithValue(clusterID, netv1alpha1.Subnets{
+				RemotePodCIDR: "10.50.0.0/16", RemoteExternalCIDR: "10.51.0.0/16", LocalNATPodCIDR: "None", LocalNATExternalCIDR: "None"}))
+		})
+
+		It("should reflect the changes of the reservations", func() {
+			Expect(ipam.RemoveClusterConfig(clusterID)).To(Succeed())
+			get(ReservationsDebugPath, &storage)
+			Expect(storage.Spec.ClusterSubnets).To(BeEmpty())
+		})
+
+		When("the IPManager is not set", func() {
+			BeforeEach(func() { tec.IPManager = nil })
+
+			It("should fail with an internal error", func() {
+				resp, err := http.Get(server.URL + ReservationsDebugPath)
+				Expect(err).ToNot(HaveOccurred())
+				defer resp.Body.Close()
+				Expect(resp.StatusCode).To(Equal(http.StatusInternalServerError))
+			})
+		})
+	})
+
+	Describe("the tunnels endpoint", func() {
+		var tunnels []TunnelInfo
+
+		It("should expose the TunnelEndpoints sorted by cluster ID", func() {
+			get(TunnelsDebugPath, &tunnels)
+			Expect(tunnels).To(HaveLen(2))
+			Expect(tunnels[0].ClusterID).To(Equal(otherClusterID))
+			Expect(tunnels[0].Phase).To(Equal(netv1alpha1.Connecting))
+			Expect(tunnels[1]).To(Equal(TunnelInfo{
+				Name: "tep", Namespace: namespace, ClusterID: clusterID, ClusterName: clusterID + "-name",
+				RemotePodCIDR: "10.50.0.0/16", RemoteNATPodCIDR: "10.60.0.0/16", RemoteExternalCIDR: "10.51.0.0/16",
+				RemoteNATExternalCIDR: "None", LocalNATPodCIDR: "None", LocalNATExternalCIDR: "None",
+				Phase: netv1alpha1.Connected,
+			}))
+		})
+
+		It("should reflect the changes of the tunnel phases", func() {
+			var tep netv1alpha1.TunnelEndpoint
+			Expect(cl.Get(context.Background(), client.ObjectKey{Namespace: namespace, Name: "tep-other"}, &tep)).To(Succeed())
+			tep.Status.Connection.Status = netv1alpha1.ConnectionError
+			Expect(cl.Update(context.Background(), &tep)).To(Succeed())
+
+			get(TunnelsDebugPath, &tunnels)
+			Expect(tunnels).To(HaveLen(2))
+			Expect(tunnels[0].Phase).To(Equal(netv1alpha1.ConnectionError))
+		})
+	})
+
+	It("should reject the requests modifying the state", func() {
+		resp, err := http.Post(server.URL+TunnelsDebugPath, "application/json", nil)
+		Expect(err).ToNot(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusMethodNotAllowed))
+	})
+})
@@ -0,0 +1,49 @@
+// Copyright 2019-2023 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tunnelendpointcreator
+
+import (
+	"context"
+
+	"k8s.io/klog/v2"
+)
+
+// CheckIPAMConsistency validates the reserved subnets state of the IPAM against the existing NetworkConfigs,
+// logging the detected inconsistencies. It returns the inconsistencies, or an error if the check could not be
+// performed. Inconsistencies are only reported, since automatically repairing them might disrupt existing peerings.
+func (tec *TunnelEndpointCreator) CheckIPAMConsistency(ctx context.Context) ([]error, error) {
+	ipManager, err := tec.ipManager()
+	if err != nil {
+		return nil, err
+	}
+
+	referred, err := tec.referredClusters(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	live := make([]string, 0, len(referred))
+	for clusterID := range referred {
+		live = append(live, clusterID)
+	}
+
+	unlock := tec.lockIPAM()
+	problems := ipManager.Validate(live)
+	unlock()
+	for _, problem := range problems {
+		klog.Warningf("IPAM inconsistency detected: %v", problem)
+	}
+	return problems, nil
+}
@@ -0,0 +1,75 @@
+// Copyright 2019-2023 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tunnelendpointcreator
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// validatingIPAM is a fakeIPAM recording the live clusters it is validated against.
+type validatingIPAM struct {
+	fakeIPAM
+	live     []string
+	problems []error
+}
+
+func (vi *validatingIPAM) Validate(liveClusters []string) []error {
+	vi.live = liveClusters
+	return vi.problems
+}
+
+var _ = Describe("IPAM consistency check", func() {
+	var (
+		ctx      context.Context
+		ipam     *validatingIPAM
+		tec      *TunnelEndpointCreator
+		problems []error
+		err      error
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		ipam = &validatingIPAM{}
+		cl := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(localNetworkConfig(), remoteNetworkConfig()).Build()
+		tec = &TunnelEndpointCreator{Client: cl, Scheme: scheme.Scheme, IPManager: ipam}
+	})
+
+	JustBeforeEach(func() { problems, err = tec.CheckIPAMConsistency(ctx) })
+
+	When("the IPAM state is consistent", func() {
+		It("should succeed", func() { Expect(err).ToNot(HaveOccurred()) })
+		It("should report no problems", func() { Expect(problems).To(BeEmpty()) })
+		It("should validate against the clusters with live NetworkConfigs", func() { Expect(ipam.live).To(ConsistOf(clusterID)) })
+	})
+
+	When("the IPAM state is inconsistent", func() {
+		BeforeEach(func() { ipam.problems = []error{errors.New("first"), errors.New("second")} })
+
+		It("should succeed", func() { Expect(err).ToNot(HaveOccurred()) })
+		It("should report all the problems", func() { Expect(problems).To(Equal(ipam.problems)) })
+	})
+
+	When("the IPManager is not set", func() {
+		BeforeEach(func() { tec.IPManager = nil })
+
+		It("should fail", func() { Expect(err).To(MatchError(errIPManagerUnset)) })
+	})
+})
@@ -0,0 +1,83 @@
+// Copyright 2019-2023 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tunnelendpointcreator
+
+import (
+	"context"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/klog/v2"
+
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+	liqoconst "github.com/liqotech/liqo/pkg/consts"
+	liqonetutils "github.com/liqotech/liqo/pkg/liqonet/utils"
+)
+
+// OverlapGranularity is the granularity at which the overlaps between the remote PodCIDRs and the other networks are detected.
+type OverlapGranularity string
+
+const (
+	// NetworkOverlapGranularity detects the overlaps considering the whole PodCIDR of the remote clusters.
+	NetworkOverlapGranularity OverlapGranularity = "Network"
+	// HostOverlapGranularity detects the overlaps considering only the sub-ranges of the PodCIDR actually used by
+	// the remote clusters, if advertised, hence remapping the PodCIDR only in case the used sub-ranges collide.
+	HostOverlapGranularity OverlapGranularity = "Host"
+)
+
+// usedPodCIDR returns the network to be checked for overlaps in place of the PodCIDR of the given remote NetworkConfig.
+// In case the host granularity is configured, it is the network covering the sub-ranges actually used by the remote
+// cluster, as advertised in the status of the local NetworkConfig. Otherwise, it is the remote PodCIDR itself.
+func (tec *TunnelEndpointCreator) usedPodCIDR(ctx context.Context, remote *netv1alpha1.NetworkConfig) (string, error) {
+	if tec.OverlapGranularity != HostOverlapGranularity {
+		return remote.Spec.PodCIDR, nil
+	}
+
+	clusterID := remote.Labels[liqoconst.ReplicationOriginLabel]
+	local, err := tec.ReplicationLabels.GetLocalNetworkConfig(ctx, tec.Client, nil, clusterID, remote.GetNamespace())
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			// The used sub-ranges are not yet known, hence the overlaps are detected considering the whole PodCIDR.
+			return remote.Spec.PodCIDR, nil
+		}
+		klog.Errorf("Failed to retrieve local NetworkConfig for cluster %v: %v", clusterID, err)
+		return "", err
+	}
+	return coveringPodCIDR(local, remote), nil
+}
+
+// coveringPodCIDR returns the network covering the sub-ranges of the remote PodCIDR actually used by the remote cluster,
+// as advertised in the status of the local NetworkConfig. The remote PodCIDR is returned if they are missing or invalid.
+func coveringPodCIDR(local, remote *netv1alpha1.NetworkConfig) string {
+	if len(local.Status.UsedPodCIDRs) == 0 {
+		return remote.Spec.PodCIDR
+	}
+
+	covering, err := liqonetutils.CoveringNetwork(remote.Spec.PodCIDR, local.Status.UsedPodCIDRs)
+	if err != nil {
+		klog.Warningf("Ignoring the used PodCIDRs %v advertised for NetworkConfig %q: %v", local.Status.UsedPodCIDRs, klog.KObj(local), err)
+		return remote.Spec.PodCIDR
+	}
+	return covering
+}
+
+// remotePodCIDR returns the remote PodCIDR to be configured in the TunnelEndpoint. In case it has not been remapped,
+// it is restricted to the network covering the sub-ranges actually used by the remote cluster, if advertised,
+// since the rest of the PodCIDR might be assigned to other networks when detecting the overlaps at the host granularity.
+func remotePodCIDR(local, remote *netv1alpha1.NetworkConfig) string {
+	if !liqonetutils.IsNATDisabled(remote.Status.PodCIDRNAT) {
+		return remote.Spec.PodCIDR
+	}
+	return coveringPodCIDR(local, remote)
+}
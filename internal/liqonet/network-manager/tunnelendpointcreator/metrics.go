@@ -0,0 +1,105 @@
+// Copyright 2019-2023 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tunnelendpointcreator
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	liqonetutils "github.com/liqotech/liqo/pkg/liqonet/utils"
+)
+
+var (
+	// reservedSubnetsMetric is the metric that exposes the number of networks reserved to remap the CIDRs of the remote clusters.
+	reservedSubnetsMetric = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "liqo_tunnelendpoint_reserved_subnets",
+		Help: "Number of networks reserved from the network pools to remap the CIDRs of the remote clusters.",
+	})
+
+	// natEnabledMetric is the metric that exposes whether the CIDRs of a given remote cluster are remapped.
+	natEnabledMetric = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "liqo_tunnelendpoint_nat_enabled",
+		Help: "Checks if the PodCIDR or the ExternalCIDR of a given remote cluster are remapped.",
+	}, []string{"cluster_id"})
+
+	// reconcileErrorsMetric is the metric that counts the failed reconciliations of the NetworkConfigs, by cause.
+	reconcileErrorsMetric = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "liqo_tunnelendpoint_reconcile_errors_total",
+		Help: "Number of failed reconciliations of the NetworkConfigs, partitioned by cause (e.g., PoolExhausted).",
+	}, []string{"cause"})
+)
+
+// registerMetrics registers the metrics concerning the NetworkConfigs in the controller-runtime registry, if not already registered.
+func registerMetrics() error {
+	for _, collector := range []prometheus.Collector{reservedSubnetsMetric, natEnabledMetric, reconcileErrorsMetric} {
+		if err := metrics.Registry.Register(collector); err != nil && !errors.As(err, &prometheus.AlreadyRegisteredError{}) {
+			return err
+		}
+	}
+	return nil
+}
+
+// natTracker tracks the number of networks remapped for each remote cluster, to expose the corresponding metrics.
+type natTracker struct {
+	mutex    sync.Mutex
+	remapped map[string]int
+}
+
+// observe updates the metrics given the networks assigned to the given remote cluster, which are remapped unless set to None.
+func (nt *natTracker) observe(clusterID string, networks ...string) {
+	remapped := 0
+	for _, network := range networks {
+		if !liqonetutils.IsNATDisabled(network) {
+			remapped++
+		}
+	}
+
+	nt.mutex.Lock()
+	defer nt.mutex.Unlock()
+
+	if nt.remapped == nil {
+		nt.remapped = make(map[string]int)
+	}
+	nt.remapped[clusterID] = remapped
+
+	enabled := 0.
+	if remapped > 0 {
+		enabled = 1
+	}
+	natEnabledMetric.WithLabelValues(clusterID).Set(enabled)
+	nt.updateTotal()
+}
+
+// forget updates the metrics following the release of the networks assigned to the given remote cluster.
+func (nt *natTracker) forget(clusterID string) {
+	nt.mutex.Lock()
+	defer nt.mutex.Unlock()
+
+	delete(nt.remapped, clusterID)
+	natEnabledMetric.DeleteLabelValues(clusterID)
+	nt.updateTotal()
+}
+
+// updateTotal sets the overall number of remapped networks. It is expected to be called while holding the mutex.
+func (nt *natTracker) updateTotal() {
+	total := 0
+	for _, remapped := range nt.remapped {
+		total += remapped
+	}
+	reservedSubnetsMetric.Set(float64(total))
+}
@@ -0,0 +1,81 @@
+// Copyright 2019-2023 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tunnelendpointcreator
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	promtestutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	liqoconst "github.com/liqotech/liqo/pkg/consts"
+)
+
+var _ = Describe("NAT metrics", func() {
+	var tracker *natTracker
+
+	BeforeEach(func() { tracker = &natTracker{} })
+
+	When("the networks of the remote clusters are observed", func() {
+		BeforeEach(func() {
+			tracker.observe("metrics-cluster-1", "10.200.0.0/16", liqoconst.DefaultCIDRValue)
+			tracker.observe("metrics-cluster-2", liqoconst.DefaultCIDRValue, liqoconst.DefaultCIDRValue)
+			tracker.observe("metrics-cluster-3", "10.201.0.0/16", "10.202.0.0/16")
+		})
+
+		It("should expose the number of remapped networks", func() {
+			Expect(promtestutil.ToFloat64(reservedSubnetsMetric)).To(BeNumerically("==", 3))
+		})
+
+		It("should expose whether the NAT is enabled for each cluster", func() {
+			Expect(promtestutil.ToFloat64(natEnabledMetric.WithLabelValues("metrics-cluster-1"))).To(BeNumerically("==", 1))
+			Expect(promtestutil.ToFloat64(natEnabledMetric.WithLabelValues("metrics-cluster-2"))).To(BeNumerically("==", 0))
+			Expect(promtestutil.ToFloat64(natEnabledMetric.WithLabelValues("metrics-cluster-3"))).To(BeNumerically("==", 1))
+		})
+
+		When("the networks of a cluster are observed again", func() {
+			BeforeEach(func() { tracker.observe("metrics-cluster-1", liqoconst.DefaultCIDRValue, liqoconst.DefaultCIDRValue) })
+
+			It("should update the metrics", func() {
+				Expect(promtestutil.ToFloat64(reservedSubnetsMetric)).To(BeNumerically("==", 2))
+				Expect(promtestutil.ToFloat64(natEnabledMetric.WithLabelValues("metrics-cluster-1"))).To(BeNumerically("==", 0))
+			})
+		})
+
+		When("the networks of a cluster are released", func() {
+			BeforeEach(func() { tracker.forget("metrics-cluster-3") })
+			AfterEach(func() {
+				tracker.forget("metrics-cluster-1")
+				tracker.forget("metrics-cluster-2")
+			})
+
+			It("should update the number of remapped networks", func() {
+				Expect(promtestutil.ToFloat64(reservedSubnetsMetric)).To(BeNumerically("==", 1))
+			})
+
+			It("should no longer expose the metric for the released cluster", func() {
+				Expect(natEnabledMetric.DeleteLabelValues("metrics-cluster-3")).To(BeFalse())
+			})
+		})
+	})
+
+	Describe("the registration of the metrics", func() {
+		It("should succeed, even if already registered", func() {
+			Expect(registerMetrics()).To(Succeed())
+			Expect(registerMetrics()).To(Succeed())
+			Expect(metrics.Registry.Register(reconcileErrorsMetric)).ToNot(Succeed())
+		})
+	})
+})
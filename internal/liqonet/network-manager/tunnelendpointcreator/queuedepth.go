@@ -0,0 +1,97 @@
+// Copyright 2019-2023 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tunnelendpointcreator
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// workqueueDepthMetric is the name of the metric exposed by controller-runtime with the depth of the controllers work queues.
+const workqueueDepthMetric = metrics.WorkQueueSubsystem + "_" + metrics.DepthKey
+
+// QueueDepthMonitor monitors the depth of the work queue of a controller (i.e., the backlog of the pending reconciliations),
+// as exposed by controller-runtime through the corresponding metric. It additionally outputs a warning each time the depth
+// crosses the configured threshold, to give visibility on the need to tune the concurrency.
+type QueueDepthMonitor struct {
+	gatherer  prometheus.Gatherer
+	name      string
+	threshold uint
+
+	mutex    sync.Mutex
+	exceeded bool
+}
+
+// NewQueueDepthMonitor returns a new QueueDepthMonitor for the work queue with the given name (e.g., ControllerName),
+// retrieving the metrics from the given gatherer (e.g., metrics.Registry). A zero threshold disables the warnings.
+func NewQueueDepthMonitor(gatherer prometheus.Gatherer, name string, threshold uint) *QueueDepthMonitor {
+	return &QueueDepthMonitor{gatherer: gatherer, name: name, threshold: threshold}
+}
+
+// Depth returns the current depth of the monitored work queue.
+func (qdm *QueueDepthMonitor) Depth() (float64, error) {
+	families, err := qdm.gatherer.Gather()
+	if err != nil {
+		return 0, fmt.Errorf("failed to gather the metrics: %w", err)
+	}
+
+	for _, family := range families {
+		if family.GetName() != workqueueDepthMetric {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "name" && label.GetValue() == qdm.name {
+					return metric.GetGauge().GetValue(), nil
+				}
+			}
+		}
+	}
+	return 0, fmt.Errorf("depth of work queue %q not found", qdm.name)
+}
+
+// Check retrieves the current depth of the monitored work queue, and warns in case it crossed the threshold.
+func (qdm *QueueDepthMonitor) Check() (float64, error) {
+	depth, err := qdm.Depth()
+	if err != nil {
+		return 0, err
+	}
+
+	qdm.mutex.Lock()
+	defer qdm.mutex.Unlock()
+
+	exceeded := qdm.threshold > 0 && depth > float64(qdm.threshold)
+	switch {
+	case exceeded && !qdm.exceeded:
+		klog.Warningf("Depth of work queue %q (%.0f) exceeded the threshold (%d)", qdm.name, depth, qdm.threshold)
+	case !exceeded && qdm.exceeded:
+		klog.Infof("Depth of work queue %q (%.0f) is back below the threshold (%d)", qdm.name, depth, qdm.threshold)
+	}
+	qdm.exceeded = exceeded
+
+	return depth, nil
+}
+
+// Exceeded returns whether the depth of the monitored work queue exceeded the threshold during the last check.
+func (qdm *QueueDepthMonitor) Exceeded() bool {
+	qdm.mutex.Lock()
+	defer qdm.mutex.Unlock()
+
+	return qdm.exceeded
+}
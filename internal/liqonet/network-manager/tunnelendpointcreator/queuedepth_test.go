@@ -0,0 +1,96 @@
+// Copyright 2019-2023 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tunnelendpointcreator
+
+import (
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var _ = Describe("Queue depth monitoring", func() {
+	const (
+		backlog   = 50
+		threshold = 20
+	)
+
+	var (
+		queue   workqueue.RateLimitingInterface
+		monitor *QueueDepthMonitor
+		queues  int
+	)
+
+	BeforeEach(func() {
+		// Each test uses a different queue name, as the metrics are not reset when a queue is shut down.
+		queues++
+		name := fmt.Sprintf("queue-depth-test-%d", queues)
+
+		// The queue is instrumented through the metrics provider registered by controller-runtime.
+		queue = workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), name)
+		monitor = NewQueueDepthMonitor(metrics.Registry, name, threshold)
+	})
+
+	AfterEach(func() { queue.ShutDown() })
+
+	When("the queue is empty", func() {
+		It("should report a zero depth", func() {
+			Expect(monitor.Check()).To(BeZero())
+			Expect(monitor.Exceeded()).To(BeFalse())
+		})
+	})
+
+	When("many objects are enqueued", func() {
+		BeforeEach(func() {
+			for i := 0; i < backlog; i++ {
+				queue.Add(fmt.Sprintf("object-%d", i))
+			}
+		})
+
+		It("should report the backlog", func() { Expect(monitor.Check()).To(BeNumerically("==", backlog)) })
+		It("should mark the threshold as exceeded", func() {
+			_, err := monitor.Check()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(monitor.Exceeded()).To(BeTrue())
+		})
+
+		When("the objects are processed", func() {
+			BeforeEach(func() {
+				_, err := monitor.Check()
+				Expect(err).ToNot(HaveOccurred())
+				for i := 0; i < backlog-threshold; i++ {
+					item, _ := queue.Get()
+					queue.Done(item)
+				}
+			})
+
+			It("should report the reduced backlog", func() { Expect(monitor.Check()).To(BeNumerically("==", threshold)) })
+			It("should mark the threshold as no longer exceeded", func() {
+				_, err := monitor.Check()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(monitor.Exceeded()).To(BeFalse())
+			})
+		})
+	})
+
+	When("the queue does not exist", func() {
+		It("should return an error", func() {
+			_, err := NewQueueDepthMonitor(metrics.Registry, "not-existing", threshold).Depth()
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,71 @@
+// Copyright 2019-2023 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tunnelendpointcreator
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+)
+
+// ReconcileOnce performs a single reconciliation pass of the NetworkConfig with the given name, outside of the
+// controller loop, to allow integration tests and tooling to deterministically drive the reconciliation.
+// The name is either in the namespace/name form, or a plain name, in which case the NetworkConfig is looked up
+// across all namespaces (returning an error if it is ambiguous). Each pass performs at most one step of the
+// reconciliation (e.g., adding the finalizer), hence multiple passes may be required to reach the steady state.
+func (tec *TunnelEndpointCreator) ReconcileOnce(ctx context.Context, name string) (ctrl.Result, error) {
+	key, err := tec.networkConfigKey(ctx, name)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	return tec.Reconcile(ctx, ctrl.Request{NamespacedName: key})
+}
+
+// networkConfigKey returns the namespaced name of the NetworkConfig identified by the given name.
+func (tec *TunnelEndpointCreator) networkConfigKey(ctx context.Context, name string) (types.NamespacedName, error) {
+	namespace, name, err := cache.SplitMetaNamespaceKey(name)
+	if err != nil {
+		return types.NamespacedName{}, err
+	}
+	if namespace != "" {
+		return types.NamespacedName{Namespace: namespace, Name: name}, nil
+	}
+
+	var netcfgs netv1alpha1.NetworkConfigList
+	if err := tec.List(ctx, &netcfgs); err != nil {
+		return types.NamespacedName{}, fmt.Errorf("failed to list NetworkConfigs: %w", err)
+	}
+
+	var matches []types.NamespacedName
+	for i := range netcfgs.Items {
+		if netcfgs.Items[i].GetName() == name {
+			matches = append(matches, types.NamespacedName{Namespace: netcfgs.Items[i].GetNamespace(), Name: name})
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return types.NamespacedName{}, fmt.Errorf("NetworkConfig %q not found", name)
+	case 1:
+		return matches[0], nil
+	default:
+		return types.NamespacedName{}, fmt.Errorf("NetworkConfig %q is ambiguous, as existing in %d namespaces", name, len(matches))
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright 2019-2023 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tunnelendpointcreator
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+)
+
+var _ = Describe("ReconcileOnce", func() {
+	var (
+		ctx           context.Context
+		cl            client.Client
+		tec           *TunnelEndpointCreator
+		ipam          *fakeIPAM
+		local, remote *netv1alpha1.NetworkConfig
+	)
+
+	listTunnelEndpoints := func() []netv1alpha1.TunnelEndpoint {
+		var teps netv1alpha1.TunnelEndpointList
+		Expect(cl.List(ctx, &teps, client.InNamespace(namespace))).To(Succeed())
+		return teps.Items
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		local, remote = localNetworkConfig(), remoteNetworkConfig()
+		remote.Status = netv1alpha1.NetworkConfigStatus{}
+		ipam = &fakeIPAM{}
+	})
+
+	JustBeforeEach(func() {
+		cl = fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(local, remote).Build()
+		tec = &TunnelEndpointCreator{Client: cl, Scheme: scheme.Scheme, IPManager: ipam}
+	})
+
+	It("should drive the NetworkConfigs through the full create and delete lifecycle", func() {
+		By("adding the finalizers")
+		Expect(tec.ReconcileOnce(ctx, local.GetName())).To(Equal(ctrl.Result{}))
+		Expect(tec.ReconcileOnce(ctx, remote.GetName())).To(Equal(ctrl.Result{}))
+		Expect(cl.Get(ctx, client.ObjectKeyFromObject(local), local)).To(Succeed())
+		Expect(local.GetFinalizers()).ToNot(BeEmpty())
+		Expect(listTunnelEndpoints()).To(BeEmpty())
+
+		By("processing the remote NetworkConfig")
+		Expect(tec.ReconcileOnce(ctx, client.ObjectKeyFromObject(remote).String())).To(Equal(ctrl.Result{}))
+		Expect(cl.Get(ctx, client.ObjectKeyFromObject(remote), remote)).To(Succeed())
+		Expect(remote.Status.Processed).To(BeTrue())
+
+		By("creating the TunnelEndpoint")
+		teps := listTunnelEndpoints()
+		Expect(teps).To(HaveLen(1))
+		Expect(teps[0].Spec.ClusterIdentity.ClusterID).To(Equal(clusterID))
+
+		By("converging to the steady state")
+		Expect(tec.ReconcileOnce(ctx, local.GetName())).To(Equal(ctrl.Result{}))
+		Expect(listTunnelEndpoints()).To(HaveLen(1))
+
+		By("tearing down the configuration once the local NetworkConfig is deleted")
+		Expect(cl.Delete(ctx, local)).To(Succeed())
+		Expect(tec.ReconcileOnce(ctx, local.GetName())).To(Equal(ctrl.Result{}))
+		Expect(ipam.removed).To(ConsistOf(clusterID))
+		Expect(listTunnelEndpoints()).To(BeEmpty())
+		Expect(cl.Get(ctx, client.ObjectKeyFromObject(local), local)).To(MatchError(ContainSubstring("not found")))
+	})
+
+	When("the NetworkConfig does not exist", func() {
+		It("should return an error", func() {
+			_, err := tec.ReconcileOnce(ctx, "missing")
+			Expect(err).To(MatchError(ContainSubstring("not found")))
+		})
+	})
+
+	When("the NetworkConfig name is ambiguous", func() {
+		BeforeEach(func() {
+			remote.SetName(local.GetName())
+			remote.SetNamespace("liqo-tenant-other")
+		})
+
+		It("should return an error", func() {
+			_, err := tec.ReconcileOnce(ctx, local.GetName())
+			Expect(err).To(MatchError(ContainSubstring("ambiguous")))
+		})
+	})
+})
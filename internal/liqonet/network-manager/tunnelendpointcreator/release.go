@@ -0,0 +1,105 @@
+// Copyright 2019-2023 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tunnelendpointcreator
+
+import (
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// pendingRelease tracks the deferred release of the IPAM configuration of a remote cluster.
+type pendingRelease struct {
+	timer *time.Timer
+}
+
+// releaseClusterConfig releases the IPAM configuration (i.e., the reserved subnets) of the given remote cluster, following
+// the deletion of the corresponding NetworkConfig. In case a grace period is configured, the release is deferred, so that
+// the same subnets are reused if a NetworkConfig referring to the same cluster is recreated in the meanwhile.
+// It is expected to be called while holding the lock of the given cluster.
+func (tec *TunnelEndpointCreator) releaseClusterConfig(clusterID string) error {
+	if tec.ReleaseGracePeriod <= 0 {
+		return tec.removeClusterConfig(clusterID)
+	}
+
+	tec.releasesMutex.Lock()
+	defer tec.releasesMutex.Unlock()
+
+	if tec.pendingReleases == nil {
+		tec.pendingReleases = make(map[string]*pendingRelease)
+	}
+	if previous, found := tec.pendingReleases[clusterID]; found {
+		previous.timer.Stop()
+	}
+
+	release := &pendingRelease{}
+	release.timer = time.AfterFunc(tec.ReleaseGracePeriod, func() { tec.completePendingRelease(clusterID, release) })
+	tec.pendingReleases[clusterID] = release
+	klog.Infof("Release of the subnets assigned to cluster %s deferred by %v", clusterID, tec.ReleaseGracePeriod)
+	return nil
+}
+
+// cancelPendingRelease cancels the deferred release of the IPAM configuration of the given remote cluster, if any,
+// as a NetworkConfig referring to it reappeared. It returns whether a pending release has been cancelled.
+func (tec *TunnelEndpointCreator) cancelPendingRelease(clusterID string) bool {
+	tec.releasesMutex.Lock()
+	defer tec.releasesMutex.Unlock()
+
+	release, found := tec.pendingReleases[clusterID]
+	if !found {
+		return false
+	}
+
+	release.timer.Stop()
+	delete(tec.pendingReleases, clusterID)
+	klog.Infof("Release of the subnets assigned to cluster %s cancelled, as a NetworkConfig referring to it reappeared", clusterID)
+	return true
+}
+
+// completePendingRelease performs the deferred release of the IPAM configuration of the given remote cluster,
+// unless it has been cancelled (or superseded) in the meanwhile. In case of failure, the release is deferred again.
+func (tec *TunnelEndpointCreator) completePendingRelease(clusterID string, release *pendingRelease) {
+	defer tec.lockCluster(clusterID)()
+
+	tec.releasesMutex.Lock()
+	if tec.pendingReleases[clusterID] != release {
+		tec.releasesMutex.Unlock()
+		return
+	}
+	delete(tec.pendingReleases, clusterID)
+	tec.releasesMutex.Unlock()
+
+	if err := tec.removeClusterConfig(clusterID); err != nil {
+		klog.Errorf("cannot delete local subnets assigned to cluster %s: %s", clusterID, err)
+		_ = tec.releaseClusterConfig(clusterID)
+		return
+	}
+	klog.Infof("Subnets assigned to cluster %s released, after the expiration of the grace period", clusterID)
+}
+
+// removeClusterConfig removes the IPAM configuration of the given remote cluster.
+func (tec *TunnelEndpointCreator) removeClusterConfig(clusterID string) error {
+	ipManager, err := tec.ipManager()
+	if err != nil {
+		return err
+	}
+
+	defer tec.lockIPAM()()
+	if err := ipManager.RemoveClusterConfig(clusterID); err != nil {
+		return err
+	}
+	tec.nat.forget(clusterID)
+	return nil
+}
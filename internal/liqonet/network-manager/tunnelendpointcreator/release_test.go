@@ -0,0 +1,143 @@
+// Copyright 2019-2023 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tunnelendpointcreator
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+)
+
+// reservingIPAM is a liqonetIpam.Ipam implementation which remaps the PodCIDR of each cluster to a dedicated
+// subnet, retained until the corresponding configuration is removed.
+type reservingIPAM struct {
+	fakeIPAM
+	mutex     sync.Mutex
+	allocated int
+	subnets   map[string]string
+	released  []string
+}
+
+func (ri *reservingIPAM) GetSubnetsPerCluster(_, externalCIDR, clusterID string) (mappedPodCIDR, mappedExternalCIDR string, err error) {
+	ri.mutex.Lock()
+	defer ri.mutex.Unlock()
+
+	if _, found := ri.subnets[clusterID]; !found {
+		ri.subnets[clusterID] = fmt.Sprintf("10.%d.0.0/16", 100+ri.allocated)
+		ri.allocated++
+	}
+	return ri.subnets[clusterID], externalCIDR, nil
+}
+
+func (ri *reservingIPAM) RemoveClusterConfig(clusterID string) error {
+	ri.mutex.Lock()
+	defer ri.mutex.Unlock()
+
+	delete(ri.subnets, clusterID)
+	ri.released = append(ri.released, clusterID)
+	return nil
+}
+
+func (ri *reservingIPAM) Released() []string {
+	ri.mutex.Lock()
+	defer ri.mutex.Unlock()
+	return append([]string(nil), ri.released...)
+}
+
+var _ = Describe("Release grace period", func() {
+	const grace = 200 * time.Millisecond
+
+	var (
+		ctx           context.Context
+		cl            client.Client
+		tec           *TunnelEndpointCreator
+		ipam          *reservingIPAM
+		local, remote *netv1alpha1.NetworkConfig
+	)
+
+	remotePodCIDRNAT := func() string {
+		Expect(tec.ReconcileOnce(ctx, remote.GetName())).Error().ToNot(HaveOccurred())
+		var current netv1alpha1.NetworkConfig
+		Expect(cl.Get(ctx, client.ObjectKeyFromObject(remote), &current)).To(Succeed())
+		return current.Status.PodCIDRNAT
+	}
+
+	deleteLocal := func() {
+		Expect(cl.Delete(ctx, local)).To(Succeed())
+		Expect(tec.ReconcileOnce(ctx, local.GetName())).Error().ToNot(HaveOccurred())
+		Expect(cl.Get(ctx, client.ObjectKeyFromObject(local), &netv1alpha1.NetworkConfig{})).To(MatchError(ContainSubstring("not found")))
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		local, remote = localNetworkConfig(), remoteNetworkConfig()
+		remote.Status = netv1alpha1.NetworkConfigStatus{}
+		ipam = &reservingIPAM{subnets: make(map[string]string)}
+
+		cl = fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(local, remote).Build()
+		tec = &TunnelEndpointCreator{Client: cl, Scheme: scheme.Scheme, IPManager: ipam, ReleaseGracePeriod: grace}
+
+		// Add the finalizers, and process the remote NetworkConfig.
+		Expect(tec.ReconcileOnce(ctx, local.GetName())).Error().ToNot(HaveOccurred())
+		Expect(tec.ReconcileOnce(ctx, remote.GetName())).Error().ToNot(HaveOccurred())
+		Expect(remotePodCIDRNAT()).To(Equal("10.100.0.0/16"))
+	})
+
+	When("the NetworkConfig is recreated within the grace period", func() {
+		BeforeEach(func() {
+			deleteLocal()
+			Expect(cl.Create(ctx, localNetworkConfig())).To(Succeed())
+			Expect(tec.ReconcileOnce(ctx, local.GetName())).Error().ToNot(HaveOccurred())
+		})
+
+		It("should not release the subnets", func() {
+			Consistently(ipam.Released, 2*grace, grace/10).Should(BeEmpty())
+		})
+		It("should reuse the same subnet", func() {
+			time.Sleep(2 * grace)
+			Expect(remotePodCIDRNAT()).To(Equal("10.100.0.0/16"))
+		})
+	})
+
+	When("the NetworkConfig is not recreated within the grace period", func() {
+		BeforeEach(deleteLocal)
+
+		It("should not release the subnets before the grace period expires", func() {
+			Expect(ipam.Released()).To(BeEmpty())
+		})
+		It("should release the subnets once the grace period expires", func() {
+			Eventually(ipam.Released, 5*grace, grace/10).Should(ConsistOf(clusterID))
+			Expect(remotePodCIDRNAT()).To(Equal("10.101.0.0/16"))
+		})
+	})
+
+	When("the grace period is not configured", func() {
+		BeforeEach(func() {
+			tec.ReleaseGracePeriod = 0
+			deleteLocal()
+		})
+
+		It("should release the subnets immediately", func() { Expect(ipam.Released()).To(ConsistOf(clusterID)) })
+	})
+})
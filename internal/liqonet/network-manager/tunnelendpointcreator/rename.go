@@ -0,0 +1,135 @@
+// Copyright 2019-2023 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tunnelendpointcreator
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+	liqoconst "github.com/liqotech/liqo/pkg/consts"
+	foreignclusterutils "github.com/liqotech/liqo/pkg/utils/foreignCluster"
+)
+
+// RenameCluster migrates the networking configuration of a remote cluster re-registered under a different cluster ID,
+// to prevent the existing resources keyed on the old ID from becoming orphans. The IPAM configuration is moved first
+// (atomically, preserving the reservations), then the remote NetworkConfigs are relabeled, and the local ones are
+// recreated with the name and labels derived from the new ID (as the name depends on it), copying their status.
+// The TunnelEndpoints are eventually recreated as a consequence, since their cluster ID is immutable.
+// The operation is idempotent, hence it can be safely retried in case of errors.
+func (tec *TunnelEndpointCreator) RenameCluster(ctx context.Context, oldID, newID string) error {
+	if oldID == "" || newID == "" {
+		return fmt.Errorf("the cluster IDs cannot be empty")
+	}
+	if oldID == newID {
+		return nil
+	}
+
+	// Lock both clusters, in a consistent order to prevent deadlocks.
+	first, second := oldID, newID
+	if second < first {
+		first, second = second, first
+	}
+	defer tec.lockCluster(first)()
+	defer tec.lockCluster(second)()
+
+	ipManager, err := tec.ipManager()
+	if err != nil {
+		return err
+	}
+	unlock := tec.lockIPAM()
+	err = ipManager.RenameCluster(oldID, newID)
+	unlock()
+	if err != nil {
+		return fmt.Errorf("failed to rename the IPAM configuration of cluster %q: %w", oldID, err)
+	}
+
+	var remotes netv1alpha1.NetworkConfigList
+	if err := tec.List(ctx, &remotes, client.MatchingLabels{liqoconst.ReplicationOriginLabel: oldID}); err != nil {
+		return fmt.Errorf("failed to list the remote NetworkConfigs of cluster %q: %w", oldID, err)
+	}
+	for i := range remotes.Items {
+		remotes.Items[i].Labels[liqoconst.ReplicationOriginLabel] = newID
+		if err := tec.Update(ctx, &remotes.Items[i]); err != nil {
+			return fmt.Errorf("failed to relabel the remote NetworkConfig %q: %w", klog.KObj(&remotes.Items[i]), err)
+		}
+		klog.Infof("Remote NetworkConfig %q moved from cluster %q to %q", klog.KObj(&remotes.Items[i]), oldID, newID)
+	}
+
+	var locals netv1alpha1.NetworkConfigList
+	if err := tec.List(ctx, &locals, client.MatchingLabels{tec.ReplicationLabels.DestinationKey(): oldID}); err != nil {
+		return fmt.Errorf("failed to list the local NetworkConfigs of cluster %q: %w", oldID, err)
+	}
+	for i := range locals.Items {
+		if !tec.ReplicationLabels.IsLocal(&locals.Items[i]) {
+			continue
+		}
+		if err := tec.renameLocalNetworkConfig(ctx, &locals.Items[i], newID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// renameLocalNetworkConfig replaces the given local NetworkConfig with an equivalent one referring to the new cluster ID.
+func (tec *TunnelEndpointCreator) renameLocalNetworkConfig(ctx context.Context, netcfg *netv1alpha1.NetworkConfig, newID string) error {
+	identity := netcfg.Spec.RemoteCluster
+	identity.ClusterID = newID
+
+	labels := make(map[string]string, len(netcfg.GetLabels()))
+	for key, value := range netcfg.GetLabels() {
+		labels[key] = value
+	}
+	labels[tec.ReplicationLabels.DestinationKey()] = newID
+
+	renamed := &netv1alpha1.NetworkConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            foreignclusterutils.UniqueName(&identity),
+			Namespace:       netcfg.GetNamespace(),
+			Labels:          labels,
+			Annotations:     netcfg.GetAnnotations(),
+			OwnerReferences: netcfg.GetOwnerReferences(),
+		},
+		Spec: *netcfg.Spec.DeepCopy(),
+	}
+	renamed.Spec.RemoteCluster = identity
+
+	if err := tec.Create(ctx, renamed); err != nil && !apierrors.IsAlreadyExists(err) {
+		return fmt.Errorf("failed to create the local NetworkConfig %q: %w", klog.KObj(renamed), err)
+	}
+
+	// The status is copied, as already set by the remote cluster, and not expected to change due to the renaming.
+	if err := tec.Get(ctx, client.ObjectKeyFromObject(renamed), renamed); err != nil {
+		return fmt.Errorf("failed to retrieve the local NetworkConfig %q: %w", klog.KObj(renamed), err)
+	}
+	renamed.Status = *netcfg.Status.DeepCopy()
+	if err := tec.Status().Update(ctx, renamed); err != nil {
+		return fmt.Errorf("failed to update the status of the local NetworkConfig %q: %w", klog.KObj(renamed), err)
+	}
+
+	if err := tec.Delete(ctx, netcfg); client.IgnoreNotFound(err) != nil {
+		return fmt.Errorf("failed to delete the local NetworkConfig %q: %w", klog.KObj(netcfg), err)
+	}
+
+	klog.Infof("Local NetworkConfig %q replaced by %q, after moving from cluster %q to %q",
+		klog.KObj(netcfg), klog.KObj(renamed), netcfg.Spec.RemoteCluster.ClusterID, newID)
+	return nil
+}
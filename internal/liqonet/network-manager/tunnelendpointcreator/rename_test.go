@@ -0,0 +1,91 @@
+// Copyright 2019-2023 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tunnelendpointcreator
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	discoveryv1alpha1 "github.com/liqotech/liqo/apis/discovery/v1alpha1"
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+	"github.com/liqotech/liqo/pkg/consts"
+	foreignclusterutils "github.com/liqotech/liqo/pkg/utils/foreignCluster"
+)
+
+var _ = Describe("Cluster renaming", func() {
+	const newClusterID = "renamed-cluster-id"
+
+	var (
+		ctx  context.Context
+		cl   client.Client
+		tec  *TunnelEndpointCreator
+		ipam *fakeIPAM
+		err  error
+	)
+
+	listNetworkConfigs := func(labels client.MatchingLabels) []netv1alpha1.NetworkConfig {
+		var netcfgs netv1alpha1.NetworkConfigList
+		Expect(cl.List(ctx, &netcfgs, labels)).To(Succeed())
+		return netcfgs.Items
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		ipam = &fakeIPAM{}
+		cl = fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(localNetworkConfig(), remoteNetworkConfig()).Build()
+		tec = &TunnelEndpointCreator{Client: cl, Scheme: scheme.Scheme, IPManager: ipam}
+	})
+
+	JustBeforeEach(func() { err = tec.RenameCluster(ctx, clusterID, newClusterID) })
+
+	It("should succeed", func() { Expect(err).ToNot(HaveOccurred()) })
+	It("should move the IPAM configuration, preserving the reservations", func() {
+		Expect(ipam.renamed).To(ConsistOf(clusterID + "->" + newClusterID))
+	})
+
+	It("should relabel the remote NetworkConfig, preserving its status", func() {
+		Expect(listNetworkConfigs(client.MatchingLabels{consts.ReplicationOriginLabel: clusterID})).To(BeEmpty())
+		remotes := listNetworkConfigs(client.MatchingLabels{consts.ReplicationOriginLabel: newClusterID})
+		Expect(remotes).To(HaveLen(1))
+		Expect(remotes[0].GetName()).To(Equal(remoteNetworkConfig().GetName()))
+		Expect(remotes[0].Status).To(Equal(remoteNetworkConfig().Status))
+	})
+
+	It("should replace the local NetworkConfig with one referring to the new cluster ID", func() {
+		Expect(listNetworkConfigs(client.MatchingLabels{consts.ReplicationDestinationLabel: clusterID})).To(BeEmpty())
+		locals := listNetworkConfigs(client.MatchingLabels{consts.ReplicationDestinationLabel: newClusterID})
+		Expect(locals).To(HaveLen(1))
+
+		identity := discoveryv1alpha1.ClusterIdentity{ClusterID: newClusterID, ClusterName: clusterName}
+		Expect(locals[0].GetName()).To(Equal(foreignclusterutils.UniqueName(&identity)))
+		Expect(locals[0].GetLabels()).To(HaveKeyWithValue(consts.ReplicationRequestedLabel, "true"))
+		Expect(locals[0].Spec.RemoteCluster).To(Equal(identity))
+		Expect(locals[0].Spec.PodCIDR).To(Equal(localNetworkConfig().Spec.PodCIDR))
+		Expect(locals[0].Status).To(Equal(localNetworkConfig().Status))
+	})
+
+	When("the cluster is renamed again", func() {
+		It("should be idempotent", func() {
+			Expect(tec.RenameCluster(ctx, clusterID, newClusterID)).To(Succeed())
+			Expect(listNetworkConfigs(client.MatchingLabels{consts.ReplicationDestinationLabel: newClusterID})).To(HaveLen(1))
+			Expect(listNetworkConfigs(client.MatchingLabels{consts.ReplicationOriginLabel: newClusterID})).To(HaveLen(1))
+		})
+	})
+})
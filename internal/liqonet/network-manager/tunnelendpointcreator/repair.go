@@ -0,0 +1,128 @@
+// Copyright 2019-2023 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tunnelendpointcreator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+	"github.com/liqotech/liqo/internal/liqonet/network-manager/netcfgcreator"
+)
+
+// errNetworkConfigsNotProcessed is returned when the NetworkConfigs have not yet been processed, hence the
+// TunnelEndpoint cannot be derived from them.
+var errNetworkConfigsNotProcessed = errors.New("the NetworkConfigs have not yet been processed")
+
+// RepairTunnelEndpoint re-derives the TunnelEndpoint associated with the given remote cluster from the
+// authoritative local and remote NetworkConfigs, and corrects the fields which drifted (e.g., due to manual edits),
+// as the reconciliation of the NetworkConfigs does. It returns whether the TunnelEndpoint has been repaired.
+func (tec *TunnelEndpointCreator) RepairTunnelEndpoint(ctx context.Context, clusterID, namespace string) (bool, error) {
+	remote, err := netcfgcreator.GetRemoteNetworkConfig(ctx, tec.Client, clusterID, namespace, tec.networkConfigLookupOptions(clusterID)...)
+	if err != nil {
+		return false, fmt.Errorf("failed to retrieve remote NetworkConfig for cluster %v: %w", clusterID, err)
+	}
+
+	local, err := tec.ReplicationLabels.GetLocalNetworkConfig(ctx, tec.Client, nil, clusterID, namespace, tec.networkConfigLookupOptions(clusterID)...)
+	if err != nil {
+		return false, fmt.Errorf("failed to retrieve local NetworkConfig for cluster %v: %w", clusterID, err)
+	}
+
+	if !local.Status.Processed || !remote.Status.Processed {
+		return false, fmt.Errorf("%w for cluster %v", errNetworkConfigsNotProcessed, clusterID)
+	}
+
+	param := tec.forgeTunnelEndpointParam(local, remote)
+	drifted, err := tec.updateSpecTunnelEndpoint(ctx, param, tec.tunnelEndpointNamespace(namespace))
+	if err != nil {
+		return false, fmt.Errorf("failed to repair TunnelEndpoint for cluster %v: %w", clusterID, err)
+	}
+	if len(drifted) == 0 {
+		klog.V(4).Infof("TunnelEndpoint for cluster %v is consistent with the NetworkConfigs", clusterID)
+		return false, nil
+	}
+
+	klog.Infof("TunnelEndpoint for cluster %v repaired, the following fields drifted from the NetworkConfigs: %v", clusterID, drifted)
+	return true, nil
+}
+
+// VerifyTunnelEndpoints checks each TunnelEndpoint against the authoritative NetworkConfigs, repairing those whose spec
+// drifted (see RepairTunnelEndpoint). It is meant to be invoked periodically, since the TunnelEndpoints are otherwise
+// updated only upon NetworkConfig events, and drifts might go unnoticed. TunnelEndpoints without a local NetworkConfig are
+// left to SweepOrphanTunnelEndpoints, while those whose NetworkConfigs have not yet been processed are skipped.
+// Failures concerning a single TunnelEndpoint are logged, without preventing the verification of the others.
+// It returns the number of repaired TunnelEndpoints.
+func (tec *TunnelEndpointCreator) VerifyTunnelEndpoints(ctx context.Context) (int, error) {
+	var teps netv1alpha1.TunnelEndpointList
+	var opts []client.ListOption
+	if tec.TunnelEndpointNamespace != "" {
+		opts = append(opts, client.InNamespace(tec.TunnelEndpointNamespace))
+	}
+	if err := tec.List(ctx, &teps, opts...); err != nil {
+		return 0, fmt.Errorf("failed to list TunnelEndpoints: %w", err)
+	}
+
+	var netcfgs netv1alpha1.NetworkConfigList
+	if err := tec.List(ctx, &netcfgs, client.HasLabels{tec.ReplicationLabels.DestinationKey()}); err != nil {
+		return 0, fmt.Errorf("failed to list NetworkConfigs: %w", err)
+	}
+	namespaces := make(map[string]string, len(netcfgs.Items))
+	for i := range netcfgs.Items {
+		namespaces[netcfgs.Items[i].GetLabels()[tec.ReplicationLabels.DestinationKey()]] = netcfgs.Items[i].GetNamespace()
+	}
+
+	repaired := 0
+	for i := range teps.Items {
+		tep := &teps.Items[i]
+		clusterID := tep.Spec.ClusterIdentity.ClusterID
+		namespace, found := namespaces[clusterID]
+		if !found || !tep.GetDeletionTimestamp().IsZero() {
+			continue
+		}
+
+		// Prevent the verification from racing with the reconciliation of the NetworkConfigs of the same cluster.
+		unlock := tec.lockCluster(clusterID)
+		ok, err := tec.RepairTunnelEndpoint(ctx, clusterID, namespace)
+		unlock()
+
+		switch {
+		case errors.Is(err, errNetworkConfigsNotProcessed):
+			klog.V(4).Infof("Skipping verification of TunnelEndpoint %q: %v", klog.KObj(tep), err)
+		case err != nil:
+			klog.Errorf("Failed to verify TunnelEndpoint %q: %v", klog.KObj(tep), err)
+		case ok:
+			repaired++
+		}
+	}
+
+	return repaired, nil
+}
+
+// driftedFields returns the names of the fields differing between the current and the desired TunnelEndpoint specs.
+func driftedFields(current, desired *netv1alpha1.TunnelEndpointSpec) []string {
+	var drifted []string
+	cur, des := reflect.ValueOf(current).Elem(), reflect.ValueOf(desired).Elem()
+	for i := 0; i < cur.NumField(); i++ {
+		if !reflect.DeepEqual(cur.Field(i).Interface(), des.Field(i).Interface()) {
+			drifted = append(drifted, cur.Type().Field(i).Name)
+		}
+	}
+	return drifted
+}
@@ -0,0 +1,311 @@
+// Copyright 2019-2023 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tunnelendpointcreator
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	discoveryv1alpha1 "github.com/liqotech/liqo/apis/discovery/v1alpha1"
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+	"github.com/liqotech/liqo/pkg/consts"
+	"github.com/liqotech/liqo/pkg/utils/getters"
+)
+
+const (
+	clusterID   = "remote-cluster-id"
+	clusterName = "remote-cluster-name"
+	namespace   = "liqo-tenant-remote"
+)
+
+var (
+	remoteIdentity = discoveryv1alpha1.ClusterIdentity{ClusterID: clusterID, ClusterName: clusterName}
+
+	// localNetworkConfig returns a local NetworkConfig, already processed by the remote cluster.
+	localNetworkConfig = func() *netv1alpha1.NetworkConfig {
+		return &netv1alpha1.NetworkConfig{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "local", Namespace: namespace,
+				Labels: map[string]string{
+					consts.ReplicationRequestedLabel:   "true",
+					consts.ReplicationDestinationLabel: clusterID,
+				},
+			},
+			Spec: netv1alpha1.NetworkConfigSpec{
+				RemoteCluster: remoteIdentity,
+				PodCIDR:       "10.0.0.0/16",
+				ExternalCIDR:  "10.1.0.0/16",
+				EndpointIP:    "1.1.1.1",
+				BackendType:   consts.DriverName,
+			},
+			Status: netv1alpha1.NetworkConfigStatus{Processed: true, PodCIDRNAT: "10.50.0.0/16", ExternalCIDRNAT: consts.DefaultCIDRValue},
+		}
+	}
+
+	// remoteNetworkConfig returns a remote NetworkConfig, already processed by the local cluster.
+	remoteNetworkConfig = func() *netv1alpha1.NetworkConfig {
+		return &netv1alpha1.NetworkConfig{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "remote", Namespace: namespace,
+				Labels: map[string]string{consts.ReplicationOriginLabel: clusterID},
+			},
+			Spec: netv1alpha1.NetworkConfigSpec{
+				PodCIDR:       "10.0.0.0/16",
+				ExternalCIDR:  "10.2.0.0/16",
+				EndpointIP:    "2.2.2.2",
+				BackendType:   consts.DriverName,
+				BackendConfig: map[string]string{consts.ListeningPort: "5871"},
+			},
+			Status: netv1alpha1.NetworkConfigStatus{Processed: true, PodCIDRNAT: "10.60.0.0/16", ExternalCIDRNAT: consts.DefaultCIDRValue},
+		}
+	}
+
+	// consistentTunnelEndpoint returns a TunnelEndpoint consistent with the NetworkConfigs.
+	consistentTunnelEndpoint = func() *netv1alpha1.TunnelEndpoint {
+		tep := &netv1alpha1.TunnelEndpoint{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "tep", Namespace: namespace,
+				Labels: map[string]string{consts.ClusterIDLabelName: clusterID},
+			},
+		}
+		(&TunnelEndpointCreator{}).fillTunnelEndpointSpec(tep, forgeNetworkParam(localNetworkConfig(), remoteNetworkConfig()))
+		return tep
+	}
+)
+
+var _ = Describe("TunnelEndpoint repair", func() {
+	var (
+		ctx           context.Context
+		clientBuilder *fake.ClientBuilder
+		tec           *TunnelEndpointCreator
+
+		repaired bool
+		err      error
+	)
+
+	getTunnelEndpoint := func() *netv1alpha1.TunnelEndpoint {
+		tep, err := getters.GetTunnelEndpoint(ctx, tec.Client, &remoteIdentity, namespace)
+		Expect(err).ToNot(HaveOccurred())
+		return tep
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		clientBuilder = fake.NewClientBuilder().WithScheme(scheme.Scheme).
+			WithObjects(localNetworkConfig(), remoteNetworkConfig())
+	})
+
+	JustBeforeEach(func() {
+		tec = &TunnelEndpointCreator{Client: clientBuilder.Build(), Scheme: scheme.Scheme}
+		repaired, err = tec.RepairTunnelEndpoint(ctx, clusterID, namespace)
+	})
+
+	When("the TunnelEndpoint is consistent with the NetworkConfigs", func() {
+		BeforeEach(func() { clientBuilder.WithObjects(consistentTunnelEndpoint()) })
+
+		It("should succeed", func() { Expect(err).ToNot(HaveOccurred()) })
+		It("should not perform any repair", func() { Expect(repaired).To(BeFalse()) })
+		It("should leave the TunnelEndpoint unchanged", func() {
+			Expect(getTunnelEndpoint().Spec).To(Equal(consistentTunnelEndpoint().Spec))
+		})
+	})
+
+	When("the TunnelEndpoint drifted from the NetworkConfigs", func() {
+		BeforeEach(func() {
+			tep := consistentTunnelEndpoint()
+			tep.Spec.LocalNATPodCIDR = "10.200.0.0/16"
+			tep.Spec.EndpointIP = "3.3.3.3"
+			clientBuilder.WithObjects(tep)
+		})
+
+		It("should succeed", func() { Expect(err).ToNot(HaveOccurred()) })
+		It("should perform the repair", func() { Expect(repaired).To(BeTrue()) })
+		It("should restore the values derived from the NetworkConfigs", func() {
+			tep := getTunnelEndpoint()
+			Expect(tep.Spec.LocalNATPodCIDR).To(Equal("10.50.0.0/16"))
+			Expect(tep.Spec.EndpointIP).To(Equal("2.2.2.2"))
+			Expect(tep.Spec).To(Equal(consistentTunnelEndpoint().Spec))
+		})
+		It("should be a nop if invoked again", func() {
+			Expect(tec.RepairTunnelEndpoint(ctx, clusterID, namespace)).To(BeFalse())
+		})
+	})
+
+	When("the MTU override of the local NetworkConfig has not been propagated", func() {
+		BeforeEach(func() {
+			local := localNetworkConfig()
+			local.SetAnnotations(map[string]string{consts.MTUAnnotationKey: "1300"})
+			clientBuilder = fake.NewClientBuilder().WithScheme(scheme.Scheme).
+				WithObjects(local, remoteNetworkConfig(), consistentTunnelEndpoint())
+		})
+
+		It("should succeed", func() { Expect(err).ToNot(HaveOccurred()) })
+		It("should perform the repair", func() { Expect(repaired).To(BeTrue()) })
+		It("should propagate the MTU override", func() {
+			Expect(getTunnelEndpoint().GetAnnotations()).To(HaveKeyWithValue(consts.MTUAnnotationKey, "1300"))
+		})
+		It("should be a nop if invoked again", func() {
+			Expect(tec.RepairTunnelEndpoint(ctx, clusterID, namespace)).To(BeFalse())
+		})
+	})
+
+	When("the MTU override of the local NetworkConfig is invalid", func() {
+		BeforeEach(func() {
+			local := localNetworkConfig()
+			local.SetAnnotations(map[string]string{consts.MTUAnnotationKey: "invalid"})
+			tep := consistentTunnelEndpoint()
+			tep.SetAnnotations(map[string]string{consts.MTUAnnotationKey: "invalid"})
+			clientBuilder = fake.NewClientBuilder().WithScheme(scheme.Scheme).
+				WithObjects(local, remoteNetworkConfig(), tep)
+		})
+
+		It("should succeed", func() { Expect(err).ToNot(HaveOccurred()) })
+		It("should perform the repair", func() { Expect(repaired).To(BeTrue()) })
+		It("should ignore the MTU override", func() {
+			Expect(getTunnelEndpoint().GetAnnotations()).ToNot(HaveKey(consts.MTUAnnotationKey))
+		})
+	})
+
+	When("an immutable field of the TunnelEndpoint drifted from the NetworkConfigs", func() {
+		BeforeEach(func() {
+			tep := consistentTunnelEndpoint()
+			tep.Spec.ClusterIdentity.ClusterID = "another-cluster-id"
+			tep.Spec.EndpointIP = "3.3.3.3"
+			clientBuilder.WithObjects(tep)
+		})
+
+		It("should fail", func() { Expect(err).To(MatchError(ContainSubstring("cannot be modified after creation"))) })
+		It("should not perform any repair", func() { Expect(repaired).To(BeFalse()) })
+		It("should leave the TunnelEndpoint unchanged", func() { Expect(getTunnelEndpoint().Spec.EndpointIP).To(Equal("3.3.3.3")) })
+	})
+
+	When("the local NetworkConfig has not yet been processed", func() {
+		BeforeEach(func() {
+			local := localNetworkConfig()
+			local.Status = netv1alpha1.NetworkConfigStatus{}
+			clientBuilder = fake.NewClientBuilder().WithScheme(scheme.Scheme).
+				WithObjects(local, remoteNetworkConfig(), consistentTunnelEndpoint())
+		})
+
+		It("should fail", func() { Expect(err).To(HaveOccurred()) })
+		It("should not perform any repair", func() { Expect(repaired).To(BeFalse()) })
+	})
+
+	When("the TunnelEndpoint does not exist", func() {
+		It("should fail", func() { Expect(err).To(HaveOccurred()) })
+		It("should not create it", func() {
+			var teps netv1alpha1.TunnelEndpointList
+			Expect(tec.List(ctx, &teps, client.InNamespace(namespace))).To(Succeed())
+			Expect(teps.Items).To(BeEmpty())
+		})
+	})
+})
+
+var _ = Describe("TunnelEndpoints verification", func() {
+	var (
+		ctx           context.Context
+		clientBuilder *fake.ClientBuilder
+		tec           *TunnelEndpointCreator
+
+		repaired int
+		err      error
+	)
+
+	getTunnelEndpoint := func() *netv1alpha1.TunnelEndpoint {
+		tep, err := getters.GetTunnelEndpoint(ctx, tec.Client, &remoteIdentity, namespace)
+		Expect(err).ToNot(HaveOccurred())
+		return tep
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		clientBuilder = fake.NewClientBuilder().WithScheme(scheme.Scheme).
+			WithObjects(localNetworkConfig(), remoteNetworkConfig())
+	})
+
+	JustBeforeEach(func() {
+		tec = &TunnelEndpointCreator{Client: clientBuilder.Build(), Scheme: scheme.Scheme}
+		repaired, err = tec.VerifyTunnelEndpoints(ctx)
+	})
+
+	When("the TunnelEndpoints are consistent with the NetworkConfigs", func() {
+		BeforeEach(func() { clientBuilder.WithObjects(consistentTunnelEndpoint()) })
+
+		It("should succeed", func() { Expect(err).ToNot(HaveOccurred()) })
+		It("should not repair any TunnelEndpoint", func() { Expect(repaired).To(BeZero()) })
+	})
+
+	When("the spec of a TunnelEndpoint drifted from the NetworkConfigs", func() {
+		BeforeEach(func() {
+			tep := consistentTunnelEndpoint()
+			tep.Spec.RemoteNATPodCIDR = "10.200.0.0/16"
+			tep.Spec.BackendConfig = map[string]string{consts.ListeningPort: "1234"}
+			clientBuilder.WithObjects(tep)
+		})
+
+		It("should succeed", func() { Expect(err).ToNot(HaveOccurred()) })
+		It("should repair the TunnelEndpoint", func() { Expect(repaired).To(Equal(1)) })
+		It("should correct the drift", func() { Expect(getTunnelEndpoint().Spec).To(Equal(consistentTunnelEndpoint().Spec)) })
+		It("should be a nop if invoked again", func() { Expect(tec.VerifyTunnelEndpoints(ctx)).To(BeZero()) })
+	})
+
+	When("the NetworkConfigs have not yet been processed", func() {
+		BeforeEach(func() {
+			local := localNetworkConfig()
+			local.Status = netv1alpha1.NetworkConfigStatus{}
+			tep := consistentTunnelEndpoint()
+			tep.Spec.EndpointIP = "3.3.3.3"
+			clientBuilder = fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(local, remoteNetworkConfig(), tep)
+		})
+
+		It("should succeed", func() { Expect(err).ToNot(HaveOccurred()) })
+		It("should skip the TunnelEndpoint", func() {
+			Expect(repaired).To(BeZero())
+			Expect(getTunnelEndpoint().Spec.EndpointIP).To(Equal("3.3.3.3"))
+		})
+	})
+
+	When("the TunnelEndpoint has no local NetworkConfig", func() {
+		BeforeEach(func() {
+			tep := consistentTunnelEndpoint()
+			tep.Spec.EndpointIP = "3.3.3.3"
+			clientBuilder = fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(remoteNetworkConfig(), tep)
+		})
+
+		It("should succeed", func() { Expect(err).ToNot(HaveOccurred()) })
+		It("should leave the TunnelEndpoint to the sweeper", func() {
+			Expect(repaired).To(BeZero())
+			Expect(getTunnelEndpoint().Spec.EndpointIP).To(Equal("3.3.3.3"))
+		})
+	})
+})
+
+var _ = Describe("The driftedFields function", func() {
+	It("should return the names of the drifted fields", func() {
+		current := netv1alpha1.TunnelEndpointSpec{LocalNATPodCIDR: "10.0.0.0/16", EndpointIP: "1.1.1.1", BackendType: "wireguard"}
+		desired := netv1alpha1.TunnelEndpointSpec{LocalNATPodCIDR: "10.1.0.0/16", EndpointIP: "1.1.1.1", BackendType: "wireguard"}
+		Expect(driftedFields(&current, &desired)).To(ConsistOf("LocalNATPodCIDR"))
+	})
+	It("should return no fields if the specs are equal", func() {
+		spec := netv1alpha1.TunnelEndpointSpec{LocalNATPodCIDR: "10.0.0.0/16"}
+		Expect(driftedFields(&spec, spec.DeepCopy())).To(BeEmpty())
+	})
+})
@@ -0,0 +1,85 @@
+// Copyright 2019-2023 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tunnelendpointcreator
+
+import (
+	"errors"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/klog/v2"
+
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+	liqonetIpam "github.com/liqotech/liqo/pkg/liqonet/ipam"
+)
+
+// RequeueReason is the reason why the reconciliation of a NetworkConfig is requeued,
+// surfaced through the events recorded on the NetworkConfig to explain each requeue decision.
+type RequeueReason string
+
+const (
+	// RequeueWaitingForRemote means that the local NetworkConfig has not yet been processed by the remote cluster.
+	RequeueWaitingForRemote RequeueReason = "WaitingForRemote"
+	// RequeueRemoteChanged means that the remote NetworkConfig changed while being processed.
+	RequeueRemoteChanged RequeueReason = "RemoteChanged"
+	// RequeueConflict means that an update failed as the object was concurrently modified.
+	RequeueConflict RequeueReason = "Conflict"
+	// RequeuePoolExhausted means that no networks are available in the pools to remap the remote CIDRs.
+	RequeuePoolExhausted RequeueReason = "PoolExhausted"
+	// RequeueError means that the reconciliation failed due to any other error.
+	RequeueError RequeueReason = "Error"
+)
+
+// requeueReasonFor returns the reason why the reconciliation is requeued, given the error it failed with.
+func requeueReasonFor(err error) RequeueReason {
+	switch {
+	case apierrors.IsConflict(err):
+		return RequeueConflict
+	case errors.Is(err, liqonetIpam.ErrNoNetworksAvailable):
+		return RequeuePoolExhausted
+	default:
+		return RequeueError
+	}
+}
+
+// recordRequeue records an event explaining why the reconciliation of the given NetworkConfig is requeued,
+// if the event recorder is configured.
+func (tec *TunnelEndpointCreator) recordRequeue(netcfg *netv1alpha1.NetworkConfig, reason RequeueReason, message string) {
+	klog.V(4).Infof("Requeueing the reconciliation of NetworkConfig %q (%s): %s", klog.KObj(netcfg), reason, message)
+	if tec.EventRecorder == nil {
+		return
+	}
+
+	eventType := corev1.EventTypeNormal
+	if reason != RequeueWaitingForRemote && reason != RequeueRemoteChanged {
+		eventType = corev1.EventTypeWarning
+	}
+	tec.EventRecorder.Event(netcfg, eventType, string(reason), message)
+}
+
+// recordRequeueError records an event explaining why the reconciliation of the given NetworkConfig,
+// which failed with the given error, is requeued.
+func (tec *TunnelEndpointCreator) recordRequeueError(netcfg *netv1alpha1.NetworkConfig, err error) {
+	reason := requeueReasonFor(err)
+	switch reason {
+	case RequeueConflict:
+		tec.recordRequeue(netcfg, reason, fmt.Sprintf("Concurrent modification detected, retrying: %v", err))
+	case RequeuePoolExhausted:
+		tec.recordRequeue(netcfg, reason, fmt.Sprintf("No networks available to remap the remote CIDRs, retrying: %v", err))
+	default:
+		tec.recordRequeue(netcfg, reason, fmt.Sprintf("Reconciliation failed, retrying: %v", err))
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright 2019-2023 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tunnelendpointcreator
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+)
+
+// DefaultStaleTunnelThreshold is the default time after which a TunnelEndpoint not yet connected is considered stale.
+const DefaultStaleTunnelThreshold = 5 * time.Minute
+
+// staleEventsBufferSize is the size of the buffer of the channel used to enqueue the NetworkConfigs of stale TunnelEndpoints.
+const staleEventsBufferSize = 100
+
+// RequeueStaleTunnelEndpoints finds the TunnelEndpoints stuck in a pending (i.e., no status yet) or connecting phase
+// for longer than the given threshold, and enqueues the corresponding local NetworkConfigs for reconciliation, to recover
+// from missed status updates. Since TunnelEndpoints do not record phase transitions, the time a TunnelEndpoint has been
+// stuck is measured from when it was first observed in that phase by this function, which is then expected to be invoked
+// periodically. It returns the number of enqueued NetworkConfigs.
+func (tec *TunnelEndpointCreator) RequeueStaleTunnelEndpoints(ctx context.Context, threshold time.Duration) (int, error) {
+	var teps netv1alpha1.TunnelEndpointList
+	var opts []client.ListOption
+	if tec.TunnelEndpointNamespace != "" {
+		opts = append(opts, client.InNamespace(tec.TunnelEndpointNamespace))
+	}
+	if err := tec.List(ctx, &teps, opts...); err != nil {
+		return 0, fmt.Errorf("failed to list TunnelEndpoints: %w", err)
+	}
+
+	var netcfgs netv1alpha1.NetworkConfigList
+	if err := tec.List(ctx, &netcfgs, client.HasLabels{tec.ReplicationLabels.DestinationKey()}); err != nil {
+		return 0, fmt.Errorf("failed to list NetworkConfigs: %w", err)
+	}
+	locals := make(map[string]*netv1alpha1.NetworkConfig, len(netcfgs.Items))
+	for i := range netcfgs.Items {
+		locals[netcfgs.Items[i].GetLabels()[tec.ReplicationLabels.DestinationKey()]] = &netcfgs.Items[i]
+	}
+
+	tec.staleMutex.Lock()
+	defer tec.staleMutex.Unlock()
+
+	now := time.Now()
+	observed := make(map[types.NamespacedName]time.Time, len(teps.Items))
+	enqueued := 0
+	for i := range teps.Items {
+		tep := &teps.Items[i]
+		if !tunnelEndpointPending(tep) || !tep.GetDeletionTimestamp().IsZero() {
+			continue
+		}
+
+		key := client.ObjectKeyFromObject(tep)
+		since, found := tec.staleSince[key]
+		if !found {
+			since = now
+		}
+		observed[key] = since
+
+		if now.Sub(since) < threshold {
+			continue
+		}
+
+		local, found := locals[tep.Spec.ClusterIdentity.ClusterID]
+		if !found {
+			klog.V(4).Infof("TunnelEndpoint %q stuck in phase %q, but no local NetworkConfig found", klog.KObj(tep), tep.Status.Connection.Status)
+			continue
+		}
+
+		select {
+		case tec.staleTunnelEndpointEvents() <- event.GenericEvent{Object: local}:
+			klog.Warningf("TunnelEndpoint %q stuck in phase %q since %v, enqueuing NetworkConfig %q for reconciliation",
+				klog.KObj(tep), tep.Status.Connection.Status, since.Format(time.RFC3339), klog.KObj(local))
+			// Reset the timer, to enqueue the NetworkConfig again only in case the TunnelEndpoint is still stuck after the threshold.
+			observed[key] = now
+			enqueued++
+		default:
+			klog.V(4).Infof("Failed to enqueue NetworkConfig %q for reconciliation, as the buffer is full", klog.KObj(local))
+		}
+	}
+
+	// Replace the tracked TunnelEndpoints, forgetting those no longer stuck (or deleted).
+	tec.staleSince = observed
+	return enqueued, nil
+}
+
+// tunnelEndpointPending returns whether the given TunnelEndpoint is in a pending or connecting phase.
+func tunnelEndpointPending(tep *netv1alpha1.TunnelEndpoint) bool {
+	status := tep.Status.Connection.Status
+	return status == "" || status == netv1alpha1.Connecting
+}
+
+// staleTunnelEndpointEvents returns the channel used to enqueue the NetworkConfigs corresponding to stale TunnelEndpoints.
+func (tec *TunnelEndpointCreator) staleTunnelEndpointEvents() chan event.GenericEvent {
+	tec.staleEventsOnce.Do(func() { tec.staleEvents = make(chan event.GenericEvent, staleEventsBufferSize) })
+	return tec.staleEvents
+}
@@ -0,0 +1,111 @@
+// Copyright 2019-2023 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tunnelendpointcreator
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	discoveryv1alpha1 "github.com/liqotech/liqo/apis/discovery/v1alpha1"
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+)
+
+var _ = Describe("Stale TunnelEndpoints detection", func() {
+	const threshold = 100 * time.Millisecond
+
+	var (
+		ctx context.Context
+		tec *TunnelEndpointCreator
+		tep *netv1alpha1.TunnelEndpoint
+	)
+
+	tunnelEndpoint := func(status netv1alpha1.ConnectionStatus) *netv1alpha1.TunnelEndpoint {
+		return &netv1alpha1.TunnelEndpoint{
+			ObjectMeta: metav1.ObjectMeta{Name: "tep", Namespace: namespace},
+			Spec:       netv1alpha1.TunnelEndpointSpec{ClusterIdentity: discoveryv1alpha1.ClusterIdentity{ClusterID: clusterID}},
+			Status:     netv1alpha1.TunnelEndpointStatus{Connection: netv1alpha1.Connection{Status: status}},
+		}
+	}
+
+	requeue := func() int {
+		enqueued, err := tec.RequeueStaleTunnelEndpoints(ctx, threshold)
+		ExpectWithOffset(1, err).ToNot(HaveOccurred())
+		return enqueued
+	}
+
+	BeforeEach(func() { ctx = context.Background() })
+
+	JustBeforeEach(func() {
+		cl := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(localNetworkConfig(), remoteNetworkConfig(), tep).Build()
+		tec = &TunnelEndpointCreator{Client: cl, Scheme: scheme.Scheme}
+	})
+
+	When("the TunnelEndpoint is stuck in the connecting phase", func() {
+		BeforeEach(func() { tep = tunnelEndpoint(netv1alpha1.Connecting) })
+
+		It("should not enqueue the NetworkConfig before the threshold", func() {
+			Expect(requeue()).To(BeZero())
+			Expect(tec.staleTunnelEndpointEvents()).ToNot(Receive())
+		})
+
+		It("should enqueue the local NetworkConfig after the threshold", func() {
+			Expect(requeue()).To(BeZero())
+			time.Sleep(threshold)
+			Expect(requeue()).To(Equal(1))
+
+			var evt event.GenericEvent
+			Expect(tec.staleTunnelEndpointEvents()).To(Receive(&evt))
+			Expect(client.ObjectKeyFromObject(evt.Object)).To(Equal(client.ObjectKeyFromObject(localNetworkConfig())))
+		})
+
+		It("should enqueue the local NetworkConfig again only after a further threshold", func() {
+			Expect(requeue()).To(BeZero())
+			time.Sleep(threshold)
+			Expect(requeue()).To(Equal(1))
+			Expect(requeue()).To(BeZero())
+			time.Sleep(threshold)
+			Expect(requeue()).To(Equal(1))
+		})
+	})
+
+	When("the TunnelEndpoint has no status yet", func() {
+		BeforeEach(func() { tep = tunnelEndpoint("") })
+
+		It("should enqueue the local NetworkConfig after the threshold", func() {
+			Expect(requeue()).To(BeZero())
+			time.Sleep(threshold)
+			Expect(requeue()).To(Equal(1))
+		})
+	})
+
+	When("the TunnelEndpoint is connected", func() {
+		BeforeEach(func() { tep = tunnelEndpoint(netv1alpha1.Connected) })
+
+		It("should never enqueue the NetworkConfig", func() {
+			Expect(requeue()).To(BeZero())
+			time.Sleep(threshold)
+			Expect(requeue()).To(BeZero())
+			Expect(tec.staleTunnelEndpointEvents()).ToNot(Receive())
+		})
+	})
+})
@@ -0,0 +1,138 @@
+// Copyright 2019-2023 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tunnelendpointcreator
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+	liqoconst "github.com/liqotech/liqo/pkg/consts"
+	liqonetIpam "github.com/liqotech/liqo/pkg/liqonet/ipam"
+)
+
+// SweepOrphanTunnelEndpoints deletes the TunnelEndpoints whose NetworkConfigs (both local and remote) no longer
+// exist, and reclaims the subnets reserved for the corresponding remote clusters. Orphaned TunnelEndpoints might
+// be left behind in case the NetworkConfigs are removed without the finalizer being processed (e.g., if forcefully
+// removed), hence keeping the IPAM reservations busy. It returns the number of TunnelEndpoints which have been swept.
+func (tec *TunnelEndpointCreator) SweepOrphanTunnelEndpoints(ctx context.Context) (int, error) {
+	ipManager, err := tec.ipManager()
+	if err != nil {
+		return 0, err
+	}
+
+	referred, err := tec.referredClusters(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var teps netv1alpha1.TunnelEndpointList
+	var opts []client.ListOption
+	if tec.TunnelEndpointNamespace != "" {
+		opts = append(opts, client.InNamespace(tec.TunnelEndpointNamespace))
+	}
+	if err := tec.List(ctx, &teps, opts...); err != nil {
+		return 0, fmt.Errorf("failed to list TunnelEndpoints: %w", err)
+	}
+
+	swept := 0
+	for i := range teps.Items {
+		tep := &teps.Items[i]
+		if _, found := referred[tep.Spec.ClusterIdentity.ClusterID]; found || !tep.GetDeletionTimestamp().IsZero() {
+			continue
+		}
+
+		ok, err := tec.sweepTunnelEndpoint(ctx, ipManager, tep)
+		if err != nil {
+			return swept, err
+		}
+		if ok {
+			swept++
+		}
+	}
+
+	return swept, nil
+}
+
+// sweepTunnelEndpoint deletes the given orphaned TunnelEndpoint, and reclaims the subnets reserved for the corresponding
+// remote cluster. The absence of the NetworkConfigs is verified again while holding the lock associated with the cluster,
+// to prevent racing with a concurrent reconciliation (e.g., creating the TunnelEndpoint for a just appeared NetworkConfig).
+// It returns whether the TunnelEndpoint has been swept.
+func (tec *TunnelEndpointCreator) sweepTunnelEndpoint(ctx context.Context, ipManager liqonetIpam.Ipam, tep *netv1alpha1.TunnelEndpoint) (bool, error) {
+	clusterID := tep.Spec.ClusterIdentity.ClusterID
+	defer tec.lockCluster(clusterID)()
+
+	referred, err := tec.clusterReferred(ctx, clusterID)
+	if err != nil {
+		return false, err
+	}
+	if referred {
+		klog.V(4).Infof("Skipping sweep of TunnelEndpoint %q, as a NetworkConfig for cluster %s appeared", klog.KObj(tep), tep.Spec.ClusterIdentity)
+		return false, nil
+	}
+
+	klog.Infof("Sweeping TunnelEndpoint %q, as no NetworkConfigs for cluster %s exist", klog.KObj(tep), tep.Spec.ClusterIdentity)
+	unlock := tec.lockIPAM()
+	err = ipManager.RemoveClusterConfig(clusterID)
+	unlock()
+	if err != nil {
+		return false, fmt.Errorf("failed to reclaim the subnets assigned to cluster %s: %w", tep.Spec.ClusterIdentity, err)
+	}
+	tec.nat.forget(clusterID)
+	if err := tec.Delete(ctx, tep); client.IgnoreNotFound(err) != nil {
+		return false, fmt.Errorf("failed to delete orphaned TunnelEndpoint %q: %w", klog.KObj(tep), err)
+	}
+	return true, nil
+}
+
+// clusterReferred returns whether at least one NetworkConfig, either local or remote, refers to the given cluster.
+func (tec *TunnelEndpointCreator) clusterReferred(ctx context.Context, clusterID string) (bool, error) {
+	for _, labels := range []client.MatchingLabels{
+		{tec.ReplicationLabels.DestinationKey(): clusterID},
+		{liqoconst.ReplicationOriginLabel: clusterID},
+	} {
+		var netcfgs netv1alpha1.NetworkConfigList
+		if err := tec.List(ctx, &netcfgs, labels); err != nil {
+			return false, fmt.Errorf("failed to list NetworkConfigs: %w", err)
+		}
+		if len(netcfgs.Items) > 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// referredClusters returns the set of cluster IDs referred by at least one NetworkConfig, either local or remote.
+func (tec *TunnelEndpointCreator) referredClusters(ctx context.Context) (map[string]struct{}, error) {
+	var netcfgs netv1alpha1.NetworkConfigList
+	if err := tec.List(ctx, &netcfgs); err != nil {
+		return nil, fmt.Errorf("failed to list NetworkConfigs: %w", err)
+	}
+
+	referred := make(map[string]struct{}, len(netcfgs.Items))
+	for i := range netcfgs.Items {
+		labels := netcfgs.Items[i].GetLabels()
+		if clusterID, ok := labels[tec.ReplicationLabels.DestinationKey()]; ok {
+			referred[clusterID] = struct{}{}
+		}
+		if clusterID, ok := labels[liqoconst.ReplicationOriginLabel]; ok {
+			referred[clusterID] = struct{}{}
+		}
+	}
+	return referred, nil
+}
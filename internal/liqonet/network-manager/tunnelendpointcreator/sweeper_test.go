@@ -0,0 +1,129 @@
+// Copyright 2019-2023 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tunnelendpointcreator
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	discoveryv1alpha1 "github.com/liqotech/liqo/apis/discovery/v1alpha1"
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+	"github.com/liqotech/liqo/pkg/consts"
+	. "github.com/liqotech/liqo/pkg/utils/testutil"
+)
+
+var _ = Describe("Orphaned TunnelEndpoints sweeping", func() {
+	const orphanClusterID = "orphan-cluster-id"
+
+	var (
+		ctx   context.Context
+		cl    client.Client
+		ipam  *fakeIPAM
+		swept int
+		err   error
+	)
+
+	tunnelEndpoint := func(name, clusterID string) *netv1alpha1.TunnelEndpoint {
+		return &netv1alpha1.TunnelEndpoint{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: map[string]string{consts.ClusterIDLabelName: clusterID}},
+			Spec:       netv1alpha1.TunnelEndpointSpec{ClusterIdentity: discoveryv1alpha1.ClusterIdentity{ClusterID: clusterID}},
+		}
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		ipam = &fakeIPAM{}
+	})
+
+	JustBeforeEach(func() {
+		tec := &TunnelEndpointCreator{Client: cl, Scheme: scheme.Scheme, IPManager: ipam}
+		swept, err = tec.SweepOrphanTunnelEndpoints(ctx)
+	})
+
+	When("a TunnelEndpoint has no corresponding NetworkConfigs", func() {
+		BeforeEach(func() {
+			cl = fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(
+				localNetworkConfig(), remoteNetworkConfig(),
+				tunnelEndpoint("valid", clusterID), tunnelEndpoint("orphan", orphanClusterID),
+			).Build()
+		})
+
+		It("should succeed", func() { Expect(err).ToNot(HaveOccurred()) })
+		It("should sweep the orphaned TunnelEndpoint only", func() { Expect(swept).To(Equal(1)) })
+		It("should reclaim the subnets of the orphaned TunnelEndpoint", func() { Expect(ipam.removed).To(ConsistOf(orphanClusterID)) })
+		It("should delete the orphaned TunnelEndpoint", func() {
+			Expect(cl.Get(ctx, client.ObjectKey{Name: "orphan", Namespace: namespace}, &netv1alpha1.TunnelEndpoint{})).To(BeNotFound())
+		})
+		It("should preserve the TunnelEndpoint with NetworkConfigs", func() {
+			Expect(cl.Get(ctx, client.ObjectKey{Name: "valid", Namespace: namespace}, &netv1alpha1.TunnelEndpoint{})).To(Succeed())
+		})
+	})
+
+	When("a NetworkConfig appears while the sweep is in progress", func() {
+		BeforeEach(func() {
+			cl = &racingClient{
+				Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(tunnelEndpoint("orphan", orphanClusterID)).Build(),
+				appearing: &netv1alpha1.NetworkConfig{ObjectMeta: metav1.ObjectMeta{
+					Name: "appearing", Namespace: namespace, Labels: map[string]string{consts.ReplicationOriginLabel: orphanClusterID},
+				}},
+			}
+		})
+
+		It("should succeed", func() { Expect(err).ToNot(HaveOccurred()) })
+		It("should not sweep the TunnelEndpoint", func() {
+			Expect(swept).To(BeZero())
+			Expect(ipam.removed).To(BeEmpty())
+			Expect(cl.Get(ctx, client.ObjectKey{Name: "orphan", Namespace: namespace}, &netv1alpha1.TunnelEndpoint{})).To(Succeed())
+		})
+	})
+
+	When("only the remote NetworkConfig still exists", func() {
+		BeforeEach(func() {
+			cl = fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(
+				remoteNetworkConfig(), tunnelEndpoint("valid", clusterID)).Build()
+		})
+
+		It("should succeed", func() { Expect(err).ToNot(HaveOccurred()) })
+		It("should not sweep the TunnelEndpoint", func() {
+			Expect(swept).To(BeZero())
+			Expect(ipam.removed).To(BeEmpty())
+		})
+	})
+})
+
+// racingClient is a client creating the given NetworkConfig once the TunnelEndpoints have been listed, to simulate
+// a NetworkConfig appearing (and being reconciled) while the sweep of the orphaned TunnelEndpoints is in progress.
+type racingClient struct {
+	client.Client
+	appearing *netv1alpha1.NetworkConfig
+}
+
+func (rc *racingClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	if err := rc.Client.List(ctx, list, opts...); err != nil {
+		return err
+	}
+	if _, ok := list.(*netv1alpha1.TunnelEndpointList); ok && rc.appearing != nil {
+		appearing := rc.appearing
+		rc.appearing = nil
+		return rc.Client.Create(ctx, appearing)
+	}
+	return nil
+}
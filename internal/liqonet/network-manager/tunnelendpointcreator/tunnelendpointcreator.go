@@ -16,22 +16,34 @@ package tunnelendpointcreator
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
 	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/retry"
 	"k8s.io/klog/v2"
 	"k8s.io/utils/trace"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	discoveryv1alpha1 "github.com/liqotech/liqo/apis/discovery/v1alpha1"
@@ -60,6 +72,7 @@ type networkParam struct {
 	localNatExternalCIDR  string
 	backendType           string
 	backendConfig         map[string]string
+	mtuOverride           string
 }
 
 // TunnelEndpointCreator manages the most of liqo networking.
@@ -67,6 +80,87 @@ type TunnelEndpointCreator struct {
 	client.Client
 	Scheme    *runtime.Scheme
 	IPManager liqonetIpam.Ipam
+
+	// LocalClusterID is the cluster ID of the local cluster, used to ignore the NetworkConfigs referring to
+	// the local cluster itself (e.g., in case of misreplication). The check is disabled if empty.
+	LocalClusterID string
+	// TunnelEndpointNamespace, if set, is the namespace hosting the TunnelEndpoints. Otherwise, each TunnelEndpoint
+	// is created in the same namespace of the corresponding NetworkConfigs (i.e., the tenant namespace).
+	TunnelEndpointNamespace string
+	// ReplicationLabels are the keys of the labels identifying the local NetworkConfigs.
+	ReplicationLabels netcfgcreator.ReplicationLabels
+	// UnprocessedRequeueInterval is the interval after which a local NetworkConfig not yet processed by the remote
+	// cluster is checked again, to resume the processing even in case of missed events (default: DefaultUnprocessedRequeueInterval).
+	UnprocessedRequeueInterval time.Duration
+	// EventRecorder, if set, is used to record the events concerning the processed NetworkConfigs.
+	EventRecorder record.EventRecorder
+	// NATDecisionEvents enables the recording of an event on the remote NetworkConfigs whenever the NAT configuration
+	// is (re)computed, reporting whether the remote CIDRs overlap with the already reserved networks, and hence are remapped.
+	NATDecisionEvents bool
+	// MaxConcurrentReconciles is the maximum number of NetworkConfigs reconciled concurrently (default: DefaultMaxConcurrentReconciles).
+	// The NetworkConfigs referring to the same remote cluster are anyway reconciled sequentially.
+	MaxConcurrentReconciles int
+	// OverlapGranularity is the granularity at which the overlaps of the remote PodCIDRs are detected (default: NetworkOverlapGranularity).
+	OverlapGranularity OverlapGranularity
+	// UsedPodCIDRs, if set, are the sub-ranges of the local PodCIDR actually used (e.g., those assigned to the nodes),
+	// advertised to the remote clusters to allow them to detect the overlaps at the host granularity.
+	UsedPodCIDRs []string
+	// ReleaseGracePeriod, if positive, is the time the subnets reserved for a remote cluster are retained after the deletion
+	// of the corresponding NetworkConfig, so that they are reused in case it is quickly recreated (e.g., during replication
+	// flaps), rather than being freed and immediately reallocated. Pending releases are not persisted across restarts.
+	ReleaseGracePeriod time.Duration
+	// FinalizerConflictRequeueInterval, if positive, is the interval after which a NetworkConfig is reconciled again in case
+	// the addition of the finalizer failed due to a conflict, so that it is promptly processed even under contention.
+	// Otherwise, the NetworkConfig is processed again only upon the next event concerning it.
+	FinalizerConflictRequeueInterval time.Duration
+
+	// clusterLocks serializes the reconciliation of the NetworkConfigs referring to the same remote cluster.
+	clusterLocks sync.Map
+	// ipamMutex serializes the operations on the IPManager, which is not safe for concurrent use.
+	ipamMutex sync.Mutex
+
+	// pendingReleases tracks the deferred releases of the IPAM configuration of the remote clusters, by cluster ID.
+	pendingReleases map[string]*pendingRelease
+	releasesMutex   sync.Mutex
+
+	// staleSince tracks since when each TunnelEndpoint is observed in a pending phase, to detect the stale ones.
+	staleSince map[types.NamespacedName]time.Time
+	staleMutex sync.Mutex
+	// staleEvents is the channel used to enqueue the NetworkConfigs corresponding to stale TunnelEndpoints.
+	staleEvents     chan event.GenericEvent
+	staleEventsOnce sync.Once
+
+	// nat tracks the networks remapped for each remote cluster, to expose the corresponding metrics.
+	nat natTracker
+
+	// indexed is whether the RemoteClusterIDIndex field index has been registered on the NetworkConfigs cached by the client.
+	indexed bool
+}
+
+// DefaultUnprocessedRequeueInterval is the default interval after which a local NetworkConfig
+// not yet processed by the remote cluster is checked again.
+const DefaultUnprocessedRequeueInterval = 10 * time.Second
+
+// DefaultFinalizerConflictRequeueInterval is the default interval after which a NetworkConfig is reconciled again,
+// in case the addition of the finalizer failed due to a conflict.
+const DefaultFinalizerConflictRequeueInterval = 1 * time.Second
+
+// DefaultMaxConcurrentReconciles is the default maximum number of NetworkConfigs reconciled concurrently.
+const DefaultMaxConcurrentReconciles = 1
+
+// errMalformedNetworkConfig is returned when a NetworkConfig contains invalid parameters, which
+// cannot be fixed by retrying the reconciliation, but only through a modification of its spec.
+var errMalformedNetworkConfig = errors.New("malformed NetworkConfig")
+
+// errIPManagerUnset is returned when the IPManager of the TunnelEndpointCreator has not been configured.
+var errIPManagerUnset = errors.New("the IPManager of the TunnelEndpointCreator is not set")
+
+// ipManager returns the configured IPManager, or an error in case it is not set.
+func (tec *TunnelEndpointCreator) ipManager() (liqonetIpam.Ipam, error) {
+	if tec.IPManager == nil {
+		return nil, errIPManagerUnset
+	}
+	return tec.IPManager, nil
 }
 
 // rbac for the net.liqo.io api
@@ -81,7 +175,7 @@ type TunnelEndpointCreator struct {
 // +kubebuilder:rbac:groups=core,resources=events,verbs=get;list;watch;create;update;patch;delete
 
 // Reconcile reconciles the state of NetworkConfig resources.
-func (tec *TunnelEndpointCreator) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+func (tec *TunnelEndpointCreator) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, err error) {
 	tunnelEndpointCreatorFinalizer := "tunnelendpointcreator." + liqoconst.FinalizersSuffix
 
 	klog.V(4).Infof("Reconciling NetworkConfig %q", req)
@@ -98,8 +192,33 @@ func (tec *TunnelEndpointCreator) Reconcile(ctx context.Context, req ctrl.Reques
 		klog.Errorf("an error occurred while getting resource %s: %s", req.NamespacedName, err)
 		return ctrl.Result{}, err
 	}
+
+	// Explain why the reconciliation is requeued, in case of failure.
+	defer func() {
+		if err != nil {
+			reconcileErrorsMetric.WithLabelValues(string(requeueReasonFor(err))).Inc()
+			tec.recordRequeueError(&netConfig, err)
+		}
+	}()
+
+	// Prevent the concurrent reconciliation of the local and remote NetworkConfigs referring to the same cluster.
+	if clusterID, ok := tec.clusterIDOf(&netConfig); ok {
+		defer tec.lockCluster(clusterID)()
+	}
+
 	// examine DeletionTimestamp to determine if object is under deletion
 	if netConfig.ObjectMeta.DeletionTimestamp.IsZero() {
+		// Ignore the NetworkConfigs referring to the local cluster, as it would lead to peer with itself.
+		if tec.isSelfNetworkConfig(&netConfig) {
+			klog.Warningf("Ignoring NetworkConfig %q, as referring to the local cluster %q", klog.KObj(&netConfig), tec.LocalClusterID)
+			return ctrl.Result{}, nil
+		}
+
+		// Preserve the subnets reserved for the remote cluster, in case the NetworkConfig reappeared within the grace period.
+		if clusterID, ok := tec.clusterIDOf(&netConfig); ok {
+			tec.cancelPendingRelease(clusterID)
+		}
+
 		if !controllerutil.ContainsFinalizer(&netConfig, tunnelEndpointCreatorFinalizer) {
 			// The object is not being deleted, so if it does not have our finalizer,
 			// then lets add the finalizer and update the object. This is equivalent
@@ -108,11 +227,16 @@ func (tec *TunnelEndpointCreator) Reconcile(ctx context.Context, req ctrl.Reques
 			if err := tec.Update(ctx, &netConfig); err != nil {
 				// while updating we check if the a resource version conflict happened
 				// which means the version of the object we have is outdated.
-				// a solution could be to return an error and requeue the object for later process
-				// but if the object has been changed by another instance of the controller running in
-				// another host it already has been put in the working queue so decide to forget the
-				// current version and process the next item in the queue assured that we handle the object later
+				// if the object has been changed by another instance of the controller running in
+				// another host it already has been put in the working queue, hence we either requeue
+				// it after a short interval (if configured), to retry promptly under contention, or
+				// forget the current version assured that we handle the object upon the next event.
 				if apierrors.IsConflict(err) {
+					if tec.FinalizerConflictRequeueInterval > 0 {
+						tec.recordRequeue(&netConfig, RequeueConflict,
+							fmt.Sprintf("Concurrent modification detected while adding the finalizer, retrying: %v", err))
+						return ctrl.Result{RequeueAfter: tec.FinalizerConflictRequeueInterval}, nil
+					}
 					return ctrl.Result{}, nil
 				}
 				klog.Errorf("an error occurred while setting finalizer for resource %s: %s", req.NamespacedName, err)
@@ -125,8 +249,8 @@ func (tec *TunnelEndpointCreator) Reconcile(ctx context.Context, req ctrl.Reques
 	} else {
 		// The object is being deleted
 		if controllerutil.ContainsFinalizer(&netConfig, tunnelEndpointCreatorFinalizer) {
-			// Remove IPAM configuration per cluster
-			if err := tec.IPManager.RemoveClusterConfig(netConfig.Spec.RemoteCluster.ClusterID); err != nil {
+			// Remove IPAM configuration per cluster (possibly after a grace period)
+			if err := tec.releaseClusterConfig(netConfig.Spec.RemoteCluster.ClusterID); err != nil {
 				klog.Errorf("cannot delete local subnets assigned to cluster %s: %s", netConfig.Spec.RemoteCluster, err.Error())
 				return ctrl.Result{}, err
 			}
@@ -146,32 +270,139 @@ func (tec *TunnelEndpointCreator) Reconcile(ctx context.Context, req ctrl.Reques
 	}
 
 	// Check if the netconfig is local or remote, and retrieve the cluster ID of the remote cluster
-	var clusterID string
-	if val, ok := netConfig.GetLabels()[liqoconst.ReplicationRequestedLabel]; ok && val == "true" {
-		// This is a local NetworkConfig
-		clusterID = netConfig.Spec.RemoteCluster.ClusterID
-	} else if cid, ok := netConfig.GetLabels()[liqoconst.ReplicationOriginLabel]; ok {
-		// This is a remote NetworkConfig
-		// the cluster ID in the spec is the one of the destination cluster (the local cluster in this case)
-		// In order to take the ClusterID of the sender we need to retrieve it from the labels.
-		clusterID = cid
-	} else {
+	clusterID, ok := tec.clusterIDOf(&netConfig)
+	if !ok {
 		klog.Warning("NetworkConfig %q is invalid, as neither local nor remote", klog.KObj(&netConfig))
 		return ctrl.Result{}, nil
 	}
 
-	return ctrl.Result{}, tec.processNetworkConfig(ctx, clusterID, netConfig.Namespace)
+	return tec.processNetworkConfig(ctx, clusterID, netConfig.Namespace)
+}
+
+// clusterIDOf returns the cluster ID of the remote cluster the given NetworkConfig refers to, and whether
+// it is valid (i.e., either local or remote).
+func (tec *TunnelEndpointCreator) clusterIDOf(netConfig *netv1alpha1.NetworkConfig) (string, bool) {
+	if tec.ReplicationLabels.IsLocal(netConfig) {
+		// This is a local NetworkConfig
+		return netConfig.Spec.RemoteCluster.ClusterID, true
+	}
+
+	// This is a remote NetworkConfig
+	// the cluster ID in the spec is the one of the destination cluster (the local cluster in this case)
+	// In order to take the ClusterID of the sender we need to retrieve it from the labels.
+	clusterID, ok := netConfig.GetLabels()[liqoconst.ReplicationOriginLabel]
+	return clusterID, ok
+}
+
+// sameNetwork returns whether the two given CIDRs identify the same network, independently of their notation.
+func sameNetwork(first, second string) bool {
+	normalized, ok := liqonetutils.NormalizePodCIDR(first)
+	other, _ := liqonetutils.NormalizePodCIDR(second)
+	return first == second || (ok && normalized == other)
+}
+
+// lockCluster acquires the lock associated with the given remote cluster, returning the function to release it.
+func (tec *TunnelEndpointCreator) lockCluster(clusterID string) (unlock func()) {
+	lock, _ := tec.clusterLocks.LoadOrStore(clusterID, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	return lock.(*sync.Mutex).Unlock
+}
+
+// lockIPAM acquires exclusive access to the IPManager, returning the function to release it.
+func (tec *TunnelEndpointCreator) lockIPAM() (unlock func()) {
+	tec.ipamMutex.Lock()
+	return tec.ipamMutex.Unlock
+}
+
+// IPAMLocker returns the locker serializing the operations on the IPManager, to be held by the
+// external components accessing it concurrently.
+func (tec *TunnelEndpointCreator) IPAMLocker() sync.Locker {
+	return &tec.ipamMutex
+}
+
+// isSelfNetworkConfig returns whether the given NetworkConfig refers to the local cluster itself, either as the
+// destination (in case of local NetworkConfigs) or as the origin (in case of remote NetworkConfigs).
+func (tec *TunnelEndpointCreator) isSelfNetworkConfig(netConfig *netv1alpha1.NetworkConfig) bool {
+	if tec.LocalClusterID == "" {
+		return false
+	}
+
+	// The cluster ID in the spec of remote NetworkConfigs always refers to the local cluster, hence it is checked only for local ones.
+	if tec.ReplicationLabels.IsLocal(netConfig) {
+		return netConfig.Spec.RemoteCluster.ClusterID == tec.LocalClusterID
+	}
+	return netConfig.GetLabels()[liqoconst.ReplicationOriginLabel] == tec.LocalClusterID
 }
 
+// ControllerName is the name of the TunnelEndpointCreator controller, which identifies its work queue.
+const ControllerName = "tunnelendpointcreator"
+
 // SetupWithManager informs the manager that the tunnelEndpointCreator will deal with networkconfigs.
+// The controller relies on a dedicated work queue (and workers), independent from the one used to create the NetworkConfigs.
 func (tec *TunnelEndpointCreator) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
+	// Fail early, rather than panicking later during the reconciliation.
+	if _, err := tec.ipManager(); err != nil {
+		return err
+	}
+	if err := registerMetrics(); err != nil {
+		return fmt.Errorf("failed to register the metrics: %w", err)
+	}
+	if err := tec.ReplicationLabels.IndexNetworkConfigs(context.Background(), mgr.GetFieldIndexer()); err != nil {
+		return fmt.Errorf("failed to index the NetworkConfigs by remote cluster ID: %w", err)
+	}
+	tec.indexed = true
+
+	return ctrl.NewControllerManagedBy(mgr).Named(ControllerName).
 		For(&netv1alpha1.NetworkConfig{}).
-		Watches(&source.Kind{Type: &netv1alpha1.TunnelEndpoint{}},
-			&handler.EnqueueRequestForOwner{OwnerType: &netv1alpha1.NetworkConfig{}, IsController: false}).
+		// The TunnelEndpoints are mapped through the cluster ID label, rather than the owner reference, as the latter
+		// is not set in case they are hosted in a custom namespace (i.e., different from the one of the NetworkConfigs).
+		Watches(&source.Kind{Type: &netv1alpha1.TunnelEndpoint{}}, handler.EnqueueRequestsFromMapFunc(tec.tunnelEndpointToNetworkConfigs)).
+		Watches(&source.Channel{Source: tec.staleTunnelEndpointEvents()}, &handler.EnqueueRequestForObject{}).
+		WithOptions(tec.controllerOptions()).
 		Complete(tec)
 }
 
+// tunnelEndpointToNetworkConfigs maps the given TunnelEndpoint to the local NetworkConfigs referring to the same remote cluster.
+func (tec *TunnelEndpointCreator) tunnelEndpointToNetworkConfigs(obj client.Object) []reconcile.Request {
+	clusterID, found := obj.GetLabels()[liqoconst.ClusterIDLabelName]
+	if !found {
+		return nil
+	}
+
+	var netcfgs netv1alpha1.NetworkConfigList
+	if err := tec.List(context.Background(), &netcfgs, client.MatchingLabels{
+		tec.ReplicationLabels.RequestedKey():   strconv.FormatBool(true),
+		tec.ReplicationLabels.DestinationKey(): clusterID,
+	}); err != nil {
+		klog.Errorf("Failed to retrieve the local NetworkConfigs corresponding to TunnelEndpoint %q: %v", klog.KObj(obj), err)
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(netcfgs.Items))
+	for i := range netcfgs.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&netcfgs.Items[i])})
+	}
+	return requests
+}
+
+// controllerOptions returns the options of the TunnelEndpointCreator controller.
+func (tec *TunnelEndpointCreator) controllerOptions() controller.Options {
+	workers := tec.MaxConcurrentReconciles
+	if workers <= 0 {
+		workers = DefaultMaxConcurrentReconciles
+	}
+	return controller.Options{MaxConcurrentReconciles: workers}
+}
+
+// networkConfigLookupOptions returns the additional options to retrieve the NetworkConfigs referring to the given remote cluster,
+// leveraging the RemoteClusterIDIndex field index (if registered) instead of filtering all the NetworkConfigs in the namespace.
+func (tec *TunnelEndpointCreator) networkConfigLookupOptions(clusterID string) []client.ListOption {
+	if !tec.indexed {
+		return nil
+	}
+	return []client.ListOption{netcfgcreator.MatchingRemoteClusterID(clusterID)}
+}
+
 // SetupSignalHandlerForTunEndCreator registers for SIGTERM, SIGINT, SIGKILL. A stop channel is returned
 // which is closed on one of these signals.
 func (tec *TunnelEndpointCreator) SetupSignalHandlerForTunEndCreator() context.Context {
@@ -183,52 +414,60 @@ func (tec *TunnelEndpointCreator) SetupSignalHandlerForTunEndCreator() context.C
 		sig := <-c
 		klog.Infof("received signal: %s", sig.String())
 		// Stop IPAM.
-		tec.IPManager.Terminate()
+		if tec.IPManager != nil {
+			tec.IPManager.Terminate()
+		}
 		done()
 	}()
 	return ctx
 }
 
-func (tec *TunnelEndpointCreator) processNetworkConfig(ctx context.Context, clusterID, namespace string) error {
+func (tec *TunnelEndpointCreator) processNetworkConfig(ctx context.Context, clusterID, namespace string) (ctrl.Result, error) {
 	tracer := trace.FromContext(ctx)
 	klog.V(4).Infof("Processing NetworkConfigs for cluster ID %v", clusterID)
 
 	// Get the NetworkConfig created by the remote cluster.
 	// In case a duplicate is found, it is not immediately deleted, since it will be
 	// recollected by the origin cluster and eventually propagated here.
-	remote, err := netcfgcreator.GetRemoteNetworkConfig(ctx, tec.Client, clusterID, namespace)
+	remote, err := netcfgcreator.GetRemoteNetworkConfig(ctx, tec.Client, clusterID, namespace, tec.networkConfigLookupOptions(clusterID)...)
 	if err != nil {
 		if apierrors.IsNotFound(err) {
 			klog.V(4).Infof("No remote NetworkConfig for cluster %v found yet", clusterID)
-			return nil
+			return ctrl.Result{}, nil
 		}
 
 		klog.Errorf("Failed to retrieve remote NetworkConfig for cluster %v: %v", clusterID, err)
-		return err
+		return ctrl.Result{}, err
 	}
 
 	// Process the remote NetworkConfig, and enforce its meta (i.e. owner reference) and status
 	klog.V(4).Infof("Retrieved remote NetworkConfig %q for cluster %v", klog.KObj(remote), clusterID)
 	tracer.Step("Remote NetworkConfig retrieval")
 	if err := tec.enforceRemoteNetConfigMeta(ctx, remote); err != nil {
-		return err
+		return ctrl.Result{}, err
 	}
 	tracer.Step("Remote NetworkConfig meta enforcement")
 	if err := tec.enforceRemoteNetConfigStatus(ctx, remote); err != nil {
-		return err
+		if errors.Is(err, errMalformedNetworkConfig) {
+			// Do not requeue the request, as it would fail again until the spec is modified, which triggers a new reconciliation.
+			klog.Errorf("Remote NetworkConfig %q cannot be processed: %v", klog.KObj(remote), err)
+			tec.recordWarning(remote, "Malformed", err.Error())
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, err
 	}
 	tracer.Step("Remote NetworkConfig status enforcement")
 
 	// Get the NetworkConfig created by the local cluster.
-	local, err := netcfgcreator.GetLocalNetworkConfig(ctx, tec.Client, nil, clusterID, namespace)
+	local, err := tec.ReplicationLabels.GetLocalNetworkConfig(ctx, tec.Client, nil, clusterID, namespace, tec.networkConfigLookupOptions(clusterID)...)
 	if err != nil {
 		if apierrors.IsNotFound(err) {
 			klog.V(4).Infof("No local NetworkConfig for cluster %v found yet", clusterID)
-			return nil
+			return ctrl.Result{}, nil
 		}
 
 		klog.Error("failed to retrieve local NetworkConfig for cluster %v: %v", clusterID, err)
-		return err
+		return ctrl.Result{}, err
 	}
 
 	// Check if the resource has been processed by the remote cluster
@@ -236,17 +475,78 @@ func (tec *TunnelEndpointCreator) processNetworkConfig(ctx context.Context, clus
 	tracer.Step("Local NetworkConfig retrieval")
 	if !local.Status.Processed {
 		klog.V(4).Infof("Local NetworkConfig %q has not yet been processed by the remote cluster %v", klog.KObj(local), clusterID)
-		return nil
+		tec.recordRequeue(local, RequeueWaitingForRemote, fmt.Sprintf(
+			"Waiting for the NetworkConfig to be processed by the remote cluster, checking again in %v", tec.unprocessedRequeueInterval()))
+		return ctrl.Result{RequeueAfter: tec.unprocessedRequeueInterval()}, nil
+	}
+
+	// Warn in case only one of the two clusters remapped the PodCIDR of the other, as the resulting
+	// tunnel is half-remapped and the traffic may be subtly misrouted.
+	if asymmetricNAT(local, remote) {
+		klog.Warningf("Asymmetric NAT configuration for cluster %v: local PodCIDR remapped to %q, remote PodCIDR remapped to %q",
+			clusterID, local.Status.PodCIDRNAT, remote.Status.PodCIDRNAT)
+		tec.recordWarning(local, "AsymmetricNAT", fmt.Sprintf(
+			"Asymmetric NAT configuration: local PodCIDR remapped to %q by the remote cluster, remote PodCIDR remapped to %q locally",
+			local.Status.PodCIDRNAT, remote.Status.PodCIDRNAT))
 	}
 
-	if err := tec.IPManager.AddLocalSubnetsPerCluster(local.Status.PodCIDRNAT, local.Status.ExternalCIDRNAT, clusterID); err != nil {
+	ipManager, err := tec.ipManager()
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	unlock := tec.lockIPAM()
+	err = ipManager.AddLocalSubnetsPerCluster(local.Status.PodCIDRNAT, local.Status.ExternalCIDRNAT, clusterID)
+	unlock()
+	if err != nil {
 		klog.Errorf("Failed to add local subnets to IPAM for cluster %s: %v", local.Spec.RemoteCluster, err)
-		return err
+		return ctrl.Result{}, err
 	}
 	tracer.Step("IPAM configuration")
 
+	// Ensure the remote PodCIDR did not change since the remapping has been computed, as the TunnelEndpoint
+	// would be otherwise configured with stale parameters. In that case, the NetworkConfigs are processed again.
+	changed, err := tec.remotePodCIDRChanged(ctx, remote)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if changed {
+		tec.recordRequeue(remote, RequeueRemoteChanged, "The PodCIDR changed while processing the NetworkConfig, processing it again")
+		return ctrl.Result{Requeue: true}, nil
+	}
+	tracer.Step("Remote PodCIDR validation")
+
 	// If we reached this point, then it is possible to enforce the TunnelEndpoint resource
-	return tec.enforceTunnelEndpoint(ctx, local, remote)
+	return ctrl.Result{}, tec.enforceTunnelEndpoint(ctx, local, remote)
+}
+
+// asymmetricNAT returns whether the PodCIDR remapping is enabled by only one of the two peered clusters.
+func asymmetricNAT(local, remote *netv1alpha1.NetworkConfig) bool {
+	return liqonetutils.IsNATDisabled(local.Status.PodCIDRNAT) != liqonetutils.IsNATDisabled(remote.Status.PodCIDRNAT)
+}
+
+// remotePodCIDRChanged returns whether the PodCIDR of the given remote NetworkConfig changed
+// with respect to the latest version retrieved from the cluster.
+func (tec *TunnelEndpointCreator) remotePodCIDRChanged(ctx context.Context, remote *netv1alpha1.NetworkConfig) (bool, error) {
+	clusterID := remote.Labels[liqoconst.ReplicationOriginLabel]
+
+	current, err := netcfgcreator.GetRemoteNetworkConfig(ctx, tec.Client, clusterID, remote.GetNamespace(),
+		tec.networkConfigLookupOptions(clusterID)...)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			// The remote NetworkConfig has been deleted in the meanwhile, hence the TunnelEndpoint shall not be enforced.
+			klog.V(4).Infof("Remote NetworkConfig for cluster %v vanished while processing it", clusterID)
+			return true, nil
+		}
+		klog.Errorf("Failed to retrieve remote NetworkConfig for cluster %v: %v", clusterID, err)
+		return false, err
+	}
+
+	if current.Spec.PodCIDR != remote.Spec.PodCIDR {
+		klog.Infof("Remote PodCIDR for cluster %v changed from %v to %v while processing it, skipping the TunnelEndpoint enforcement",
+			clusterID, remote.Spec.PodCIDR, current.Spec.PodCIDR)
+		return true, nil
+	}
+	return false, nil
 }
 
 func (tec *TunnelEndpointCreator) enforceRemoteNetConfigMeta(ctx context.Context, netcfg *netv1alpha1.NetworkConfig) error {
@@ -281,47 +581,189 @@ func (tec *TunnelEndpointCreator) enforceRemoteNetConfigStatus(ctx context.Conte
 	tracer := trace.FromContext(ctx)
 	clusterID := netcfg.Labels[liqoconst.ReplicationOriginLabel]
 
+	// Ensure the CIDRs are valid, as otherwise they could never be remapped
+	if err := liqonetutils.IsValidCIDR(netcfg.Spec.PodCIDR); err != nil {
+		return fmt.Errorf("%w: invalid PodCIDR %q: %v", errMalformedNetworkConfig, netcfg.Spec.PodCIDR, err)
+	}
+	if err := liqonetutils.IsValidCIDR(netcfg.Spec.ExternalCIDR); err != nil {
+		return fmt.Errorf("%w: invalid ExternalCIDR %q: %v", errMalformedNetworkConfig, netcfg.Spec.ExternalCIDR, err)
+	}
+
+	// Get the network to be checked for overlaps, possibly restricted to the sub-ranges used by the remote cluster.
+	usedPodCIDR, err := tec.usedPodCIDR(ctx, netcfg)
+	if err != nil {
+		return err
+	}
+
 	// Get the CIDR remappings
-	podCIDR, externalCIDR, err := tec.IPManager.GetSubnetsPerCluster(netcfg.Spec.PodCIDR, netcfg.Spec.ExternalCIDR, clusterID)
+	ipManager, err := tec.ipManager()
+	if err != nil {
+		return err
+	}
+	unlock := tec.lockIPAM()
+	// Re-evaluate the PodCIDR previously remapped, as it might no longer overlap (e.g., if changed by the remote cluster).
+	if netcfg.Status.Processed && !liqonetutils.IsNATDisabled(netcfg.Status.PodCIDRNAT) {
+		if _, err := ipManager.ReclaimRemappedPodCIDR(usedPodCIDR, clusterID); err != nil {
+			unlock()
+			klog.Errorf("An error occurred while re-evaluating the remapped PodCIDR for resource %q: %v", klog.KObj(netcfg), err)
+			return err
+		}
+	}
+	var podCIDR, externalCIDR string
+	if usedPodCIDR != netcfg.Spec.PodCIDR {
+		podCIDR, externalCIDR, err = ipManager.GetSubnetsPerClusterByUsage(netcfg.Spec.PodCIDR, usedPodCIDR, netcfg.Spec.ExternalCIDR, clusterID)
+	} else {
+		podCIDR, externalCIDR, err = ipManager.GetSubnetsPerCluster(netcfg.Spec.PodCIDR, netcfg.Spec.ExternalCIDR, clusterID)
+	}
+	unlock()
 	if err != nil {
 		klog.Errorf("An error occurred while getting a new subnet for resource %q: %v", klog.KObj(netcfg), err)
+		if errors.Is(err, liqonetIpam.ErrNoNetworksAvailable) {
+			// The error is returned anyhow, to retry with an exponential backoff until networks become available.
+			tec.reportPoolExhaustion(ctx, netcfg)
+		}
 		return err
 	}
 	tracer.Step("CIDR remappings retrieval")
 
 	// Set the default values in case the CIDRs have not been remapped
-	if podCIDR == netcfg.Spec.PodCIDR {
+	if sameNetwork(podCIDR, netcfg.Spec.PodCIDR) || sameNetwork(podCIDR, usedPodCIDR) {
 		podCIDR = liqoconst.DefaultCIDRValue
 	}
-	if externalCIDR == netcfg.Spec.ExternalCIDR {
+	if sameNetwork(externalCIDR, netcfg.Spec.ExternalCIDR) {
 		externalCIDR = liqoconst.DefaultCIDRValue
 	}
+	tec.nat.observe(clusterID, podCIDR, externalCIDR)
 
 	// Update the status fields
 	original := netcfg.Status.DeepCopy()
 	netcfg.Status.Processed = true
 	netcfg.Status.PodCIDRNAT = podCIDR
 	netcfg.Status.ExternalCIDRNAT = externalCIDR
+	netcfg.Status.UsedPodCIDRs = tec.UsedPodCIDRs
+	netcfg.Status.StatusMessage = ""
+	netcfg.Status.NATEnabled = netv1alpha1.NATEnabledFalse
+	if podCIDR != liqoconst.DefaultCIDRValue || externalCIDR != liqoconst.DefaultCIDRValue {
+		netcfg.Status.NATEnabled = netv1alpha1.NATEnabledTrue
+	}
+	meta.RemoveStatusCondition(&netcfg.Status.Conditions, netv1alpha1.NetworkConfigPoolsExhaustedCondition)
 
 	// Avoid performing updates in case it is not necessary
-	if !reflect.DeepEqual(original, netcfg.Status) {
+	if !reflect.DeepEqual(*original, netcfg.Status) {
 		if err := tec.Status().Update(ctx, netcfg); err != nil {
 			klog.Errorf("An error occurred while updating the status of remote NetworkConfig %q: %v", klog.KObj(netcfg), err)
 			return err
 		}
+		tec.recordNATDecision(netcfg)
 	}
 
 	return nil
 }
 
+// reportPoolExhaustion surfaces in the status of the given remote NetworkConfig that its CIDRs cannot be remapped,
+// as no networks are available in the network pools.
+func (tec *TunnelEndpointCreator) reportPoolExhaustion(ctx context.Context, netcfg *netv1alpha1.NetworkConfig) {
+	message := fmt.Sprintf("No networks available to remap PodCIDR %s and ExternalCIDR %s, as the network pools are exhausted",
+		netcfg.Spec.PodCIDR, netcfg.Spec.ExternalCIDR)
+
+	original := netcfg.DeepCopy()
+	netcfg.Status.StatusMessage = message
+	netcfg.Status.NATEnabled = netv1alpha1.NATEnabledError
+	meta.SetStatusCondition(&netcfg.Status.Conditions, metav1.Condition{
+		Type:    netv1alpha1.NetworkConfigPoolsExhaustedCondition,
+		Status:  metav1.ConditionTrue,
+		Reason:  "NoNetworksAvailable",
+		Message: message,
+	})
+
+	// Avoid performing updates in case it is not necessary
+	if reflect.DeepEqual(original.Status, netcfg.Status) {
+		return
+	}
+	if err := tec.Status().Patch(ctx, netcfg, client.MergeFrom(original)); err != nil {
+		klog.Errorf("An error occurred while updating the status of remote NetworkConfig %q: %v", klog.KObj(netcfg), err)
+	}
+}
+
+// recordNATDecision records an event concerning the NAT configuration of the given remote NetworkConfig, if enabled.
+func (tec *TunnelEndpointCreator) recordNATDecision(netcfg *netv1alpha1.NetworkConfig) {
+	if !tec.NATDecisionEvents || tec.EventRecorder == nil {
+		return
+	}
+
+	var remapped []string
+	if !liqonetutils.IsNATDisabled(netcfg.Status.PodCIDRNAT) {
+		remapped = append(remapped, fmt.Sprintf("PodCIDR %s overlaps with an already reserved network, remapped to %s",
+			netcfg.Spec.PodCIDR, netcfg.Status.PodCIDRNAT))
+	}
+	if !liqonetutils.IsNATDisabled(netcfg.Status.ExternalCIDRNAT) {
+		remapped = append(remapped, fmt.Sprintf("ExternalCIDR %s overlaps with an already reserved network, remapped to %s",
+			netcfg.Spec.ExternalCIDR, netcfg.Status.ExternalCIDRNAT))
+	}
+
+	if len(remapped) == 0 {
+		tec.EventRecorder.Eventf(netcfg, corev1.EventTypeNormal, "NATDisabled",
+			"PodCIDR %s and ExternalCIDR %s do not overlap with any reserved network", netcfg.Spec.PodCIDR, netcfg.Spec.ExternalCIDR)
+		return
+	}
+	tec.EventRecorder.Event(netcfg, corev1.EventTypeNormal, "NATEnabled", strings.Join(remapped, "; "))
+}
+
 func (tec *TunnelEndpointCreator) enforceTunnelEndpoint(ctx context.Context, local, remote *netv1alpha1.NetworkConfig) error {
 	tracer := trace.FromContext(ctx)
 
 	// At this point we have all the necessary parameters to create the tunnelEndpoint resource
-	param := &networkParam{
+	param := tec.forgeTunnelEndpointParam(local, remote)
+
+	// Try to get the tunnelEndpoint, which may not exist
+	namespace := tec.tunnelEndpointNamespace(local.GetNamespace())
+	_, err := getters.GetTunnelEndpoint(ctx, tec.Client, &param.remoteCluster, namespace)
+	tracer.Step("TunnelEndpoint retrieval")
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			controllerRef := metav1.GetControllerOf(local)
+			defer tracer.Step("TunnelEndpoint creation")
+			return tec.createTunnelEndpoint(ctx, param, controllerRef, namespace, local)
+		}
+		klog.Errorf("an error occurred while getting resource tunnelEndpoint for cluster %s: %s",
+			param.remoteCluster, err)
+		return err
+	}
+
+	defer tracer.Step("TunnelEndpoint update")
+	_, err = tec.updateSpecTunnelEndpoint(ctx, param, namespace)
+	return err
+}
+
+// unprocessedRequeueInterval returns the interval after which a local NetworkConfig not yet processed is checked again.
+func (tec *TunnelEndpointCreator) unprocessedRequeueInterval() time.Duration {
+	if tec.UnprocessedRequeueInterval > 0 {
+		return tec.UnprocessedRequeueInterval
+	}
+	return DefaultUnprocessedRequeueInterval
+}
+
+// recordWarning records a warning event concerning the given NetworkConfig, if the event recorder is configured.
+func (tec *TunnelEndpointCreator) recordWarning(netcfg *netv1alpha1.NetworkConfig, reason, message string) {
+	if tec.EventRecorder != nil {
+		tec.EventRecorder.Event(netcfg, corev1.EventTypeWarning, reason, message)
+	}
+}
+
+// tunnelEndpointNamespace returns the namespace hosting the TunnelEndpoint, given the one of the corresponding NetworkConfigs.
+func (tec *TunnelEndpointCreator) tunnelEndpointNamespace(netcfgNamespace string) string {
+	if tec.TunnelEndpointNamespace != "" {
+		return tec.TunnelEndpointNamespace
+	}
+	return netcfgNamespace
+}
+
+// forgeNetworkParam derives the parameters of the TunnelEndpoint from the local and the remote NetworkConfigs.
+func forgeNetworkParam(local, remote *netv1alpha1.NetworkConfig) *networkParam {
+	return &networkParam{
 		remoteCluster:         local.Spec.RemoteCluster,
 		remoteEndpointIP:      remote.Spec.EndpointIP,
-		remotePodCIDR:         remote.Spec.PodCIDR,
+		remotePodCIDR:         remotePodCIDR(local, remote),
 		remoteNatPodCIDR:      remote.Status.PodCIDRNAT,
 		remoteExternalCIDR:    remote.Spec.ExternalCIDR,
 		remoteNatExternalCIDR: remote.Status.ExternalCIDRNAT,
@@ -332,51 +774,57 @@ func (tec *TunnelEndpointCreator) enforceTunnelEndpoint(ctx context.Context, loc
 		localNatExternalCIDR:  local.Status.ExternalCIDRNAT,
 		backendType:           remote.Spec.BackendType,
 		backendConfig:         remote.Spec.BackendConfig,
+		mtuOverride:           local.GetAnnotations()[liqoconst.MTUAnnotationKey],
 	}
+}
 
-	// Try to get the tunnelEndpoint, which may not exist
-	_, err := getters.GetTunnelEndpoint(ctx, tec.Client, &param.remoteCluster, local.GetNamespace())
-	tracer.Step("TunnelEndpoint retrieval")
-	if err != nil {
-		if apierrors.IsNotFound(err) {
-			controllerRef := metav1.GetControllerOf(local)
-			defer tracer.Step("TunnelEndpoint creation")
-			return tec.createTunnelEndpoint(ctx, param, controllerRef, local.GetNamespace(), local)
-		}
-		klog.Errorf("an error occurred while getting resource tunnelEndpoint for cluster %s: %s",
-			param.remoteCluster, err)
-		return err
+// forgeTunnelEndpointParam derives the parameters of the TunnelEndpoint from the local and the remote NetworkConfigs,
+// ignoring the MTU override configured on the local NetworkConfig in case it is invalid.
+func (tec *TunnelEndpointCreator) forgeTunnelEndpointParam(local, remote *netv1alpha1.NetworkConfig) *networkParam {
+	param := forgeNetworkParam(local, remote)
+	if _, _, err := liqonetutils.MTUOverride(local); err != nil {
+		klog.Warningf("ignoring the MTU override of NetworkConfig %q: %v", klog.KObj(local), err)
+		tec.recordWarning(local, "InvalidMTUOverride", err.Error())
+		param.mtuOverride = ""
 	}
-
-	defer tracer.Step("TunnelEndpoint update")
-	return tec.updateSpecTunnelEndpoint(ctx, param, local.GetNamespace())
+	return param
 }
 
-func (tec *TunnelEndpointCreator) updateSpecTunnelEndpoint(ctx context.Context, param *networkParam, namespace string) error {
-	var tep *netv1alpha1.TunnelEndpoint
-	var err error
+// updateSpecTunnelEndpoint aligns the existing TunnelEndpoint with the given parameters, and returns the names of the
+// fields which have been updated (i.e., the spec fields and the annotations), if any.
+func (tec *TunnelEndpointCreator) updateSpecTunnelEndpoint(ctx context.Context, param *networkParam, namespace string) ([]string, error) {
+	var drifted []string
 	// here we recover from conflicting resource versions
 	retryError := retry.RetryOnConflict(retry.DefaultRetry, func() error {
-		tep, err = getters.GetTunnelEndpoint(ctx, tec.Client, &param.remoteCluster, namespace)
+		tep, err := getters.GetTunnelEndpoint(ctx, tec.Client, &param.remoteCluster, namespace)
 		if err != nil {
 			return err
 		}
 
-		original := tep.Spec.DeepCopy()
+		original := tep.DeepCopy()
 		tec.fillTunnelEndpointSpec(tep, param)
+		fillTunnelEndpointMTUOverride(tep, param)
+
+		drifted = driftedFields(&original.Spec, &tep.Spec)
+		if !reflect.DeepEqual(original.GetAnnotations(), tep.GetAnnotations()) {
+			drifted = append(drifted, "Annotations")
+		}
 
 		// Avoid performing updates in case it is not necessary
-		if !reflect.DeepEqual(original, tep.Spec) {
-			err = tec.Update(ctx, tep)
-			return err
+		if len(drifted) > 0 {
+			// Reject the mutations of the immutable fields early, rather than relying on the validating webhook.
+			if err = liqonetutils.ValidateTunnelEndpointUpdate(original, tep); err != nil {
+				return err
+			}
+			return tec.Update(ctx, tep)
 		}
 		return nil
 	})
 	if retryError != nil {
-		klog.Errorf("an error occurred while updating spec of tunnelEndpoint resource %s: %s", tep.Name, retryError)
-		return retryError
+		klog.Errorf("an error occurred while updating spec of tunnelEndpoint resource for cluster %s: %s", param.remoteCluster, retryError)
+		return nil, retryError
 	}
-	return nil
+	return drifted, nil
 }
 
 func (tec *TunnelEndpointCreator) createTunnelEndpoint(ctx context.Context, param *networkParam,
@@ -392,16 +840,21 @@ func (tec *TunnelEndpointCreator) createTunnelEndpoint(ctx context.Context, para
 		},
 	}
 
-	if err := controllerutil.SetOwnerReference(localNet, tep, tec.Scheme); err != nil {
-		klog.Errorf("an error occurred while setting owner reference to resource %s: %v", tep.Name, err)
-		return err
-	}
+	// Cross-namespace owner references are not allowed, hence they are set only if the TunnelEndpoint is in the same
+	// namespace of the NetworkConfig. Otherwise, its deletion is anyhow guaranteed by the NetworkConfig finalizer.
+	if namespace == localNet.GetNamespace() {
+		if err := controllerutil.SetOwnerReference(localNet, tep, tec.Scheme); err != nil {
+			klog.Errorf("an error occurred while setting owner reference to resource %s: %v", tep.Name, err)
+			return err
+		}
 
-	if ownerRef != nil {
-		tep.OwnerReferences = append(tep.OwnerReferences, *ownerRef)
+		if ownerRef != nil {
+			tep.OwnerReferences = append(tep.OwnerReferences, *ownerRef)
+		}
 	}
 
 	tec.fillTunnelEndpointSpec(tep, param)
+	fillTunnelEndpointMTUOverride(tep, param)
 
 	if err := tec.Create(ctx, tep); err != nil {
 		klog.Errorf("an error occurred while creating resource %s of type %s: %s",
@@ -432,8 +885,20 @@ func (tec *TunnelEndpointCreator) fillTunnelEndpointSpec(tep *netv1alpha1.Tunnel
 	tep.Spec.BackendConfig = param.backendConfig
 }
 
+// fillTunnelEndpointMTUOverride propagates the per-cluster MTU override (if any) from the local NetworkConfig to the TunnelEndpoint.
+func fillTunnelEndpointMTUOverride(tep *netv1alpha1.TunnelEndpoint, param *networkParam) {
+	if param.mtuOverride == "" {
+		delete(tep.Annotations, liqoconst.MTUAnnotationKey)
+		return
+	}
+	if tep.Annotations == nil {
+		tep.Annotations = map[string]string{}
+	}
+	tep.Annotations[liqoconst.MTUAnnotationKey] = param.mtuOverride
+}
+
 func (tec *TunnelEndpointCreator) deleteTunEndpoint(ctx context.Context, netConfig *netv1alpha1.NetworkConfig) error {
-	tep, err := getters.GetTunnelEndpoint(ctx, tec.Client, &netConfig.Spec.RemoteCluster, netConfig.GetNamespace())
+	tep, err := getters.GetTunnelEndpoint(ctx, tec.Client, &netConfig.Spec.RemoteCluster, tec.tunnelEndpointNamespace(netConfig.GetNamespace()))
 	if err != nil {
 		if apierrors.IsNotFound(err) {
 			klog.Infof("tunnelendpoint resource for cluster %s not found", netConfig.Spec.RemoteCluster)
@@ -441,8 +906,9 @@ func (tec *TunnelEndpointCreator) deleteTunEndpoint(ctx context.Context, netConf
 		}
 		return err
 	}
+	// The TunnelEndpoint might have been concurrently deleted in the meanwhile, which is fine as well.
 	err = tec.Delete(ctx, tep)
-	if err != nil {
+	if client.IgnoreNotFound(err) != nil {
 		return fmt.Errorf("unable to delete endpoint %s in namespace %s : %w", tep.Name, tep.Namespace, err)
 	}
 	klog.Infof("resource %s of type %s for remote cluster %s has been removed",
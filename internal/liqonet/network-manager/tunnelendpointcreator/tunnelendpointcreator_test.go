@@ -0,0 +1,1005 @@
+// Copyright 2019-2023 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tunnelendpointcreator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	promtestutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"inet.af/netaddr"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/trace"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+	"github.com/liqotech/liqo/internal/liqonet/network-manager/netcfgcreator"
+	"github.com/liqotech/liqo/pkg/consts"
+	liqonetIpam "github.com/liqotech/liqo/pkg/liqonet/ipam"
+	"github.com/liqotech/liqo/pkg/utils/getters"
+	. "github.com/liqotech/liqo/pkg/utils/testutil"
+)
+
+// fakeIPAM is a liqonetIpam.Ipam implementation which does not remap any network.
+type fakeIPAM struct {
+	liqonetIpam.Ipam
+	// removed tracks the cluster IDs whose configuration has been removed.
+	removed []string
+	// reclaimed tracks the cluster IDs whose remapped PodCIDR has been re-evaluated.
+	reclaimed []string
+	// renamed tracks the renamed clusters, in the old->new form.
+	renamed []string
+}
+
+func (fi *fakeIPAM) RenameCluster(oldID, newID string) error {
+	fi.renamed = append(fi.renamed, oldID+"->"+newID)
+	return nil
+}
+
+func (fi *fakeIPAM) ReclaimRemappedPodCIDR(_, clusterID string) (bool, error) {
+	fi.reclaimed = append(fi.reclaimed, clusterID)
+	return true, nil
+}
+
+func (fi *fakeIPAM) RemoveClusterConfig(clusterID string) error {
+	fi.removed = append(fi.removed, clusterID)
+	return nil
+}
+
+func (fi *fakeIPAM) GetSubnetsPerCluster(podCIDR, externalCIDR, _ string) (mappedPodCIDR, mappedExternalCIDR string, err error) {
+	return podCIDR, externalCIDR, nil
+}
+
+func (fi *fakeIPAM) AddLocalSubnetsPerCluster(_, _, _ string) error { return nil }
+
+// overlappingIPAM is a liqonetIpam.Ipam implementation which remaps the PodCIDRs overlapping with the taken network.
+type overlappingIPAM struct {
+	fakeIPAM
+	taken    string
+	remapped string
+}
+
+func (oi *overlappingIPAM) GetSubnetsPerCluster(podCIDR, externalCIDR, _ string) (mappedPodCIDR, mappedExternalCIDR string, err error) {
+	if netaddr.MustParseIPPrefix(podCIDR).Overlaps(netaddr.MustParseIPPrefix(oi.taken)) {
+		return oi.remapped, externalCIDR, nil
+	}
+	return podCIDR, externalCIDR, nil
+}
+
+func (oi *overlappingIPAM) GetSubnetsPerClusterByUsage(podCIDR, usedPodCIDR, externalCIDR, clusterID string) (
+	mappedPodCIDR, mappedExternalCIDR string, err error) {
+	if netaddr.MustParseIPPrefix(usedPodCIDR).Overlaps(netaddr.MustParseIPPrefix(oi.taken)) {
+		return oi.GetSubnetsPerCluster(podCIDR, externalCIDR, clusterID)
+	}
+	return usedPodCIDR, externalCIDR, nil
+}
+
+// remappingIPAM is a liqonetIpam.Ipam implementation which remaps the PodCIDR to the given network.
+type remappingIPAM struct {
+	fakeIPAM
+	podCIDR string
+}
+
+func (ri *remappingIPAM) GetSubnetsPerCluster(_, externalCIDR, _ string) (mappedPodCIDR, mappedExternalCIDR string, err error) {
+	return ri.podCIDR, externalCIDR, nil
+}
+
+// exhaustedIPAM is a liqonetIpam.Ipam implementation whose network pools are exhausted.
+type exhaustedIPAM struct {
+	fakeIPAM
+}
+
+func (ei *exhaustedIPAM) GetSubnetsPerCluster(_, _, clusterID string) (mappedPodCIDR, mappedExternalCIDR string, err error) {
+	return "", "", fmt.Errorf("cannot get a PodCIDR for cluster %s: %w", clusterID, liqonetIpam.ErrNoNetworksAvailable)
+}
+
+// conflictingClient is a client.Client whose status updates always fail due to a conflict.
+type conflictingClient struct {
+	client.Client
+}
+
+func (cc *conflictingClient) Status() client.StatusWriter {
+	return &conflictingStatusWriter{StatusWriter: cc.Client.Status()}
+}
+
+type conflictingStatusWriter struct {
+	client.StatusWriter
+}
+
+func (csw *conflictingStatusWriter) Update(_ context.Context, obj client.Object, _ ...client.UpdateOption) error {
+	return apierrors.NewConflict(netv1alpha1.NetworkConfigGroupResource, obj.GetName(), errors.New("the object has been modified"))
+}
+
+// receivedEvents returns the events recorded so far by the given recorder.
+func receivedEvents(recorder *record.FakeRecorder) []string {
+	var events []string
+	for {
+		select {
+		case event := <-recorder.Events:
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+}
+
+// conflictingUpdateClient is a client.Client whose updates (excluding the status ones) always fail due to a conflict.
+type conflictingUpdateClient struct {
+	client.Client
+}
+
+func (cuc *conflictingUpdateClient) Update(_ context.Context, obj client.Object, _ ...client.UpdateOption) error {
+	return apierrors.NewConflict(netv1alpha1.NetworkConfigGroupResource, obj.GetName(), errors.New("the object has been modified"))
+}
+
+// hookedClient is a client.Client invoking a hook before each list operation of NetworkConfigs.
+type hookedClient struct {
+	client.Client
+	listed int
+	hook   func(listed int)
+}
+
+func (hc *hookedClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	if _, ok := list.(*netv1alpha1.NetworkConfigList); ok {
+		hc.listed++
+		hc.hook(hc.listed)
+	}
+	return hc.Client.List(ctx, list, opts...)
+}
+
+var _ = Describe("NetworkConfig processing", func() {
+	var (
+		ctx      context.Context
+		cl       *hookedClient
+		tec      *TunnelEndpointCreator
+		hook     func(listed int)
+		local    *netv1alpha1.NetworkConfig
+		remote   *netv1alpha1.NetworkConfig
+		recorder *record.FakeRecorder
+		ipam     liqonetIpam.Ipam
+
+		granularity       OverlapGranularity
+		usedPodCIDRs      []string
+		natDecisionEvents bool
+
+		res ctrl.Result
+		err error
+	)
+
+	listTunnelEndpoints := func() []netv1alpha1.TunnelEndpoint {
+		var teps netv1alpha1.TunnelEndpointList
+		Expect(tec.List(ctx, &teps, client.InNamespace(namespace))).To(Succeed())
+		return teps.Items
+	}
+
+	BeforeEach(func() {
+		ctx = trace.ContextWithTrace(context.Background(), trace.New("Reconcile"))
+		hook = func(listed int) {}
+		local = localNetworkConfig()
+		remote = remoteNetworkConfig()
+		remote.Status = netv1alpha1.NetworkConfigStatus{}
+		recorder = record.NewFakeRecorder(10)
+		ipam = &fakeIPAM{}
+		granularity, usedPodCIDRs = "", nil
+		natDecisionEvents = false
+	})
+
+	JustBeforeEach(func() {
+		cl = &hookedClient{
+			Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(local, remote).Build(),
+			hook:   hook,
+		}
+		tec = &TunnelEndpointCreator{Client: cl, Scheme: scheme.Scheme, IPManager: ipam, EventRecorder: recorder,
+			NATDecisionEvents: natDecisionEvents, OverlapGranularity: granularity, UsedPodCIDRs: usedPodCIDRs}
+		res, err = tec.processNetworkConfig(ctx, clusterID, namespace)
+	})
+
+	When("the remote PodCIDR does not change while processing the NetworkConfigs", func() {
+		It("should succeed", func() { Expect(err).ToNot(HaveOccurred()) })
+		It("should not requeue", func() { Expect(res).To(Equal(ctrl.Result{})) })
+		It("should create the TunnelEndpoint", func() {
+			teps := listTunnelEndpoints()
+			Expect(teps).To(HaveLen(1))
+			Expect(teps[0].Spec.RemotePodCIDR).To(Equal(remoteNetworkConfig().Spec.PodCIDR))
+		})
+	})
+
+	When("the remote PodCIDR is an IPv6 network", func() {
+		BeforeEach(func() { remote.Spec.PodCIDR = "fd00::/48" })
+
+		When("it does not overlap with any reserved network", func() {
+			It("should succeed", func() { Expect(err).ToNot(HaveOccurred()) })
+			It("should disable the NAT for the PodCIDR", func() {
+				current, err := getRemoteNetworkConfig(ctx, cl.Client)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(current.Status.PodCIDRNAT).To(Equal(consts.DefaultCIDRValue))
+			})
+			It("should create the TunnelEndpoint", func() {
+				teps := listTunnelEndpoints()
+				Expect(teps).To(HaveLen(1))
+				Expect(teps[0].Spec.RemotePodCIDR).To(Equal("fd00::/48"))
+				Expect(teps[0].Spec.RemoteNATPodCIDR).To(Equal(consts.DefaultCIDRValue))
+			})
+		})
+
+		When("it is remapped to another IPv6 network", func() {
+			BeforeEach(func() { ipam = &remappingIPAM{podCIDR: "fd00:0:1::/48"} })
+
+			It("should succeed", func() { Expect(err).ToNot(HaveOccurred()) })
+			It("should record the remapped PodCIDR", func() {
+				current, err := getRemoteNetworkConfig(ctx, cl.Client)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(current.Status.PodCIDRNAT).To(Equal("fd00:0:1::/48"))
+			})
+			It("should propagate the remapped PodCIDR to the TunnelEndpoint", func() {
+				teps := listTunnelEndpoints()
+				Expect(teps).To(HaveLen(1))
+				Expect(teps[0].Spec.RemotePodCIDR).To(Equal("fd00::/48"))
+				Expect(teps[0].Spec.RemoteNATPodCIDR).To(Equal("fd00:0:1::/48"))
+			})
+		})
+	})
+
+	When("the remote gateway is exposed on a non-default port", func() {
+		BeforeEach(func() { remote.Spec.BackendConfig = map[string]string{consts.ListeningPort: "32100"} })
+
+		It("should succeed", func() { Expect(err).ToNot(HaveOccurred()) })
+		It("should propagate the port to the TunnelEndpoint", func() {
+			teps := listTunnelEndpoints()
+			Expect(teps).To(HaveLen(1))
+			Expect(teps[0].Spec.BackendConfig).To(HaveKeyWithValue(consts.ListeningPort, "32100"))
+		})
+
+		When("the port is subsequently changed", func() {
+			JustBeforeEach(func() {
+				current, err := getRemoteNetworkConfig(ctx, cl.Client)
+				Expect(err).ToNot(HaveOccurred())
+				current.Spec.BackendConfig[consts.ListeningPort] = "32200"
+				Expect(cl.Client.Update(ctx, current)).To(Succeed())
+
+				_, err = tec.processNetworkConfig(ctx, clusterID, namespace)
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("should update the TunnelEndpoint spec", func() {
+				teps := listTunnelEndpoints()
+				Expect(teps).To(HaveLen(1))
+				Expect(teps[0].Spec.BackendConfig).To(HaveKeyWithValue(consts.ListeningPort, "32200"))
+			})
+		})
+	})
+
+	When("the local NetworkConfig overrides the MTU", func() {
+		BeforeEach(func() { local.SetAnnotations(map[string]string{consts.MTUAnnotationKey: "1300"}) })
+
+		It("should succeed", func() { Expect(err).ToNot(HaveOccurred()) })
+		It("should propagate the override to the TunnelEndpoint", func() {
+			teps := listTunnelEndpoints()
+			Expect(teps).To(HaveLen(1))
+			Expect(teps[0].GetAnnotations()).To(HaveKeyWithValue(consts.MTUAnnotationKey, "1300"))
+		})
+
+		When("the override is subsequently removed", func() {
+			JustBeforeEach(func() {
+				var current netv1alpha1.NetworkConfig
+				Expect(cl.Client.Get(ctx, client.ObjectKeyFromObject(local), &current)).To(Succeed())
+				current.SetAnnotations(nil)
+				Expect(cl.Client.Update(ctx, &current)).To(Succeed())
+
+				_, err = tec.processNetworkConfig(ctx, clusterID, namespace)
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("should remove the override from the TunnelEndpoint", func() {
+				teps := listTunnelEndpoints()
+				Expect(teps).To(HaveLen(1))
+				Expect(teps[0].GetAnnotations()).ToNot(HaveKey(consts.MTUAnnotationKey))
+			})
+		})
+	})
+
+	When("the local NetworkConfig specifies an invalid MTU override", func() {
+		BeforeEach(func() {
+			local.Status.PodCIDRNAT = consts.DefaultCIDRValue
+			local.SetAnnotations(map[string]string{consts.MTUAnnotationKey: "invalid"})
+		})
+
+		It("should succeed", func() { Expect(err).ToNot(HaveOccurred()) })
+		It("should record a warning event", func() {
+			Expect(recorder.Events).To(Receive(And(HavePrefix(corev1.EventTypeWarning), ContainSubstring("InvalidMTUOverride"))))
+		})
+		It("should not propagate the override to the TunnelEndpoint", func() {
+			teps := listTunnelEndpoints()
+			Expect(teps).To(HaveLen(1))
+			Expect(teps[0].GetAnnotations()).ToNot(HaveKey(consts.MTUAnnotationKey))
+		})
+	})
+
+	When("the remote PodCIDR was previously remapped, and does no longer overlap", func() {
+		BeforeEach(func() {
+			remote.Status = netv1alpha1.NetworkConfigStatus{
+				Processed: true, PodCIDRNAT: "10.60.0.0/16", ExternalCIDRNAT: consts.DefaultCIDRValue}
+		})
+
+		It("should succeed", func() { Expect(err).ToNot(HaveOccurred()) })
+		It("should re-evaluate the remapped PodCIDR", func() {
+			Expect(ipam.(*fakeIPAM).reclaimed).To(ConsistOf(clusterID))
+		})
+		It("should disable the PodCIDR remapping", func() {
+			current, err := getRemoteNetworkConfig(ctx, cl.Client)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(current.Status.PodCIDRNAT).To(Equal(consts.DefaultCIDRValue))
+			Expect(current.Status.NATEnabled).To(Equal(netv1alpha1.NATEnabledFalse))
+		})
+		It("should create the TunnelEndpoint without remapping", func() {
+			teps := listTunnelEndpoints()
+			Expect(teps).To(HaveLen(1))
+			Expect(teps[0].Spec.RemoteNATPodCIDR).To(Equal(consts.DefaultCIDRValue))
+		})
+	})
+
+	When("the remote PodCIDR was not previously remapped", func() {
+		It("should not re-evaluate the PodCIDR", func() { Expect(ipam.(*fakeIPAM).reclaimed).To(BeEmpty()) })
+	})
+
+	When("the whole remote PodCIDR overlaps, but the sub-ranges used by the remote cluster do not", func() {
+		BeforeEach(func() {
+			ipam = &overlappingIPAM{taken: "10.0.0.0/17", remapped: "10.70.0.0/16"}
+			local.Status.UsedPodCIDRs = []string{"10.0.128.0/24", "10.0.129.0/24"}
+		})
+
+		When("the overlaps are detected at the host granularity", func() {
+			BeforeEach(func() { granularity = HostOverlapGranularity })
+
+			It("should succeed", func() { Expect(err).ToNot(HaveOccurred()) })
+			It("should not remap the PodCIDR", func() {
+				current, err := getRemoteNetworkConfig(ctx, cl.Client)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(current.Status.PodCIDRNAT).To(Equal(consts.DefaultCIDRValue))
+			})
+			It("should create the TunnelEndpoint without remapping, restricted to the used sub-ranges", func() {
+				teps := listTunnelEndpoints()
+				Expect(teps).To(HaveLen(1))
+				Expect(teps[0].Spec.RemotePodCIDR).To(Equal("10.0.128.0/23"))
+				Expect(teps[0].Spec.RemoteNATPodCIDR).To(Equal(consts.DefaultCIDRValue))
+			})
+
+			When("the used sub-ranges overlap as well", func() {
+				BeforeEach(func() { local.Status.UsedPodCIDRs = []string{"10.0.1.0/24", "10.0.129.0/24"} })
+
+				It("should succeed", func() { Expect(err).ToNot(HaveOccurred()) })
+				It("should remap the whole PodCIDR", func() {
+					current, err := getRemoteNetworkConfig(ctx, cl.Client)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(current.Status.PodCIDRNAT).To(Equal("10.70.0.0/16"))
+
+					teps := listTunnelEndpoints()
+					Expect(teps).To(HaveLen(1))
+					Expect(teps[0].Spec.RemotePodCIDR).To(Equal(remote.Spec.PodCIDR))
+					Expect(teps[0].Spec.RemoteNATPodCIDR).To(Equal("10.70.0.0/16"))
+				})
+			})
+
+			When("the used sub-ranges are not contained in the remote PodCIDR", func() {
+				BeforeEach(func() { local.Status.UsedPodCIDRs = []string{"10.5.0.0/24"} })
+
+				It("should succeed", func() { Expect(err).ToNot(HaveOccurred()) })
+				It("should ignore them, and remap the whole PodCIDR", func() {
+					current, err := getRemoteNetworkConfig(ctx, cl.Client)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(current.Status.PodCIDRNAT).To(Equal("10.70.0.0/16"))
+				})
+			})
+		})
+
+		When("the overlaps are detected at the network granularity", func() {
+			It("should succeed", func() { Expect(err).ToNot(HaveOccurred()) })
+			It("should remap the whole PodCIDR", func() {
+				current, err := getRemoteNetworkConfig(ctx, cl.Client)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(current.Status.PodCIDRNAT).To(Equal("10.70.0.0/16"))
+			})
+		})
+	})
+
+	When("the sub-ranges of the local PodCIDR actually used are configured", func() {
+		BeforeEach(func() { usedPodCIDRs = []string{"10.0.4.0/24"} })
+
+		It("should succeed", func() { Expect(err).ToNot(HaveOccurred()) })
+		It("should advertise them in the status of the remote NetworkConfig", func() {
+			current, err := getRemoteNetworkConfig(ctx, cl.Client)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(current.Status.UsedPodCIDRs).To(ConsistOf("10.0.4.0/24"))
+		})
+	})
+
+	When("the existing TunnelEndpoint refers to a different cluster", func() {
+		var reprocessErr error
+
+		JustBeforeEach(func() {
+			teps := listTunnelEndpoints()
+			Expect(teps).To(HaveLen(1))
+			teps[0].Spec.ClusterIdentity.ClusterID = "corrupted-cluster-id"
+			Expect(cl.Client.Update(ctx, &teps[0])).To(Succeed())
+
+			_, reprocessErr = tec.processNetworkConfig(ctx, clusterID, namespace)
+		})
+
+		It("should reject the mutation of the cluster ID", func() { Expect(reprocessErr).To(HaveOccurred()) })
+		It("should leave the TunnelEndpoint unmodified", func() {
+			teps := listTunnelEndpoints()
+			Expect(teps).To(HaveLen(1))
+			Expect(teps[0].Spec.ClusterIdentity.ClusterID).To(Equal("corrupted-cluster-id"))
+		})
+	})
+
+	When("the remote PodCIDR changes while processing the NetworkConfigs", func() {
+		BeforeEach(func() {
+			hook = func(listed int) {
+				// Modify the remote NetworkConfig after it has been retrieved for the first time.
+				if listed != 2 {
+					return
+				}
+				remote, err := getRemoteNetworkConfig(ctx, cl.Client)
+				Expect(err).ToNot(HaveOccurred())
+				remote.Spec.PodCIDR = "10.100.0.0/16"
+				Expect(cl.Client.Update(ctx, remote)).To(Succeed())
+			}
+		})
+
+		It("should succeed", func() { Expect(err).ToNot(HaveOccurred()) })
+		It("should requeue the request", func() { Expect(res.Requeue).To(BeTrue()) })
+		It("should record the reason of the requeue", func() {
+			Expect(receivedEvents(recorder)).To(ContainElement(And(HavePrefix(corev1.EventTypeNormal), ContainSubstring(string(RequeueRemoteChanged)))))
+		})
+		It("should skip the stale TunnelEndpoint creation", func() { Expect(listTunnelEndpoints()).To(BeEmpty()) })
+	})
+
+	When("only the remote cluster remapped the PodCIDR", func() {
+		BeforeEach(func() { local.Status.PodCIDRNAT = "10.50.0.0/16" })
+
+		It("should succeed", func() { Expect(err).ToNot(HaveOccurred()) })
+		It("should record an asymmetric NAT warning event", func() {
+			Expect(recorder.Events).To(Receive(And(HavePrefix(corev1.EventTypeWarning), ContainSubstring("AsymmetricNAT"))))
+		})
+		It("should still create the TunnelEndpoint", func() { Expect(listTunnelEndpoints()).To(HaveLen(1)) })
+	})
+
+	When("neither cluster remapped the PodCIDR", func() {
+		BeforeEach(func() { local.Status.PodCIDRNAT = consts.DefaultCIDRValue })
+
+		It("should succeed", func() { Expect(err).ToNot(HaveOccurred()) })
+		It("should not record any warning event", func() { Expect(recorder.Events).ToNot(Receive()) })
+		It("should create the TunnelEndpoint", func() { Expect(listTunnelEndpoints()).To(HaveLen(1)) })
+	})
+
+	When("the NAT decision events are enabled", func() {
+		BeforeEach(func() {
+			natDecisionEvents = true
+			local.Status.PodCIDRNAT = consts.DefaultCIDRValue
+		})
+
+		When("the remote PodCIDR does not overlap", func() {
+			It("should succeed", func() { Expect(err).ToNot(HaveOccurred()) })
+			It("should record a NATDisabled event", func() {
+				Expect(recorder.Events).To(Receive(And(HavePrefix(corev1.EventTypeNormal), ContainSubstring("NATDisabled"),
+					ContainSubstring(remoteNetworkConfig().Spec.PodCIDR))))
+				Expect(recorder.Events).ToNot(Receive())
+			})
+		})
+
+		When("the remote PodCIDR overlaps", func() {
+			BeforeEach(func() {
+				ipam = &remappingIPAM{podCIDR: "10.61.0.0/16"}
+				local.Status.PodCIDRNAT = "10.50.0.0/16"
+			})
+
+			It("should succeed", func() { Expect(err).ToNot(HaveOccurred()) })
+			It("should record a NATEnabled event reporting the remapping", func() {
+				Expect(recorder.Events).To(Receive(And(HavePrefix(corev1.EventTypeNormal), ContainSubstring("NATEnabled"),
+					ContainSubstring("PodCIDR %s overlaps", remoteNetworkConfig().Spec.PodCIDR), ContainSubstring("10.61.0.0/16"))))
+				Expect(recorder.Events).ToNot(Receive())
+			})
+		})
+
+		When("the NAT configuration is already up-to-date", func() {
+			JustBeforeEach(func() {
+				Expect(recorder.Events).To(Receive(ContainSubstring("NATDisabled")))
+				_, err = tec.processNetworkConfig(ctx, clusterID, namespace)
+			})
+
+			It("should succeed", func() { Expect(err).ToNot(HaveOccurred()) })
+			It("should not record the event again", func() { Expect(recorder.Events).ToNot(Receive()) })
+		})
+	})
+
+	When("the NAT decision events are disabled", func() {
+		BeforeEach(func() { local.Status.PodCIDRNAT = consts.DefaultCIDRValue })
+
+		It("should not record any event", func() { Expect(recorder.Events).ToNot(Receive()) })
+	})
+
+	When("the local NetworkConfig has not yet been processed by the remote cluster", func() {
+		BeforeEach(func() { local.Status = netv1alpha1.NetworkConfigStatus{} })
+
+		It("should succeed", func() { Expect(err).ToNot(HaveOccurred()) })
+		It("should requeue the request after a short backoff", func() {
+			Expect(res.RequeueAfter).To(Equal(DefaultUnprocessedRequeueInterval))
+		})
+		It("should record the reason of the requeue", func() {
+			Expect(receivedEvents(recorder)).To(ContainElement(And(HavePrefix(corev1.EventTypeNormal), ContainSubstring(string(RequeueWaitingForRemote)))))
+		})
+		It("should not create the TunnelEndpoint", func() { Expect(listTunnelEndpoints()).To(BeEmpty()) })
+	})
+
+	When("the remote PodCIDR is malformed", func() {
+		BeforeEach(func() { remote.Spec.PodCIDR = "10.100.0.0/33" })
+
+		It("should succeed, as retrying would not help", func() { Expect(err).ToNot(HaveOccurred()) })
+		It("should not requeue the request", func() { Expect(res).To(Equal(ctrl.Result{})) })
+		It("should record a warning event", func() {
+			Expect(recorder.Events).To(Receive(And(HavePrefix(corev1.EventTypeWarning), ContainSubstring("invalid PodCIDR"))))
+		})
+		It("should not mark the remote NetworkConfig as processed", func() {
+			current, err := getRemoteNetworkConfig(ctx, cl.Client)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(current.Status.Processed).To(BeFalse())
+		})
+		It("should not create the TunnelEndpoint", func() { Expect(listTunnelEndpoints()).To(BeEmpty()) })
+	})
+
+	When("the IPManager is not set", func() {
+		BeforeEach(func() { ipam = nil })
+
+		It("should fail with a clear error, rather than panicking", func() { Expect(err).To(MatchError(errIPManagerUnset)) })
+		It("should not create the TunnelEndpoint", func() { Expect(listTunnelEndpoints()).To(BeEmpty()) })
+	})
+})
+
+var _ = Describe("Controller setup", func() {
+	When("the IPManager is not set", func() {
+		It("should fail with a clear error", func() {
+			tec := &TunnelEndpointCreator{Scheme: scheme.Scheme}
+			Expect(tec.SetupWithManager(nil)).To(MatchError(errIPManagerUnset))
+		})
+	})
+
+	When("the concurrency is not configured", func() {
+		It("should apply the default concurrency", func() {
+			tec := &TunnelEndpointCreator{}
+			Expect(tec.controllerOptions().MaxConcurrentReconciles).To(Equal(DefaultMaxConcurrentReconciles))
+		})
+	})
+
+	When("the concurrency is configured", func() {
+		It("should apply the configured concurrency", func() {
+			tec := &TunnelEndpointCreator{MaxConcurrentReconciles: 8}
+			Expect(tec.controllerOptions().MaxConcurrentReconciles).To(Equal(8))
+		})
+	})
+})
+
+var _ = Describe("Per-cluster locking", func() {
+	var tec *TunnelEndpointCreator
+
+	BeforeEach(func() { tec = &TunnelEndpointCreator{} })
+
+	It("should serialize the processing of the same cluster", func() {
+		unlock := tec.lockCluster("cluster")
+		acquired := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			tec.lockCluster("cluster")()
+			close(acquired)
+		}()
+
+		Consistently(acquired, "100ms").ShouldNot(BeClosed())
+		unlock()
+		Eventually(acquired).Should(BeClosed())
+	})
+
+	It("should not serialize the processing of different clusters", func() {
+		defer tec.lockCluster("cluster")()
+		acquired := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			tec.lockCluster("another-cluster")()
+			close(acquired)
+		}()
+
+		Eventually(acquired).Should(BeClosed())
+	})
+})
+
+func getRemoteNetworkConfig(ctx context.Context, cl client.Client) (*netv1alpha1.NetworkConfig, error) {
+	var netcfgs netv1alpha1.NetworkConfigList
+	if err := cl.List(ctx, &netcfgs, client.MatchingLabels{consts.ReplicationOriginLabel: clusterID}); err != nil {
+		return nil, err
+	}
+	return &netcfgs.Items[0], nil
+}
+
+var _ = Describe("Self-referential NetworkConfigs", func() {
+	const localClusterID = "local-cluster-id"
+
+	var (
+		ctx     context.Context
+		cl      client.Client
+		tec     *TunnelEndpointCreator
+		netcfg  *netv1alpha1.NetworkConfig
+		res     ctrl.Result
+		err     error
+		updated netv1alpha1.NetworkConfig
+	)
+
+	BeforeEach(func() { ctx = context.Background() })
+
+	JustBeforeEach(func() {
+		cl = fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(netcfg).Build()
+		tec = &TunnelEndpointCreator{Client: cl, Scheme: scheme.Scheme, IPManager: &fakeIPAM{}, LocalClusterID: localClusterID}
+		res, err = tec.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(netcfg)})
+		Expect(cl.Get(ctx, client.ObjectKeyFromObject(netcfg), &updated)).To(Succeed())
+	})
+
+	When("a local NetworkConfig refers to the local cluster", func() {
+		BeforeEach(func() {
+			netcfg = localNetworkConfig()
+			netcfg.Spec.RemoteCluster.ClusterID = localClusterID
+		})
+
+		It("should succeed", func() { Expect(err).ToNot(HaveOccurred()) })
+		It("should not requeue", func() { Expect(res).To(Equal(ctrl.Result{})) })
+		It("should ignore the NetworkConfig", func() { Expect(updated.GetFinalizers()).To(BeEmpty()) })
+	})
+
+	When("a remote NetworkConfig originates from the local cluster", func() {
+		BeforeEach(func() {
+			netcfg = remoteNetworkConfig()
+			netcfg.Labels[consts.ReplicationOriginLabel] = localClusterID
+		})
+
+		It("should succeed", func() { Expect(err).ToNot(HaveOccurred()) })
+		It("should not requeue", func() { Expect(res).To(Equal(ctrl.Result{})) })
+		It("should ignore the NetworkConfig", func() { Expect(updated.GetFinalizers()).To(BeEmpty()) })
+	})
+
+	When("a NetworkConfig refers to a remote cluster", func() {
+		BeforeEach(func() { netcfg = localNetworkConfig() })
+
+		It("should succeed", func() { Expect(err).ToNot(HaveOccurred()) })
+		It("should process the NetworkConfig", func() { Expect(updated.GetFinalizers()).ToNot(BeEmpty()) })
+	})
+})
+
+var _ = Describe("TunnelEndpoint namespace", func() {
+	const tepNamespace = "liqo-tunnels"
+
+	var (
+		ctx context.Context
+		tec *TunnelEndpointCreator
+	)
+
+	BeforeEach(func() {
+		ctx = trace.ContextWithTrace(context.Background(), trace.New("Reconcile"))
+		cl := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(localNetworkConfig(), remoteNetworkConfig()).Build()
+		tec = &TunnelEndpointCreator{Client: cl, Scheme: scheme.Scheme, IPManager: &fakeIPAM{}, TunnelEndpointNamespace: tepNamespace}
+
+		_, err := tec.processNetworkConfig(ctx, clusterID, namespace)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("should create the TunnelEndpoint in the configured namespace", func() {
+		tep, err := getters.GetTunnelEndpoint(ctx, tec.Client, &remoteIdentity, tepNamespace)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(tep.GetNamespace()).To(Equal(tepNamespace))
+		Expect(tep.Spec.RemotePodCIDR).To(Equal(remoteNetworkConfig().Spec.PodCIDR))
+		// Cross-namespace owner references are not allowed.
+		Expect(tep.GetOwnerReferences()).To(BeEmpty())
+	})
+
+	It("should not create the TunnelEndpoint in the namespace of the NetworkConfigs", func() {
+		_, err := getters.GetTunnelEndpoint(ctx, tec.Client, &remoteIdentity, namespace)
+		Expect(err).To(BeNotFound())
+	})
+
+	It("should repair the TunnelEndpoint in the configured namespace", func() {
+		repaired, err := tec.RepairTunnelEndpoint(ctx, clusterID, namespace)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(repaired).To(BeFalse())
+	})
+
+	It("should delete the TunnelEndpoint from the configured namespace", func() {
+		Expect(tec.deleteTunEndpoint(ctx, localNetworkConfig())).To(Succeed())
+		_, err := getters.GetTunnelEndpoint(ctx, tec.Client, &remoteIdentity, tepNamespace)
+		Expect(err).To(BeNotFound())
+	})
+
+	When("the TunnelEndpoint is deleted", func() {
+		var requests []reconcile.Request
+
+		BeforeEach(func() {
+			tep, err := getters.GetTunnelEndpoint(ctx, tec.Client, &remoteIdentity, tepNamespace)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tec.Client.Delete(ctx, tep)).To(Succeed())
+			// Emulate the delete event observed by the controller watch.
+			requests = tec.tunnelEndpointToNetworkConfigs(tep)
+		})
+
+		It("should enqueue the local NetworkConfig", func() {
+			Expect(requests).To(ConsistOf(reconcile.Request{NamespacedName: client.ObjectKeyFromObject(localNetworkConfig())}))
+		})
+
+		It("should recreate the TunnelEndpoint in the configured namespace upon reconciliation", func() {
+			// The first reconciliation adds the finalizer to the NetworkConfig, which is then processed by the second one.
+			for i := 0; i < 2; i++ {
+				for _, request := range requests {
+					_, err := tec.Reconcile(ctx, request)
+					Expect(err).ToNot(HaveOccurred())
+				}
+			}
+
+			tep, err := getters.GetTunnelEndpoint(ctx, tec.Client, &remoteIdentity, tepNamespace)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tep.Spec.RemotePodCIDR).To(Equal(remoteNetworkConfig().Spec.PodCIDR))
+		})
+	})
+
+	When("an unrelated TunnelEndpoint is modified", func() {
+		It("should not enqueue any NetworkConfig", func() {
+			unrelated := &netv1alpha1.TunnelEndpoint{ObjectMeta: metav1.ObjectMeta{Name: "unrelated", Namespace: tepNamespace,
+				Labels: map[string]string{consts.ClusterIDLabelName: "unrelated-cluster-id"}}}
+			Expect(tec.tunnelEndpointToNetworkConfigs(unrelated)).To(BeEmpty())
+		})
+	})
+})
+
+var _ = Describe("NetworkConfig deletion", func() {
+	var (
+		ctx   context.Context
+		cl    client.Client
+		tec   *TunnelEndpointCreator
+		local *netv1alpha1.NetworkConfig
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		local = localNetworkConfig()
+		cl = fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(local, remoteNetworkConfig()).Build()
+		tec = &TunnelEndpointCreator{Client: cl, Scheme: scheme.Scheme, IPManager: &fakeIPAM{}}
+
+		// Add the finalizer, and create the TunnelEndpoint.
+		Expect(tec.ReconcileOnce(ctx, local.GetName())).Error().ToNot(HaveOccurred())
+		Expect(tec.ReconcileOnce(ctx, local.GetName())).Error().ToNot(HaveOccurred())
+		Expect(getters.GetTunnelEndpoint(ctx, cl, &remoteIdentity, namespace)).Error().ToNot(HaveOccurred())
+	})
+
+	JustBeforeEach(func() {
+		Expect(cl.Delete(ctx, local)).To(Succeed())
+		Expect(tec.ReconcileOnce(ctx, local.GetName())).Error().ToNot(HaveOccurred())
+	})
+
+	It("should delete the TunnelEndpoint", func() {
+		_, err := getters.GetTunnelEndpoint(ctx, cl, &remoteIdentity, namespace)
+		Expect(err).To(BeNotFound())
+	})
+
+	It("should remove the finalizer", func() {
+		Expect(cl.Get(ctx, client.ObjectKeyFromObject(local), &netv1alpha1.NetworkConfig{})).To(BeNotFound())
+	})
+
+	When("the TunnelEndpoint has already been deleted", func() {
+		BeforeEach(func() {
+			tep, err := getters.GetTunnelEndpoint(ctx, cl, &remoteIdentity, namespace)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cl.Delete(ctx, tep)).To(Succeed())
+		})
+
+		It("should remove the finalizer", func() {
+			Expect(cl.Get(ctx, client.ObjectKeyFromObject(local), &netv1alpha1.NetworkConfig{})).To(BeNotFound())
+		})
+	})
+})
+
+var _ = Describe("Custom replication labels", func() {
+	const (
+		requestedLabel   = "example.com/replicate"
+		destinationLabel = "example.com/destination"
+	)
+
+	var (
+		ctx     context.Context
+		cl      client.Client
+		tec     *TunnelEndpointCreator
+		local   *netv1alpha1.NetworkConfig
+		updated netv1alpha1.NetworkConfig
+		err     error
+	)
+
+	BeforeEach(func() {
+		ctx = trace.ContextWithTrace(context.Background(), trace.New("Reconcile"))
+		local = localNetworkConfig()
+		local.Labels = map[string]string{requestedLabel: "true", destinationLabel: clusterID}
+		// Make sure the finalizer is already present, so that the NetworkConfig gets processed immediately.
+		local.Finalizers = []string{"tunnelendpointcreator." + consts.FinalizersSuffix}
+
+		cl = fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(local, remoteNetworkConfig()).Build()
+		tec = &TunnelEndpointCreator{
+			Client: cl, Scheme: scheme.Scheme, IPManager: &fakeIPAM{},
+			ReplicationLabels: netcfgcreator.ReplicationLabels{Requested: requestedLabel, Destination: destinationLabel},
+		}
+	})
+
+	JustBeforeEach(func() {
+		_, err = tec.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(local)})
+		Expect(cl.Get(ctx, client.ObjectKeyFromObject(local), &updated)).To(Succeed())
+	})
+
+	It("should succeed", func() { Expect(err).ToNot(HaveOccurred()) })
+	It("should identify the local NetworkConfig and create the TunnelEndpoint", func() {
+		tep, err := getters.GetTunnelEndpoint(ctx, cl, &remoteIdentity, namespace)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(tep.Spec.LocalNATPodCIDR).To(Equal(local.Status.PodCIDRNAT))
+	})
+
+	When("the default label keys are used", func() {
+		BeforeEach(func() { tec.ReplicationLabels = netcfgcreator.ReplicationLabels{} })
+
+		It("should not find the local NetworkConfig", func() {
+			_, err := getters.GetTunnelEndpoint(ctx, cl, &remoteIdentity, namespace)
+			Expect(err).To(BeNotFound())
+		})
+	})
+})
+
+var _ = Describe("Requeue reasons", func() {
+	var (
+		ctx      context.Context
+		cl       client.Client
+		ipam     liqonetIpam.Ipam
+		recorder *record.FakeRecorder
+		remote   *netv1alpha1.NetworkConfig
+		err      error
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		remote = remoteNetworkConfig()
+		remote.Status = netv1alpha1.NetworkConfigStatus{}
+		// Make sure the finalizer is already present, so that the NetworkConfig gets processed immediately.
+		remote.Finalizers = []string{"tunnelendpointcreator." + consts.FinalizersSuffix}
+		recorder = record.NewFakeRecorder(10)
+		ipam = &fakeIPAM{}
+		cl = fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(localNetworkConfig(), remote).Build()
+	})
+
+	JustBeforeEach(func() {
+		tec := &TunnelEndpointCreator{Client: cl, Scheme: scheme.Scheme, IPManager: ipam, EventRecorder: recorder}
+		_, err = tec.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(remote)})
+	})
+
+	When("the network pools are exhausted", func() {
+		BeforeEach(func() { ipam = &exhaustedIPAM{} })
+
+		It("should fail", func() { Expect(err).To(MatchError(liqonetIpam.ErrNoNetworksAvailable)) })
+		It("should count the failure by cause", func() {
+			Expect(promtestutil.ToFloat64(reconcileErrorsMetric.WithLabelValues(string(RequeuePoolExhausted)))).To(BeNumerically(">", 0))
+		})
+		It("should record the reason of the requeue", func() {
+			Expect(recorder.Events).To(Receive(And(HavePrefix(corev1.EventTypeWarning), ContainSubstring(string(RequeuePoolExhausted)))))
+		})
+		It("should explain the exhaustion in the status of the NetworkConfig", func() {
+			var updated netv1alpha1.NetworkConfig
+			Expect(cl.Get(ctx, client.ObjectKeyFromObject(remote), &updated)).To(Succeed())
+			Expect(updated.Status.Processed).To(BeFalse())
+			Expect(updated.Status.StatusMessage).To(ContainSubstring("No networks available to remap PodCIDR %s", remote.Spec.PodCIDR))
+			Expect(updated.Status.NATEnabled).To(Equal(netv1alpha1.NATEnabledError))
+			condition := meta.FindStatusCondition(updated.Status.Conditions, netv1alpha1.NetworkConfigPoolsExhaustedCondition)
+			Expect(condition).ToNot(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+			Expect(condition.Message).To(Equal(updated.Status.StatusMessage))
+		})
+		It("should clear the status message once networks become available", func() {
+			tec := &TunnelEndpointCreator{Client: cl, Scheme: scheme.Scheme, IPManager: &fakeIPAM{}}
+			_, err = tec.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(remote)})
+			Expect(err).ToNot(HaveOccurred())
+
+			var updated netv1alpha1.NetworkConfig
+			Expect(cl.Get(ctx, client.ObjectKeyFromObject(remote), &updated)).To(Succeed())
+			Expect(updated.Status.Processed).To(BeTrue())
+			Expect(updated.Status.StatusMessage).To(BeEmpty())
+			Expect(updated.Status.NATEnabled).ToNot(Equal(netv1alpha1.NATEnabledError))
+			Expect(meta.FindStatusCondition(updated.Status.Conditions, netv1alpha1.NetworkConfigPoolsExhaustedCondition)).To(BeNil())
+		})
+	})
+
+	When("the NetworkConfig is concurrently modified", func() {
+		BeforeEach(func() { cl = &conflictingClient{Client: cl} })
+
+		It("should fail", func() { Expect(apierrors.IsConflict(err)).To(BeTrue()) })
+		It("should record the reason of the requeue", func() {
+			Expect(recorder.Events).To(Receive(And(HavePrefix(corev1.EventTypeWarning), ContainSubstring(string(RequeueConflict)))))
+		})
+	})
+
+	When("any other error occurs", func() {
+		BeforeEach(func() { ipam = nil })
+
+		It("should fail", func() { Expect(err).To(MatchError(errIPManagerUnset)) })
+		It("should record the reason of the requeue", func() {
+			Expect(recorder.Events).To(Receive(And(HavePrefix(corev1.EventTypeWarning), ContainSubstring(string(RequeueError)))))
+		})
+	})
+
+	When("the reconciliation succeeds", func() {
+		It("should succeed", func() { Expect(err).ToNot(HaveOccurred()) })
+		It("should not record any requeue reason", func() {
+			events := receivedEvents(recorder)
+			for _, reason := range []RequeueReason{RequeueWaitingForRemote, RequeueRemoteChanged, RequeueConflict, RequeuePoolExhausted, RequeueError} {
+				Expect(events).ToNot(ContainElement(ContainSubstring(" %s ", reason)))
+			}
+		})
+	})
+})
+
+var _ = Describe("Finalizer addition conflicts", func() {
+	var (
+		ctx      context.Context
+		tec      *TunnelEndpointCreator
+		recorder *record.FakeRecorder
+		interval time.Duration
+		res      ctrl.Result
+		err      error
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		interval = 0
+		recorder = record.NewFakeRecorder(10)
+	})
+
+	JustBeforeEach(func() {
+		cl := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(localNetworkConfig()).Build()
+		tec = &TunnelEndpointCreator{Client: &conflictingUpdateClient{Client: cl}, Scheme: scheme.Scheme, IPManager: &fakeIPAM{},
+			EventRecorder: recorder, FinalizerConflictRequeueInterval: interval}
+		res, err = tec.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(localNetworkConfig())})
+	})
+
+	When("the requeue is not configured", func() {
+		It("should succeed", func() { Expect(err).ToNot(HaveOccurred()) })
+		It("should not requeue", func() { Expect(res).To(Equal(ctrl.Result{})) })
+	})
+
+	When("the requeue is configured", func() {
+		BeforeEach(func() { interval = 500 * time.Millisecond })
+
+		It("should succeed", func() { Expect(err).ToNot(HaveOccurred()) })
+		It("should requeue after the configured interval", func() { Expect(res).To(Equal(ctrl.Result{RequeueAfter: interval})) })
+		It("should record the reason of the requeue", func() {
+			Expect(recorder.Events).To(Receive(And(HavePrefix(corev1.EventTypeWarning), ContainSubstring(string(RequeueConflict)))))
+		})
+	})
+})
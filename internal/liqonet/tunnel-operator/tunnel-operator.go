@@ -72,6 +72,10 @@ type TunnelController struct {
 	readyClustersMutex   *sync.Mutex
 	readyClusters        map[string]struct{}
 	updateStatusInterval time.Duration
+	mtu                  int
+
+	// OnTunnelReady, if set, is invoked each time the connection status of a TunnelEndpoint transitions to connected.
+	OnTunnelReady func(tep *netv1alpha1.TunnelEndpoint)
 }
 
 // cluster-role
@@ -100,6 +104,7 @@ func NewTunnelController(podIP, namespace string, er record.EventRecorder, k8sCl
 		gatewayNetns:         gatewayNetns,
 		hostNetns:            hostNetns,
 		updateStatusInterval: updateStatusInterval,
+		mtu:                  mtu,
 	}
 
 	err := tc.SetUpTunnelDrivers(tunnel.Config{
@@ -359,10 +364,12 @@ func (tc *TunnelController) forgeConncheckUpdateStatus(ctx context.Context, req
 				Value:     liqonetutils.FormatLatency(latency),
 				Timestamp: metav1.Time{Time: timestamp},
 			}
+			previous := tep.Status.Connection.Status
 			tep.Status.Connection = conn
 			if err := tc.Client.Status().Update(ctx, tep); err != nil {
 				return fmt.Errorf("unable to update resource %s: %w", req.String(), err)
 			}
+			tc.notifyTunnelReady(previous, tep)
 		}
 		return nil
 	}
@@ -535,16 +542,20 @@ func (tc *TunnelController) setUpGWNetns(hostVethName, gatewayVethName string, v
 }
 
 func (tc *TunnelController) updateStatus(con *netv1alpha1.Connection, tep *netv1alpha1.TunnelEndpoint) error {
+	mtu := liqonetutils.EffectiveMTU(tep, tc.mtu)
 	if reflect.DeepEqual(*con, tep.Status.Connection) && tep.Status.GatewayIP == tc.podIP &&
-		tep.Status.VethIFaceIndex == tc.hostVeth.Index && tep.Status.VethIP == liqoconst.GatewayVethIPAddr {
+		tep.Status.VethIFaceIndex == tc.hostVeth.Index && tep.Status.VethIP == liqoconst.GatewayVethIPAddr &&
+		tep.Status.MTU == mtu {
 		return nil
 	}
 
+	previous := tep.Status.Connection.Status
 	tep.Status.Connection = *con
 	tep.Status.GatewayIP = tc.podIP
 	tep.Status.VethIFaceIndex = tc.hostVeth.Index
 	tep.Status.VethIFaceName = tc.hostVeth.Name
 	tep.Status.VethIP = liqoconst.GatewayVethIPAddr
+	tep.Status.MTU = mtu
 
 	if err := tc.Status().Update(context.Background(), tep); err != nil {
 		if k8sApiErrors.IsConflict(err) {
@@ -555,9 +566,18 @@ func (tc *TunnelController) updateStatus(con *netv1alpha1.Connection, tep *netv1
 		return err
 	}
 
+	tc.notifyTunnelReady(previous, tep)
 	return nil
 }
 
+// notifyTunnelReady invokes the OnTunnelReady hook (if any), in case the connection status transitioned to connected.
+func (tc *TunnelController) notifyTunnelReady(previous netv1alpha1.ConnectionStatus, tep *netv1alpha1.TunnelEndpoint) {
+	if tc.OnTunnelReady == nil || previous == netv1alpha1.Connected || tep.Status.Connection.Status != netv1alpha1.Connected {
+		return
+	}
+	tc.OnTunnelReady(tep)
+}
+
 // cleanupRouteFinalizers removes possible leftover route controller finalizers,
 // which might have not been deleted in case a node is tore down ungracefully.
 func (tc *TunnelController) cleanupRouteFinalizers(ctx context.Context, tep *netv1alpha1.TunnelEndpoint) error {
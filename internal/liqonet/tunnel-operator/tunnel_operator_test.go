@@ -15,11 +15,19 @@
 package tunneloperator
 
 import (
+	"time"
+
 	"github.com/containernetworking/plugins/pkg/ns"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"github.com/vishvananda/netlink"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
 	liqoconst "github.com/liqotech/liqo/pkg/consts"
 )
 
@@ -69,4 +77,59 @@ var _ = Describe("TunnelOperator", func() {
 			})
 		})
 	})
+
+	Describe("the OnTunnelReady hook", func() {
+		var (
+			controller *TunnelController
+			req        ctrl.Request
+			invoked    int
+		)
+
+		BeforeEach(func() {
+			invoked = 0
+			req = ctrl.Request{NamespacedName: types.NamespacedName{Name: "tep", Namespace: namespace}}
+			tep := &netv1alpha1.TunnelEndpoint{ObjectMeta: metav1.ObjectMeta{Name: req.Name, Namespace: req.Namespace}}
+			tep.Status.Connection.Status = netv1alpha1.Connecting
+
+			controller = &TunnelController{
+				Client:               fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(tep).Build(),
+				updateStatusInterval: time.Hour,
+			}
+		})
+
+		When("the hook is not set", func() {
+			It("should update the status without failing", func() {
+				Expect(controller.forgeConncheckUpdateStatus(ctx, req)(true, time.Millisecond, time.Now())).To(Succeed())
+			})
+		})
+
+		When("the hook is set", func() {
+			BeforeEach(func() {
+				controller.OnTunnelReady = func(tep *netv1alpha1.TunnelEndpoint) {
+					Expect(tep.Status.Connection.Status).To(Equal(netv1alpha1.Connected))
+					invoked++
+				}
+			})
+
+			It("should be invoked exactly once when the tunnel becomes ready", func() {
+				update := controller.forgeConncheckUpdateStatus(ctx, req)
+				Expect(update(true, time.Millisecond, time.Now())).To(Succeed())
+				Expect(update(true, time.Millisecond, time.Now().Add(2*time.Hour))).To(Succeed())
+				Expect(invoked).To(Equal(1))
+			})
+
+			It("should be invoked again after the connection is lost and restored", func() {
+				update := controller.forgeConncheckUpdateStatus(ctx, req)
+				Expect(update(true, time.Millisecond, time.Now())).To(Succeed())
+				Expect(update(false, time.Millisecond, time.Now())).To(Succeed())
+				Expect(update(true, time.Millisecond, time.Now())).To(Succeed())
+				Expect(invoked).To(Equal(2))
+			})
+
+			It("should not be invoked if the connection fails", func() {
+				Expect(controller.forgeConncheckUpdateStatus(ctx, req)(false, time.Millisecond, time.Now())).To(Succeed())
+				Expect(invoked).To(BeZero())
+			})
+		})
+	})
 })
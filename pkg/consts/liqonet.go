@@ -93,6 +93,13 @@ const (
 	//  - the route operator for the vxlan interfaces;
 	//  - the gateway operator for vpn tunnel and veth pair between host network namespace and custom network namespace.
 	DefaultMTU = 1440
+	// MTUAnnotationKey is the annotation which can be set on the local NetworkConfig to override, for the given
+	// remote cluster only, the MTU computed by the network operators. It is propagated to the TunnelEndpoint.
+	MTUAnnotationKey = "net.liqo.io/mtu"
+	// MinMTU is the minimum MTU value accepted as override (i.e., the minimum MTU mandated for IPv4 links).
+	MinMTU = 68
+	// MaxMTU is the maximum MTU value accepted as override.
+	MaxMTU = 65535
 	// GatewayListeningPort port used by the vpn tunnel.
 	GatewayListeningPort = 5871
 	// NotApplicable is a constant used to represent a not applicable value.
@@ -54,6 +54,42 @@ const (
 	// use the same node port on both clusters.
 	ForceRemoteNodePortAnnotationKey = "liqo.io/force-remote-node-port"
 
+	// RemotePortMappingAnnotationKey is the annotation key used to specify how the target ports of a service shall be
+	// remapped in the remote cluster, as a comma-separated list of "local[/protocol]:remote" entries (e.g., "8080:9090,53/UDP:5353").
+	RemotePortMappingAnnotationKey = "liqo.io/remote-port-mapping"
+
+	// ReflectedFromLabelKey is the key of a label added to a reflected Service to record the cluster it originates from.
+	// Services carrying this label are never reflected further, to prevent them from being reflected back to their origin.
+	ReflectedFromLabelKey = "liqo.io/reflected-from"
+
+	// SourceNameAnnotationKey is the annotation key added to a reflected object to record the name of the local object
+	// it originates from, in case the two differ (i.e., a name transformation is configured).
+	SourceNameAnnotationKey = "liqo.io/source-name"
+
+	// SourceResourceVersionAnnotationKey is the annotation key added to a reflected object to record the resource version
+	// of the local object it originates from, to ease the detection of drifts between the two.
+	SourceResourceVersionAnnotationKey = "liqo.io/source-resource-version"
+
+	// SourceFQDNAnnotationKey is the annotation key added to a reflected service to record the cluster-local DNS name
+	// of the local service it originates from, as a hint for the remote consumers. It is purely informative.
+	SourceFQDNAnnotationKey = "liqo.io/source-fqdn"
+
+	// ReflectedHashAnnotationKey is the annotation key added to a reflected object to record the hash of the reflected fields,
+	// so that updates not modifying them can be skipped.
+	ReflectedHashAnnotationKey = "liqo.io/reflected-hash"
+
+	// ReflectionTTLAnnotationKey is the annotation key added to a reflected object to specify the duration (e.g., "10m")
+	// after which it is considered stale, and thus removed, in case it is no longer refreshed by the origin cluster.
+	ReflectionTTLAnnotationKey = "liqo.io/reflection-ttl"
+
+	// ReflectionLastSyncAnnotationKey is the annotation key added to a reflected object to record the time (in RFC3339 format)
+	// it has been last refreshed by the origin cluster, in case a reflection TTL is configured.
+	ReflectionLastSyncAnnotationKey = "liqo.io/reflection-last-sync"
+
+	// TargetClustersAnnotationKey is the annotation key used to specify the comma-separated list of the remote clusters
+	// (either by ID or by name) a Service shall be reflected to. Services without this annotation are reflected to every cluster.
+	TargetClustersAnnotationKey = "liqo.io/target-clusters"
+
 	// SkipReflectionAnnotationKey is the annotation key used to indicate that a given object should not be reflected into a remote cluster.
 	SkipReflectionAnnotationKey = "liqo.io/skip-reflection"
 
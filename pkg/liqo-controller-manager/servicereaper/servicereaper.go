@@ -0,0 +1,94 @@
+// Copyright 2019-2023 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package servicereaper
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+
+	"github.com/liqotech/liqo/pkg/virtualKubelet/forge"
+)
+
+// +kubebuilder:rbac:groups=core,resources=services,verbs=list;delete
+
+// ServiceReaper periodically deletes the reflected Services which have not been refreshed by the origin cluster
+// within their TTL (e.g., since the origin cluster went offline without unpeering), and would otherwise linger.
+type ServiceReaper struct {
+	client   kubernetes.Interface
+	interval time.Duration
+}
+
+// NewServiceReaper returns a new ServiceReaper, checking the reflected Services with the given interval.
+func NewServiceReaper(client kubernetes.Interface, interval time.Duration) *ServiceReaper {
+	return &ServiceReaper{client: client, interval: interval}
+}
+
+// Start periodically reaps the stale reflected Services, until the context is canceled.
+func (sr *ServiceReaper) Start(ctx context.Context) error {
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if _, err := sr.Reap(ctx, time.Now()); err != nil {
+			klog.Errorf("Failed to reap the stale reflected Services: %v", err)
+		}
+	}, sr.interval)
+	return nil
+}
+
+// Reap deletes the reflected Services which became stale at the given time, and returns the number of deleted ones.
+func (sr *ServiceReaper) Reap(ctx context.Context, now time.Time) (int, error) {
+	// The selection is restricted to the reflected Services, as identified by the origin label.
+	req, err := labels.NewRequirement(forge.LiqoOriginClusterIDKey, selection.Exists, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	services, err := sr.client.CoreV1().Services(corev1.NamespaceAll).List(ctx,
+		metav1.ListOptions{LabelSelector: labels.NewSelector().Add(*req).String()})
+	if err != nil {
+		return 0, fmt.Errorf("failed to list the reflected Services: %w", err)
+	}
+
+	reaped := 0
+	for i := range services.Items {
+		svc := &services.Items[i]
+		if !svc.GetDeletionTimestamp().IsZero() || !forge.IsReflectionStale(svc, now) {
+			continue
+		}
+
+		klog.Infof("Deleting reflected Service %q (origin: %s), as no longer refreshed within its TTL",
+			klog.KObj(svc), svc.GetLabels()[forge.LiqoOriginClusterIDKey])
+		// The preconditions guarantee that the Service has not been refreshed in the meanwhile.
+		uid, resourceVersion := svc.GetUID(), svc.GetResourceVersion()
+		err := sr.client.CoreV1().Services(svc.GetNamespace()).Delete(ctx, svc.GetName(), metav1.DeleteOptions{
+			Preconditions: &metav1.Preconditions{UID: &uid, ResourceVersion: &resourceVersion}})
+		if err != nil && !kerrors.IsNotFound(err) && !kerrors.IsConflict(err) {
+			return reaped, fmt.Errorf("failed to delete reflected Service %q: %w", klog.KObj(svc), err)
+		}
+		if err == nil {
+			reaped++
+		}
+	}
+
+	return reaped, nil
+}
@@ -0,0 +1,99 @@
+// Copyright 2019-2023 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package servicereaper
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+
+	liqoconst "github.com/liqotech/liqo/pkg/consts"
+	"github.com/liqotech/liqo/pkg/virtualKubelet/forge"
+)
+
+var _ = Describe("ServiceReaper", func() {
+	const (
+		namespace = "namespace"
+		ttl       = 10 * time.Minute
+	)
+
+	var (
+		ctx    context.Context
+		client kubernetes.Interface
+		reaper *ServiceReaper
+		now    time.Time
+
+		reaped int
+		err    error
+	)
+
+	service := func(name string, reflected bool, annotations map[string]string) *corev1.Service {
+		svc := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Annotations: annotations}}
+		if reflected {
+			svc.SetLabels(map[string]string{forge.LiqoOriginClusterIDKey: "origin-cluster-id"})
+		}
+		return svc
+	}
+
+	withTTL := func(lastSync time.Time) map[string]string {
+		return map[string]string{
+			liqoconst.ReflectionTTLAnnotationKey:      ttl.String(),
+			liqoconst.ReflectionLastSyncAnnotationKey: lastSync.UTC().Format(time.RFC3339),
+		}
+	}
+
+	exists := func(name string) bool {
+		_, err := client.CoreV1().Services(namespace).Get(ctx, name, metav1.GetOptions{})
+		return err == nil
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		now = time.Now()
+		client = fake.NewSimpleClientset(
+			service("fresh", true, withTTL(now.Add(-ttl/2))),
+			service("stale", true, withTTL(now.Add(-2*ttl))),
+			service("without-ttl", true, nil),
+			service("not-reflected", false, withTTL(now.Add(-2*ttl))),
+		)
+		reaper = NewServiceReaper(client, time.Minute)
+	})
+
+	JustBeforeEach(func() { reaped, err = reaper.Reap(ctx, now) })
+
+	It("should succeed", func() { Expect(err).ToNot(HaveOccurred()) })
+	It("should reap only the stale reflected Service", func() {
+		Expect(reaped).To(Equal(1))
+		Expect(exists("stale")).To(BeFalse())
+	})
+	It("should retain the reflected Services refreshed within the TTL", func() { Expect(exists("fresh")).To(BeTrue()) })
+	It("should retain the reflected Services without TTL", func() { Expect(exists("without-ttl")).To(BeTrue()) })
+	It("should retain the Services which are not reflected", func() { Expect(exists("not-reflected")).To(BeTrue()) })
+
+	When("the TTL of the fresh Service expires as well", func() {
+		BeforeEach(func() { now = now.Add(ttl) })
+
+		It("should reap it", func() {
+			Expect(reaped).To(Equal(2))
+			Expect(exists("fresh")).To(BeFalse())
+		})
+	})
+})
@@ -0,0 +1,79 @@
+// Copyright 2019-2023 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tepwh
+
+import (
+	"context"
+	"net/http"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+	liqonetutils "github.com/liqotech/liqo/pkg/liqonet/utils"
+)
+
+type tepwh struct {
+	decoder *admission.Decoder
+}
+
+// NewValidator returns a new TunnelEndpoint validating webhook.
+func NewValidator() *webhook.Admission {
+	return &webhook.Admission{Handler: &tepwh{}}
+}
+
+// InjectDecoder injects the decoder - this method is used by controller runtime.
+func (w *tepwh) InjectDecoder(decoder *admission.Decoder) error {
+	w.decoder = decoder
+	return nil
+}
+
+// DecodeTunnelEndpoint decodes the TunnelEndpoint from the incoming request.
+func (w *tepwh) DecodeTunnelEndpoint(obj runtime.RawExtension) (*netv1alpha1.TunnelEndpoint, error) {
+	var tep netv1alpha1.TunnelEndpoint
+	err := w.decoder.DecodeRaw(obj, &tep)
+	return &tep, err
+}
+
+// Handle implements the TunnelEndpoint validating webhook logic.
+//
+//nolint:gocritic // The signature of this method is imposed by controller runtime.
+func (w *tepwh) Handle(ctx context.Context, req admission.Request) admission.Response {
+	if req.Operation != admissionv1.Update {
+		return admission.Allowed("")
+	}
+
+	// In case of updates, prevent the mutation of the immutable fields.
+	tepnew, err := w.DecodeTunnelEndpoint(req.Object)
+	if err != nil {
+		klog.Errorf("Failed decoding TunnelEndpoint object: %v", err)
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	tepold, err := w.DecodeTunnelEndpoint(req.OldObject)
+	if err != nil {
+		klog.Errorf("Failed decoding TunnelEndpoint object: %v", err)
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	if err := liqonetutils.ValidateTunnelEndpointUpdate(tepold, tepnew); err != nil {
+		return admission.Denied(err.Error())
+	}
+
+	return admission.Allowed("")
+}
@@ -198,12 +198,12 @@ func (pic *PeerInfoChecker) addNetworkSection(ctx context.Context, rootSection o
 				remoteSectionMsg = fmt.Sprintf("how %q remapped %q", localClusterName, foreignCluster.Name)
 			}
 
-			if nc.Status.PodCIDRNAT == liqoconsts.DefaultCIDRValue {
+			if liqonetutils.IsNATDisabled(nc.Status.PodCIDRNAT) {
 				remotePodCIDRMsg = NotRemappedMsg
 			} else {
 				remotePodCIDRMsg = nc.Status.PodCIDRNAT
 			}
-			if nc.Status.ExternalCIDRNAT == liqoconsts.DefaultCIDRValue {
+			if liqonetutils.IsNATDisabled(nc.Status.ExternalCIDRNAT) {
 				remoteExternalCIDRMsg = NotRemappedMsg
 			} else {
 				remoteExternalCIDRMsg = nc.Status.ExternalCIDRNAT
@@ -227,8 +227,7 @@ func (pic *PeerInfoChecker) addNetworkSection(ctx context.Context, rootSection o
 			networkSection.AddSectionWithDetail("Remote CIDRs", NetworkConfigNotFoundMsg)
 		}
 	}
-	return pic.addVpnSection(ctx, networkSection, foreignCluster.Spec.ClusterIdentity,
-		foreignCluster.Status.TenantNamespace.Local, vpnLocalEndpointAddress)
+	return pic.addVpnSection(ctx, networkSection, foreignCluster.Spec.ClusterIdentity, vpnLocalEndpointAddress)
 }
 
 func (pic *PeerInfoChecker) getVpnEndpointLocalAddress(ctx context.Context) (address string, err error) {
@@ -250,8 +249,9 @@ func (pic *PeerInfoChecker) getVpnEndpointLocalAddress(ctx context.Context) (add
 
 // addVpnSection adds a section about the VPN configuration.
 func (pic *PeerInfoChecker) addVpnSection(ctx context.Context, rootSection output.Section,
-	remoteClusterIdentity discoveryv1alpha1.ClusterIdentity, tenantNamespace, vpnEndpointLocalEnpointAddress string) error {
-	te, err := liqogetters.GetTunnelEndpoint(ctx, pic.options.CRClient, &remoteClusterIdentity, tenantNamespace)
+	remoteClusterIdentity discoveryv1alpha1.ClusterIdentity, vpnEndpointLocalEnpointAddress string) error {
+	// The TunnelEndpoint is looked up in all namespaces, as it may be hosted in a custom one, rather than in the tenant namespace.
+	te, err := liqogetters.GetTunnelEndpoint(ctx, pic.options.CRClient, &remoteClusterIdentity, corev1.NamespaceAll)
 	if err != nil {
 		if kerrors.IsNotFound(err) {
 			rootSection.AddSectionWithDetail("VPN Connection", TunnelEndpointNotFoundMsg)
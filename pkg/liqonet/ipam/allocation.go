@@ -0,0 +1,231 @@
+// Copyright 2019-2023 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipam
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+
+	"inet.af/netaddr"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/strings/slices"
+
+	liqonetutils "github.com/liqotech/liqo/pkg/liqonet/utils"
+)
+
+// AllocationPolicy defines the strategy used to select the networks allocated from the pools in case of remapping.
+type AllocationPolicy string
+
+const (
+	// FirstFitAllocationPolicy selects the network from the first pool with enough free space, following the order of the pools.
+	FirstFitAllocationPolicy AllocationPolicy = "FirstFit"
+	// BestFitAllocationPolicy selects the network from the smallest free block large enough, across all pools,
+	// to preserve the larger blocks for subsequent requests and reduce fragmentation.
+	BestFitAllocationPolicy AllocationPolicy = "BestFit"
+)
+
+// SetAllocationPolicy configures the strategy used to select the networks allocated from the pools.
+func (liqoIPAM *IPAM) SetAllocationPolicy(policy AllocationPolicy) error {
+	switch policy {
+	case FirstFitAllocationPolicy, BestFitAllocationPolicy:
+		liqoIPAM.allocationPolicy = policy
+		klog.Infof("Network allocation policy set to %s", policy)
+		return nil
+	default:
+		return fmt.Errorf("invalid network allocation policy %q", policy)
+	}
+}
+
+// getBestFitNetworkFromPool returns a network with mask length equal to mask, taken
+// from the smallest free block (among all network pools) which is large enough.
+func (liqoIPAM *IPAM) getBestFitNetworkFromPool(mask uint8) (string, error) {
+	network, pool, err := liqoIPAM.selectBestFitNetwork(mask)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := liqoIPAM.ipam.AcquireSpecificChildPrefix(context.TODO(), pool, network.String()); err != nil {
+		return "", fmt.Errorf("cannot acquire prefix %s from prefix %s: %w", network, pool, err)
+	}
+	klog.Infof("Acquired network %s from pool %s", network, pool)
+	return network.String(), nil
+}
+
+// selectBestFitNetwork returns a network with mask length equal to mask, within the smallest free block
+// (among all network pools) which is large enough, along with the corresponding pool, without acquiring it.
+func (liqoIPAM *IPAM) selectBestFitNetwork(mask uint8) (network netaddr.IPPrefix, pool string, err error) {
+	var best netaddr.IPPrefix
+	var bestPool string
+
+	for _, pool := range liqoIPAM.ipamStorage.getPools() {
+		prefix := liqoIPAM.ipam.PrefixFrom(context.TODO(), pool)
+		if prefix == nil {
+			continue
+		}
+
+		for _, available := range prefix.Usage().AvailablePrefixes {
+			block, err := netaddr.ParseIPPrefix(available)
+			if err != nil {
+				return netaddr.IPPrefix{}, "", fmt.Errorf("cannot parse network %s: %w", available, err)
+			}
+
+			// Blocks are CIDR aligned, hence a larger (i.e., shorter mask) block always contains an aligned network of the given size.
+			if block.Bits() > mask || (!best.IsZero() && block.Bits() <= best.Bits()) {
+				continue
+			}
+			best, bestPool = block, pool
+		}
+	}
+
+	if best.IsZero() {
+		return netaddr.IPPrefix{}, "", ErrNoNetworksAvailable
+	}
+	return netaddr.IPPrefixFrom(best.IP(), mask), bestPool, nil
+}
+
+// selectFirstFitNetwork returns the network with mask length equal to mask which would be acquired from the first
+// network pool with enough free space, along with the corresponding pool, without acquiring it. It mirrors the
+// selection performed by the underlying IPAM, which picks the network from the smallest free range of each pool.
+func (liqoIPAM *IPAM) selectFirstFitNetwork(mask uint8) (network netaddr.IPPrefix, pool string, err error) {
+	for _, pool := range liqoIPAM.ipamStorage.getPools() {
+		prefix := liqoIPAM.ipam.PrefixFrom(context.TODO(), pool)
+		if prefix == nil || liqonetutils.GetMask(pool) >= mask {
+			continue
+		}
+
+		var builder netaddr.IPSetBuilder
+		for _, available := range prefix.Usage().AvailablePrefixes {
+			block, err := netaddr.ParseIPPrefix(available)
+			if err != nil {
+				return netaddr.IPPrefix{}, "", fmt.Errorf("cannot parse network %s: %w", available, err)
+			}
+			builder.AddPrefix(block)
+		}
+
+		free, err := builder.IPSet()
+		if err != nil {
+			return netaddr.IPPrefix{}, "", fmt.Errorf("cannot compute the free networks of pool %s: %w", pool, err)
+		}
+		if network, _, ok := free.RemoveFreePrefix(mask); ok {
+			return network, pool, nil
+		}
+	}
+	return netaddr.IPPrefix{}, "", ErrNoNetworksAvailable
+}
+
+// PeekNextSubnet returns the subnet with the given prefix length which would be allocated next from the network pools
+// (e.g., when remapping a network of a remote cluster), according to the configured allocation policy and pools priority,
+// without reserving it. The remap headroom, if configured, is not applied, hence the returned subnet has exactly the given size.
+func (liqoIPAM *IPAM) PeekNextSubnet(prefixLen int) (*net.IPNet, error) {
+	if prefixLen < 0 || prefixLen > 32 {
+		return nil, fmt.Errorf("invalid prefix length %d", prefixLen)
+	}
+
+	selectNetwork := liqoIPAM.selectFirstFitNetwork
+	if liqoIPAM.allocationPolicy == BestFitAllocationPolicy {
+		selectNetwork = liqoIPAM.selectBestFitNetwork
+	}
+
+	network, _, err := selectNetwork(uint8(prefixLen))
+	if err != nil {
+		return nil, err
+	}
+	return network.IPNet(), nil
+}
+
+// SetRemapHeadroom configures the number of additional bits reserved when remapping a network, so that the
+// allocated network is larger than strictly needed (e.g., a /24 network is remapped to a /22 one with 2 bits of
+// headroom), leaving room for future growth. A headroom of zero (the default) leads to exact-fit allocations.
+func (liqoIPAM *IPAM) SetRemapHeadroom(bits uint8) error {
+	if bits > 32 {
+		return fmt.Errorf("invalid remap headroom of %d bits", bits)
+	}
+	liqoIPAM.remapHeadroom = bits
+	klog.Infof("Remap headroom set to %d bits", bits)
+	return nil
+}
+
+// getRemappedNetworkFromPool returns a network to be used as a remapping for a network with mask length
+// equal to mask, enlarged according to the configured headroom.
+func (liqoIPAM *IPAM) getRemappedNetworkFromPool(mask uint8) (string, error) {
+	if liqoIPAM.remapHeadroom > mask {
+		return "", fmt.Errorf("remap headroom of %d bits exceeds the mask length %d", liqoIPAM.remapHeadroom, mask)
+	}
+	network, err := liqoIPAM.getNetworkFromPool(mask - liqoIPAM.remapHeadroom)
+	if errors.Is(err, ErrNoNetworksAvailable) {
+		return "", fmt.Errorf("%w: none of the network pools %v can accommodate a /%d network",
+			err, liqoIPAM.ipamStorage.getPools(), mask-liqoIPAM.remapHeadroom)
+	}
+	return network, err
+}
+
+// getRemappedIPv6Network returns an IPv6 network to be used as a remapping for a network with mask length
+// equal to mask, enlarged according to the configured headroom, taking it from the first IPv6 pool with room for it.
+func (liqoIPAM *IPAM) getRemappedIPv6Network(mask uint8) (string, error) {
+	if liqoIPAM.remapHeadroom > mask {
+		return "", fmt.Errorf("remap headroom of %d bits exceeds the mask length %d", liqoIPAM.remapHeadroom, mask)
+	}
+
+	for _, pool := range liqoIPAM.ipv6Pools {
+		if mappedNetwork, err := liqoIPAM.ipam.AcquireChildPrefix(context.TODO(), pool, mask-liqoIPAM.remapHeadroom); err == nil {
+			klog.Infof("Acquired network %s from IPv6 pool %s", mappedNetwork, pool)
+			return mappedNetwork.String(), nil
+		}
+	}
+	return "", ErrNoNetworksAvailable
+}
+
+// SetPoolsPriority configures the order in which the network pools are used to allocate the remapped networks:
+// the given pools are tried first, in the given order, while the remaining ones are used only once they are exhausted
+// (e.g., a primary pool followed by an overflow one). The priority is honored by the FirstFit allocation policy only,
+// as the BestFit one selects the most appropriate block across all pools.
+func (liqoIPAM *IPAM) SetPoolsPriority(pools []string) error {
+	current := liqoIPAM.ipamStorage.getPools()
+	ordered := make([]string, 0, len(current))
+	for _, pool := range pools {
+		if !slices.Contains(current, pool) {
+			return fmt.Errorf("network pool %s does not exist", pool)
+		}
+		if slices.Contains(ordered, pool) {
+			return fmt.Errorf("network pool %s specified multiple times", pool)
+		}
+		ordered = append(ordered, pool)
+	}
+	for _, pool := range current {
+		if !slices.Contains(ordered, pool) {
+			ordered = append(ordered, pool)
+		}
+	}
+
+	if err := liqoIPAM.ipamStorage.updatePools(ordered); err != nil {
+		return fmt.Errorf("cannot update the network pools: %w", err)
+	}
+	klog.Infof("Network pools priority set to %v", ordered)
+	return nil
+}
+
+// NetworkPool returns the network pool the given network has been allocated from.
+func (liqoIPAM *IPAM) NetworkPool(network string) (string, error) {
+	pool, found, err := liqoIPAM.getPoolFromNetwork(network)
+	if err != nil {
+		return "", fmt.Errorf("cannot retrieve the network pool of network %s: %w", network, err)
+	}
+	if !found {
+		return "", fmt.Errorf("network %s does not belong to any network pool", network)
+	}
+	return pool, nil
+}
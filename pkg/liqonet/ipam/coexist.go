@@ -0,0 +1,100 @@
+// Copyright 2019-2023 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipam
+
+import (
+	"net"
+
+	"inet.af/netaddr"
+
+	liqonetutils "github.com/liqotech/liqo/pkg/liqonet/utils"
+)
+
+// CanCoexist returns whether the two given remote PodCIDRs (e.g., of two prospective peers) could both be used as-is,
+// hence without NAT, given the current reservations. This is the case if they do not overlap each other, nor the local
+// networks, the reserved subnets and the networks allocated to the existing peers. Additionally, each of them shall
+// either be outside of the network pools, or strictly contained in one of them, since networks equal to a pool or
+// spanning across its boundaries are remapped. It does not reserve any network, hence the outcome is a prediction
+// which holds as long as the reservations do not change.
+func (liqoIPAM *IPAM) CanCoexist(a, b *net.IPNet) bool {
+	first, ok := netaddr.FromStdIPNet(a)
+	if !ok {
+		return false
+	}
+	second, ok := netaddr.FromStdIPNet(b)
+	if !ok {
+		return false
+	}
+	first, second = first.Masked(), second.Masked()
+
+	if first.Overlaps(second) {
+		return false
+	}
+
+	reserved := liqoIPAM.reservedPrefixes()
+	return liqoIPAM.usableWithoutNAT(first, reserved) && liqoIPAM.usableWithoutNAT(second, reserved)
+}
+
+// reservedPrefixes returns the networks currently reserved in the local cluster, which would conflict with a remote network:
+// the local PodCIDR, ServiceCIDR and ExternalCIDR, the reserved subnets and the networks allocated to the remote clusters.
+func (liqoIPAM *IPAM) reservedPrefixes() []netaddr.IPPrefix {
+	networks := []string{
+		liqoIPAM.ipamStorage.getPodCIDR(),
+		liqoIPAM.ipamStorage.getServiceCIDR(),
+		liqoIPAM.ipamStorage.getExternalCIDR(),
+	}
+	networks = append(networks, liqoIPAM.ipamStorage.getReservedSubnets()...)
+	for _, subnets := range liqoIPAM.ipamStorage.getClusterSubnets() {
+		networks = append(networks, subnets.RemotePodCIDR, subnets.RemoteExternalCIDR)
+	}
+
+	prefixes := make([]netaddr.IPPrefix, 0, len(networks))
+	for _, network := range networks {
+		if network == emptyCIDR || liqonetutils.IsNATDisabled(network) {
+			continue
+		}
+		prefix, err := netaddr.ParseIPPrefix(network)
+		if err != nil {
+			continue
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes
+}
+
+// usableWithoutNAT returns whether the given remote network would be used as-is, mirroring the behavior of getOrRemapNetwork.
+func (liqoIPAM *IPAM) usableWithoutNAT(network netaddr.IPPrefix, reserved []netaddr.IPPrefix) bool {
+	if overlapsAny(network, reserved) {
+		return false
+	}
+
+	pools := append(append([]string{}, liqoIPAM.ipamStorage.getPools()...), liqoIPAM.ipv6Pools...)
+	for _, pool := range pools {
+		poolPrefix, err := netaddr.ParseIPPrefix(pool)
+		if err != nil {
+			continue
+		}
+		poolPrefix = poolPrefix.Masked()
+
+		if !network.Overlaps(poolPrefix) {
+			continue
+		}
+		// Networks equal to a pool are preferably remapped, while those only partially overlapping it cannot be acquired.
+		if network == poolPrefix || network.Bits() < poolPrefix.Bits() {
+			return false
+		}
+	}
+	return true
+}
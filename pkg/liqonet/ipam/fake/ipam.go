@@ -74,6 +74,13 @@ func (mock *IPAMClient) UnmapEndpointIP(_ context.Context, req *ipam.UnmapReques
 	return &ipam.UnmapResponse{}, nil
 }
 
+// SetLocalRemappedPodCIDR modifies the network the local endpoints are remapped to, discarding the existing
+// translations, to mock the migration of the NAT configuration.
+func (mock *IPAMClient) SetLocalRemappedPodCIDR(localRemappedPodCIDR string) {
+	mock.localRemappedPodCIDR = localRemappedPodCIDR
+	mock.endpoints = make(map[string]string)
+}
+
 // IsEndpointTranslated returns whether the given endpoint has a valid translation.
 func (mock *IPAMClient) IsEndpointTranslated(ip string) bool {
 	_, found := mock.endpoints[ip]
@@ -0,0 +1,61 @@
+// Copyright 2019-2023 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipam
+
+import (
+	"fmt"
+
+	"k8s.io/klog/v2"
+
+	liqoneterrors "github.com/liqotech/liqo/pkg/liqonet/errors"
+	liqonetutils "github.com/liqotech/liqo/pkg/liqonet/utils"
+)
+
+// GetSubnetsPerClusterByUsage behaves as GetSubnetsPerCluster, but detects the overlaps of the PodCIDR at a finer granularity,
+// i.e., considering only usedPodCIDR, the network covering the sub-ranges actually used by the remote cluster. In case
+// it does not overlap with the other networks, it is reserved and returned as is, hence avoiding the remapping even if
+// the whole PodCIDR overlaps. Otherwise, the whole PodCIDR is remapped as usual, so that the remapped network matches its size.
+func (liqoIPAM *IPAM) GetSubnetsPerClusterByUsage(podCIDR, usedPodCIDR, externalCIDR, clusterID string) (
+	mappedPodCIDR, mappedExternalCIDR string, err error) {
+	if liqoIPAM.ipamStorage == nil {
+		return "", "", &liqoneterrors.MissingInit{StructureName: "IPAM"}
+	}
+
+	// The networks of an already configured cluster are returned as is by GetSubnetsPerCluster.
+	subnets, exists := liqoIPAM.ipamStorage.getClusterSubnets()[clusterID]
+	if (exists && subnets.RemotePodCIDR != "" && subnets.RemoteExternalCIDR != "") || usedPodCIDR == podCIDR {
+		return liqoIPAM.GetSubnetsPerCluster(podCIDR, externalCIDR, clusterID)
+	}
+
+	if err := liqonetutils.IsValidCIDR(usedPodCIDR); err != nil {
+		return "", "", fmt.Errorf("used PodCIDR is an invalid CIDR: %w", err)
+	}
+
+	available, err := liqoIPAM.reserveIfAvailable(usedPodCIDR)
+	if err != nil {
+		return "", "", err
+	}
+	if !available {
+		klog.Infof("Used PodCIDR %s of cluster %s overlaps as well, remapping the whole PodCIDR %s", usedPodCIDR, clusterID, podCIDR)
+		return liqoIPAM.GetSubnetsPerCluster(podCIDR, externalCIDR, clusterID)
+	}
+
+	// Release the network, which is reserved again while configuring the subnets of the cluster.
+	if err := liqoIPAM.FreeReservedSubnet(usedPodCIDR); err != nil {
+		return "", "", err
+	}
+	klog.Infof("Used PodCIDR %s of cluster %s does not overlap, reserving it in place of the PodCIDR %s", usedPodCIDR, clusterID, podCIDR)
+	return liqoIPAM.GetSubnetsPerCluster(usedPodCIDR, externalCIDR, clusterID)
+}
@@ -19,6 +19,7 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"sort"
 	"strings"
 	"sync"
 
@@ -50,6 +51,15 @@ type Ipam interface {
 	- Both.
 	*/
 	GetSubnetsPerCluster(podCidr, externalCIDR, clusterID string) (string, string, error)
+	// ReclaimRemappedPodCIDR re-evaluates the previously remapped PodCIDR of a remote cluster, disabling the remapping
+	// and freeing the corresponding subnet in case the given PodCIDR does no longer overlap with the other networks.
+	ReclaimRemappedPodCIDR(podCIDR, clusterID string) (bool, error)
+	// GetSubnetsPerClusterByUsage behaves as GetSubnetsPerCluster, but avoids remapping the PodCIDR of a remote cluster
+	// in case the network covering the sub-ranges it actually uses (i.e., usedPodCIDR) does not overlap with the other networks.
+	GetSubnetsPerClusterByUsage(podCIDR, usedPodCIDR, externalCIDR, clusterID string) (string, string, error)
+	// RenameCluster atomically moves the IPAM configuration of a remote cluster from the old to the new cluster ID,
+	// preserving the reserved subnets and the NAT mappings.
+	RenameCluster(oldID, newID string) error
 	// RemoveClusterConfig deletes the IPAM configuration of a remote cluster,
 	// by freeing networks and removing data structures related to that cluster.
 	RemoveClusterConfig(clusterID string) error
@@ -75,6 +85,14 @@ type Ipam interface {
 	Terminate()
 	// SetSpecificNatMapping sets a specific NAT mapping.
 	SetSpecificNatMapping(oldIPLocal, oldIP, newIP, clusterID string) error
+	// WithTransaction executes the given function within a transaction, rolling back
+	// all the reservations and releases performed through it in case of error.
+	WithTransaction(fn func(tx *AllocTx) error) error
+	// Validate checks the reserved subnets state for internal consistency, returning all the detected inconsistencies.
+	// The liveClusters parameter lists the clusters which are currently associated with a live NetworkConfig.
+	Validate(liveClusters []string) []error
+	// MarshalState serializes the whole IPAM state (i.e., network pools, reservations and cluster subnets) as JSON.
+	MarshalState() ([]byte, error)
 	IpamServer
 }
 
@@ -84,13 +102,20 @@ type IPAM struct {
 	ipamStorage        IpamStorage
 	natMappingInflater natmappinginflater.Interface
 	grpcServer         *grpc.Server
-	mutex              sync.Mutex
+	allocationPolicy   AllocationPolicy
+	remapHeadroom      uint8
+	allocationRecorder AllocationRecorder
+	staticIPOverrides  map[string]string
+	// ipv6Pools are the IPv6 network pools which have been successfully initialized.
+	ipv6Pools        []string
+	mutex            sync.Mutex
+	transactionMutex sync.Mutex
 	UnimplementedIpamServer
 }
 
 // NewIPAM returns a IPAM instance.
 func NewIPAM() *IPAM {
-	return &IPAM{}
+	return &IPAM{allocationPolicy: FirstFitAllocationPolicy, allocationRecorder: NoopAllocationRecorder{}}
 }
 
 // Pools is a constant slice containing private IPv4 networks.
@@ -100,8 +125,18 @@ var Pools = []string{
 	"172.16.0.0/12",
 }
 
+// IPv6Pools is a constant slice containing private IPv6 networks (i.e., unique local addresses), used to allocate
+// the IPv6 networks of the remote clusters (e.g., in dual-stack setups) and their remappings in case of conflicts.
+var IPv6Pools = []string{
+	"fd00::/8",
+}
+
 const emptyCIDR = ""
 
+// ErrNoNetworksAvailable is returned when the network pools cannot satisfy the allocation of a network (e.g., to remap
+// the CIDRs of a remote cluster), as exhausted or fragmented.
+var ErrNoNetworksAvailable = errors.New("no networks available")
+
 // Init uses the Ipam resource to retrieve and allocate reserved networks.
 func (liqoIPAM *IPAM) Init(pools []string, dynClient dynamic.Interface, listeningPort int) error {
 	var err error
@@ -115,7 +150,13 @@ func (liqoIPAM *IPAM) Init(pools []string, dynClient dynamic.Interface, listenin
 	// Get resource
 	ipamPools := liqoIPAM.ipamStorage.getPools()
 
-	// Have network pools been already set? If not, take them from caller
+	// Have network pools been already set? If so, reconcile them with the configured ones.
+	if len(ipamPools) > 0 {
+		if err := liqoIPAM.reconcilePools(ipamPools, pools); err != nil {
+			return err
+		}
+	}
+	// If not, take them from caller
 	if len(ipamPools) == 0 {
 		for _, network := range pools {
 			if _, err := liqoIPAM.ipam.NewPrefix(context.TODO(), network); err != nil {
@@ -129,6 +170,16 @@ func (liqoIPAM *IPAM) Init(pools []string, dynClient dynamic.Interface, listenin
 			return fmt.Errorf("cannot set pools: %w", err)
 		}
 	}
+
+	// The IPv6 network pools are not persisted among the configured ones, to leave the IPv4 allocations untouched,
+	// and are created only once IPv6 networks are in use (i.e., here only if already created before a restart).
+	liqoIPAM.ipv6Pools = nil
+	for _, pool := range IPv6Pools {
+		if liqoIPAM.ipam.PrefixFrom(context.TODO(), pool) != nil {
+			liqoIPAM.ipv6Pools = append(liqoIPAM.ipv6Pools, pool)
+		}
+	}
+
 	if listeningPort > 0 {
 		err = liqoIPAM.initRPCServer(listeningPort)
 		if err != nil {
@@ -140,6 +191,53 @@ func (liqoIPAM *IPAM) Init(pools []string, dynClient dynamic.Interface, listenin
 	return nil
 }
 
+// reconcilePools aligns the network pools already stored with the configured ones upon restarts. The newly configured
+// pools are added, while an error is returned if any of the stored ones is no longer configured, as possibly still in use
+// by the networks of the remote clusters, rather than silently ignoring the configuration change.
+func (liqoIPAM *IPAM) reconcilePools(stored, configured []string) error {
+	var dropped []string
+	for _, pool := range stored {
+		if !slice.ContainsString(configured, pool) {
+			dropped = append(dropped, pool)
+		}
+	}
+	if len(dropped) > 0 {
+		return fmt.Errorf("network pools %v are no longer configured, but the removal of existing pools is not supported: "+
+			"configure them again, or reset the IPAM storage to start over with the new pools", dropped)
+	}
+
+	for _, pool := range configured {
+		if slice.ContainsString(stored, pool) {
+			continue
+		}
+		if err := liqoIPAM.AddNetworkPool(pool); err != nil {
+			return fmt.Errorf("cannot add the configured network pool %s: %w", pool, err)
+		}
+	}
+	return nil
+}
+
+// ensureIPv6Pools creates the IPv6 network pools, if not already present, in case the given network is an IPv6 one.
+func (liqoIPAM *IPAM) ensureIPv6Pools(network string) {
+	if len(liqoIPAM.ipv6Pools) > 0 {
+		return
+	}
+	if prefix, err := netaddr.ParseIPPrefix(network); err != nil || !prefix.IP().Is6() {
+		return
+	}
+
+	for _, pool := range IPv6Pools {
+		if liqoIPAM.ipam.PrefixFrom(context.TODO(), pool) == nil {
+			if _, err := liqoIPAM.ipam.NewPrefix(context.TODO(), pool); err != nil {
+				klog.Warningf("Failed to create the IPv6 network pool %s: %v", pool, err)
+				continue
+			}
+		}
+		klog.Infof("IPv6 network pool %s has been successfully initialized", pool)
+		liqoIPAM.ipv6Pools = append(liqoIPAM.ipv6Pools, pool)
+	}
+}
+
 // Terminate function stops the gRPC server.
 func (liqoIPAM *IPAM) Terminate() {
 	// Stop GRPC server
@@ -185,6 +283,7 @@ func (liqoIPAM *IPAM) reservePoolInHalves(pool string) error {
 // AcquireReservedSubnet marks as used the network received as parameter.
 func (liqoIPAM *IPAM) AcquireReservedSubnet(reservedNetwork string) error {
 	klog.Infof("Request to reserve network %s has been received", reservedNetwork)
+	liqoIPAM.ensureIPv6Pools(reservedNetwork)
 	cluster, overlaps, err := liqoIPAM.overlapsWithCluster(reservedNetwork)
 	if err != nil {
 		return fmt.Errorf("cannot acquire network %s: %w", reservedNetwork, err)
@@ -217,6 +316,7 @@ func (liqoIPAM *IPAM) AcquireReservedSubnet(reservedNetwork string) error {
 // MarkAsAcquiredReservedSubnet marks as used the network received as parameter.
 func (liqoIPAM *IPAM) MarkAsAcquiredReservedSubnet(reservedNetwork string) error {
 	klog.Infof("Request to reserve network %s has been received", reservedNetwork)
+	liqoIPAM.ensureIPv6Pools(reservedNetwork)
 
 	pool, ok, err := liqoIPAM.getPoolFromNetwork(reservedNetwork)
 	if err != nil {
@@ -332,7 +432,7 @@ func (liqoIPAM *IPAM) getPoolFromNetwork(network string) (networkPool string, su
 	var poolIPset netaddr.IPSetBuilder
 	var c netaddr.IPPrefix
 	// Get resource
-	pools := liqoIPAM.ipamStorage.getPools()
+	pools := append(append([]string{}, liqoIPAM.ipamStorage.getPools()...), liqoIPAM.ipv6Pools...)
 	// Build IPSet for new network
 	ipprefix, err := netaddr.ParseIPPrefix(network)
 	if err != nil {
@@ -360,25 +460,26 @@ func (liqoIPAM *IPAM) getPoolFromNetwork(network string) (networkPool string, su
 	return
 }
 
-func (liqoIPAM *IPAM) clusterSubnetEqualToPool(pool string) (string, error) {
+func (liqoIPAM *IPAM) clusterSubnetEqualToPool(pool, clusterID string) (string, error) {
 	klog.Infof("Network %s is equal to a pool, looking for a mapping..", pool)
-	mappedNetwork, err := liqoIPAM.getNetworkFromPool(liqonetutils.GetMask(pool))
+	mappedNetwork, err := liqoIPAM.remapNetwork(pool, clusterID)
 	if err != nil {
 		klog.Infof("Mapping not found, acquiring the entire network pool..")
 		err = liqoIPAM.reservePoolInHalves(pool)
 		if err != nil {
-			return "", fmt.Errorf("no networks available: %w", err)
+			return "", fmt.Errorf("%w: %v", ErrNoNetworksAvailable, err)
 		}
 		return pool, nil
 	}
 	return mappedNetwork, nil
 }
 
-// getOrRemapNetwork first tries to acquire the received network.
+// getOrRemapNetwork first tries to acquire the received network of the given cluster.
 // If conflicts are found, a new mapped network is returned.
-func (liqoIPAM *IPAM) getOrRemapNetwork(network string) (string, error) {
+func (liqoIPAM *IPAM) getOrRemapNetwork(network, clusterID string) (string, error) {
 	var mappedNetwork string
 	klog.Infof("Allocating network %s", network)
+	liqoIPAM.ensureIPv6Pools(network)
 	// First try to get a new Prefix
 	_, err := liqoIPAM.ipam.NewPrefix(context.TODO(), network)
 
@@ -400,7 +501,7 @@ func (liqoIPAM *IPAM) getOrRemapNetwork(network string) (string, error) {
 		is better to look first for a mapping rather than acquire the entire network pool.
 		Consider the impact of having a network pool n completely filled and multiple clusters asking for
 		networks in n. This would create the necessity of nat-ting the traffic towards these clusters. */
-		mappedNetwork, err = liqoIPAM.clusterSubnetEqualToPool(pool)
+		mappedNetwork, err = liqoIPAM.clusterSubnetEqualToPool(pool, clusterID)
 		if err != nil {
 			return "", err
 		}
@@ -418,7 +519,7 @@ func (liqoIPAM *IPAM) getOrRemapNetwork(network string) (string, error) {
 		}
 	}
 	/* Network is already reserved, need a mapping */
-	mappedNetwork, err = liqoIPAM.getNetworkFromPool(liqonetutils.GetMask(network))
+	mappedNetwork, err = liqoIPAM.remapNetwork(network, clusterID)
 	if err != nil {
 		return "", err
 	}
@@ -426,6 +527,20 @@ func (liqoIPAM *IPAM) getOrRemapNetwork(network string) (string, error) {
 	return mappedNetwork, nil
 }
 
+// remapNetwork returns a network to be used as a remapping for the given network of a remote cluster, taken from
+// the network pools of the same address family: IPv6 networks are remapped through the IPv6 network pools, while the
+// IPv4 ones are subject to the organization blocks and the allocation policy.
+func (liqoIPAM *IPAM) remapNetwork(network, clusterID string) (string, error) {
+	prefix, err := netaddr.ParseIPPrefix(network)
+	if err != nil {
+		return "", fmt.Errorf("cannot parse network %s: %w", network, err)
+	}
+	if prefix.IP().Is6() {
+		return liqoIPAM.getRemappedIPv6Network(prefix.Bits())
+	}
+	return liqoIPAM.getRemappedNetworkForCluster(clusterID, prefix.Bits())
+}
+
 /*
 GetSubnetsPerCluster receives a PodCIDR, and a Cluster ID and returns a PodCIDR and an ExternalCIDR.
 The PodCIDR can be either the received one or a new one, if conflicts have been found.
@@ -455,7 +570,7 @@ func (liqoIPAM *IPAM) GetSubnetsPerCluster(
 	klog.Infof("Cluster networks allocation request received: %s", clusterID)
 
 	// Get PodCidr
-	mappedPodCIDR, err = liqoIPAM.getOrRemapNetwork(podCidr)
+	mappedPodCIDR, err = liqoIPAM.getOrRemapNetwork(podCidr, clusterID)
 	if err != nil {
 		return "", "", fmt.Errorf("cannot get a PodCIDR for cluster %s: %w", clusterID, err)
 	}
@@ -469,7 +584,7 @@ func (liqoIPAM *IPAM) GetSubnetsPerCluster(
 	}
 
 	// Get ExternalCIDR
-	mappedExternalCIDR, err = liqoIPAM.getOrRemapNetwork(externalCIDR)
+	mappedExternalCIDR, err = liqoIPAM.getOrRemapNetwork(externalCIDR, clusterID)
 	if err != nil {
 		_ = liqoIPAM.FreeReservedSubnet(mappedPodCIDR)
 		return "", "", fmt.Errorf("cannot get an ExternalCIDR for cluster %s: %w", clusterID, err)
@@ -498,21 +613,29 @@ func (liqoIPAM *IPAM) GetSubnetsPerCluster(
 		_ = liqoIPAM.FreeReservedSubnet(mappedExternalCIDR)
 		return "", "", fmt.Errorf("cannot update cluster subnets: %w", err)
 	}
+
+	liqoIPAM.recordAllocation(clusterID, "PodCIDR", podCidr, mappedPodCIDR)
+	liqoIPAM.recordAllocation(clusterID, "ExternalCIDR", externalCIDR, mappedExternalCIDR)
 	return mappedPodCIDR, mappedExternalCIDR, nil
 }
 
-// getNetworkFromPool returns a network with mask length equal to mask taken by a network pool.
+// getNetworkFromPool returns a network with mask length equal to mask taken by a network pool,
+// according to the configured allocation policy.
 func (liqoIPAM *IPAM) getNetworkFromPool(mask uint8) (string, error) {
+	if liqoIPAM.allocationPolicy == BestFitAllocationPolicy {
+		return liqoIPAM.getBestFitNetworkFromPool(mask)
+	}
+
 	// Get network pools
 	pools := liqoIPAM.ipamStorage.getPools()
-	// For each pool, try to get a network with mask length mask
+	// For each pool, in priority order, try to get a network with mask length mask
 	for _, pool := range pools {
 		if mappedNetwork, err := liqoIPAM.ipam.AcquireChildPrefix(context.TODO(), pool, mask); err == nil {
-			klog.Infof("Acquired network %s", mappedNetwork)
+			klog.Infof("Acquired network %s from pool %s", mappedNetwork, pool)
 			return mappedNetwork.String(), nil
 		}
 	}
-	return "", fmt.Errorf("no networks available")
+	return "", ErrNoNetworksAvailable
 }
 
 func (liqoIPAM *IPAM) freePoolInHalves(pool string) error {
@@ -615,6 +738,10 @@ func (liqoIPAM *IPAM) RemoveClusterConfig(clusterID string) error {
 		}
 	}
 
+	if liqoIPAM.ipamStorage == nil {
+		return &liqoneterrors.MissingInit{StructureName: "IPAM"}
+	}
+
 	// Get cluster subnets
 	clusterSubnets := liqoIPAM.ipamStorage.getClusterSubnets()
 
@@ -653,9 +780,12 @@ func (liqoIPAM *IPAM) RemoveClusterConfig(clusterID string) error {
 		return fmt.Errorf("unable to terminate NAT mappings for cluster %s: %w", clusterID, err)
 	}
 
+	if !subnetsExist {
+		klog.Warningf("NAT mappings for cluster %s were configured, although no subnets were assigned to it", clusterID)
+	}
+
+	// Update natMappingsConfigured, so that subsequent calls are a nop.
 	delete(natMappingsConfigured, clusterID)
-	// Update natMappingsConfigured
-	natMappingsConfigured[clusterID] = netv1alpha1.ConfiguredCluster{}
 	if err := liqoIPAM.ipamStorage.updateNatMappingsConfigured(natMappingsConfigured); err != nil {
 		return fmt.Errorf("unable to update NatMappingsConfigured: %w", err)
 	}
@@ -667,13 +797,7 @@ func (liqoIPAM *IPAM) initNatMappingsPerCluster(clusterID string, subnets netv1a
 	// InitNatMappingsPerCluster does need the Pod CIDR used in home cluster for remote pods (subnets.RemotePodCIDR)
 	// and the ExternalCIDR used in remote cluster for local exported resources.
 	localExternalCIDR := liqoIPAM.ipamStorage.getExternalCIDR()
-	var externalCIDR string
-	if subnets.LocalNATExternalCIDR == consts.DefaultCIDRValue {
-		// Remote cluster has not remapped home ExternalCIDR
-		externalCIDR = localExternalCIDR
-	} else {
-		externalCIDR = subnets.LocalNATExternalCIDR
-	}
+	externalCIDR := liqoIPAM.natExternalCIDR(subnets)
 
 	if err := liqoIPAM.natMappingInflater.InitNatMappingsPerCluster(subnets.RemotePodCIDR, externalCIDR, clusterID); err != nil {
 		return err
@@ -705,6 +829,29 @@ func (liqoIPAM *IPAM) initNatMappingsPerCluster(clusterID string, subnets netv1a
 	return nil
 }
 
+// updateNatMappingsPerCluster updates the NAT mappings of the given cluster from the previous to the current subnets
+// (e.g., after the re-allocation of its networks), as part of the given transaction.
+func (liqoIPAM *IPAM) updateNatMappingsPerCluster(tx *AllocTx, clusterID string, previous, current netv1alpha1.Subnets) error {
+	if err := liqoIPAM.natMappingInflater.UpdateNatMappingsPerCluster(
+		current.RemotePodCIDR, liqoIPAM.natExternalCIDR(current), clusterID); err != nil {
+		return err
+	}
+	tx.track(func() error {
+		return liqoIPAM.natMappingInflater.UpdateNatMappingsPerCluster(
+			previous.RemotePodCIDR, liqoIPAM.natExternalCIDR(previous), clusterID)
+	})
+	return nil
+}
+
+// natExternalCIDR returns the ExternalCIDR used in the remote cluster for local exported resources, given its subnets.
+func (liqoIPAM *IPAM) natExternalCIDR(subnets netv1alpha1.Subnets) string {
+	if liqonetutils.IsNATDisabled(subnets.LocalNATExternalCIDR) {
+		// Remote cluster has not remapped home ExternalCIDR
+		return liqoIPAM.ipamStorage.getExternalCIDR()
+	}
+	return subnets.LocalNATExternalCIDR
+}
+
 // terminateNatMappingsPerCluster is used to update endpointMappings after a cluster peering is terminated.
 func (liqoIPAM *IPAM) terminateNatMappingsPerCluster(clusterID string) error {
 	// Get NAT mappings
@@ -1026,7 +1173,7 @@ func (liqoIPAM *IPAM) mapIPToExternalCIDR(clusterID, remoteExternalCIDR, ip stri
 	// Get local ExternalCIDR
 	localExternalCIDR := liqoIPAM.ipamStorage.getExternalCIDR()
 
-	if remoteExternalCIDR == consts.DefaultCIDRValue {
+	if liqonetutils.IsNATDisabled(remoteExternalCIDR) {
 		externalCIDR = localExternalCIDR
 	} else {
 		externalCIDR = remoteExternalCIDR
@@ -1193,6 +1340,12 @@ func (liqoIPAM *IPAM) getHomePodIPInternal(clusterID, ip string) (string, error)
 	liqoIPAM.mutex.Lock()
 	defer liqoIPAM.mutex.Unlock()
 
+	// Static overrides take precedence over the algorithmic remapping.
+	if fixed, found := liqoIPAM.staticIPOverride(ip); found {
+		klog.V(5).Infof("GetHomePodIP(%s, %s): statically mapped to %s", ip, clusterID, fixed)
+		return fixed, nil
+	}
+
 	// Get cluster subnets
 	clusterSubnets := liqoIPAM.ipamStorage.getClusterSubnets()
 	subnets, exists := clusterSubnets[clusterID]
@@ -1312,6 +1465,9 @@ func (liqoIPAM *IPAM) SetPodCIDR(podCIDR string) error {
 
 // SetServiceCIDR sets the ServiceCIDR.
 func (liqoIPAM *IPAM) SetServiceCIDR(serviceCIDR string) error {
+	if serviceCIDR == "" {
+		return fmt.Errorf("the ServiceCIDR is not configured, while required to detect the conflicts with the remote networks")
+	}
 	// Get ServiceCIDR
 	oldServiceCIDR := liqoIPAM.ipamStorage.getServiceCIDR()
 	if oldServiceCIDR != "" && oldServiceCIDR != serviceCIDR {
@@ -1331,6 +1487,153 @@ func (liqoIPAM *IPAM) SetServiceCIDR(serviceCIDR string) error {
 	return nil
 }
 
+// ReconcileLocalNetworks aligns the IPAM state with the given local PodCIDR and ServiceCIDR, which may differ from the
+// stored ones in case the configuration of the operator changed. The previous local networks are released, and the
+// networks reserved for remote clusters which now overlap with the local ones are re-allocated, before acquiring the
+// new local networks. It returns the IDs of the clusters whose networks have been re-allocated: the status of the
+// corresponding NetworkConfigs is then realigned when reconciled, as it reflects the networks assigned by the IPAM.
+// It is meant to be invoked at startup, before SetPodCIDR and SetServiceCIDR.
+func (liqoIPAM *IPAM) ReconcileLocalNetworks(podCIDR, serviceCIDR string) (reallocated []string, err error) {
+	if err := CheckReservedSubnets(podCIDR, serviceCIDR, nil); err != nil {
+		return nil, err
+	}
+
+	// Release the local networks no longer configured.
+	if old := liqoIPAM.ipamStorage.getPodCIDR(); old != "" && old != podCIDR {
+		klog.Infof("PodCIDR changed from %s to %s, releasing the previous one", old, podCIDR)
+		if err := liqoIPAM.FreeReservedSubnet(old); err != nil {
+			return nil, fmt.Errorf("cannot free previous PodCIDR %s: %w", old, err)
+		}
+		if err := liqoIPAM.ipamStorage.updatePodCIDR(emptyCIDR); err != nil {
+			return nil, fmt.Errorf("cannot unset PodCIDR: %w", err)
+		}
+	}
+	if old := liqoIPAM.ipamStorage.getServiceCIDR(); old != "" && old != serviceCIDR {
+		klog.Infof("ServiceCIDR changed from %s to %s, releasing the previous one", old, serviceCIDR)
+		if err := liqoIPAM.FreeReservedSubnet(old); err != nil {
+			return nil, fmt.Errorf("cannot free previous ServiceCIDR %s: %w", old, err)
+		}
+		if err := liqoIPAM.ipamStorage.updateServiceCIDR(emptyCIDR); err != nil {
+			return nil, fmt.Errorf("cannot unset ServiceCIDR: %w", err)
+		}
+	}
+
+	// Release the networks of the remote clusters which conflict with the local ones.
+	clusterSubnets := liqoIPAM.ipamStorage.getClusterSubnets()
+	clusterIDs := make([]string, 0, len(clusterSubnets))
+	for clusterID := range clusterSubnets {
+		clusterIDs = append(clusterIDs, clusterID)
+	}
+	// Sort the cluster IDs, to re-allocate the networks in a deterministic order.
+	sort.Strings(clusterIDs)
+
+	var conflicting []reservation
+	// previous are the subnets of the clusters with conflicting networks, before being re-allocated.
+	previous := make(map[string]netv1alpha1.Subnets)
+	for _, clusterID := range clusterIDs {
+		subnets := clusterSubnets[clusterID]
+		for _, r := range []reservation{
+			{clusterID: clusterID, kind: "PodCIDR", network: subnets.RemotePodCIDR},
+			{clusterID: clusterID, kind: "ExternalCIDR", network: subnets.RemoteExternalCIDR},
+		} {
+			if r.network == "" || liqonetutils.IsNATDisabled(r.network) {
+				continue
+			}
+			for _, local := range []string{podCIDR, serviceCIDR} {
+				overlaps, err := liqoIPAM.overlapsWithNetwork(r.network, local)
+				if err != nil {
+					return nil, err
+				}
+				if overlaps {
+					klog.Warningf("%s %s of cluster %s overlaps with the local network %s, re-allocating it", r.kind, r.network, clusterID, local)
+					conflicting = append(conflicting, r)
+					previous[clusterID] = subnets
+					break
+				}
+			}
+		}
+	}
+
+	for _, r := range conflicting {
+		if err := liqoIPAM.FreeReservedSubnet(r.network); err != nil {
+			return nil, fmt.Errorf("cannot free %s %s of cluster %s: %w", r.kind, r.network, r.clusterID, err)
+		}
+		// Unset the network, so that it is allocated again from scratch in case of failures during the remapping.
+		subnets := clusterSubnets[r.clusterID]
+		setClusterNetwork(&subnets, r.kind, emptyCIDR)
+		clusterSubnets[r.clusterID] = subnets
+	}
+	if len(conflicting) > 0 {
+		if err := liqoIPAM.ipamStorage.updateClusterSubnets(clusterSubnets); err != nil {
+			return nil, fmt.Errorf("cannot update cluster subnets: %w", err)
+		}
+	}
+
+	// Acquire the new local networks, before remapping the conflicting ones to prevent overlaps.
+	if err := liqoIPAM.SetPodCIDR(podCIDR); err != nil {
+		return nil, err
+	}
+	if err := liqoIPAM.SetServiceCIDR(serviceCIDR); err != nil {
+		return nil, err
+	}
+
+	// Re-allocate the conflicting networks and update the NAT mappings of the affected clusters as a whole, to prevent
+	// the latter from being left referring to the released networks in case of failures.
+	remapped := make([]string, len(conflicting))
+	err = liqoIPAM.WithTransaction(func(tx *AllocTx) error {
+		for i, r := range conflicting {
+			mapped, err := liqoIPAM.getRemappedNetworkForCluster(r.clusterID, liqonetutils.GetMask(r.network))
+			if err != nil {
+				return fmt.Errorf("cannot re-allocate %s %s of cluster %s: %w", r.kind, r.network, r.clusterID, err)
+			}
+			tx.track(func() error { return liqoIPAM.FreeReservedSubnet(mapped) })
+			remapped[i] = mapped
+
+			subnets := clusterSubnets[r.clusterID]
+			setClusterNetwork(&subnets, r.kind, mapped)
+			clusterSubnets[r.clusterID] = subnets
+			if !slice.ContainsString(reallocated, r.clusterID) {
+				reallocated = append(reallocated, r.clusterID)
+			}
+		}
+
+		natMappingsConfigured := liqoIPAM.ipamStorage.getNatMappingsConfigured()
+		for _, clusterID := range reallocated {
+			if _, configured := natMappingsConfigured[clusterID]; !configured {
+				continue
+			}
+			if err := liqoIPAM.updateNatMappingsPerCluster(tx, clusterID, previous[clusterID], clusterSubnets[clusterID]); err != nil {
+				return fmt.Errorf("cannot update the NAT mappings of cluster %s: %w", clusterID, err)
+			}
+		}
+
+		if err := liqoIPAM.ipamStorage.updateClusterSubnets(clusterSubnets); err != nil {
+			return fmt.Errorf("cannot update cluster subnets: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i, r := range conflicting {
+		klog.Infof("%s %s of cluster %s has been re-allocated to %s", r.kind, r.network, r.clusterID, remapped[i])
+		liqoIPAM.recordAllocation(r.clusterID, r.kind, r.network, remapped[i])
+	}
+
+	return reallocated, nil
+}
+
+// setClusterNetwork sets the network of the given kind reserved for a remote cluster.
+func setClusterNetwork(subnets *netv1alpha1.Subnets, kind, network string) {
+	switch kind {
+	case "PodCIDR":
+		subnets.RemotePodCIDR = network
+	case "ExternalCIDR":
+		subnets.RemoteExternalCIDR = network
+	}
+}
+
 // SetReservedSubnets acquires and/or frees the reserved networks.
 func (liqoIPAM *IPAM) SetReservedSubnets(subnets []string) error {
 	reserved := liqoIPAM.ipamStorage.getReservedSubnets()
@@ -1384,6 +1687,39 @@ func (liqoIPAM *IPAM) SetReservedSubnets(subnets []string) error {
 	return nil
 }
 
+// CheckReservedSubnets verifies that the local PodCIDR and ServiceCIDR are set, and do not overlap with
+// any of the reserved subnets. It is meant to be invoked at startup, before configuring the IPAM,
+// to fail early with a clear message in case of misconfigurations.
+func CheckReservedSubnets(podCIDR, serviceCIDR string, reservedSubnets []string) error {
+	for _, reserved := range reservedSubnets {
+		if err := liqonetutils.IsValidCIDR(reserved); err != nil {
+			return fmt.Errorf("the reserved subnet %q is not a valid CIDR: %w", reserved, err)
+		}
+	}
+
+	check := func(name, network string) error {
+		if network == "" {
+			// An unset network would be silently ignored when checking the conflicts with the remote ones,
+			// leading to remote networks not being remapped even if overlapping (e.g., breaking the Service routing).
+			return fmt.Errorf("the local %s is not configured, while required to detect the conflicts with the remote networks", name)
+		}
+		if err := liqonetutils.IsValidCIDR(network); err != nil {
+			return fmt.Errorf("the local %s %q is not a valid CIDR: %w", name, network, err)
+		}
+		for _, reserved := range reservedSubnets {
+			if err := goipam.PrefixesOverlapping([]string{reserved}, []string{network}); err != nil {
+				return fmt.Errorf("the local %s %s overlaps with the reserved subnet %s", name, network, reserved)
+			}
+		}
+		return nil
+	}
+
+	if err := check("podCIDR", podCIDR); err != nil {
+		return err
+	}
+	return check("serviceCIDR", serviceCIDR)
+}
+
 func (liqoIPAM *IPAM) reservedSubnetOverlaps(subnet string) error {
 	// Check if subnet overlaps with local pod CIDR.
 	podCidr := liqoIPAM.ipamStorage.getPodCIDR()
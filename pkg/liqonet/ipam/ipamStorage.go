@@ -47,6 +47,8 @@ const (
 	podCIDRUpdate               = "podCIDR"
 	serviceCIDRUpdate           = "serviceCIDR"
 	natMappingsConfiguredUpdate = "natMappingsConfigured"
+	organizationBlocksUpdate    = "organizationBlocks"
+	updateOpReplace             = "replace"
 	updateOpAdd                 = "add"
 	updateOpRemove              = "remove"
 )
@@ -61,6 +63,9 @@ type IpamStorage interface {
 	updateServiceCIDR(serviceCIDR string) error
 	updateReservedSubnets(subnet, operation string) error
 	updateNatMappingsConfigured(natMappingsConfigured map[string]netv1alpha1.ConfiguredCluster) error
+	updateOrganizationBlocks(organizationBlocks map[string]netv1alpha1.OrganizationBlock) error
+	updateClusterReferences(clusterSubnets map[string]netv1alpha1.Subnets, natMappingsConfigured map[string]netv1alpha1.ConfiguredCluster,
+		endpoints map[string]netv1alpha1.EndpointMapping, organizationBlocks map[string]netv1alpha1.OrganizationBlock) error
 	getClusterSubnets() map[string]netv1alpha1.Subnets
 	getPools() []string
 	getExternalCIDR() string
@@ -69,6 +74,9 @@ type IpamStorage interface {
 	getServiceCIDR() string
 	getReservedSubnets() []string
 	getNatMappingsConfigured() map[string]netv1alpha1.ConfiguredCluster
+	getOrganizationBlocks() map[string]netv1alpha1.OrganizationBlock
+	getSpec() netv1alpha1.IpamSpec
+	restoreSpec(spec *netv1alpha1.IpamSpec) error
 	goipam.Storage
 }
 
@@ -267,20 +275,84 @@ func (ipamStorage *IPAMStorage) updateNatMappingsConfigured(natMappingsConfigure
 	return ipamStorage.updateConfig(natMappingsConfiguredUpdate, natMappingsConfigured)
 }
 
-func (ipamStorage *IPAMStorage) updateConfig(updateType string, data interface{}) error {
-	jsonData, err := json.Marshal(data)
-	if err != nil {
-		klog.Errorf("cannot marshal object: %s", err.Error())
-		return err
+func (ipamStorage *IPAMStorage) updateOrganizationBlocks(organizationBlocks map[string]netv1alpha1.OrganizationBlock) error {
+	// The field is optional, hence it is added rather than replaced, as possibly not yet present.
+	return ipamStorage.patchConfig(updateOpAdd, organizationBlocksUpdate, organizationBlocks)
+}
+
+// updateClusterReferences replaces at once (i.e., atomically) all the fields referring to the remote clusters.
+func (ipamStorage *IPAMStorage) updateClusterReferences(clusterSubnets map[string]netv1alpha1.Subnets,
+	natMappingsConfigured map[string]netv1alpha1.ConfiguredCluster, endpoints map[string]netv1alpha1.EndpointMapping,
+	organizationBlocks map[string]netv1alpha1.OrganizationBlock) error {
+	ops := []patchOp{
+		{operation: updateOpReplace, updateType: clusterSubnetUpdate, data: clusterSubnets},
+		{operation: updateOpReplace, updateType: natMappingsConfiguredUpdate, data: natMappingsConfigured},
+		{operation: updateOpReplace, updateType: endpointMappingsUpdate, data: endpoints},
+	}
+	if organizationBlocks != nil {
+		// The field is optional, hence it is added rather than replaced, as possibly not yet present.
+		ops = append(ops, patchOp{operation: updateOpAdd, updateType: organizationBlocksUpdate, data: organizationBlocks})
+	}
+	return ipamStorage.patchConfigOps(ops...)
+}
+
+// restoreSpec replaces at once (i.e., atomically) the whole IPAM configuration with the given one.
+func (ipamStorage *IPAMStorage) restoreSpec(spec *netv1alpha1.IpamSpec) error {
+	// The add operation is used, as it replaces the existing fields while also setting the possibly missing ones.
+	ops := []patchOp{
+		{operation: updateOpAdd, updateType: prefixesUpdate, data: spec.Prefixes},
+		{operation: updateOpAdd, updateType: poolsUpdate, data: spec.Pools},
+		{operation: updateOpAdd, updateType: reservedSubnetsUpdate, data: spec.ReservedSubnets},
+		{operation: updateOpAdd, updateType: clusterSubnetUpdate, data: spec.ClusterSubnets},
+		{operation: updateOpAdd, updateType: externalCIDRUpdate, data: spec.ExternalCIDR},
+		{operation: updateOpAdd, updateType: endpointMappingsUpdate, data: spec.EndpointMappings},
+		{operation: updateOpAdd, updateType: natMappingsConfiguredUpdate, data: spec.NatMappingsConfigured},
+		{operation: updateOpAdd, updateType: podCIDRUpdate, data: spec.PodCIDR},
+		{operation: updateOpAdd, updateType: serviceCIDRUpdate, data: spec.ServiceCIDR},
 	}
+	if spec.OrganizationBlocks != nil {
+		ops = append(ops, patchOp{operation: updateOpAdd, updateType: organizationBlocksUpdate, data: spec.OrganizationBlocks})
+	}
+	return ipamStorage.patchConfigOps(ops...)
+}
+
+func (ipamStorage *IPAMStorage) updateConfig(updateType string, data interface{}) error {
+	return ipamStorage.patchConfig(updateOpReplace, updateType, data)
+}
 
+// patchOp describes a single operation of the JSON patch applied to the IPAM configuration.
+type patchOp struct {
+	operation  string
+	updateType string
+	data       interface{}
+}
+
+func (ipamStorage *IPAMStorage) patchConfig(operation, updateType string, data interface{}) error {
+	return ipamStorage.patchConfigOps(patchOp{operation: operation, updateType: updateType, data: data})
+}
+
+// patchConfigOps applies the given operations to the IPAM configuration, within a single JSON patch.
+func (ipamStorage *IPAMStorage) patchConfigOps(ops ...patchOp) error {
 	var b bytes.Buffer
-	patch := fmt.Sprintf(
-		`[{"op": "replace", "path": "/spec/%s", "value": `,
-		updateType)
-	b.WriteString(patch)
-	b.Write(jsonData)
-	b.WriteString("}]")
+	b.WriteString("[")
+	for i, op := range ops {
+		jsonData, err := json.Marshal(op.data)
+		if err != nil {
+			klog.Errorf("cannot marshal object: %s", err.Error())
+			return err
+		}
+
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		patch := fmt.Sprintf(
+			`{"op": "%s", "path": "/spec/%s", "value": `,
+			op.operation, op.updateType)
+		b.WriteString(patch)
+		b.Write(jsonData)
+		b.WriteString("}")
+	}
+	b.WriteString("]")
 
 	unstr, err := ipamStorage.dynClient.Resource(netv1alpha1.IpamGroupVersionResource).Patch(context.Background(),
 		ipamStorage.getConfigName(), types.JSONPatchType, b.Bytes(), metav1.PatchOptions{})
@@ -332,6 +404,15 @@ func (ipamStorage *IPAMStorage) getNatMappingsConfigured() map[string]netv1alpha
 	return ipamStorage.getConfig().Spec.NatMappingsConfigured
 }
 
+func (ipamStorage *IPAMStorage) getOrganizationBlocks() map[string]netv1alpha1.OrganizationBlock {
+	return ipamStorage.getConfig().Spec.OrganizationBlocks
+}
+
+// getSpec returns a consistent snapshot of the whole IPAM configuration.
+func (ipamStorage *IPAMStorage) getSpec() netv1alpha1.IpamSpec {
+	return ipamStorage.getConfig().Spec
+}
+
 func (ipamStorage *IPAMStorage) getConfig() *netv1alpha1.IpamStorage {
 	ipamStorage.m.RLock()
 	defer ipamStorage.m.RUnlock()
@@ -17,13 +17,18 @@ package ipam
 import (
 	"context"
 	"crypto/rand"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"math/big"
+	"net"
 	"strings"
+	"sync"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
@@ -318,6 +323,88 @@ var _ = Describe("Ipam", func() {
 			})
 		})
 	})
+	Describe("GetSubnetsPerCluster with IPv6 networks", func() {
+		// ipv6Network parses the given network, asserting it is a valid IPv6 one.
+		ipv6Network := func(network string) *net.IPNet {
+			ip, ipnet, err := net.ParseCIDR(network)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ip.To4()).To(BeNil())
+			return ipnet
+		}
+
+		Context("When no IPv6 network is in use", func() {
+			It("should not create the IPv6 network pools", func() {
+				Expect(ipam.ipam.PrefixFrom(context.TODO(), IPv6Pools[0])).To(BeNil())
+				Expect(ipam.ipv6Pools).To(BeEmpty())
+			})
+		})
+
+		Context("When an IPv6 network is first allocated", func() {
+			It("should create the IPv6 network pools, restoring them upon restarts", func() {
+				_, _, err := ipam.GetSubnetsPerCluster("fd00::/48", "10.1.0.0/16", clusterID1)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(ipam.ipam.PrefixFrom(context.TODO(), IPv6Pools[0])).ToNot(BeNil())
+				Expect(ipam.ipv6Pools).To(ConsistOf(IPv6Pools))
+
+				restarted := NewIPAM()
+				Expect(restarted.Init(Pools, dynClient, 0)).To(Succeed())
+				Expect(restarted.ipv6Pools).To(ConsistOf(IPv6Pools))
+			})
+		})
+
+		Context("When the remote PodCIDR has not already been assigned to any other cluster", func() {
+			It("Should allocate the subnet itself, without mapping", func() {
+				p, e, err := ipam.GetSubnetsPerCluster("fd00::/48", "10.1.0.0/16", clusterID1)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p).To(Equal("fd00::/48"))
+				Expect(e).To(Equal("10.1.0.0/16"))
+			})
+		})
+
+		Context("When the remote PodCIDR has already been assigned to another cluster", func() {
+			It("should map it to another IPv6 network with the same mask length, leaving the IPv4 ones untouched", func() {
+				_, _, err := ipam.GetSubnetsPerCluster("fd00::/48", "10.1.0.0/16", clusterID1)
+				Expect(err).ToNot(HaveOccurred())
+
+				p, e, err := ipam.GetSubnetsPerCluster("fd00::/48", "10.2.0.0/16", clusterID2)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(e).To(Equal("10.2.0.0/16"))
+
+				mapped := ipv6Network(p)
+				ones, _ := mapped.Mask.Size()
+				Expect(ones).To(Equal(48))
+				Expect(ipv6Network("fd00::/8").Contains(mapped.IP)).To(BeTrue())
+				Expect(ipv6Network("fd00::/48").Contains(mapped.IP)).To(BeFalse())
+			})
+
+			It("should release the mapped network when the cluster configuration is removed", func() {
+				Expect(ipam.SetPodCIDR(homePodCIDR)).To(Succeed())
+				_, err := ipam.GetExternalCIDR(uint8(24))
+				Expect(err).ToNot(HaveOccurred())
+
+				_, _, err = ipam.GetSubnetsPerCluster("fd00::/48", "10.1.0.0/16", clusterID1)
+				Expect(err).ToNot(HaveOccurred())
+				first, _, err := ipam.GetSubnetsPerCluster("fd00::/48", "10.2.0.0/16", clusterID2)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(ipam.AddLocalSubnetsPerCluster(localNATPodCIDR, localNATExternalCIDR, clusterID2)).To(Succeed())
+
+				Expect(ipam.RemoveClusterConfig(clusterID2)).To(Succeed())
+				second, _, err := ipam.GetSubnetsPerCluster("fd00::/48", "10.2.0.0/16", clusterID2)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(second).To(Equal(first))
+			})
+		})
+
+		Context("When the remote PodCIDR overlaps with a reserved IPv6 network outside of the pools", func() {
+			It("should map it to a network taken from the IPv6 pools", func() {
+				Expect(ipam.AcquireReservedSubnet("2001:db8::/48")).To(Succeed())
+				p, _, err := ipam.GetSubnetsPerCluster("2001:db8::/48", "10.1.0.0/16", clusterID1)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(ipv6Network("fd00::/8").Contains(ipv6Network(p).IP)).To(BeTrue())
+			})
+		})
+	})
+
 	Describe("RemoveClusterConfig", func() {
 		BeforeEach(func() {
 			err := ipam.SetPodCIDR(homePodCIDR)
@@ -383,6 +470,7 @@ var _ = Describe("Ipam", func() {
 				// Get config before second call
 				ipamStorage, err := getIpamStorageResource()
 				Expect(err).To(BeNil())
+				Expect(ipamStorage.Spec.NatMappingsConfigured).ToNot(HaveKey(clusterID1))
 				_, err = getNatMappingResourcePerCluster(clusterID3)
 				Expect(k8serrors.IsNotFound(err)).To(BeTrue())
 
@@ -399,6 +487,11 @@ var _ = Describe("Ipam", func() {
 				Expect(ipamStorage).To(Equal(newIpamStorage))
 			})
 		})
+		Context("Call on a non-initialized IPAM", func() {
+			It("Should return a MissingInit error without panicking", func() {
+				Expect(NewIPAM().RemoveClusterConfig(clusterID1)).To(MatchError(&liqoneterrors.MissingInit{}))
+			})
+		})
 		Context("Passing an empty cluster ID", func() {
 			It("Should return a WrongParameter error", func() {
 				err := ipam.RemoveClusterConfig("")
@@ -578,6 +671,186 @@ var _ = Describe("Ipam", func() {
 		})
 	})
 
+	Describe("RenameCluster", func() {
+		BeforeEach(func() {
+			Expect(ipam.SetPodCIDR(homePodCIDR)).To(Succeed())
+			_, err := ipam.GetExternalCIDR(uint8(24))
+			Expect(err).To(BeNil())
+		})
+
+		Context("Rename a configured cluster", func() {
+			It("should move the configuration, preserving the reservations", func() {
+				p, e, err := ipam.GetSubnetsPerCluster(remotePodCIDR, remoteExternalCIDR, clusterID1)
+				Expect(err).To(BeNil())
+				Expect(ipam.AddLocalSubnetsPerCluster(consts.DefaultCIDRValue, consts.DefaultCIDRValue, clusterID1)).To(Succeed())
+				response, err := ipam.MapEndpointIP(context.Background(), &MapRequest{ClusterID: clusterID1, Ip: externalEndpointIP})
+				Expect(err).To(BeNil())
+
+				Expect(ipam.RenameCluster(clusterID1, clusterID3)).To(Succeed())
+
+				ipamStorage, err := getIpamStorageResource()
+				Expect(err).To(BeNil())
+				Expect(ipamStorage.Spec.ClusterSubnets).ToNot(HaveKey(clusterID1))
+				Expect(ipamStorage.Spec.ClusterSubnets).To(HaveKey(clusterID3))
+				Expect(ipamStorage.Spec.NatMappingsConfigured).ToNot(HaveKey(clusterID1))
+				Expect(ipamStorage.Spec.NatMappingsConfigured).To(HaveKey(clusterID3))
+				Expect(ipamStorage.Spec.EndpointMappings[externalEndpointIP].ClusterMappings).ToNot(HaveKey(clusterID1))
+				Expect(ipamStorage.Spec.EndpointMappings[externalEndpointIP].ClusterMappings).To(HaveKeyWithValue(
+					clusterID3, liqonetapi.ClusterMapping{ExternalCIDRNattedIP: response.GetIp()}))
+				Expect(ipamStorage.Spec.Prefixes).To(HaveKey(p))
+				Expect(ipamStorage.Spec.Prefixes).To(HaveKey(e))
+
+				// Check the NatMapping resource has been moved
+				_, err = getNatMappingResourcePerCluster(clusterID1)
+				Expect(k8serrors.IsNotFound(err)).To(BeTrue())
+				natMapping, err := getNatMappingResourcePerCluster(clusterID3)
+				Expect(err).To(BeNil())
+				Expect(natMapping.Spec.ClusterID).To(Equal(clusterID3))
+
+				// The same subnets are subsequently returned for the new cluster ID.
+				newP, newE, err := ipam.GetSubnetsPerCluster(remotePodCIDR, remoteExternalCIDR, clusterID3)
+				Expect(err).To(BeNil())
+				Expect(newP).To(Equal(p))
+				Expect(newE).To(Equal(e))
+			})
+		})
+		Context("Rename to a cluster ID already configured", func() {
+			It("should fail, leaving the configuration untouched", func() {
+				_, _, err := ipam.GetSubnetsPerCluster(remotePodCIDR, remoteExternalCIDR, clusterID1)
+				Expect(err).To(BeNil())
+				_, _, err = ipam.GetSubnetsPerCluster(remotePodCIDR, remoteExternalCIDR, clusterID2)
+				Expect(err).To(BeNil())
+				ipamStorage, err := getIpamStorageResource()
+				Expect(err).To(BeNil())
+
+				Expect(ipam.RenameCluster(clusterID1, clusterID2)).To(HaveOccurred())
+
+				newIpamStorage, err := getIpamStorageResource()
+				Expect(err).To(BeNil())
+				Expect(newIpamStorage).To(Equal(ipamStorage))
+			})
+		})
+		Context("Rename a non-configured cluster", func() {
+			It("should be a nop", func() {
+				ipamStorage, err := getIpamStorageResource()
+				Expect(err).To(BeNil())
+				Expect(ipam.RenameCluster(clusterID1, clusterID3)).To(Succeed())
+				newIpamStorage, err := getIpamStorageResource()
+				Expect(err).To(BeNil())
+				Expect(newIpamStorage).To(Equal(ipamStorage))
+			})
+		})
+		Context("Passing an empty cluster ID", func() {
+			It("should return a WrongParameter error", func() {
+				Expect(ipam.RenameCluster(clusterID1, "")).To(MatchError(
+					fmt.Sprintf("%s must be %s", consts.ClusterIDLabelName, liqoneterrors.StringNotEmpty)))
+			})
+		})
+	})
+
+	Describe("ReclaimRemappedPodCIDR", func() {
+		var remapped string
+
+		BeforeEach(func() {
+			_, _, err := ipam.GetSubnetsPerCluster("11.0.0.0/16", "11.1.0.0/16", clusterID1)
+			Expect(err).To(BeNil())
+			remapped, _, err = ipam.GetSubnetsPerCluster("11.0.0.0/16", "11.2.0.0/16", clusterID2)
+			Expect(err).To(BeNil())
+			Expect(remapped).ToNot(Equal("11.0.0.0/16"))
+		})
+
+		Context("When the remote PodCIDR becomes non-overlapping", func() {
+			It("should disable the remapping and free the previously reserved subnet", func() {
+				reclaimed, err := ipam.ReclaimRemappedPodCIDR("12.0.0.0/16", clusterID2)
+				Expect(err).To(BeNil())
+				Expect(reclaimed).To(BeTrue())
+
+				ipamStorage, err := getIpamStorageResource()
+				Expect(err).To(BeNil())
+				Expect(ipamStorage.Spec.ClusterSubnets[clusterID2].RemotePodCIDR).To(Equal("12.0.0.0/16"))
+				Expect(ipamStorage.Spec.Prefixes).To(HaveKey("12.0.0.0/16"))
+				Expect(ipamStorage.Spec.Prefixes).ToNot(HaveKey(remapped))
+
+				// The subnets are subsequently returned without remapping.
+				p, _, err := ipam.GetSubnetsPerCluster("12.0.0.0/16", "11.2.0.0/16", clusterID2)
+				Expect(err).To(BeNil())
+				Expect(p).To(Equal("12.0.0.0/16"))
+			})
+		})
+
+		Context("When the remote PodCIDR still overlaps", func() {
+			It("should preserve the remapping", func() {
+				reclaimed, err := ipam.ReclaimRemappedPodCIDR("11.0.0.0/16", clusterID2)
+				Expect(err).To(BeNil())
+				Expect(reclaimed).To(BeFalse())
+
+				p, _, err := ipam.GetSubnetsPerCluster("11.0.0.0/16", "11.2.0.0/16", clusterID2)
+				Expect(err).To(BeNil())
+				Expect(p).To(Equal(remapped))
+			})
+		})
+
+		Context("When the remote PodCIDR had not been remapped", func() {
+			It("should be a no-op", func() {
+				reclaimed, err := ipam.ReclaimRemappedPodCIDR("11.0.0.0/16", clusterID1)
+				Expect(err).To(BeNil())
+				Expect(reclaimed).To(BeFalse())
+			})
+		})
+
+		Context("When the remote cluster is unknown", func() {
+			It("should be a no-op", func() {
+				reclaimed, err := ipam.ReclaimRemappedPodCIDR("13.0.0.0/16", "unknown-cluster-id")
+				Expect(err).To(BeNil())
+				Expect(reclaimed).To(BeFalse())
+			})
+		})
+	})
+
+	Describe("GetSubnetsPerClusterByUsage", func() {
+		BeforeEach(func() {
+			// The first half of the remote PodCIDR is assigned to another cluster, hence the whole PodCIDR overlaps.
+			_, _, err := ipam.GetSubnetsPerCluster("11.0.0.0/17", "11.1.0.0/16", clusterID1)
+			Expect(err).To(BeNil())
+		})
+
+		Context("When the used sub-ranges do not overlap", func() {
+			It("should reserve them without remapping the PodCIDR", func() {
+				p, e, err := ipam.GetSubnetsPerClusterByUsage("11.0.0.0/16", "11.0.128.0/20", "11.2.0.0/16", clusterID2)
+				Expect(err).To(BeNil())
+				Expect(p).To(Equal("11.0.128.0/20"))
+				Expect(e).To(Equal("11.2.0.0/16"))
+
+				ipamStorage, err := getIpamStorageResource()
+				Expect(err).To(BeNil())
+				Expect(ipamStorage.Spec.ClusterSubnets[clusterID2].RemotePodCIDR).To(Equal("11.0.128.0/20"))
+				Expect(ipamStorage.Spec.Prefixes).To(HaveKey("11.0.128.0/20"))
+
+				// The subnets are subsequently returned unchanged.
+				p, _, err = ipam.GetSubnetsPerClusterByUsage("11.0.0.0/16", "11.0.128.0/20", "11.2.0.0/16", clusterID2)
+				Expect(err).To(BeNil())
+				Expect(p).To(Equal("11.0.128.0/20"))
+			})
+		})
+
+		Context("When the used sub-ranges overlap as well", func() {
+			It("should remap the whole PodCIDR", func() {
+				p, _, err := ipam.GetSubnetsPerClusterByUsage("11.0.0.0/16", "11.0.0.0/20", "11.2.0.0/16", clusterID2)
+				Expect(err).To(BeNil())
+				Expect(p).ToNot(Equal("11.0.0.0/16"))
+				Expect(p).ToNot(Equal("11.0.0.0/20"))
+				Expect(liqonetutils.GetMask(p)).To(BeNumerically("==", 16))
+			})
+		})
+
+		Context("When the used PodCIDR is invalid", func() {
+			It("should return an error", func() {
+				_, _, err := ipam.GetSubnetsPerClusterByUsage("11.0.0.0/16", invalidValue, "11.2.0.0/16", clusterID2)
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+
 	Describe("FreeReservedSubnet", func() {
 		Context("Freeing a network that has been reserved previously", func() {
 			It("Should successfully free the subnet", func() {
@@ -948,6 +1221,169 @@ var _ = Describe("Ipam", func() {
 			})
 		})
 	})
+	Describe("ReconcileLocalNetworks", func() {
+		const serviceCIDR = "10.0.1.0/24"
+
+		BeforeEach(func() {
+			Expect(ipam.SetPodCIDR(homePodCIDR)).To(Succeed())
+			Expect(ipam.SetServiceCIDR(serviceCIDR)).To(Succeed())
+			_, _, err := ipam.GetSubnetsPerCluster(remotePodCIDR, remoteExternalCIDR, clusterID1)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		Context("When the local networks did not change", func() {
+			It("should not re-allocate any network", func() {
+				reallocated, err := ipam.ReconcileLocalNetworks(homePodCIDR, serviceCIDR)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(reallocated).To(BeEmpty())
+
+				subnets := ipam.ipamStorage.getClusterSubnets()[clusterID1]
+				Expect(subnets.RemotePodCIDR).To(Equal(remotePodCIDR))
+				Expect(subnets.RemoteExternalCIDR).To(Equal(remoteExternalCIDR))
+			})
+		})
+
+		Context("When the PodCIDR changed, and now overlaps with the networks of a remote cluster", func() {
+			const newPodCIDR = "10.50.0.0/16"
+			var (
+				reallocated []string
+				err         error
+			)
+
+			JustBeforeEach(func() {
+				reallocated, err = ipam.ReconcileLocalNetworks(newPodCIDR, serviceCIDR)
+			})
+
+			It("should succeed", func() { Expect(err).ToNot(HaveOccurred()) })
+			It("should report the cluster whose networks have been re-allocated", func() {
+				Expect(reallocated).To(ConsistOf(clusterID1))
+			})
+			It("should re-allocate the conflicting network", func() {
+				subnets := ipam.ipamStorage.getClusterSubnets()[clusterID1]
+				Expect(subnets.RemotePodCIDR).ToNot(BeEmpty())
+				Expect(liqonetutils.GetMask(subnets.RemotePodCIDR)).To(BeNumerically("==", 16))
+				overlaps, err := ipam.overlapsWithNetwork(subnets.RemotePodCIDR, newPodCIDR)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(overlaps).To(BeFalse())
+			})
+			It("should preserve the non conflicting networks", func() {
+				Expect(ipam.ipamStorage.getClusterSubnets()[clusterID1].RemoteExternalCIDR).To(Equal(remoteExternalCIDR))
+			})
+			It("should return the re-allocated networks for the cluster", func() {
+				podCIDR, externalCIDR, err := ipam.GetSubnetsPerCluster(remotePodCIDR, remoteExternalCIDR, clusterID1)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(podCIDR).To(Equal(ipam.ipamStorage.getClusterSubnets()[clusterID1].RemotePodCIDR))
+				Expect(externalCIDR).To(Equal(remoteExternalCIDR))
+			})
+			It("should update the local PodCIDR", func() {
+				Expect(ipam.ipamStorage.getPodCIDR()).To(Equal(newPodCIDR))
+			})
+			It("should release the previous PodCIDR", func() {
+				Expect(ipam.AcquireReservedSubnet(homePodCIDR)).To(Succeed())
+			})
+			It("should leave a consistent state", func() {
+				Expect(ipam.Validate([]string{clusterID1})).To(BeEmpty())
+			})
+			It("should be a no-op when invoked again", func() {
+				podCIDR := ipam.ipamStorage.getClusterSubnets()[clusterID1].RemotePodCIDR
+				reallocated, err := ipam.ReconcileLocalNetworks(newPodCIDR, serviceCIDR)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(reallocated).To(BeEmpty())
+				Expect(ipam.ipamStorage.getClusterSubnets()[clusterID1].RemotePodCIDR).To(Equal(podCIDR))
+			})
+		})
+
+		Context("When the PodCIDR changed, and the NAT mappings of the remote cluster have been initialized", func() {
+			const newPodCIDR = "10.50.0.0/16"
+			var mappedIP string
+
+			BeforeEach(func() {
+				_, err := ipam.GetExternalCIDR(uint8(24))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(ipam.AddLocalSubnetsPerCluster(localNATPodCIDR, localNATExternalCIDR, clusterID1)).To(Succeed())
+
+				response, err := ipam.MapEndpointIP(context.Background(), &MapRequest{ClusterID: clusterID1, Ip: externalEndpointIP})
+				Expect(err).ToNot(HaveOccurred())
+				mappedIP = response.GetIp()
+
+				_, err = ipam.ReconcileLocalNetworks(newPodCIDR, serviceCIDR)
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("should update the PodCIDR of the NAT mappings to the re-allocated one", func() {
+				natMapping, err := getNatMappingResourcePerCluster(clusterID1)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(natMapping.Spec.PodCIDR).To(Equal(ipam.ipamStorage.getClusterSubnets()[clusterID1].RemotePodCIDR))
+				Expect(natMapping.Spec.PodCIDR).ToNot(Equal(remotePodCIDR))
+			})
+			It("should preserve the ExternalCIDR of the NAT mappings", func() {
+				natMapping, err := getNatMappingResourcePerCluster(clusterID1)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(natMapping.Spec.ExternalCIDR).To(Equal(localNATExternalCIDR))
+			})
+			It("should preserve the existing endpoint mappings", func() {
+				natMapping, err := getNatMappingResourcePerCluster(clusterID1)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(natMapping.Spec.ClusterMappings).To(HaveKeyWithValue(externalEndpointIP, mappedIP))
+
+				mappings, err := ipam.natMappingInflater.GetNatMappings(clusterID1)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(mappings).To(HaveKeyWithValue(externalEndpointIP, mappedIP))
+			})
+		})
+
+		Context("When the update of the NAT mappings of a cluster fails", func() {
+			const (
+				otherPodCIDR = "10.51.0.0/16"
+				newPodCIDR   = "10.50.0.0/15"
+			)
+
+			BeforeEach(func() {
+				_, err := ipam.GetExternalCIDR(uint8(24))
+				Expect(err).ToNot(HaveOccurred())
+				_, _, err = ipam.GetSubnetsPerCluster(otherPodCIDR, "10.61.0.0/16", clusterID2)
+				Expect(err).ToNot(HaveOccurred())
+				for _, clusterID := range []string{clusterID1, clusterID2} {
+					Expect(ipam.AddLocalSubnetsPerCluster(consts.DefaultCIDRValue, consts.DefaultCIDRValue, clusterID)).To(Succeed())
+				}
+
+				// Delete the NatMapping resource of the second cluster, to make the update of its NAT mappings fail.
+				natMapping, err := getNatMappingResourcePerCluster(clusterID2)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(dynClient.Resource(liqonetapi.NatMappingGroupResource).Delete(
+					context.Background(), natMapping.GetName(), v1.DeleteOptions{})).To(Succeed())
+			})
+
+			It("should fail, rolling back the NAT mappings of the other clusters", func() {
+				_, err := ipam.ReconcileLocalNetworks(newPodCIDR, serviceCIDR)
+				Expect(err).To(MatchError(ContainSubstring("cannot update the NAT mappings of cluster %s", clusterID2)))
+
+				natMapping, err := getNatMappingResourcePerCluster(clusterID1)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(natMapping.Spec.PodCIDR).To(Equal(remotePodCIDR))
+			})
+		})
+
+		Context("When the ServiceCIDR changed, and now overlaps with the networks of a remote cluster", func() {
+			It("should re-allocate the conflicting network", func() {
+				reallocated, err := ipam.ReconcileLocalNetworks(homePodCIDR, "10.60.0.0/20")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(reallocated).To(ConsistOf(clusterID1))
+
+				subnets := ipam.ipamStorage.getClusterSubnets()[clusterID1]
+				Expect(subnets.RemotePodCIDR).To(Equal(remotePodCIDR))
+				Expect(subnets.RemoteExternalCIDR).ToNot(Equal(remoteExternalCIDR))
+				Expect(ipam.ipamStorage.getServiceCIDR()).To(Equal("10.60.0.0/20"))
+			})
+		})
+
+		Context("When the new networks are not configured", func() {
+			It("should return an error", func() {
+				_, err := ipam.ReconcileLocalNetworks(homePodCIDR, "")
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
 	Describe("MapEndpointIP", func() {
 		Context("If the endpoint IP belongs to local PodCIDR", func() {
 			Context("and the remote cluster has not remapped the local PodCIDR", func() {
@@ -1320,6 +1756,58 @@ var _ = Describe("Ipam", func() {
 		})
 	})
 
+	Describe("StaticIPOverrides", func() {
+		Context("When setting an invalid override", func() {
+			It("should return an error", func() {
+				Expect(ipam.SetStaticIPOverrides(map[string]string{invalidValue: "10.0.0.1"})).ToNot(Succeed())
+				Expect(ipam.SetStaticIPOverrides(map[string]string{"10.0.0.1": invalidValue})).ToNot(Succeed())
+			})
+		})
+
+		Context("When the remote Pod CIDR has been remapped by home cluster", func() {
+			var mappedPodCIDR, overridden, other string
+
+			BeforeEach(func() {
+				var err error
+				overridden, err = liqonetutils.GetFirstIP(remotePodCIDR)
+				Expect(err).ToNot(HaveOccurred())
+				other, err = liqonetutils.MapIPToNetwork(remotePodCIDR, "0.0.0.2")
+				Expect(err).ToNot(HaveOccurred())
+
+				// Reserve original PodCIDR so that home cluster will remap it
+				Expect(ipam.AcquireReservedSubnet(remotePodCIDR)).To(Succeed())
+				mappedPodCIDR, _, err = ipam.GetSubnetsPerCluster(remotePodCIDR, remoteExternalCIDR, clusterID1)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(mappedPodCIDR).ToNot(Equal(remotePodCIDR))
+
+				Expect(ipam.SetStaticIPOverrides(map[string]string{overridden: "192.168.100.53"})).To(Succeed())
+			})
+
+			It("should map the overridden IPs to their fixed targets", func() {
+				response, err := ipam.GetHomePodIP(context.Background(), &GetHomePodIPRequest{Ip: overridden, ClusterID: clusterID1})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(response.GetHomeIP()).To(Equal("192.168.100.53"))
+			})
+
+			It("should map the other IPs through the algorithmic remapping", func() {
+				response, err := ipam.GetHomePodIP(context.Background(), &GetHomePodIPRequest{Ip: other, ClusterID: clusterID1})
+				Expect(err).ToNot(HaveOccurred())
+				remappedIP, err := liqonetutils.MapIPToNetwork(mappedPodCIDR, other)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(response.GetHomeIP()).To(Equal(remappedIP))
+			})
+
+			It("should stop overriding the IPs once the table is replaced", func() {
+				Expect(ipam.SetStaticIPOverrides(nil)).To(Succeed())
+				response, err := ipam.GetHomePodIP(context.Background(), &GetHomePodIPRequest{Ip: overridden, ClusterID: clusterID1})
+				Expect(err).ToNot(HaveOccurred())
+				remappedIP, err := liqonetutils.MapIPToNetwork(mappedPodCIDR, overridden)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(response.GetHomeIP()).To(Equal(remappedIP))
+			})
+		})
+	})
+
 	Describe("UnmapEndpointIP", func() {
 		Context("Passing invalid parameters", func() {
 			It("Empty clusterID", func() {
@@ -1725,7 +2213,1015 @@ var _ = Describe("Ipam", func() {
 			})
 		})
 	})
-})
+
+	Describe("CheckReservedSubnets", func() {
+		const (
+			podCIDR     = "10.200.0.0/16"
+			serviceCIDR = "10.96.0.0/12"
+		)
+
+		Context("When the reserved subnets do not overlap with the local networks", func() {
+			It("should succeed", func() {
+				Expect(CheckReservedSubnets(podCIDR, serviceCIDR, []string{"192.168.0.0/24", "172.16.0.0/16"})).To(Succeed())
+			})
+		})
+
+		Context("When no reserved subnets are configured", func() {
+			It("should succeed", func() {
+				Expect(CheckReservedSubnets(podCIDR, serviceCIDR, nil)).To(Succeed())
+			})
+		})
+
+		Context("When a reserved subnet overlaps with the PodCIDR", func() {
+			It("should return an error mentioning the PodCIDR", func() {
+				err := CheckReservedSubnets(podCIDR, serviceCIDR, []string{"192.168.0.0/24", "10.200.10.0/24"})
+				Expect(err).To(MatchError(ContainSubstring("podCIDR %s overlaps with the reserved subnet %s", podCIDR, "10.200.10.0/24")))
+			})
+		})
+
+		Context("When a reserved subnet overlaps with the ServiceCIDR", func() {
+			It("should return an error mentioning the ServiceCIDR", func() {
+				err := CheckReservedSubnets(podCIDR, serviceCIDR, []string{"10.0.0.0/8"})
+				Expect(err).To(MatchError(ContainSubstring("podCIDR")))
+				err = CheckReservedSubnets(podCIDR, serviceCIDR, []string{"10.100.0.0/16"})
+				Expect(err).To(MatchError(ContainSubstring("serviceCIDR %s overlaps with the reserved subnet %s", serviceCIDR, "10.100.0.0/16")))
+			})
+		})
+
+		Context("When a reserved subnet is invalid", func() {
+			It("should return an error", func() {
+				Expect(CheckReservedSubnets(podCIDR, serviceCIDR, []string{invalidValue})).ToNot(Succeed())
+			})
+		})
+
+		Context("When the ServiceCIDR is not configured", func() {
+			It("should return an error mentioning the ServiceCIDR", func() {
+				err := CheckReservedSubnets(podCIDR, "", nil)
+				Expect(err).To(MatchError(ContainSubstring("serviceCIDR is not configured")))
+			})
+		})
+
+		Context("When the ServiceCIDR is invalid", func() {
+			It("should return an error mentioning the ServiceCIDR", func() {
+				err := CheckReservedSubnets(podCIDR, invalidValue, nil)
+				Expect(err).To(MatchError(ContainSubstring("serviceCIDR %q is not a valid CIDR", invalidValue)))
+			})
+		})
+
+		Context("When the PodCIDR is not configured", func() {
+			It("should return an error mentioning the PodCIDR", func() {
+				err := CheckReservedSubnets("", serviceCIDR, nil)
+				Expect(err).To(MatchError(ContainSubstring("podCIDR is not configured")))
+			})
+		})
+	})
+
+	Describe("PlanReservations", func() {
+		const pool = "10.0.0.0/16"
+
+		// cidrs parses the given networks, failing the test in case of errors.
+		cidrs := func(networks ...string) []*net.IPNet {
+			var parsed []*net.IPNet
+			for _, network := range networks {
+				_, ipnet, err := net.ParseCIDR(network)
+				Expect(err).ToNot(HaveOccurred())
+				parsed = append(parsed, ipnet)
+			}
+			return parsed
+		}
+
+		strs := func(networks []*net.IPNet) []string {
+			var out []string
+			for _, network := range networks {
+				out = append(out, network.String())
+			}
+			return out
+		}
+
+		Context("When the remote networks do not overlap with the pool nor among each other", func() {
+			It("should reserve no subnets", func() {
+				reserved, err := PlanReservations(cidrs("192.168.0.0/24", "172.16.0.0/16"), cidrs(pool)[0])
+				Expect(err).ToNot(HaveOccurred())
+				Expect(reserved).To(BeEmpty())
+			})
+		})
+
+		Context("When the remote networks overlap among each other", func() {
+			It("should reserve a subnet from the pool for each conflicting network", func() {
+				reserved, err := PlanReservations(cidrs("192.168.0.0/24", "192.168.0.0/24", "192.168.0.128/25"), cidrs(pool)[0])
+				Expect(err).ToNot(HaveOccurred())
+				Expect(reserved).To(HaveLen(2))
+				first, _ := reserved[0].Mask.Size()
+				second, _ := reserved[1].Mask.Size()
+				Expect(first).To(Equal(24))
+				Expect(second).To(Equal(25))
+				Expect(reserved[0].Contains(reserved[1].IP)).To(BeFalse())
+			})
+		})
+
+		Context("When the remote networks belong to the pool", func() {
+			It("should reserve them as-is if free, and remap them otherwise", func() {
+				reserved, err := PlanReservations(cidrs("10.0.1.0/24", "10.0.1.0/24", "10.0.2.0/24"), cidrs(pool)[0])
+				Expect(err).ToNot(HaveOccurred())
+				Expect(strs(reserved)).To(HaveLen(3))
+				Expect(strs(reserved)[0]).To(Equal("10.0.1.0/24"))
+				Expect(strs(reserved)[1]).ToNot(BeElementOf("10.0.1.0/24", "10.0.2.0/24"))
+				Expect(strs(reserved)[2]).To(Equal("10.0.2.0/24"))
+			})
+		})
+
+		Context("When the pool cannot satisfy the plan", func() {
+			It("should return an error", func() {
+				_, err := PlanReservations(cidrs("10.0.0.0/17", "10.0.0.0/17", "10.0.0.0/17"), cidrs(pool)[0])
+				Expect(err).To(MatchError(ContainSubstring("cannot satisfy the plan")))
+			})
+		})
+
+		Context("When the pool is not specified", func() {
+			It("should return an error", func() {
+				_, err := PlanReservations(cidrs("10.0.0.0/24"), nil)
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+
+	Describe("MarshalState and LoadIPAMState", func() {
+		var (
+			source *IPAM
+			state  []byte
+			err    error
+		)
+
+		BeforeEach(func() {
+			Expect(ipam.SetPodCIDR(homePodCIDR)).To(Succeed())
+			Expect(ipam.SetServiceCIDR("10.96.0.0/12")).To(Succeed())
+			_, err = ipam.GetExternalCIDR(24)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ipam.AcquireReservedSubnet("10.200.0.0/16")).To(Succeed())
+			// The second cluster conflicts with the first one, hence its networks are remapped.
+			_, _, err = ipam.GetSubnetsPerCluster(remotePodCIDR, remoteExternalCIDR, clusterID1)
+			Expect(err).ToNot(HaveOccurred())
+			_, _, err = ipam.GetSubnetsPerCluster(remotePodCIDR, remoteExternalCIDR, clusterID2)
+			Expect(err).ToNot(HaveOccurred())
+
+			state, err = ipam.MarshalState()
+			Expect(err).ToNot(HaveOccurred())
+
+			// Switch to a brand new storage, to load the state into.
+			source = ipam
+			Expect(setDynClient()).To(Succeed())
+		})
+
+		Context("When the state is internally consistent", func() {
+			var loaded *IPAM
+
+			BeforeEach(func() {
+				loaded, err = LoadIPAMState(state, dynClient)
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("should round-trip the whole state", func() {
+				reloaded, err := loaded.MarshalState()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(reloaded).To(Equal(state))
+				Expect(loaded.ipamStorage.getSpec()).To(Equal(source.ipamStorage.getSpec()))
+			})
+
+			It("should preserve the networks allocated to the remote clusters", func() {
+				for _, clusterID := range []string{clusterID1, clusterID2} {
+					expectedPod, expectedExt, err := source.GetSubnetsPerCluster(remotePodCIDR, remoteExternalCIDR, clusterID)
+					Expect(err).ToNot(HaveOccurred())
+					p, e, err := loaded.GetSubnetsPerCluster(remotePodCIDR, remoteExternalCIDR, clusterID)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(p).To(Equal(expectedPod))
+					Expect(e).To(Equal(expectedExt))
+				}
+			})
+
+			It("should keep the acquired networks unavailable", func() {
+				Expect(loaded.AcquireReservedSubnet("10.200.0.0/16")).ToNot(Succeed())
+			})
+		})
+
+		Context("When the state refers to a network not acquired by the IPAM", func() {
+			It("should return an error", func() {
+				spec := source.ipamStorage.getSpec()
+				spec.ReservedSubnets = append(spec.ReservedSubnets, "10.210.0.0/16")
+				inconsistent, err := json.Marshal(&liqonetapi.IpamStorage{TypeMeta: v1.TypeMeta{
+					APIVersion: liqonetapi.GroupVersion.String(), Kind: "IpamStorage"}, Spec: spec})
+				Expect(err).ToNot(HaveOccurred())
+
+				_, err = LoadIPAMState(inconsistent, dynClient)
+				Expect(err).To(MatchError(ContainSubstring("reserved subnet 10.210.0.0/16 does not correspond to any prefix")))
+			})
+		})
+
+		Context("When the data is not an IPAM state", func() {
+			It("should return an error", func() {
+				_, err = LoadIPAMState([]byte(`{"apiVersion": "v1", "kind": "ConfigMap"}`), dynClient)
+				Expect(err).To(MatchError(ContainSubstring("unexpected IPAM state type")))
+			})
+		})
+
+		Context("When the target storage already holds the networks of some remote clusters", func() {
+			It("should return an error", func() {
+				_, err = LoadIPAMState(state, source.ipamStorage.(*IPAMStorage).dynClient)
+				Expect(err).To(MatchError(ContainSubstring("networks are already allocated")))
+			})
+		})
+	})
+
+	Describe("PreflightPeering", func() {
+		var (
+			localReserved                []*net.IPNet
+			remotePodCIDR, remoteSvcCIDR *net.IPNet
+			report                       *PreflightReport
+			err                          error
+		)
+
+		cidr := func(network string) *net.IPNet {
+			_, ipnet, err := net.ParseCIDR(network)
+			Expect(err).ToNot(HaveOccurred())
+			return ipnet
+		}
+
+		BeforeEach(func() {
+			localReserved = []*net.IPNet{cidr("10.0.0.0/16"), cidr("10.1.0.0/16"), cidr("192.168.0.0/24")}
+		})
+
+		JustBeforeEach(func() {
+			report, err = PreflightPeering(localReserved, remotePodCIDR, remoteSvcCIDR)
+		})
+
+		Context("When the remote networks do not overlap with the local ones", func() {
+			BeforeEach(func() { remotePodCIDR, remoteSvcCIDR = cidr("10.2.0.0/16"), cidr("10.3.0.0/16") })
+
+			It("should succeed", func() { Expect(err).ToNot(HaveOccurred()) })
+			It("should report that NAT is not needed", func() {
+				Expect(report.NATNeeded()).To(BeFalse())
+				Expect(report.PodCIDRConflicts).To(BeEmpty())
+				Expect(report.ServiceCIDRConflicts).To(BeEmpty())
+			})
+			It("should report that the allocation is possible", func() { Expect(report.AllocationPossible).To(BeTrue()) })
+		})
+
+		Context("When the remote networks overlap with the local ones", func() {
+			BeforeEach(func() { remotePodCIDR, remoteSvcCIDR = cidr("10.0.0.0/16"), cidr("192.168.0.0/16") })
+
+			It("should succeed", func() { Expect(err).ToNot(HaveOccurred()) })
+			It("should report that NAT is needed for both networks", func() {
+				Expect(report.PodCIDRNATNeeded).To(BeTrue())
+				Expect(report.ServiceCIDRNATNeeded).To(BeTrue())
+				Expect(report.PodCIDRConflicts).To(ConsistOf(cidr("10.0.0.0/16")))
+				Expect(report.ServiceCIDRConflicts).To(ConsistOf(cidr("192.168.0.0/24")))
+			})
+			It("should report that the allocation is possible", func() { Expect(report.AllocationPossible).To(BeTrue()) })
+		})
+
+		Context("When only the remote ServiceCIDR overlaps with the local networks", func() {
+			BeforeEach(func() { remotePodCIDR, remoteSvcCIDR = cidr("10.2.0.0/16"), cidr("10.1.128.0/17") })
+
+			It("should report that NAT is needed for the ServiceCIDR only", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(report.PodCIDRNATNeeded).To(BeFalse())
+				Expect(report.ServiceCIDRNATNeeded).To(BeTrue())
+				Expect(report.ServiceCIDRConflicts).To(ConsistOf(cidr("10.1.0.0/16")))
+			})
+		})
+
+		Context("When the network pools are entirely reserved", func() {
+			BeforeEach(func() {
+				localReserved = []*net.IPNet{cidr("10.0.0.0/8"), cidr("172.16.0.0/12"), cidr("192.168.0.0/16")}
+				remotePodCIDR, remoteSvcCIDR = cidr("10.0.0.0/16"), cidr("100.64.0.0/16")
+			})
+
+			It("should succeed", func() { Expect(err).ToNot(HaveOccurred()) })
+			It("should report that NAT is needed", func() { Expect(report.PodCIDRNATNeeded).To(BeTrue()) })
+			It("should report that the allocation is not possible", func() { Expect(report.AllocationPossible).To(BeFalse()) })
+		})
+
+		Context("When the remote networks are not specified", func() {
+			BeforeEach(func() { remotePodCIDR, remoteSvcCIDR = nil, cidr("10.3.0.0/16") })
+
+			It("should return an error", func() { Expect(err).To(HaveOccurred()) })
+		})
+	})
+
+	Describe("AllocationPolicy", func() {
+		// fragments returns the number of free blocks across all the network pools.
+		fragments := func() int {
+			count := 0
+			for _, pool := range ipam.ipamStorage.getPools() {
+				count += len(ipam.ipam.PrefixFrom(context.TODO(), pool).Usage().AvailablePrefixes)
+			}
+			return count
+		}
+
+		// allocate reserves all the 192.168.0.0/16 pool, except for the last /23 network,
+		// and then allocates two /24 networks according to the configured policy.
+		allocate := func() []string {
+			for _, network := range []string{"192.168.0.0/17", "192.168.128.0/18", "192.168.192.0/19", "192.168.224.0/20",
+				"192.168.240.0/21", "192.168.248.0/22", "192.168.252.0/23", "192.168.254.0/23"} {
+				Expect(ipam.AcquireReservedSubnet(network)).To(Succeed())
+			}
+			Expect(ipam.FreeReservedSubnet("192.168.254.0/23")).To(Succeed())
+
+			var networks []string
+			for i := 0; i < 2; i++ {
+				network, err := ipam.getNetworkFromPool(24)
+				Expect(err).ToNot(HaveOccurred())
+				networks = append(networks, network)
+			}
+			return networks
+		}
+
+		Context("When setting an invalid policy", func() {
+			It("should return an error", func() {
+				Expect(ipam.SetAllocationPolicy("invalid")).ToNot(Succeed())
+			})
+		})
+
+		Context("When using the first-fit policy", func() {
+			It("should fragment the first pool with enough free space", func() {
+				Expect(ipam.SetAllocationPolicy(FirstFitAllocationPolicy)).To(Succeed())
+				Expect(allocate()).To(ConsistOf("10.0.0.0/24", "10.0.1.0/24"))
+				// 10.0.0.0/8 is split into 15 free blocks (from /23 to /9), while the hole in 192.168.0.0/16 remains unused.
+				Expect(fragments()).To(Equal(17))
+			})
+		})
+
+		Context("When using the best-fit policy", func() {
+			It("should fill the smallest adequate block, leading to less fragmentation", func() {
+				Expect(ipam.SetAllocationPolicy(BestFitAllocationPolicy)).To(Succeed())
+				Expect(allocate()).To(ConsistOf("192.168.254.0/24", "192.168.255.0/24"))
+				// The other pools are left untouched.
+				Expect(fragments()).To(Equal(2))
+			})
+		})
+
+		Context("When no block is large enough", func() {
+			It("should return an error", func() {
+				Expect(ipam.SetAllocationPolicy(BestFitAllocationPolicy)).To(Succeed())
+				_, err := ipam.getNetworkFromPool(7)
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+
+	Describe("PeekNextSubnet", func() {
+		// peekAndAllocate checks that the peeked subnet matches the one subsequently allocated, for each of the given sizes.
+		peekAndAllocate := func(sizes ...int) {
+			for _, size := range sizes {
+				peeked, err := ipam.PeekNextSubnet(size)
+				Expect(err).ToNot(HaveOccurred())
+				allocated, err := ipam.getNetworkFromPool(uint8(size))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(peeked.String()).To(Equal(allocated))
+			}
+		}
+
+		BeforeEach(func() {
+			// Fragment the pools, so that the allocations do not trivially start from the beginning of the first pool.
+			for _, network := range []string{"10.0.0.0/24", "10.0.2.0/23", "192.168.0.0/17", "192.168.192.0/18"} {
+				Expect(ipam.AcquireReservedSubnet(network)).To(Succeed())
+			}
+		})
+
+		Context("When using the first-fit policy", func() {
+			It("should match the subsequent allocations", func() { peekAndAllocate(24, 24, 16, 25, 24, 23, 30, 9) })
+		})
+
+		Context("When using the best-fit policy", func() {
+			BeforeEach(func() { Expect(ipam.SetAllocationPolicy(BestFitAllocationPolicy)).To(Succeed()) })
+
+			It("should match the subsequent allocations", func() { peekAndAllocate(24, 24, 16, 25, 24, 23, 30, 18) })
+		})
+
+		Context("When the pools priority is configured", func() {
+			BeforeEach(func() { Expect(ipam.SetPoolsPriority([]string{"192.168.0.0/16"})).To(Succeed()) })
+
+			It("should take the subnet from the prioritized pool", func() {
+				peeked, err := ipam.PeekNextSubnet(18)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(peeked.String()).To(Equal("192.168.128.0/18"))
+				peekAndAllocate(18, 18)
+			})
+		})
+
+		Context("When peeking multiple times", func() {
+			It("should not reserve the subnet", func() {
+				first, err := ipam.PeekNextSubnet(24)
+				Expect(err).ToNot(HaveOccurred())
+				second, err := ipam.PeekNextSubnet(24)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(second).To(Equal(first))
+				Expect(ipam.isAcquired(first.String())).To(BeFalse())
+			})
+		})
+
+		Context("When no subnet of the given size is available", func() {
+			It("should return an error", func() {
+				_, err := ipam.PeekNextSubnet(7)
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("When the prefix length is invalid", func() {
+			It("should return an error", func() {
+				_, err := ipam.PeekNextSubnet(33)
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+
+	Describe("RemapHeadroom", func() {
+		const network = "10.0.1.0/24"
+
+		BeforeEach(func() {
+			// Reserve the network, so that a subsequent request for the same network requires a remapping.
+			Expect(ipam.AcquireReservedSubnet(network)).To(Succeed())
+		})
+
+		Context("When setting an invalid headroom", func() {
+			It("should return an error", func() {
+				Expect(ipam.SetRemapHeadroom(33)).ToNot(Succeed())
+			})
+		})
+
+		Context("When no headroom is configured", func() {
+			It("should remap the network to one of the same size", func() {
+				mapped, err := ipam.getOrRemapNetwork(network, clusterID1)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(liqonetutils.GetMask(mapped)).To(BeNumerically("==", 24))
+			})
+		})
+
+		Context("When a headroom is configured", func() {
+			It("should remap the network to a larger one", func() {
+				Expect(ipam.SetRemapHeadroom(2)).To(Succeed())
+				mapped, err := ipam.getOrRemapNetwork(network, clusterID1)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(liqonetutils.GetMask(mapped)).To(BeNumerically("==", 22))
+			})
+		})
+
+		Context("When the pools cannot satisfy the headroom", func() {
+			It("should return an error", func() {
+				// A /7 network is larger than any of the pools.
+				Expect(ipam.SetRemapHeadroom(17)).To(Succeed())
+				_, err := ipam.getOrRemapNetwork(network, clusterID1)
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("When the headroom exceeds the mask length", func() {
+			It("should return an error", func() {
+				Expect(ipam.SetRemapHeadroom(25)).To(Succeed())
+				_, err := ipam.getOrRemapNetwork(network, clusterID1)
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+
+	Describe("PoolsPriority", func() {
+		const (
+			network  = "10.0.0.0/17"
+			primary  = "192.168.0.0/16"
+			overflow = "172.16.0.0/12"
+		)
+
+		Context("When setting the priority of a non-existing pool", func() {
+			It("should return an error", func() {
+				Expect(ipam.SetPoolsPriority([]string{"100.64.0.0/10"})).ToNot(Succeed())
+			})
+		})
+
+		Context("When setting the same pool multiple times", func() {
+			It("should return an error", func() {
+				Expect(ipam.SetPoolsPriority([]string{primary, primary})).ToNot(Succeed())
+			})
+		})
+
+		Context("When the pools priority is configured", func() {
+			BeforeEach(func() {
+				Expect(ipam.SetPoolsPriority([]string{primary, overflow})).To(Succeed())
+				// Reserve the network, so that subsequent requests for the same network require a remapping.
+				Expect(ipam.AcquireReservedSubnet(network)).To(Succeed())
+			})
+
+			It("should preserve the remaining pools after the prioritized ones", func() {
+				Expect(ipam.ipamStorage.getPools()).To(Equal([]string{primary, overflow, "10.0.0.0/8"}))
+			})
+
+			It("should fall through to the overflow pool once the primary one is exhausted", func() {
+				// The primary pool can accommodate two /17 networks only.
+				for i := 0; i < 2; i++ {
+					mapped, err := ipam.getOrRemapNetwork(network, clusterID1)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(ipam.NetworkPool(mapped)).To(Equal(primary))
+				}
+
+				mapped, err := ipam.getOrRemapNetwork(network, clusterID1)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(ipam.NetworkPool(mapped)).To(Equal(overflow))
+			})
+		})
+	})
+
+	Describe("custom network pools", func() {
+		const pool = "100.64.0.0/10"
+
+		var custom *IPAM
+
+		BeforeEach(func() {
+			Expect(setDynClient()).To(Succeed())
+			custom = NewIPAM()
+			Expect(custom.Init([]string{pool}, dynClient, 0)).To(Succeed())
+			Expect(custom.SetPodCIDR(homePodCIDR)).To(Succeed())
+		})
+
+		It("should configure the given pools only", func() {
+			Expect(custom.ipamStorage.getPools()).To(ConsistOf(pool))
+		})
+
+		It("should remap the conflicting networks within the given pools", func() {
+			for _, clusterID := range []string{clusterID1, clusterID2, clusterID3} {
+				mappedPodCIDR, mappedExternalCIDR, err := custom.GetSubnetsPerCluster(homePodCIDR, "10.0.1.0/24", clusterID)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(custom.NetworkPool(mappedPodCIDR)).To(Equal(pool))
+				if clusterID != clusterID1 {
+					Expect(custom.NetworkPool(mappedExternalCIDR)).To(Equal(pool))
+				}
+			}
+		})
+
+		It("should fail with a clear error if the network does not fit in the given pools", func() {
+			_, _, err := custom.GetSubnetsPerCluster("10.0.0.0/8", "192.168.0.0/24", clusterID1)
+			Expect(err).To(MatchError(ErrNoNetworksAvailable))
+			Expect(err).To(MatchError(ContainSubstring("none of the network pools [%s] can accommodate a /8 network", pool)))
+		})
+
+		Context("When the pools have already been initialized", func() {
+			const additional = "100.128.0.0/16"
+
+			var restarted *IPAM
+
+			BeforeEach(func() { restarted = NewIPAM() })
+
+			It("should preserve the existing pools, if unchanged", func() {
+				Expect(restarted.Init([]string{pool}, dynClient, 0)).To(Succeed())
+				Expect(restarted.ipamStorage.getPools()).To(ConsistOf(pool))
+			})
+
+			It("should add the newly configured pools", func() {
+				Expect(restarted.Init([]string{pool, additional}, dynClient, 0)).To(Succeed())
+				Expect(restarted.ipamStorage.getPools()).To(ConsistOf(pool, additional))
+				Expect(restarted.NetworkPool("100.128.1.0/24")).To(Equal(additional))
+			})
+
+			It("should fail if the configured pools replace the existing ones", func() {
+				err := restarted.Init(Pools, dynClient, 0)
+				Expect(err).To(MatchError(ContainSubstring("network pools [%s] are no longer configured", pool)))
+				Expect(restarted.ipamStorage.getPools()).To(ConsistOf(pool))
+			})
+
+			It("should fail if the configured pools overlap with the existing ones", func() {
+				Expect(restarted.Init([]string{pool, "100.64.0.0/16"}, dynClient, 0)).To(
+					MatchError(ContainSubstring("cannot add the configured network pool 100.64.0.0/16")))
+				Expect(restarted.ipamStorage.getPools()).To(ConsistOf(pool))
+			})
+		})
+	})
+
+	Describe("WithTransaction", func() {
+		const (
+			network1 = "10.0.1.0/24"
+			network2 = "10.0.2.0/24"
+			network3 = "10.0.3.0/24"
+		)
+
+		Context("When the transaction succeeds", func() {
+			It("should commit all the operations", func() {
+				Expect(ipam.AcquireReservedSubnet(network3)).To(Succeed())
+				Expect(ipam.WithTransaction(func(tx *AllocTx) error {
+					Expect(tx.Reserve(network1)).To(Succeed())
+					Expect(tx.Reserve(network2)).To(Succeed())
+					return tx.Release(network3)
+				})).To(Succeed())
+
+				Expect(ipam.isAcquired(network1)).To(BeTrue())
+				Expect(ipam.isAcquired(network2)).To(BeTrue())
+				Expect(ipam.isAcquired(network3)).To(BeFalse())
+			})
+		})
+
+		Context("When the transaction fails", func() {
+			It("should roll back all the operations", func() {
+				Expect(ipam.AcquireReservedSubnet(network3)).To(Succeed())
+				err := ipam.WithTransaction(func(tx *AllocTx) error {
+					Expect(tx.Reserve(network1)).To(Succeed())
+					Expect(tx.Release(network3)).To(Succeed())
+					Expect(tx.Reserve(network2)).To(Succeed())
+					// The network has already been reserved within the transaction.
+					return tx.Reserve(network1)
+				})
+				Expect(err).To(HaveOccurred())
+
+				Expect(ipam.isAcquired(network1)).To(BeFalse())
+				Expect(ipam.isAcquired(network2)).To(BeFalse())
+				Expect(ipam.isAcquired(network3)).To(BeTrue())
+			})
+
+			It("should return the error of the function", func() {
+				err := ipam.WithTransaction(func(tx *AllocTx) error { return fmt.Errorf("fake error") })
+				Expect(err).To(MatchError("fake error"))
+			})
+		})
+
+		Context("When releasing a network which is not reserved", func() {
+			It("should not reserve it in case of rollback", func() {
+				Expect(ipam.WithTransaction(func(tx *AllocTx) error {
+					Expect(tx.Release(network1)).To(Succeed())
+					return fmt.Errorf("fake error")
+				})).ToNot(Succeed())
+				Expect(ipam.isAcquired(network1)).To(BeFalse())
+			})
+		})
+	})
+
+	Describe("PoolUtilizationCollector", func() {
+		const pool = "192.168.0.0/16"
+		var collector *PoolUtilizationCollector
+
+		BeforeEach(func() { collector = NewPoolUtilizationCollector(ipam, 0.7, nil) })
+
+		Context("When no networks have been reserved from the pool", func() {
+			It("should report an empty pool, not exceeding the threshold", func() {
+				utilization, err := collector.Check()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(utilization).To(HaveKeyWithValue(pool, 0.))
+				Expect(collector.Exceeded(pool)).To(BeFalse())
+			})
+		})
+
+		Context("When the reserved networks do not cross the threshold", func() {
+			It("should not fire the signal", func() {
+				Expect(ipam.AcquireReservedSubnet("192.168.0.0/17")).To(Succeed())
+				utilization, err := collector.Check()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(utilization).To(HaveKeyWithValue(pool, 0.5))
+				Expect(collector.Exceeded(pool)).To(BeFalse())
+			})
+		})
+
+		Context("When the reserved networks cross the threshold", func() {
+			It("should fire the signal for that pool only", func() {
+				Expect(ipam.AcquireReservedSubnet("192.168.0.0/17")).To(Succeed())
+				Expect(ipam.AcquireReservedSubnet("192.168.128.0/18")).To(Succeed())
+				utilization, err := collector.Check()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(utilization).To(HaveKeyWithValue(pool, 0.75))
+				Expect(collector.Exceeded(pool)).To(BeTrue())
+				Expect(collector.Exceeded("10.0.0.0/8")).To(BeFalse())
+
+				expected := fmt.Sprintf(`
+# HELP liqo_ipam_pool_utilization_threshold_exceeded Checks if the utilization of a given network pool exceeds the configured threshold.
+# TYPE liqo_ipam_pool_utilization_threshold_exceeded gauge
+liqo_ipam_pool_utilization_threshold_exceeded{pool="%s"} 0
+liqo_ipam_pool_utilization_threshold_exceeded{pool="%s"} 0
+liqo_ipam_pool_utilization_threshold_exceeded{pool="%s"} 1
+`, "10.0.0.0/8", "172.16.0.0/12", pool)
+				Expect(testutil.CollectAndCompare(collector, strings.NewReader(expected),
+					"liqo_ipam_pool_utilization_threshold_exceeded")).To(Succeed())
+			})
+
+			It("should clear the signal once the networks are freed", func() {
+				Expect(ipam.AcquireReservedSubnet("192.168.0.0/17")).To(Succeed())
+				Expect(ipam.AcquireReservedSubnet("192.168.128.0/18")).To(Succeed())
+				_, err := collector.Check()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(collector.Exceeded(pool)).To(BeTrue())
+
+				Expect(ipam.FreeReservedSubnet("192.168.128.0/18")).To(Succeed())
+				_, err = collector.Check()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(collector.Exceeded(pool)).To(BeFalse())
+			})
+		})
+
+		Context("When a locker is configured", func() {
+			var locker *sync.Mutex
+
+			BeforeEach(func() {
+				locker = &sync.Mutex{}
+				collector = NewPoolUtilizationCollector(ipam, 0.7, locker)
+			})
+
+			It("should not retrieve the utilization while the locker is held by another component", func() {
+				locker.Lock()
+				done := make(chan error, 1)
+				go func() {
+					_, err := collector.Check()
+					done <- err
+				}()
+
+				// The reservation performed while holding the locker is observed by the collector.
+				Consistently(done, 100*time.Millisecond).ShouldNot(Receive())
+				Expect(ipam.AcquireReservedSubnet("192.168.0.0/17")).To(Succeed())
+				Expect(ipam.AcquireReservedSubnet("192.168.128.0/18")).To(Succeed())
+				locker.Unlock()
+
+				Eventually(done).Should(Receive(BeNil()))
+				Expect(collector.Exceeded(pool)).To(BeTrue())
+			})
+		})
+	})
+
+	Describe("Validate", func() {
+		const serviceCIDR = "10.1.0.0/24"
+
+		BeforeEach(func() {
+			Expect(ipam.SetPodCIDR(homePodCIDR)).To(Succeed())
+			Expect(ipam.SetServiceCIDR(serviceCIDR)).To(Succeed())
+		})
+
+		Context("When the state is consistent", func() {
+			It("should report no errors", func() {
+				_, _, err := ipam.GetSubnetsPerCluster(remotePodCIDR, remoteExternalCIDR, clusterID1)
+				Expect(err).ToNot(HaveOccurred())
+				_, _, err = ipam.GetSubnetsPerCluster(remotePodCIDR, remoteExternalCIDR, clusterID2)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(ipam.Validate([]string{clusterID1, clusterID2})).To(BeEmpty())
+			})
+		})
+
+		Context("When the state is inconsistent", func() {
+			BeforeEach(func() {
+				Expect(ipam.ipamStorage.updateClusterSubnets(map[string]liqonetapi.Subnets{
+					clusterID1: {RemotePodCIDR: remotePodCIDR, RemoteExternalCIDR: "10.0.0.128/25",
+						LocalNATPodCIDR: consts.DefaultCIDRValue, LocalNATExternalCIDR: consts.DefaultCIDRValue},
+					clusterID2: {RemotePodCIDR: "10.50.128.0/17", RemoteExternalCIDR: remoteExternalCIDR},
+					clusterID3: {RemotePodCIDR: consts.DefaultCIDRValue, RemoteExternalCIDR: "10.1.0.0/16"},
+				})).To(Succeed())
+			})
+
+			It("should report all the inconsistencies", func() {
+				errs := ipam.Validate([]string{clusterID1, clusterID2})
+				Expect(errs).To(HaveLen(4))
+				Expect(errs[0]).To(MatchError(ContainSubstring("cluster cluster3, which has no live NetworkConfig")))
+				Expect(errs[1]).To(MatchError(
+					"PodCIDR 10.50.0.0/16 of cluster cluster1 overlaps with PodCIDR 10.50.128.0/17 of cluster cluster2"))
+				Expect(errs[2]).To(MatchError("ExternalCIDR 10.0.0.128/25 of cluster cluster1 overlaps with the local PodCIDR 10.0.0.0/24"))
+				Expect(errs[3]).To(MatchError("ExternalCIDR 10.1.0.0/16 of cluster cluster3 overlaps with the local ServiceCIDR 10.1.0.0/24"))
+			})
+
+			It("should not modify the state", func() {
+				before := ipam.ipamStorage.getClusterSubnets()
+				ipam.Validate(nil)
+				Expect(ipam.ipamStorage.getClusterSubnets()).To(Equal(before))
+			})
+		})
+	})
+
+	Describe("OrganizationBlocks", func() {
+		const (
+			org         = "partner"
+			conflicting = "10.0.0.0/16"
+		)
+
+		var block *net.IPNet
+
+		BeforeEach(func() {
+			// Reserve the network, so that subsequent requests for the same network require a remapping.
+			Expect(ipam.AcquireReservedSubnet(conflicting)).To(Succeed())
+
+			var err error
+			block, err = ipam.ReserveBlock(org, 14)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		Context("When reserving a block", func() {
+			It("should reserve a contiguous network of the given size", func() {
+				ones, _ := block.Mask.Size()
+				Expect(ones).To(Equal(14))
+				Expect(ipam.isAcquired(block.String())).To(BeTrue())
+			})
+
+			It("should be idempotent", func() {
+				again, err := ipam.ReserveBlock(org, 14)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(again.String()).To(Equal(block.String()))
+			})
+
+			It("should fail if the size does not match the existing block", func() {
+				_, err := ipam.ReserveBlock(org, 16)
+				Expect(err).To(HaveOccurred())
+			})
+
+			It("should fail if the size is invalid", func() {
+				_, err := ipam.ReserveBlock("other", 33)
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("When associating a cluster with an organization without a block", func() {
+			It("should return an error", func() {
+				Expect(ipam.SetClusterOrganization(clusterID1, "unknown")).ToNot(Succeed())
+			})
+		})
+
+		Context("When the clusters of the organization require a remapping", func() {
+			BeforeEach(func() {
+				for _, clusterID := range []string{clusterID1, clusterID2, clusterID3} {
+					Expect(ipam.SetClusterOrganization(clusterID, org)).To(Succeed())
+				}
+			})
+
+			It("should allocate adjacent networks inside the reserved block", func() {
+				var previous string
+				for i, clusterID := range []string{clusterID1, clusterID2, clusterID3} {
+					podCIDR, _, err := ipam.GetSubnetsPerCluster(conflicting, fmt.Sprintf("192.168.%d.0/24", i), clusterID)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(podCIDR).ToNot(Equal(conflicting))
+
+					ip, _, err := net.ParseCIDR(podCIDR)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(block.Contains(ip)).To(BeTrue(), "network %s is not inside block %s", podCIDR, block)
+					Expect(liqonetutils.GetMask(podCIDR)).To(BeNumerically("==", 16))
+					if previous != "" {
+						Expect(podCIDR).To(Equal(liqonetutils.Next(previous)))
+					}
+					previous = podCIDR
+				}
+			})
+
+			It("should not allocate the networks of the other clusters inside the block", func() {
+				podCIDR, _, err := ipam.GetSubnetsPerCluster(conflicting, "192.168.0.0/24", "other-cluster")
+				Expect(err).ToNot(HaveOccurred())
+				ip, _, err := net.ParseCIDR(podCIDR)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(block.Contains(ip)).To(BeFalse())
+			})
+
+			It("should fail once the block is exhausted, rather than allocating outside of it", func() {
+				// The /14 block can accommodate four /16 networks only.
+				for i := 0; i < 4; i++ {
+					_, err := ipam.getOrRemapNetwork(conflicting, clusterID1)
+					Expect(err).ToNot(HaveOccurred())
+				}
+				_, err := ipam.getOrRemapNetwork(conflicting, clusterID1)
+				Expect(err).To(HaveOccurred())
+			})
+
+			It("should release the networks back to the block once freed", func() {
+				mapped, err := ipam.getOrRemapNetwork(conflicting, clusterID1)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(ipam.FreeReservedSubnet(mapped)).To(Succeed())
+				Expect(ipam.isAcquired(mapped)).To(BeFalse())
+				Expect(ipam.isAcquired(block.String())).To(BeTrue())
+
+				again, err := ipam.getOrRemapNetwork(conflicting, clusterID2)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(again).To(Equal(mapped))
+			})
+		})
+
+		Context("When moving a cluster to a different organization", func() {
+			It("should remove it from the previous one", func() {
+				_, err := ipam.ReserveBlock("other", 16)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(ipam.SetClusterOrganization(clusterID1, org)).To(Succeed())
+				Expect(ipam.SetClusterOrganization(clusterID1, "other")).To(Succeed())
+
+				blocks := ipam.ipamStorage.getOrganizationBlocks()
+				Expect(blocks[org].Clusters).To(BeEmpty())
+				Expect(blocks["other"].Clusters).To(ConsistOf(clusterID1))
+			})
+		})
+	})
+
+	Describe("AllocationRecorder", func() {
+		var recorder *capturingAllocationRecorder
+
+		BeforeEach(func() {
+			recorder = &capturingAllocationRecorder{}
+			ipam.SetAllocationRecorder(recorder)
+		})
+
+		Context("When the requested networks do not conflict", func() {
+			It("should record a no-remap decision for each network", func() {
+				_, _, err := ipam.GetSubnetsPerCluster("11.0.0.0/16", "11.1.0.0/16", clusterID1)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(recorder.records).To(ConsistOf(
+					AllocationRecord{ClusterID: clusterID1, Network: "PodCIDR", OriginalCIDR: "11.0.0.0/16",
+						Decision: NoRemapDecision, ResultingCIDR: "11.0.0.0/16"},
+					AllocationRecord{ClusterID: clusterID1, Network: "ExternalCIDR", OriginalCIDR: "11.1.0.0/16",
+						Decision: NoRemapDecision, ResultingCIDR: "11.1.0.0/16"},
+				))
+			})
+		})
+
+		Context("When the requested networks conflict with those of another cluster", func() {
+			It("should record a remap decision, along with the resulting network", func() {
+				_, _, err := ipam.GetSubnetsPerCluster("11.0.0.0/16", "11.1.0.0/16", clusterID1)
+				Expect(err).ToNot(HaveOccurred())
+				p, e, err := ipam.GetSubnetsPerCluster("11.0.0.0/16", "11.1.0.0/16", clusterID2)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(recorder.records).To(HaveLen(4))
+				Expect(recorder.records[2:]).To(ConsistOf(
+					AllocationRecord{ClusterID: clusterID2, Network: "PodCIDR", OriginalCIDR: "11.0.0.0/16",
+						Decision: RemapDecision, ResultingCIDR: p},
+					AllocationRecord{ClusterID: clusterID2, Network: "ExternalCIDR", OriginalCIDR: "11.1.0.0/16",
+						Decision: RemapDecision, ResultingCIDR: e},
+				))
+			})
+		})
+
+		Context("When the networks have already been allocated for the same cluster", func() {
+			It("should not record the decision again", func() {
+				_, _, err := ipam.GetSubnetsPerCluster("11.0.0.0/16", "11.1.0.0/16", clusterID1)
+				Expect(err).ToNot(HaveOccurred())
+				_, _, err = ipam.GetSubnetsPerCluster("11.0.0.0/16", "11.1.0.0/16", clusterID1)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(recorder.records).To(HaveLen(2))
+			})
+		})
+
+		Context("When the recorder is unset", func() {
+			It("should not record anything", func() {
+				ipam.SetAllocationRecorder(nil)
+				_, _, err := ipam.GetSubnetsPerCluster("11.0.0.0/16", "11.1.0.0/16", clusterID1)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(recorder.records).To(BeEmpty())
+			})
+		})
+	})
+
+	Describe("CanCoexist", func() {
+		cidr := func(network string) *net.IPNet {
+			_, ipnet, err := net.ParseCIDR(network)
+			Expect(err).ToNot(HaveOccurred())
+			return ipnet
+		}
+
+		Context("When no networks are reserved", func() {
+			DescribeTable("should report whether the two networks can coexist",
+				func(a, b string, expected bool) { Expect(ipam.CanCoexist(cidr(a), cidr(b))).To(Equal(expected)) },
+				Entry("disjoint networks outside of the pools", "11.0.0.0/16", "12.0.0.0/16", true),
+				Entry("disjoint networks inside the pools", "10.0.0.0/16", "10.1.0.0/16", true),
+				Entry("overlapping networks", "10.0.0.0/16", "10.0.128.0/17", false),
+				Entry("identical networks", "11.0.0.0/16", "11.0.0.0/16", false),
+				Entry("a network equal to a pool", "10.0.0.0/8", "11.0.0.0/16", false),
+				Entry("a network containing a pool", "192.168.0.0/15", "11.0.0.0/16", false),
+			)
+
+			It("should be consistent with the subsequent allocations", func() {
+				Expect(ipam.CanCoexist(cidr("10.0.0.0/16"), cidr("10.1.0.0/16"))).To(BeTrue())
+				podCIDR, _, err := ipam.GetSubnetsPerCluster("10.0.0.0/16", "11.0.0.0/16", clusterID1)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(podCIDR).To(Equal("10.0.0.0/16"))
+				podCIDR, _, err = ipam.GetSubnetsPerCluster("10.1.0.0/16", "11.1.0.0/16", clusterID2)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(podCIDR).To(Equal("10.1.0.0/16"))
+			})
+		})
+
+		Context("When some networks are reserved", func() {
+			BeforeEach(func() {
+				Expect(ipam.SetPodCIDR("10.0.0.0/16")).To(Succeed())
+				Expect(ipam.SetServiceCIDR("10.1.0.0/16")).To(Succeed())
+				Expect(ipam.SetReservedSubnets([]string{"192.168.0.0/24"})).To(Succeed())
+				_, _, err := ipam.GetSubnetsPerCluster("11.0.0.0/16", "11.1.0.0/16", clusterID1)
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			DescribeTable("should report whether the two networks can coexist",
+				func(a, b string, expected bool) { Expect(ipam.CanCoexist(cidr(a), cidr(b))).To(Equal(expected)) },
+				Entry("networks not overlapping the reservations", "10.2.0.0/16", "12.0.0.0/16", true),
+				Entry("a network overlapping the local PodCIDR", "10.0.128.0/17", "12.0.0.0/16", false),
+				Entry("a network overlapping the local ServiceCIDR", "12.0.0.0/16", "10.1.0.0/24", false),
+				Entry("a network overlapping a reserved subnet", "192.168.0.0/23", "12.0.0.0/16", false),
+				Entry("a network overlapping the one of an existing peer", "12.0.0.0/16", "11.0.0.0/8", false),
+				Entry("a network overlapping the ExternalCIDR of an existing peer", "11.1.0.0/24", "12.0.0.0/16", false),
+			)
+
+			It("should not reserve any network", func() {
+				Expect(ipam.CanCoexist(cidr("10.2.0.0/16"), cidr("12.0.0.0/16"))).To(BeTrue())
+				Expect(ipam.isAcquired("10.2.0.0/16")).To(BeFalse())
+			})
+		})
+	})
+})
+
+// capturingAllocationRecorder is an AllocationRecorder storing all the records, for testing purposes.
+type capturingAllocationRecorder struct {
+	records []AllocationRecord
+}
+
+func (car *capturingAllocationRecorder) RecordAllocation(record *AllocationRecord) {
+	car.records = append(car.records, *record)
+}
 
 func checkForPrefixes(subnets []string) {
 	for _, s := range subnets {
@@ -0,0 +1,130 @@
+// Copyright 2019-2023 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipam
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"k8s.io/klog/v2"
+	"k8s.io/utils/strings/slices"
+
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+	liqonetutils "github.com/liqotech/liqo/pkg/liqonet/utils"
+)
+
+// ReserveBlock reserves a contiguous network with the given mask length (e.g., 14 for a /14 network) for the
+// clusters of the given organization, allocated from the network pools according to the configured policy.
+// Once the clusters are associated with the organization (see SetClusterOrganization), the networks remapping
+// their subnets are allocated from within the block, e.g., to enable simple firewalling rules. The operation
+// is idempotent, returning the network already reserved for the organization, provided that the size matches.
+func (liqoIPAM *IPAM) ReserveBlock(org string, size int) (*net.IPNet, error) {
+	if org == "" {
+		return nil, fmt.Errorf("the organization name must not be empty")
+	}
+	if size <= 0 || size > 32 {
+		return nil, fmt.Errorf("invalid block size /%d for organization %s", size, org)
+	}
+
+	blocks := liqoIPAM.ipamStorage.getOrganizationBlocks()
+	if block, found := blocks[org]; found {
+		if liqonetutils.GetMask(block.Network) != uint8(size) {
+			return nil, fmt.Errorf("network %s is already reserved for organization %s, with a different size than /%d",
+				block.Network, org, size)
+		}
+		_, network, err := net.ParseCIDR(block.Network)
+		return network, err
+	}
+
+	reserved, err := liqoIPAM.getNetworkFromPool(uint8(size))
+	if err != nil {
+		return nil, fmt.Errorf("cannot reserve a /%d network for organization %s: %w", size, org, err)
+	}
+
+	if blocks == nil {
+		blocks = make(map[string]netv1alpha1.OrganizationBlock)
+	}
+	blocks[org] = netv1alpha1.OrganizationBlock{Network: reserved}
+	if err := liqoIPAM.ipamStorage.updateOrganizationBlocks(blocks); err != nil {
+		_ = liqoIPAM.FreeReservedSubnet(reserved)
+		return nil, fmt.Errorf("cannot update organization blocks: %w", err)
+	}
+
+	klog.Infof("Network %s has been reserved for organization %s", reserved, org)
+	_, network, err := net.ParseCIDR(reserved)
+	return network, err
+}
+
+// SetClusterOrganization associates the given cluster with an organization, for which a network must have
+// already been reserved (see ReserveBlock). The networks remapping the subnets of the cluster which are
+// allocated afterwards are taken from within that network, while the existing ones are not modified.
+func (liqoIPAM *IPAM) SetClusterOrganization(clusterID, org string) error {
+	blocks := liqoIPAM.ipamStorage.getOrganizationBlocks()
+	block, found := blocks[org]
+	if !found {
+		return fmt.Errorf("no network is reserved for organization %s", org)
+	}
+	if slices.Contains(block.Clusters, clusterID) {
+		return nil
+	}
+
+	// A cluster belongs to at most one organization.
+	for name, other := range blocks {
+		if slices.Contains(other.Clusters, clusterID) {
+			other.Clusters = slices.Filter(nil, other.Clusters, func(c string) bool { return c != clusterID })
+			blocks[name] = other
+		}
+	}
+
+	block.Clusters = append(block.Clusters, clusterID)
+	blocks[org] = block
+	if err := liqoIPAM.ipamStorage.updateOrganizationBlocks(blocks); err != nil {
+		return fmt.Errorf("cannot update organization blocks: %w", err)
+	}
+
+	klog.Infof("Cluster %s has been associated with organization %s (network %s)", clusterID, org, block.Network)
+	return nil
+}
+
+// organizationBlock returns the network reserved for the organization the given cluster belongs to, if any.
+func (liqoIPAM *IPAM) organizationBlock(clusterID string) (block string, found bool) {
+	for _, candidate := range liqoIPAM.ipamStorage.getOrganizationBlocks() {
+		if slices.Contains(candidate.Clusters, clusterID) {
+			return candidate.Network, true
+		}
+	}
+	return "", false
+}
+
+// getRemappedNetworkForCluster returns a network to be used as a remapping for a network of the given cluster with
+// mask length equal to mask. The network is taken from the block reserved for the organization the cluster belongs
+// to, if any, without falling back to the pools once exhausted, as it would no longer match the expected range.
+func (liqoIPAM *IPAM) getRemappedNetworkForCluster(clusterID string, mask uint8) (string, error) {
+	block, found := liqoIPAM.organizationBlock(clusterID)
+	if !found {
+		return liqoIPAM.getRemappedNetworkFromPool(mask)
+	}
+
+	if liqoIPAM.remapHeadroom > mask {
+		return "", fmt.Errorf("remap headroom of %d bits exceeds the mask length %d", liqoIPAM.remapHeadroom, mask)
+	}
+	network, err := liqoIPAM.ipam.AcquireChildPrefix(context.TODO(), block, mask-liqoIPAM.remapHeadroom)
+	if err != nil {
+		return "", fmt.Errorf("%w in block %s reserved for the organization of cluster %s: %v", ErrNoNetworksAvailable, block, clusterID, err)
+	}
+	klog.Infof("Acquired network %s from the organization block %s", network, block)
+	return network.String(), nil
+}
@@ -0,0 +1,56 @@
+// Copyright 2019-2023 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipam
+
+import (
+	"fmt"
+	"net"
+
+	"k8s.io/klog/v2"
+)
+
+// SetStaticIPOverrides configures a table of static overrides (original remote IP -> fixed home IP), consulted before
+// the algorithmic remapping when translating the remote Pod IPs, so that specific remote infrastructure addresses
+// (e.g., DNS servers and gateways) are always mapped to fixed home addresses. Any previous table is replaced.
+func (liqoIPAM *IPAM) SetStaticIPOverrides(overrides map[string]string) error {
+	table := make(map[string]string, len(overrides))
+	for original, fixed := range overrides {
+		originalIP, fixedIP := net.ParseIP(original), net.ParseIP(fixed)
+		if originalIP == nil {
+			return fmt.Errorf("invalid original IP %q in static override", original)
+		}
+		if fixedIP == nil {
+			return fmt.Errorf("invalid fixed IP %q in static override for %s", fixed, original)
+		}
+		table[originalIP.String()] = fixedIP.String()
+	}
+
+	liqoIPAM.mutex.Lock()
+	defer liqoIPAM.mutex.Unlock()
+	liqoIPAM.staticIPOverrides = table
+	klog.Infof("Configured %d static IP overrides", len(table))
+	return nil
+}
+
+// staticIPOverride returns the fixed IP the given one is statically mapped to, if any.
+// It is expected to be called with the mutex held.
+func (liqoIPAM *IPAM) staticIPOverride(ip string) (string, bool) {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return "", false
+	}
+	fixed, found := liqoIPAM.staticIPOverrides[parsed.String()]
+	return fixed, found
+}
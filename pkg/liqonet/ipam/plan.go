@@ -0,0 +1,231 @@
+// Copyright 2019-2023 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipam
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+
+	goipam "github.com/metal-stack/go-ipam"
+	"inet.af/netaddr"
+)
+
+// PlanReservations simulates the allocation of the given remote networks (e.g., the PodCIDRs of the expected peers),
+// mirroring the behavior of the IPAM, and returns the subnets of the pool which would be reserved as a result.
+// Remote networks not overlapping with the pool nor with the previously allocated ones are used as-is, hence not
+// consuming any address space. Networks contained in the pool are acquired from it if still free, while all the
+// others are remapped to a free network of the same size taken from the pool.
+// An error is returned in case the pool cannot satisfy the plan.
+func PlanReservations(remoteCIDRs []*net.IPNet, pool *net.IPNet) ([]*net.IPNet, error) {
+	if pool == nil {
+		return nil, fmt.Errorf("the network pool must be specified")
+	}
+
+	poolPrefix, ok := netaddr.FromStdIPNet(pool)
+	if !ok {
+		return nil, fmt.Errorf("invalid network pool %s", pool)
+	}
+	poolPrefix = poolPrefix.Masked()
+
+	ctx := context.TODO()
+	simulator := goipam.New()
+	if _, err := simulator.NewPrefix(ctx, poolPrefix.String()); err != nil {
+		return nil, fmt.Errorf("cannot initialize network pool %s: %w", poolPrefix, err)
+	}
+
+	var used []netaddr.IPPrefix
+	var reserved []*net.IPNet
+	for _, remoteCIDR := range remoteCIDRs {
+		remote, ok := netaddr.FromStdIPNet(remoteCIDR)
+		if !ok {
+			return nil, fmt.Errorf("invalid remote network %s", remoteCIDR)
+		}
+		remote = remote.Masked()
+
+		// The remote network does not conflict with anything, hence it can be used without remapping.
+		if !remote.Overlaps(poolPrefix) && !overlapsAny(remote, used) {
+			used = append(used, remote)
+			continue
+		}
+
+		network := remote.String()
+		if _, err := simulator.AcquireSpecificChildPrefix(ctx, poolPrefix.String(), network); err != nil {
+			// The remote network is not (entirely) available in the pool, hence a remapping is needed.
+			mapped, err := simulator.AcquireChildPrefix(ctx, poolPrefix.String(), remote.Bits())
+			if err != nil {
+				return nil, fmt.Errorf("network pool %s cannot satisfy the plan: no networks available for %s", poolPrefix, remote)
+			}
+			network = mapped.String()
+		}
+
+		_, ipnet, err := net.ParseCIDR(network)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse network %s: %w", network, err)
+		}
+		reserved = append(reserved, ipnet)
+	}
+
+	return reserved, nil
+}
+
+// overlapsAny returns whether the given prefix overlaps with any of the others.
+func overlapsAny(prefix netaddr.IPPrefix, others []netaddr.IPPrefix) bool {
+	for i := range others {
+		if prefix.Overlaps(others[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// PreflightReport is the outcome of the pre-flight assessment of the CIDR plan of a remote cluster, before peering.
+type PreflightReport struct {
+	// PodCIDRNATNeeded is true if the remote PodCIDR conflicts with the local networks, hence it needs to be remapped.
+	PodCIDRNATNeeded bool
+	// PodCIDRConflicts lists the local networks the remote PodCIDR overlaps with.
+	PodCIDRConflicts []*net.IPNet
+	// ServiceCIDRNATNeeded is true if the remote ServiceCIDR conflicts with the local networks, hence it needs to be remapped.
+	ServiceCIDRNATNeeded bool
+	// ServiceCIDRConflicts lists the local networks the remote ServiceCIDR overlaps with.
+	ServiceCIDRConflicts []*net.IPNet
+	// AllocationPossible is true if the network pools can satisfy all the required remappings.
+	AllocationPossible bool
+}
+
+// NATNeeded returns whether at least one of the remote networks needs to be remapped.
+func (pr *PreflightReport) NATNeeded() bool {
+	return pr.PodCIDRNATNeeded || pr.ServiceCIDRNATNeeded
+}
+
+// PreflightPeering assesses the CIDR plan of a remote cluster before enabling the peering, without side effects:
+// given the networks already reserved in the local cluster (e.g., the local PodCIDR and ServiceCIDR, the reserved
+// subnets and the networks allocated to the other peers), it reports whether the remote PodCIDR and ServiceCIDR need
+// to be remapped, and whether the default network pools can satisfy the corresponding allocations.
+// An error is returned only in case of invalid inputs.
+func PreflightPeering(localReserved []*net.IPNet, remotePodCIDR, remoteServiceCIDR *net.IPNet) (*PreflightReport, error) {
+	if remotePodCIDR == nil || remoteServiceCIDR == nil {
+		return nil, fmt.Errorf("both the remote PodCIDR and ServiceCIDR must be specified")
+	}
+
+	reserved := make([]netaddr.IPPrefix, 0, len(localReserved))
+	for _, network := range localReserved {
+		prefix, ok := netaddr.FromStdIPNet(network)
+		if !ok {
+			return nil, fmt.Errorf("invalid reserved network %s", network)
+		}
+		reserved = append(reserved, prefix.Masked())
+	}
+
+	simulator, err := newPoolsSimulator(Pools)
+	if err != nil {
+		return nil, err
+	}
+
+	// Mirror the local reservations in the network pools, so that they are not considered for the remappings.
+	// Larger networks are reserved first, so that the smaller ones they contain are already accounted for.
+	sorted := append([]netaddr.IPPrefix(nil), reserved...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Bits() < sorted[j].Bits() })
+	for _, prefix := range sorted {
+		simulator.reserve(prefix)
+	}
+
+	report := &PreflightReport{AllocationPossible: true}
+	for _, remote := range []struct {
+		network   *net.IPNet
+		natNeeded *bool
+		conflicts *[]*net.IPNet
+	}{
+		{network: remotePodCIDR, natNeeded: &report.PodCIDRNATNeeded, conflicts: &report.PodCIDRConflicts},
+		{network: remoteServiceCIDR, natNeeded: &report.ServiceCIDRNATNeeded, conflicts: &report.ServiceCIDRConflicts},
+	} {
+		prefix, ok := netaddr.FromStdIPNet(remote.network)
+		if !ok {
+			return nil, fmt.Errorf("invalid remote network %s", remote.network)
+		}
+		prefix = prefix.Masked()
+
+		for i := range reserved {
+			if prefix.Overlaps(reserved[i]) {
+				*remote.conflicts = append(*remote.conflicts, localReserved[i])
+			}
+		}
+
+		if len(*remote.conflicts) == 0 {
+			// The remote network is used as-is, hence it is no longer available for the subsequent remappings.
+			simulator.reserve(prefix)
+			continue
+		}
+
+		*remote.natNeeded = true
+		if !simulator.remap(prefix.Bits()) {
+			report.AllocationPossible = false
+		}
+	}
+
+	return report, nil
+}
+
+// poolsSimulator simulates the allocation of networks from a set of network pools, mirroring the behavior of the IPAM.
+type poolsSimulator struct {
+	ipam  goipam.Ipamer
+	pools []netaddr.IPPrefix
+}
+
+// newPoolsSimulator returns a new poolsSimulator for the given network pools.
+func newPoolsSimulator(pools []string) (*poolsSimulator, error) {
+	simulator := &poolsSimulator{ipam: goipam.New()}
+	for _, pool := range pools {
+		poolPrefix, err := netaddr.ParseIPPrefix(pool)
+		if err != nil {
+			return nil, fmt.Errorf("invalid network pool %s: %w", pool, err)
+		}
+		poolPrefix = poolPrefix.Masked()
+		if _, err := simulator.ipam.NewPrefix(context.TODO(), poolPrefix.String()); err != nil {
+			return nil, fmt.Errorf("cannot initialize network pool %s: %w", poolPrefix, err)
+		}
+		simulator.pools = append(simulator.pools, poolPrefix)
+	}
+	return simulator, nil
+}
+
+// reserve marks the portion of the network pools overlapping with the given network as allocated.
+func (ps *poolsSimulator) reserve(network netaddr.IPPrefix) {
+	pools := ps.pools[:0]
+	for _, pool := range ps.pools {
+		switch {
+		case !pool.Overlaps(network):
+		case network.Bits() <= pool.Bits():
+			// The network covers the entire pool, which is hence no longer usable.
+			continue
+		default:
+			// Errors are ignored, as they are due to the network overlapping with an already reserved one.
+			_, _ = ps.ipam.AcquireSpecificChildPrefix(context.TODO(), pool.String(), network.String())
+		}
+		pools = append(pools, pool)
+	}
+	ps.pools = pools
+}
+
+// remap allocates a network of the given size from the network pools, returning whether it succeeded.
+func (ps *poolsSimulator) remap(bits uint8) bool {
+	for _, pool := range ps.pools {
+		if _, err := ps.ipam.AcquireChildPrefix(context.TODO(), pool.String(), bits); err == nil {
+			return true
+		}
+	}
+	return false
+}
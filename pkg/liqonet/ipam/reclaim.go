@@ -0,0 +1,94 @@
+// Copyright 2019-2023 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipam
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"k8s.io/klog/v2"
+
+	liqoneterrors "github.com/liqotech/liqo/pkg/liqonet/errors"
+	liqonetutils "github.com/liqotech/liqo/pkg/liqonet/utils"
+)
+
+// ReclaimRemappedPodCIDR re-evaluates the PodCIDR of the given remote cluster, previously remapped due to a conflict
+// (e.g., because the remote cluster later changed it). In case the given PodCIDR does no longer overlap with the other
+// networks, it is reserved as is, while the previously remapped subnet is freed. It returns whether the remapping
+// has been disabled, i.e., the PodCIDR shall not be NATted anymore. The ExternalCIDR is never re-evaluated.
+func (liqoIPAM *IPAM) ReclaimRemappedPodCIDR(podCIDR, clusterID string) (bool, error) {
+	if err := liqonetutils.IsValidCIDR(podCIDR); err != nil {
+		return false, fmt.Errorf("PodCidr is an invalid CIDR: %w", err)
+	}
+	if liqoIPAM.ipamStorage == nil {
+		return false, &liqoneterrors.MissingInit{StructureName: "IPAM"}
+	}
+
+	clusterSubnets := liqoIPAM.ipamStorage.getClusterSubnets()
+	subnets, exists := clusterSubnets[clusterID]
+	if !exists || subnets.RemotePodCIDR == "" || subnets.RemotePodCIDR == podCIDR {
+		// Nothing has been remapped, hence there is nothing to reclaim.
+		return false, nil
+	}
+
+	reserved, err := liqoIPAM.reserveIfAvailable(podCIDR)
+	if err != nil || !reserved {
+		return false, err
+	}
+
+	remapped := subnets.RemotePodCIDR
+	subnets.RemotePodCIDR = podCIDR
+	clusterSubnets[clusterID] = subnets
+	if err := liqoIPAM.ipamStorage.updateClusterSubnets(clusterSubnets); err != nil {
+		_ = liqoIPAM.FreeReservedSubnet(podCIDR)
+		return false, fmt.Errorf("cannot update cluster subnets: %w", err)
+	}
+
+	if err := liqoIPAM.FreeReservedSubnet(remapped); err != nil {
+		// The cluster subnets have already been updated, hence the leftover subnet is eventually detected by Validate.
+		return true, fmt.Errorf("cannot free the previously remapped PodCIDR %s: %w", remapped, err)
+	}
+
+	klog.Infof("PodCIDR %s of cluster %s does no longer overlap, remapping to %s disabled", podCIDR, clusterID, remapped)
+	liqoIPAM.recordAllocation(clusterID, "PodCIDR", podCIDR, podCIDR)
+	return true, nil
+}
+
+// reserveIfAvailable reserves the given network as is, returning false if it overlaps with
+// an already reserved network, or corresponds to an entire network pool.
+func (liqoIPAM *IPAM) reserveIfAvailable(network string) (bool, error) {
+	_, err := liqoIPAM.ipam.NewPrefix(context.TODO(), network)
+	if err == nil {
+		return true, nil
+	}
+	if !strings.Contains(err.Error(), "overlaps") {
+		return false, fmt.Errorf("cannot reserve network %s: %w", network, err)
+	}
+
+	// The network overlaps with a network pool or with a reserved network.
+	pool, ok, err := liqoIPAM.getPoolFromNetwork(network)
+	if err != nil || !ok || network == pool {
+		return false, err
+	}
+
+	if _, err := liqoIPAM.ipam.AcquireSpecificChildPrefix(context.TODO(), pool, network); err != nil {
+		if strings.Contains(err.Error(), "is not available") {
+			return false, nil
+		}
+		return false, fmt.Errorf("cannot acquire prefix %s from prefix %s: %w", network, pool, err)
+	}
+	return true, nil
+}
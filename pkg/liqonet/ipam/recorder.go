@@ -0,0 +1,84 @@
+// Copyright 2019-2023 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipam
+
+import "k8s.io/klog/v2"
+
+// AllocationDecision is the outcome of the allocation of a network requested by a remote cluster.
+type AllocationDecision string
+
+const (
+	// NoRemapDecision means that the requested network has been allocated as is.
+	NoRemapDecision AllocationDecision = "NoRemap"
+	// RemapDecision means that the requested network conflicted with an already allocated one, and has been remapped.
+	RemapDecision AllocationDecision = "Remap"
+)
+
+// AllocationRecord describes a network allocation decision, for audit purposes.
+type AllocationRecord struct {
+	// ClusterID is the ID of the remote cluster the network has been allocated for.
+	ClusterID string
+	// Network is the kind of the allocated network (i.e., PodCIDR or ExternalCIDR).
+	Network string
+	// OriginalCIDR is the network requested by the remote cluster.
+	OriginalCIDR string
+	// Decision is the outcome of the allocation.
+	Decision AllocationDecision
+	// ResultingCIDR is the network actually allocated, which differs from the original one in case of remapping.
+	ResultingCIDR string
+}
+
+// AllocationRecorder records the network allocation decisions performed by the IPAM.
+type AllocationRecorder interface {
+	RecordAllocation(record *AllocationRecord)
+}
+
+// NoopAllocationRecorder is an AllocationRecorder discarding all the records.
+type NoopAllocationRecorder struct{}
+
+// RecordAllocation discards the given record.
+func (NoopAllocationRecorder) RecordAllocation(_ *AllocationRecord) {}
+
+// LoggingAllocationRecorder is an AllocationRecorder emitting each record as a structured log entry.
+type LoggingAllocationRecorder struct{}
+
+// RecordAllocation logs the given record.
+func (LoggingAllocationRecorder) RecordAllocation(record *AllocationRecord) {
+	klog.InfoS("Network allocation decision", "clusterID", record.ClusterID, "network", record.Network,
+		"originalCIDR", record.OriginalCIDR, "decision", record.Decision, "resultingCIDR", record.ResultingCIDR)
+}
+
+// SetAllocationRecorder configures the recorder notified of each network allocation decision (a nil value disables recording).
+func (liqoIPAM *IPAM) SetAllocationRecorder(recorder AllocationRecorder) {
+	if recorder == nil {
+		recorder = NoopAllocationRecorder{}
+	}
+	liqoIPAM.allocationRecorder = recorder
+}
+
+// recordAllocation notifies the configured recorder of the allocation of the given network for a remote cluster.
+func (liqoIPAM *IPAM) recordAllocation(clusterID, network, original, resulting string) {
+	if liqoIPAM.allocationRecorder == nil {
+		return
+	}
+
+	decision := NoRemapDecision
+	if original != resulting {
+		decision = RemapDecision
+	}
+	liqoIPAM.allocationRecorder.RecordAllocation(&AllocationRecord{
+		ClusterID: clusterID, Network: network, OriginalCIDR: original, Decision: decision, ResultingCIDR: resulting,
+	})
+}
@@ -0,0 +1,125 @@
+// Copyright 2019-2023 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipam
+
+import (
+	"fmt"
+
+	"k8s.io/klog/v2"
+	"k8s.io/utils/strings/slices"
+
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+	"github.com/liqotech/liqo/pkg/consts"
+	liqoneterrors "github.com/liqotech/liqo/pkg/liqonet/errors"
+)
+
+// RenameCluster moves the IPAM configuration of a remote cluster (i.e., the reserved subnets, the NAT mappings and
+// the organization membership) from the old to the new cluster ID, e.g., because the cluster has been re-registered
+// under a different identity. The reservations are preserved, and the configuration is updated atomically.
+// It is a no-op in case no configuration exists for the old cluster ID, and fails if one exists for the new one.
+func (liqoIPAM *IPAM) RenameCluster(oldID, newID string) error {
+	if oldID == "" || newID == "" {
+		return &liqoneterrors.WrongParameter{Parameter: consts.ClusterIDLabelName, Reason: liqoneterrors.StringNotEmpty}
+	}
+	if oldID == newID {
+		return nil
+	}
+	if liqoIPAM.ipamStorage == nil {
+		return &liqoneterrors.MissingInit{StructureName: "IPAM"}
+	}
+
+	// Copies are modified, to leave the current configuration untouched in case of errors.
+	clusterSubnets := make(map[string]netv1alpha1.Subnets)
+	for clusterID, subnets := range liqoIPAM.ipamStorage.getClusterSubnets() {
+		clusterSubnets[clusterID] = subnets
+	}
+	natMappingsConfigured := make(map[string]netv1alpha1.ConfiguredCluster)
+	for clusterID, configured := range liqoIPAM.ipamStorage.getNatMappingsConfigured() {
+		natMappingsConfigured[clusterID] = configured
+	}
+
+	_, subnetsExist := clusterSubnets[oldID]
+	_, natMappingsExist := natMappingsConfigured[oldID]
+	if !subnetsExist && !natMappingsExist {
+		klog.V(4).Infof("No IPAM configuration found for cluster %s, nothing to rename", oldID)
+		return nil
+	}
+
+	if _, exists := clusterSubnets[newID]; exists {
+		return fmt.Errorf("cannot rename cluster %s to %s: subnets already assigned to the latter", oldID, newID)
+	}
+	if _, exists := natMappingsConfigured[newID]; exists {
+		return fmt.Errorf("cannot rename cluster %s to %s: NAT mappings already configured for the latter", oldID, newID)
+	}
+
+	if subnetsExist {
+		clusterSubnets[newID] = clusterSubnets[oldID]
+		delete(clusterSubnets, oldID)
+	}
+	if natMappingsExist {
+		natMappingsConfigured[newID] = natMappingsConfigured[oldID]
+		delete(natMappingsConfigured, oldID)
+	}
+
+	endpointMappings := make(map[string]netv1alpha1.EndpointMapping)
+	for ip, endpointMapping := range liqoIPAM.ipamStorage.getEndpointMappings() {
+		clusterMappings := make(map[string]netv1alpha1.ClusterMapping, len(endpointMapping.ClusterMappings))
+		for clusterID, mapping := range endpointMapping.ClusterMappings {
+			if clusterID == oldID {
+				clusterID = newID
+			}
+			clusterMappings[clusterID] = mapping
+		}
+		endpointMapping.ClusterMappings = clusterMappings
+		endpointMappings[ip] = endpointMapping
+	}
+
+	var organizationBlocks map[string]netv1alpha1.OrganizationBlock
+	if current := liqoIPAM.ipamStorage.getOrganizationBlocks(); current != nil {
+		organizationBlocks = make(map[string]netv1alpha1.OrganizationBlock, len(current))
+		for organization, block := range current {
+			if slices.Contains(block.Clusters, oldID) {
+				clusters := make([]string, 0, len(block.Clusters))
+				for _, clusterID := range block.Clusters {
+					if clusterID == oldID {
+						clusterID = newID
+					}
+					clusters = append(clusters, clusterID)
+				}
+				block.Clusters = clusters
+			}
+			organizationBlocks[organization] = block
+		}
+	}
+
+	// The NAT mappings are moved first, and restored in case the IPAM configuration cannot be updated.
+	if natMappingsExist {
+		if err := liqoIPAM.natMappingInflater.RenameCluster(oldID, newID); err != nil {
+			return fmt.Errorf("unable to rename NAT mappings of cluster %s: %w", oldID, err)
+		}
+	}
+
+	if err := liqoIPAM.ipamStorage.updateClusterReferences(clusterSubnets, natMappingsConfigured, endpointMappings, organizationBlocks); err != nil {
+		if natMappingsExist {
+			if rerr := liqoIPAM.natMappingInflater.RenameCluster(newID, oldID); rerr != nil {
+				klog.Errorf("Failed to restore the NAT mappings of cluster %s: %v", oldID, rerr)
+			}
+		}
+		return fmt.Errorf("cannot update the IPAM configuration: %w", err)
+	}
+
+	klog.Infof("IPAM configuration of cluster %s moved to cluster %s", oldID, newID)
+	return nil
+}
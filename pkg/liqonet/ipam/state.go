@@ -0,0 +1,151 @@
+// Copyright 2019-2023 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipam
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+
+	goipam "github.com/metal-stack/go-ipam"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/klog/v2"
+
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+	liqoneterrors "github.com/liqotech/liqo/pkg/liqonet/errors"
+	liqonetutils "github.com/liqotech/liqo/pkg/liqonet/utils"
+)
+
+// ipamStateKind is the kind of the serialized IPAM state, which is an IpamStorage resource stripped of its metadata.
+const ipamStateKind = "IpamStorage"
+
+// MarshalState serializes the whole IPAM state (i.e., the network pools, the acquired prefixes, the reserved subnets
+// and the networks allocated to the remote clusters), for it to be migrated to a different IPAM instance through
+// LoadIPAMState. The state is encoded as an IpamStorage resource without metadata, hence bound to its API version.
+func (liqoIPAM *IPAM) MarshalState() ([]byte, error) {
+	if liqoIPAM.ipamStorage == nil {
+		return nil, &liqoneterrors.MissingInit{StructureName: "IPAM"}
+	}
+
+	state := netv1alpha1.IpamStorage{Spec: liqoIPAM.ipamStorage.getSpec()}
+	state.APIVersion = netv1alpha1.GroupVersion.String()
+	state.Kind = ipamStateKind
+
+	data, err := json.Marshal(&state)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal the IPAM state: %w", err)
+	}
+	return data, nil
+}
+
+// LoadIPAMState restores the IPAM state previously serialized through MarshalState into the IpamStorage resource
+// accessed through the given client, and returns the corresponding (initialized) IPAM instance, with no gRPC server.
+// The state is validated for internal consistency before being loaded, and it is refused in case the existing
+// storage already holds the networks of some remote clusters, to prevent overwriting live allocations.
+func LoadIPAMState(data []byte, dynClient dynamic.Interface) (*IPAM, error) {
+	var state netv1alpha1.IpamStorage
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal the IPAM state: %w", err)
+	}
+	if state.APIVersion != netv1alpha1.GroupVersion.String() || state.Kind != ipamStateKind {
+		return nil, fmt.Errorf("unexpected IPAM state type %s, kind %s", state.APIVersion, state.Kind)
+	}
+	if err := validateState(&state.Spec); err != nil {
+		return nil, fmt.Errorf("invalid IPAM state: %w", err)
+	}
+
+	storage, err := NewIPAMStorage(dynClient)
+	if err != nil {
+		return nil, fmt.Errorf("cannot set up storage for ipam: %w", err)
+	}
+	if clusters := storage.getClusterSubnets(); len(clusters) > 0 {
+		return nil, fmt.Errorf("cannot load the IPAM state, as networks are already allocated for %d remote clusters", len(clusters))
+	}
+	if err := storage.restoreSpec(&state.Spec); err != nil {
+		return nil, fmt.Errorf("cannot restore the IPAM state: %w", err)
+	}
+	klog.Infof("IPAM state successfully loaded (%d prefixes, %d remote clusters)", len(state.Spec.Prefixes), len(state.Spec.ClusterSubnets))
+
+	liqoIPAM := NewIPAM()
+	if err := liqoIPAM.Init(state.Spec.Pools, dynClient, 0); err != nil {
+		return nil, err
+	}
+	return liqoIPAM, nil
+}
+
+// validateState checks the serialized IPAM state for internal consistency: the prefixes must be correctly encoded and
+// refer to existing parents, while the pools and the networks acquired by the IPAM must correspond to a prefix.
+func validateState(spec *netv1alpha1.IpamSpec) error {
+	prefixes := make(map[string]goipam.Prefix, len(spec.Prefixes))
+	for cidr, encoded := range spec.Prefixes {
+		var prefix goipam.Prefix
+		if err := prefix.GobDecode(encoded); err != nil {
+			return fmt.Errorf("cannot decode prefix %s: %w", cidr, err)
+		}
+		if prefix.Cidr != cidr {
+			return fmt.Errorf("prefix %s is stored with key %s", prefix.Cidr, cidr)
+		}
+		prefixes[cidr] = prefix
+	}
+	for cidr, prefix := range prefixes {
+		if prefix.ParentCidr == "" {
+			continue
+		}
+		if _, found := prefixes[prefix.ParentCidr]; !found {
+			return fmt.Errorf("prefix %s refers to the missing parent prefix %s", cidr, prefix.ParentCidr)
+		}
+	}
+
+	// acquired checks that the given network is valid and corresponds to a prefix acquired by the IPAM.
+	acquired := func(kind, network string) error {
+		if _, _, err := net.ParseCIDR(network); err != nil {
+			return fmt.Errorf("invalid %s %q: %w", kind, network, err)
+		}
+		if _, found := prefixes[network]; !found {
+			return fmt.Errorf("%s %s does not correspond to any prefix", kind, network)
+		}
+		return nil
+	}
+
+	for _, pool := range spec.Pools {
+		if err := acquired("pool", pool); err != nil {
+			return err
+		}
+	}
+	for kind, network := range map[string]string{"PodCIDR": spec.PodCIDR, "ServiceCIDR": spec.ServiceCIDR, "ExternalCIDR": spec.ExternalCIDR} {
+		if network == "" {
+			continue
+		}
+		if err := acquired(kind, network); err != nil {
+			return err
+		}
+	}
+	for _, reserved := range spec.ReservedSubnets {
+		if err := acquired("reserved subnet", reserved); err != nil {
+			return err
+		}
+	}
+	for clusterID, subnets := range spec.ClusterSubnets {
+		for kind, network := range map[string]string{"PodCIDR": subnets.RemotePodCIDR, "ExternalCIDR": subnets.RemoteExternalCIDR} {
+			if network == "" || liqonetutils.IsNATDisabled(network) {
+				continue
+			}
+			if err := acquired(fmt.Sprintf("%s of cluster %s", kind, clusterID), network); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
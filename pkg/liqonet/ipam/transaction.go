@@ -0,0 +1,85 @@
+// Copyright 2019-2023 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipam
+
+import (
+	"fmt"
+
+	"k8s.io/klog/v2"
+)
+
+// AllocTx is a transaction grouping a set of network reservations and releases,
+// which are either all committed or all rolled back (see WithTransaction).
+type AllocTx struct {
+	ipam *IPAM
+	// undo contains the operations reverting the ones already performed, in the same order.
+	undo []func() error
+}
+
+// Reserve reserves the given network, as part of the transaction.
+func (tx *AllocTx) Reserve(network string) error {
+	if err := tx.ipam.AcquireReservedSubnet(network); err != nil {
+		return err
+	}
+	tx.undo = append(tx.undo, func() error { return tx.ipam.FreeReservedSubnet(network) })
+	return nil
+}
+
+// Release releases the given network, as part of the transaction.
+// Releasing a network which is not reserved is a no-op.
+func (tx *AllocTx) Release(network string) error {
+	if !tx.ipam.isAcquired(network) {
+		return nil
+	}
+	if err := tx.ipam.FreeReservedSubnet(network); err != nil {
+		return err
+	}
+	tx.undo = append(tx.undo, func() error { return tx.ipam.AcquireReservedSubnet(network) })
+	return nil
+}
+
+// track registers the operation reverting one performed outside of Reserve and Release, as part of the transaction.
+func (tx *AllocTx) track(undo func() error) {
+	tx.undo = append(tx.undo, undo)
+}
+
+// rollback reverts the operations performed as part of the transaction, in reverse order.
+func (tx *AllocTx) rollback() error {
+	for i := len(tx.undo) - 1; i >= 0; i-- {
+		if err := tx.undo[i](); err != nil {
+			return err
+		}
+	}
+	tx.undo = nil
+	return nil
+}
+
+// WithTransaction executes the given function within a transaction: in case it returns an error,
+// all the reservations and releases performed through the transaction are rolled back, hence
+// preventing partial allocation states during batch operations. Transactions are serialized.
+func (liqoIPAM *IPAM) WithTransaction(fn func(tx *AllocTx) error) error {
+	liqoIPAM.transactionMutex.Lock()
+	defer liqoIPAM.transactionMutex.Unlock()
+
+	tx := &AllocTx{ipam: liqoIPAM}
+	if err := fn(tx); err != nil {
+		if rerr := tx.rollback(); rerr != nil {
+			return fmt.Errorf("%w (rollback failed: %v)", err, rerr)
+		}
+		klog.Infof("Transaction rolled back: %v", err)
+		return err
+	}
+	return nil
+}
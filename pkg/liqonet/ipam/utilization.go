@@ -0,0 +1,162 @@
+// Copyright 2019-2023 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipam
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"inet.af/netaddr"
+	"k8s.io/klog/v2"
+)
+
+var (
+	// PoolUtilization is the metric that exposes the fraction of addresses allocated from a given network pool.
+	PoolUtilization = prometheus.NewDesc(
+		"liqo_ipam_pool_utilization_ratio",
+		"Fraction of the addresses of a given network pool currently allocated.",
+		[]string{"pool"},
+		nil,
+	)
+
+	// PoolUtilizationThresholdExceeded is the metric that outputs whether the utilization of a given network pool exceeds the threshold.
+	PoolUtilizationThresholdExceeded = prometheus.NewDesc(
+		"liqo_ipam_pool_utilization_threshold_exceeded",
+		"Checks if the utilization of a given network pool exceeds the configured threshold.",
+		[]string{"pool"},
+		nil,
+	)
+)
+
+// PoolsUtilization returns, for each network pool, the fraction (in the range [0, 1]) of addresses currently allocated.
+func (liqoIPAM *IPAM) PoolsUtilization() (map[string]float64, error) {
+	liqoIPAM.mutex.Lock()
+	defer liqoIPAM.mutex.Unlock()
+
+	utilization := make(map[string]float64)
+	for _, pool := range liqoIPAM.ipamStorage.getPools() {
+		prefix, err := netaddr.ParseIPPrefix(pool)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse network pool %s: %w", pool, err)
+		}
+
+		p := liqoIPAM.ipam.PrefixFrom(context.TODO(), pool)
+		if p == nil {
+			return nil, fmt.Errorf("network pool %s not found", pool)
+		}
+
+		available := 0.
+		for _, network := range p.Usage().AvailablePrefixes {
+			free, err := netaddr.ParseIPPrefix(network)
+			if err != nil {
+				return nil, fmt.Errorf("cannot parse network %s: %w", network, err)
+			}
+			available += prefixSize(free)
+		}
+		utilization[pool] = 1 - available/prefixSize(prefix)
+	}
+	return utilization, nil
+}
+
+// prefixSize returns the number of addresses of the given prefix.
+func prefixSize(prefix netaddr.IPPrefix) float64 {
+	return math.Ldexp(1, int(prefix.IP().BitLen())-int(prefix.Bits()))
+}
+
+// PoolUtilizationCollector is a prometheus.Collector exposing the utilization of the network pools.
+// It additionally outputs a warning each time the utilization of a pool crosses the configured threshold,
+// to give operators lead time before the pool gets exhausted.
+type PoolUtilizationCollector struct {
+	ipam      *IPAM
+	threshold float64
+	// locker, if set, serializes the retrieval of the utilization with the other (external) users of the IPAM.
+	locker sync.Locker
+
+	mutex    sync.Mutex
+	exceeded map[string]bool
+}
+
+// NewPoolUtilizationCollector returns a new PoolUtilizationCollector, given the threshold (in the range [0, 1]).
+// The locker, if not nil, is held while retrieving the utilization, to prevent concurrent modifications by
+// the components serializing the IPAM operations through it.
+func NewPoolUtilizationCollector(ipam *IPAM, threshold float64, locker sync.Locker) *PoolUtilizationCollector {
+	return &PoolUtilizationCollector{ipam: ipam, threshold: threshold, locker: locker, exceeded: make(map[string]bool)}
+}
+
+// Describe implements prometheus.Collector.
+func (puc *PoolUtilizationCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- PoolUtilization
+	ch <- PoolUtilizationThresholdExceeded
+}
+
+// Collect implements prometheus.Collector.
+func (puc *PoolUtilizationCollector) Collect(ch chan<- prometheus.Metric) {
+	utilization, err := puc.Check()
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(PoolUtilization, err)
+		ch <- prometheus.NewInvalidMetric(PoolUtilizationThresholdExceeded, err)
+		return
+	}
+
+	for pool, value := range utilization {
+		exceeded := 0.
+		if value > puc.threshold {
+			exceeded = 1
+		}
+		ch <- prometheus.MustNewConstMetric(PoolUtilization, prometheus.GaugeValue, value, pool)
+		ch <- prometheus.MustNewConstMetric(PoolUtilizationThresholdExceeded, prometheus.GaugeValue, exceeded, pool)
+	}
+}
+
+// Check retrieves the current utilization of the network pools, and warns about those crossing the threshold.
+func (puc *PoolUtilizationCollector) Check() (map[string]float64, error) {
+	if puc.locker != nil {
+		puc.locker.Lock()
+	}
+	utilization, err := puc.ipam.PoolsUtilization()
+	if puc.locker != nil {
+		puc.locker.Unlock()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot retrieve the utilization of the network pools: %w", err)
+	}
+
+	puc.mutex.Lock()
+	defer puc.mutex.Unlock()
+
+	for pool, value := range utilization {
+		exceeded := value > puc.threshold
+		switch {
+		case exceeded && !puc.exceeded[pool]:
+			klog.Warningf("Utilization of network pool %s (%.2f%%) exceeded the threshold (%.2f%%)", pool, value*100, puc.threshold*100)
+		case !exceeded && puc.exceeded[pool]:
+			klog.Infof("Utilization of network pool %s (%.2f%%) is back below the threshold (%.2f%%)", pool, value*100, puc.threshold*100)
+		}
+		puc.exceeded[pool] = exceeded
+	}
+
+	return utilization, nil
+}
+
+// Exceeded returns whether the utilization of the given pool exceeded the threshold during the last check.
+func (puc *PoolUtilizationCollector) Exceeded(pool string) bool {
+	puc.mutex.Lock()
+	defer puc.mutex.Unlock()
+
+	return puc.exceeded[pool]
+}
@@ -0,0 +1,113 @@
+// Copyright 2019-2023 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipam
+
+import (
+	"fmt"
+	"sort"
+
+	liqonetutils "github.com/liqotech/liqo/pkg/liqonet/utils"
+	"github.com/liqotech/liqo/pkg/utils/slice"
+)
+
+// reservation associates a network reserved for a remote cluster with the cluster and the role it refers to.
+type reservation struct {
+	clusterID string
+	kind      string
+	network   string
+}
+
+// Validate checks the reserved subnets state for internal consistency, returning all the detected inconsistencies:
+// the networks reserved for remote clusters must not overlap each other, must not overlap the local PodCIDR,
+// ServiceCIDR and reserved subnets, and must refer to a cluster in liveClusters (i.e., with a live NetworkConfig).
+// It does not modify the state, leaving to the caller the decision about how to react to the detected problems.
+func (liqoIPAM *IPAM) Validate(liveClusters []string) []error {
+	var errs []error
+
+	clusterSubnets := liqoIPAM.ipamStorage.getClusterSubnets()
+	clusterIDs := make([]string, 0, len(clusterSubnets))
+	for clusterID := range clusterSubnets {
+		clusterIDs = append(clusterIDs, clusterID)
+	}
+	// Sort the cluster IDs, to output the errors in a deterministic order.
+	sort.Strings(clusterIDs)
+
+	var reservations []reservation
+	for _, clusterID := range clusterIDs {
+		if !slice.ContainsString(liveClusters, clusterID) {
+			errs = append(errs, fmt.Errorf("networks are reserved for cluster %s, which has no live NetworkConfig", clusterID))
+		}
+
+		subnets := clusterSubnets[clusterID]
+		for _, r := range []reservation{
+			{clusterID: clusterID, kind: "PodCIDR", network: subnets.RemotePodCIDR},
+			{clusterID: clusterID, kind: "ExternalCIDR", network: subnets.RemoteExternalCIDR},
+		} {
+			if r.network != "" && !liqonetutils.IsNATDisabled(r.network) {
+				reservations = append(reservations, r)
+			}
+		}
+	}
+
+	// Check the reservations of the remote clusters against each other.
+	for i := range reservations {
+		for j := i + 1; j < len(reservations); j++ {
+			overlaps, err := liqoIPAM.overlapsWithNetwork(reservations[i].network, reservations[j].network)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if overlaps {
+				errs = append(errs, fmt.Errorf("%s %s of cluster %s overlaps with %s %s of cluster %s",
+					reservations[i].kind, reservations[i].network, reservations[i].clusterID,
+					reservations[j].kind, reservations[j].network, reservations[j].clusterID))
+			}
+		}
+	}
+
+	// Check the reservations of the remote clusters against the local networks.
+	local := map[string]string{
+		liqoIPAM.ipamStorage.getPodCIDR():     "local PodCIDR",
+		liqoIPAM.ipamStorage.getServiceCIDR(): "local ServiceCIDR",
+	}
+	for _, reserved := range liqoIPAM.ipamStorage.getReservedSubnets() {
+		if _, found := local[reserved]; !found {
+			local[reserved] = "reserved subnet"
+		}
+	}
+	localNetworks := make([]string, 0, len(local))
+	for network := range local {
+		if network != "" {
+			localNetworks = append(localNetworks, network)
+		}
+	}
+	sort.Strings(localNetworks)
+
+	for i := range reservations {
+		for _, network := range localNetworks {
+			overlaps, err := liqoIPAM.overlapsWithNetwork(reservations[i].network, network)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if overlaps {
+				errs = append(errs, fmt.Errorf("%s %s of cluster %s overlaps with the %s %s",
+					reservations[i].kind, reservations[i].network, reservations[i].clusterID, local[network], network))
+			}
+		}
+	}
+
+	return errs
+}
@@ -513,7 +513,7 @@ func getPreRoutingRulesPerTunnelEndpoint(tep *netv1alpha1.TunnelEndpoint) ([]IPT
 	localRemappedPodCIDR, remotePodCIDR := liqonetutils.GetPodCIDRS(tep)
 
 	rules := make([]IPTableRule, 0)
-	if localRemappedPodCIDR == consts.DefaultCIDRValue {
+	if liqonetutils.IsNATDisabled(localRemappedPodCIDR) {
 		// Remote cluster has not remapped home PodCIDR,
 		// this means there is no need to NAT
 		return rules, nil
@@ -719,7 +719,7 @@ func getPostroutingRules(tep *netv1alpha1.TunnelEndpoint) ([]IPTableRule, error)
 	localPodCIDR := tep.Spec.LocalPodCIDR
 	localRemappedPodCIDR, remotePodCIDR := liqonetutils.GetPodCIDRS(tep)
 	_, remoteExternalCIDR := liqonetutils.GetExternalCIDRS(tep)
-	if localRemappedPodCIDR != consts.DefaultCIDRValue {
+	if !liqonetutils.IsNATDisabled(localRemappedPodCIDR) {
 		// Get the first IP address from the podCIDR of the local cluster
 		// in this case it is the podCIDR to which the local podCIDR has bee remapped by the remote peering cluster
 		natIP, err := liqonetutils.GetFirstIP(localRemappedPodCIDR)
@@ -790,7 +790,7 @@ func getChainRulesPerCluster(tep *netv1alpha1.TunnelEndpoint) (map[string][]IPTa
 			"-m", "comment", "--comment", getClusterPreRoutingChainComment(tep.Spec.ClusterIdentity.ClusterName, consts.ExternalCIDR),
 			"-j", getClusterPreRoutingMappingChain(clusterID)})
 
-	if localRemappedPodCIDR != consts.DefaultCIDRValue {
+	if !liqonetutils.IsNATDisabled(localRemappedPodCIDR) {
 		// For the following rule, source is necessary
 		// because more remote clusters could have
 		// remapped home PodCIDR in the same way, then only use dst is not enough.
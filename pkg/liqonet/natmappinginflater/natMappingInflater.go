@@ -41,6 +41,9 @@ type Interface interface {
 	// externalCIDR is the ExternalCIDR used in the remote cluster for local exported resources:
 	// it can be either the LocalExternalCIDR or the LocalNATExternalCIDR.
 	InitNatMappingsPerCluster(podCIDR, externalCIDR, clusterID string) error
+	// UpdateNatMappingsPerCluster updates the networks of the NAT mappings of a remote cluster (e.g., as re-allocated),
+	// preserving the existing mappings. Parameters are the same of InitNatMappingsPerCluster.
+	UpdateNatMappingsPerCluster(podCIDR, externalCIDR, clusterID string) error
 	// TerminateNatMappingsPerCluster frees/deletes resources allocated for remote cluster.
 	TerminateNatMappingsPerCluster(clusterID string) error
 	// GetNatMappings returns the set of mappings related to a remote cluster.
@@ -49,6 +52,8 @@ type Interface interface {
 	AddMapping(oldIP, newIP, clusterID string) error
 	// RemoveMapping removes a NAT mapping.
 	RemoveMapping(oldIP, clusterID string) error
+	// RenameCluster moves the NAT mappings of a remote cluster from the old to the new cluster ID.
+	RenameCluster(oldID, newID string) error
 }
 
 // NatMappingInflater is an implementation of the NatMappingInflaterInterface
@@ -178,6 +183,37 @@ func (inflater *NatMappingInflater) initResource(podCIDR, externalCIDR, clusterI
 	return nil
 }
 
+// UpdateNatMappingsPerCluster updates the PodCIDR and the ExternalCIDR of the NatMapping resource for remote cluster,
+// preserving the existing mappings. The NAT mappings are initialized in case they have not been yet.
+func (inflater *NatMappingInflater) UpdateNatMappingsPerCluster(podCIDR, externalCIDR, clusterID string) error {
+	// Check parameters
+	if err := checkParams(podCIDR, externalCIDR, clusterID); err != nil {
+		return err
+	}
+	if _, exists := inflater.natMappingsPerCluster[clusterID]; !exists {
+		return inflater.InitNatMappingsPerCluster(podCIDR, externalCIDR, clusterID)
+	}
+
+	retryError := retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		natMappings, err := inflater.getNatMappingResource(clusterID)
+		if err != nil {
+			return fmt.Errorf("cannot retrieve NatMapping resource for cluster %s: %w", clusterID, err)
+		}
+		if natMappings.Spec.PodCIDR == podCIDR && natMappings.Spec.ExternalCIDR == externalCIDR {
+			return nil
+		}
+
+		natMappings.Spec.PodCIDR = podCIDR
+		natMappings.Spec.ExternalCIDR = externalCIDR
+		return inflater.updateNatMappingResource(natMappings)
+	})
+	if retryError != nil {
+		return fmt.Errorf("cannot update NatMapping resource for cluster %s: %w", clusterID, retryError)
+	}
+	klog.Infof("NAT mappings for cluster %s updated (PodCIDR: %s, ExternalCIDR: %s)", clusterID, podCIDR, externalCIDR)
+	return nil
+}
+
 // TerminateNatMappingsPerCluster deletes the NatMapping resource for remote cluster.
 func (inflater *NatMappingInflater) TerminateNatMappingsPerCluster(clusterID string) error {
 	if err := inflater.deleteResourceForCluster(clusterID); err != nil {
@@ -222,6 +258,38 @@ func (inflater *NatMappingInflater) deleteResourceForCluster(clusterID string) e
 	return nil
 }
 
+// RenameCluster moves the NAT mappings of a remote cluster (both the resource and the in-memory structure)
+// from the old to the new cluster ID. It fails in case mappings are already present for the new cluster ID.
+func (inflater *NatMappingInflater) RenameCluster(oldID, newID string) error {
+	if _, exists := inflater.natMappingsPerCluster[newID]; exists {
+		return fmt.Errorf("%s for cluster %s already initialized", consts.NatMappingKind, newID)
+	}
+
+	retryError := retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		natMappings, err := inflater.getNatMappingResource(oldID)
+		if k8sErr.IsNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("cannot retrieve NatMapping resource for cluster %s: %w", oldID, err)
+		}
+
+		natMappings.ObjectMeta.Labels[consts.ClusterIDLabelName] = newID
+		natMappings.Spec.ClusterID = newID
+		return inflater.updateNatMappingResource(natMappings)
+	})
+	if retryError != nil {
+		return fmt.Errorf("cannot rename NatMapping resource for cluster %s: %w", oldID, retryError)
+	}
+
+	if mappings, exists := inflater.natMappingsPerCluster[oldID]; exists {
+		inflater.natMappingsPerCluster[newID] = mappings
+		delete(inflater.natMappingsPerCluster, oldID)
+	}
+	klog.Infof("NAT mappings for cluster %s moved to cluster %s", oldID, newID)
+	return nil
+}
+
 // AddMapping adds a mapping in the resource related to a remote cluster.
 // It also adds the mapping in natMappingsPerCluster.
 func (inflater *NatMappingInflater) AddMapping(oldIP, newIP, clusterID string) error {
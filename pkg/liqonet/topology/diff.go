@@ -0,0 +1,84 @@
+// Copyright 2019-2023 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package topology
+
+// TunnelChange describes a tunnel which changed between two topologies.
+type TunnelChange struct {
+	// Old is the tunnel before the change.
+	Old Tunnel
+	// New is the tunnel after the change.
+	New Tunnel
+}
+
+// RemapsChanged returns whether the CIDR remappings changed.
+func (tc *TunnelChange) RemapsChanged() bool {
+	return tc.Old.Remaps != tc.New.Remaps
+}
+
+// TopologyDiff describes the differences between two topologies.
+type TopologyDiff struct {
+	// Added are the tunnels present only in the new topology.
+	Added []Tunnel
+	// Removed are the tunnels present only in the old topology.
+	Removed []Tunnel
+	// Changed are the tunnels present in both topologies, but with different parameters.
+	Changed []TunnelChange
+}
+
+// Empty returns whether the two topologies are equivalent.
+func (td *TopologyDiff) Empty() bool {
+	return len(td.Added) == 0 && len(td.Removed) == 0 && len(td.Changed) == 0
+}
+
+// Remaps returns the subset of changed tunnels whose CIDR remappings changed.
+func (td *TopologyDiff) Remaps() []TunnelChange {
+	var remaps []TunnelChange
+	for i := range td.Changed {
+		if td.Changed[i].RemapsChanged() {
+			remaps = append(remaps, td.Changed[i])
+		}
+	}
+	return remaps
+}
+
+// DiffTopology compares two topologies, returning the added, removed and changed tunnels (sorted by cluster ID).
+// A nil topology is considered equivalent to an empty one.
+func DiffTopology(old, new *Topology) *TopologyDiff {
+	if old == nil {
+		old = &Topology{}
+	}
+	if new == nil {
+		new = &Topology{}
+	}
+
+	diff := &TopologyDiff{}
+	for _, clusterID := range new.ClusterIDs() {
+		current := new.Tunnels[clusterID]
+		previous, found := old.Tunnels[clusterID]
+		switch {
+		case !found:
+			diff.Added = append(diff.Added, current)
+		case previous != current:
+			diff.Changed = append(diff.Changed, TunnelChange{Old: previous, New: current})
+		}
+	}
+
+	for _, clusterID := range old.ClusterIDs() {
+		if _, found := new.Tunnels[clusterID]; !found {
+			diff.Removed = append(diff.Removed, old.Tunnels[clusterID])
+		}
+	}
+	return diff
+}
@@ -0,0 +1,104 @@
+// Copyright 2019-2023 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package topology_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	discoveryv1alpha1 "github.com/liqotech/liqo/apis/discovery/v1alpha1"
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+	"github.com/liqotech/liqo/pkg/liqonet/topology"
+)
+
+var _ = Describe("Topology diff", func() {
+	tep := func(clusterID, endpointIP, remoteNATPodCIDR string) netv1alpha1.TunnelEndpoint {
+		return netv1alpha1.TunnelEndpoint{Spec: netv1alpha1.TunnelEndpointSpec{
+			ClusterIdentity:  discoveryv1alpha1.ClusterIdentity{ClusterID: clusterID},
+			EndpointIP:       endpointIP,
+			RemotePodCIDR:    "10.0.0.0/16",
+			RemoteNATPodCIDR: remoteNATPodCIDR,
+		}}
+	}
+
+	var old, new *topology.Topology
+	var diff *topology.TopologyDiff
+
+	BeforeEach(func() {
+		old = topology.NewTopology([]netv1alpha1.TunnelEndpoint{
+			tep("foo", "1.1.1.1", "None"),
+			tep("bar", "2.2.2.2", "None"),
+			tep("baz", "3.3.3.3", "None"),
+		})
+	})
+
+	JustBeforeEach(func() { diff = topology.DiffTopology(old, new) })
+
+	When("the topologies are equivalent", func() {
+		BeforeEach(func() { new = old })
+
+		It("should report no differences", func() { Expect(diff.Empty()).To(BeTrue()) })
+	})
+
+	When("a tunnel is added and others change", func() {
+		BeforeEach(func() {
+			new = topology.NewTopology([]netv1alpha1.TunnelEndpoint{
+				tep("foo", "1.1.1.1", "None"),
+				tep("bar", "2.2.2.2", "10.200.0.0/16"),
+				tep("baz", "4.4.4.4", "None"),
+				tep("qux", "5.5.5.5", "None"),
+			})
+		})
+
+		It("should report the added tunnel", func() {
+			Expect(diff.Added).To(HaveLen(1))
+			Expect(diff.Added[0].ClusterID).To(Equal("qux"))
+		})
+		It("should report the changed tunnels", func() {
+			Expect(diff.Changed).To(HaveLen(2))
+			Expect(diff.Changed[0].Old.ClusterID).To(Equal("bar"))
+			Expect(diff.Changed[1].Old.EndpointIP).To(Equal("3.3.3.3"))
+			Expect(diff.Changed[1].New.EndpointIP).To(Equal("4.4.4.4"))
+		})
+		It("should report the CIDR remaps", func() {
+			Expect(diff.Remaps()).To(HaveLen(1))
+			Expect(diff.Remaps()[0].New.Remaps.RemoteNATPodCIDR).To(Equal("10.200.0.0/16"))
+		})
+		It("should report no removed tunnels", func() { Expect(diff.Removed).To(BeEmpty()) })
+	})
+
+	When("a tunnel is removed", func() {
+		BeforeEach(func() {
+			new = topology.NewTopology([]netv1alpha1.TunnelEndpoint{tep("foo", "1.1.1.1", "None"), tep("bar", "2.2.2.2", "None")})
+		})
+
+		It("should report the removed tunnel", func() {
+			Expect(diff.Removed).To(HaveLen(1))
+			Expect(diff.Removed[0].ClusterID).To(Equal("baz"))
+		})
+		It("should report no other differences", func() {
+			Expect(diff.Added).To(BeEmpty())
+			Expect(diff.Changed).To(BeEmpty())
+		})
+	})
+
+	When("the old topology is nil", func() {
+		BeforeEach(func() {
+			old, new = nil, topology.NewTopology([]netv1alpha1.TunnelEndpoint{tep("foo", "1.1.1.1", "None")})
+		})
+
+		It("should report all tunnels as added", func() { Expect(diff.Added).To(HaveLen(1)) })
+	})
+})
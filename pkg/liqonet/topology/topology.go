@@ -0,0 +1,83 @@
+// Copyright 2019-2023 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package topology
+
+import (
+	"sort"
+
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+)
+
+// Tunnel describes the tunnel towards a remote cluster.
+type Tunnel struct {
+	// ClusterID is the cluster ID of the remote cluster.
+	ClusterID string
+	// EndpointIP is the public IP of the remote tunnel endpoint.
+	EndpointIP string
+	// BackendType is the technology used to establish the tunnel.
+	BackendType string
+	// Remaps are the CIDR remappings concerning the remote cluster.
+	Remaps Remaps
+}
+
+// Remaps describes the CIDR remappings concerning a remote cluster, in both directions.
+type Remaps struct {
+	// RemotePodCIDR is the PodCIDR of the remote cluster, and RemoteNATPodCIDR the network it is remapped to locally.
+	RemotePodCIDR, RemoteNATPodCIDR string
+	// RemoteExternalCIDR is the ExternalCIDR of the remote cluster, and RemoteNATExternalCIDR the network it is remapped to locally.
+	RemoteExternalCIDR, RemoteNATExternalCIDR string
+	// LocalNATPodCIDR is the network the local PodCIDR is remapped to by the remote cluster.
+	LocalNATPodCIDR string
+	// LocalNATExternalCIDR is the network the local ExternalCIDR is remapped to by the remote cluster.
+	LocalNATExternalCIDR string
+}
+
+// Topology is a snapshot of the network topology of the local cluster.
+type Topology struct {
+	// Tunnels are the tunnels towards the remote clusters, indexed by cluster ID.
+	Tunnels map[string]Tunnel
+}
+
+// NewTopology builds the topology from the given TunnelEndpoints.
+func NewTopology(teps []netv1alpha1.TunnelEndpoint) *Topology {
+	topology := &Topology{Tunnels: make(map[string]Tunnel, len(teps))}
+	for i := range teps {
+		spec := &teps[i].Spec
+		topology.Tunnels[spec.ClusterIdentity.ClusterID] = Tunnel{
+			ClusterID:   spec.ClusterIdentity.ClusterID,
+			EndpointIP:  spec.EndpointIP,
+			BackendType: spec.BackendType,
+			Remaps: Remaps{
+				RemotePodCIDR:         spec.RemotePodCIDR,
+				RemoteNATPodCIDR:      spec.RemoteNATPodCIDR,
+				RemoteExternalCIDR:    spec.RemoteExternalCIDR,
+				RemoteNATExternalCIDR: spec.RemoteNATExternalCIDR,
+				LocalNATPodCIDR:       spec.LocalNATPodCIDR,
+				LocalNATExternalCIDR:  spec.LocalNATExternalCIDR,
+			},
+		}
+	}
+	return topology
+}
+
+// ClusterIDs returns the sorted cluster IDs of the remote clusters the tunnels are established with.
+func (t *Topology) ClusterIDs() []string {
+	clusterIDs := make([]string, 0, len(t.Tunnels))
+	for clusterID := range t.Tunnels {
+		clusterIDs = append(clusterIDs, clusterID)
+	}
+	sort.Strings(clusterIDs)
+	return clusterIDs
+}
@@ -0,0 +1,108 @@
+// Copyright 2019-2023 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+)
+
+// NATDirection identifies the direction of the traffic a NATRule applies to.
+type NATDirection string
+
+const (
+	// NATDirectionEgress identifies the traffic from the local cluster, whose networks are possibly remapped by the remote one.
+	NATDirectionEgress NATDirection = "Egress"
+	// NATDirectionIngress identifies the traffic from the remote cluster, whose networks are possibly remapped by the local one.
+	NATDirectionIngress NATDirection = "Ingress"
+)
+
+// NATRule describes the translation of a network through a given tunnel, in a format directly consumable by
+// the dataplane tooling (e.g., to configure the corresponding NETMAP rules and ipsets).
+type NATRule struct {
+	// ClusterID is the identifier of the remote cluster the tunnel is established with.
+	ClusterID string `json:"clusterID"`
+	// Network identifies the network the rule refers to.
+	Network CIDRPairName `json:"network"`
+	// Direction is the direction of the traffic the rule applies to.
+	Direction NATDirection `json:"direction"`
+	// Original is the network as configured in the owner cluster.
+	Original string `json:"original"`
+	// Remapped is the network the original one is translated to, equal to the original one in case of pass-through.
+	Remapped string `json:"remapped"`
+	// PassThrough is whether the network is not remapped, hence no translation is required.
+	PassThrough bool `json:"passThrough"`
+}
+
+// String returns the space separated representation of the rule (i.e., cluster ID, network, direction, original and remapped CIDR).
+func (r *NATRule) String() string {
+	return fmt.Sprintf("%s %s %s %s %s", r.ClusterID, r.Network, r.Direction, r.Original, r.Remapped)
+}
+
+// NATRules returns the NAT rules configured by the given TunnelEndpoint, one for each of the local and remote networks.
+func NATRules(tep *netv1alpha1.TunnelEndpoint) []NATRule {
+	pairs := RemapPairs(tep)
+	rules := make([]NATRule, 0, len(pairs))
+	for i := range pairs {
+		direction := NATDirectionIngress
+		if pairs[i].Name == LocalPodCIDRPair || pairs[i].Name == LocalExternalCIDRPair {
+			direction = NATDirectionEgress
+		}
+
+		rules = append(rules, NATRule{
+			ClusterID: tep.Spec.ClusterIdentity.ClusterID,
+			Network:   pairs[i].Name,
+			Direction: direction,
+			Original:  pairs[i].Original,
+			Remapped:  pairs[i].Remapped,
+
+			PassThrough: !pairs[i].IsRemapped(),
+		})
+	}
+	return rules
+}
+
+// ExportNATMappings returns the NAT rules of all active tunnels (i.e., TunnelEndpoints not being deleted), sorted
+// by cluster ID, including the pass-through ones for the networks which are not remapped. Invalid TunnelEndpoints are skipped.
+func ExportNATMappings(ctx context.Context, cl client.Client) ([]NATRule, error) {
+	var teps netv1alpha1.TunnelEndpointList
+	if err := cl.List(ctx, &teps); err != nil {
+		return nil, fmt.Errorf("failed to list TunnelEndpoints: %w", err)
+	}
+
+	sort.Slice(teps.Items, func(i, j int) bool {
+		return teps.Items[i].Spec.ClusterIdentity.ClusterID < teps.Items[j].Spec.ClusterIdentity.ClusterID
+	})
+
+	var rules []NATRule
+	for i := range teps.Items {
+		tep := &teps.Items[i]
+		if !tep.GetDeletionTimestamp().IsZero() {
+			continue
+		}
+		if err := CheckTep(tep); err != nil {
+			klog.Warningf("Skipping the NAT rules of invalid TunnelEndpoint %q: %v", klog.KObj(tep), err)
+			continue
+		}
+		rules = append(rules, NATRules(tep)...)
+	}
+	return rules, nil
+}
@@ -0,0 +1,130 @@
+// Copyright 2019-2023 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils_test
+
+import (
+	"context"
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	discoveryv1alpha1 "github.com/liqotech/liqo/apis/discovery/v1alpha1"
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+	"github.com/liqotech/liqo/pkg/consts"
+	liqonetutils "github.com/liqotech/liqo/pkg/liqonet/utils"
+)
+
+var _ = Describe("ExportNATMappings", func() {
+	var (
+		ctx   context.Context
+		teps  []*netv1alpha1.TunnelEndpoint
+		rules []liqonetutils.NATRule
+		err   error
+	)
+
+	tunnelEndpoint := func(clusterID, localNATPodCIDR, remoteNATPodCIDR string) *netv1alpha1.TunnelEndpoint {
+		return &netv1alpha1.TunnelEndpoint{
+			ObjectMeta: metav1.ObjectMeta{Name: clusterID, Namespace: "liqo-tenant"},
+			Spec: netv1alpha1.TunnelEndpointSpec{
+				ClusterIdentity:       discoveryv1alpha1.ClusterIdentity{ClusterID: clusterID},
+				LocalPodCIDR:          "10.0.0.0/16",
+				LocalNATPodCIDR:       localNATPodCIDR,
+				LocalExternalCIDR:     "10.1.0.0/16",
+				LocalNATExternalCIDR:  consts.DefaultCIDRValue,
+				RemotePodCIDR:         "10.0.0.0/16",
+				RemoteNATPodCIDR:      remoteNATPodCIDR,
+				RemoteExternalCIDR:    "10.2.0.0/16",
+				RemoteNATExternalCIDR: consts.DefaultCIDRValue,
+			},
+		}
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		teps = nil
+	})
+
+	JustBeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(netv1alpha1.AddToScheme(scheme)).To(Succeed())
+		builder := fake.NewClientBuilder().WithScheme(scheme)
+		for _, tep := range teps {
+			builder = builder.WithObjects(tep)
+		}
+		rules, err = liqonetutils.ExportNATMappings(ctx, builder.Build())
+	})
+
+	When("no tunnels are active", func() {
+		It("should succeed", func() { Expect(err).ToNot(HaveOccurred()) })
+		It("should return no rules", func() { Expect(rules).To(BeEmpty()) })
+	})
+
+	When("multiple tunnels are active", func() {
+		BeforeEach(func() {
+			teps = []*netv1alpha1.TunnelEndpoint{
+				tunnelEndpoint("remapped", "10.50.0.0/16", "10.60.0.0/16"),
+				tunnelEndpoint("pass-through", consts.DefaultCIDRValue, consts.DefaultCIDRValue),
+			}
+		})
+
+		It("should succeed", func() { Expect(err).ToNot(HaveOccurred()) })
+		It("should return the rules of all tunnels, sorted by cluster ID", func() {
+			Expect(rules).To(HaveLen(8))
+			Expect(rules[0].ClusterID).To(Equal("pass-through"))
+			Expect(rules[7].ClusterID).To(Equal("remapped"))
+		})
+		It("should return the rules of the remapped networks", func() {
+			Expect(rules).To(ContainElements(
+				liqonetutils.NATRule{ClusterID: "remapped", Network: liqonetutils.LocalPodCIDRPair,
+					Direction: liqonetutils.NATDirectionEgress, Original: "10.0.0.0/16", Remapped: "10.50.0.0/16"},
+				liqonetutils.NATRule{ClusterID: "remapped", Network: liqonetutils.RemotePodCIDRPair,
+					Direction: liqonetutils.NATDirectionIngress, Original: "10.0.0.0/16", Remapped: "10.60.0.0/16"},
+			))
+		})
+		It("should return pass-through rules for the networks not remapped", func() {
+			Expect(rules).To(ContainElements(
+				liqonetutils.NATRule{ClusterID: "pass-through", Network: liqonetutils.LocalPodCIDRPair,
+					Direction: liqonetutils.NATDirectionEgress, Original: "10.0.0.0/16", Remapped: "10.0.0.0/16", PassThrough: true},
+				liqonetutils.NATRule{ClusterID: "remapped", Network: liqonetutils.RemoteExternalCIDRPair,
+					Direction: liqonetutils.NATDirectionIngress, Original: "10.2.0.0/16", Remapped: "10.2.0.0/16", PassThrough: true},
+			))
+		})
+		It("should produce serializable rules", func() {
+			data, err := json.Marshal(rules[0])
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(data)).To(MatchJSON(`{"clusterID":"pass-through","network":"LocalPodCIDR","direction":"Egress",` +
+				`"original":"10.0.0.0/16","remapped":"10.0.0.0/16","passThrough":true}`))
+			Expect(rules[0].String()).To(Equal("pass-through LocalPodCIDR Egress 10.0.0.0/16 10.0.0.0/16"))
+		})
+	})
+
+	When("a tunnel is invalid", func() {
+		BeforeEach(func() {
+			invalid := tunnelEndpoint("invalid", consts.DefaultCIDRValue, consts.DefaultCIDRValue)
+			invalid.Spec.RemotePodCIDR = "invalid"
+			teps = []*netv1alpha1.TunnelEndpoint{invalid, tunnelEndpoint("valid", consts.DefaultCIDRValue, consts.DefaultCIDRValue)}
+		})
+
+		It("should succeed", func() { Expect(err).ToNot(HaveOccurred()) })
+		It("should skip the invalid tunnel", func() {
+			Expect(rules).To(HaveLen(4))
+			Expect(rules).To(HaveEach(HaveField("ClusterID", "valid")))
+		})
+	})
+})
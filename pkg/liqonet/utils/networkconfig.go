@@ -0,0 +1,79 @@
+// Copyright 2019-2023 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"inet.af/netaddr"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+)
+
+// netConfigPollInterval is the interval between two consecutive checks of the status of a NetworkConfig.
+const netConfigPollInterval = 500 * time.Millisecond
+
+// WaitForNetConfigProcessed polls until the given NetworkConfig has been processed, i.e., its NAT status fields have
+// been populated, and returns it. An error is returned in case the timeout expires, or the NetworkConfig reaches a
+// terminal status (i.e., it is deleted, or the NAT fields are set to invalid values).
+func WaitForNetConfigProcessed(ctx context.Context, cl client.Client, name types.NamespacedName,
+	timeout time.Duration) (*netv1alpha1.NetworkConfig, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var netcfg netv1alpha1.NetworkConfig
+	err := wait.PollImmediateUntilWithContext(ctx, netConfigPollInterval, func(ctx context.Context) (done bool, err error) {
+		if err := cl.Get(ctx, name, &netcfg); err != nil {
+			if kerrors.IsNotFound(err) {
+				return false, fmt.Errorf("NetworkConfig %q does no longer exist", name)
+			}
+			return false, err
+		}
+
+		if !netcfg.GetDeletionTimestamp().IsZero() {
+			return false, fmt.Errorf("NetworkConfig %q is being deleted", name)
+		}
+		return IsNetConfigProcessed(&netcfg)
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("failed waiting for NetworkConfig %q to be processed: %w", name, err)
+	}
+	return &netcfg, nil
+}
+
+// IsNetConfigProcessed returns whether the NAT status fields of the given NetworkConfig have been populated.
+// An error is returned in case they are set to invalid values.
+func IsNetConfigProcessed(netcfg *netv1alpha1.NetworkConfig) (bool, error) {
+	if !netcfg.Status.Processed || netcfg.Status.PodCIDRNAT == "" || netcfg.Status.ExternalCIDRNAT == "" {
+		return false, nil
+	}
+
+	for _, network := range []string{netcfg.Status.PodCIDRNAT, netcfg.Status.ExternalCIDRNAT} {
+		if IsNATDisabled(network) {
+			continue
+		}
+		if _, err := netaddr.ParseIPPrefix(network); err != nil {
+			return false, fmt.Errorf("invalid NAT network %q: %w", network, err)
+		}
+	}
+	return true, nil
+}
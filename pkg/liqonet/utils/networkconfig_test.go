@@ -0,0 +1,95 @@
+// Copyright 2019-2023 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+	"github.com/liqotech/liqo/pkg/consts"
+	liqonetutils "github.com/liqotech/liqo/pkg/liqonet/utils"
+)
+
+var _ = Describe("WaitForNetConfigProcessed", func() {
+	var (
+		ctx    context.Context
+		cl     client.Client
+		name   types.NamespacedName
+		netcfg *netv1alpha1.NetworkConfig
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		name = types.NamespacedName{Name: "netcfg", Namespace: "liqo-tenant"}
+		netcfg = &netv1alpha1.NetworkConfig{ObjectMeta: metav1.ObjectMeta{Name: name.Name, Namespace: name.Namespace}}
+
+		scheme := runtime.NewScheme()
+		Expect(netv1alpha1.AddToScheme(scheme)).To(Succeed())
+		cl = fake.NewClientBuilder().WithScheme(scheme).WithObjects(netcfg).Build()
+	})
+
+	setStatus := func(status netv1alpha1.NetworkConfigStatus) {
+		Expect(cl.Get(ctx, name, netcfg)).To(Succeed())
+		netcfg.Status = status
+		Expect(cl.Status().Update(ctx, netcfg)).To(Succeed())
+	}
+
+	When("the NetworkConfig is processed", func() {
+		It("should return once the status is set", func() {
+			go func() {
+				defer GinkgoRecover()
+				time.Sleep(100 * time.Millisecond)
+				setStatus(netv1alpha1.NetworkConfigStatus{Processed: true, PodCIDRNAT: "10.50.0.0/16", ExternalCIDRNAT: consts.DefaultCIDRValue})
+			}()
+
+			processed, err := liqonetutils.WaitForNetConfigProcessed(ctx, cl, name, 5*time.Second)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(processed.Status.PodCIDRNAT).To(Equal("10.50.0.0/16"))
+			Expect(processed.Status.ExternalCIDRNAT).To(Equal(consts.DefaultCIDRValue))
+		})
+	})
+
+	When("the NetworkConfig is not processed before the timeout", func() {
+		It("should return an error", func() {
+			_, err := liqonetutils.WaitForNetConfigProcessed(ctx, cl, name, 100*time.Millisecond)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	When("the NetworkConfig reaches a terminal error status", func() {
+		It("should return an error", func() {
+			setStatus(netv1alpha1.NetworkConfigStatus{Processed: true, PodCIDRNAT: "invalid", ExternalCIDRNAT: consts.DefaultCIDRValue})
+			_, err := liqonetutils.WaitForNetConfigProcessed(ctx, cl, name, 5*time.Second)
+			Expect(err).To(MatchError(ContainSubstring("invalid NAT network")))
+		})
+	})
+
+	When("the NetworkConfig does not exist", func() {
+		It("should return an error", func() {
+			Expect(cl.Delete(ctx, netcfg)).To(Succeed())
+			_, err := liqonetutils.WaitForNetConfigProcessed(ctx, cl, name, 5*time.Second)
+			Expect(err).To(MatchError(ContainSubstring("does no longer exist")))
+		})
+	})
+})
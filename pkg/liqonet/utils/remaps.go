@@ -0,0 +1,66 @@
+// Copyright 2019-2023 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+)
+
+// CIDRPairName identifies the network a CIDRPair refers to.
+type CIDRPairName string
+
+const (
+	// LocalPodCIDRPair identifies the PodCIDR of the local cluster, possibly remapped by the remote cluster.
+	LocalPodCIDRPair CIDRPairName = "LocalPodCIDR"
+	// LocalExternalCIDRPair identifies the ExternalCIDR of the local cluster, possibly remapped by the remote cluster.
+	LocalExternalCIDRPair CIDRPairName = "LocalExternalCIDR"
+	// RemotePodCIDRPair identifies the PodCIDR of the remote cluster, possibly remapped by the local cluster.
+	RemotePodCIDRPair CIDRPairName = "RemotePodCIDR"
+	// RemoteExternalCIDRPair identifies the ExternalCIDR of the remote cluster, possibly remapped by the local cluster.
+	RemoteExternalCIDRPair CIDRPairName = "RemoteExternalCIDR"
+)
+
+// CIDRPair associates an original network with the one it is remapped to.
+type CIDRPair struct {
+	Name CIDRPairName
+	// Original is the network as configured in the owner cluster.
+	Original string
+	// Remapped is the network the original one is remapped to, or equal to the original one if NAT is not required.
+	Remapped string
+}
+
+// IsRemapped returns whether the network is remapped to a different one.
+func (p *CIDRPair) IsRemapped() bool {
+	return p.Original != p.Remapped
+}
+
+// RemapPairs returns the pairs of original and remapped networks configured in the given TunnelEndpoint,
+// for both the local and the remote PodCIDR and ExternalCIDR.
+func RemapPairs(tep *netv1alpha1.TunnelEndpoint) []CIDRPair {
+	return []CIDRPair{
+		newCIDRPair(LocalPodCIDRPair, tep.Spec.LocalPodCIDR, tep.Spec.LocalNATPodCIDR),
+		newCIDRPair(LocalExternalCIDRPair, tep.Spec.LocalExternalCIDR, tep.Spec.LocalNATExternalCIDR),
+		newCIDRPair(RemotePodCIDRPair, tep.Spec.RemotePodCIDR, tep.Spec.RemoteNATPodCIDR),
+		newCIDRPair(RemoteExternalCIDRPair, tep.Spec.RemoteExternalCIDR, tep.Spec.RemoteNATExternalCIDR),
+	}
+}
+
+// newCIDRPair returns a new CIDRPair, given the original and remapped networks (possibly unset, if NAT is not required).
+func newCIDRPair(name CIDRPairName, original, remapped string) CIDRPair {
+	if IsNATDisabled(remapped) || remapped == "" {
+		remapped = original
+	}
+	return CIDRPair{Name: name, Original: original, Remapped: remapped}
+}
@@ -0,0 +1,81 @@
+// Copyright 2019-2023 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+	"github.com/liqotech/liqo/pkg/consts"
+	liqonetutils "github.com/liqotech/liqo/pkg/liqonet/utils"
+)
+
+var _ = Describe("RemapPairs", func() {
+	var (
+		tep   *netv1alpha1.TunnelEndpoint
+		pairs []liqonetutils.CIDRPair
+	)
+
+	BeforeEach(func() {
+		tep = &netv1alpha1.TunnelEndpoint{Spec: netv1alpha1.TunnelEndpointSpec{
+			LocalPodCIDR:          "10.0.0.0/16",
+			LocalNATPodCIDR:       consts.DefaultCIDRValue,
+			LocalExternalCIDR:     "10.1.0.0/16",
+			LocalNATExternalCIDR:  consts.DefaultCIDRValue,
+			RemotePodCIDR:         "10.0.0.0/16",
+			RemoteNATPodCIDR:      consts.DefaultCIDRValue,
+			RemoteExternalCIDR:    "10.1.0.0/16",
+			RemoteNATExternalCIDR: consts.DefaultCIDRValue,
+		}}
+	})
+
+	JustBeforeEach(func() { pairs = liqonetutils.RemapPairs(tep) })
+
+	When("NAT is disabled", func() {
+		It("should return the pairs for all networks", func() { Expect(pairs).To(HaveLen(4)) })
+		It("should map each network to itself", func() {
+			for i := range pairs {
+				Expect(pairs[i].Remapped).To(Equal(pairs[i].Original))
+				Expect(pairs[i].IsRemapped()).To(BeFalse())
+			}
+		})
+	})
+
+	When("NAT is enabled", func() {
+		BeforeEach(func() {
+			tep.Spec.LocalNATPodCIDR = "10.50.0.0/16"
+			tep.Spec.RemoteNATExternalCIDR = "10.60.0.0/16"
+		})
+
+		It("should return the remapped networks", func() {
+			Expect(pairs).To(ConsistOf(
+				liqonetutils.CIDRPair{Name: liqonetutils.LocalPodCIDRPair, Original: "10.0.0.0/16", Remapped: "10.50.0.0/16"},
+				liqonetutils.CIDRPair{Name: liqonetutils.LocalExternalCIDRPair, Original: "10.1.0.0/16", Remapped: "10.1.0.0/16"},
+				liqonetutils.CIDRPair{Name: liqonetutils.RemotePodCIDRPair, Original: "10.0.0.0/16", Remapped: "10.0.0.0/16"},
+				liqonetutils.CIDRPair{Name: liqonetutils.RemoteExternalCIDRPair, Original: "10.1.0.0/16", Remapped: "10.60.0.0/16"},
+			))
+		})
+		It("should report the remapped networks as such", func() {
+			Expect(pairs[0].IsRemapped()).To(BeTrue())
+			Expect(pairs[1].IsRemapped()).To(BeFalse())
+		})
+	})
+
+	When("the NAT fields are unset", func() {
+		BeforeEach(func() { tep.Spec.RemoteNATPodCIDR = "" })
+		It("should map the network to itself", func() { Expect(pairs[2].Remapped).To(Equal("10.0.0.0/16")) })
+	})
+})
@@ -0,0 +1,115 @@
+// Copyright 2019-2023 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+	liqoconst "github.com/liqotech/liqo/pkg/consts"
+)
+
+// ValidateTunnelEndpointUpdate returns an error in case the update from oldTep to newTep mutates any of the TunnelEndpoint
+// fields which are immutable after creation (i.e., the identifier of the remote cluster).
+func ValidateTunnelEndpointUpdate(oldTep, newTep *netv1alpha1.TunnelEndpoint) error {
+	oldID, newID := oldTep.Spec.ClusterIdentity.ClusterID, newTep.Spec.ClusterIdentity.ClusterID
+	if oldID != "" && oldID != newID {
+		return fmt.Errorf("the ClusterID value cannot be modified after creation (from %q to %q)", oldID, newID)
+	}
+	return nil
+}
+
+// MTUOverride returns the per-cluster MTU override configured through the annotation of the given object (i.e., the
+// local NetworkConfig or the TunnelEndpoint), and whether it is present. An error is returned if the value is invalid.
+func MTUOverride(obj metav1.Object) (mtu int, found bool, err error) {
+	value, found := obj.GetAnnotations()[liqoconst.MTUAnnotationKey]
+	if !found {
+		return 0, false, nil
+	}
+
+	mtu, err = strconv.Atoi(value)
+	if err != nil {
+		return 0, true, fmt.Errorf("invalid MTU override %q: %w", value, err)
+	}
+	if mtu < liqoconst.MinMTU || mtu > liqoconst.MaxMTU {
+		return 0, true, fmt.Errorf("invalid MTU override %d: must be between %d and %d", mtu, liqoconst.MinMTU, liqoconst.MaxMTU)
+	}
+	return mtu, true, nil
+}
+
+// EffectiveMTU returns the MTU to be used towards the remote cluster described by the given TunnelEndpoint:
+// the per-cluster override takes precedence, if present and valid, while the computed value is returned otherwise.
+func EffectiveMTU(tep *netv1alpha1.TunnelEndpoint, computed int) int {
+	mtu, found, err := MTUOverride(tep)
+	if err != nil {
+		klog.Warningf("%s -> ignoring MTU override: %v", tep.Spec.ClusterIdentity, err)
+		return computed
+	}
+	if !found {
+		return computed
+	}
+	return mtu
+}
+
+// tunnelsPollInterval is the interval between two consecutive checks of the status of the TunnelEndpoints.
+const tunnelsPollInterval = 500 * time.Millisecond
+
+// WaitForTunnelsReady polls until all the TunnelEndpoints associated with the given remote cluster (possibly more than
+// one, e.g., in case of dual-stack or multi-backend setups) are connected. In case the timeout expires, it returns the
+// names of the TunnelEndpoints which are not yet ready (or none, if no TunnelEndpoint exists), along with an error.
+func WaitForTunnelsReady(ctx context.Context, cl client.Client, clusterID string, timeout time.Duration) (notReady []string, err error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	err = wait.PollImmediateUntilWithContext(ctx, tunnelsPollInterval, func(ctx context.Context) (done bool, err error) {
+		var teps netv1alpha1.TunnelEndpointList
+		if err := cl.List(ctx, &teps, client.MatchingLabels{liqoconst.ClusterIDLabelName: clusterID}); err != nil {
+			return false, err
+		}
+
+		notReady = nil
+		for i := range teps.Items {
+			if !IsTunnelReady(&teps.Items[i]) {
+				notReady = append(notReady, client.ObjectKeyFromObject(&teps.Items[i]).String())
+			}
+		}
+		sort.Strings(notReady)
+		return len(teps.Items) > 0 && len(notReady) == 0, nil
+	})
+
+	if err != nil {
+		if len(notReady) > 0 {
+			return notReady, fmt.Errorf("failed waiting for the tunnels towards cluster %q to be ready (not ready: %s): %w",
+				clusterID, strings.Join(notReady, ", "), err)
+		}
+		return nil, fmt.Errorf("failed waiting for the tunnels towards cluster %q to be ready: %w", clusterID, err)
+	}
+	return nil, nil
+}
+
+// IsTunnelReady returns whether the given TunnelEndpoint is connected, and not being deleted.
+func IsTunnelReady(tep *netv1alpha1.TunnelEndpoint) bool {
+	return tep.GetDeletionTimestamp().IsZero() && tep.Status.Connection.Status == netv1alpha1.Connected
+}
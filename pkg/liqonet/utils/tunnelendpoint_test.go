@@ -0,0 +1,179 @@
+// Copyright 2019-2023 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	discoveryv1alpha1 "github.com/liqotech/liqo/apis/discovery/v1alpha1"
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+	liqoconst "github.com/liqotech/liqo/pkg/consts"
+	liqonetutils "github.com/liqotech/liqo/pkg/liqonet/utils"
+)
+
+var _ = Describe("ValidateTunnelEndpointUpdate", func() {
+	var old, updated *netv1alpha1.TunnelEndpoint
+
+	BeforeEach(func() {
+		old = &netv1alpha1.TunnelEndpoint{Spec: netv1alpha1.TunnelEndpointSpec{
+			ClusterIdentity: discoveryv1alpha1.ClusterIdentity{ClusterID: "remote-cluster-id", ClusterName: "remote-cluster"},
+			RemotePodCIDR:   "10.200.0.0/16",
+			EndpointIP:      "172.16.0.1",
+		}}
+		updated = old.DeepCopy()
+	})
+
+	When("no field is modified", func() {
+		It("should allow the update", func() { Expect(liqonetutils.ValidateTunnelEndpointUpdate(old, updated)).To(Succeed()) })
+	})
+
+	When("mutable fields are modified", func() {
+		BeforeEach(func() {
+			updated.Spec.ClusterIdentity.ClusterName = "renamed-cluster"
+			updated.Spec.RemotePodCIDR = "10.201.0.0/16"
+			updated.Spec.EndpointIP = "172.16.0.2"
+		})
+
+		It("should allow the update", func() { Expect(liqonetutils.ValidateTunnelEndpointUpdate(old, updated)).To(Succeed()) })
+	})
+
+	When("the cluster ID is modified", func() {
+		BeforeEach(func() { updated.Spec.ClusterIdentity.ClusterID = "other-cluster-id" })
+
+		It("should reject the update", func() {
+			Expect(liqonetutils.ValidateTunnelEndpointUpdate(old, updated)).To(MatchError(ContainSubstring("ClusterID")))
+		})
+	})
+
+	When("the cluster ID was not yet set", func() {
+		BeforeEach(func() {
+			old.Spec.ClusterIdentity.ClusterID = ""
+			updated.Spec.ClusterIdentity.ClusterID = "remote-cluster-id"
+		})
+
+		It("should allow the update", func() { Expect(liqonetutils.ValidateTunnelEndpointUpdate(old, updated)).To(Succeed()) })
+	})
+})
+
+var _ = Describe("EffectiveMTU", func() {
+	const computed = 1440
+	var tep *netv1alpha1.TunnelEndpoint
+
+	BeforeEach(func() {
+		tep = &netv1alpha1.TunnelEndpoint{Spec: netv1alpha1.TunnelEndpointSpec{
+			ClusterIdentity: discoveryv1alpha1.ClusterIdentity{ClusterID: "remote-cluster-id", ClusterName: "remote-cluster"},
+		}}
+	})
+
+	When("no override is configured", func() {
+		It("should return the computed MTU", func() { Expect(liqonetutils.EffectiveMTU(tep, computed)).To(Equal(computed)) })
+	})
+
+	When("a valid override is configured", func() {
+		BeforeEach(func() { tep.SetAnnotations(map[string]string{liqoconst.MTUAnnotationKey: "1300"}) })
+
+		It("should win over the computed MTU", func() { Expect(liqonetutils.EffectiveMTU(tep, computed)).To(Equal(1300)) })
+	})
+
+	DescribeTable("an invalid override is configured",
+		func(value string) {
+			tep.SetAnnotations(map[string]string{liqoconst.MTUAnnotationKey: value})
+			_, found, err := liqonetutils.MTUOverride(tep)
+			Expect(found).To(BeTrue())
+			Expect(err).To(HaveOccurred())
+			Expect(liqonetutils.EffectiveMTU(tep, computed)).To(Equal(computed))
+		},
+		Entry("not a number", "foo"),
+		Entry("empty", ""),
+		Entry("too small", "67"),
+		Entry("too large", "65536"),
+	)
+})
+
+var _ = Describe("WaitForTunnelsReady", func() {
+	const clusterID = "remote-cluster-id"
+
+	var (
+		ctx           context.Context
+		cl            client.Client
+		first, second *netv1alpha1.TunnelEndpoint
+	)
+
+	forgeTunnelEndpoint := func(name string) *netv1alpha1.TunnelEndpoint {
+		return &netv1alpha1.TunnelEndpoint{ObjectMeta: metav1.ObjectMeta{
+			Name: name, Namespace: "liqo-tenant", Labels: map[string]string{liqoconst.ClusterIDLabelName: clusterID},
+		}}
+	}
+
+	setConnected := func(tep *netv1alpha1.TunnelEndpoint) {
+		Expect(cl.Get(ctx, client.ObjectKeyFromObject(tep), tep)).To(Succeed())
+		tep.Status.Connection.Status = netv1alpha1.Connected
+		Expect(cl.Status().Update(ctx, tep)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		first, second = forgeTunnelEndpoint("tep-ipv4"), forgeTunnelEndpoint("tep-ipv6")
+		other := forgeTunnelEndpoint("tep-other")
+		other.Labels[liqoconst.ClusterIDLabelName] = "other-cluster-id"
+
+		scheme := runtime.NewScheme()
+		Expect(netv1alpha1.AddToScheme(scheme)).To(Succeed())
+		cl = fake.NewClientBuilder().WithScheme(scheme).WithObjects(first, second, other).Build()
+	})
+
+	When("the tunnels become ready at different times", func() {
+		It("should return once all of them are ready", func() {
+			go func() {
+				defer GinkgoRecover()
+				time.Sleep(100 * time.Millisecond)
+				setConnected(first)
+				time.Sleep(600 * time.Millisecond)
+				setConnected(second)
+			}()
+
+			start := time.Now()
+			notReady, err := liqonetutils.WaitForTunnelsReady(ctx, cl, clusterID, 5*time.Second)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(notReady).To(BeEmpty())
+			Expect(time.Since(start)).To(BeNumerically(">=", 700*time.Millisecond))
+		})
+	})
+
+	When("only one tunnel becomes ready before the timeout", func() {
+		It("should return the tunnel which is not ready", func() {
+			setConnected(first)
+			notReady, err := liqonetutils.WaitForTunnelsReady(ctx, cl, clusterID, 100*time.Millisecond)
+			Expect(err).To(MatchError(ContainSubstring("liqo-tenant/tep-ipv6")))
+			Expect(notReady).To(ConsistOf("liqo-tenant/tep-ipv6"))
+		})
+	})
+
+	When("no tunnel exists for the given cluster", func() {
+		It("should return an error once the timeout expires", func() {
+			notReady, err := liqonetutils.WaitForTunnelsReady(ctx, cl, "missing-cluster-id", 100*time.Millisecond)
+			Expect(err).To(HaveOccurred())
+			Expect(notReady).To(BeEmpty())
+		})
+	})
+})
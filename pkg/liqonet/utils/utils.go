@@ -40,7 +40,7 @@ var (
 
 // MapIPToNetwork creates a new IP address obtained by means of the old IP address and the new network.
 func MapIPToNetwork(newNetwork, oldIP string) (newIP string, err error) {
-	if newNetwork == consts.DefaultCIDRValue {
+	if IsNATDisabled(newNetwork) {
 		return oldIP, nil
 	}
 	// Parse newNetwork
@@ -128,7 +128,7 @@ func Next(network string) string {
 // GetPodCIDRS for a given tep the function retrieves the values for localPodCIDR and remotePodCIDR.
 // Their values depend if the NAT is required or not.
 func GetPodCIDRS(tep *netv1alpha1.TunnelEndpoint) (localRemappedPodCIDR, remotePodCIDR string) {
-	if tep.Spec.RemoteNATPodCIDR != consts.DefaultCIDRValue {
+	if !IsNATDisabled(tep.Spec.RemoteNATPodCIDR) {
 		remotePodCIDR = tep.Spec.RemoteNATPodCIDR
 	} else {
 		remotePodCIDR = tep.Spec.RemotePodCIDR
@@ -140,12 +140,12 @@ func GetPodCIDRS(tep *netv1alpha1.TunnelEndpoint) (localRemappedPodCIDR, remoteP
 // GetExternalCIDRS for a given tep the function retrieves the values for localExternalCIDR and remoteExternalCIDR.
 // Their values depend if the NAT is required or not.
 func GetExternalCIDRS(tep *netv1alpha1.TunnelEndpoint) (localExternalCIDR, remoteExternalCIDR string) {
-	if tep.Spec.LocalNATExternalCIDR != consts.DefaultCIDRValue {
+	if !IsNATDisabled(tep.Spec.LocalNATExternalCIDR) {
 		localExternalCIDR = tep.Spec.LocalNATExternalCIDR
 	} else {
 		localExternalCIDR = tep.Spec.LocalExternalCIDR
 	}
-	if tep.Spec.RemoteNATExternalCIDR != consts.DefaultCIDRValue {
+	if !IsNATDisabled(tep.Spec.RemoteNATExternalCIDR) {
 		remoteExternalCIDR = tep.Spec.RemoteNATExternalCIDR
 	} else {
 		remoteExternalCIDR = tep.Spec.RemoteExternalCIDR
@@ -159,6 +159,27 @@ func IsValidCIDR(cidr string) error {
 	return err
 }
 
+// IsNATDisabled returns whether the given CIDR is the sentinel value (i.e., "None") signaling that
+// the corresponding network has not been remapped, and thus no NAT is required.
+func IsNATDisabled(cidr string) bool {
+	return cidr == consts.DefaultCIDRValue
+}
+
+// NormalizePodCIDR parses the given CIDR, returning it in canonical form (i.e., with the host bits zeroed)
+// and true in case it is a real network. False is returned in case the given CIDR is either the sentinel
+// value signaling that NAT is disabled, or it is not valid.
+func NormalizePodCIDR(cidr string) (string, bool) {
+	if IsNATDisabled(cidr) {
+		return "", false
+	}
+
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return "", false
+	}
+	return network.String(), true
+}
+
 // GetFirstIP returns the first IP address of a network.
 func GetFirstIP(network string) (string, error) {
 	firstIP, _, err := net.ParseCIDR(network)
@@ -209,28 +230,28 @@ func CheckTep(tep *netv1alpha1.TunnelEndpoint) error {
 			Reason:    liqoneterrors.ValidCIDR,
 		}
 	}
-	if err := IsValidCIDR(tep.Spec.LocalNATPodCIDR); tep.Spec.LocalNATPodCIDR != consts.DefaultCIDRValue &&
+	if err := IsValidCIDR(tep.Spec.LocalNATPodCIDR); !IsNATDisabled(tep.Spec.LocalNATPodCIDR) &&
 		err != nil {
 		return &liqoneterrors.WrongParameter{
 			Parameter: consts.LocalNATPodCIDR,
 			Reason:    liqoneterrors.ValidCIDR,
 		}
 	}
-	if err := IsValidCIDR(tep.Spec.LocalNATExternalCIDR); tep.Spec.LocalNATExternalCIDR != consts.DefaultCIDRValue &&
+	if err := IsValidCIDR(tep.Spec.LocalNATExternalCIDR); !IsNATDisabled(tep.Spec.LocalNATExternalCIDR) &&
 		err != nil {
 		return &liqoneterrors.WrongParameter{
 			Parameter: consts.LocalNATExternalCIDR,
 			Reason:    liqoneterrors.ValidCIDR,
 		}
 	}
-	if err := IsValidCIDR(tep.Spec.RemoteNATPodCIDR); tep.Spec.RemoteNATPodCIDR != consts.DefaultCIDRValue &&
+	if err := IsValidCIDR(tep.Spec.RemoteNATPodCIDR); !IsNATDisabled(tep.Spec.RemoteNATPodCIDR) &&
 		err != nil {
 		return &liqoneterrors.WrongParameter{
 			Parameter: consts.RemoteNATPodCIDR,
 			Reason:    liqoneterrors.ValidCIDR,
 		}
 	}
-	if err := IsValidCIDR(tep.Spec.RemoteNATExternalCIDR); tep.Spec.RemoteNATExternalCIDR != consts.DefaultCIDRValue &&
+	if err := IsValidCIDR(tep.Spec.RemoteNATExternalCIDR); !IsNATDisabled(tep.Spec.RemoteNATExternalCIDR) &&
 		err != nil {
 		return &liqoneterrors.WrongParameter{
 			Parameter: consts.RemoteNATExternalCIDR,
@@ -317,6 +338,41 @@ func SplitNetwork(network string) []string {
 	return halves
 }
 
+// CoveringNetwork returns the smallest network including all the given subnets, which must be contained in the
+// given network. The network itself is returned in case no subnets are specified.
+func CoveringNetwork(network string, subnets []string) (string, error) {
+	parent, err := netaddr.ParseIPPrefix(network)
+	if err != nil {
+		return "", err
+	}
+	parent = parent.Masked()
+	if len(subnets) == 0 {
+		return parent.String(), nil
+	}
+
+	var covering netaddr.IPPrefix
+	for i, subnet := range subnets {
+		prefix, err := netaddr.ParseIPPrefix(subnet)
+		if err != nil {
+			return "", err
+		}
+		prefix = prefix.Masked()
+		if !parent.Contains(prefix.IP()) || prefix.Bits() < parent.Bits() {
+			return "", fmt.Errorf("subnet %s is not contained in network %s", subnet, network)
+		}
+
+		if i == 0 {
+			covering = prefix
+			continue
+		}
+		// Enlarge the covering network until it includes the current subnet as well.
+		for !covering.Contains(prefix.IP()) || covering.Bits() > prefix.Bits() {
+			covering = netaddr.IPPrefixFrom(covering.IP(), covering.Bits()-1).Masked()
+		}
+	}
+	return covering.String(), nil
+}
+
 // FormatLatency returns a string representing the given latency in a human readable format.
 func FormatLatency(latency time.Duration) string {
 	if latency == 0 {
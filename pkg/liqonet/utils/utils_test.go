@@ -22,6 +22,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
+	"github.com/liqotech/liqo/pkg/consts"
 	liqonetutils "github.com/liqotech/liqo/pkg/liqonet/utils"
 )
 
@@ -72,6 +73,51 @@ var _ = Describe("Liqonet", func() {
 		Entry("Using an invalid oldIp", "10.0.0.0/25", "10.2...128", "", "cannot parse oldIP"),
 	)
 
+	DescribeTable("IsNATDisabled",
+		func(cidr string, expected bool) {
+			Expect(liqonetutils.IsNATDisabled(cidr)).To(Equal(expected))
+		},
+		Entry("The sentinel value", consts.DefaultCIDRValue, true),
+		Entry("A real CIDR", "10.0.0.0/16", false),
+		Entry("An empty value", "", false),
+	)
+
+	DescribeTable("NormalizePodCIDR",
+		func(cidr, expectedCIDR string, expectedOK bool) {
+			normalized, ok := liqonetutils.NormalizePodCIDR(cidr)
+			Expect(ok).To(Equal(expectedOK))
+			Expect(normalized).To(Equal(expectedCIDR))
+		},
+		Entry("The sentinel value", consts.DefaultCIDRValue, "", false),
+		Entry("A canonical CIDR", "10.0.0.0/16", "10.0.0.0/16", true),
+		Entry("A CIDR with the host bits set", "10.0.1.5/16", "10.0.0.0/16", true),
+		Entry("An IPv6 CIDR", "fd00::1/64", "fd00::/64", true),
+		Entry("An invalid value", invalidValue, "", false),
+		Entry("An empty value", "", "", false),
+	)
+
+	DescribeTable("CoveringNetwork",
+		func(network string, subnets []string, expectedCIDR string, expectedErr bool) {
+			covering, err := liqonetutils.CoveringNetwork(network, subnets)
+			if expectedErr {
+				Expect(err).To(HaveOccurred())
+				return
+			}
+			Expect(err).ToNot(HaveOccurred())
+			Expect(covering).To(Equal(expectedCIDR))
+		},
+		Entry("No subnets", "10.0.0.0/16", nil, "10.0.0.0/16", false),
+		Entry("A single subnet", "10.0.0.0/16", []string{"10.0.4.0/24"}, "10.0.4.0/24", false),
+		Entry("Two adjacent subnets", "10.0.0.0/16", []string{"10.0.4.0/24", "10.0.5.0/24"}, "10.0.4.0/23", false),
+		Entry("Two distant subnets", "10.0.0.0/16", []string{"10.0.1.0/24", "10.0.6.0/24"}, "10.0.0.0/21", false),
+		Entry("Nested subnets", "10.0.0.0/16", []string{"10.0.4.0/24", "10.0.4.0/22"}, "10.0.4.0/22", false),
+		Entry("A subnet with the host bits set", "10.0.0.0/16", []string{"10.0.4.5/24"}, "10.0.4.0/24", false),
+		Entry("A subnet outside the network", "10.0.0.0/16", []string{"10.1.0.0/24"}, "", true),
+		Entry("A subnet larger than the network", "10.0.0.0/16", []string{"10.0.0.0/8"}, "", true),
+		Entry("An invalid subnet", "10.0.0.0/16", []string{invalidValue}, "", true),
+		Entry("An invalid network", invalidValue, nil, "", true),
+	)
+
 	DescribeTable("GetFirstIP",
 		func(network, expectedIP string, expectedErr *net.ParseError) {
 			ip, err := liqonetutils.GetFirstIP(network)
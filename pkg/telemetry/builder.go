@@ -133,8 +133,8 @@ func (c *Builder) getPeeringInfo(ctx context.Context,
 	}
 
 	var latency time.Duration
-	tunnel, err := liqogetters.GetTunnelEndpoint(ctx, c.Client,
-		&foreignCluster.Spec.ClusterIdentity, foreignCluster.Status.TenantNamespace.Local)
+	// The TunnelEndpoint is looked up in all namespaces, as it may be hosted in a custom one, rather than in the tenant namespace.
+	tunnel, err := liqogetters.GetTunnelEndpoint(ctx, c.Client, &foreignCluster.Spec.ClusterIdentity, corev1.NamespaceAll)
 	switch {
 	case apierrors.IsNotFound(err):
 		// do nothing
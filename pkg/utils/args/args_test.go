@@ -208,6 +208,15 @@ var _ = Describe("ParseArguments", func() {
 			}),
 		)
 
+		It("should be stringified as the empty string, if not set", func() {
+			Expect((&CIDR{}).String()).To(BeEmpty())
+		})
+
+		It("should be stringified as the configured network, if set", func() {
+			cidr := CIDR{}
+			Expect(cidr.Set("10.0.0.0/16")).To(Succeed())
+			Expect(cidr.String()).To(Equal("10.0.0.0/16"))
+		})
 	})
 
 	Context("ClusterIdentity", func() {
@@ -22,8 +22,11 @@ type CIDR struct {
 	network net.IPNet
 }
 
-// String returns the stringified list.
+// String returns the stringified CIDR, or the empty string if not set.
 func (c *CIDR) String() string {
+	if c.network.IP == nil {
+		return ""
+	}
 	return c.network.String()
 }
 
@@ -0,0 +1,38 @@
+// Copyright 2019-2023 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package foreigncluster
+
+import (
+	discoveryv1alpha1 "github.com/liqotech/liqo/apis/discovery/v1alpha1"
+)
+
+// SetDesiredOutgoingPeering mutates the spec of the given ForeignCluster to enable (or disable) the outgoing peering
+// towards the remote cluster. The ForeignCluster is not updated, hence the modification needs to be persisted by the caller.
+func SetDesiredOutgoingPeering(foreignCluster *discoveryv1alpha1.ForeignCluster, enabled bool) {
+	foreignCluster.Spec.OutgoingPeeringEnabled = peeringEnabledType(enabled)
+}
+
+// SetDesiredIncomingPeering mutates the spec of the given ForeignCluster to allow (or forbid) the incoming peering
+// from the remote cluster. The ForeignCluster is not updated, hence the modification needs to be persisted by the caller.
+func SetDesiredIncomingPeering(foreignCluster *discoveryv1alpha1.ForeignCluster, enabled bool) {
+	foreignCluster.Spec.IncomingPeeringEnabled = peeringEnabledType(enabled)
+}
+
+func peeringEnabledType(enabled bool) discoveryv1alpha1.PeeringEnabledType {
+	if enabled {
+		return discoveryv1alpha1.PeeringEnabledYes
+	}
+	return discoveryv1alpha1.PeeringEnabledNo
+}
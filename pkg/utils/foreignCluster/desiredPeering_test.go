@@ -0,0 +1,55 @@
+// Copyright 2019-2023 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package foreigncluster
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	discoveryv1alpha1 "github.com/liqotech/liqo/apis/discovery/v1alpha1"
+)
+
+var _ = Describe("DesiredPeering", func() {
+	var foreignCluster *discoveryv1alpha1.ForeignCluster
+
+	BeforeEach(func() {
+		foreignCluster = &discoveryv1alpha1.ForeignCluster{
+			Spec: discoveryv1alpha1.ForeignClusterSpec{
+				OutgoingPeeringEnabled: discoveryv1alpha1.PeeringEnabledAuto,
+				IncomingPeeringEnabled: discoveryv1alpha1.PeeringEnabledAuto,
+			},
+		}
+	})
+
+	DescribeTable("SetDesiredOutgoingPeering func",
+		func(enabled bool, expected discoveryv1alpha1.PeeringEnabledType) {
+			SetDesiredOutgoingPeering(foreignCluster, enabled)
+			Expect(foreignCluster.Spec.OutgoingPeeringEnabled).To(Equal(expected))
+			Expect(foreignCluster.Spec.IncomingPeeringEnabled).To(Equal(discoveryv1alpha1.PeeringEnabledAuto))
+		},
+		Entry("enabling the outgoing peering", true, discoveryv1alpha1.PeeringEnabledYes),
+		Entry("disabling the outgoing peering", false, discoveryv1alpha1.PeeringEnabledNo),
+	)
+
+	DescribeTable("SetDesiredIncomingPeering func",
+		func(enabled bool, expected discoveryv1alpha1.PeeringEnabledType) {
+			SetDesiredIncomingPeering(foreignCluster, enabled)
+			Expect(foreignCluster.Spec.IncomingPeeringEnabled).To(Equal(expected))
+			Expect(foreignCluster.Spec.OutgoingPeeringEnabled).To(Equal(discoveryv1alpha1.PeeringEnabledAuto))
+		},
+		Entry("enabling the incoming peering", true, discoveryv1alpha1.PeeringEnabledYes),
+		Entry("disabling the incoming peering", false, discoveryv1alpha1.PeeringEnabledNo),
+	)
+})
@@ -36,7 +36,7 @@ import (
 )
 
 // GetForeignClusterByID returns a ForeignCluster CR retrieving it by its clusterID.
-func GetForeignClusterByID(ctx context.Context, cl client.Client, clusterID string) (*discoveryv1alpha1.ForeignCluster, error) {
+func GetForeignClusterByID(ctx context.Context, cl client.Reader, clusterID string) (*discoveryv1alpha1.ForeignCluster, error) {
 	lSelector := labels.SelectorFromSet(labels.Set{
 		discovery.ClusterIDLabel: clusterID,
 	})
@@ -16,12 +16,18 @@ package foreigncluster
 
 import (
 	"context"
+	"errors"
+	"sync"
 	"time"
 
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/util/wait"
+	toolscache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	discoveryv1alpha1 "github.com/liqotech/liqo/apis/discovery/v1alpha1"
+	"github.com/liqotech/liqo/pkg/discovery"
 )
 
 type fcEventChecker func(fc *discoveryv1alpha1.ForeignCluster) bool
@@ -31,6 +37,12 @@ var (
 	UnpeerChecker fcEventChecker = func(fc *discoveryv1alpha1.ForeignCluster) bool {
 		return IsIncomingPeeringNone(fc) && IsOutgoingPeeringNone(fc)
 	}
+	// PeeredChecker checks if at least one peering direction has been completely established.
+	PeeredChecker fcEventChecker = func(fc *discoveryv1alpha1.ForeignCluster) bool {
+		return IsIncomingJoined(fc) || IsOutgoingJoined(fc)
+	}
+	// AuthenticatedChecker checks if the identity has been accepted by the remote cluster.
+	AuthenticatedChecker fcEventChecker = IsAuthenticated
 )
 
 // PollForEvent polls until the given events occurs on the foreign cluster corresponding to the identity.
@@ -50,3 +62,170 @@ func PollForEvent(ctx context.Context, cl client.Client, identity *discoveryv1al
 	}
 	return nil
 }
+
+// errPollAborted is the reason of the abort in case none is specified by the caller.
+var errPollAborted = errors.New("no reason specified")
+
+// PollAbortedError is the error returned by PollForEventController.Poll in case the poll has been aborted.
+type PollAbortedError struct {
+	// Reason is the reason of the abort, as specified by the caller.
+	Reason error
+}
+
+// Error implements the error interface.
+func (e *PollAbortedError) Error() string {
+	return "poll aborted: " + e.Reason.Error()
+}
+
+// Unwrap returns the reason of the abort.
+func (e *PollAbortedError) Unwrap() error {
+	return e.Reason
+}
+
+// PollForEventController wraps PollForEvent, allowing to abort an in-progress poll from another goroutine
+// (e.g., in case of unrelated failures), independently of the context cancellation.
+type PollForEventController struct {
+	once    sync.Once
+	aborted chan struct{}
+	reason  error
+}
+
+// NewPollForEventController returns a new PollForEventController.
+func NewPollForEventController() *PollForEventController {
+	return &PollForEventController{aborted: make(chan struct{})}
+}
+
+// Abort aborts the in-progress poll (if any), which returns a PollAbortedError wrapping the given reason.
+// Subsequent polls return immediately, and only the first reason is retained in case of multiple invocations.
+func (c *PollForEventController) Abort(reason error) {
+	if reason == nil {
+		reason = errPollAborted
+	}
+	c.once.Do(func() {
+		c.reason = reason
+		close(c.aborted)
+	})
+}
+
+// Poll polls until the given events occurs on the foreign cluster corresponding to the identity (see PollForEvent).
+// It returns a PollAbortedError in case Abort is invoked before the event occurs.
+func (c *PollForEventController) Poll(ctx context.Context, cl client.Client, identity *discoveryv1alpha1.ClusterIdentity,
+	checker fcEventChecker, interval time.Duration) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	go func() {
+		select {
+		case <-c.aborted:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	if err := PollForEvent(ctx, cl, identity, checker, interval); err != nil {
+		select {
+		case <-c.aborted:
+			return &PollAbortedError{Reason: c.reason}
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+// fcEventDispatcher dispatches the events of a ForeignCluster informer to the in-progress WaitForEvent calls.
+// It is required since the event handlers cannot be removed from the informers once registered: a single
+// handler is hence registered for each informer, while the waiters are added and removed by each call.
+type fcEventDispatcher struct {
+	mutex   sync.RWMutex
+	nextID  uint64
+	waiters map[uint64]func(fc *discoveryv1alpha1.ForeignCluster)
+}
+
+var (
+	fcEventDispatchersMutex sync.Mutex
+	fcEventDispatchers      = map[cache.Informer]*fcEventDispatcher{}
+)
+
+// getFcEventDispatcher returns the dispatcher associated with the given informer, creating it if not yet present.
+func getFcEventDispatcher(informer cache.Informer) *fcEventDispatcher {
+	fcEventDispatchersMutex.Lock()
+	defer fcEventDispatchersMutex.Unlock()
+
+	if dispatcher, found := fcEventDispatchers[informer]; found {
+		return dispatcher
+	}
+
+	dispatcher := &fcEventDispatcher{waiters: map[uint64]func(fc *discoveryv1alpha1.ForeignCluster){}}
+	informer.AddEventHandler(toolscache.ResourceEventHandlerFuncs{
+		AddFunc:    dispatcher.dispatch,
+		UpdateFunc: func(_, newObj interface{}) { dispatcher.dispatch(newObj) },
+	})
+	fcEventDispatchers[informer] = dispatcher
+	return dispatcher
+}
+
+// dispatch forwards the given object to all the registered waiters.
+func (d *fcEventDispatcher) dispatch(obj interface{}) {
+	fc, ok := obj.(*discoveryv1alpha1.ForeignCluster)
+	if !ok {
+		return
+	}
+
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	for _, waiter := range d.waiters {
+		waiter(fc)
+	}
+}
+
+// register adds a new waiter, and returns the function to remove it.
+func (d *fcEventDispatcher) register(waiter func(fc *discoveryv1alpha1.ForeignCluster)) (unregister func()) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	id := d.nextID
+	d.nextID++
+	d.waiters[id] = waiter
+
+	return func() {
+		d.mutex.Lock()
+		defer d.mutex.Unlock()
+		delete(d.waiters, id)
+	}
+}
+
+// WaitForEvent waits until the given event occurs on the foreign cluster corresponding to the identity.
+// Differently from PollForEvent, it relies on the informer backing the given cache, hence avoiding
+// to repeatedly query the API server. The current status is checked once at startup through the cache.
+func WaitForEvent(ctx context.Context, c cache.Cache, identity *discoveryv1alpha1.ClusterIdentity, checker fcEventChecker) error {
+	informer, err := c.GetInformer(ctx, &discoveryv1alpha1.ForeignCluster{})
+	if err != nil {
+		return err
+	}
+
+	matched := make(chan struct{})
+	var once sync.Once
+	unregister := getFcEventDispatcher(informer).register(func(fc *discoveryv1alpha1.ForeignCluster) {
+		if fc.GetLabels()[discovery.ClusterIDLabel] == identity.ClusterID && checker(fc) {
+			once.Do(func() { close(matched) })
+		}
+	})
+	defer unregister()
+
+	// Check the current status, in case the event already occurred before registering the waiter.
+	fc, err := GetForeignClusterByID(ctx, c, identity.ClusterID)
+	switch {
+	case err == nil && checker(fc):
+		return nil
+	case err != nil && !kerrors.IsNotFound(err):
+		return err
+	}
+
+	select {
+	case <-matched:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,209 @@
+// Copyright 2019-2023 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package foreigncluster
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/cache/informertest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllertest"
+
+	discoveryv1alpha1 "github.com/liqotech/liqo/apis/discovery/v1alpha1"
+	"github.com/liqotech/liqo/pkg/discovery"
+)
+
+var _ = Describe("WaitForEvent", func() {
+	var (
+		ctx      context.Context
+		cancel   context.CancelFunc
+		informer *controllertest.FakeInformer
+		fakeInf  *informertest.FakeInformers
+		identity *discoveryv1alpha1.ClusterIdentity
+		done     chan error
+	)
+
+	foreignCluster := func(clusterID string, outgoing, incoming discoveryv1alpha1.PeeringConditionStatusType) *discoveryv1alpha1.ForeignCluster {
+		return &discoveryv1alpha1.ForeignCluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   clusterID,
+				Labels: map[string]string{discovery.ClusterIDLabel: clusterID},
+			},
+			Status: discoveryv1alpha1.ForeignClusterStatus{
+				PeeringConditions: []discoveryv1alpha1.PeeringCondition{
+					{Type: discoveryv1alpha1.OutgoingPeeringCondition, Status: outgoing},
+					{Type: discoveryv1alpha1.IncomingPeeringCondition, Status: incoming},
+				},
+			},
+		}
+	}
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithCancel(context.Background())
+		scheme := runtime.NewScheme()
+		Expect(discoveryv1alpha1.AddToScheme(scheme)).To(Succeed())
+		fakeInf = &informertest.FakeInformers{Scheme: scheme}
+
+		var err error
+		informer, err = fakeInf.FakeInformerFor(&discoveryv1alpha1.ForeignCluster{})
+		Expect(err).ToNot(HaveOccurred())
+		// Register the event handler in advance, since the fake informer is not safe for concurrent use.
+		getFcEventDispatcher(informer)
+
+		identity = &discoveryv1alpha1.ClusterIdentity{ClusterID: "remote-cluster-id", ClusterName: "remote-cluster-name"}
+		done = make(chan error, 1)
+		// The variables are captured, as the goroutine may outlive the current spec.
+		ctx, fakeInf, identity, done := ctx, fakeInf, identity, done
+		go func() { done <- WaitForEvent(ctx, fakeInf, identity, UnpeerChecker) }()
+	})
+
+	waiters := func() int {
+		dispatcher := getFcEventDispatcher(informer)
+		dispatcher.mutex.RLock()
+		defer dispatcher.mutex.RUnlock()
+		return len(dispatcher.waiters)
+	}
+
+	AfterEach(func() { cancel() })
+
+	Context("when no matching event is received", func() {
+		It("should not return", func() {
+			informer.Add(foreignCluster(identity.ClusterID, discoveryv1alpha1.PeeringConditionStatusEstablished,
+				discoveryv1alpha1.PeeringConditionStatusNone))
+			informer.Add(foreignCluster("other-cluster-id", discoveryv1alpha1.PeeringConditionStatusNone,
+				discoveryv1alpha1.PeeringConditionStatusNone))
+			Consistently(done, 200*time.Millisecond).ShouldNot(Receive())
+		})
+
+		It("should return an error when the context is canceled", func() {
+			cancel()
+			Eventually(done).Should(Receive(MatchError(context.Canceled)))
+		})
+
+		It("should unregister the waiter when returning", func() {
+			Eventually(waiters).Should(Equal(1))
+			cancel()
+			Eventually(done).Should(Receive())
+			Expect(waiters()).To(BeZero())
+		})
+	})
+
+	Context("when a matching event is received", func() {
+		It("should return without errors", func() {
+			old := foreignCluster(identity.ClusterID, discoveryv1alpha1.PeeringConditionStatusEstablished,
+				discoveryv1alpha1.PeeringConditionStatusNone)
+			updated := foreignCluster(identity.ClusterID, discoveryv1alpha1.PeeringConditionStatusNone,
+				discoveryv1alpha1.PeeringConditionStatusNone)
+
+			// The event is delivered repeatedly, since the waiter may not have been registered yet.
+			Eventually(func() bool {
+				informer.Update(old, updated)
+				select {
+				case err := <-done:
+					Expect(err).ToNot(HaveOccurred())
+					return true
+				default:
+					return false
+				}
+			}).Should(BeTrue())
+			Expect(waiters()).To(BeZero())
+		})
+	})
+})
+
+var _ = Describe("PollForEventController", func() {
+	var (
+		ctx        context.Context
+		cancel     context.CancelFunc
+		cl         client.Client
+		identity   *discoveryv1alpha1.ClusterIdentity
+		controller *PollForEventController
+		done       chan error
+	)
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithCancel(context.Background())
+		scheme := runtime.NewScheme()
+		Expect(discoveryv1alpha1.AddToScheme(scheme)).To(Succeed())
+
+		identity = &discoveryv1alpha1.ClusterIdentity{ClusterID: "remote-cluster-id", ClusterName: "remote-cluster-name"}
+		cl = fake.NewClientBuilder().WithScheme(scheme).WithObjects(&discoveryv1alpha1.ForeignCluster{
+			ObjectMeta: metav1.ObjectMeta{Name: identity.ClusterName, Labels: map[string]string{discovery.ClusterIDLabel: identity.ClusterID}},
+			Status: discoveryv1alpha1.ForeignClusterStatus{
+				PeeringConditions: []discoveryv1alpha1.PeeringCondition{
+					{Type: discoveryv1alpha1.OutgoingPeeringCondition, Status: discoveryv1alpha1.PeeringConditionStatusEstablished},
+				},
+			},
+		}).Build()
+
+		controller = NewPollForEventController()
+		done = make(chan error, 1)
+	})
+
+	JustBeforeEach(func() {
+		// The variables are captured, as the goroutine may outlive the current spec.
+		ctx, cl, identity, controller, done := ctx, cl, identity, controller, done
+		go func() { done <- controller.Poll(ctx, cl, identity, UnpeerChecker, time.Hour) }()
+	})
+
+	AfterEach(func() { cancel() })
+
+	Context("when the poll is not aborted", func() {
+		It("should not return", func() { Consistently(done, 200*time.Millisecond).ShouldNot(Receive()) })
+		It("should return a timeout error when the context is canceled", func() {
+			cancel()
+			Eventually(done).Should(Receive(MatchError(wait.ErrWaitTimeout)))
+		})
+	})
+
+	Context("when the poll is aborted", func() {
+		reason := errors.New("unrelated failure")
+
+		It("should promptly return the reason of the abort", func() {
+			Consistently(done, 100*time.Millisecond).ShouldNot(Receive())
+			controller.Abort(reason)
+
+			var err error
+			Eventually(done, time.Second).Should(Receive(&err))
+			Expect(err).To(MatchError(reason))
+			var aborted *PollAbortedError
+			Expect(errors.As(err, &aborted)).To(BeTrue())
+		})
+
+		It("should immediately return if aborted before starting", func() {
+			controller.Abort(reason)
+			Expect(controller.Poll(ctx, cl, identity, UnpeerChecker, time.Hour)).To(MatchError(reason))
+		})
+
+		It("should return a PollAbortedError if aborted without a reason", func() {
+			controller.Abort(nil)
+
+			var err error
+			Eventually(done, time.Second).Should(Receive(&err))
+			var aborted *PollAbortedError
+			Expect(errors.As(err, &aborted)).To(BeTrue())
+			Expect(err).To(MatchError(errPollAborted))
+			Expect(err.Error()).To(Equal("poll aborted: no reason specified"))
+		})
+	})
+})
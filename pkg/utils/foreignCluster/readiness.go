@@ -0,0 +1,145 @@
+// Copyright 2019-2023 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package foreigncluster
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	discoveryv1alpha1 "github.com/liqotech/liqo/apis/discovery/v1alpha1"
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+	"github.com/liqotech/liqo/pkg/utils/getters"
+	peeringconditionsutils "github.com/liqotech/liqo/pkg/utils/peeringConditions"
+)
+
+// SubsystemReadiness summarizes the readiness of one of the subsystems involved in a peering.
+type SubsystemReadiness struct {
+	// Required is whether the subsystem is required for the peering to be ready.
+	// For instance, the network subsystems are not required when the networking is handled out of band.
+	Required bool
+	// Ready is whether the subsystem is ready.
+	Ready bool
+	// Reason describes the status of the subsystem, in case it is not ready.
+	Reason string
+}
+
+// ReadinessSummary summarizes the readiness of a peering across all the required subsystems.
+type ReadinessSummary struct {
+	// Authentication refers to the acceptance of the local identity by the remote cluster.
+	Authentication SubsystemReadiness
+	// OutgoingPeering refers to the peering from the local to the remote cluster.
+	OutgoingPeering SubsystemReadiness
+	// IncomingPeering refers to the peering from the remote to the local cluster.
+	IncomingPeering SubsystemReadiness
+	// Network refers to the exchange of the network parameters between the two clusters.
+	Network SubsystemReadiness
+	// Tunnel refers to the establishment of the tunnel towards the remote cluster.
+	Tunnel SubsystemReadiness
+}
+
+// Ready returns whether all the required subsystems are ready.
+func (rs *ReadinessSummary) Ready() bool {
+	for _, subsystem := range rs.subsystems() {
+		if subsystem.Required && !subsystem.Ready {
+			return false
+		}
+	}
+	return true
+}
+
+// NotReady returns the names of the required subsystems which are not yet ready, along with the corresponding reasons.
+func (rs *ReadinessSummary) NotReady() map[string]string {
+	notReady := make(map[string]string)
+	for name, subsystem := range rs.subsystems() {
+		if subsystem.Required && !subsystem.Ready {
+			notReady[name] = subsystem.Reason
+		}
+	}
+	return notReady
+}
+
+func (rs *ReadinessSummary) subsystems() map[string]SubsystemReadiness {
+	return map[string]SubsystemReadiness{
+		"Authentication":  rs.Authentication,
+		"OutgoingPeering": rs.OutgoingPeering,
+		"IncomingPeering": rs.IncomingPeering,
+		"Network":         rs.Network,
+		"Tunnel":          rs.Tunnel,
+	}
+}
+
+// PeeringReadiness computes the readiness of the peering with the cluster corresponding to the given identity,
+// which is fully ready once the local identity is authenticated, the peering is established in both directions,
+// the network parameters have been exchanged and the tunnel has been connected (unless the networking is handled
+// out of band). It returns a per-subsystem summary, while an error is returned only if the status cannot be retrieved.
+func PeeringReadiness(ctx context.Context, cl client.Client, identity *discoveryv1alpha1.ClusterIdentity) (ReadinessSummary, error) {
+	fc, err := GetForeignClusterByID(ctx, cl, identity.ClusterID)
+	if err != nil {
+		return ReadinessSummary{}, err
+	}
+
+	networking := IsNetworkingEnabled(fc)
+	summary := ReadinessSummary{
+		Authentication:  conditionReadiness(fc, discoveryv1alpha1.AuthenticationStatusCondition, true),
+		OutgoingPeering: conditionReadiness(fc, discoveryv1alpha1.OutgoingPeeringCondition, true),
+		IncomingPeering: conditionReadiness(fc, discoveryv1alpha1.IncomingPeeringCondition, true),
+		Network:         conditionReadiness(fc, discoveryv1alpha1.NetworkStatusCondition, networking),
+		Tunnel:          SubsystemReadiness{Required: networking},
+	}
+
+	if !networking {
+		summary.Tunnel.Reason = "the networking is handled out of band"
+		return summary, nil
+	}
+
+	if fc.Status.TenantNamespace.Local == "" {
+		summary.Tunnel.Reason = "the tenant namespace has not yet been created"
+		return summary, nil
+	}
+
+	// The TunnelEndpoint is looked up in all namespaces, as it may be hosted in a custom one, rather than in the tenant namespace.
+	tep, err := getters.GetTunnelEndpoint(ctx, cl, identity, corev1.NamespaceAll)
+	switch {
+	case kerrors.IsNotFound(err):
+		summary.Tunnel.Reason = "the TunnelEndpoint has not yet been created"
+	case err != nil:
+		return ReadinessSummary{}, fmt.Errorf("failed to retrieve the TunnelEndpoint for cluster %q: %w", identity.ClusterName, err)
+	case tep.Status.Connection.Status != netv1alpha1.Connected:
+		summary.Tunnel.Reason = fmt.Sprintf("the tunnel is not connected (status: %q)", tep.Status.Connection.Status)
+	default:
+		summary.Tunnel.Ready = true
+	}
+
+	return summary, nil
+}
+
+// conditionReadiness returns the readiness of the subsystem corresponding to the given peering condition.
+func conditionReadiness(fc *discoveryv1alpha1.ForeignCluster, condition discoveryv1alpha1.PeeringConditionType,
+	required bool) SubsystemReadiness {
+	status := peeringconditionsutils.GetStatus(fc, condition)
+	if status == discoveryv1alpha1.PeeringConditionStatusEstablished {
+		return SubsystemReadiness{Required: required, Ready: true}
+	}
+
+	reason := fmt.Sprintf("the %s condition is %q", condition, status)
+	if message := peeringconditionsutils.GetMessage(fc, condition); message != "" {
+		reason = fmt.Sprintf("%s: %s", reason, message)
+	}
+	return SubsystemReadiness{Required: required, Reason: reason}
+}
@@ -0,0 +1,161 @@
+// Copyright 2019-2023 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package foreigncluster
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	discoveryv1alpha1 "github.com/liqotech/liqo/apis/discovery/v1alpha1"
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+	"github.com/liqotech/liqo/pkg/consts"
+	"github.com/liqotech/liqo/pkg/discovery"
+)
+
+var _ = Describe("PeeringReadiness", func() {
+	const (
+		clusterID       = "remote-cluster-id"
+		tenantNamespace = "liqo-tenant-remote"
+	)
+
+	var (
+		ctx      context.Context
+		identity *discoveryv1alpha1.ClusterIdentity
+		fc       *discoveryv1alpha1.ForeignCluster
+		tep      *netv1alpha1.TunnelEndpoint
+		summary  ReadinessSummary
+		err      error
+	)
+
+	forgeConditions := func(status discoveryv1alpha1.PeeringConditionStatusType) []discoveryv1alpha1.PeeringCondition {
+		var conditions []discoveryv1alpha1.PeeringCondition
+		for _, condition := range []discoveryv1alpha1.PeeringConditionType{
+			discoveryv1alpha1.AuthenticationStatusCondition, discoveryv1alpha1.OutgoingPeeringCondition,
+			discoveryv1alpha1.IncomingPeeringCondition, discoveryv1alpha1.NetworkStatusCondition,
+		} {
+			conditions = append(conditions, discoveryv1alpha1.PeeringCondition{Type: condition, Status: status})
+		}
+		return conditions
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		identity = &discoveryv1alpha1.ClusterIdentity{ClusterID: clusterID, ClusterName: "remote-cluster-name"}
+		fc = &discoveryv1alpha1.ForeignCluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "remote-cluster-name", Labels: map[string]string{discovery.ClusterIDLabel: clusterID}},
+			Spec:       discoveryv1alpha1.ForeignClusterSpec{PeeringType: discoveryv1alpha1.PeeringTypeOutOfBand},
+			Status: discoveryv1alpha1.ForeignClusterStatus{
+				TenantNamespace:   discoveryv1alpha1.TenantNamespaceType{Local: tenantNamespace},
+				PeeringConditions: forgeConditions(discoveryv1alpha1.PeeringConditionStatusEstablished),
+			},
+		}
+		tep = &netv1alpha1.TunnelEndpoint{
+			ObjectMeta: metav1.ObjectMeta{Name: "tep", Namespace: tenantNamespace, Labels: map[string]string{consts.ClusterIDLabelName: clusterID}},
+			Status:     netv1alpha1.TunnelEndpointStatus{Connection: netv1alpha1.Connection{Status: netv1alpha1.Connected}},
+		}
+	})
+
+	JustBeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(discoveryv1alpha1.AddToScheme(scheme)).To(Succeed())
+		Expect(netv1alpha1.AddToScheme(scheme)).To(Succeed())
+
+		objects := []client.Object{fc}
+		if tep != nil {
+			objects = append(objects, tep)
+		}
+		cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+		summary, err = PeeringReadiness(ctx, cl, identity)
+	})
+
+	When("all the subsystems are ready", func() {
+		It("should succeed", func() { Expect(err).ToNot(HaveOccurred()) })
+		It("should report the peering as ready", func() {
+			Expect(summary.Ready()).To(BeTrue())
+			Expect(summary.NotReady()).To(BeEmpty())
+		})
+	})
+
+	When("the incoming peering is still pending", func() {
+		BeforeEach(func() {
+			fc.Status.PeeringConditions[2].Status = discoveryv1alpha1.PeeringConditionStatusPending
+			fc.Status.PeeringConditions[2].Message = "waiting for the remote cluster"
+		})
+
+		It("should succeed", func() { Expect(err).ToNot(HaveOccurred()) })
+		It("should report the peering as not ready", func() { Expect(summary.Ready()).To(BeFalse()) })
+		It("should report the reason for the incoming peering only", func() {
+			Expect(summary.NotReady()).To(HaveLen(1))
+			Expect(summary.NotReady()).To(HaveKeyWithValue("IncomingPeering", ContainSubstring("waiting for the remote cluster")))
+			Expect(summary.OutgoingPeering.Ready).To(BeTrue())
+			Expect(summary.Tunnel.Ready).To(BeTrue())
+		})
+	})
+
+	When("the tunnel is not yet connected", func() {
+		BeforeEach(func() { tep.Status.Connection.Status = netv1alpha1.Connecting })
+
+		It("should succeed", func() { Expect(err).ToNot(HaveOccurred()) })
+		It("should report the peering as not ready", func() { Expect(summary.Ready()).To(BeFalse()) })
+		It("should report the reason for the tunnel", func() {
+			Expect(summary.NotReady()).To(ConsistOf(ContainSubstring(string(netv1alpha1.Connecting))))
+		})
+	})
+
+	When("the TunnelEndpoint is hosted in a custom namespace", func() {
+		BeforeEach(func() { tep.SetNamespace("liqo-tunnels") })
+
+		It("should succeed", func() { Expect(err).ToNot(HaveOccurred()) })
+		It("should report the peering as ready", func() { Expect(summary.Ready()).To(BeTrue()) })
+	})
+
+	When("the TunnelEndpoint does not exist", func() {
+		BeforeEach(func() { tep = nil })
+
+		It("should succeed", func() { Expect(err).ToNot(HaveOccurred()) })
+		It("should report the tunnel as not ready", func() {
+			Expect(summary.Ready()).To(BeFalse())
+			Expect(summary.Tunnel.Ready).To(BeFalse())
+			Expect(summary.Tunnel.Reason).To(ContainSubstring("not yet been created"))
+		})
+	})
+
+	When("the networking is handled out of band", func() {
+		BeforeEach(func() {
+			fc.Spec.PeeringType = discoveryv1alpha1.PeeringTypeInBand
+			fc.Status.PeeringConditions[3].Status = discoveryv1alpha1.PeeringConditionStatusNone
+			tep = nil
+		})
+
+		It("should succeed", func() { Expect(err).ToNot(HaveOccurred()) })
+		It("should not require the network subsystems", func() {
+			Expect(summary.Network.Required).To(BeFalse())
+			Expect(summary.Tunnel.Required).To(BeFalse())
+		})
+		It("should report the peering as ready", func() { Expect(summary.Ready()).To(BeTrue()) })
+	})
+
+	When("the ForeignCluster does not exist", func() {
+		BeforeEach(func() { identity.ClusterID = "another-cluster-id" })
+
+		It("should return an error", func() { Expect(err).To(HaveOccurred()) })
+	})
+})
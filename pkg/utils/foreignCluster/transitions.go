@@ -0,0 +1,102 @@
+// Copyright 2019-2023 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package foreigncluster
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	toolscache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+
+	discoveryv1alpha1 "github.com/liqotech/liqo/apis/discovery/v1alpha1"
+	"github.com/liqotech/liqo/pkg/discovery"
+)
+
+// PeeringTransition identifies a peering state transition of a ForeignCluster.
+type PeeringTransition string
+
+const (
+	// PeeredTransition is the transition towards an established peering (in at least one direction).
+	PeeredTransition PeeringTransition = "peered"
+	// UnpeeredTransition is the transition towards no active peering.
+	UnpeeredTransition PeeringTransition = "unpeered"
+	// AuthenticatedTransition is the transition towards an established authentication.
+	AuthenticatedTransition PeeringTransition = "authenticated"
+)
+
+// transitionCheckers associates each transition with the checker detecting the corresponding state.
+var transitionCheckers = map[PeeringTransition]fcEventChecker{
+	PeeredTransition:        PeeredChecker,
+	UnpeeredTransition:      UnpeerChecker,
+	AuthenticatedTransition: AuthenticatedChecker,
+}
+
+// PeeringTransitionWatcher is a prometheus.Collector counting the peering state transitions of the ForeignClusters.
+// A transition is detected when the corresponding checker is not satisfied by the old version of the object,
+// while it is satisfied by the new one.
+type PeeringTransitionWatcher struct {
+	transitions *prometheus.CounterVec
+}
+
+// NewPeeringTransitionWatcher returns a new PeeringTransitionWatcher.
+func NewPeeringTransitionWatcher() *PeeringTransitionWatcher {
+	return &PeeringTransitionWatcher{
+		transitions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "liqo_peering_transitions_total",
+			Help: "The number of peering state transitions observed for a given foreign cluster.",
+		}, []string{"cluster_id", "transition"}),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (ptw *PeeringTransitionWatcher) Describe(ch chan<- *prometheus.Desc) {
+	ptw.transitions.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (ptw *PeeringTransitionWatcher) Collect(ch chan<- prometheus.Metric) {
+	ptw.transitions.Collect(ch)
+}
+
+// Watch registers the watcher to the ForeignCluster informer backing the given cache.
+// Note: the event handler cannot be unregistered from the informer.
+func (ptw *PeeringTransitionWatcher) Watch(ctx context.Context, c cache.Cache) error {
+	informer, err := c.GetInformer(ctx, &discoveryv1alpha1.ForeignCluster{})
+	if err != nil {
+		return err
+	}
+
+	informer.AddEventHandler(toolscache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldFC, oldOk := oldObj.(*discoveryv1alpha1.ForeignCluster)
+			newFC, newOk := newObj.(*discoveryv1alpha1.ForeignCluster)
+			if oldOk && newOk {
+				ptw.Observe(oldFC, newFC)
+			}
+		},
+	})
+	return nil
+}
+
+// Observe compares the two versions of the given ForeignCluster, and increments the counters of the detected transitions.
+func (ptw *PeeringTransitionWatcher) Observe(oldFC, newFC *discoveryv1alpha1.ForeignCluster) {
+	clusterID := newFC.GetLabels()[discovery.ClusterIDLabel]
+	for transition, checker := range transitionCheckers {
+		if !checker(oldFC) && checker(newFC) {
+			ptw.transitions.WithLabelValues(clusterID, string(transition)).Inc()
+		}
+	}
+}
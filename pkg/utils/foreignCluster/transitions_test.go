@@ -0,0 +1,114 @@
+// Copyright 2019-2023 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package foreigncluster
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache/informertest"
+
+	discoveryv1alpha1 "github.com/liqotech/liqo/apis/discovery/v1alpha1"
+	"github.com/liqotech/liqo/pkg/discovery"
+)
+
+var _ = Describe("PeeringTransitionWatcher", func() {
+	const clusterID = "remote-cluster-id"
+
+	var watcher *PeeringTransitionWatcher
+
+	foreignCluster := func(auth, outgoing, incoming discoveryv1alpha1.PeeringConditionStatusType) *discoveryv1alpha1.ForeignCluster {
+		return &discoveryv1alpha1.ForeignCluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   clusterID,
+				Labels: map[string]string{discovery.ClusterIDLabel: clusterID},
+			},
+			Status: discoveryv1alpha1.ForeignClusterStatus{
+				PeeringConditions: []discoveryv1alpha1.PeeringCondition{
+					{Type: discoveryv1alpha1.AuthenticationStatusCondition, Status: auth},
+					{Type: discoveryv1alpha1.OutgoingPeeringCondition, Status: outgoing},
+					{Type: discoveryv1alpha1.IncomingPeeringCondition, Status: incoming},
+				},
+			},
+		}
+	}
+
+	transitions := func(transition PeeringTransition) float64 {
+		return testutil.ToFloat64(watcher.transitions.WithLabelValues(clusterID, string(transition)))
+	}
+
+	var (
+		unauthenticated = foreignCluster(discoveryv1alpha1.PeeringConditionStatusPending,
+			discoveryv1alpha1.PeeringConditionStatusNone, discoveryv1alpha1.PeeringConditionStatusNone)
+		authenticated = foreignCluster(discoveryv1alpha1.PeeringConditionStatusEstablished,
+			discoveryv1alpha1.PeeringConditionStatusNone, discoveryv1alpha1.PeeringConditionStatusNone)
+		pending = foreignCluster(discoveryv1alpha1.PeeringConditionStatusEstablished,
+			discoveryv1alpha1.PeeringConditionStatusPending, discoveryv1alpha1.PeeringConditionStatusNone)
+		peered = foreignCluster(discoveryv1alpha1.PeeringConditionStatusEstablished,
+			discoveryv1alpha1.PeeringConditionStatusEstablished, discoveryv1alpha1.PeeringConditionStatusNone)
+		bidirectional = foreignCluster(discoveryv1alpha1.PeeringConditionStatusEstablished,
+			discoveryv1alpha1.PeeringConditionStatusEstablished, discoveryv1alpha1.PeeringConditionStatusEstablished)
+	)
+
+	BeforeEach(func() { watcher = NewPeeringTransitionWatcher() })
+
+	Context("when observing a full peering lifecycle", func() {
+		It("should count each transition once", func() {
+			watcher.Observe(unauthenticated, authenticated)
+			watcher.Observe(authenticated, pending)
+			watcher.Observe(pending, peered)
+			watcher.Observe(peered, bidirectional)
+			watcher.Observe(bidirectional, authenticated)
+
+			Expect(transitions(AuthenticatedTransition)).To(BeNumerically("==", 1))
+			Expect(transitions(PeeredTransition)).To(BeNumerically("==", 1))
+			Expect(transitions(UnpeeredTransition)).To(BeNumerically("==", 1))
+		})
+	})
+
+	Context("when the state does not change", func() {
+		It("should not count any transition", func() {
+			watcher.Observe(peered, peered)
+			watcher.Observe(authenticated, authenticated)
+
+			Expect(transitions(AuthenticatedTransition)).To(BeZero())
+			Expect(transitions(PeeredTransition)).To(BeZero())
+			Expect(transitions(UnpeeredTransition)).To(BeZero())
+		})
+	})
+
+	Context("when watching the informer events", func() {
+		It("should count the transitions of the updated objects", func() {
+			scheme := runtime.NewScheme()
+			Expect(discoveryv1alpha1.AddToScheme(scheme)).To(Succeed())
+			fakeInf := &informertest.FakeInformers{Scheme: scheme}
+			informer, err := fakeInf.FakeInformerFor(&discoveryv1alpha1.ForeignCluster{})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(watcher.Watch(context.Background(), fakeInf)).To(Succeed())
+			informer.Add(authenticated)
+			informer.Update(authenticated, peered)
+			informer.Update(peered, authenticated)
+
+			Expect(transitions(AuthenticatedTransition)).To(BeZero())
+			Expect(transitions(PeeredTransition)).To(BeNumerically("==", 1))
+			Expect(transitions(UnpeeredTransition)).To(BeNumerically("==", 1))
+		})
+	})
+})
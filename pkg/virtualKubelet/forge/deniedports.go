@@ -0,0 +1,98 @@
+// Copyright 2019-2023 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package forge
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// DeniedPorts is the set of ports which are never exposed towards the remote cluster (e.g., SSH and management ones).
+// Keys are either in the "port/protocol" format, to deny the port only for the given protocol, or a plain port
+// number, to deny it regardless of the protocol.
+type DeniedPorts map[string]struct{}
+
+// ParseDeniedPorts parses a list of denied ports, each expressed as a "port[/protocol]" entry.
+func ParseDeniedPorts(entries []string) (DeniedPorts, error) {
+	denied := DeniedPorts{}
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		key, err := parsePortKey(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid denied port %q: %w", entry, err)
+		}
+		denied[key] = struct{}{}
+	}
+	return denied, nil
+}
+
+// Denied returns whether the given port is denied for the given protocol (which defaults to TCP if nil).
+func (dp DeniedPorts) Denied(port int32, protocol *corev1.Protocol) bool {
+	if len(dp) == 0 {
+		return false
+	}
+	if protocol == nil {
+		tcp := corev1.ProtocolTCP
+		protocol = &tcp
+	}
+
+	_, specific := dp[portMappingKey(port, protocol)]
+	_, generic := dp[portMappingKey(port, nil)]
+	return specific || generic
+}
+
+// AllowedServicePorts returns the given service ports, excluding those either exposing a denied port,
+// or targeting a denied (numeric) port. The original slice is returned unmodified if no port is denied.
+func AllowedServicePorts(ports []corev1.ServicePort, denied DeniedPorts) []corev1.ServicePort {
+	if len(denied) == 0 {
+		return ports
+	}
+
+	allowed := make([]corev1.ServicePort, 0, len(ports))
+	for i := range ports {
+		port := &ports[i]
+		if denied.Denied(port.Port, &port.Protocol) ||
+			(port.TargetPort.Type == intstr.Int && denied.Denied(port.TargetPort.IntVal, &port.Protocol)) {
+			continue
+		}
+		allowed = append(allowed, *port)
+	}
+	return allowed
+}
+
+// AllowedEndpointPorts returns the given endpoint ports, excluding the denied ones.
+// The original slice is returned unmodified if no port is denied.
+func AllowedEndpointPorts(ports []discoveryv1.EndpointPort, denied DeniedPorts) []discoveryv1.EndpointPort {
+	if len(denied) == 0 {
+		return ports
+	}
+
+	allowed := make([]discoveryv1.EndpointPort, 0, len(ports))
+	for i := range ports {
+		if ports[i].Port != nil && denied.Denied(*ports[i].Port, ports[i].Protocol) {
+			continue
+		}
+		allowed = append(allowed, ports[i])
+	}
+	return allowed
+}
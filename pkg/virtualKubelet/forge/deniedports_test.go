@@ -0,0 +1,99 @@
+// Copyright 2019-2023 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package forge_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/pointer"
+
+	"github.com/liqotech/liqo/pkg/virtualKubelet/forge"
+)
+
+var _ = Describe("Denied ports", func() {
+	Describe("the ParseDeniedPorts function", func() {
+		DescribeTable("parsing valid denied ports",
+			func(entries []string, expected forge.DeniedPorts) {
+				Expect(forge.ParseDeniedPorts(entries)).To(Equal(expected))
+			},
+			Entry("with no entries", nil, forge.DeniedPorts{}),
+			Entry("with a generic entry", []string{"22"}, forge.DeniedPorts{"22": {}}),
+			Entry("with a protocol-specific entry", []string{" 161/udp "}, forge.DeniedPorts{"161/UDP": {}}),
+		)
+
+		DescribeTable("parsing invalid denied ports",
+			func(entries []string) {
+				_, err := forge.ParseDeniedPorts(entries)
+				Expect(err).To(HaveOccurred())
+			},
+			Entry("with a non-numeric port", []string{"ssh"}),
+			Entry("with an out of range port", []string{"70000"}),
+			Entry("with an unsupported protocol", []string{"22/ICMP"}),
+		)
+	})
+
+	Describe("the Denied function", func() {
+		udp, sctp := corev1.ProtocolUDP, corev1.ProtocolSCTP
+		denied := forge.DeniedPorts{"22": {}, "161/UDP": {}}
+
+		DescribeTable("checking a port",
+			func(denied forge.DeniedPorts, port int32, protocol *corev1.Protocol, expected bool) {
+				Expect(denied.Denied(port, protocol)).To(Equal(expected))
+			},
+			Entry("with no denied ports", nil, int32(22), nil, false),
+			Entry("with an allowed port", denied, int32(80), nil, false),
+			Entry("with a generic entry and a nil protocol", denied, int32(22), nil, true),
+			Entry("with a generic entry and a different protocol", denied, int32(22), &sctp, true),
+			Entry("with a protocol-specific entry", denied, int32(161), &udp, true),
+			Entry("with a protocol-specific entry and a different protocol", denied, int32(161), nil, false),
+		)
+	})
+
+	Describe("the AllowedServicePorts function", func() {
+		ports := []corev1.ServicePort{
+			{Name: "http", Port: 80, TargetPort: intstr.FromInt(8080), Protocol: corev1.ProtocolTCP},
+			{Name: "ssh", Port: 2222, TargetPort: intstr.FromInt(22), Protocol: corev1.ProtocolTCP},
+			{Name: "named", Port: 22, TargetPort: intstr.FromString("ssh"), Protocol: corev1.ProtocolTCP},
+		}
+
+		It("should return the original ports if none is denied", func() {
+			Expect(forge.AllowedServicePorts(ports, nil)).To(Equal(ports))
+		})
+		It("should drop the ports either exposing or targeting a denied port", func() {
+			allowed := forge.AllowedServicePorts(ports, forge.DeniedPorts{"22": {}})
+			Expect(allowed).To(ConsistOf(ports[0]))
+		})
+	})
+
+	Describe("the AllowedEndpointPorts function", func() {
+		tcp := corev1.ProtocolTCP
+		ports := []discoveryv1.EndpointPort{
+			{Name: pointer.String("http"), Port: pointer.Int32(8080), Protocol: &tcp},
+			{Name: pointer.String("ssh"), Port: pointer.Int32(22), Protocol: &tcp},
+			{Name: pointer.String("unset")},
+		}
+
+		It("should return the original ports if none is denied", func() {
+			Expect(forge.AllowedEndpointPorts(ports, nil)).To(Equal(ports))
+		})
+		It("should drop the denied ports", func() {
+			allowed := forge.AllowedEndpointPorts(ports, forge.DeniedPorts{"22/TCP": {}})
+			Expect(allowed).To(ConsistOf(ports[0], ports[2]))
+		})
+	})
+})
@@ -15,6 +15,15 @@
 package forge
 
 import (
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
 	discoveryv1 "k8s.io/api/discovery/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
@@ -25,6 +34,18 @@ import (
 // EndpointSliceManagedBy -> The manager associated with the reflected EndpointSlices.
 const EndpointSliceManagedBy = "endpointslice.reflection.liqo.io"
 
+// EndpointWeightMax -> The endpoint weight corresponding to all endpoints being reflected.
+const EndpointWeightMax = 100
+
+const (
+	// EndpointSliceAggregatedLabel -> The label identifying the remote EndpointSlices aggregating the endpoints of multiple sources.
+	EndpointSliceAggregatedLabel = "endpointslice.reflection.liqo.io/aggregated"
+	// EndpointOriginClusterTopologyKey -> The topology key tagging the aggregated endpoints with the originating cluster.
+	EndpointOriginClusterTopologyKey = "endpointslice.reflection.liqo.io/origin-cluster-id"
+	// EndpointOriginSliceTopologyKey -> The topology key tagging the aggregated endpoints with the originating EndpointSlice.
+	EndpointOriginSliceTopologyKey = "endpointslice.reflection.liqo.io/origin-endpointslice"
+)
+
 // EndpointTranslator defines the function to translate between local and remote endpoint addresses.
 type EndpointTranslator func([]string) []string
 
@@ -43,21 +64,105 @@ func EndpointToBeReflected(endpoint *discoveryv1.Endpoint) bool {
 	return !pointer.StringEqual(endpoint.NodeName, &LiqoNodeName)
 }
 
+// EndpointSampled returns whether the endpoint is part of the sample reflected to the remote cluster, given the
+// weight (i.e., the percentage of endpoints to be reflected). The sampling is deterministic, and depends only on the
+// first address of the endpoint and on the remote cluster, hence it is stable across subsequent synchronizations.
+// Additionally, the sample corresponding to a given weight is a subset of those corresponding to higher weights.
+func EndpointSampled(endpoint *discoveryv1.Endpoint, weight uint) bool {
+	if weight >= EndpointWeightMax || len(endpoint.Addresses) == 0 {
+		return weight > 0
+	}
+
+	hash := fnv.New32a()
+	// Writing to a hash never returns an error.
+	_, _ = hash.Write([]byte(RemoteCluster.ClusterID + "/" + endpoint.Addresses[0]))
+	return hash.Sum32()%EndpointWeightMax < uint32(weight)
+}
+
+// EndpointReady returns whether the endpoint is ready. A nil condition is interpreted as ready, as per the API specification.
+func EndpointReady(endpoint *discoveryv1.Endpoint) bool {
+	return endpoint.Conditions.Ready == nil || *endpoint.Conditions.Ready
+}
+
+// ReadyEndpointsWithGrace filters out the endpoints not ready for at least the grace period, given the local ones.
+// Endpoints not ready for a shorter time are retained and marked as ready, to prevent flapping in case of transient
+// failures. The notReadySince map tracks the time each endpoint (identified by its first address) has been first
+// observed not ready, and it is updated accordingly. The returned duration, if not zero, is the interval after which
+// the endpoints shall be filtered again, since the grace period of a retained endpoint expires.
+func ReadyEndpointsWithGrace(locals []discoveryv1.Endpoint, notReadySince map[string]time.Time,
+	grace time.Duration, now time.Time) (endpoints []discoveryv1.Endpoint, recheck time.Duration) {
+	observed := make(map[string]bool, len(locals))
+
+	for i := range locals {
+		if EndpointReady(&locals[i]) || len(locals[i].Addresses) == 0 {
+			endpoints = append(endpoints, locals[i])
+			continue
+		}
+
+		key := locals[i].Addresses[0]
+		observed[key] = true
+		since, found := notReadySince[key]
+		if !found {
+			since = now
+			notReadySince[key] = since
+		}
+
+		if remaining := since.Add(grace).Sub(now); remaining > 0 {
+			// DeepCopy the local object, to avoid mutating the cache.
+			retained := locals[i].DeepCopy()
+			retained.Conditions.Ready = pointer.Bool(true)
+			endpoints = append(endpoints, *retained)
+			if recheck == 0 || remaining < recheck {
+				recheck = remaining
+			}
+		}
+	}
+
+	// Forget about the endpoints which are either ready again or vanished.
+	for key := range notReadySince {
+		if !observed[key] {
+			delete(notReadySince, key)
+		}
+	}
+
+	return endpoints, recheck
+}
+
 // RemoteEndpointSlice forges the apply patch for the reflected endpointslice, given the local one.
-func RemoteEndpointSlice(local *discoveryv1.EndpointSlice, targetNamespace string,
-	translator EndpointTranslator) *discoveryv1apply.EndpointSliceApplyConfiguration {
-	return discoveryv1apply.EndpointSlice(local.GetName(), targetNamespace).
-		WithLabels(local.GetLabels()).WithLabels(ReflectionLabels()).
+// The ports are translated according to the mapping configured for the corresponding service,
+// while only the sample of endpoints corresponding to the given weight is reflected.
+// The names of both the endpointslice and the associated service are transformed through the given NameMapper
+// (possibly nil), which shall match the one used to reflect the service, for the two to be correctly associated.
+func RemoteEndpointSlice(local *discoveryv1.EndpointSlice, targetNamespace string, translator EndpointTranslator,
+	mapping PortMapping, weight uint, mapper NameMapper) *discoveryv1apply.EndpointSliceApplyConfiguration {
+	name := mapper.RemoteName(local.GetName())
+	return discoveryv1apply.EndpointSlice(name, targetNamespace).
+		WithLabels(local.GetLabels()).WithLabels(RemoteServiceNameLabels(local, mapper)).WithLabels(ReflectionLabels()).
 		WithLabels(EndpointSliceLabels()).WithAnnotations(local.GetAnnotations()).
+		WithAnnotations(SourceNameAnnotations(local.GetName(), name)).
 		WithAddressType(local.AddressType).
-		WithEndpoints(RemoteEndpointSliceEndpoints(local.Endpoints, translator)...).
-		WithPorts(RemoteEndpointSlicePorts(local.Ports)...)
+		WithEndpoints(RemoteEndpointSliceEndpoints(local.Endpoints, translator, weight)...).
+		WithPorts(RemoteEndpointSlicePorts(local.Ports, mapping)...)
+}
+
+// RemoteServiceNameLabels returns the label associating the reflected endpointslice with the corresponding remote
+// service, whose name is transformed through the given NameMapper (possibly nil).
+func RemoteServiceNameLabels(local *discoveryv1.EndpointSlice, mapper NameMapper) map[string]string {
+	service, found := local.GetLabels()[discoveryv1.LabelServiceName]
+	if !found {
+		return nil
+	}
+	return map[string]string{discoveryv1.LabelServiceName: mapper.RemoteName(service)}
 }
 
 // RemoteEndpointSliceEndpoints forges the apply patch for the endpoints of the reflected endpointslice, given the local ones.
+// Addresses are de-duplicated across endpoints (e.g., in case multiple local addresses are translated to the same remote one),
+// keeping only the first occurrence, as some kube-proxy implementations handle duplicates poorly. Endpoints left without
+// any address are dropped.
 func RemoteEndpointSliceEndpoints(locals []discoveryv1.Endpoint,
-	translator EndpointTranslator) []*discoveryv1apply.EndpointApplyConfiguration {
+	translator EndpointTranslator, weight uint) []*discoveryv1apply.EndpointApplyConfiguration {
 	var remotes []*discoveryv1apply.EndpointApplyConfiguration
+	seen := make(map[string]struct{})
 
 	for i := range locals {
 		if !EndpointToBeReflected(&locals[i]) {
@@ -65,11 +170,21 @@ func RemoteEndpointSliceEndpoints(locals []discoveryv1.Endpoint,
 			continue
 		}
 
+		if !EndpointSampled(&locals[i], weight) {
+			// Skip the endpoints not part of the sample to be reflected.
+			continue
+		}
+
 		local := locals[i].DeepCopy()
+		addresses := DeduplicatedAddresses(translator(local.Addresses), seen)
+		if len(addresses) == 0 {
+			// Skip the endpoints whose addresses are all already exposed by previous ones.
+			continue
+		}
 		conditions := &discoveryv1apply.EndpointConditionsApplyConfiguration{Ready: local.Conditions.Ready}
 
 		remote := discoveryv1apply.Endpoint().
-			WithAddresses(translator(local.Addresses)...).WithConditions(conditions).
+			WithAddresses(SortedAddresses(addresses)...).WithConditions(conditions).
 			WithNodeName(LocalCluster.ClusterName).WithHints(RemoteEndpointHints(local.Hints)).
 			WithTargetRef(RemoteObjectReference(local.TargetRef))
 		remote.Hostname = local.Hostname
@@ -78,16 +193,71 @@ func RemoteEndpointSliceEndpoints(locals []discoveryv1.Endpoint,
 		remotes = append(remotes, remote)
 	}
 
+	// Sort the endpoints by address, so that the outcome does not depend on the order of the local ones,
+	// which would otherwise cause needless updates of the remote object.
+	sort.SliceStable(remotes, func(i, j int) bool {
+		return compareAddressLists(remotes[i].Addresses, remotes[j].Addresses) < 0
+	})
+
 	return remotes
 }
 
+// DeduplicatedAddresses returns the given addresses, except for those already seen (which is updated accordingly),
+// preserving the order of the first occurrences.
+func DeduplicatedAddresses(addresses []string, seen map[string]struct{}) []string {
+	var deduplicated []string
+	for _, address := range addresses {
+		if _, found := seen[address]; found {
+			continue
+		}
+		seen[address] = struct{}{}
+		deduplicated = append(deduplicated, address)
+	}
+	return deduplicated
+}
+
+// SortedAddresses returns a copy of the given addresses, sorted by IP (the invalid ones last, lexicographically).
+func SortedAddresses(addresses []string) []string {
+	sorted := append([]string(nil), addresses...)
+	sort.SliceStable(sorted, func(i, j int) bool { return compareAddresses(sorted[i], sorted[j]) < 0 })
+	return sorted
+}
+
+// compareAddresses compares two addresses by IP, with the invalid ones last and compared lexicographically.
+func compareAddresses(first, second string) int {
+	firstIP, secondIP := net.ParseIP(first), net.ParseIP(second)
+	switch {
+	case firstIP != nil && secondIP != nil:
+		return bytes.Compare(firstIP.To16(), secondIP.To16())
+	case firstIP != nil:
+		return -1
+	case secondIP != nil:
+		return 1
+	default:
+		return strings.Compare(first, second)
+	}
+}
+
+// compareAddressLists compares two lists of addresses element-wise, with the shorter one first in case of common prefix.
+func compareAddressLists(first, second []string) int {
+	for i := 0; i < len(first) && i < len(second); i++ {
+		if cmp := compareAddresses(first[i], second[i]); cmp != 0 {
+			return cmp
+		}
+	}
+	return len(first) - len(second)
+}
+
 // RemoteEndpointSlicePorts forges the apply patch for the ports of the reflected endpointslice, given the local ones.
-func RemoteEndpointSlicePorts(locals []discoveryv1.EndpointPort) []*discoveryv1apply.EndpointPortApplyConfiguration {
+func RemoteEndpointSlicePorts(locals []discoveryv1.EndpointPort, mapping PortMapping) []*discoveryv1apply.EndpointPortApplyConfiguration {
 	var remotes []*discoveryv1apply.EndpointPortApplyConfiguration
 
 	for i := range locals {
 		// DeepCopy the local object, to avoid mutating the cache.
 		local := locals[i].DeepCopy()
+		if local.Port != nil {
+			local.Port = pointer.Int32(mapping.Remap(*local.Port, local.Protocol))
+		}
 		remotes = append(remotes, &discoveryv1apply.EndpointPortApplyConfiguration{
 			Name: local.Name, Port: local.Port, Protocol: local.Protocol, AppProtocol: local.AppProtocol,
 		})
@@ -108,3 +278,144 @@ func RemoteEndpointHints(local *discoveryv1.EndpointHints) *discoveryv1apply.End
 	}
 	return hints
 }
+
+// EndpointSliceAggregatedLabels returns the labels assigned to the remote EndpointSlices aggregating multiple sources.
+func EndpointSliceAggregatedLabels() labels.Set {
+	return map[string]string{EndpointSliceAggregatedLabel: "true"}
+}
+
+// IsEndpointSliceAggregated returns whether the EndpointSlice aggregates the endpoints of multiple sources.
+func IsEndpointSliceAggregated(obj metav1.Object) bool {
+	return EndpointSliceAggregatedLabels().AsSelectorPreValidated().Matches(labels.Set(obj.GetLabels()))
+}
+
+// AggregatedEndpointSliceName returns the name of the remote EndpointSlice aggregating the endpoints
+// of the given service and address type, independently of the originating cluster.
+func AggregatedEndpointSliceName(service string, addressType discoveryv1.AddressType) string {
+	return fmt.Sprintf("%s-liqo-%s", service, strings.ToLower(string(addressType)))
+}
+
+// AggregatedRemoteEndpointSlice forges the remote EndpointSlice aggregating the endpoints of the local one with those
+// contributed by other sources (i.e., other local EndpointSlices, or other clusters sharing the same remote namespace),
+// given the current remote object (nil if it does not yet exist). The name of the service is transformed through the
+// given NameMapper (possibly nil). The endpoints are tagged with their origin, to
+// replace only those previously contributed by the same source, and duplicated addresses are not added again.
+// The ports are overwritten with those of the local EndpointSlice, as all sources refer to the same service.
+func AggregatedRemoteEndpointSlice(remote, local *discoveryv1.EndpointSlice, targetNamespace string,
+	translator EndpointTranslator, mapping PortMapping, weight uint, mapper NameMapper) *discoveryv1.EndpointSlice {
+	service := mapper.RemoteName(local.GetLabels()[discoveryv1.LabelServiceName])
+
+	aggregated := &discoveryv1.EndpointSlice{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      AggregatedEndpointSliceName(service, local.AddressType),
+			Namespace: targetNamespace,
+		},
+		AddressType: local.AddressType,
+	}
+	if remote != nil {
+		// DeepCopy the remote object, to avoid mutating the cache.
+		aggregated = remote.DeepCopy()
+	}
+
+	aggregated.SetLabels(labels.Merge(aggregated.GetLabels(), labels.Set{discoveryv1.LabelServiceName: service}))
+	aggregated.SetLabels(labels.Merge(aggregated.GetLabels(), ReflectionLabels()))
+	aggregated.SetLabels(labels.Merge(aggregated.GetLabels(), EndpointSliceLabels()))
+	aggregated.SetLabels(labels.Merge(aggregated.GetLabels(), EndpointSliceAggregatedLabels()))
+
+	var contributed []discoveryv1.Endpoint
+	for _, endpoint := range RemoteEndpointSliceEndpoints(local.Endpoints, translator, weight) {
+		contributed = append(contributed, endpointFromApplyConfiguration(endpoint))
+	}
+	aggregated.Endpoints = AggregateEndpoints(aggregated.Endpoints, local.GetName(), contributed)
+
+	aggregated.Ports = nil
+	for _, port := range RemoteEndpointSlicePorts(local.Ports, mapping) {
+		aggregated.Ports = append(aggregated.Ports, discoveryv1.EndpointPort{
+			Name: port.Name, Port: port.Port, Protocol: port.Protocol, AppProtocol: port.AppProtocol,
+		})
+	}
+
+	return aggregated
+}
+
+// AggregateEndpoints merges the endpoints contributed by the given local EndpointSlice with the existing ones.
+// The existing endpoints previously contributed by the same source are replaced, while the contributed endpoints
+// are tagged with their origin, and discarded if any of their addresses is already exposed by another endpoint.
+// A nil contribution withdraws all the endpoints previously contributed by the given source.
+func AggregateEndpoints(existing []discoveryv1.Endpoint, source string, contributed []discoveryv1.Endpoint) []discoveryv1.Endpoint {
+	var aggregated []discoveryv1.Endpoint
+	seen := make(map[string]struct{})
+
+	for i := range existing {
+		topology := existing[i].DeprecatedTopology
+		if topology[EndpointOriginClusterTopologyKey] == LocalCluster.ClusterID && topology[EndpointOriginSliceTopologyKey] == source {
+			// Skip the endpoints previously contributed by the same source, as replaced by the current ones.
+			continue
+		}
+
+		aggregated = append(aggregated, existing[i])
+		for _, address := range existing[i].Addresses {
+			seen[address] = struct{}{}
+		}
+	}
+
+	for i := range contributed {
+		duplicated := false
+		for _, address := range contributed[i].Addresses {
+			if _, found := seen[address]; found {
+				duplicated = true
+				break
+			}
+		}
+		if duplicated {
+			continue
+		}
+
+		endpoint := contributed[i].DeepCopy()
+		endpoint.DeprecatedTopology = labels.Merge(endpoint.DeprecatedTopology, map[string]string{
+			EndpointOriginClusterTopologyKey: LocalCluster.ClusterID,
+			EndpointOriginSliceTopologyKey:   source,
+		})
+		aggregated = append(aggregated, *endpoint)
+		for _, address := range endpoint.Addresses {
+			seen[address] = struct{}{}
+		}
+	}
+
+	return aggregated
+}
+
+// endpointFromApplyConfiguration converts the apply patch of an endpoint into the corresponding object.
+func endpointFromApplyConfiguration(apply *discoveryv1apply.EndpointApplyConfiguration) discoveryv1.Endpoint {
+	endpoint := discoveryv1.Endpoint{
+		Addresses: apply.Addresses, Hostname: apply.Hostname, NodeName: apply.NodeName,
+		Zone: apply.Zone, DeprecatedTopology: apply.DeprecatedTopology,
+	}
+
+	if apply.Conditions != nil {
+		endpoint.Conditions = discoveryv1.EndpointConditions{
+			Ready: apply.Conditions.Ready, Serving: apply.Conditions.Serving, Terminating: apply.Conditions.Terminating,
+		}
+	}
+
+	if apply.TargetRef != nil {
+		ref := apply.TargetRef
+		endpoint.TargetRef = &corev1.ObjectReference{
+			APIVersion: pointer.StringDeref(ref.APIVersion, ""), FieldPath: pointer.StringDeref(ref.FieldPath, ""),
+			Kind: pointer.StringDeref(ref.Kind, ""), Name: pointer.StringDeref(ref.Name, ""),
+			Namespace: pointer.StringDeref(ref.Namespace, ""), ResourceVersion: pointer.StringDeref(ref.ResourceVersion, ""),
+		}
+		if ref.UID != nil {
+			endpoint.TargetRef.UID = *ref.UID
+		}
+	}
+
+	if apply.Hints != nil {
+		endpoint.Hints = &discoveryv1.EndpointHints{}
+		for _, zone := range apply.Hints.ForZones {
+			endpoint.Hints.ForZones = append(endpoint.Hints.ForZones, discoveryv1.ForZone{Name: pointer.StringDeref(zone.Name, "")})
+		}
+	}
+
+	return endpoint
+}
@@ -15,15 +15,20 @@
 package forge_test
 
 import (
+	"fmt"
+	"time"
+
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	. "github.com/onsi/gomega/gstruct"
 	corev1 "k8s.io/api/core/v1"
 	discoveryv1 "k8s.io/api/discovery/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1apply "k8s.io/client-go/applyconfigurations/core/v1"
 	discoveryv1apply "k8s.io/client-go/applyconfigurations/discovery/v1"
 	"k8s.io/utils/pointer"
 
+	liqoconst "github.com/liqotech/liqo/pkg/consts"
 	"github.com/liqotech/liqo/pkg/virtualKubelet/forge"
 )
 
@@ -53,7 +58,9 @@ var _ = Describe("EndpointSlices Forging", func() {
 				Ports:       []discoveryv1.EndpointPort{{Name: pointer.String("HTTPS")}},
 			}
 
-			JustBeforeEach(func() { output = forge.RemoteEndpointSlice(input, "reflected", Translator) })
+			JustBeforeEach(func() {
+				output = forge.RemoteEndpointSlice(input, "reflected", Translator, nil, forge.EndpointWeightMax, nil)
+			})
 
 			It("should correctly set the name and namespace", func() {
 				Expect(output.Name).To(PointTo(Equal("name")))
@@ -104,7 +111,10 @@ var _ = Describe("EndpointSlices Forging", func() {
 			}
 		})
 
-		JustBeforeEach(func() { output = forge.RemoteEndpointSliceEndpoints(input, Translator) })
+		var weight uint
+
+		BeforeEach(func() { weight = forge.EndpointWeightMax })
+		JustBeforeEach(func() { output = forge.RemoteEndpointSliceEndpoints(input, Translator, weight) })
 
 		When("translating a single endpoint", func() {
 			BeforeEach(func() { input = []discoveryv1.Endpoint{endpoint} })
@@ -142,19 +152,226 @@ var _ = Describe("EndpointSlices Forging", func() {
 		})
 
 		When("translating multiple endpoints", func() {
-			BeforeEach(func() { input = []discoveryv1.Endpoint{endpoint, endpoint, endpoint} })
+			BeforeEach(func() {
+				second, third := *endpoint.DeepCopy(), *endpoint.DeepCopy()
+				second.Addresses = []string{"third"}
+				third.Addresses = []string{"fourth", "fifth"}
+				input = []discoveryv1.Endpoint{endpoint, second, third}
+			})
 			It("should return the correct number of endpoints", func() { Expect(output).To(HaveLen(3)) })
 		})
+
+		When("translating endpoints with duplicate addresses", func() {
+			BeforeEach(func() {
+				duplicate, partial, other := *endpoint.DeepCopy(), *endpoint.DeepCopy(), *endpoint.DeepCopy()
+				duplicate.Hostname = pointer.String("duplicate")
+				partial.Addresses = []string{"second", "third", "third"}
+				partial.Hostname = pointer.String("partial")
+				other.Addresses = []string{"fourth"}
+				input = []discoveryv1.Endpoint{endpoint, duplicate, partial, other}
+			})
+
+			It("should drop the endpoints whose addresses are all duplicated", func() { Expect(output).To(HaveLen(3)) })
+			It("should keep the first occurrence of each address", func() {
+				var addresses []string
+				for _, ep := range output {
+					addresses = append(addresses, ep.Addresses...)
+				}
+				Expect(addresses).To(ConsistOf("first-reflected", "second-reflected", "third-reflected", "fourth-reflected"))
+				Expect(output).To(ContainElement(And(
+					HaveField("Hostname", PointTo(Equal("foo.bar.com"))),
+					HaveField("Addresses", ConsistOf("first-reflected", "second-reflected")))))
+				Expect(output).To(ContainElement(And(
+					HaveField("Hostname", PointTo(Equal("partial"))),
+					HaveField("Addresses", ConsistOf("third-reflected")))))
+			})
+		})
+
+		When("multiple local addresses are translated to the same remote one", func() {
+			JustBeforeEach(func() {
+				collapsing := func([]string) []string { return []string{"collapsed"} }
+				output = forge.RemoteEndpointSliceEndpoints(input, collapsing, weight)
+			})
+
+			BeforeEach(func() { input = []discoveryv1.Endpoint{endpoint, endpoint} })
+			It("should expose the remote address only once", func() {
+				Expect(output).To(HaveLen(1))
+				Expect(output[0].Addresses).To(ConsistOf("collapsed"))
+			})
+		})
+
+		When("the weight is zero", func() {
+			BeforeEach(func() {
+				input = []discoveryv1.Endpoint{endpoint, endpoint, endpoint}
+				weight = 0
+			})
+			It("should return no endpoints", func() { Expect(output).To(HaveLen(0)) })
+		})
+
+		When("translating endpoints with unordered addresses", func() {
+			Identity := func(inputs []string) []string { return inputs }
+
+			var reordered []discoveryv1.Endpoint
+
+			addresses := func(endpoints []*discoveryv1apply.EndpointApplyConfiguration) (addrs [][]string) {
+				for _, ep := range endpoints {
+					addrs = append(addrs, ep.Addresses)
+				}
+				return addrs
+			}
+
+			BeforeEach(func() {
+				first, second, third := *endpoint.DeepCopy(), *endpoint.DeepCopy(), *endpoint.DeepCopy()
+				first.Addresses = []string{"10.0.0.10", "10.0.0.9"}
+				second.Addresses = []string{"10.0.0.2"}
+				third.Addresses = []string{"fd00::1", "10.0.1.1"}
+
+				input = []discoveryv1.Endpoint{first, second, third}
+				reordered = []discoveryv1.Endpoint{third, first, second}
+				for i := range reordered {
+					reordered[i].Addresses = append([]string(nil), reordered[i].Addresses...)
+					for j, k := 0, len(reordered[i].Addresses)-1; j < k; j, k = j+1, k-1 {
+						reordered[i].Addresses[j], reordered[i].Addresses[k] = reordered[i].Addresses[k], reordered[i].Addresses[j]
+					}
+				}
+			})
+
+			JustBeforeEach(func() { output = forge.RemoteEndpointSliceEndpoints(input, Identity, weight) })
+
+			It("should sort the addresses of each endpoint, and the endpoints, by IP", func() {
+				Expect(addresses(output)).To(Equal([][]string{
+					{"10.0.0.2"}, {"10.0.0.9", "10.0.0.10"}, {"10.0.1.1", "fd00::1"},
+				}))
+			})
+			It("should not depend on the order of the local endpoints and addresses", func() {
+				// Forging the same apply patch guarantees that no remote update is performed.
+				Expect(forge.RemoteEndpointSliceEndpoints(reordered, Identity, weight)).To(Equal(output))
+			})
+			It("should not mutate the local addresses", func() {
+				Expect(input[0].Addresses).To(Equal([]string{"10.0.0.10", "10.0.0.9"}))
+			})
+		})
+	})
+
+	Describe("the EndpointSampled function", func() {
+		const count = 1000
+
+		var endpoints []discoveryv1.Endpoint
+
+		// sample returns the indexes of the endpoints which are sampled with the given weight.
+		sample := func(weight uint) []int {
+			var sampled []int
+			for i := range endpoints {
+				if forge.EndpointSampled(&endpoints[i], weight) {
+					sampled = append(sampled, i)
+				}
+			}
+			return sampled
+		}
+
+		BeforeEach(func() {
+			endpoints = make([]discoveryv1.Endpoint, count)
+			for i := range endpoints {
+				endpoints[i].Addresses = []string{fmt.Sprintf("10.0.%d.%d", i/256, i%256)}
+			}
+		})
+
+		It("should sample roughly the fraction of endpoints corresponding to the weight", func() {
+			Expect(len(sample(30))).To(BeNumerically("~", 300, 50))
+			Expect(len(sample(75))).To(BeNumerically("~", 750, 50))
+		})
+		It("should sample all endpoints with the maximum weight", func() {
+			Expect(sample(forge.EndpointWeightMax)).To(HaveLen(count))
+		})
+		It("should sample no endpoints with a zero weight", func() {
+			Expect(sample(0)).To(BeEmpty())
+		})
+		It("should be deterministic", func() {
+			Expect(sample(50)).To(Equal(sample(50)))
+		})
+		It("should sample a subset of the endpoints sampled with a higher weight", func() {
+			Expect(sample(75)).To(ContainElements(sample(30)))
+		})
+	})
+
+	Describe("the ReadyEndpointsWithGrace function", func() {
+		const grace = time.Minute
+
+		var (
+			locals        []discoveryv1.Endpoint
+			notReadySince map[string]time.Time
+			start         time.Time
+		)
+
+		endpoint := func(address string, ready bool) discoveryv1.Endpoint {
+			return discoveryv1.Endpoint{Addresses: []string{address}, Conditions: discoveryv1.EndpointConditions{Ready: pointer.Bool(ready)}}
+		}
+
+		BeforeEach(func() {
+			locals = []discoveryv1.Endpoint{endpoint("10.0.0.1", true), endpoint("10.0.0.2", false)}
+			notReadySince = map[string]time.Time{}
+			start = time.Now()
+		})
+
+		When("an endpoint goes not ready", func() {
+			It("should be retained as ready until the grace period elapses", func() {
+				output, recheck := forge.ReadyEndpointsWithGrace(locals, notReadySince, grace, start)
+				Expect(output).To(HaveLen(2))
+				Expect(output[1].Conditions.Ready).To(PointTo(BeTrue()))
+				Expect(recheck).To(Equal(grace))
+				By("checking the local object has not been mutated")
+				Expect(locals[1].Conditions.Ready).To(PointTo(BeFalse()))
+
+				output, recheck = forge.ReadyEndpointsWithGrace(locals, notReadySince, grace, start.Add(grace/2))
+				Expect(output).To(HaveLen(2))
+				Expect(recheck).To(Equal(grace / 2))
+			})
+
+			It("should be removed once the grace period elapsed", func() {
+				forge.ReadyEndpointsWithGrace(locals, notReadySince, grace, start)
+				output, recheck := forge.ReadyEndpointsWithGrace(locals, notReadySince, grace, start.Add(grace))
+				Expect(output).To(ConsistOf(locals[0]))
+				Expect(recheck).To(BeZero())
+			})
+
+			It("should be forgotten once ready again", func() {
+				forge.ReadyEndpointsWithGrace(locals, notReadySince, grace, start)
+				locals[1] = endpoint("10.0.0.2", true)
+				output, _ := forge.ReadyEndpointsWithGrace(locals, notReadySince, grace, start.Add(grace))
+				Expect(output).To(HaveLen(2))
+				Expect(notReadySince).To(BeEmpty())
+			})
+		})
+
+		When("the grace period is zero", func() {
+			It("should immediately remove the not ready endpoints", func() {
+				output, recheck := forge.ReadyEndpointsWithGrace(locals, notReadySince, 0, start)
+				Expect(output).To(ConsistOf(locals[0]))
+				Expect(recheck).To(BeZero())
+			})
+		})
+
+		When("the readiness is unknown", func() {
+			BeforeEach(func() { locals[1].Conditions.Ready = nil })
+			It("should consider the endpoint as ready", func() {
+				output, _ := forge.ReadyEndpointsWithGrace(locals, notReadySince, 0, start)
+				Expect(output).To(HaveLen(2))
+			})
+		})
 	})
 
 	Describe("the RemoteEndpointSlicePorts function", func() {
 		var (
-			input  discoveryv1.EndpointPort
-			output []*discoveryv1apply.EndpointPortApplyConfiguration
+			input   discoveryv1.EndpointPort
+			output  []*discoveryv1apply.EndpointPortApplyConfiguration
+			mapping forge.PortMapping
 		)
 
-		BeforeEach(func() { input = discoveryv1.EndpointPort{} })
-		JustBeforeEach(func() { output = forge.RemoteEndpointSlicePorts([]discoveryv1.EndpointPort{input, input}) })
+		BeforeEach(func() {
+			input = discoveryv1.EndpointPort{}
+			mapping = nil
+		})
+		JustBeforeEach(func() { output = forge.RemoteEndpointSlicePorts([]discoveryv1.EndpointPort{input, input}, mapping) })
 
 		When("the ports are correctly initialized", func() {
 			BeforeEach(func() {
@@ -171,6 +388,12 @@ var _ = Describe("EndpointSlices Forging", func() {
 				Expect(output[0].Protocol).To(PointTo(Equal(corev1.ProtocolTCP)))
 				Expect(output[0].AppProtocol).To(PointTo(Equal("protocol")))
 			})
+
+			When("a port mapping matching the port is specified", func() {
+				BeforeEach(func() { mapping = forge.PortMapping{"443": 8443} })
+				It("should translate the port", func() { Expect(output[0].Port).To(PointTo(BeNumerically("==", 8443))) })
+				It("should not mutate the local object", func() { Expect(input.Port).To(PointTo(BeNumerically("==", 443))) })
+			})
 		})
 
 		When("the ports are not initialized", func() {
@@ -183,4 +406,158 @@ var _ = Describe("EndpointSlices Forging", func() {
 			})
 		})
 	})
+
+	Describe("the AggregatedRemoteEndpointSlice function", func() {
+		const otherClusterID = "other-cluster-id"
+
+		var (
+			first, second, third *discoveryv1.EndpointSlice
+			aggregated           *discoveryv1.EndpointSlice
+		)
+
+		LocalEndpointSlice := func(name string, addresses ...string) *discoveryv1.EndpointSlice {
+			slice := &discoveryv1.EndpointSlice{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: name, Namespace: "original",
+					Labels: map[string]string{discoveryv1.LabelServiceName: "service"},
+				},
+				AddressType: discoveryv1.AddressTypeIPv4,
+				Ports:       []discoveryv1.EndpointPort{{Name: pointer.String("http"), Port: pointer.Int32(80)}},
+			}
+			for _, address := range addresses {
+				slice.Endpoints = append(slice.Endpoints, discoveryv1.Endpoint{Addresses: []string{address}})
+			}
+			return slice
+		}
+
+		// AggregateFrom aggregates the given local EndpointSlice, pretending to be the given cluster.
+		AggregateFrom := func(clusterID string, remote, local *discoveryv1.EndpointSlice) *discoveryv1.EndpointSlice {
+			original := forge.LocalCluster
+			defer func() { forge.LocalCluster = original }()
+			forge.LocalCluster.ClusterID = clusterID
+			return forge.AggregatedRemoteEndpointSlice(remote, local, "reflected", Translator, nil, forge.EndpointWeightMax, nil)
+		}
+
+		Origins := func(slice *discoveryv1.EndpointSlice) (origins []string) {
+			for i := range slice.Endpoints {
+				topology := slice.Endpoints[i].DeprecatedTopology
+				origins = append(origins, fmt.Sprintf("%s/%s/%s", topology[forge.EndpointOriginClusterTopologyKey],
+					topology[forge.EndpointOriginSliceTopologyKey], slice.Endpoints[i].Addresses[0]))
+			}
+			return origins
+		}
+
+		BeforeEach(func() {
+			first = LocalEndpointSlice("first", "10.0.0.1", "10.0.0.2")
+			second = LocalEndpointSlice("second", "10.0.0.3", "10.0.0.2")
+			third = LocalEndpointSlice("first", "10.0.0.4")
+		})
+
+		When("aggregating the endpoints of two clusters", func() {
+			JustBeforeEach(func() {
+				aggregated = AggregateFrom(LocalClusterID, nil, first)
+				aggregated = AggregateFrom(otherClusterID, aggregated, second)
+			})
+
+			It("should forge a single remote EndpointSlice for the service", func() {
+				Expect(aggregated.GetName()).To(Equal("service-liqo-ipv4"))
+				Expect(aggregated.GetNamespace()).To(Equal("reflected"))
+				Expect(aggregated.AddressType).To(Equal(discoveryv1.AddressTypeIPv4))
+				Expect(aggregated.GetLabels()).To(HaveKeyWithValue(discoveryv1.LabelServiceName, "service"))
+				Expect(forge.IsEndpointSliceAggregated(aggregated)).To(BeTrue())
+				Expect(forge.IsEndpointSliceManagedByReflection(aggregated)).To(BeTrue())
+			})
+			It("should merge the translated endpoints, tagging them with their origin and discarding duplicates", func() {
+				Expect(Origins(aggregated)).To(ConsistOf(
+					LocalClusterID+"/first/10.0.0.1-reflected",
+					LocalClusterID+"/first/10.0.0.2-reflected",
+					otherClusterID+"/second/10.0.0.3-reflected",
+				))
+			})
+			It("should set the ports", func() {
+				Expect(aggregated.Ports).To(ConsistOf(discoveryv1.EndpointPort{Name: pointer.String("http"), Port: pointer.Int32(80)}))
+			})
+
+			When("a source updates its endpoints", func() {
+				JustBeforeEach(func() { aggregated = AggregateFrom(LocalClusterID, aggregated, third) })
+
+				It("should replace only the endpoints previously contributed by the same source", func() {
+					Expect(Origins(aggregated)).To(ConsistOf(
+						otherClusterID+"/second/10.0.0.3-reflected",
+						LocalClusterID+"/first/10.0.0.4-reflected",
+					))
+				})
+			})
+
+			When("a source withdraws its endpoints", func() {
+				It("should remove only the endpoints contributed by that source", func() {
+					forge.LocalCluster.ClusterID = otherClusterID
+					endpoints := forge.AggregateEndpoints(aggregated.Endpoints, "second", nil)
+					Expect(endpoints).To(HaveLen(2))
+					Expect(endpoints).To(HaveEach(HaveField("DeprecatedTopology",
+						HaveKeyWithValue(forge.EndpointOriginClusterTopologyKey, LocalClusterID))))
+				})
+			})
+
+			It("should not mutate the existing object", func() {
+				existing := aggregated.DeepCopy()
+				AggregateFrom(LocalClusterID, aggregated, third)
+				Expect(aggregated).To(Equal(existing))
+			})
+		})
+	})
+
+	Describe("the reflection with a NameMapper", func() {
+		var (
+			mapper   forge.NameMapper
+			service  *corev1apply.ServiceApplyConfiguration
+			slice    *discoveryv1apply.EndpointSliceApplyConfiguration
+			localSvc *corev1.Service
+			localEps *discoveryv1.EndpointSlice
+		)
+
+		BeforeEach(func() {
+			mapper = forge.PrefixNameMapper("staging-")
+			localSvc = &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "service", Namespace: "original"}}
+			localEps = &discoveryv1.EndpointSlice{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "service-abcde", Namespace: "original",
+					Labels: map[string]string{discoveryv1.LabelServiceName: "service"},
+				},
+				AddressType: discoveryv1.AddressTypeIPv4,
+			}
+		})
+
+		JustBeforeEach(func() {
+			service = forge.RemoteService(localSvc, "reflected", &forge.RemoteServiceOptions{NameMapper: mapper})
+			slice = forge.RemoteEndpointSlice(localEps, "reflected", Translator, nil, forge.EndpointWeightMax, mapper)
+		})
+
+		It("should transform the names of the service and the endpointslice", func() {
+			Expect(service.Name).To(PointTo(Equal("staging-service")))
+			Expect(slice.Name).To(PointTo(Equal("staging-service-abcde")))
+		})
+		It("should associate the endpointslice with the transformed service", func() {
+			Expect(slice.Labels).To(HaveKeyWithValue(discoveryv1.LabelServiceName, *service.Name))
+		})
+		It("should record the names of the local objects", func() {
+			Expect(service.Annotations).To(HaveKeyWithValue(liqoconst.SourceNameAnnotationKey, "service"))
+			Expect(slice.Annotations).To(HaveKeyWithValue(liqoconst.SourceNameAnnotationKey, "service-abcde"))
+			Expect(forge.SourceName(&metav1.ObjectMeta{Name: *service.Name, Annotations: service.Annotations})).To(Equal("service"))
+		})
+
+		When("the NameMapper is not set", func() {
+			BeforeEach(func() { mapper = nil })
+
+			It("should preserve the names", func() {
+				Expect(service.Name).To(PointTo(Equal("service")))
+				Expect(slice.Name).To(PointTo(Equal("service-abcde")))
+				Expect(slice.Labels).To(HaveKeyWithValue(discoveryv1.LabelServiceName, "service"))
+			})
+			It("should not record the names of the local objects", func() {
+				Expect(service.Annotations).ToNot(HaveKey(liqoconst.SourceNameAnnotationKey))
+				Expect(slice.Annotations).ToNot(HaveKey(liqoconst.SourceNameAnnotationKey))
+			})
+		})
+	})
 })
@@ -14,7 +14,11 @@
 
 package forge
 
-import "fmt"
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+)
 
 const (
 	// EventSuccessfulReflection -> the reason for the event when the reflection completes successfully.
@@ -87,6 +91,29 @@ func EventObjectReflectionDisabledMsg() string {
 	return fmt.Sprintf("Reflection to cluster %q disabled for the current object", RemoteCluster.ClusterName)
 }
 
+// EventReflectedServiceReflectionDisabledMsg returns the message for the event when reflection is disabled for
+// services originating from another cluster.
+func EventReflectedServiceReflectionDisabledMsg(origin string) string {
+	return fmt.Sprintf("Reflection to cluster %q disabled for services reflected from cluster %q", RemoteCluster.ClusterName, origin)
+}
+
+// EventNotSelectedReflectionDisabledMsg returns the message for the event when reflection is disabled for
+// an object not selected by the offloading intent.
+func EventNotSelectedReflectionDisabledMsg() string {
+	return fmt.Sprintf("Reflection to cluster %q disabled for the current object, as not selected for offloading", RemoteCluster.ClusterName)
+}
+
+// EventNotTargetedReflectionDisabledMsg returns the message for the event when reflection is disabled for
+// an object not targeting the remote cluster.
+func EventNotTargetedReflectionDisabledMsg() string {
+	return fmt.Sprintf("Reflection to cluster %q disabled for the current object, as not among its target clusters", RemoteCluster.ClusterName)
+}
+
+// EventServiceTypeReflectionDisabledMsg returns the message for the event when reflection is disabled for services of the given type.
+func EventServiceTypeReflectionDisabledMsg(svcType corev1.ServiceType) string {
+	return fmt.Sprintf("Reflection to cluster %q disabled for services of type %s", RemoteCluster.ClusterName, svcType)
+}
+
 // EventSAReflectionDisabledMsg returns the message for the event when service account reflection is disabled.
 func EventSAReflectionDisabledMsg() string {
 	return fmt.Sprintf("Reflection to cluster %q disabled for secrets holding service account tokens", RemoteCluster.ClusterName)
@@ -19,6 +19,9 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	corev1apply "k8s.io/client-go/applyconfigurations/core/v1"
+	"k8s.io/utils/strings/slices"
+
+	liqoconst "github.com/liqotech/liqo/pkg/consts"
 )
 
 const (
@@ -46,6 +49,48 @@ func IsReflected(obj metav1.Object) bool {
 	return ReflectedLabelSelector().Matches(labels.Set(obj.GetLabels()))
 }
 
+// NameMapper transforms the name of a local object into the name of the corresponding reflected one.
+type NameMapper func(local string) string
+
+// PrefixNameMapper returns a NameMapper prepending the given prefix to the name of the local objects.
+func PrefixNameMapper(prefix string) NameMapper {
+	return func(local string) string { return prefix + local }
+}
+
+// RemoteName returns the name of the reflected object, given the local one. A nil NameMapper preserves the name.
+func (nm NameMapper) RemoteName(local string) string {
+	if nm == nil {
+		return local
+	}
+	return nm(local)
+}
+
+// SourceNameAnnotations returns the annotations to record the name of the local object a reflected one originates from,
+// if it differs from the remote one.
+func SourceNameAnnotations(local, remote string) map[string]string {
+	if local == remote {
+		return nil
+	}
+	return map[string]string{liqoconst.SourceNameAnnotationKey: local}
+}
+
+// SourceName returns the name of the local object the given reflected one originates from.
+func SourceName(remote metav1.Object) string {
+	if name, found := remote.GetAnnotations()[liqoconst.SourceNameAnnotationKey]; found {
+		return name
+	}
+	return remote.GetName()
+}
+
+// IsNamespaceAllowed returns whether the objects in the given local namespace can be reflected towards the remote cluster,
+// according to the given allowlist. An empty allowlist allows every namespace.
+func IsNamespaceAllowed(namespace string, allowed []string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	return slices.Contains(allowed, namespace)
+}
+
 // RemoteObjectMeta forges the local ObjectMeta for a reflected object.
 func RemoteObjectMeta(local, remote *metav1.ObjectMeta) metav1.ObjectMeta {
 	output := remote.DeepCopy()
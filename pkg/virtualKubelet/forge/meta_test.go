@@ -70,6 +70,18 @@ var _ = Describe("Meta forging", func() {
 		}))
 	})
 
+	Describe("the IsNamespaceAllowed function", func() {
+		DescribeTable("checking whether the namespace is allowed",
+			func(namespace string, allowed []string, expected bool) {
+				Expect(forge.IsNamespaceAllowed(namespace, allowed)).To(Equal(expected))
+			},
+			Entry("nil allowlist", "foo", nil, true),
+			Entry("empty allowlist", "foo", []string{}, true),
+			Entry("namespace in the allowlist", "foo", []string{"bar", "foo"}, true),
+			Entry("namespace not in the allowlist", "baz", []string{"bar", "foo"}, false),
+		)
+	})
+
 	Describe("the RemoteObjectMeta function", func() {
 		var local, remote, original, output metav1.ObjectMeta
 
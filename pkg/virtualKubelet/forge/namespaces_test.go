@@ -0,0 +1,60 @@
+// Copyright 2019-2023 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package forge_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/liqotech/liqo/pkg/virtualKubelet/forge"
+)
+
+var _ = Describe("Namespaces Forging", func() {
+	Describe("the RemoteNamespace function", func() {
+		var output *corev1.Namespace
+
+		var extraLabels map[string]string
+
+		BeforeEach(func() { extraLabels = nil })
+		JustBeforeEach(func() { output = forge.RemoteNamespace("remote", extraLabels) })
+
+		It("should correctly set the name", func() { Expect(output.GetName()).To(Equal("remote")) })
+		It("should correctly set the reflection labels", func() {
+			Expect(output.GetLabels()).To(HaveKeyWithValue(forge.LiqoOriginClusterIDKey, LocalClusterID))
+			Expect(output.GetLabels()).To(HaveKeyWithValue(forge.LiqoDestinationClusterIDKey, RemoteClusterID))
+		})
+
+		When("extra labels are configured", func() {
+			BeforeEach(func() {
+				extraLabels = map[string]string{
+					"pod-security.kubernetes.io/enforce": "baseline",
+					forge.LiqoOriginClusterIDKey:         "overridden",
+				}
+			})
+
+			It("should set the extra labels", func() {
+				Expect(output.GetLabels()).To(HaveKeyWithValue("pod-security.kubernetes.io/enforce", "baseline"))
+			})
+			It("should not allow overriding the reflection labels", func() {
+				Expect(output.GetLabels()).To(HaveKeyWithValue(forge.LiqoOriginClusterIDKey, LocalClusterID))
+			})
+			It("should not mutate the extra labels", func() {
+				Expect(extraLabels).To(HaveKeyWithValue(forge.LiqoOriginClusterIDKey, "overridden"))
+				Expect(extraLabels).ToNot(HaveKey(forge.LiqoDestinationClusterIDKey))
+			})
+		})
+	})
+})
@@ -0,0 +1,126 @@
+// Copyright 2019-2023 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package forge
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	liqoconst "github.com/liqotech/liqo/pkg/consts"
+)
+
+// PortMapping associates the local target ports of a service with the ones to be used in the remote cluster.
+// Keys are either in the "port/protocol" format, to remap the port only for the given protocol, or a plain port
+// number, to remap it regardless of the protocol.
+type PortMapping map[string]int32
+
+// ParsePortMapping parses a port mapping, expressed as a comma-separated list of "local[/protocol]:remote" entries.
+func ParsePortMapping(value string) (PortMapping, error) {
+	mapping := PortMapping{}
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		local, remote, found := strings.Cut(entry, ":")
+		if !found {
+			return nil, fmt.Errorf("invalid port mapping entry %q: expected format local[/protocol]:remote", entry)
+		}
+
+		key, err := parsePortKey(local)
+		if err != nil {
+			return nil, fmt.Errorf("invalid port mapping entry %q: %w", entry, err)
+		}
+		rport, err := parsePort(remote)
+		if err != nil {
+			return nil, fmt.Errorf("invalid port mapping entry %q: %w", entry, err)
+		}
+
+		if _, ok := mapping[key]; ok {
+			return nil, fmt.Errorf("invalid port mapping entry %q: duplicated mapping for %s", entry, key)
+		}
+		mapping[key] = rport
+	}
+	return mapping, nil
+}
+
+// RemotePortMapping returns the port mapping configured for the given object through the corresponding annotation.
+func RemotePortMapping(obj metav1.Object) (PortMapping, error) {
+	value, ok := obj.GetAnnotations()[liqoconst.RemotePortMappingAnnotationKey]
+	if !ok {
+		return nil, nil
+	}
+	return ParsePortMapping(value)
+}
+
+// Remap returns the remote port corresponding to the given local one and protocol (which defaults to TCP if nil).
+// Protocol-specific mappings take precedence over generic ones, and unmapped ports are returned unchanged.
+func (pm PortMapping) Remap(port int32, protocol *corev1.Protocol) int32 {
+	if protocol == nil {
+		tcp := corev1.ProtocolTCP
+		protocol = &tcp
+	}
+
+	if remote, ok := pm[portMappingKey(port, protocol)]; ok {
+		return remote
+	}
+	if remote, ok := pm[portMappingKey(port, nil)]; ok {
+		return remote
+	}
+	return port
+}
+
+func portMappingKey(port int32, protocol *corev1.Protocol) string {
+	if protocol == nil {
+		return strconv.Itoa(int(port))
+	}
+	return fmt.Sprintf("%d/%s", port, *protocol)
+}
+
+// parsePortKey parses a "port[/protocol]" value, returning the corresponding key (see portMappingKey).
+func parsePortKey(value string) (string, error) {
+	value, protocol, hasProtocol := strings.Cut(value, "/")
+	port, err := parsePort(value)
+	if err != nil {
+		return "", err
+	}
+
+	if !hasProtocol {
+		return portMappingKey(port, nil), nil
+	}
+	switch proto := corev1.Protocol(strings.ToUpper(protocol)); proto {
+	case corev1.ProtocolTCP, corev1.ProtocolUDP, corev1.ProtocolSCTP:
+		return portMappingKey(port, &proto), nil
+	default:
+		return "", fmt.Errorf("unsupported protocol %q", protocol)
+	}
+}
+
+func parsePort(value string) (int32, error) {
+	port, err := strconv.ParseInt(strings.TrimSpace(value), 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid port %q: %w", value, err)
+	}
+	if errs := validation.IsValidPortNum(int(port)); len(errs) > 0 {
+		return 0, fmt.Errorf("invalid port %q: %s", value, strings.Join(errs, ", "))
+	}
+	return int32(port), nil
+}
@@ -0,0 +1,85 @@
+// Copyright 2019-2023 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package forge_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	liqoconst "github.com/liqotech/liqo/pkg/consts"
+	"github.com/liqotech/liqo/pkg/virtualKubelet/forge"
+)
+
+var _ = Describe("Port mapping", func() {
+	Describe("the ParsePortMapping function", func() {
+		DescribeTable("parsing valid port mappings",
+			func(value string, expected forge.PortMapping) {
+				Expect(forge.ParsePortMapping(value)).To(Equal(expected))
+			},
+			Entry("with an empty value", "", forge.PortMapping{}),
+			Entry("with a single entry", "8080:9090", forge.PortMapping{"8080": 9090}),
+			Entry("with a protocol-specific entry", "53/udp:5353", forge.PortMapping{"53/UDP": 5353}),
+			Entry("with multiple entries and spaces", "8080:9090, 53/UDP:5353 ,53/TCP:5354",
+				forge.PortMapping{"8080": 9090, "53/UDP": 5353, "53/TCP": 5354}),
+		)
+
+		DescribeTable("parsing invalid port mappings",
+			func(value string) {
+				_, err := forge.ParsePortMapping(value)
+				Expect(err).To(HaveOccurred())
+			},
+			Entry("with a missing separator", "8080"),
+			Entry("with a non-numeric port", "http:9090"),
+			Entry("with an out of range port", "8080:70000"),
+			Entry("with an unsupported protocol", "8080/ICMP:9090"),
+			Entry("with a duplicated entry", "8080:9090,8080:9091"),
+		)
+	})
+
+	Describe("the RemotePortMapping function", func() {
+		var svc corev1.Service
+
+		BeforeEach(func() { svc = corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "name", Namespace: "namespace"}} })
+
+		When("the annotation is not set", func() {
+			It("should return a nil mapping", func() { Expect(forge.RemotePortMapping(&svc)).To(BeNil()) })
+		})
+
+		When("the annotation is set", func() {
+			BeforeEach(func() { svc.Annotations = map[string]string{liqoconst.RemotePortMappingAnnotationKey: "8080:9090"} })
+			It("should return the corresponding mapping", func() {
+				Expect(forge.RemotePortMapping(&svc)).To(Equal(forge.PortMapping{"8080": 9090}))
+			})
+		})
+	})
+
+	Describe("the Remap function", func() {
+		mapping := forge.PortMapping{"53": 5353, "53/UDP": 5354}
+		udp, sctp := corev1.ProtocolUDP, corev1.ProtocolSCTP
+
+		DescribeTable("translating a port",
+			func(pm forge.PortMapping, port int32, protocol *corev1.Protocol, expected int32) {
+				Expect(pm.Remap(port, protocol)).To(BeNumerically("==", expected))
+			},
+			Entry("with a nil mapping", nil, int32(53), nil, int32(53)),
+			Entry("with an unmapped port", mapping, int32(80), nil, int32(80)),
+			Entry("with a generic mapping and a nil protocol", mapping, int32(53), nil, int32(5353)),
+			Entry("with a generic mapping and a different protocol", mapping, int32(53), &sctp, int32(5353)),
+			Entry("with a protocol-specific mapping", mapping, int32(53), &udp, int32(5354)),
+		)
+	})
+})
@@ -15,30 +15,178 @@
 package forge
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	corev1apply "k8s.io/client-go/applyconfigurations/core/v1"
 	"k8s.io/utils/pointer"
 
+	discoveryv1alpha1 "github.com/liqotech/liqo/apis/discovery/v1alpha1"
 	liqoconst "github.com/liqotech/liqo/pkg/consts"
 )
 
 // nodePortUnset -> the value representing an unset NodePort.
 const nodePortUnset = 0
 
+// DefaultClusterDomain -> the cluster domain assumed by default when forging the FQDN of the local services.
+const DefaultClusterDomain = "cluster.local"
+
+// ExternalIPsPolicy defines how the external IPs of the local services are handled when reflected.
+type ExternalIPsPolicy string
+
+const (
+	// ExternalIPsPolicyStrip -> the external IPs are not reflected, as referring to the local cluster.
+	ExternalIPsPolicyStrip ExternalIPsPolicy = "Strip"
+	// ExternalIPsPolicyRetain -> the external IPs are reflected verbatim.
+	ExternalIPsPolicyRetain ExternalIPsPolicy = "Retain"
+	// ExternalIPsPolicyRemap -> the external IPs are translated according to the configured mapping,
+	// while those without a corresponding entry are not reflected.
+	ExternalIPsPolicyRemap ExternalIPsPolicy = "Remap"
+)
+
+// RemoteServiceOptions contains the configuration parameters driving the forging of the reflected services.
+type RemoteServiceOptions struct {
+	// RemoteIPFamilies, if specified, are the IP families supported by the remote cluster (see RemoteServiceIPFamilies).
+	RemoteIPFamilies []corev1.IPFamily
+	// ExternalIPsPolicy is the policy used to handle the external IPs (defaults to ExternalIPsPolicyStrip).
+	ExternalIPsPolicy ExternalIPsPolicy
+	// ExternalIPsMapping maps the local external IPs to the remote ones, in case of ExternalIPsPolicyRemap.
+	ExternalIPsMapping map[string]string
+	// NameMapper transforms the name of the local service into the remote one (defaults to the identity).
+	NameMapper NameMapper
+	// MaxAnnotationSize, if positive, is the maximum size (key plus value, in bytes) of the local annotations
+	// which are reflected, while the larger ones are pruned (see OversizedAnnotations).
+	MaxAnnotationSize int
+	// DeniedPorts are the ports never exposed towards the remote cluster (see AllowedServicePorts).
+	DeniedPorts DeniedPorts
+	// ClusterDomain is the domain of the local cluster, used to forge the FQDN hint (defaults to DefaultClusterDomain).
+	ClusterDomain string
+}
+
 // RemoteService forges the apply patch for the reflected service, given the local one.
-func RemoteService(local *corev1.Service, targetNamespace string) *corev1apply.ServiceApplyConfiguration {
-	return corev1apply.Service(local.GetName(), targetNamespace).
+// Finalizers, owner references and the other cluster-specific metadata (e.g., UID and resource version) are never replicated,
+// as meaningless in the remote cluster, and possibly preventing the deletion of the reflected object.
+// An invalid port mapping annotation is ignored, as expected to be validated by the caller.
+// The deprecated fields no longer part of the API (e.g., topologyKeys) are never reflected, as already dropped when
+// decoding the local service, hence preventing the reflection from failing towards newer remote clusters.
+// The options are optional, and default to the zero value if nil.
+// The reflected service is additionally annotated with the resource version of the local one, as well as with the hash
+// of the reflected fields (see RemoteServiceHash), to allow skipping the updates which would not modify the remote object.
+func RemoteService(local *corev1.Service, targetNamespace string, opts *RemoteServiceOptions) *corev1apply.ServiceApplyConfiguration {
+	var mapper NameMapper
+	var maxAnnotationSize int
+	var clusterDomain string
+	if opts != nil {
+		mapper = opts.NameMapper
+		maxAnnotationSize = opts.MaxAnnotationSize
+		clusterDomain = opts.ClusterDomain
+	}
+
+	mapping, _ := RemotePortMapping(local)
+	name := mapper.RemoteName(local.GetName())
+	remote := corev1apply.Service(name, targetNamespace).
 		WithLabels(local.GetLabels()).WithLabels(ReflectionLabels()).
-		WithAnnotations(local.GetAnnotations()).
-		WithSpec(RemoteServiceSpec(local.Spec.DeepCopy(), getForceRemoteNodePort(local)))
+		WithLabels(map[string]string{liqoconst.ReflectedFromLabelKey: LocalCluster.ClusterID}).
+		WithAnnotations(PrunedAnnotations(local.GetAnnotations(), maxAnnotationSize)).
+		WithAnnotations(SourceNameAnnotations(local.GetName(), name)).
+		WithAnnotations(SourceFQDNAnnotations(local, clusterDomain)).
+		WithSpec(RemoteServiceSpec(local.Spec.DeepCopy(), getForceRemoteNodePort(local), mapping, opts))
+
+	// The hash is computed before adding the corresponding annotations, to cover only the reflected fields.
+	return remote.WithAnnotations(map[string]string{
+		liqoconst.ReflectedHashAnnotationKey:         RemoteServiceHash(remote),
+		liqoconst.SourceResourceVersionAnnotationKey: local.GetResourceVersion(),
+	})
+}
+
+// ServiceFQDN returns the cluster-local DNS name of the service with the given name and namespace.
+// The cluster domain defaults to DefaultClusterDomain, if empty.
+func ServiceFQDN(name, namespace, clusterDomain string) string {
+	if clusterDomain == "" {
+		clusterDomain = DefaultClusterDomain
+	}
+	return fmt.Sprintf("%s.%s.svc.%s", name, namespace, strings.TrimSuffix(clusterDomain, "."))
+}
+
+// SourceFQDNAnnotations returns the annotations recording the cluster-local DNS name of the given local service,
+// to be added to the reflected one as a hint for the remote consumers (e.g., to rewrite the original DNS names).
+func SourceFQDNAnnotations(local *corev1.Service, clusterDomain string) map[string]string {
+	return map[string]string{liqoconst.SourceFQDNAnnotationKey: ServiceFQDN(local.GetName(), local.GetNamespace(), clusterDomain)}
+}
+
+// OversizedAnnotations returns the sorted keys of the annotations whose size (key plus value, in bytes) exceeds
+// the given maximum, if positive, and which are thus pruned when reflecting the object.
+func OversizedAnnotations(annotations map[string]string, maxSize int) []string {
+	if maxSize <= 0 {
+		return nil
+	}
+
+	var oversized []string
+	for key, value := range annotations {
+		if len(key)+len(value) > maxSize {
+			oversized = append(oversized, key)
+		}
+	}
+	sort.Strings(oversized)
+	return oversized
+}
+
+// PrunedAnnotations returns a copy of the given annotations, excluding the oversized ones (see OversizedAnnotations).
+// The original annotations are returned unmodified in case no pruning is necessary.
+func PrunedAnnotations(annotations map[string]string, maxSize int) map[string]string {
+	oversized := OversizedAnnotations(annotations, maxSize)
+	if len(oversized) == 0 {
+		return annotations
+	}
+
+	pruned := make(map[string]string, len(annotations)-len(oversized))
+	for key, value := range annotations {
+		pruned[key] = value
+	}
+	for _, key := range oversized {
+		delete(pruned, key)
+	}
+	return pruned
+}
+
+// RemoteServiceHash returns the hash of the given apply patch for a reflected service.
+func RemoteServiceHash(remote *corev1apply.ServiceApplyConfiguration) string {
+	// Marshaling an apply configuration never fails, and the resulting map keys are sorted.
+	data, _ := json.Marshal(remote)
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
+
+// RemoteServiceUpToDate returns whether the given remote service already matches the forged apply patch,
+// according to the hash of the reflected fields.
+func RemoteServiceUpToDate(remote *corev1.Service, mutation *corev1apply.ServiceApplyConfiguration) bool {
+	if remote == nil {
+		return false
+	}
+	current, found := remote.GetAnnotations()[liqoconst.ReflectedHashAnnotationKey]
+	return found && current == mutation.Annotations[liqoconst.ReflectedHashAnnotationKey]
 }
 
 // RemoteServiceSpec forges the apply patch for the specs of the reflected service, given the local ones.
 // It expects the local object to be a deepcopy, as it is mutated.
-func RemoteServiceSpec(local *corev1.ServiceSpec, forceRemoteNodePort bool) *corev1apply.ServiceSpecApplyConfiguration {
+func RemoteServiceSpec(local *corev1.ServiceSpec, forceRemoteNodePort bool, mapping PortMapping,
+	opts *RemoteServiceOptions) *corev1apply.ServiceSpecApplyConfiguration {
+	if opts == nil {
+		opts = &RemoteServiceOptions{}
+	}
+
+	local.Ports = AllowedServicePorts(local.Ports, opts.DeniedPorts)
 	remote := corev1apply.ServiceSpec().
 		WithType(local.Type).WithSelector(local.Selector).
-		WithPorts(RemoteServicePorts(local.Ports, forceRemoteNodePort)...).
+		WithPorts(RemoteServicePorts(local.Ports, forceRemoteNodePort, mapping)...).
 		WithExternalName(local.ExternalName)
 
 	// The additional fields are set manually instead of using the "With" functions,
@@ -47,7 +195,8 @@ func RemoteServiceSpec(local *corev1.ServiceSpec, forceRemoteNodePort bool) *cor
 	remote.AllocateLoadBalancerNodePorts = local.AllocateLoadBalancerNodePorts
 	remote.ExternalTrafficPolicy = &local.ExternalTrafficPolicy
 	remote.InternalTrafficPolicy = local.InternalTrafficPolicy
-	remote.IPFamilyPolicy = local.IPFamilyPolicy
+	remote.ExternalIPs = RemoteServiceExternalIPs(local.ExternalIPs, opts.ExternalIPsPolicy, opts.ExternalIPsMapping)
+	remote.IPFamilies, remote.IPFamilyPolicy = RemoteServiceIPFamilies(local.IPFamilies, local.IPFamilyPolicy, opts.RemoteIPFamilies)
 	remote.LoadBalancerClass = local.LoadBalancerClass
 	remote.LoadBalancerSourceRanges = local.LoadBalancerSourceRanges
 	remote.PublishNotReadyAddresses = &local.PublishNotReadyAddresses
@@ -60,13 +209,61 @@ func RemoteServiceSpec(local *corev1.ServiceSpec, forceRemoteNodePort bool) *cor
 	return remote
 }
 
+// RemoteServiceWithTTL adds to the given apply patch the annotations specifying the reflection TTL, along with the current
+// time as the last synchronization timestamp. They are not covered by the hash, to avoid spurious differences.
+func RemoteServiceWithTTL(mutation *corev1apply.ServiceApplyConfiguration, ttl time.Duration, now time.Time) *corev1apply.ServiceApplyConfiguration {
+	return mutation.WithAnnotations(map[string]string{
+		liqoconst.ReflectionTTLAnnotationKey:      ttl.String(),
+		liqoconst.ReflectionLastSyncAnnotationKey: now.UTC().Format(time.RFC3339),
+	})
+}
+
+// ReflectionLastSync returns the last synchronization timestamp of the given reflected object, if present and valid.
+func ReflectionLastSync(obj metav1.Object) (time.Time, bool) {
+	value, found := obj.GetAnnotations()[liqoconst.ReflectionLastSyncAnnotationKey]
+	if !found {
+		return time.Time{}, false
+	}
+	timestamp, err := time.Parse(time.RFC3339, value)
+	return timestamp, err == nil
+}
+
+// ReflectionTTL returns the reflection TTL of the given reflected object, if present and valid.
+func ReflectionTTL(obj metav1.Object) (time.Duration, bool) {
+	value, found := obj.GetAnnotations()[liqoconst.ReflectionTTLAnnotationKey]
+	if !found {
+		return 0, false
+	}
+	ttl, err := time.ParseDuration(value)
+	return ttl, err == nil && ttl > 0
+}
+
+// IsReflectionStale returns whether the given reflected object has not been refreshed within its TTL.
+// Objects without a (valid) TTL never become stale, while those with a TTL but without a valid timestamp are always stale.
+func IsReflectionStale(obj metav1.Object, now time.Time) bool {
+	ttl, found := ReflectionTTL(obj)
+	if !found {
+		return false
+	}
+	lastSync, found := ReflectionLastSync(obj)
+	return !found || now.Sub(lastSync) > ttl
+}
+
 // RemoteServicePorts forges the apply patch for the ports of the reflected service, given the local ones.
-func RemoteServicePorts(locals []corev1.ServicePort, forceRemoteNodePort bool) []*corev1apply.ServicePortApplyConfiguration {
+// Numeric target ports are translated according to the given mapping, while named ones are left untouched.
+func RemoteServicePorts(locals []corev1.ServicePort, forceRemoteNodePort bool,
+	mapping PortMapping) []*corev1apply.ServicePortApplyConfiguration {
 	var remotes []*corev1apply.ServicePortApplyConfiguration
 
-	for _, local := range locals {
+	for i := range locals {
+		local := &locals[i]
+		targetPort := local.TargetPort
+		if targetPort.Type == intstr.Int && targetPort.IntVal != 0 {
+			targetPort = intstr.FromInt(int(mapping.Remap(targetPort.IntVal, &local.Protocol)))
+		}
+
 		remote := corev1apply.ServicePort().WithName(local.Name).WithPort(local.Port).
-			WithTargetPort(local.TargetPort).WithProtocol(local.Protocol)
+			WithTargetPort(targetPort).WithProtocol(local.Protocol)
 
 		if local.NodePort == nodePortUnset {
 			// Ensure the nodeport is unset in case it is removed, to allow
@@ -88,6 +285,105 @@ func RemoteServicePorts(locals []corev1.ServicePort, forceRemoteNodePort bool) [
 	return remotes
 }
 
+// RemoteServiceExternalIPs forges the external IPs of the reflected service, given the local ones and the policy.
+func RemoteServiceExternalIPs(locals []string, policy ExternalIPsPolicy, mapping map[string]string) []string {
+	var remotes []string
+
+	switch policy {
+	case ExternalIPsPolicyRetain:
+		remotes = append(remotes, locals...)
+	case ExternalIPsPolicyRemap:
+		for _, local := range locals {
+			if remote, found := mapping[local]; found {
+				remotes = append(remotes, remote)
+			}
+		}
+	}
+
+	return remotes
+}
+
+// RemoteServiceIPFamilies normalizes the IP families and the IP family policy of the reflected service,
+// given the local ones and the IP families supported by the remote cluster. In case the supported families
+// are not specified, the IP families are not reflected (i.e., they are defaulted by the remote cluster),
+// while the policy is reflected verbatim. Otherwise, the families not supported by the remote cluster are
+// dropped, and the policy is downgraded to SingleStack in case the remote cluster is not dual-stack.
+func RemoteServiceIPFamilies(families []corev1.IPFamily, policy *corev1.IPFamilyPolicy,
+	supported []corev1.IPFamily) ([]corev1.IPFamily, *corev1.IPFamilyPolicy) {
+	if len(supported) == 0 {
+		return nil, policy
+	}
+
+	var remotes []corev1.IPFamily
+	for _, family := range families {
+		for _, s := range supported {
+			if family == s {
+				remotes = append(remotes, family)
+				break
+			}
+		}
+	}
+
+	if policy != nil && *policy != corev1.IPFamilyPolicySingleStack && len(supported) == 1 {
+		singleStack := corev1.IPFamilyPolicySingleStack
+		policy = &singleStack
+	}
+	return remotes, policy
+}
+
+// IsServiceReflectedFrom returns whether the given Service has been reflected from another cluster,
+// along with the ID of the origin cluster. Such Services shall not be reflected further, to prevent loops.
+func IsServiceReflectedFrom(svc metav1.Object) (origin string, reflected bool) {
+	origin, reflected = svc.GetLabels()[liqoconst.ReflectedFromLabelKey]
+	return origin, reflected
+}
+
+// ServiceTargetClusters returns the remote clusters (either by ID or by name) the given Service shall be reflected to,
+// as specified by the target clusters annotation, and whether the annotation is present. Empty entries are ignored.
+func ServiceTargetClusters(svc metav1.Object) (clusters []string, found bool) {
+	value, found := svc.GetAnnotations()[liqoconst.TargetClustersAnnotationKey]
+	if !found {
+		return nil, false
+	}
+
+	for _, cluster := range strings.Split(value, ",") {
+		if cluster = strings.TrimSpace(cluster); cluster != "" {
+			clusters = append(clusters, cluster)
+		}
+	}
+	return clusters, true
+}
+
+// IsServiceTargetingCluster returns whether the given Service shall be reflected to the given remote cluster, according to
+// the target clusters annotation. Services without the annotation target every cluster, while an empty list targets none.
+func IsServiceTargetingCluster(svc metav1.Object, cluster discoveryv1alpha1.ClusterIdentity) bool {
+	clusters, found := ServiceTargetClusters(svc)
+	if !found {
+		return true
+	}
+
+	for _, target := range clusters {
+		if target == cluster.ClusterID || target == cluster.ClusterName {
+			return true
+		}
+	}
+	return false
+}
+
+// IsServiceTypeAllowed returns whether a Service of the given type can be reflected towards the remote cluster,
+// according to the given allowlist. An empty allowlist allows every type.
+func IsServiceTypeAllowed(svcType corev1.ServiceType, allowed []corev1.ServiceType) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, t := range allowed {
+		if t == svcType {
+			return true
+		}
+	}
+	return false
+}
+
 func getForceRemoteNodePort(local *corev1.Service) bool {
 	val, ok := local.Annotations[liqoconst.ForceRemoteNodePortAnnotationKey]
 	return ok && val == "true"
@@ -15,6 +15,9 @@
 package forge_test
 
 import (
+	"encoding/json"
+	"strings"
+
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	. "github.com/onsi/gomega/gstruct"
@@ -24,6 +27,8 @@ import (
 	corev1apply "k8s.io/client-go/applyconfigurations/core/v1"
 	"k8s.io/utils/pointer"
 
+	discoveryv1alpha1 "github.com/liqotech/liqo/apis/discovery/v1alpha1"
+	liqoconst "github.com/liqotech/liqo/pkg/consts"
 	"github.com/liqotech/liqo/pkg/virtualKubelet/forge"
 )
 
@@ -32,39 +37,305 @@ var _ = Describe("Services Forging", func() {
 		var (
 			input  *corev1.Service
 			output *corev1apply.ServiceApplyConfiguration
+			opts   *forge.RemoteServiceOptions
 		)
 
 		BeforeEach(func() {
 			input = &corev1.Service{
 				ObjectMeta: metav1.ObjectMeta{
 					Name: "name", Namespace: "original",
-					Labels:      map[string]string{"foo": "bar"},
-					Annotations: map[string]string{"bar": "baz"},
+					Labels:          map[string]string{"foo": "bar"},
+					Annotations:     map[string]string{"bar": "baz"},
+					Finalizers:      []string{"foo.example.com/finalizer"},
+					UID:             "uid",
+					ResourceVersion: "123",
+					OwnerReferences: []metav1.OwnerReference{{APIVersion: "v1", Kind: "ConfigMap", Name: "owner", UID: "owner-uid"}},
 				},
 				Spec: corev1.ServiceSpec{Type: corev1.ServiceTypeNodePort},
 			}
+			opts = nil
+		})
 
-			JustBeforeEach(func() { output = forge.RemoteService(input, "reflected") })
+		JustBeforeEach(func() { output = forge.RemoteService(input, "reflected", opts) })
 
-			It("should correctly set the name and namespace", func() {
-				Expect(output.Name).To(PointTo(Equal("name")))
-				Expect(output.Namespace).To(PointTo(Equal("reflected")))
+		It("should correctly set the name and namespace", func() {
+			Expect(output.Name).To(PointTo(Equal("name")))
+			Expect(output.Namespace).To(PointTo(Equal("reflected")))
+		})
+
+		It("should correctly set the labels", func() {
+			Expect(output.Labels).To(HaveKeyWithValue("foo", "bar"))
+			Expect(output.Labels).To(HaveKeyWithValue(forge.LiqoOriginClusterIDKey, LocalClusterID))
+			Expect(output.Labels).To(HaveKeyWithValue(forge.LiqoDestinationClusterIDKey, RemoteClusterID))
+			Expect(output.Labels).To(HaveKeyWithValue(liqoconst.ReflectedFromLabelKey, LocalClusterID))
+		})
+		It("should correctly set the annotations", func() {
+			Expect(output.Annotations).To(HaveKeyWithValue("bar", "baz"))
+			Expect(output.Annotations).To(HaveKeyWithValue(liqoconst.SourceResourceVersionAnnotationKey, "123"))
+			Expect(output.Annotations).To(HaveKeyWithValue(liqoconst.ReflectedHashAnnotationKey, Not(BeEmpty())))
+		})
+		It("should annotate the FQDN of the local service", func() {
+			Expect(output.Annotations).To(HaveKeyWithValue(liqoconst.SourceFQDNAnnotationKey, "name.original.svc.cluster.local"))
+		})
+
+		When("a custom cluster domain is configured", func() {
+			BeforeEach(func() { opts = &forge.RemoteServiceOptions{ClusterDomain: "example.org."} })
+
+			It("should annotate the FQDN of the local service accordingly", func() {
+				Expect(output.Annotations).To(HaveKeyWithValue(liqoconst.SourceFQDNAnnotationKey, "name.original.svc.example.org"))
 			})
+		})
 
-			It("should correctly set the labels", func() {
-				Expect(output.Labels).To(HaveKeyWithValue("foo", "bar"))
-				Expect(output.Labels).To(HaveKeyWithValue(forge.LiqoOriginClusterIDKey, LocalClusterID))
-				Expect(output.Labels).To(HaveKeyWithValue(forge.LiqoDestinationClusterIDKey, RemoteClusterID))
+		When("a name transformation is configured", func() {
+			BeforeEach(func() { opts = &forge.RemoteServiceOptions{NameMapper: forge.PrefixNameMapper("cluster-")} })
+
+			It("should annotate the FQDN of the local service, with the original name", func() {
+				Expect(output.Name).To(PointTo(Equal("cluster-name")))
+				Expect(output.Annotations).To(HaveKeyWithValue(liqoconst.SourceFQDNAnnotationKey, "name.original.svc.cluster.local"))
+			})
+		})
+
+		When("checking whether the remote service is up-to-date", func() {
+			var remote *corev1.Service
+
+			JustBeforeEach(func() {
+				remote = &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "name", Namespace: "reflected", Annotations: output.Annotations}}
+			})
+
+			It("should not require an update if the local service did not change", func() {
+				input.ResourceVersion = "124"
+				Expect(forge.RemoteServiceUpToDate(remote, forge.RemoteService(input, "reflected", opts))).To(BeTrue())
+			})
+			It("should require an update if the reflected fields changed", func() {
+				input.Spec.Type = corev1.ServiceTypeClusterIP
+				Expect(forge.RemoteServiceUpToDate(remote, forge.RemoteService(input, "reflected", opts))).To(BeFalse())
+			})
+			It("should require an update if the remote service is not annotated", func() {
+				remote.Annotations = nil
+				Expect(forge.RemoteServiceUpToDate(remote, output)).To(BeFalse())
+			})
+			It("should require an update if the remote service does not exist", func() {
+				Expect(forge.RemoteServiceUpToDate(nil, output)).To(BeFalse())
+			})
+		})
+		It("should correctly set the spec", func() {
+			Expect(output.Spec.Type).To(PointTo(Equal(corev1.ServiceTypeNodePort)))
+		})
+		It("should not replicate the finalizers", func() {
+			Expect(output.Finalizers).To(BeEmpty())
+		})
+		It("should not replicate the cluster-specific metadata", func() {
+			Expect(output.UID).To(BeNil())
+			Expect(output.ResourceVersion).To(BeNil())
+			Expect(output.OwnerReferences).To(BeEmpty())
+			Expect(output.Generation).To(BeNil())
+			Expect(output.CreationTimestamp).To(BeNil())
+		})
+
+		When("a maximum annotation size is configured", func() {
+			BeforeEach(func() {
+				input.Annotations["kubectl.kubernetes.io/last-applied-configuration"] = strings.Repeat("x", 1024)
+				input.Annotations[liqoconst.ForceRemoteNodePortAnnotationKey] = "true"
+				input.Spec.Ports = []corev1.ServicePort{{Name: "http", Port: 80, NodePort: 30080}}
+				opts = &forge.RemoteServiceOptions{MaxAnnotationSize: 256}
 			})
-			It("should correctly set the annotations", func() {
+
+			It("should prune the oversized annotations", func() {
+				Expect(output.Annotations).ToNot(HaveKey("kubectl.kubernetes.io/last-applied-configuration"))
+			})
+			It("should preserve the other annotations", func() {
 				Expect(output.Annotations).To(HaveKeyWithValue("bar", "baz"))
+				Expect(output.Annotations).To(HaveKeyWithValue(liqoconst.ForceRemoteNodePortAnnotationKey, "true"))
+				Expect(output.Annotations).To(HaveKeyWithValue(liqoconst.SourceResourceVersionAnnotationKey, "123"))
+				Expect(output.Annotations).To(HaveKeyWithValue(liqoconst.ReflectedHashAnnotationKey, Not(BeEmpty())))
 			})
-			It("should correctly set the spec", func() {
+			It("should preserve the essential fields", func() {
+				Expect(output.Name).To(PointTo(Equal("name")))
+				Expect(output.Labels).To(HaveKeyWithValue("foo", "bar"))
 				Expect(output.Spec.Type).To(PointTo(Equal(corev1.ServiceTypeNodePort)))
+				Expect(output.Spec.Ports).To(HaveLen(1))
+				Expect(output.Spec.Ports[0].NodePort).To(PointTo(BeNumerically("==", 30080)))
+			})
+			It("should not mutate the local service", func() {
+				Expect(input.Annotations).To(HaveKey("kubectl.kubernetes.io/last-applied-configuration"))
+			})
+		})
+
+		When("some ports are denied", func() {
+			BeforeEach(func() {
+				input.Spec.Ports = []corev1.ServicePort{
+					{Name: "http", Port: 80, TargetPort: intstr.FromInt(8080), Protocol: corev1.ProtocolTCP},
+					{Name: "ssh", Port: 2222, TargetPort: intstr.FromInt(22), Protocol: corev1.ProtocolTCP},
+					{Name: "snmp", Port: 161, TargetPort: intstr.FromInt(161), Protocol: corev1.ProtocolUDP},
+				}
+				opts = &forge.RemoteServiceOptions{DeniedPorts: forge.DeniedPorts{"22": {}, "161/UDP": {}}}
+			})
+
+			It("should strip the denied ports", func() {
+				Expect(output.Spec.Ports).To(HaveLen(1))
+				Expect(output.Spec.Ports[0].Name).To(PointTo(Equal("http")))
+			})
+			It("should not mutate the local service", func() {
+				Expect(input.Spec.Ports).To(HaveLen(3))
+			})
+		})
+
+		When("the local service has external IPs", func() {
+			BeforeEach(func() { input.Spec.ExternalIPs = []string{"10.0.0.1"} })
+
+			It("should strip them by default", func() { Expect(output.Spec.ExternalIPs).To(BeEmpty()) })
+
+			When("the remap policy is configured", func() {
+				BeforeEach(func() {
+					opts = &forge.RemoteServiceOptions{
+						ExternalIPsPolicy:  forge.ExternalIPsPolicyRemap,
+						ExternalIPsMapping: map[string]string{"10.0.0.1": "10.1.0.1"},
+					}
+				})
+				It("should remap them", func() { Expect(output.Spec.ExternalIPs).To(ConsistOf("10.1.0.1")) })
+			})
+		})
+
+		When("the local service specifies deprecated fields no longer supported by the remote cluster", func() {
+			BeforeEach(func() {
+				// The service is decoded as it would be by the informers, from a cluster still using the deprecated fields.
+				raw := `{"metadata": {"name": "name", "namespace": "original"}, "spec": {"type": "ClusterIP",
+					"topologyKeys": ["kubernetes.io/hostname", "*"], "ipFamily": "IPv4", "ports": [{"port": 80}]}}`
+				input = &corev1.Service{}
+				Expect(json.Unmarshal([]byte(raw), input)).To(Succeed())
+			})
+
+			It("should strip them from the reflected service", func() {
+				patch, err := json.Marshal(output)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(string(patch)).ToNot(ContainSubstring("topologyKeys"))
+				Expect(string(patch)).ToNot(ContainSubstring(`"ipFamily"`))
+			})
+			It("should preserve the supported fields", func() {
+				Expect(output.Spec.Type).To(PointTo(Equal(corev1.ServiceTypeClusterIP)))
+				Expect(output.Spec.Ports).To(HaveLen(1))
+			})
+		})
+
+		When("reflecting a dual-stack service towards a single-stack remote cluster", func() {
+			BeforeEach(func() {
+				policy := corev1.IPFamilyPolicyRequireDualStack
+				input.Spec.IPFamilies = []corev1.IPFamily{corev1.IPv6Protocol, corev1.IPv4Protocol}
+				input.Spec.IPFamilyPolicy = &policy
+				opts = &forge.RemoteServiceOptions{RemoteIPFamilies: []corev1.IPFamily{corev1.IPv4Protocol}}
+			})
+
+			It("should drop the unsupported IP families", func() {
+				Expect(output.Spec.IPFamilies).To(ConsistOf(corev1.IPv4Protocol))
+			})
+			It("should downgrade the IP family policy to single-stack", func() {
+				Expect(output.Spec.IPFamilyPolicy).To(PointTo(Equal(corev1.IPFamilyPolicySingleStack)))
 			})
 		})
 	})
 
+	Describe("the OversizedAnnotations function", func() {
+		annotations := map[string]string{"small": "value", "large": strings.Repeat("x", 100), "medium": strings.Repeat("x", 20)}
+
+		It("should return the sorted keys of the oversized annotations", func() {
+			Expect(forge.OversizedAnnotations(annotations, 20)).To(Equal([]string{"large", "medium"}))
+		})
+		It("should count the size of both the key and the value", func() {
+			Expect(forge.OversizedAnnotations(annotations, 26)).To(Equal([]string{"large"}))
+		})
+		It("should return nothing if the maximum size is not positive", func() {
+			Expect(forge.OversizedAnnotations(annotations, 0)).To(BeEmpty())
+		})
+	})
+
+	Describe("the RemoteServiceExternalIPs function", func() {
+		locals := []string{"10.0.0.1", "10.0.0.2"}
+		mapping := map[string]string{"10.0.0.1": "10.1.0.1"}
+
+		DescribeTable("handling the external IPs according to the policy",
+			func(policy forge.ExternalIPsPolicy, expected []string) {
+				Expect(forge.RemoteServiceExternalIPs(locals, policy, mapping)).To(Equal(expected))
+			},
+			Entry("unset policy", forge.ExternalIPsPolicy(""), nil),
+			Entry("strip policy", forge.ExternalIPsPolicyStrip, nil),
+			Entry("retain policy", forge.ExternalIPsPolicyRetain, locals),
+			Entry("remap policy", forge.ExternalIPsPolicyRemap, []string{"10.1.0.1"}),
+		)
+
+		It("should not mutate the local external IPs", func() {
+			forge.RemoteServiceExternalIPs(locals, forge.ExternalIPsPolicyRemap, mapping)
+			Expect(locals).To(Equal([]string{"10.0.0.1", "10.0.0.2"}))
+		})
+	})
+
+	Describe("the RemoteServiceIPFamilies function", func() {
+		var (
+			singleStack      = corev1.IPFamilyPolicySingleStack
+			preferDualStack  = corev1.IPFamilyPolicyPreferDualStack
+			requireDualStack = corev1.IPFamilyPolicyRequireDualStack
+			dualStack        = []corev1.IPFamily{corev1.IPv4Protocol, corev1.IPv6Protocol}
+		)
+
+		DescribeTable("normalizing the IP families and policy",
+			func(families []corev1.IPFamily, policy *corev1.IPFamilyPolicy, supported []corev1.IPFamily,
+				expectedFamilies []corev1.IPFamily, expectedPolicy *corev1.IPFamilyPolicy) {
+				outFamilies, outPolicy := forge.RemoteServiceIPFamilies(families, policy, supported)
+				Expect(outFamilies).To(Equal(expectedFamilies))
+				Expect(outPolicy).To(Equal(expectedPolicy))
+			},
+			Entry("unknown remote capabilities", dualStack, &requireDualStack, nil, nil, &requireDualStack),
+			Entry("dual-stack remote cluster", dualStack, &preferDualStack, dualStack, dualStack, &preferDualStack),
+			Entry("IPv4-only remote cluster", dualStack, &preferDualStack, []corev1.IPFamily{corev1.IPv4Protocol},
+				[]corev1.IPFamily{corev1.IPv4Protocol}, &singleStack),
+			Entry("IPv6-only remote cluster", dualStack, &requireDualStack, []corev1.IPFamily{corev1.IPv6Protocol},
+				[]corev1.IPFamily{corev1.IPv6Protocol}, &singleStack),
+			Entry("no supported families", []corev1.IPFamily{corev1.IPv6Protocol}, &singleStack,
+				[]corev1.IPFamily{corev1.IPv4Protocol}, nil, &singleStack),
+			Entry("unset policy", []corev1.IPFamily{corev1.IPv4Protocol}, nil, []corev1.IPFamily{corev1.IPv4Protocol},
+				[]corev1.IPFamily{corev1.IPv4Protocol}, nil),
+		)
+	})
+
+	Describe("the IsServiceReflectedFrom function", func() {
+		It("should detect a previously reflected service", func() {
+			svc := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{liqoconst.ReflectedFromLabelKey: "origin"}}}
+			origin, reflected := forge.IsServiceReflectedFrom(svc)
+			Expect(reflected).To(BeTrue())
+			Expect(origin).To(Equal("origin"))
+		})
+		It("should not flag a native local service", func() {
+			_, reflected := forge.IsServiceReflectedFrom(&corev1.Service{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"foo": "bar"}}})
+			Expect(reflected).To(BeFalse())
+		})
+	})
+
+	Describe("the IsServiceTargetingCluster function", func() {
+		cluster := discoveryv1alpha1.ClusterIdentity{ClusterID: "cluster-id", ClusterName: "cluster-name"}
+
+		DescribeTable("checking whether the service targets the given cluster",
+			func(annotations map[string]string, expected bool) {
+				svc := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Annotations: annotations}}
+				Expect(forge.IsServiceTargetingCluster(svc, cluster)).To(Equal(expected))
+			},
+			Entry("no annotation", nil, true),
+			Entry("cluster listed by ID", map[string]string{liqoconst.TargetClustersAnnotationKey: "other-id,cluster-id"}, true),
+			Entry("cluster listed by name", map[string]string{liqoconst.TargetClustersAnnotationKey: "other-name, cluster-name "}, true),
+			Entry("cluster not listed", map[string]string{liqoconst.TargetClustersAnnotationKey: "other-id,other-name"}, false),
+			Entry("empty list", map[string]string{liqoconst.TargetClustersAnnotationKey: ""}, false),
+		)
+	})
+
+	Describe("the IsServiceTypeAllowed function", func() {
+		DescribeTable("checking whether the service type is allowed",
+			func(svcType corev1.ServiceType, allowed []corev1.ServiceType, expected bool) {
+				Expect(forge.IsServiceTypeAllowed(svcType, allowed)).To(Equal(expected))
+			},
+			Entry("nil allowlist", corev1.ServiceTypeLoadBalancer, nil, true),
+			Entry("type in the allowlist", corev1.ServiceTypeClusterIP, []corev1.ServiceType{corev1.ServiceTypeClusterIP}, true),
+			Entry("type not in the allowlist", corev1.ServiceTypeLoadBalancer, []corev1.ServiceType{corev1.ServiceTypeClusterIP}, false),
+		)
+	})
+
 	Describe("the RemoteServiceSpec function", func() {
 		var (
 			getService = func(serviceType corev1.ServiceType, clusterIP string) *corev1.ServiceSpec {
@@ -102,7 +373,7 @@ var _ = Describe("Services Forging", func() {
 		}
 
 		DescribeTable("RemoteServiceSpec table", func(c remoteServiceTestcase) {
-			output := forge.RemoteServiceSpec(c.input.DeepCopy(), false)
+			output := forge.RemoteServiceSpec(c.input.DeepCopy(), false, nil, nil)
 
 			By("should correctly replicate the core fields", func() {
 				Expect(output.Type).To(PointTo(c.expectedServiceType))
@@ -150,6 +421,7 @@ var _ = Describe("Services Forging", func() {
 			input               corev1.ServicePort
 			output              []*corev1apply.ServicePortApplyConfiguration
 			forceRemoteNodePort bool
+			mapping             forge.PortMapping
 		)
 
 		BeforeEach(func() {
@@ -157,9 +429,12 @@ var _ = Describe("Services Forging", func() {
 				Name: "HTTPS", Port: 443, TargetPort: intstr.FromInt(8443), Protocol: corev1.ProtocolTCP,
 			}
 			forceRemoteNodePort = false
+			mapping = nil
 		})
 
-		JustBeforeEach(func() { output = forge.RemoteServicePorts([]corev1.ServicePort{input, input}, forceRemoteNodePort) })
+		JustBeforeEach(func() {
+			output = forge.RemoteServicePorts([]corev1.ServicePort{input, input}, forceRemoteNodePort, mapping)
+		})
 
 		It("should return the correct number of ports", func() { Expect(output).To(HaveLen(2)) })
 		It("should correctly replicate the port fields", func() {
@@ -190,5 +465,24 @@ var _ = Describe("Services Forging", func() {
 			})
 			It("should be replicated", func() { Expect(output[0].NodePort).To(PointTo(BeNumerically("==", 33333))) })
 		})
+
+		When("a port mapping matching the target port is specified", func() {
+			BeforeEach(func() { mapping = forge.PortMapping{"8443/TCP": 9443} })
+			It("should translate the target port", func() { Expect(output[0].TargetPort).To(PointTo(Equal(intstr.FromInt(9443)))) })
+			It("should preserve the service port", func() { Expect(output[0].Port).To(PointTo(BeNumerically("==", 443))) })
+		})
+
+		When("a port mapping for a different protocol is specified", func() {
+			BeforeEach(func() { mapping = forge.PortMapping{"8443/UDP": 9443} })
+			It("should not translate the target port", func() { Expect(output[0].TargetPort).To(PointTo(Equal(intstr.FromInt(8443)))) })
+		})
+
+		When("the target port is named", func() {
+			BeforeEach(func() {
+				input.TargetPort = intstr.FromString("https")
+				mapping = forge.PortMapping{"8443": 9443}
+			})
+			It("should not be translated", func() { Expect(output[0].TargetPort).To(PointTo(Equal(intstr.FromString("https")))) })
+		})
 	})
 })
@@ -49,8 +49,11 @@ func init() {
 
 // InitConfig is the config passed to initialize the LiqoPodProvider.
 type InitConfig struct {
-	LocalConfig   *rest.Config
-	RemoteConfig  *rest.Config
+	LocalConfig  *rest.Config
+	RemoteConfig *rest.Config
+	// HubConfig, if set, is the configuration of the hub cluster the Services (and the corresponding EndpointSlices)
+	// are reflected to, in place of the remote one.
+	HubConfig     *rest.Config
 	LocalCluster  discoveryv1alpha1.ClusterIdentity
 	RemoteCluster discoveryv1alpha1.ClusterIdentity
 	Namespace     string
@@ -69,6 +72,9 @@ type InitConfig struct {
 	SecretWorkers               uint
 	ServiceAccountWorkers       uint
 
+	ServiceReflection       exposition.ServiceReflectorConfig
+	EndpointSliceReflection exposition.EndpointSliceReflectorConfig
+
 	EnableAPIServerSupport     bool
 	EnableStorage              bool
 	VirtualStorageClassName    string
@@ -117,9 +123,21 @@ func NewLiqoProvider(ctx context.Context, cfg *InitConfig, eb record.EventBroadc
 	reflectionManager := manager.New(localClient, remoteClient, localLiqoClient, remoteLiqoClient, cfg.InformerResyncPeriod, eb)
 	podreflector := workload.NewPodReflector(cfg.RemoteConfig, remoteMetricsClient, ipamClient, apiServerSupport, cfg.PodWorkers)
 	namespaceMapHandler := namespacemap.NewHandler(localLiqoClient, cfg.Namespace, cfg.InformerResyncPeriod)
+
+	serviceReflector := exposition.NewServiceReflector(cfg.ServiceWorkers, &cfg.ServiceReflection)
+	endpointSliceReflector := exposition.NewEndpointSliceReflector(ipamClient, cfg.EndpointSliceWorkers, &cfg.EndpointSliceReflection)
+	if cfg.HubConfig != nil {
+		// The EndpointSlices are reflected to the hub as well, as otherwise the reflected Services would have no endpoints.
+		klog.Infof("Reflecting Services and EndpointSlices to the hub cluster at %q", cfg.HubConfig.Host)
+		hubClient, hubLiqoClient := kubernetes.NewForConfigOrDie(cfg.HubConfig), liqoclient.NewForConfigOrDie(cfg.HubConfig)
+		reflectionManager.
+			WithHub(serviceReflector, hubClient, hubLiqoClient).
+			WithHub(endpointSliceReflector, hubClient, hubLiqoClient)
+	} else {
+		reflectionManager.With(serviceReflector).With(endpointSliceReflector)
+	}
+
 	reflectionManager.
-		With(exposition.NewServiceReflector(cfg.ServiceWorkers)).
-		With(exposition.NewEndpointSliceReflector(ipamClient, cfg.EndpointSliceWorkers)).
 		With(exposition.NewIngressReflector(cfg.IngressWorkers)).
 		With(configuration.NewConfigMapReflector(cfg.ConfigMapWorkers)).
 		With(configuration.NewSecretReflector(apiServerSupport == forge.APIServerSupportLegacy, cfg.SecretWorkers)).
@@ -18,6 +18,8 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	discoveryv1 "k8s.io/api/discovery/v1"
@@ -27,6 +29,7 @@ import (
 	"k8s.io/apimachinery/pkg/selection"
 	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
 	discoveryv1clients "k8s.io/client-go/kubernetes/typed/discovery/v1"
 	corev1listers "k8s.io/client-go/listers/core/v1"
 	discoveryv1listers "k8s.io/client-go/listers/discovery/v1"
@@ -42,6 +45,7 @@ import (
 )
 
 var _ manager.NamespacedReflector = (*NamespacedEndpointSliceReflector)(nil)
+var _ manager.NamespacedResyncer = (*NamespacedEndpointSliceReflector)(nil)
 
 const (
 	// EndpointSliceReflectorName -> The name associated with the EndpointSlice reflector.
@@ -59,22 +63,74 @@ type NamespacedEndpointSliceReflector struct {
 
 	ipamclient   ipam.IpamClient
 	translations sync.Map
+
+	// weight is the percentage of local endpoints reflected towards the remote cluster.
+	weight uint
+	// readyOnly, if set, prevents the reflection of the endpoints not ready for at least notReadyGracePeriod.
+	readyOnly           bool
+	notReadyGracePeriod time.Duration
+	notReadySince       sync.Map
+	// aggregate, if set, merges the endpoints of all the local EndpointSlices of a service into a single remote one.
+	aggregate bool
+	// nameMapper transforms the names of the local EndpointSlices and Services into the remote ones.
+	nameMapper forge.NameMapper
+	// deniedPorts are the ports never reflected towards the remote cluster.
+	deniedPorts forge.DeniedPorts
+	// pauser, if set, allows to pause the reflection, while generation tracks the resumes the cached translations refer to.
+	pauser     *generic.ReflectionPauser
+	generation atomic.Uint64
+}
+
+// EndpointSliceReflectorConfig contains the configuration parameters of the EndpointSlice reflector.
+type EndpointSliceReflectorConfig struct {
+	// Weight is the percentage (0-100) of local endpoints reflected towards the remote cluster, to enable gradual
+	// traffic shifting across clusters. Endpoints are sampled deterministically, hence stably across synchronizations.
+	Weight uint
+	// ReadyOnly, if set, restricts the reflection to the ready endpoints only.
+	ReadyOnly bool
+	// NotReadyGracePeriod is the time an endpoint is still reflected (as ready) after becoming not ready, in case
+	// of ReadyOnly reflection, to prevent flapping of the remote endpoints in case of transient failures.
+	NotReadyGracePeriod time.Duration
+	// Aggregate, if set, merges the endpoints of all the EndpointSlices of a given service into a single remote
+	// EndpointSlice, shared with the other clusters reflecting the same service in the same remote namespace (e.g.,
+	// in fan-in scenarios). Each endpoint is tagged with its origin, and duplicated addresses are discarded.
+	Aggregate bool
+	// NameMapper, if set, transforms the names of the local EndpointSlices and of the associated Services into the remote ones
+	// (defaults to the identity). It shall match the one configured for the Service reflector, to preserve the association.
+	NameMapper forge.NameMapper
+	// Pauser, if set, allows to pause the reflection towards the remote cluster, e.g., while its NAT subnets are migrated, as
+	// the reflected endpoints would otherwise refer to stale addresses. Upon resume, the addresses are translated again and re-pushed.
+	Pauser *generic.ReflectionPauser
+	// DeniedPorts are the ports never exposed towards the remote cluster, which are dropped from the reflected EndpointSlices.
+	// They shall match those configured for the Service reflector, which drops them from the reflected Services.
+	DeniedPorts forge.DeniedPorts
 }
 
 // NewEndpointSliceReflector returns a new EndpointSliceReflector instance.
-func NewEndpointSliceReflector(ipamclient ipam.IpamClient, workers uint) manager.Reflector {
-	return generic.NewReflector(EndpointSliceReflectorName, NewNamespacedEndpointSliceReflector(ipamclient), generic.WithoutFallback(), workers)
+func NewEndpointSliceReflector(ipamclient ipam.IpamClient, workers uint, cfg *EndpointSliceReflectorConfig) manager.Reflector {
+	reflector := generic.NewReflector(EndpointSliceReflectorName, NewNamespacedEndpointSliceReflector(ipamclient, cfg), generic.WithoutFallback(), workers)
+
+	// Re-push all the EndpointSlices once the reflection is resumed, to propagate the updated address translations.
+	if resyncer, ok := reflector.(manager.Resyncer); ok && cfg.Pauser != nil {
+		cfg.Pauser.OnResume(func(clusterID string) {
+			if clusterID == forge.RemoteCluster.ClusterID {
+				resyncer.Resync()
+			}
+		})
+	}
+	return reflector
 }
 
 // NewNamespacedEndpointSliceReflector returns a function generating NamespacedEndpointSliceReflector instances.
-func NewNamespacedEndpointSliceReflector(ipamclient ipam.IpamClient) func(*options.NamespacedOpts) manager.NamespacedReflector {
+func NewNamespacedEndpointSliceReflector(ipamclient ipam.IpamClient,
+	cfg *EndpointSliceReflectorConfig) func(*options.NamespacedOpts) manager.NamespacedReflector {
 	return func(opts *options.NamespacedOpts) manager.NamespacedReflector {
 		local := opts.LocalFactory.Discovery().V1().EndpointSlices()
 		remote := opts.RemoteFactory.Discovery().V1().EndpointSlices()
 		localServices := opts.LocalFactory.Core().V1().Services()
 
 		local.Informer().AddEventHandler(opts.HandlerFactory(generic.NamespacedKeyer(opts.LocalNamespace)))
-		remote.Informer().AddEventHandler(opts.HandlerFactory(generic.NamespacedKeyer(opts.LocalNamespace)))
+		remote.Informer().AddEventHandler(opts.HandlerFactory(RemoteKeyer(opts.LocalNamespace)))
 
 		ner := &NamespacedEndpointSliceReflector{
 			NamespacedReflector:        generic.NewNamespacedReflector(opts, EndpointSliceReflectorName),
@@ -83,6 +139,13 @@ func NewNamespacedEndpointSliceReflector(ipamclient ipam.IpamClient) func(*optio
 			remoteEndpointSlices:       remote.Lister().EndpointSlices(opts.RemoteNamespace),
 			remoteEndpointSlicesClient: opts.RemoteClient.DiscoveryV1().EndpointSlices(opts.RemoteNamespace),
 			ipamclient:                 ipamclient,
+			weight:                     cfg.Weight,
+			readyOnly:                  cfg.ReadyOnly,
+			notReadyGracePeriod:        cfg.NotReadyGracePeriod,
+			aggregate:                  cfg.Aggregate,
+			nameMapper:                 cfg.NameMapper,
+			pauser:                     cfg.Pauser,
+			deniedPorts:                cfg.DeniedPorts,
 		}
 
 		// Enqueue all existing remote EndpointSlices in case the local Service has the "skip-reflection" annotation, to ensure they are also deleted.
@@ -102,14 +165,30 @@ func (ner *NamespacedEndpointSliceReflector) Handle(ctx context.Context, name st
 		return nil
 	}
 
+	// Do not perform any remote update while the reflection is paused, as the translations might be stale.
+	// All EndpointSlices are enqueued again once the reflection is resumed.
+	if ner.pauser.Paused(forge.RemoteCluster.ClusterID) {
+		klog.V(4).Infof("Skipping reflection of local EndpointSlice %q as the reflection towards cluster %s is paused",
+			ner.LocalRef(name), forge.RemoteCluster)
+		return nil
+	}
+	ner.discardStaleTranslations()
+
 	// Retrieve the local and remote objects (only not found errors can occur).
-	klog.V(4).Infof("Handling reflection of local EndpointSlice %q (remote: %q)", ner.LocalRef(name), ner.RemoteRef(name))
+	remoteName := ner.nameMapper.RemoteName(name)
+	klog.V(4).Infof("Handling reflection of local EndpointSlice %q (remote: %q)", ner.LocalRef(name), ner.RemoteRef(remoteName))
 	local, lerr := ner.localEndpointSlices.Get(name)
 	utilruntime.Must(client.IgnoreNotFound(lerr))
-	remote, rerr := ner.remoteEndpointSlices.Get(name)
+	remote, rerr := ner.remoteEndpointSlices.Get(remoteName)
 	utilruntime.Must(client.IgnoreNotFound(rerr))
 	tracer.Step("Retrieved the local and remote objects")
 
+	// In aggregation mode, the remote aggregated EndpointSlices do not correspond to any local one, hence we ignore them.
+	if ner.aggregate && rerr == nil && forge.IsEndpointSliceAggregated(remote) {
+		klog.V(4).Infof("Skipping remote aggregated EndpointSlice %q, as not associated with any local EndpointSlice", ner.RemoteRef(remoteName))
+		return nil
+	}
+
 	// Abort the reflection if the remote object is not managed by us, as we do not want to mutate others' objects.
 	if rerr == nil && (!forge.IsReflected(remote) || !forge.IsEndpointSliceManagedByReflection(remote)) {
 		// Prevent misleading warnings triggered by remote non-reflected endpointslices, since they inherit
@@ -141,14 +220,19 @@ func (ner *NamespacedEndpointSliceReflector) Handle(ctx context.Context, name st
 		if err := ner.UnmapEndpointIPs(ctx, name); err != nil {
 			return err
 		}
+		ner.notReadySince.Delete(name)
 
 		defer tracer.Step("Ensured the absence of the remote object")
+		if ner.aggregate {
+			return ner.WithdrawAggregatedEndpoints(ctx, name)
+		}
+
 		if !kerrors.IsNotFound(rerr) {
-			klog.V(4).Infof("Deleting remote EndpointSlice %q, since local %q does no longer exist", ner.RemoteRef(name), ner.LocalRef(name))
-			return ner.DeleteRemote(ctx, ner.remoteEndpointSlicesClient, EndpointSliceReflectorName, name, remote.GetUID())
+			klog.V(4).Infof("Deleting remote EndpointSlice %q, since local %q does no longer exist", ner.RemoteRef(remoteName), ner.LocalRef(name))
+			return ner.DeleteRemote(ctx, ner.remoteEndpointSlicesClient, EndpointSliceReflectorName, remoteName, remote.GetUID())
 		}
 
-		klog.V(4).Infof("Local EndpointSlice %q and remote EndpointSlice %q both vanished", ner.LocalRef(name), ner.RemoteRef(name))
+		klog.V(4).Infof("Local EndpointSlice %q and remote EndpointSlice %q both vanished", ner.LocalRef(name), ner.RemoteRef(remoteName))
 		return nil
 	}
 
@@ -165,10 +249,32 @@ func (ner *NamespacedEndpointSliceReflector) Handle(ctx context.Context, name st
 		return translations
 	}
 
+	// Filter out the endpoints not ready for longer than the grace period, if configured.
+	var recheck time.Duration
+	if ner.readyOnly {
+		// The cache is not synchronized, since we are guaranteed to be the only ones operating on this object.
+		ucache, _ := ner.notReadySince.LoadOrStore(name, map[string]time.Time{})
+		filtered := *local // Shallow copy, as only the endpoints are replaced.
+		filtered.Endpoints, recheck = forge.ReadyEndpointsWithGrace(local.Endpoints, ucache.(map[string]time.Time),
+			ner.notReadyGracePeriod, time.Now())
+		local = &filtered
+	}
+
+	// Filter out the ports never to be exposed towards the remote cluster, if configured.
+	if len(ner.deniedPorts) > 0 {
+		filtered := *local // Shallow copy, as only the ports are replaced.
+		filtered.Ports = forge.AllowedEndpointPorts(local.Ports, ner.deniedPorts)
+		local = &filtered
+	}
+
+	if ner.aggregate {
+		return ner.handleAggregated(ctx, local, translator, &terr, recheck)
+	}
+
 	// Forge the mutation to be applied to the remote cluster.
-	mutation := forge.RemoteEndpointSlice(local, ner.RemoteNamespace(), translator)
+	mutation := forge.RemoteEndpointSlice(local, ner.RemoteNamespace(), translator, ner.PortMapping(local), ner.weight, ner.nameMapper)
 	if terr != nil {
-		klog.Errorf("Reflection of local EndpointSlice %q to %q failed: %v", ner.LocalRef(name), ner.RemoteRef(name), terr)
+		klog.Errorf("Reflection of local EndpointSlice %q to %q failed: %v", ner.LocalRef(name), ner.RemoteRef(remoteName), terr)
 		ner.Event(local, corev1.EventTypeWarning, forge.EventFailedReflection, forge.EventFailedReflectionMsg(terr))
 		return terr
 	}
@@ -177,17 +283,160 @@ func (ner *NamespacedEndpointSliceReflector) Handle(ctx context.Context, name st
 	// Apply the mutation.
 	defer tracer.Step("Enforced the correctness of the remote object")
 	if _, err := ner.remoteEndpointSlicesClient.Apply(ctx, mutation, forge.ApplyOptions()); err != nil {
-		klog.Errorf("Failed to enforce remote EndpointSlice %q (local: %q): %v", ner.RemoteRef(name), ner.LocalRef(name), err)
+		klog.Errorf("Failed to enforce remote EndpointSlice %q (local: %q): %v", ner.RemoteRef(remoteName), ner.LocalRef(name), err)
 		ner.Event(local, corev1.EventTypeWarning, forge.EventFailedReflection, forge.EventFailedReflectionMsg(err))
 		return err
 	}
 
-	klog.Infof("Remote EndpointSlice %q successfully enforced (local: %q)", ner.RemoteRef(name), ner.LocalRef(name))
+	klog.Infof("Remote EndpointSlice %q successfully enforced (local: %q)", ner.RemoteRef(remoteName), ner.LocalRef(name))
 	ner.Event(local, corev1.EventTypeNormal, forge.EventSuccessfulReflection, forge.EventSuccessfulReflectionMsg())
 
+	if recheck > 0 {
+		// Reenqueue the event, to remove the retained not ready endpoints once the grace period expires.
+		klog.V(4).Infof("Scheduling the check of the not ready endpoints of local EndpointSlice %q in %v", ner.LocalRef(name), recheck)
+		return generic.EnqueueAfter(recheck)
+	}
 	return nil
 }
 
+// handleAggregated merges the endpoints of the given local EndpointSlice into the corresponding remote aggregated one.
+func (ner *NamespacedEndpointSliceReflector) handleAggregated(ctx context.Context, local *discoveryv1.EndpointSlice,
+	translator forge.EndpointTranslator, terr *error, recheck time.Duration) error {
+	tracer := trace.FromContext(ctx)
+
+	svcname, ok := local.GetLabels()[discoveryv1.LabelServiceName]
+	if !ok {
+		klog.V(4).Infof("Skipping aggregation of local EndpointSlice %q, as not associated with any service", ner.LocalRef(local.GetName()))
+		return nil
+	}
+
+	name := forge.AggregatedEndpointSliceName(ner.nameMapper.RemoteName(svcname), local.AddressType)
+	remote, rerr := ner.remoteEndpointSlices.Get(name)
+	utilruntime.Must(client.IgnoreNotFound(rerr))
+	if rerr == nil && !forge.IsEndpointSliceAggregated(remote) {
+		klog.Infof("Skipping aggregation of local EndpointSlice %q as remote %q already exists and is not managed by us",
+			ner.LocalRef(local.GetName()), ner.RemoteRef(name))
+		ner.Event(local, corev1.EventTypeWarning, forge.EventFailedReflection, forge.EventFailedReflectionAlreadyExistsMsg())
+		return nil
+	}
+	if rerr != nil {
+		remote = nil
+	}
+
+	// Forge the remote object, merging the local endpoints with those contributed by the other sources.
+	aggregated := forge.AggregatedRemoteEndpointSlice(remote, local, ner.RemoteNamespace(), translator,
+		ner.PortMapping(local), ner.weight, ner.nameMapper)
+	if *terr != nil {
+		klog.Errorf("Aggregation of local EndpointSlice %q into %q failed: %v", ner.LocalRef(local.GetName()), ner.RemoteRef(name), *terr)
+		ner.Event(local, corev1.EventTypeWarning, forge.EventFailedReflection, forge.EventFailedReflectionMsg(*terr))
+		return *terr
+	}
+	tracer.Step("Remote aggregated object forged")
+
+	// The remote object is created or updated (rather than applied), to rely on optimistic concurrency in case
+	// multiple sources concurrently contribute to the same aggregated EndpointSlice. Conflicts trigger a retry.
+	defer tracer.Step("Enforced the correctness of the remote aggregated object")
+	var err error
+	if remote == nil {
+		_, err = ner.remoteEndpointSlicesClient.Create(ctx, aggregated, metav1.CreateOptions{FieldManager: forge.ReflectionFieldManager})
+	} else {
+		_, err = ner.remoteEndpointSlicesClient.Update(ctx, aggregated, metav1.UpdateOptions{FieldManager: forge.ReflectionFieldManager})
+	}
+	if err != nil {
+		klog.Errorf("Failed to enforce remote aggregated EndpointSlice %q (local: %q): %v", ner.RemoteRef(name), ner.LocalRef(local.GetName()), err)
+		ner.Event(local, corev1.EventTypeWarning, forge.EventFailedReflection, forge.EventFailedReflectionMsg(err))
+		return err
+	}
+
+	klog.Infof("Remote aggregated EndpointSlice %q successfully enforced (local: %q)", ner.RemoteRef(name), ner.LocalRef(local.GetName()))
+	ner.Event(local, corev1.EventTypeNormal, forge.EventSuccessfulReflection, forge.EventSuccessfulReflectionMsg())
+
+	if recheck > 0 {
+		klog.V(4).Infof("Scheduling the check of the not ready endpoints of local EndpointSlice %q in %v", ner.LocalRef(local.GetName()), recheck)
+		return generic.EnqueueAfter(recheck)
+	}
+	return nil
+}
+
+// WithdrawAggregatedEndpoints removes the endpoints contributed by the given local EndpointSlice from the remote
+// aggregated ones, deleting the remote EndpointSlices which are left without endpoints.
+func (ner *NamespacedEndpointSliceReflector) WithdrawAggregatedEndpoints(ctx context.Context, endpointslice string) error {
+	remotes, err := ner.remoteEndpointSlices.List(forge.EndpointSliceAggregatedLabels().AsSelectorPreValidated())
+	utilruntime.Must(err)
+
+	for _, remote := range remotes {
+		endpoints := forge.AggregateEndpoints(remote.Endpoints, endpointslice, nil)
+		if len(endpoints) == len(remote.Endpoints) {
+			// No endpoints were contributed by the given local EndpointSlice.
+			continue
+		}
+
+		if len(endpoints) == 0 {
+			klog.V(4).Infof("Deleting remote aggregated EndpointSlice %q, since left without endpoints", ner.RemoteRef(remote.GetName()))
+			// Enforce also the resource version, to prevent deleting the endpoints concurrently contributed by other sources.
+			uid, rv := remote.GetUID(), remote.GetResourceVersion()
+			opts := metav1.DeleteOptions{Preconditions: &metav1.Preconditions{UID: &uid, ResourceVersion: &rv}}
+			if err := ner.remoteEndpointSlicesClient.Delete(ctx, remote.GetName(), opts); client.IgnoreNotFound(err) != nil {
+				klog.Errorf("Failed to delete remote aggregated EndpointSlice %q: %v", ner.RemoteRef(remote.GetName()), err)
+				return err
+			}
+			klog.Infof("Remote aggregated EndpointSlice %q successfully deleted", ner.RemoteRef(remote.GetName()))
+			continue
+		}
+
+		// DeepCopy the remote object, to avoid mutating the cache.
+		updated := remote.DeepCopy()
+		updated.Endpoints = endpoints
+		if _, err := ner.remoteEndpointSlicesClient.Update(ctx, updated, metav1.UpdateOptions{FieldManager: forge.ReflectionFieldManager}); err != nil {
+			klog.Errorf("Failed to withdraw the endpoints of local EndpointSlice %q from remote %q: %v",
+				ner.LocalRef(endpointslice), ner.RemoteRef(remote.GetName()), err)
+			return err
+		}
+		klog.Infof("Endpoints of local EndpointSlice %q withdrawn from remote %q", ner.LocalRef(endpointslice), ner.RemoteRef(remote.GetName()))
+	}
+
+	return nil
+}
+
+// ResyncKeys returns the names of the local EndpointSlices, as well as those the reflected remote EndpointSlices originate from.
+func (ner *NamespacedEndpointSliceReflector) ResyncKeys() []string {
+	keys := sets.NewString()
+
+	locals, err := ner.localEndpointSlices.List(labels.Everything())
+	utilruntime.Must(err)
+	for _, local := range locals {
+		keys.Insert(local.GetName())
+	}
+
+	remotes, err := ner.remoteEndpointSlices.List(labels.Everything())
+	utilruntime.Must(err)
+	for _, remote := range remotes {
+		if forge.IsReflected(remote) && forge.IsEndpointSliceManagedByReflection(remote) && !forge.IsEndpointSliceAggregated(remote) {
+			keys.Insert(forge.SourceName(remote))
+		}
+	}
+
+	return keys.List()
+}
+
+// discardStaleTranslations discards the cached address translations in case the reflection has been resumed (or invalidated) since
+// they have been retrieved, as they might refer to the previous remapping. The stale translations are not released, as the corresponding
+// mappings are expected to be already torn down as part of the migration of the NAT configuration.
+func (ner *NamespacedEndpointSliceReflector) discardStaleTranslations() {
+	current := ner.pauser.Generation(forge.RemoteCluster.ClusterID)
+	cached := ner.generation.Load()
+	if current == cached || !ner.generation.CompareAndSwap(cached, current) {
+		return
+	}
+
+	klog.Infof("Discarding the address translations cached by the %v reflector for local namespace %q, as the remapping might have changed",
+		EndpointSliceReflectorName, ner.LocalNamespace())
+	ner.translations.Range(func(key, _ interface{}) bool {
+		ner.translations.Delete(key)
+		return true
+	})
+}
+
 // MapEndpointIPs maps the local set of addresses to the corresponding remote ones.
 func (ner *NamespacedEndpointSliceReflector) MapEndpointIPs(ctx context.Context, endpointslice string, originals []string) ([]string, error) {
 	var translations []string
@@ -268,6 +517,27 @@ func (ner *NamespacedEndpointSliceReflector) ShouldSkipReflection(obj metav1.Obj
 	return err == nil && ner.NamespacedReflector.ShouldSkipReflection(svc)
 }
 
+// PortMapping returns the port mapping configured for the Service associated with the given EndpointSlice, if any.
+// Invalid mappings are ignored, as already surfaced by the Service reflection.
+func (ner *NamespacedEndpointSliceReflector) PortMapping(obj metav1.Object) forge.PortMapping {
+	svcname, ok := obj.GetLabels()[discoveryv1.LabelServiceName]
+	if !ok {
+		return nil
+	}
+
+	svc, err := ner.localServices.Get(svcname)
+	if err != nil {
+		return nil
+	}
+
+	mapping, err := forge.RemotePortMapping(svc)
+	if err != nil {
+		klog.V(4).Infof("Ignoring invalid port mapping of local Service %q: %v", ner.LocalRef(svcname), err)
+		return nil
+	}
+	return mapping
+}
+
 // ServiceToEndpointSlicesKeyer returns the NamespacedName of all local EndpointSlices associated with the given local Service.
 func (ner *NamespacedEndpointSliceReflector) ServiceToEndpointSlicesKeyer(metadata metav1.Object) []types.NamespacedName {
 	req, err := labels.NewRequirement(discoveryv1.LabelServiceName, selection.Equals, []string{metadata.GetName()})
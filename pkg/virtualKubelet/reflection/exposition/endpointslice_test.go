@@ -30,11 +30,13 @@ import (
 	"k8s.io/client-go/tools/record"
 	"k8s.io/utils/trace"
 
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
 	"github.com/liqotech/liqo/pkg/consts"
 	fakeipam "github.com/liqotech/liqo/pkg/liqonet/ipam/fake"
 	. "github.com/liqotech/liqo/pkg/utils/testutil"
 	"github.com/liqotech/liqo/pkg/virtualKubelet/forge"
 	"github.com/liqotech/liqo/pkg/virtualKubelet/reflection/exposition"
+	"github.com/liqotech/liqo/pkg/virtualKubelet/reflection/generic"
 	"github.com/liqotech/liqo/pkg/virtualKubelet/reflection/manager"
 	"github.com/liqotech/liqo/pkg/virtualKubelet/reflection/options"
 )
@@ -42,7 +44,112 @@ import (
 var _ = Describe("EndpointSlice Reflection Tests", func() {
 	Describe("the NewEndpointSliceReflector function", func() {
 		It("should not return a nil reflector", func() {
-			Expect(exposition.NewEndpointSliceReflector(nil, 1)).ToNot(BeNil())
+			Expect(exposition.NewEndpointSliceReflector(nil, 1, &exposition.EndpointSliceReflectorConfig{})).ToNot(BeNil())
+		})
+	})
+
+	Describe("paused endpointslice reflection", func() {
+		const EndpointSliceName = "paused"
+
+		var (
+			reflector manager.NamespacedReflector
+			ipam      *fakeipam.IPAMClient
+			pauser    *generic.ReflectionPauser
+		)
+
+		Handle := func() {
+			Expect(reflector.Handle(trace.ContextWithTrace(ctx, trace.New("EndpointSlice")), EndpointSliceName)).To(Succeed())
+		}
+
+		RemoteAddresses := func() []string {
+			remote, err := client.DiscoveryV1().EndpointSlices(RemoteNamespace).Get(ctx, EndpointSliceName, metav1.GetOptions{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(remote.Endpoints).To(HaveLen(1))
+			return remote.Endpoints[0].Addresses
+		}
+
+		BeforeEach(func() {
+			local := discoveryv1.EndpointSlice{
+				ObjectMeta:  metav1.ObjectMeta{Name: EndpointSliceName, Namespace: LocalNamespace},
+				AddressType: discoveryv1.AddressTypeIPv4,
+				Endpoints:   []discoveryv1.Endpoint{{Addresses: []string{"192.168.0.25"}}},
+			}
+			_, err := client.DiscoveryV1().EndpointSlices(LocalNamespace).Create(ctx, &local, metav1.CreateOptions{})
+			Expect(err).ToNot(HaveOccurred())
+
+			ipam = fakeipam.NewIPAMClient("192.168.200.0/24", "192.168.201.0/24", true)
+			pauser = generic.NewReflectionPauser()
+		})
+
+		AfterEach(func() {
+			Expect(client.DiscoveryV1().EndpointSlices(LocalNamespace).DeleteCollection(ctx, metav1.DeleteOptions{}, metav1.ListOptions{})).To(Succeed())
+			Expect(client.DiscoveryV1().EndpointSlices(RemoteNamespace).DeleteCollection(ctx, metav1.DeleteOptions{}, metav1.ListOptions{})).To(Succeed())
+		})
+
+		JustBeforeEach(func() {
+			factory := informers.NewSharedInformerFactory(client, 10*time.Hour)
+			reflector = exposition.NewNamespacedEndpointSliceReflector(ipam,
+				&exposition.EndpointSliceReflectorConfig{Weight: forge.EndpointWeightMax, Pauser: pauser})(options.NewNamespaced().
+				WithLocal(LocalNamespace, client, factory).
+				WithRemote(RemoteNamespace, client, factory).
+				WithHandlerFactory(FakeEventHandler).
+				WithEventBroadcaster(record.NewBroadcaster()))
+
+			factory.Start(ctx.Done())
+			factory.WaitForCacheSync(ctx.Done())
+
+			// Reflect the EndpointSlice with the initial remapping.
+			Handle()
+			Expect(RemoteAddresses()).To(ConsistOf("192.168.200.25"))
+		})
+
+		When("the reflection is paused", func() {
+			JustBeforeEach(func() {
+				pauser.PauseReflection(RemoteClusterID)
+				ipam.SetLocalRemappedPodCIDR("192.168.210.0/24")
+				Handle()
+			})
+
+			It("should not update the remote object", func() { Expect(RemoteAddresses()).To(ConsistOf("192.168.200.25")) })
+
+			When("the reflection is resumed", func() {
+				JustBeforeEach(func() {
+					pauser.ResumeReflection(RemoteClusterID)
+					Handle()
+				})
+
+				It("should update the remote object with the new remapping", func() {
+					Expect(RemoteAddresses()).To(ConsistOf("192.168.210.25"))
+				})
+			})
+		})
+
+		When("the remapping configured in the TunnelEndpoint changes", func() {
+			JustBeforeEach(func() {
+				TunnelEndpoint := func(natPodCIDR string) *netv1alpha1.TunnelEndpoint {
+					return &netv1alpha1.TunnelEndpoint{Spec: netv1alpha1.TunnelEndpointSpec{LocalNATPodCIDR: natPodCIDR}}
+				}
+
+				watcher := exposition.NewTunnelRemapWatcher(pauser, RemoteClusterID)
+				watcher.Observe(TunnelEndpoint("192.168.200.0/24"))
+				ipam.SetLocalRemappedPodCIDR("192.168.210.0/24")
+				watcher.Observe(TunnelEndpoint("192.168.210.0/24"))
+				Handle()
+			})
+
+			It("should update the remote object with the new remapping", func() {
+				Expect(RemoteAddresses()).To(ConsistOf("192.168.210.25"))
+			})
+		})
+
+		When("the reflection towards a different cluster is paused", func() {
+			JustBeforeEach(func() {
+				pauser.PauseReflection("other-cluster-id")
+				Expect(client.DiscoveryV1().EndpointSlices(RemoteNamespace).Delete(ctx, EndpointSliceName, metav1.DeleteOptions{})).To(Succeed())
+				Handle()
+			})
+
+			It("should keep reflecting the object", func() { Expect(RemoteAddresses()).To(ConsistOf("192.168.200.25")) })
 		})
 	})
 
@@ -51,8 +158,9 @@ var _ = Describe("EndpointSlice Reflection Tests", func() {
 		const ServiceName = "service"
 
 		var (
-			reflector manager.NamespacedReflector
-			ipam      *fakeipam.IPAMClient
+			reflector       manager.NamespacedReflector
+			ipam            *fakeipam.IPAMClient
+			remoteNamespace string
 
 			local, remote discoveryv1.EndpointSlice
 			err           error
@@ -97,6 +205,7 @@ var _ = Describe("EndpointSlice Reflection Tests", func() {
 		BeforeEach(func() {
 			local = discoveryv1.EndpointSlice{ObjectMeta: metav1.ObjectMeta{Name: EndpointSliceName, Namespace: LocalNamespace}}
 			remote = discoveryv1.EndpointSlice{ObjectMeta: metav1.ObjectMeta{Name: EndpointSliceName, Namespace: RemoteNamespace}}
+			remoteNamespace = RemoteNamespace
 		})
 
 		AfterEach(func() {
@@ -106,14 +215,19 @@ var _ = Describe("EndpointSlice Reflection Tests", func() {
 				Or(BeNil(), WithTransform(kerrors.IsNotFound, BeTrue())))
 			Expect(client.CoreV1().Services(LocalNamespace).Delete(ctx, ServiceName, metav1.DeleteOptions{})).To(
 				Or(BeNil(), WithTransform(kerrors.IsNotFound, BeTrue())))
+			Expect(client.DiscoveryV1().EndpointSlices(HubNamespace).DeleteCollection(ctx, metav1.DeleteOptions{}, metav1.ListOptions{})).To(
+				Or(BeNil(), WithTransform(kerrors.IsNotFound, BeTrue())))
+			Expect(client.CoreV1().Services(HubNamespace).Delete(ctx, ServiceName, metav1.DeleteOptions{})).To(
+				Or(BeNil(), WithTransform(kerrors.IsNotFound, BeTrue())))
 		})
 
 		JustBeforeEach(func() {
 			ipam = fakeipam.NewIPAMClient("192.168.200.0/24", "192.168.201.0/24", true)
 			factory := informers.NewSharedInformerFactory(client, 10*time.Hour)
-			reflector = exposition.NewNamespacedEndpointSliceReflector(ipam)(options.NewNamespaced().
+			reflector = exposition.NewNamespacedEndpointSliceReflector(ipam,
+				&exposition.EndpointSliceReflectorConfig{Weight: forge.EndpointWeightMax})(options.NewNamespaced().
 				WithLocal(LocalNamespace, client, factory).
-				WithRemote(RemoteNamespace, client, factory).
+				WithRemote(remoteNamespace, client, factory).
 				WithHandlerFactory(FakeEventHandler).
 				WithEventBroadcaster(record.NewBroadcaster()))
 
@@ -202,6 +316,40 @@ var _ = Describe("EndpointSlice Reflection Tests", func() {
 				})
 			})
 
+			When("the local object does exist, and is reflected to a hub cluster alongside its Service", func() {
+				BeforeEach(func() {
+					// The hub cluster is emulated through a separate namespace, as the destination is transparent to the reflector.
+					remoteNamespace = HubNamespace
+					spec := corev1.ServiceSpec{Ports: []corev1.ServicePort{{Name: "http", Port: 80, TargetPort: intstr.FromInt(8080)}}}
+					CreateService(&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: ServiceName, Namespace: LocalNamespace}, Spec: spec})
+					// The Service reflected to the hub, as created by the Service reflector targeting the same hub.
+					CreateService(&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: ServiceName, Namespace: HubNamespace,
+						Labels: labels.Merge(forge.ReflectionLabels(), map[string]string{forge.LiqoOriginClusterIDKey: LocalClusterID})}, Spec: spec})
+
+					local.Labels = map[string]string{discoveryv1.LabelServiceName: ServiceName}
+					local.AddressType = discoveryv1.AddressTypeIPv4
+					local.Endpoints = []discoveryv1.Endpoint{{Addresses: []string{"192.168.0.25"}}}
+					CreateEndpointSlice(&local)
+				})
+
+				It("should succeed", func() { Expect(err).ToNot(HaveOccurred()) })
+				It("the object should have been reflected to the hub, and associated with the hub Service", func() {
+					hubAfter := GetEndpointSlice(HubNamespace)
+					Expect(hubAfter.Labels).To(HaveKeyWithValue(discoveryv1.LabelServiceName, ServiceName))
+					Expect(hubAfter.Labels).To(HaveKeyWithValue(forge.LiqoOriginClusterIDKey, LocalClusterID))
+					Expect(hubAfter.Endpoints).To(HaveLen(1))
+					Expect(hubAfter.Endpoints[0].Addresses).To(ConsistOf("192.168.200.25"))
+
+					hubService, err := client.CoreV1().Services(HubNamespace).Get(ctx, hubAfter.Labels[discoveryv1.LabelServiceName], metav1.GetOptions{})
+					Expect(err).ToNot(HaveOccurred())
+					Expect(hubService.Labels).To(HaveKeyWithValue(forge.LiqoOriginClusterIDKey, LocalClusterID))
+				})
+				It("the object should not have been reflected to the remote cluster", func() {
+					_, err = client.DiscoveryV1().EndpointSlices(RemoteNamespace).Get(ctx, EndpointSliceName, metav1.GetOptions{})
+					Expect(err).To(BeNotFound())
+				})
+			})
+
 			When("the local object does exist, but has the skip annotation", func() {
 				BeforeEach(func() {
 					local.SetAnnotations(map[string]string{consts.SkipReflectionAnnotationKey: "whatever"})
@@ -35,6 +35,7 @@ import (
 const (
 	LocalNamespace  = "local-namespace"
 	RemoteNamespace = "remote-namespace"
+	HubNamespace    = "hub-namespace"
 
 	LocalClusterID    = "local-cluster-id"
 	LocalClusterName  = "local-cluster-name"
@@ -73,6 +74,8 @@ var _ = BeforeSuite(func() {
 	Expect(err).ToNot(HaveOccurred())
 	_, err = client.CoreV1().Namespaces().Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: RemoteNamespace}}, metav1.CreateOptions{})
 	Expect(err).ToNot(HaveOccurred())
+	_, err = client.CoreV1().Namespaces().Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: HubNamespace}}, metav1.CreateOptions{})
+	Expect(err).ToNot(HaveOccurred())
 
 	local := discoveryv1alpha1.ClusterIdentity{ClusterID: LocalClusterID, ClusterName: LocalClusterName}
 	remote := discoveryv1alpha1.ClusterIdentity{ClusterID: RemoteClusterID, ClusterName: RemoteClusterName}
@@ -0,0 +1,39 @@
+// Copyright 2019-2023 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exposition
+
+import (
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// OffloadingSelectorSource provides the offloading intent driving the Service reflection (e.g., derived from a custom
+// resource modeling which Services shall be offloaded), as an alternative to reflecting all the Services of a namespace.
+// The reflector does not watch the source for changes: it is up to the caller to trigger a resync of the reflector
+// (see manager.Resyncer) whenever the intent changes, or to configure a periodic resync.
+type OffloadingSelectorSource interface {
+	// OffloadingSelector returns the label selector the Services of the given local namespace shall match to be
+	// reflected, and false in case no Service of that namespace shall be reflected. A nil selector matches everything.
+	OffloadingSelector(namespace string) (selector labels.Selector, offloaded bool)
+}
+
+// StaticOffloadingSelectors is an OffloadingSelectorSource mapping each local namespace to the label selector the
+// corresponding Services shall match to be reflected, while those of the namespaces not present are never reflected.
+type StaticOffloadingSelectors map[string]labels.Selector
+
+// OffloadingSelector returns the label selector associated with the given namespace, if any.
+func (s StaticOffloadingSelectors) OffloadingSelector(namespace string) (selector labels.Selector, offloaded bool) {
+	selector, offloaded = s[namespace]
+	return selector, offloaded
+}
@@ -0,0 +1,61 @@
+// Copyright 2019-2023 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exposition
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog/v2"
+)
+
+// DefaultMutationRetries is the default number of times a remote mutation failed due to a transient error is retried.
+const DefaultMutationRetries = 3
+
+// mutationRetryBackoff returns the backoff used to retry the remote mutations failed due to transient errors,
+// performing at most the given number of retries (in addition to the first attempt).
+func mutationRetryBackoff(retries uint) wait.Backoff {
+	return wait.Backoff{Steps: int(retries) + 1, Duration: 100 * time.Millisecond, Factor: 2, Jitter: 0.1}
+}
+
+// isTransientError returns whether the given error is expected to be momentary (e.g., timeouts and conflicts),
+// hence worth retrying the corresponding operation rather than waiting for the next event.
+func isTransientError(err error) bool {
+	return kerrors.IsConflict(err) || kerrors.IsServerTimeout(err) || kerrors.IsTimeout(err) ||
+		kerrors.IsTooManyRequests(err) || kerrors.IsServiceUnavailable(err) || kerrors.IsInternalError(err)
+}
+
+// isUnreachableError returns whether the given error originates from the impossibility to reach the API server
+// (e.g., connection refused or DNS failures), rather than being returned by the API server itself.
+func isUnreachableError(err error) bool {
+	var status kerrors.APIStatus
+	return err != nil && !errors.As(err, &status) && !errors.Is(err, context.Canceled)
+}
+
+// retryOnTransientError executes the given remote mutation, retrying it up to the given number of times
+// in case it fails due to a transient error. Any other error is returned immediately.
+func retryOnTransientError(retries uint, description string, mutation func() error) error {
+	return retry.OnError(mutationRetryBackoff(retries), func(err error) bool {
+		if !isTransientError(err) {
+			return false
+		}
+		klog.Warningf("Failed to %s, retrying: %v", description, err)
+		return true
+	}, mutation)
+}
@@ -16,16 +16,27 @@ package exposition
 
 import (
 	"context"
+	"fmt"
+	"sync"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
 	corev1clients "k8s.io/client-go/kubernetes/typed/core/v1"
 	corev1listers "k8s.io/client-go/listers/core/v1"
 	"k8s.io/klog/v2"
+	"k8s.io/utils/strings/slices"
 	"k8s.io/utils/trace"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
+	"github.com/liqotech/liqo/pkg/consts"
 	"github.com/liqotech/liqo/pkg/virtualKubelet/forge"
 	"github.com/liqotech/liqo/pkg/virtualKubelet/reflection/generic"
 	"github.com/liqotech/liqo/pkg/virtualKubelet/reflection/manager"
@@ -33,6 +44,8 @@ import (
 )
 
 var _ manager.NamespacedReflector = (*NamespacedServiceReflector)(nil)
+var _ manager.NamespacedResyncer = (*NamespacedServiceReflector)(nil)
+var _ manager.NamespacedStopper = (*NamespacedServiceReflector)(nil)
 
 const (
 	// ServiceReflectorName -> The name associated with the Service reflector.
@@ -48,27 +61,167 @@ type NamespacedServiceReflector struct {
 
 	localServices        corev1listers.ServiceNamespaceLister
 	remoteServices       corev1listers.ServiceNamespaceLister
+	localServicesClient  corev1clients.ServiceInterface
 	remoteServicesClient corev1clients.ServiceInterface
+	localServicesGetter  corev1clients.ServicesGetter
+
+	// namespaceAllowed is false if the local namespace is not part of the allowlist configured for the remote cluster.
+	namespaceAllowed bool
+	// namespaceExcluded is true if the local namespace is one of those excluded from the reflection (e.g., system ones).
+	namespaceExcluded bool
+	// allowedTypes, if not empty, restricts the types of the Services which are reflected.
+	allowedTypes []corev1.ServiceType
+	// offloadingSelectors, if set, restricts the reflected Services to those selected by the offloading intent.
+	offloadingSelectors OffloadingSelectorSource
+	// forgingOpts are the options driving the forging of the remote Services.
+	forgingOpts forge.RemoteServiceOptions
+	// ttl, if positive, is the duration after which the remote Services not refreshed are considered stale.
+	ttl time.Duration
+	// mutationRetries is the number of times the remote mutations failed due to transient errors are retried.
+	mutationRetries uint
+	// teardownFinalizer, if set, is the finalizer added to the reflected local Services, to delay their deletion
+	// until the remote ones have been removed.
+	teardownFinalizer string
+
+	// remoteNamespacesClient is set only if the remote namespace shall be created when not already present.
+	remoteNamespacesClient corev1clients.NamespaceInterface
+	remoteNamespaceMutex   sync.Mutex
+	remoteNamespaceExists  bool
+	// remoteNamespaceLabels are the additional labels set on the remote namespace, in case it is created.
+	remoteNamespaceLabels map[string]string
+}
+
+// ServiceReflectorConfig contains the configuration parameters of the Service reflector.
+type ServiceReflectorConfig struct {
+	// AllowedNamespaces, if not empty, restricts the set of local namespaces whose Services are reflected towards the remote cluster.
+	AllowedNamespaces []string
+	// ExcludedNamespaces are the local namespaces whose Services are never reflected, as they would collide with the remote
+	// ones or leak infrastructure details. Defaults to DefaultExcludedNamespaces if nil, while an empty list disables the exclusion.
+	ExcludedNamespaces []string
+	// AllowedTypes, if not empty, restricts the types of the reflected Services.
+	AllowedTypes []corev1.ServiceType
+	// OffloadingSelectors, if set, restricts the reflected Services to those matching the selectors provided by the source,
+	// to react to an externally modeled offloading intent rather than reflecting all the Services of the allowed namespaces.
+	OffloadingSelectors OffloadingSelectorSource
+	// CreateRemoteNamespace enables the creation of the remote namespace, in case it does not already exist.
+	// It shall be disabled if namespaces are managed externally.
+	CreateRemoteNamespace bool
+	// RemoteNamespaceLabels are the additional labels (e.g., PodSecurity admission or governance ones) set on the remote
+	// namespace, in case it is created. Already existing namespaces are never modified.
+	RemoteNamespaceLabels map[string]string
+	// RemoteIPFamilies, if not empty, are the IP families supported by the remote cluster. The IP families of the
+	// reflected Services are normalized accordingly, to prevent dual-stack Services from failing in single-stack clusters.
+	RemoteIPFamilies []corev1.IPFamily
+	// ExternalIPsPolicy is the policy used to handle the external IPs of the reflected Services, which typically
+	// refer to the local cluster, and are thus meaningless remotely (defaults to stripping them).
+	ExternalIPsPolicy forge.ExternalIPsPolicy
+	// ExternalIPsMapping maps the local external IPs to the remote ones, in case of the Remap policy.
+	ExternalIPsMapping map[string]string
+	// TTL, if positive, is the duration after which the reflected Services not refreshed are considered stale, and thus
+	// removed by the remote cluster (e.g., if the local cluster went offline without unpeering). The reflected Services
+	// are periodically refreshed accordingly, with a period equal to half of the TTL.
+	TTL time.Duration
+	// NameMapper, if set, transforms the names of the local Services into those of the remote ones (defaults to the identity).
+	// It shall match the one configured for the EndpointSlice reflector, for the reflected EndpointSlices to be associated with
+	// the correct Services. Ingresses referring to the reflected Services are not adapted, and still refer to the local names.
+	NameMapper forge.NameMapper
+	// MaxAnnotationSize, if positive, is the maximum size (key plus value, in bytes) of the annotations of the local Services
+	// which are reflected, while the larger ones (e.g., the last applied configuration) are pruned to prevent bloating the
+	// remote cluster. The managed fields and the other cluster-specific metadata are never reflected, regardless of this setting.
+	MaxAnnotationSize int
+	// DeniedPorts are the ports never exposed towards the remote cluster (e.g., SSH and management ones), which are dropped
+	// from the reflected Services. They shall match those configured for the EndpointSlice reflector.
+	DeniedPorts forge.DeniedPorts
+	// ClusterDomain is the domain of the local cluster, used to annotate the reflected Services with the FQDN of the local
+	// ones, as a hint for the remote consumers (defaults to cluster.local).
+	ClusterDomain string
+	// MutationRetries is the number of times the remote mutations (i.e., the enforcement of the reflected Services, and the
+	// creation of the remote namespace) failed due to transient errors (e.g., timeouts and conflicts) are retried with an
+	// exponential backoff, before giving up until the next event (default: disabled).
+	MutationRetries uint
+	// ResyncInterval, if positive, is the period the local Services are reconciled with, regardless of the received events, to
+	// ensure the remote ones match even if events were missed (e.g., creating the missing, and deleting the orphaned ones).
+	ResyncInterval time.Duration
+	// TeardownFinalizer, if set, is the finalizer (e.g., liqo.io/service-teardown) added to the reflected local Services, so that
+	// their deletion completes only after the removal of the remote ones has been confirmed. It is suffixed with the ID of the
+	// remote cluster, as each virtual kubelet releases its own. The finalizers are unconditionally released when the reflection
+	// of a namespace stops, when the virtual kubelet terminates (through ReleaseTeardownFinalizers), and when the remote cluster
+	// cannot be reached during the teardown, as the remote cleanup could not be confirmed otherwise.
+	TeardownFinalizer string
+}
+
+// RemoteKeyer returns a keyer associating the remote objects with the local ones they originate from,
+// taking into account the possible transformation of their names.
+func RemoteKeyer(namespace string) func(metadata metav1.Object) []types.NamespacedName {
+	return func(metadata metav1.Object) []types.NamespacedName {
+		return []types.NamespacedName{{Namespace: namespace, Name: forge.SourceName(metadata)}}
+	}
+}
+
+// DefaultExcludedNamespaces are the local namespaces whose Services are not reflected by default.
+var DefaultExcludedNamespaces = []string{metav1.NamespaceSystem, metav1.NamespacePublic, corev1.NamespaceNodeLease, consts.DefaultLiqoNamespace}
+
+// teardownFinalizer returns the finalizer added to the reflected local Services, if configured.
+func (cfg *ServiceReflectorConfig) teardownFinalizer() string {
+	if cfg.TeardownFinalizer == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s-%s", cfg.TeardownFinalizer, forge.RemoteCluster.ClusterID)
+}
+
+// excludedNamespaces returns the local namespaces whose Services shall not be reflected.
+func (cfg *ServiceReflectorConfig) excludedNamespaces() []string {
+	if cfg.ExcludedNamespaces == nil {
+		return DefaultExcludedNamespaces
+	}
+	return cfg.ExcludedNamespaces
 }
 
 // NewServiceReflector returns a new ServiceReflector instance.
-func NewServiceReflector(workers uint) manager.Reflector {
-	return generic.NewReflector(ServiceReflectorName, NewNamespacedServiceReflector, generic.WithoutFallback(), workers)
+func NewServiceReflector(workers uint, cfg *ServiceReflectorConfig) manager.Reflector {
+	return generic.NewResyncingReflector(ServiceReflectorName, NewNamespacedServiceReflector(cfg), generic.WithoutFallback(),
+		workers, cfg.ResyncInterval)
 }
 
-// NewNamespacedServiceReflector returns a new NamespacedServiceReflector instance.
-func NewNamespacedServiceReflector(opts *options.NamespacedOpts) manager.NamespacedReflector {
-	local := opts.LocalFactory.Core().V1().Services()
-	remote := opts.RemoteFactory.Core().V1().Services()
+// NewNamespacedServiceReflector returns a function generating NamespacedServiceReflector instances.
+func NewNamespacedServiceReflector(cfg *ServiceReflectorConfig) func(*options.NamespacedOpts) manager.NamespacedReflector {
+	return func(opts *options.NamespacedOpts) manager.NamespacedReflector {
+		local := opts.LocalFactory.Core().V1().Services()
+		remote := opts.RemoteFactory.Core().V1().Services()
 
-	local.Informer().AddEventHandler(opts.HandlerFactory(generic.NamespacedKeyer(opts.LocalNamespace)))
-	remote.Informer().AddEventHandler(opts.HandlerFactory(generic.NamespacedKeyer(opts.LocalNamespace)))
+		local.Informer().AddEventHandler(opts.HandlerFactory(generic.NamespacedKeyer(opts.LocalNamespace)))
+		remote.Informer().AddEventHandler(opts.HandlerFactory(RemoteKeyer(opts.LocalNamespace)))
 
-	return &NamespacedServiceReflector{
-		NamespacedReflector:  generic.NewNamespacedReflector(opts, ServiceReflectorName),
-		localServices:        local.Lister().Services(opts.LocalNamespace),
-		remoteServices:       remote.Lister().Services(opts.RemoteNamespace),
-		remoteServicesClient: opts.RemoteClient.CoreV1().Services(opts.RemoteNamespace),
+		reflector := &NamespacedServiceReflector{
+			NamespacedReflector:  generic.NewNamespacedReflector(opts, ServiceReflectorName),
+			localServices:        local.Lister().Services(opts.LocalNamespace),
+			remoteServices:       remote.Lister().Services(opts.RemoteNamespace),
+			localServicesClient:  opts.LocalClient.CoreV1().Services(opts.LocalNamespace),
+			remoteServicesClient: opts.RemoteClient.CoreV1().Services(opts.RemoteNamespace),
+			localServicesGetter:  opts.LocalClient.CoreV1(),
+			namespaceAllowed:     forge.IsNamespaceAllowed(opts.LocalNamespace, cfg.AllowedNamespaces),
+			namespaceExcluded:    slices.Contains(cfg.excludedNamespaces(), opts.LocalNamespace),
+			allowedTypes:         cfg.AllowedTypes,
+			offloadingSelectors:  cfg.OffloadingSelectors,
+			ttl:                  cfg.TTL,
+			mutationRetries:      cfg.MutationRetries,
+			teardownFinalizer:    cfg.teardownFinalizer(),
+			forgingOpts: forge.RemoteServiceOptions{
+				RemoteIPFamilies:   cfg.RemoteIPFamilies,
+				ExternalIPsPolicy:  cfg.ExternalIPsPolicy,
+				ExternalIPsMapping: cfg.ExternalIPsMapping,
+				NameMapper:         cfg.NameMapper,
+				MaxAnnotationSize:  cfg.MaxAnnotationSize,
+				DeniedPorts:        cfg.DeniedPorts,
+				ClusterDomain:      cfg.ClusterDomain,
+			},
+		}
+
+		if cfg.CreateRemoteNamespace {
+			reflector.remoteNamespacesClient = opts.RemoteClient.CoreV1().Namespaces()
+			reflector.remoteNamespaceLabels = cfg.RemoteNamespaceLabels
+		}
+		return reflector
 	}
 }
 
@@ -83,10 +236,11 @@ func (nsr *NamespacedServiceReflector) Handle(ctx context.Context, name string)
 	}
 
 	// Retrieve the local and remote objects (only not found errors can occur).
-	klog.V(4).Infof("Handling reflection of local Service %q (remote: %q)", nsr.LocalRef(name), nsr.RemoteRef(name))
+	remoteName := nsr.forgingOpts.NameMapper.RemoteName(name)
+	klog.V(4).Infof("Handling reflection of local Service %q (remote: %q)", nsr.LocalRef(name), nsr.RemoteRef(remoteName))
 	local, lerr := nsr.localServices.Get(name)
 	utilruntime.Must(client.IgnoreNotFound(lerr))
-	remote, rerr := nsr.remoteServices.Get(name)
+	remote, rerr := nsr.remoteServices.Get(remoteName)
 	utilruntime.Must(client.IgnoreNotFound(rerr))
 	tracer.Step("Retrieved the local and remote objects")
 
@@ -96,18 +250,27 @@ func (nsr *NamespacedServiceReflector) Handle(ctx context.Context, name string)
 			klog.Infof("Skipping reflection of local Service %q as remote already exists and is not managed by us", nsr.LocalRef(name))
 			nsr.Event(local, corev1.EventTypeWarning, forge.EventFailedReflection, forge.EventFailedReflectionAlreadyExistsMsg())
 		}
-		return nil
+		// The remote object is not ours, hence there is nothing to clean up.
+		return nsr.releaseTeardownFinalizer(ctx, local)
 	}
 
-	// Abort the reflection if the local object has the "skip-reflection" annotation.
-	if !kerrors.IsNotFound(lerr) && nsr.ShouldSkipReflection(local) {
-		klog.Infof("Skipping reflection of local Service %q as marked with the skip annotation", nsr.LocalRef(name))
-		nsr.Event(local, corev1.EventTypeNormal, forge.EventReflectionDisabled, forge.EventObjectReflectionDisabledMsg())
-		if kerrors.IsNotFound(rerr) { // The remote object does not already exist, hence no further action is required.
-			return nil
+	// Abort the reflection if the local object has the "skip-reflection" annotation,
+	// or if it is excluded by the reflection policy configured for the remote cluster.
+	if !kerrors.IsNotFound(lerr) {
+		if reason, msg, skip := nsr.skipReflection(local); skip {
+			klog.Infof("Skipping reflection of local Service %q as %s", nsr.LocalRef(name), reason)
+			nsr.Event(local, corev1.EventTypeNormal, forge.EventReflectionDisabled, msg)
+			if kerrors.IsNotFound(rerr) { // The remote object does not already exist, hence no further action is required.
+				return nsr.releaseTeardownFinalizer(ctx, local)
+			}
+
+			// Otherwise, let pretend the local object does not exist, so that the remote one gets deleted.
+			lerr = kerrors.NewNotFound(corev1.Resource("service"), local.GetName())
 		}
+	}
 
-		// Otherwise, let pretend the local object does not exist, so that the remote one gets deleted.
+	// The local service is being deleted (i.e., it is only retained by the finalizers). Handle it as if it did no longer exist.
+	if lerr == nil && !local.GetDeletionTimestamp().IsZero() {
 		lerr = kerrors.NewNotFound(corev1.Resource("service"), local.GetName())
 	}
 
@@ -117,27 +280,265 @@ func (nsr *NamespacedServiceReflector) Handle(ctx context.Context, name string)
 	if kerrors.IsNotFound(lerr) {
 		defer tracer.Step("Ensured the absence of the remote object")
 		if !kerrors.IsNotFound(rerr) {
-			klog.V(4).Infof("Deleting remote Service %q, since local %q does no longer exist", nsr.RemoteRef(name), nsr.LocalRef(name))
-			return nsr.DeleteRemote(ctx, nsr.remoteServicesClient, ServiceReflectorName, name, remote.GetUID())
+			// The teardown finalizer is released once the deletion is observed, which triggers a new reconciliation.
+			klog.V(4).Infof("Deleting remote Service %q, since local %q does no longer exist", nsr.RemoteRef(remoteName), nsr.LocalRef(name))
+			err := nsr.DeleteRemote(ctx, nsr.remoteServicesClient, ServiceReflectorName, remoteName, remote.GetUID())
+			if err != nil && isUnreachableError(err) && controllerutil.ContainsFinalizer(local, nsr.teardownFinalizer) {
+				// The remote cleanup cannot be confirmed, hence do not prevent the deletion of the local Service indefinitely.
+				klog.Warningf("Skipping the cleanup of remote Service %q, as the remote cluster cannot be reached", nsr.RemoteRef(remoteName))
+				nsr.Event(local, corev1.EventTypeWarning, forge.EventFailedReflection, forge.EventFailedReflectionMsg(err))
+				return nsr.releaseTeardownFinalizer(ctx, local)
+			}
+			return err
 		}
 
-		klog.V(4).Infof("Local Service %q and remote Service %q both vanished", nsr.LocalRef(name), nsr.RemoteRef(name))
+		klog.V(4).Infof("Local Service %q and remote Service %q both vanished", nsr.LocalRef(name), nsr.RemoteRef(remoteName))
+		return nsr.releaseTeardownFinalizer(ctx, local)
+	}
+
+	// Abort the reflection if the port mapping annotation is invalid, since the remote object would not match the expectations.
+	// No retry is performed, as the local object needs to be fixed (which will trigger a new reconciliation).
+	if _, err := forge.RemotePortMapping(local); err != nil {
+		klog.Errorf("Reflection of local Service %q to %q failed: %v", nsr.LocalRef(name), nsr.RemoteRef(remoteName), err)
+		nsr.Event(local, corev1.EventTypeWarning, forge.EventFailedReflection, forge.EventFailedReflectionMsg(err))
 		return nil
 	}
 
+	// Ensure the remote namespace exists, if configured to create it.
+	if err := nsr.ensureRemoteNamespace(ctx); err != nil {
+		klog.Errorf("Reflection of local Service %q to %q failed: %v", nsr.LocalRef(name), nsr.RemoteRef(remoteName), err)
+		nsr.Event(local, corev1.EventTypeWarning, forge.EventFailedReflection, forge.EventFailedReflectionMsg(err))
+		return err
+	}
+
+	// Ensure the teardown finalizer is present before the remote object is created, for it not to be leaked.
+	if err := nsr.ensureTeardownFinalizer(ctx, local); err != nil {
+		klog.Errorf("Reflection of local Service %q to %q failed: %v", nsr.LocalRef(name), nsr.RemoteRef(remoteName), err)
+		nsr.Event(local, corev1.EventTypeWarning, forge.EventFailedReflection, forge.EventFailedReflectionMsg(err))
+		return err
+	}
+
 	// Forge the mutation to be applied to the remote cluster.
-	mutation := forge.RemoteService(local, nsr.RemoteNamespace())
+	mutation := forge.RemoteService(local, nsr.RemoteNamespace(), &nsr.forgingOpts)
+	now := time.Now()
+	if nsr.ttl > 0 {
+		forge.RemoteServiceWithTTL(mutation, nsr.ttl, now)
+	}
 	tracer.Step("Remote mutation created")
 
+	// Skip the update if the reflected fields did not change since the last time they have been enforced.
+	if rerr == nil && forge.RemoteServiceUpToDate(remote, mutation) && !nsr.refreshNeeded(remote, now) {
+		klog.V(4).Infof("Skipping reflection of local Service %q as remote %q is already up-to-date", nsr.LocalRef(name), nsr.RemoteRef(remoteName))
+		return nsr.scheduleRefresh()
+	}
+
+	if pruned := forge.OversizedAnnotations(local.GetAnnotations(), nsr.forgingOpts.MaxAnnotationSize); len(pruned) > 0 {
+		klog.Infof("Pruned oversized annotations %v while reflecting local Service %q (max size: %d bytes)",
+			pruned, nsr.LocalRef(name), nsr.forgingOpts.MaxAnnotationSize)
+	}
+
 	defer tracer.Step("Enforced the correctness of the remote object")
-	if _, err := nsr.remoteServicesClient.Apply(ctx, mutation, forge.ApplyOptions()); err != nil {
-		klog.Errorf("Failed to enforce remote Service %q (local: %q): %v", nsr.RemoteRef(name), nsr.LocalRef(name), err)
+	if err := retryOnTransientError(nsr.mutationRetries, fmt.Sprintf("enforce remote Service %q", nsr.RemoteRef(remoteName)), func() error {
+		_, err := nsr.remoteServicesClient.Apply(ctx, mutation, forge.ApplyOptions())
+		return err
+	}); err != nil {
+		klog.Errorf("Failed to enforce remote Service %q (local: %q): %v", nsr.RemoteRef(remoteName), nsr.LocalRef(name), err)
 		nsr.Event(local, corev1.EventTypeWarning, forge.EventFailedReflection, forge.EventFailedReflectionMsg(err))
 		return err
 	}
 
-	klog.Infof("Remote Service %q successfully enforced (local: %q)", nsr.RemoteRef(name), nsr.LocalRef(name))
+	klog.Infof("Remote Service %q successfully enforced (local: %q)", nsr.RemoteRef(remoteName), nsr.LocalRef(name))
 	nsr.Event(local, corev1.EventTypeNormal, forge.EventSuccessfulReflection, forge.EventSuccessfulReflectionMsg())
 
+	return nsr.scheduleRefresh()
+}
+
+// ResyncKeys returns the names of the local Services, as well as those the reflected remote Services originate from,
+// so that the missing remote Services are created and the orphaned ones are deleted.
+func (nsr *NamespacedServiceReflector) ResyncKeys() []string {
+	keys := sets.NewString()
+
+	locals, err := nsr.localServices.List(labels.Everything())
+	utilruntime.Must(err)
+	for _, local := range locals {
+		keys.Insert(local.GetName())
+	}
+
+	remotes, err := nsr.remoteServices.List(labels.Everything())
+	utilruntime.Must(err)
+	for _, remote := range remotes {
+		if forge.IsReflected(remote) {
+			keys.Insert(forge.SourceName(remote))
+		}
+	}
+
+	return keys.List()
+}
+
+// refreshNeeded returns whether the given remote Service shall be refreshed, to prevent it from becoming stale.
+// The refresh is performed once half of the TTL elapsed, to tolerate possible delays.
+func (nsr *NamespacedServiceReflector) refreshNeeded(remote *corev1.Service, now time.Time) bool {
+	if nsr.ttl <= 0 {
+		return false
+	}
+
+	ttl, found := forge.ReflectionTTL(remote)
+	if !found || ttl != nsr.ttl {
+		return true
+	}
+	lastSync, found := forge.ReflectionLastSync(remote)
+	return !found || now.Sub(lastSync) >= nsr.ttl/2
+}
+
+// scheduleRefresh returns the error to reenqueue the current Service for the refresh, if a TTL is configured.
+func (nsr *NamespacedServiceReflector) scheduleRefresh() error {
+	if nsr.ttl <= 0 {
+		return nil
+	}
+	return generic.EnqueueAfter(nsr.ttl / 2)
+}
+
+// ensureRemoteNamespace creates the remote namespace, in case it does not already exist and the reflector is configured to do so.
+// Already existing namespaces are never modified, as possibly managed by third parties.
+func (nsr *NamespacedServiceReflector) ensureRemoteNamespace(ctx context.Context) error {
+	if nsr.remoteNamespacesClient == nil {
+		return nil
+	}
+
+	nsr.remoteNamespaceMutex.Lock()
+	defer nsr.remoteNamespaceMutex.Unlock()
+
+	if nsr.remoteNamespaceExists {
+		return nil
+	}
+
+	_, err := nsr.remoteNamespacesClient.Get(ctx, nsr.RemoteNamespace(), metav1.GetOptions{})
+	if kerrors.IsNotFound(err) {
+		err = retryOnTransientError(nsr.mutationRetries, fmt.Sprintf("create remote namespace %q", nsr.RemoteNamespace()), func() error {
+			_, err := nsr.remoteNamespacesClient.Create(ctx, forge.RemoteNamespace(nsr.RemoteNamespace(), nsr.remoteNamespaceLabels),
+				metav1.CreateOptions{FieldManager: forge.ReflectionFieldManager})
+			return err
+		})
+		switch {
+		case kerrors.IsAlreadyExists(err):
+			err = nil
+		case err == nil:
+			klog.Infof("Remote namespace %q successfully created", nsr.RemoteNamespace())
+		}
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to ensure the presence of remote namespace %q: %w", nsr.RemoteNamespace(), err)
+	}
+
+	nsr.remoteNamespaceExists = true
 	return nil
 }
+
+// ensureTeardownFinalizer adds the teardown finalizer to the given local Service, if configured and not already present.
+func (nsr *NamespacedServiceReflector) ensureTeardownFinalizer(ctx context.Context, local *corev1.Service) error {
+	if nsr.teardownFinalizer == "" || controllerutil.ContainsFinalizer(local, nsr.teardownFinalizer) {
+		return nil
+	}
+
+	local = local.DeepCopy()
+	controllerutil.AddFinalizer(local, nsr.teardownFinalizer)
+	if _, err := nsr.localServicesClient.Update(ctx, local, metav1.UpdateOptions{FieldManager: forge.ReflectionFieldManager}); err != nil {
+		return fmt.Errorf("failed to add the teardown finalizer: %w", err)
+	}
+	klog.V(4).Infof("Teardown finalizer added to local Service %q", nsr.LocalRef(local.GetName()))
+	return nil
+}
+
+// releaseTeardownFinalizer removes the teardown finalizer from the given local Service, if present. It shall be invoked only
+// once the absence of the remote Service has been confirmed. The local Service is nil in case it does no longer exist.
+func (nsr *NamespacedServiceReflector) releaseTeardownFinalizer(ctx context.Context, local *corev1.Service) error {
+	if local == nil || nsr.teardownFinalizer == "" || !controllerutil.ContainsFinalizer(local, nsr.teardownFinalizer) {
+		return nil
+	}
+
+	local = local.DeepCopy()
+	controllerutil.RemoveFinalizer(local, nsr.teardownFinalizer)
+	if _, err := nsr.localServicesClient.Update(ctx, local, metav1.UpdateOptions{FieldManager: forge.ReflectionFieldManager}); err != nil {
+		klog.Errorf("Failed to release the teardown finalizer of local Service %q: %v", nsr.LocalRef(local.GetName()), err)
+		return err
+	}
+	klog.Infof("Teardown finalizer of local Service %q released, as the remote one has been removed", nsr.LocalRef(local.GetName()))
+	return nil
+}
+
+// Stop releases the teardown finalizers of the local Services, as they are no longer handled once the reflection of the namespace stops.
+func (nsr *NamespacedServiceReflector) Stop(ctx context.Context) error {
+	return releaseTeardownFinalizers(ctx, nsr.localServicesGetter, nsr.LocalNamespace(), nsr.teardownFinalizer)
+}
+
+// ReleaseTeardownFinalizers removes the teardown finalizers configured for the reflection towards the remote cluster from all
+// the local Services. It shall be invoked when the virtual kubelet terminates, as they would never be released otherwise.
+func (cfg *ServiceReflectorConfig) ReleaseTeardownFinalizers(ctx context.Context, local corev1clients.ServicesGetter) error {
+	return releaseTeardownFinalizers(ctx, local, corev1.NamespaceAll, cfg.teardownFinalizer())
+}
+
+// releaseTeardownFinalizers removes the given teardown finalizer from the local Services in the given namespace (or in all
+// namespaces), regardless of the presence of the remote ones. It returns an aggregated error in case any removal failed.
+func releaseTeardownFinalizers(ctx context.Context, local corev1clients.ServicesGetter, namespace, finalizer string) error {
+	if finalizer == "" {
+		return nil
+	}
+
+	services, err := local.Services(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list the local Services: %w", err)
+	}
+
+	var errs []error
+	for i := range services.Items {
+		service := &services.Items[i]
+		if !controllerutil.ContainsFinalizer(service, finalizer) {
+			continue
+		}
+
+		controllerutil.RemoveFinalizer(service, finalizer)
+		if _, err := local.Services(service.GetNamespace()).Update(ctx, service,
+			metav1.UpdateOptions{FieldManager: forge.ReflectionFieldManager}); err != nil && !kerrors.IsNotFound(err) {
+			errs = append(errs, fmt.Errorf("failed to release the teardown finalizer of local Service %q: %w", klog.KObj(service), err))
+			continue
+		}
+		klog.Infof("Teardown finalizer of local Service %q released, as no longer reflected", klog.KObj(service))
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// skipReflection returns whether the given local Service shall not be reflected, along with the reason and the event message.
+func (nsr *NamespacedServiceReflector) skipReflection(local *corev1.Service) (reason, msg string, skip bool) {
+	origin, reflected := forge.IsServiceReflectedFrom(local)
+
+	switch {
+	case nsr.ShouldSkipReflection(local):
+		return "marked with the skip annotation", forge.EventObjectReflectionDisabledMsg(), true
+	case reflected:
+		return fmt.Sprintf("reflected from cluster %q", origin), forge.EventReflectedServiceReflectionDisabledMsg(origin), true
+	case nsr.namespaceExcluded:
+		return "the namespace is excluded from the reflection", forge.EventReflectionDisabledMsg(nsr.LocalNamespace()), true
+	case !nsr.namespaceAllowed:
+		return "the namespace is not allowed for the remote cluster", forge.EventReflectionDisabledMsg(nsr.LocalNamespace()), true
+	case !nsr.selectedForOffloading(local):
+		return "it is not selected for offloading", forge.EventNotSelectedReflectionDisabledMsg(), true
+	case !forge.IsServiceTargetingCluster(local, forge.RemoteCluster):
+		return "the remote cluster is not among its target clusters", forge.EventNotTargetedReflectionDisabledMsg(), true
+	case !forge.IsServiceTypeAllowed(local.Spec.Type, nsr.allowedTypes):
+		return fmt.Sprintf("of type %s, not allowed for the remote cluster", local.Spec.Type),
+			forge.EventServiceTypeReflectionDisabledMsg(local.Spec.Type), true
+	default:
+		return "", "", false
+	}
+}
+
+// selectedForOffloading returns whether the given local Service is selected by the offloading intent, if configured.
+func (nsr *NamespacedServiceReflector) selectedForOffloading(local *corev1.Service) bool {
+	if nsr.offloadingSelectors == nil {
+		return true
+	}
+
+	selector, offloaded := nsr.offloadingSelectors.OffloadingSelector(nsr.LocalNamespace())
+	return offloaded && (selector == nil || selector.Matches(labels.Set(local.GetLabels())))
+}
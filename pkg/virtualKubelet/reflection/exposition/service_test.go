@@ -15,6 +15,7 @@
 package exposition_test
 
 import (
+	"errors"
 	"time"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -22,11 +23,16 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/testing"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/utils/trace"
 
+	discoveryv1alpha1 "github.com/liqotech/liqo/apis/discovery/v1alpha1"
 	"github.com/liqotech/liqo/pkg/consts"
 	. "github.com/liqotech/liqo/pkg/utils/testutil"
 	"github.com/liqotech/liqo/pkg/virtualKubelet/forge"
@@ -38,7 +44,54 @@ import (
 var _ = Describe("Service Reflection Tests", func() {
 	Describe("the NewServiceReflector function", func() {
 		It("should not return a nil reflector", func() {
-			Expect(exposition.NewServiceReflector(1)).ToNot(BeNil())
+			Expect(exposition.NewServiceReflector(1, &exposition.ServiceReflectorConfig{})).ToNot(BeNil())
+		})
+	})
+
+	Describe("service resync", func() {
+		const ServiceName = "resynced"
+
+		var (
+			reflector manager.NamespacedReflector
+			keys      []string
+		)
+
+		BeforeEach(func() {
+			// The local Service has been deleted, but the corresponding event has been missed, hence the reflected one is orphaned.
+			remote := corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{Name: ServiceName, Namespace: RemoteNamespace, Labels: forge.ReflectionLabels()},
+				Spec:       corev1.ServiceSpec{Ports: []corev1.ServicePort{{Name: "http", Port: 80, TargetPort: intstr.FromInt(8080)}}},
+			}
+			_, err := client.CoreV1().Services(RemoteNamespace).Create(ctx, &remote, metav1.CreateOptions{})
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			Expect(client.CoreV1().Services(RemoteNamespace).Delete(ctx, ServiceName, metav1.DeleteOptions{})).To(
+				Or(BeNil(), WithTransform(kerrors.IsNotFound, BeTrue())))
+		})
+
+		JustBeforeEach(func() {
+			factory := informers.NewSharedInformerFactory(client, 10*time.Hour)
+			reflector = exposition.NewNamespacedServiceReflector(&exposition.ServiceReflectorConfig{})(options.NewNamespaced().
+				WithLocal(LocalNamespace, client, factory).
+				WithRemote(RemoteNamespace, client, factory).
+				WithHandlerFactory(FakeEventHandler).
+				WithEventBroadcaster(record.NewBroadcaster()))
+
+			factory.Start(ctx.Done())
+			factory.WaitForCacheSync(ctx.Done())
+
+			keys = reflector.(manager.NamespacedResyncer).ResyncKeys()
+			for _, key := range keys {
+				Expect(reflector.Handle(trace.ContextWithTrace(ctx, trace.New("Service")), key)).To(Succeed())
+			}
+		})
+
+		It("should return the orphaned remote Service among the keys", func() { Expect(keys).To(ContainElement(ServiceName)) })
+		It("should eventually reconcile the missed deletion", func() {
+			_, err := client.CoreV1().Services(RemoteNamespace).Get(ctx, ServiceName, metav1.GetOptions{})
+			Expect(err).To(BeNotFound())
 		})
 	})
 
@@ -46,7 +99,10 @@ var _ = Describe("Service Reflection Tests", func() {
 		const ServiceName = "name"
 
 		var (
-			reflector manager.NamespacedReflector
+			reflector       manager.NamespacedReflector
+			config          exposition.ServiceReflectorConfig
+			localNamespace  string
+			remoteNamespace string
 
 			local, remote corev1.Service
 			err           error
@@ -83,6 +139,9 @@ var _ = Describe("Service Reflection Tests", func() {
 		}
 
 		BeforeEach(func() {
+			config = exposition.ServiceReflectorConfig{}
+			localNamespace = LocalNamespace
+			remoteNamespace = RemoteNamespace
 			local = corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: ServiceName, Namespace: LocalNamespace}}
 			remote = corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: ServiceName, Namespace: RemoteNamespace}}
 		})
@@ -92,13 +151,17 @@ var _ = Describe("Service Reflection Tests", func() {
 				Or(BeNil(), WithTransform(kerrors.IsNotFound, BeTrue())))
 			Expect(client.CoreV1().Services(RemoteNamespace).Delete(ctx, ServiceName, metav1.DeleteOptions{})).To(
 				Or(BeNil(), WithTransform(kerrors.IsNotFound, BeTrue())))
+			Expect(client.CoreV1().Services(HubNamespace).Delete(ctx, ServiceName, metav1.DeleteOptions{})).To(
+				Or(BeNil(), WithTransform(kerrors.IsNotFound, BeTrue())))
+			Expect(client.CoreV1().Services(metav1.NamespaceSystem).Delete(ctx, ServiceName, metav1.DeleteOptions{})).To(
+				Or(BeNil(), WithTransform(kerrors.IsNotFound, BeTrue())))
 		})
 
 		JustBeforeEach(func() {
 			factory := informers.NewSharedInformerFactory(client, 10*time.Hour)
-			reflector = exposition.NewNamespacedServiceReflector(options.NewNamespaced().
-				WithLocal(LocalNamespace, client, factory).
-				WithRemote(RemoteNamespace, client, factory).
+			reflector = exposition.NewNamespacedServiceReflector(&config)(options.NewNamespaced().
+				WithLocal(localNamespace, client, factory).
+				WithRemote(remoteNamespace, client, factory).
 				WithHandlerFactory(FakeEventHandler).
 				WithEventBroadcaster(record.NewBroadcaster()))
 
@@ -180,6 +243,26 @@ var _ = Describe("Service Reflection Tests", func() {
 			})
 		})
 
+		When("the local object does exist, and is reflected to a hub cluster", func() {
+			BeforeEach(func() {
+				// The hub cluster is emulated through a separate namespace, as the destination is transparent to the reflector.
+				remoteNamespace = HubNamespace
+				local.Spec = corev1.ServiceSpec{Ports: []corev1.ServicePort{{Name: "http", Port: 80, TargetPort: intstr.FromInt(8080)}}}
+				CreateService(&local)
+			})
+
+			It("should succeed", func() { Expect(err).ToNot(HaveOccurred()) })
+			It("the object should have been reflected to the hub, tagged with the origin cluster", func() {
+				hubAfter := GetService(HubNamespace)
+				Expect(hubAfter.Labels).To(HaveKeyWithValue(forge.LiqoOriginClusterIDKey, LocalClusterID))
+				Expect(forge.IsReflected(hubAfter)).To(BeTrue())
+			})
+			It("the object should not have been reflected to the remote cluster", func() {
+				_, err = client.CoreV1().Services(RemoteNamespace).Get(ctx, ServiceName, metav1.GetOptions{})
+				Expect(err).To(BeNotFound())
+			})
+		})
+
 		When("the local object does exist, but has the skip annotation", func() {
 			BeforeEach(func() {
 				local.SetAnnotations(map[string]string{consts.SkipReflectionAnnotationKey: "whatever"})
@@ -190,5 +273,487 @@ var _ = Describe("Service Reflection Tests", func() {
 			When("the remote object does not exist", WhenBodyRemoteShouldNotExist(false))
 			When("the remote object does exist", WhenBodyRemoteShouldNotExist(true))
 		})
+
+		When("the local object does exist, but the namespace is not allowed for the remote cluster", func() {
+			BeforeEach(func() {
+				config.AllowedNamespaces = []string{"another-namespace"}
+				local.Spec = corev1.ServiceSpec{Ports: []corev1.ServicePort{{Name: "http", Port: 80, TargetPort: intstr.FromInt(8080)}}}
+				CreateService(&local)
+			})
+
+			When("the remote object does not exist", WhenBodyRemoteShouldNotExist(false))
+			When("the remote object does exist", WhenBodyRemoteShouldNotExist(true))
+		})
+
+		When("the local object does exist, but the namespace is excluded by default", func() {
+			BeforeEach(func() {
+				localNamespace = metav1.NamespaceSystem
+				local.SetNamespace(metav1.NamespaceSystem)
+				local.Spec = corev1.ServiceSpec{Ports: []corev1.ServicePort{{Name: "http", Port: 80, TargetPort: intstr.FromInt(8080)}}}
+				CreateService(&local)
+			})
+
+			When("the remote object does not exist", WhenBodyRemoteShouldNotExist(false))
+			When("the remote object does exist", WhenBodyRemoteShouldNotExist(true))
+		})
+
+		When("the local object does exist, and the default namespace exclusion is disabled", func() {
+			BeforeEach(func() {
+				config.ExcludedNamespaces = []string{}
+				localNamespace = metav1.NamespaceSystem
+				local.SetNamespace(metav1.NamespaceSystem)
+				local.Spec = corev1.ServiceSpec{Ports: []corev1.ServicePort{{Name: "http", Port: 80, TargetPort: intstr.FromInt(8080)}}}
+				CreateService(&local)
+			})
+
+			It("should succeed", func() { Expect(err).ToNot(HaveOccurred()) })
+			It("the remote object should be present", func() {
+				Expect(GetService(RemoteNamespace).Labels).To(HaveKeyWithValue(forge.LiqoOriginClusterIDKey, LocalClusterID))
+			})
+		})
+
+		When("the local object does exist, and the namespace is allowed for the remote cluster", func() {
+			BeforeEach(func() {
+				config.AllowedNamespaces = []string{"another-namespace", LocalNamespace}
+				local.Spec = corev1.ServiceSpec{Ports: []corev1.ServicePort{{Name: "http", Port: 80, TargetPort: intstr.FromInt(8080)}}}
+				CreateService(&local)
+			})
+
+			It("should succeed", func() { Expect(err).ToNot(HaveOccurred()) })
+			It("the remote object should be present", func() {
+				Expect(GetService(RemoteNamespace).Labels).To(HaveKeyWithValue(forge.LiqoOriginClusterIDKey, LocalClusterID))
+			})
+		})
+
+		When("the local object does exist, and exposes a denied port", func() {
+			BeforeEach(func() {
+				config.DeniedPorts = forge.DeniedPorts{"22": {}}
+				local.Spec = corev1.ServiceSpec{Ports: []corev1.ServicePort{
+					{Name: "http", Port: 80, TargetPort: intstr.FromInt(8080)},
+					{Name: "ssh", Port: 22, TargetPort: intstr.FromInt(22)},
+				}}
+				CreateService(&local)
+			})
+
+			It("should succeed", func() { Expect(err).ToNot(HaveOccurred()) })
+			It("the denied port should have been stripped from the remote object", func() {
+				ports := GetService(RemoteNamespace).Spec.Ports
+				Expect(ports).To(HaveLen(1))
+				Expect(ports[0].Name).To(Equal("http"))
+			})
+		})
+
+		When("the local object does exist, but its type is not allowed for the remote cluster", func() {
+			BeforeEach(func() {
+				config.AllowedTypes = []corev1.ServiceType{corev1.ServiceTypeClusterIP}
+				local.Spec = corev1.ServiceSpec{
+					Type:  corev1.ServiceTypeLoadBalancer,
+					Ports: []corev1.ServicePort{{Name: "http", Port: 80, TargetPort: intstr.FromInt(8080)}},
+				}
+				CreateService(&local)
+			})
+
+			When("the remote object does not exist", WhenBodyRemoteShouldNotExist(false))
+			When("the remote object does exist", WhenBodyRemoteShouldNotExist(true))
+		})
+
+		When("the reflection is driven by an offloading selector", func() {
+			BeforeEach(func() {
+				local.SetLabels(map[string]string{"offload": "true"})
+				local.Spec = corev1.ServiceSpec{Ports: []corev1.ServicePort{{Name: "http", Port: 80, TargetPort: intstr.FromInt(8080)}}}
+				CreateService(&local)
+			})
+
+			When("the local object matches the selector of its namespace", func() {
+				BeforeEach(func() {
+					config.OffloadingSelectors = exposition.StaticOffloadingSelectors{
+						LocalNamespace: labels.SelectorFromSet(labels.Set{"offload": "true"}),
+					}
+				})
+
+				It("should succeed", func() { Expect(err).ToNot(HaveOccurred()) })
+				It("the remote object should be present", func() {
+					Expect(GetService(RemoteNamespace).Labels).To(HaveKeyWithValue(forge.LiqoOriginClusterIDKey, LocalClusterID))
+				})
+			})
+
+			When("the local object does not match the selector of its namespace", func() {
+				BeforeEach(func() {
+					config.OffloadingSelectors = exposition.StaticOffloadingSelectors{
+						LocalNamespace: labels.SelectorFromSet(labels.Set{"offload": "false"}),
+					}
+				})
+
+				When("the remote object does not exist", WhenBodyRemoteShouldNotExist(false))
+				When("the remote object does exist", WhenBodyRemoteShouldNotExist(true))
+			})
+
+			When("the namespace is not selected for offloading", func() {
+				BeforeEach(func() {
+					config.OffloadingSelectors = exposition.StaticOffloadingSelectors{"another-namespace": labels.Everything()}
+				})
+
+				When("the remote object does not exist", WhenBodyRemoteShouldNotExist(false))
+				When("the remote object does exist", WhenBodyRemoteShouldNotExist(true))
+			})
+		})
+
+		When("the local object does exist, but has been reflected from another cluster", func() {
+			BeforeEach(func() {
+				local.SetLabels(map[string]string{consts.ReflectedFromLabelKey: "another-cluster-id"})
+				local.Spec = corev1.ServiceSpec{Ports: []corev1.ServicePort{{Name: "http", Port: 80, TargetPort: intstr.FromInt(8080)}}}
+				CreateService(&local)
+			})
+
+			When("the remote object does not exist", WhenBodyRemoteShouldNotExist(false))
+			When("the remote object does exist", WhenBodyRemoteShouldNotExist(true))
+		})
+
+		When("the local object does exist, and the remote namespace does not exist", func() {
+			const NotExistingNamespace = "not-existing"
+
+			BeforeEach(func() {
+				remoteNamespace = NotExistingNamespace
+				local.Spec = corev1.ServiceSpec{Ports: []corev1.ServicePort{{Name: "http", Port: 80, TargetPort: intstr.FromInt(8080)}}}
+				CreateService(&local)
+			})
+
+			AfterEach(func() {
+				Expect(client.CoreV1().Services(NotExistingNamespace).Delete(ctx, ServiceName, metav1.DeleteOptions{})).To(
+					Or(BeNil(), WithTransform(kerrors.IsNotFound, BeTrue())))
+				Expect(client.CoreV1().Namespaces().Delete(ctx, NotExistingNamespace, metav1.DeleteOptions{})).To(
+					Or(BeNil(), WithTransform(kerrors.IsNotFound, BeTrue())))
+			})
+
+			When("the creation of the remote namespace is disabled", func() {
+				It("should fail", func() { Expect(err).To(HaveOccurred()) })
+				It("the remote namespace should not be created", func() {
+					_, err = client.CoreV1().Namespaces().Get(ctx, NotExistingNamespace, metav1.GetOptions{})
+					Expect(err).To(BeNotFound())
+				})
+			})
+
+			When("the creation of the remote namespace is enabled", func() {
+				BeforeEach(func() { config.CreateRemoteNamespace = true })
+
+				It("should succeed", func() { Expect(err).ToNot(HaveOccurred()) })
+				It("the remote namespace should be created, with the reflection labels", func() {
+					namespace, errns := client.CoreV1().Namespaces().Get(ctx, NotExistingNamespace, metav1.GetOptions{})
+					Expect(errns).ToNot(HaveOccurred())
+					Expect(namespace.Labels).To(HaveKeyWithValue(forge.LiqoOriginClusterIDKey, LocalClusterID))
+					Expect(namespace.Labels).To(HaveKeyWithValue(forge.LiqoDestinationClusterIDKey, RemoteClusterID))
+				})
+				It("the remote object should be present", func() {
+					Expect(GetService(NotExistingNamespace).Labels).To(HaveKeyWithValue(forge.LiqoOriginClusterIDKey, LocalClusterID))
+				})
+				It("should be idempotent", func() {
+					Expect(reflector.Handle(trace.ContextWithTrace(ctx, trace.New("Service")), ServiceName)).To(Succeed())
+				})
+
+				When("additional labels are configured for the remote namespace", func() {
+					BeforeEach(func() {
+						config.RemoteNamespaceLabels = map[string]string{
+							"pod-security.kubernetes.io/enforce": "restricted",
+							"governance.example.com/owner":       "platform-team",
+						}
+					})
+
+					It("should succeed", func() { Expect(err).ToNot(HaveOccurred()) })
+					It("the remote namespace should be created, with the configured labels", func() {
+						namespace, errns := client.CoreV1().Namespaces().Get(ctx, NotExistingNamespace, metav1.GetOptions{})
+						Expect(errns).ToNot(HaveOccurred())
+						Expect(namespace.Labels).To(HaveKeyWithValue("pod-security.kubernetes.io/enforce", "restricted"))
+						Expect(namespace.Labels).To(HaveKeyWithValue("governance.example.com/owner", "platform-team"))
+						Expect(namespace.Labels).To(HaveKeyWithValue(forge.LiqoOriginClusterIDKey, LocalClusterID))
+					})
+				})
+			})
+		})
+	})
+
+	Describe("remote mutations failing transiently", func() {
+		const ServiceName = "transient"
+
+		var (
+			fakeClient *fake.Clientset
+			config     exposition.ServiceReflectorConfig
+			attempts   int
+			err        error
+		)
+
+		BeforeEach(func() {
+			attempts = 0
+			config = exposition.ServiceReflectorConfig{MutationRetries: 3}
+			local := corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{Name: ServiceName, Namespace: LocalNamespace},
+				Spec:       corev1.ServiceSpec{Ports: []corev1.ServicePort{{Name: "http", Port: 80, TargetPort: intstr.FromInt(8080)}}},
+			}
+			// The fake client does not support server side apply, hence the reflected object is returned by the reactor.
+			fakeClient = fake.NewSimpleClientset(&local)
+			fakeClient.PrependReactor("patch", "services", func(action testing.Action) (bool, runtime.Object, error) {
+				attempts++
+				if attempts == 1 {
+					return true, nil, kerrors.NewServerTimeout(corev1.Resource("services"), "create", 1)
+				}
+				return true, &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: ServiceName, Namespace: RemoteNamespace}}, nil
+			})
+		})
+
+		JustBeforeEach(func() {
+			factory := informers.NewSharedInformerFactory(fakeClient, 10*time.Hour)
+			reflector := exposition.NewNamespacedServiceReflector(&config)(options.NewNamespaced().
+				WithLocal(LocalNamespace, fakeClient, factory).
+				WithRemote(RemoteNamespace, fakeClient, factory).
+				WithHandlerFactory(FakeEventHandler).
+				WithEventBroadcaster(record.NewBroadcaster()))
+
+			factory.Start(ctx.Done())
+			factory.WaitForCacheSync(ctx.Done())
+
+			err = reflector.Handle(trace.ContextWithTrace(ctx, trace.New("Service")), ServiceName)
+		})
+
+		When("the retries are enabled", func() {
+			It("should succeed", func() { Expect(err).ToNot(HaveOccurred()) })
+			It("should retry the creation of the remote object", func() { Expect(attempts).To(Equal(2)) })
+		})
+
+		When("the retries are disabled", func() {
+			BeforeEach(func() { config.MutationRetries = 0 })
+
+			It("should fail", func() { Expect(err).To(WithTransform(kerrors.IsServerTimeout, BeTrue())) })
+			It("should not retry the creation of the remote object", func() { Expect(attempts).To(Equal(1)) })
+		})
+	})
+
+	Describe("services fanned out to a subset of the remote clusters", func() {
+		const ServiceName = "fanned-out"
+
+		var (
+			clusters    []discoveryv1alpha1.ClusterIdentity
+			localClient *fake.Clientset
+			remotes     map[string]*fake.Clientset
+			applied     map[string]bool
+		)
+
+		BeforeEach(func() {
+			clusters = []discoveryv1alpha1.ClusterIdentity{
+				{ClusterID: "cluster-a-id", ClusterName: "cluster-a"},
+				{ClusterID: "cluster-b-id", ClusterName: "cluster-b"},
+				{ClusterID: "cluster-c-id", ClusterName: "cluster-c"},
+			}
+
+			// The target clusters can be specified either by ID or by name.
+			localClient = fake.NewSimpleClientset(&corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{Name: ServiceName, Namespace: LocalNamespace,
+					Annotations: map[string]string{consts.TargetClustersAnnotationKey: "cluster-a-id, cluster-c"}},
+				Spec: corev1.ServiceSpec{Ports: []corev1.ServicePort{{Name: "http", Port: 80, TargetPort: intstr.FromInt(8080)}}},
+			})
+
+			original := forge.RemoteCluster
+			DeferCleanup(func() { forge.RemoteCluster = original })
+
+			remotes, applied = make(map[string]*fake.Clientset), make(map[string]bool)
+			for _, cluster := range clusters {
+				forge.RemoteCluster = cluster
+				// A copy of the Service has been previously reflected to every cluster.
+				remote := fake.NewSimpleClientset(&corev1.Service{
+					ObjectMeta: metav1.ObjectMeta{Name: ServiceName, Namespace: RemoteNamespace, Labels: forge.ReflectionLabels()},
+				})
+				// The fake client does not support server side apply, hence the reflected object is returned by the reactor.
+				clusterID := cluster.ClusterID
+				remote.PrependReactor("patch", "services", func(action testing.Action) (bool, runtime.Object, error) {
+					applied[clusterID] = true
+					return true, &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: ServiceName, Namespace: RemoteNamespace}}, nil
+				})
+				remotes[clusterID] = remote
+			}
+		})
+
+		JustBeforeEach(func() {
+			localFactory := informers.NewSharedInformerFactory(localClient, 10*time.Hour)
+			for _, cluster := range clusters {
+				// Each virtual kubelet reflects the Services towards the corresponding remote cluster.
+				forge.RemoteCluster = cluster
+				remoteFactory := informers.NewSharedInformerFactory(remotes[cluster.ClusterID], 10*time.Hour)
+				reflector := exposition.NewNamespacedServiceReflector(&exposition.ServiceReflectorConfig{})(options.NewNamespaced().
+					WithLocal(LocalNamespace, localClient, localFactory).
+					WithRemote(RemoteNamespace, remotes[cluster.ClusterID], remoteFactory).
+					WithHandlerFactory(FakeEventHandler).
+					WithEventBroadcaster(record.NewBroadcaster()))
+
+				localFactory.Start(ctx.Done())
+				remoteFactory.Start(ctx.Done())
+				localFactory.WaitForCacheSync(ctx.Done())
+				remoteFactory.WaitForCacheSync(ctx.Done())
+
+				Expect(reflector.Handle(trace.ContextWithTrace(ctx, trace.New("Service")), ServiceName)).To(Succeed())
+			}
+		})
+
+		It("should reflect the Service to the target clusters only", func() {
+			Expect(applied).To(HaveKey("cluster-a-id"))
+			Expect(applied).ToNot(HaveKey("cluster-b-id"))
+			Expect(applied).To(HaveKey("cluster-c-id"))
+		})
+
+		It("should remove the copy reflected to the other clusters", func() {
+			_, err := remotes["cluster-b-id"].CoreV1().Services(RemoteNamespace).Get(ctx, ServiceName, metav1.GetOptions{})
+			Expect(err).To(BeNotFound())
+		})
+	})
+
+	Describe("services with the teardown finalizer", func() {
+		const ServiceName = "teardown"
+		const Finalizer = "liqo.io/service-teardown-" + RemoteClusterID
+
+		var (
+			localClient, remoteClient *fake.Clientset
+			reflector                 manager.NamespacedReflector
+			local                     *corev1.Service
+		)
+
+		handle := func() error { return reflector.Handle(trace.ContextWithTrace(ctx, trace.New("Service")), ServiceName) }
+		localFinalizers := func() []string {
+			svc, err := localClient.CoreV1().Services(LocalNamespace).Get(ctx, ServiceName, metav1.GetOptions{})
+			Expect(err).ToNot(HaveOccurred())
+			return svc.GetFinalizers()
+		}
+
+		BeforeEach(func() {
+			local = &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{Name: ServiceName, Namespace: LocalNamespace},
+				Spec:       corev1.ServiceSpec{Ports: []corev1.ServicePort{{Name: "http", Port: 80, TargetPort: intstr.FromInt(8080)}}},
+			}
+			remoteClient = fake.NewSimpleClientset()
+		})
+
+		JustBeforeEach(func() {
+			localClient = fake.NewSimpleClientset(local)
+			// The fake client does not support server side apply, hence the reflected object is returned by the reactor.
+			remoteClient.PrependReactor("patch", "services", func(action testing.Action) (bool, runtime.Object, error) {
+				return true, &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: ServiceName, Namespace: RemoteNamespace}}, nil
+			})
+
+			localFactory := informers.NewSharedInformerFactory(localClient, 10*time.Hour)
+			remoteFactory := informers.NewSharedInformerFactory(remoteClient, 10*time.Hour)
+			reflector = exposition.NewNamespacedServiceReflector(&exposition.ServiceReflectorConfig{TeardownFinalizer: "liqo.io/service-teardown"})(
+				options.NewNamespaced().
+					WithLocal(LocalNamespace, localClient, localFactory).
+					WithRemote(RemoteNamespace, remoteClient, remoteFactory).
+					WithHandlerFactory(FakeEventHandler).
+					WithEventBroadcaster(record.NewBroadcaster()))
+
+			localFactory.Start(ctx.Done())
+			remoteFactory.Start(ctx.Done())
+			localFactory.WaitForCacheSync(ctx.Done())
+			remoteFactory.WaitForCacheSync(ctx.Done())
+		})
+
+		When("the local Service is reflected", func() {
+			It("should add the teardown finalizer, suffixed with the remote cluster ID", func() {
+				Expect(handle()).To(Succeed())
+				Expect(localFinalizers()).To(ConsistOf(Finalizer))
+			})
+		})
+
+		When("the local Service is being deleted", func() {
+			BeforeEach(func() {
+				local.SetFinalizers([]string{Finalizer})
+				local.SetDeletionTimestamp(&metav1.Time{Time: time.Now()})
+				remoteClient = fake.NewSimpleClientset(&corev1.Service{
+					ObjectMeta: metav1.ObjectMeta{Name: ServiceName, Namespace: RemoteNamespace, Labels: forge.ReflectionLabels()},
+				})
+			})
+
+			It("should wait for the remote cleanup before releasing the teardown finalizer", func() {
+				Expect(handle()).To(Succeed())
+				_, err := remoteClient.CoreV1().Services(RemoteNamespace).Get(ctx, ServiceName, metav1.GetOptions{})
+				Expect(err).To(BeNotFound())
+				// The finalizer is retained until the removal of the remote Service is observed.
+				Expect(localFinalizers()).To(ConsistOf(Finalizer))
+
+				Eventually(func() []string {
+					Expect(handle()).To(Succeed())
+					return localFinalizers()
+				}).Should(BeEmpty())
+			})
+		})
+
+		When("the local Service is no longer reflected", func() {
+			BeforeEach(func() {
+				local.SetFinalizers([]string{Finalizer})
+				local.SetAnnotations(map[string]string{consts.SkipReflectionAnnotationKey: "true"})
+			})
+
+			It("should release the teardown finalizer", func() {
+				Expect(handle()).To(Succeed())
+				Expect(localFinalizers()).To(BeEmpty())
+			})
+		})
+
+		When("the remote cluster cannot be reached during the teardown", func() {
+			BeforeEach(func() {
+				local.SetFinalizers([]string{Finalizer})
+				local.SetDeletionTimestamp(&metav1.Time{Time: time.Now()})
+				remoteClient = fake.NewSimpleClientset(&corev1.Service{
+					ObjectMeta: metav1.ObjectMeta{Name: ServiceName, Namespace: RemoteNamespace, Labels: forge.ReflectionLabels()},
+				})
+				remoteClient.PrependReactor("delete", "services", func(action testing.Action) (bool, runtime.Object, error) {
+					return true, nil, errors.New("dial tcp 10.0.0.1:6443: connect: connection refused")
+				})
+			})
+
+			It("should skip the remote cleanup and release the teardown finalizer", func() {
+				Expect(handle()).To(Succeed())
+				Expect(localFinalizers()).To(BeEmpty())
+			})
+		})
+
+		When("the reflection of the namespace is stopped", func() {
+			BeforeEach(func() { local.SetFinalizers([]string{Finalizer, "other-finalizer"}) })
+
+			It("should release the teardown finalizer only", func() {
+				Expect(reflector.(manager.NamespacedStopper).Stop(ctx)).To(Succeed())
+				Expect(localFinalizers()).To(ConsistOf("other-finalizer"))
+			})
+		})
+	})
+
+	Describe("the ReleaseTeardownFinalizers function", func() {
+		const Finalizer = "liqo.io/service-teardown-" + RemoteClusterID
+
+		var localClient *fake.Clientset
+
+		service := func(namespace, name string, finalizers ...string) *corev1.Service {
+			return &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Finalizers: finalizers}}
+		}
+		finalizers := func(namespace, name string) []string {
+			svc, err := localClient.CoreV1().Services(namespace).Get(ctx, name, metav1.GetOptions{})
+			Expect(err).ToNot(HaveOccurred())
+			return svc.GetFinalizers()
+		}
+
+		BeforeEach(func() {
+			localClient = fake.NewSimpleClientset(
+				service("foo", "first", Finalizer),
+				service("bar", "second", Finalizer, "other-finalizer"),
+				service("bar", "third", "liqo.io/service-teardown-other-cluster"),
+			)
+		})
+
+		It("should release the teardown finalizers of the remote cluster from the Services in all namespaces", func() {
+			cfg := exposition.ServiceReflectorConfig{TeardownFinalizer: "liqo.io/service-teardown"}
+			Expect(cfg.ReleaseTeardownFinalizers(ctx, localClient.CoreV1())).To(Succeed())
+			Expect(finalizers("foo", "first")).To(BeEmpty())
+			Expect(finalizers("bar", "second")).To(ConsistOf("other-finalizer"))
+			Expect(finalizers("bar", "third")).To(ConsistOf("liqo.io/service-teardown-other-cluster"))
+		})
+
+		It("should be a no-op if the teardown finalizer is not configured", func() {
+			cfg := exposition.ServiceReflectorConfig{}
+			Expect(cfg.ReleaseTeardownFinalizers(ctx, localClient.CoreV1())).To(Succeed())
+			Expect(finalizers("foo", "first")).To(ConsistOf(Finalizer))
+		})
 	})
 })
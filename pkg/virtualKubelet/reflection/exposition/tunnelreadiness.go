@@ -0,0 +1,98 @@
+// Copyright 2019-2023 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exposition
+
+import (
+	"context"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+	"github.com/liqotech/liqo/pkg/consts"
+	"github.com/liqotech/liqo/pkg/virtualKubelet/reflection/generic"
+)
+
+// TunnelNotReadyPauseReason is the reason the reflection is paused for, while the tunnel towards the remote cluster is not ready.
+const TunnelNotReadyPauseReason = "tunnel not ready"
+
+// TunnelReadinessGate pauses the reflection towards the remote cluster while the corresponding TunnelEndpoint is not
+// connected, as the reflected endpoints would otherwise refer to remapped addresses not yet (or no longer) reachable.
+// The reflection is paused until the tunnel is observed to be ready for the first time, and it is resumed (re-pushing
+// all the reflected endpoints) as soon as it becomes ready again.
+type TunnelReadinessGate struct {
+	pauser    *generic.ReflectionPauser
+	clusterID string
+}
+
+// NewTunnelReadinessGate returns a new TunnelReadinessGate, pausing the reflection towards the given cluster until the tunnel is ready.
+func NewTunnelReadinessGate(pauser *generic.ReflectionPauser, clusterID string) *TunnelReadinessGate {
+	pauser.PauseReflectionFor(clusterID, TunnelNotReadyPauseReason)
+	return &TunnelReadinessGate{pauser: pauser, clusterID: clusterID}
+}
+
+// Observe updates the state of the gate according to the given TunnelEndpoint (nil if it does not exist).
+func (trg *TunnelReadinessGate) Observe(tep *netv1alpha1.TunnelEndpoint) {
+	if tep == nil || !tep.GetDeletionTimestamp().IsZero() || tep.Status.Connection.Status != netv1alpha1.Connected {
+		trg.pauser.PauseReflectionFor(trg.clusterID, TunnelNotReadyPauseReason)
+		return
+	}
+	trg.pauser.ResumeReflectionFor(trg.clusterID, TunnelNotReadyPauseReason)
+}
+
+// Start starts watching the TunnelEndpoint associated with the remote cluster in the given namespace, updating the gate accordingly.
+func (trg *TunnelReadinessGate) Start(ctx context.Context, dynClient dynamic.Interface, namespace string, resync time.Duration) {
+	watchTunnelEndpoint(ctx, dynClient, namespace, trg.clusterID, resync, trg.Observe)
+}
+
+// watchTunnelEndpoint starts watching the TunnelEndpoint associated with the given remote cluster in the given namespace,
+// invoking the observe function whenever it changes (with a nil TunnelEndpoint in case it is deleted).
+func watchTunnelEndpoint(ctx context.Context, dynClient dynamic.Interface, namespace, clusterID string,
+	resync time.Duration, observe func(tep *netv1alpha1.TunnelEndpoint)) {
+	factory := dynamicinformer.NewFilteredDynamicSharedInformerFactory(dynClient, resync, namespace, func(opts *metav1.ListOptions) {
+		opts.LabelSelector = consts.ClusterIDLabelName + "=" + clusterID
+	})
+
+	informer := factory.ForResource(netv1alpha1.TunnelEndpointGroupVersionResource).Informer()
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { observeUnstructured(obj, observe) },
+		UpdateFunc: func(_, obj interface{}) { observeUnstructured(obj, observe) },
+		DeleteFunc: func(_ interface{}) { observe(nil) },
+	})
+
+	factory.Start(ctx.Done())
+}
+
+// observeUnstructured converts the given object to a TunnelEndpoint, and invokes the observe function with the result.
+func observeUnstructured(obj interface{}, observe func(tep *netv1alpha1.TunnelEndpoint)) {
+	unstruct, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		klog.Errorf("Unexpected object of type %T, while expecting a TunnelEndpoint", obj)
+		return
+	}
+
+	var tep netv1alpha1.TunnelEndpoint
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(unstruct.Object, &tep); err != nil {
+		klog.Errorf("Failed to convert TunnelEndpoint %q: %v", klog.KObj(unstruct), err)
+		return
+	}
+	observe(&tep)
+}
@@ -0,0 +1,169 @@
+// Copyright 2019-2023 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exposition_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/trace"
+
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+	"github.com/liqotech/liqo/pkg/consts"
+	fakeipam "github.com/liqotech/liqo/pkg/liqonet/ipam/fake"
+	. "github.com/liqotech/liqo/pkg/utils/testutil"
+	"github.com/liqotech/liqo/pkg/virtualKubelet/forge"
+	"github.com/liqotech/liqo/pkg/virtualKubelet/reflection/exposition"
+	"github.com/liqotech/liqo/pkg/virtualKubelet/reflection/generic"
+	"github.com/liqotech/liqo/pkg/virtualKubelet/reflection/manager"
+	"github.com/liqotech/liqo/pkg/virtualKubelet/reflection/options"
+)
+
+var _ = Describe("TunnelReadinessGate", func() {
+	var (
+		pauser *generic.ReflectionPauser
+		gate   *exposition.TunnelReadinessGate
+	)
+
+	TunnelEndpoint := func(status netv1alpha1.ConnectionStatus) *netv1alpha1.TunnelEndpoint {
+		return &netv1alpha1.TunnelEndpoint{
+			TypeMeta: metav1.TypeMeta{APIVersion: netv1alpha1.GroupVersion.String(), Kind: "TunnelEndpoint"},
+			ObjectMeta: metav1.ObjectMeta{Name: "tep", Namespace: LocalNamespace,
+				Labels: map[string]string{consts.ClusterIDLabelName: RemoteClusterID}},
+			Status: netv1alpha1.TunnelEndpointStatus{Connection: netv1alpha1.Connection{Status: status}},
+		}
+	}
+
+	BeforeEach(func() {
+		pauser = generic.NewReflectionPauser()
+		gate = exposition.NewTunnelReadinessGate(pauser, RemoteClusterID)
+	})
+
+	It("should pause the reflection until the tunnel is observed", func() { Expect(pauser.Paused(RemoteClusterID)).To(BeTrue()) })
+
+	DescribeTable("observing the TunnelEndpoint",
+		func(tep *netv1alpha1.TunnelEndpoint, paused bool) {
+			gate.Observe(tep)
+			Expect(pauser.Paused(RemoteClusterID)).To(Equal(paused))
+		},
+		Entry("the tunnel is connected", TunnelEndpoint(netv1alpha1.Connected), false),
+		Entry("the tunnel is connecting", TunnelEndpoint(netv1alpha1.Connecting), true),
+		Entry("the tunnel is in error", TunnelEndpoint(netv1alpha1.ConnectionError), true),
+		Entry("the tunnel has no status yet", TunnelEndpoint(""), true),
+		Entry("the tunnel does not exist", nil, true),
+	)
+
+	When("the tunnel leaves the ready phase", func() {
+		BeforeEach(func() {
+			gate.Observe(TunnelEndpoint(netv1alpha1.Connected))
+			gate.Observe(TunnelEndpoint(netv1alpha1.ConnectionError))
+		})
+
+		It("should pause the reflection", func() { Expect(pauser.Paused(RemoteClusterID)).To(BeTrue()) })
+		It("should resume the reflection once ready again", func() {
+			gate.Observe(TunnelEndpoint(netv1alpha1.Connected))
+			Expect(pauser.Paused(RemoteClusterID)).To(BeFalse())
+		})
+	})
+
+	When("the reflection is also paused for a different reason", func() {
+		BeforeEach(func() { pauser.PauseReflection(RemoteClusterID) })
+
+		It("should not resume the reflection once the tunnel is ready", func() {
+			gate.Observe(TunnelEndpoint(netv1alpha1.Connected))
+			Expect(pauser.Paused(RemoteClusterID)).To(BeTrue())
+		})
+	})
+
+	When("watching the TunnelEndpoints", func() {
+		It("should resume the reflection once the TunnelEndpoint is connected", func() {
+			scheme := runtime.NewScheme()
+			Expect(netv1alpha1.AddToScheme(scheme)).To(Succeed())
+			dynClient := dynamicfake.NewSimpleDynamicClient(scheme, TunnelEndpoint(netv1alpha1.Connected))
+
+			gate.Start(ctx, dynClient, LocalNamespace, 10*time.Hour)
+			Eventually(func() bool { return pauser.Paused(RemoteClusterID) }).Should(BeFalse())
+		})
+	})
+
+	Describe("gating the endpointslice reflection", func() {
+		const EndpointSliceName = "gated"
+
+		var reflector manager.NamespacedReflector
+
+		Handle := func() {
+			Expect(reflector.Handle(trace.ContextWithTrace(ctx, trace.New("EndpointSlice")), EndpointSliceName)).To(Succeed())
+		}
+
+		GetRemote := func() (*discoveryv1.EndpointSlice, error) {
+			return client.DiscoveryV1().EndpointSlices(RemoteNamespace).Get(ctx, EndpointSliceName, metav1.GetOptions{})
+		}
+
+		BeforeEach(func() {
+			local := discoveryv1.EndpointSlice{
+				ObjectMeta:  metav1.ObjectMeta{Name: EndpointSliceName, Namespace: LocalNamespace},
+				AddressType: discoveryv1.AddressTypeIPv4,
+				Endpoints:   []discoveryv1.Endpoint{{Addresses: []string{"192.168.0.25"}}},
+			}
+			_, err := client.DiscoveryV1().EndpointSlices(LocalNamespace).Create(ctx, &local, metav1.CreateOptions{})
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			Expect(client.DiscoveryV1().EndpointSlices(LocalNamespace).DeleteCollection(ctx, metav1.DeleteOptions{}, metav1.ListOptions{})).To(Succeed())
+			Expect(client.DiscoveryV1().EndpointSlices(RemoteNamespace).DeleteCollection(ctx, metav1.DeleteOptions{}, metav1.ListOptions{})).To(Succeed())
+		})
+
+		JustBeforeEach(func() {
+			factory := informers.NewSharedInformerFactory(client, 10*time.Hour)
+			reflector = exposition.NewNamespacedEndpointSliceReflector(fakeipam.NewIPAMClient("192.168.200.0/24", "192.168.201.0/24", true),
+				&exposition.EndpointSliceReflectorConfig{Weight: forge.EndpointWeightMax, Pauser: pauser})(options.NewNamespaced().
+				WithLocal(LocalNamespace, client, factory).
+				WithRemote(RemoteNamespace, client, factory).
+				WithHandlerFactory(FakeEventHandler).
+				WithEventBroadcaster(record.NewBroadcaster()))
+
+			factory.Start(ctx.Done())
+			factory.WaitForCacheSync(ctx.Done())
+			Handle()
+		})
+
+		It("should not push the endpoints while the tunnel is not ready", func() {
+			_, err := GetRemote()
+			Expect(err).To(BeNotFound())
+		})
+
+		When("the tunnel becomes ready", func() {
+			JustBeforeEach(func() {
+				gate.Observe(TunnelEndpoint(netv1alpha1.Connected))
+				Handle()
+			})
+
+			It("should push the endpoints", func() {
+				remote, err := GetRemote()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(remote.Endpoints).To(HaveLen(1))
+				Expect(remote.Endpoints[0].Addresses).To(ConsistOf("192.168.200.25"))
+			})
+		})
+	})
+})
@@ -0,0 +1,74 @@
+// Copyright 2019-2023 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exposition
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"k8s.io/client-go/dynamic"
+	"k8s.io/klog/v2"
+
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+	"github.com/liqotech/liqo/pkg/virtualKubelet/reflection/generic"
+)
+
+// TunnelRemapWatcher invalidates the reflection towards the remote cluster whenever the networks the local PodCIDR and
+// ExternalCIDR are remapped to (as configured in the corresponding TunnelEndpoint) change, so that all the reflected
+// endpoints are translated again according to the new remapping and re-pushed to the remote cluster.
+type TunnelRemapWatcher struct {
+	pauser    *generic.ReflectionPauser
+	clusterID string
+
+	mutex           sync.Mutex
+	observed        bool
+	natPodCIDR      string
+	natExternalCIDR string
+}
+
+// NewTunnelRemapWatcher returns a new TunnelRemapWatcher, invalidating the reflection towards the given cluster upon remapping changes.
+func NewTunnelRemapWatcher(pauser *generic.ReflectionPauser, clusterID string) *TunnelRemapWatcher {
+	return &TunnelRemapWatcher{pauser: pauser, clusterID: clusterID}
+}
+
+// Observe compares the remapping configured in the given TunnelEndpoint (nil if it does not exist) with the one previously
+// observed, and invalidates the reflection in case it changed. The first observed remapping is assumed to be the one the
+// reflection started with, while missing or terminating TunnelEndpoints are ignored, as the reflection is then gated elsewhere.
+func (trw *TunnelRemapWatcher) Observe(tep *netv1alpha1.TunnelEndpoint) {
+	if tep == nil || !tep.GetDeletionTimestamp().IsZero() {
+		return
+	}
+
+	trw.mutex.Lock()
+	changed := trw.observed && (trw.natPodCIDR != tep.Spec.LocalNATPodCIDR || trw.natExternalCIDR != tep.Spec.LocalNATExternalCIDR)
+	if changed {
+		klog.Infof("Remapping of the local networks in remote cluster %q changed (PodCIDR: %q -> %q, ExternalCIDR: %q -> %q)",
+			trw.clusterID, trw.natPodCIDR, tep.Spec.LocalNATPodCIDR, trw.natExternalCIDR, tep.Spec.LocalNATExternalCIDR)
+	}
+	trw.observed = true
+	trw.natPodCIDR, trw.natExternalCIDR = tep.Spec.LocalNATPodCIDR, tep.Spec.LocalNATExternalCIDR
+	trw.mutex.Unlock()
+
+	// Invalidate the reflection outside of the critical section, as the callbacks might take a while to complete.
+	if changed {
+		trw.pauser.Invalidate(trw.clusterID)
+	}
+}
+
+// Start starts watching the TunnelEndpoint associated with the remote cluster in the given namespace, invalidating the reflection accordingly.
+func (trw *TunnelRemapWatcher) Start(ctx context.Context, dynClient dynamic.Interface, namespace string, resync time.Duration) {
+	watchTunnelEndpoint(ctx, dynClient, namespace, trw.clusterID, resync, trw.Observe)
+}
@@ -0,0 +1,119 @@
+// Copyright 2019-2023 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exposition_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+	"github.com/liqotech/liqo/pkg/consts"
+	"github.com/liqotech/liqo/pkg/virtualKubelet/reflection/exposition"
+	"github.com/liqotech/liqo/pkg/virtualKubelet/reflection/generic"
+)
+
+var _ = Describe("TunnelRemapWatcher", func() {
+	var (
+		pauser      *generic.ReflectionPauser
+		watcher     *exposition.TunnelRemapWatcher
+		invalidated int
+	)
+
+	TunnelEndpoint := func(natPodCIDR, natExternalCIDR string) *netv1alpha1.TunnelEndpoint {
+		return &netv1alpha1.TunnelEndpoint{
+			TypeMeta: metav1.TypeMeta{APIVersion: netv1alpha1.GroupVersion.String(), Kind: "TunnelEndpoint"},
+			ObjectMeta: metav1.ObjectMeta{Name: "tep", Namespace: LocalNamespace,
+				Labels: map[string]string{consts.ClusterIDLabelName: RemoteClusterID}},
+			Spec: netv1alpha1.TunnelEndpointSpec{LocalNATPodCIDR: natPodCIDR, LocalNATExternalCIDR: natExternalCIDR},
+		}
+	}
+
+	BeforeEach(func() {
+		invalidated = 0
+		pauser = generic.NewReflectionPauser()
+		pauser.OnResume(func(clusterID string) {
+			if clusterID == RemoteClusterID {
+				invalidated++
+			}
+		})
+		watcher = exposition.NewTunnelRemapWatcher(pauser, RemoteClusterID)
+	})
+
+	It("should not invalidate the reflection upon the first observation", func() {
+		watcher.Observe(TunnelEndpoint("10.200.0.0/16", "10.201.0.0/16"))
+		Expect(invalidated).To(BeZero())
+		Expect(pauser.Generation(RemoteClusterID)).To(BeZero())
+	})
+
+	When("the remapping has already been observed", func() {
+		BeforeEach(func() { watcher.Observe(TunnelEndpoint("10.200.0.0/16", "10.201.0.0/16")) })
+
+		DescribeTable("observing the TunnelEndpoint again",
+			func(tep *netv1alpha1.TunnelEndpoint, expected int) {
+				watcher.Observe(tep)
+				Expect(invalidated).To(Equal(expected))
+				Expect(pauser.Generation(RemoteClusterID)).To(BeNumerically("==", expected))
+			},
+			Entry("the remapping is unchanged", TunnelEndpoint("10.200.0.0/16", "10.201.0.0/16"), 0),
+			Entry("the PodCIDR remapping changed", TunnelEndpoint("10.210.0.0/16", "10.201.0.0/16"), 1),
+			Entry("the ExternalCIDR remapping changed", TunnelEndpoint("10.200.0.0/16", "10.211.0.0/16"), 1),
+			Entry("the TunnelEndpoint does not exist", nil, 0),
+		)
+
+		It("should invalidate the reflection once per change", func() {
+			watcher.Observe(TunnelEndpoint("10.210.0.0/16", "10.201.0.0/16"))
+			watcher.Observe(TunnelEndpoint("10.210.0.0/16", "10.201.0.0/16"))
+			watcher.Observe(TunnelEndpoint("10.220.0.0/16", "10.201.0.0/16"))
+			Expect(invalidated).To(Equal(2))
+		})
+
+		It("should not notify the callbacks while the reflection is paused", func() {
+			pauser.PauseReflection(RemoteClusterID)
+			watcher.Observe(TunnelEndpoint("10.210.0.0/16", "10.201.0.0/16"))
+			Expect(invalidated).To(BeZero())
+			Expect(pauser.Generation(RemoteClusterID)).To(BeNumerically("==", 1))
+		})
+	})
+
+	When("watching the TunnelEndpoints", func() {
+		It("should invalidate the reflection once the remapping changes", func() {
+			scheme := runtime.NewScheme()
+			Expect(netv1alpha1.AddToScheme(scheme)).To(Succeed())
+			dynClient := dynamicfake.NewSimpleDynamicClient(scheme, TunnelEndpoint("10.200.0.0/16", "10.201.0.0/16"))
+			teps := dynClient.Resource(netv1alpha1.TunnelEndpointGroupVersionResource).Namespace(LocalNamespace)
+
+			watcher.Start(ctx, dynClient, LocalNamespace, 10*time.Hour)
+
+			// Keep toggling the remapping, as the changes performed before the informer is synced are not detected.
+			natPodCIDRs := []string{"10.210.0.0/16", "10.200.0.0/16"}
+			Eventually(func() uint64 {
+				tep := TunnelEndpoint(natPodCIDRs[0], "10.201.0.0/16")
+				natPodCIDRs[0], natPodCIDRs[1] = natPodCIDRs[1], natPodCIDRs[0]
+
+				unstruct, err := runtime.DefaultUnstructuredConverter.ToUnstructured(tep)
+				Expect(err).ToNot(HaveOccurred())
+				_, err = teps.Update(ctx, &unstructured.Unstructured{Object: unstruct}, metav1.UpdateOptions{})
+				Expect(err).ToNot(HaveOccurred())
+				return pauser.Generation(RemoteClusterID)
+			}).ShouldNot(BeZero())
+		})
+	})
+})
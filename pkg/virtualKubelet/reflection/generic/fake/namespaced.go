@@ -24,6 +24,10 @@ import (
 type NamespacedReflector struct {
 	Opts    options.NamespacedOpts
 	Handled int
+	// Keys are the names returned when a resync is performed.
+	Keys []string
+	// Stopped counts the times the resources of the local objects have been released.
+	Stopped int
 	ready   bool
 }
 
@@ -43,3 +47,12 @@ func (r *NamespacedReflector) Ready() bool { return r.ready }
 
 // SetReady marks the NamespacedReflector as completely initialized.
 func (r *NamespacedReflector) SetReady() { r.ready = true }
+
+// ResyncKeys returns the configured keys.
+func (r *NamespacedReflector) ResyncKeys() []string { return r.Keys }
+
+// Stop increments the Stopped counter.
+func (r *NamespacedReflector) Stop(ctx context.Context) error {
+	r.Stopped++
+	return nil
+}
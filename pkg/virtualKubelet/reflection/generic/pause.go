@@ -0,0 +1,157 @@
+// Copyright 2019-2023 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generic
+
+import (
+	"fmt"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+)
+
+// ReflectionPauser coordinates the pausing of the reflection towards given remote clusters, e.g., while migrating the
+// subnets their NAT configuration is based upon, as the reflected objects would otherwise refer to stale addresses.
+// The reflection can be paused for different reasons at the same time, and it is resumed only once all of them are
+// cleared. It is safe for concurrent use.
+type ReflectionPauser struct {
+	mutex       sync.RWMutex
+	paused      map[string]sets.String
+	generations map[string]uint64
+	callbacks   []func(clusterID string)
+}
+
+// NewReflectionPauser returns a new ReflectionPauser, with the reflection towards all clusters enabled.
+func NewReflectionPauser() *ReflectionPauser {
+	return &ReflectionPauser{paused: make(map[string]sets.String), generations: make(map[string]uint64)}
+}
+
+// PauseReflection halts the reflection towards the given cluster, until ResumeReflection is invoked.
+func (rp *ReflectionPauser) PauseReflection(clusterID string) {
+	rp.PauseReflectionFor(clusterID, "")
+}
+
+// PauseReflectionFor halts the reflection towards the given cluster for the given reason,
+// until ResumeReflectionFor is invoked with the same reason.
+func (rp *ReflectionPauser) PauseReflectionFor(clusterID, reason string) {
+	rp.mutex.Lock()
+	defer rp.mutex.Unlock()
+
+	if rp.paused[clusterID].Has(reason) {
+		return
+	}
+
+	klog.Infof("Pausing the reflection towards remote cluster %q%s", clusterID, formatReason(reason))
+	if rp.paused[clusterID] == nil {
+		rp.paused[clusterID] = sets.NewString()
+	}
+	rp.paused[clusterID].Insert(reason)
+}
+
+// ResumeReflection resumes the reflection towards the given cluster, notifying the registered callbacks, so that the
+// reflected objects are enforced again (e.g., with the addresses translated according to the new remapping).
+// It is a no-op if the reflection towards the given cluster is not paused.
+func (rp *ReflectionPauser) ResumeReflection(clusterID string) {
+	rp.ResumeReflectionFor(clusterID, "")
+}
+
+// ResumeReflectionFor clears the given reason the reflection towards the given cluster is paused for, and resumes it
+// (notifying the registered callbacks) in case no other reasons are left. It is a no-op if the reason is not set.
+func (rp *ReflectionPauser) ResumeReflectionFor(clusterID, reason string) {
+	rp.mutex.Lock()
+	if !rp.paused[clusterID].Has(reason) {
+		rp.mutex.Unlock()
+		return
+	}
+
+	rp.paused[clusterID].Delete(reason)
+	if rp.paused[clusterID].Len() > 0 {
+		klog.Infof("Reflection towards remote cluster %q no longer paused%s, but still paused for %v",
+			clusterID, formatReason(reason), rp.paused[clusterID].List())
+		rp.mutex.Unlock()
+		return
+	}
+
+	klog.Infof("Resuming the reflection towards remote cluster %q", clusterID)
+	delete(rp.paused, clusterID)
+	rp.generations[clusterID]++
+	callbacks := rp.callbacks
+	rp.mutex.Unlock()
+
+	// Invoke the callbacks outside of the critical section, as they might query the pauser.
+	for _, callback := range callbacks {
+		callback(clusterID)
+	}
+}
+
+// Invalidate notifies that the state cached by the reflectors for the given cluster (e.g., address translations) is no
+// longer valid, as the remapping it is based upon changed, so that the reflected objects are enforced again. In case
+// the reflection is currently paused, the callbacks are notified only once it is resumed.
+func (rp *ReflectionPauser) Invalidate(clusterID string) {
+	rp.mutex.Lock()
+	rp.generations[clusterID]++
+	if rp.paused[clusterID].Len() > 0 {
+		rp.mutex.Unlock()
+		return
+	}
+
+	klog.Infof("Invalidating the reflection towards remote cluster %q", clusterID)
+	callbacks := rp.callbacks
+	rp.mutex.Unlock()
+
+	// Invoke the callbacks outside of the critical section, as they might query the pauser.
+	for _, callback := range callbacks {
+		callback(clusterID)
+	}
+}
+
+// Paused returns whether the reflection towards the given cluster is currently paused.
+// A nil ReflectionPauser never pauses the reflection.
+func (rp *ReflectionPauser) Paused(clusterID string) bool {
+	if rp == nil {
+		return false
+	}
+
+	rp.mutex.RLock()
+	defer rp.mutex.RUnlock()
+	return rp.paused[clusterID].Len() > 0
+}
+
+// Generation returns the number of times the reflection towards the given cluster has been resumed or invalidated, to allow
+// the reflectors to detect that the state cached beforehand (e.g., address translations) shall be discarded.
+func (rp *ReflectionPauser) Generation(clusterID string) uint64 {
+	if rp == nil {
+		return 0
+	}
+
+	rp.mutex.RLock()
+	defer rp.mutex.RUnlock()
+	return rp.generations[clusterID]
+}
+
+// OnResume registers a callback invoked whenever the reflection towards a cluster is resumed or invalidated.
+func (rp *ReflectionPauser) OnResume(callback func(clusterID string)) {
+	rp.mutex.Lock()
+	defer rp.mutex.Unlock()
+	rp.callbacks = append(rp.callbacks, callback)
+}
+
+// formatReason returns the suffix of the log messages describing the given pause reason, if any.
+func formatReason(reason string) string {
+	if reason == "" {
+		return ""
+	}
+	return fmt.Sprintf(" (reason: %s)", reason)
+}
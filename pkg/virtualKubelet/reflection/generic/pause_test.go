@@ -0,0 +1,138 @@
+// Copyright 2019-2023 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generic
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ReflectionPauser", func() {
+	const (
+		clusterID = "cluster-id"
+		otherID   = "other-cluster-id"
+	)
+
+	var (
+		pauser  *ReflectionPauser
+		resumed []string
+	)
+
+	BeforeEach(func() {
+		resumed = nil
+		pauser = NewReflectionPauser()
+		pauser.OnResume(func(clusterID string) {
+			// The pauser shall be queryable from within the callbacks, and already reflect the resume.
+			Expect(pauser.Paused(clusterID)).To(BeFalse())
+			resumed = append(resumed, clusterID)
+		})
+	})
+
+	When("no cluster is paused", func() {
+		It("should not pause the reflection", func() { Expect(pauser.Paused(clusterID)).To(BeFalse()) })
+		It("should report the initial generation", func() { Expect(pauser.Generation(clusterID)).To(BeZero()) })
+
+		It("should treat the resume as a no-op", func() {
+			pauser.ResumeReflection(clusterID)
+			Expect(resumed).To(BeEmpty())
+			Expect(pauser.Generation(clusterID)).To(BeZero())
+		})
+	})
+
+	When("a cluster is paused", func() {
+		BeforeEach(func() { pauser.PauseReflection(clusterID) })
+
+		It("should pause the reflection towards that cluster", func() { Expect(pauser.Paused(clusterID)).To(BeTrue()) })
+		It("should not pause the reflection towards the other clusters", func() { Expect(pauser.Paused(otherID)).To(BeFalse()) })
+
+		When("the cluster is resumed", func() {
+			BeforeEach(func() { pauser.ResumeReflection(clusterID) })
+
+			It("should resume the reflection", func() { Expect(pauser.Paused(clusterID)).To(BeFalse()) })
+			It("should notify the callbacks", func() { Expect(resumed).To(ConsistOf(clusterID)) })
+			It("should increase the generation of that cluster only", func() {
+				Expect(pauser.Generation(clusterID)).To(BeNumerically("==", 1))
+				Expect(pauser.Generation(otherID)).To(BeZero())
+			})
+		})
+	})
+
+	When("a cluster is paused for multiple reasons", func() {
+		BeforeEach(func() {
+			pauser.PauseReflectionFor(clusterID, "first")
+			pauser.PauseReflectionFor(clusterID, "second")
+		})
+
+		It("should pause the reflection towards that cluster", func() { Expect(pauser.Paused(clusterID)).To(BeTrue()) })
+
+		When("only one of the reasons is cleared", func() {
+			BeforeEach(func() { pauser.ResumeReflectionFor(clusterID, "first") })
+
+			It("should keep the reflection paused", func() { Expect(pauser.Paused(clusterID)).To(BeTrue()) })
+			It("should not notify the callbacks", func() { Expect(resumed).To(BeEmpty()) })
+		})
+
+		When("all the reasons are cleared", func() {
+			BeforeEach(func() {
+				pauser.ResumeReflectionFor(clusterID, "first")
+				pauser.ResumeReflectionFor(clusterID, "second")
+			})
+
+			It("should resume the reflection", func() { Expect(pauser.Paused(clusterID)).To(BeFalse()) })
+			It("should notify the callbacks once", func() { Expect(resumed).To(ConsistOf(clusterID)) })
+		})
+
+		When("a different reason is cleared", func() {
+			BeforeEach(func() { pauser.ResumeReflection(clusterID) })
+
+			It("should keep the reflection paused", func() { Expect(pauser.Paused(clusterID)).To(BeTrue()) })
+		})
+	})
+
+	When("a cluster is invalidated", func() {
+		BeforeEach(func() { pauser.Invalidate(clusterID) })
+
+		It("should not pause the reflection", func() { Expect(pauser.Paused(clusterID)).To(BeFalse()) })
+		It("should notify the callbacks", func() { Expect(resumed).To(ConsistOf(clusterID)) })
+		It("should increase the generation of that cluster only", func() {
+			Expect(pauser.Generation(clusterID)).To(BeNumerically("==", 1))
+			Expect(pauser.Generation(otherID)).To(BeZero())
+		})
+	})
+
+	When("a paused cluster is invalidated", func() {
+		BeforeEach(func() {
+			pauser.PauseReflection(clusterID)
+			pauser.Invalidate(clusterID)
+		})
+
+		It("should keep the reflection paused", func() { Expect(pauser.Paused(clusterID)).To(BeTrue()) })
+		It("should not notify the callbacks", func() { Expect(resumed).To(BeEmpty()) })
+		It("should increase the generation", func() { Expect(pauser.Generation(clusterID)).To(BeNumerically("==", 1)) })
+
+		It("should notify the callbacks once resumed", func() {
+			pauser.ResumeReflection(clusterID)
+			Expect(resumed).To(ConsistOf(clusterID))
+			Expect(pauser.Generation(clusterID)).To(BeNumerically("==", 2))
+		})
+	})
+
+	When("the pauser is nil", func() {
+		BeforeEach(func() { pauser = nil })
+
+		It("should never pause the reflection", func() { Expect(pauser.Paused(clusterID)).To(BeFalse()) })
+		It("should report the initial generation", func() { Expect(pauser.Generation(clusterID)).To(BeZero()) })
+	})
+})
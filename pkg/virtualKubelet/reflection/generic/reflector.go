@@ -39,6 +39,7 @@ import (
 
 var _ manager.Reflector = (*reflector)(nil)
 var _ manager.Reflector = (*dummyreflector)(nil)
+var _ manager.Resyncer = (*reflector)(nil)
 
 // NamespacedReflectorFactoryFunc represents the function type to create a new NamespacedReflector.
 type NamespacedReflectorFactoryFunc func(*options.NamespacedOpts) manager.NamespacedReflector
@@ -50,8 +51,16 @@ type FallbackReflectorFactoryFunc func(*options.ReflectorOpts) manager.FallbackR
 type reflector struct {
 	sync.RWMutex
 
-	name    string
+	name string
+	// workers is the number of goroutines processing the enqueued keys concurrently. Events concerning different objects
+	// are handled in parallel, while those concerning the same object are serialized, as guaranteed by the workqueue.
 	workers uint
+	// resync, if positive, is the period the keys returned by the namespaced reflectors implementing
+	// manager.NamespacedResyncer are enqueued with, to reconcile the objects whose events have been missed.
+	resync time.Duration
+
+	// ctx is the context the reflector has been started with, used to release the resources of the stopped namespaces.
+	ctx context.Context
 
 	workqueue workqueue.RateLimitingInterface
 
@@ -72,6 +81,17 @@ func NewReflector(name string, namespaced NamespacedReflectorFactoryFunc, fallba
 	return newReflector(name, namespaced, fallback, workers)
 }
 
+// NewResyncingReflector returns a new reflector, as NewReflector, which additionally resyncs the reflected objects with the given period.
+// The resync applies to the namespaced reflectors implementing manager.NamespacedResyncer, and it is disabled if the period is zero.
+func NewResyncingReflector(name string, namespaced NamespacedReflectorFactoryFunc, fallback FallbackReflectorFactoryFunc,
+	workers uint, resync time.Duration) manager.Reflector {
+	rfl := NewReflector(name, namespaced, fallback, workers)
+	if gr, ok := rfl.(*reflector); ok {
+		gr.resync = resync
+	}
+	return rfl
+}
+
 // newReflector returns a new reflector to implement the reflection towards a remote clusters.
 func newReflector(name string, namespaced NamespacedReflectorFactoryFunc, fallback FallbackReflectorFactoryFunc, workers uint) manager.Reflector {
 	return &reflector{
@@ -90,12 +110,17 @@ func newReflector(name string, namespaced NamespacedReflectorFactoryFunc, fallba
 // Start starts the reflector.
 func (gr *reflector) Start(ctx context.Context, opts *options.ReflectorOpts) {
 	klog.Infof("Starting the %v reflector with %v workers", gr.name, gr.workers)
+	gr.ctx = ctx
 	gr.fallback = gr.fallbackFactory(opts.WithHandlerFactory(gr.handlers))
 
 	for i := uint(0); i < gr.workers; i++ {
 		go wait.Until(gr.runWorker, time.Second, ctx.Done())
 	}
 
+	if gr.resync > 0 {
+		go wait.Until(gr.Resync, gr.resync, ctx.Done())
+	}
+
 	// Make sure the working queue is properly stopped when the context is closed.
 	go func() {
 		<-ctx.Done()
@@ -131,11 +156,16 @@ func (gr *reflector) StartNamespace(opts *options.NamespacedOpts) {
 
 // StopNamespace stops the reflection for a given namespace.
 func (gr *reflector) StopNamespace(local, remote string) {
+	var stopped manager.NamespacedReflector
+	// Release the resources of the stopped namespaced reflector once the lock has been released (deferred functions run in LIFO order),
+	// not to prevent the processing of the other namespaces in the meanwhile.
+	defer func() { gr.release(stopped, local) }()
+
 	gr.Lock()
 	defer gr.Unlock()
 
 	klog.Infof("Stopping %v reflection between local namespace %q and remote namespace %q", gr.name, local, remote)
-	_, found := gr.reflectors[local]
+	stopped, found := gr.reflectors[local]
 	if !found {
 		klog.Warningf("%v reflection between local namespace %q and remote namespace %q already stopped", gr.name, local, remote)
 		return
@@ -153,6 +183,23 @@ func (gr *reflector) StopNamespace(local, remote string) {
 	klog.Infof("Reflection between local namespace %q and remote namespace %q correctly stopped", local, remote)
 }
 
+// release releases the resources associated with the local objects of the given stopped namespaced reflector,
+// if it implements manager.NamespacedStopper.
+func (gr *reflector) release(stopped manager.NamespacedReflector, local string) {
+	stopper, ok := stopped.(manager.NamespacedStopper)
+	if !ok {
+		return
+	}
+
+	ctx := gr.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if err := stopper.Stop(ctx); err != nil {
+		klog.Errorf("Failed to release the %v resources of local namespace %q: %v", gr.name, local, err)
+	}
+}
+
 // namespace returns the service reflector associated with a given namespace (if any).
 func (gr *reflector) namespace(namespace string) (manager.NamespacedReflector, bool) {
 	gr.Lock()
@@ -162,6 +209,26 @@ func (gr *reflector) namespace(namespace string) (manager.NamespacedReflector, b
 	return reflector, found
 }
 
+// Resync enqueues the keys returned by the ready namespaced reflectors implementing manager.NamespacedResyncer,
+// so that the corresponding objects are reconciled even if the triggering events have been missed.
+func (gr *reflector) Resync() {
+	gr.RLock()
+	defer gr.RUnlock()
+
+	for namespace, reflector := range gr.reflectors {
+		resyncer, ok := reflector.(manager.NamespacedResyncer)
+		if !ok || !reflector.Ready() {
+			continue
+		}
+
+		keys := resyncer.ResyncKeys()
+		klog.V(4).Infof("Resyncing %v objects in local namespace %q through the %v reflector", len(keys), namespace, gr.name)
+		for _, name := range keys {
+			gr.workqueue.Add(types.NamespacedName{Namespace: namespace, Name: name})
+		}
+	}
+}
+
 // runWorker is a long-running function that will continually call the
 // processNextWorkItem function in order to read and process a message on the
 // workqueue.
@@ -16,6 +16,8 @@ package generic
 
 import (
 	"context"
+	"sync"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -78,6 +80,68 @@ var _ = Describe("Reflector tests", func() {
 			})
 		})
 
+		Context("a new resyncing reflector is created", func() {
+			var (
+				ctx    context.Context
+				cancel context.CancelFunc
+				resync time.Duration
+				ready  bool
+			)
+
+			BeforeEach(func() {
+				ctx, cancel = context.WithCancel(context.Background())
+				resync, ready = 10*time.Millisecond, true
+
+				NewFakeFallbackReflector = WithoutFallback()
+				NewFakeNamespacedReflector = func(opts *options.NamespacedOpts) manager.NamespacedReflector {
+					nsrfl = reflectionfake.NewNamespacedReflector(opts)
+					nsrfl.Keys = []string{"foo", "bar"}
+					if ready {
+						nsrfl.SetReady()
+					}
+					return nsrfl
+				}
+			})
+			AfterEach(func() { cancel() })
+
+			JustBeforeEach(func() {
+				rfl = NewResyncingReflector(reflectorName, NewFakeNamespacedReflector, NewFakeFallbackReflector, 1, resync)
+				// Do not start the workers, so that the enqueued keys are not consumed.
+				rfl.(*reflector).workers = 0
+				rfl.Start(ctx, &options.ReflectorOpts{LocalClient: fake.NewSimpleClientset()})
+				rfl.StartNamespace(&options.NamespacedOpts{LocalNamespace: localNamespace, RemoteNamespace: remoteNamespace})
+			})
+
+			When("the namespaced reflector is ready", func() {
+				It("should periodically enqueue the keys returned by the namespaced reflector", func() {
+					Eventually(rfl.(*reflector).workqueue.Len).Should(BeNumerically("==", 2))
+					for range nsrfl.Keys {
+						key, _ := rfl.(*reflector).workqueue.Get()
+						Expect(key).To(BeElementOf(
+							types.NamespacedName{Namespace: localNamespace, Name: "foo"},
+							types.NamespacedName{Namespace: localNamespace, Name: "bar"}))
+						rfl.(*reflector).workqueue.Done(key)
+					}
+				})
+			})
+
+			When("the namespaced reflector is not ready", func() {
+				BeforeEach(func() { ready = false })
+
+				It("should not enqueue any key", func() {
+					Consistently(rfl.(*reflector).workqueue.Len, 5*resync, resync).Should(BeZero())
+				})
+			})
+
+			When("the resync is disabled", func() {
+				BeforeEach(func() { resync = 0 })
+
+				It("should not enqueue any key", func() {
+					Consistently(rfl.(*reflector).workqueue.Len, 50*time.Millisecond, 10*time.Millisecond).Should(BeZero())
+				})
+			})
+		})
+
 		Context("a new real reflector is created", func() {
 			BeforeEach(func() { workers = 10 })
 			JustBeforeEach(func() {
@@ -148,6 +212,7 @@ var _ = Describe("Reflector tests", func() {
 						It("should remove the namespaced reflector", func() {
 							Expect(rfl.(*reflector).reflectors).ToNot(HaveKeyWithValue(localNamespace, nsrfl))
 						})
+						It("should release the resources of the local objects", func() { Expect(nsrfl.Stopped).To(Equal(1)) })
 
 						When("the fallback handler is set", func() {
 							It("should enqueue the returned elements", func() {
@@ -317,6 +382,54 @@ var _ = Describe("Reflector tests", func() {
 		})
 	})
 
+	Describe("the worker pool", func() {
+		const namespace = "local"
+
+		var (
+			ctx    context.Context
+			cancel context.CancelFunc
+			rfl    *reflector
+			nsrfl  *blockingNamespacedReflector
+		)
+
+		foo := types.NamespacedName{Namespace: namespace, Name: "foo"}
+		bar := types.NamespacedName{Namespace: namespace, Name: "bar"}
+
+		BeforeEach(func() {
+			ctx, cancel = context.WithCancel(context.Background())
+			nsrfl = &blockingNamespacedReflector{release: make(chan struct{}),
+				inflight: map[string]int{}, maxInflight: map[string]int{}, handled: map[string]int{}}
+
+			rfl = newReflector("reflector", func(*options.NamespacedOpts) manager.NamespacedReflector { return nsrfl },
+				WithoutFallback(), 3).(*reflector)
+			rfl.Start(ctx, &options.ReflectorOpts{LocalClient: fake.NewSimpleClientset()})
+			rfl.StartNamespace(&options.NamespacedOpts{LocalNamespace: namespace, RemoteNamespace: "remote"})
+		})
+		AfterEach(func() { cancel() })
+
+		It("should process the events for different objects concurrently", func() {
+			rfl.workqueue.Add(foo)
+			rfl.workqueue.Add(bar)
+			Eventually(func() int { return nsrfl.InFlight("foo") + nsrfl.InFlight("bar") }).Should(Equal(2))
+
+			close(nsrfl.release)
+			Eventually(func() int { return nsrfl.Handled("foo") + nsrfl.Handled("bar") }).Should(Equal(2))
+		})
+
+		It("should serialize the events for the same object", func() {
+			rfl.workqueue.Add(foo)
+			Eventually(func() int { return nsrfl.InFlight("foo") }).Should(Equal(1))
+
+			// A new event for the same object, received while the previous one is being processed.
+			rfl.workqueue.Add(foo)
+			Consistently(func() int { return nsrfl.InFlight("foo") }, 100*time.Millisecond, 10*time.Millisecond).Should(Equal(1))
+
+			close(nsrfl.release)
+			Eventually(func() int { return nsrfl.Handled("foo") }).Should(Equal(2))
+			Expect(nsrfl.MaxInFlight("foo")).To(Equal(1))
+		})
+	})
+
 	Describe("the *Keyer functions", func() {
 		const (
 			name      = "name"
@@ -345,3 +458,54 @@ var _ = Describe("Reflector tests", func() {
 		})
 	})
 })
+
+// blockingNamespacedReflector is a NamespacedReflector whose handler blocks until released,
+// tracking the number of concurrent executions for each object.
+type blockingNamespacedReflector struct {
+	sync.Mutex
+	release chan struct{}
+
+	inflight    map[string]int
+	maxInflight map[string]int
+	handled     map[string]int
+}
+
+func (r *blockingNamespacedReflector) Handle(_ context.Context, name string) error {
+	r.Lock()
+	r.inflight[name]++
+	if r.inflight[name] > r.maxInflight[name] {
+		r.maxInflight[name] = r.inflight[name]
+	}
+	r.Unlock()
+
+	<-r.release
+
+	r.Lock()
+	r.inflight[name]--
+	r.handled[name]++
+	r.Unlock()
+	return nil
+}
+
+func (r *blockingNamespacedReflector) Ready() bool { return true }
+
+// InFlight returns the number of concurrent executions of the handler for the given object.
+func (r *blockingNamespacedReflector) InFlight(name string) int {
+	r.Lock()
+	defer r.Unlock()
+	return r.inflight[name]
+}
+
+// MaxInFlight returns the maximum number of concurrent executions of the handler observed for a single object.
+func (r *blockingNamespacedReflector) MaxInFlight(name string) int {
+	r.Lock()
+	defer r.Unlock()
+	return r.maxInflight[name]
+}
+
+// Handled returns the number of completed executions of the handler for the given object.
+func (r *blockingNamespacedReflector) Handled(name string) int {
+	r.Lock()
+	defer r.Unlock()
+	return r.handled[name]
+}
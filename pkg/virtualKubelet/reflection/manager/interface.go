@@ -18,7 +18,9 @@ import (
 	"context"
 
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
 
+	liqoclient "github.com/liqotech/liqo/pkg/client/clientset/versioned"
 	"github.com/liqotech/liqo/pkg/virtualKubelet/reflection/options"
 )
 
@@ -26,6 +28,8 @@ import (
 type Manager interface {
 	// With registers the given reflector to the manager.
 	With(reflector Reflector) Manager
+	// WithHub registers the given reflector to the manager, targeting the given hub cluster rather than the remote one.
+	WithHub(reflector Reflector, hub kubernetes.Interface, hubLiqo liqoclient.Interface) Manager
 	// WithNamespaceHandler add the given NamespaceHandler to the manager.
 	WithNamespaceHandler(handler NamespaceHandler) Manager
 	// Start starts the reflection manager. It panics if executed twice.
@@ -52,6 +56,12 @@ type Reflector interface {
 	StopNamespace(local, remote string)
 }
 
+// Resyncer is optionally implemented by the Reflectors supporting on-demand resyncs of the reflected objects.
+type Resyncer interface {
+	// Resync enqueues the keys returned by the namespaced reflectors implementing NamespacedResyncer.
+	Resync()
+}
+
 // NamespacedReflector implements the reflection between a local and a remote namespace.
 type NamespacedReflector interface {
 	// Handle is responsible for reconciling the given object and ensuring it is correctly reflected.
@@ -60,6 +70,20 @@ type NamespacedReflector interface {
 	Ready() bool
 }
 
+// NamespacedResyncer is optionally implemented by the NamespacedReflectors supporting the periodic resync of the reflected objects.
+type NamespacedResyncer interface {
+	// ResyncKeys returns the names of the objects to be reconciled during a resync, including those of the local objects
+	// whose remote counterparts are possibly orphaned.
+	ResyncKeys() []string
+}
+
+// NamespacedStopper is optionally implemented by the NamespacedReflectors which need to release the resources associated
+// with the local objects (e.g., finalizers) once the reflection of the namespace is stopped.
+type NamespacedStopper interface {
+	// Stop releases the resources associated with the local objects, as they are no longer handled.
+	Stop(ctx context.Context) error
+}
+
 // FallbackReflector implements fallback reflection for "orphan" local objects not managed by namespaced reflectors.
 type FallbackReflector interface {
 	// Handle is responsible for reconciling the given "orphan" object.
@@ -49,6 +49,7 @@ type manager struct {
 	eventBroadcaster record.EventBroadcaster
 
 	reflectors              []Reflector
+	hubs                    map[Reflector]*hub
 	localPodInformerFactory informers.SharedInformerFactory
 
 	namespaceHandler NamespaceHandler
@@ -57,6 +58,12 @@ type manager struct {
 	stop    map[string]context.CancelFunc
 }
 
+// hub represents the clients towards a hub cluster, targeted by some reflectors in place of the remote one.
+type hub struct {
+	client     kubernetes.Interface
+	liqoClient liqoclient.Interface
+}
+
 // New returns a new manager to start the reflection towards a remote cluster.
 func New(local, remote kubernetes.Interface, localLiqo, remoteLiqo liqoclient.Interface, resync time.Duration,
 	eb record.EventBroadcaster) Manager {
@@ -74,6 +81,7 @@ func New(local, remote kubernetes.Interface, localLiqo, remoteLiqo liqoclient.In
 		eventBroadcaster: eb,
 
 		reflectors: make([]Reflector, 0),
+		hubs:       make(map[Reflector]*hub),
 		localPodInformerFactory: informers.NewSharedInformerFactoryWithOptions(local, resync,
 			informers.WithTweakListOptions(localPodTweakListOptions)),
 
@@ -92,6 +100,22 @@ func (m *manager) With(reflector Reflector) Manager {
 	return m
 }
 
+// WithHub registers the given reflector to the manager, targeting the given hub cluster rather than the remote one.
+// This allows, e.g., in hub-and-spoke topologies, to reflect the objects towards a central cluster which re-exports them,
+// while the reflected objects are still tagged with the origin cluster. The reflectors targeting the same hub cluster
+// (e.g., those of Services and EndpointSlices) share the same informer factories.
+func (m *manager) WithHub(reflector Reflector, hubClient kubernetes.Interface, hubLiqoClient liqoclient.Interface) Manager {
+	m.With(reflector)
+	for _, h := range m.hubs {
+		if h.client == hubClient && h.liqoClient == hubLiqoClient {
+			m.hubs[reflector] = h
+			return m
+		}
+	}
+	m.hubs[reflector] = &hub{client: hubClient, liqoClient: hubLiqoClient}
+	return m
+}
+
 func (m *manager) WithNamespaceHandler(handler NamespaceHandler) Manager {
 	if m.started {
 		panic("Attempted to register a namespace event handler while already running")
@@ -167,12 +191,26 @@ func (m *manager) StartNamespace(local, remote string) {
 	remoteFactory := informers.NewSharedInformerFactoryWithOptions(m.remote, m.resync, informers.WithNamespace(remote))
 	remoteLiqoFactory := liqoinformers.NewSharedInformerFactoryWithOptions(m.remoteLiqo, m.resync, liqoinformers.WithNamespace(remote))
 
+	// The hub informer factories, which select all resources in the given namespace of the hub clusters (if any).
+	hubFactories := make(map[*hub]informers.SharedInformerFactory)
+	hubLiqoFactories := make(map[*hub]liqoinformers.SharedInformerFactory)
+	for _, h := range m.hubs {
+		if _, found := hubFactories[h]; found {
+			continue
+		}
+		hubFactories[h] = informers.NewSharedInformerFactoryWithOptions(h.client, m.resync, informers.WithNamespace(remote))
+		hubLiqoFactories[h] = liqoinformers.NewSharedInformerFactoryWithOptions(h.liqoClient, m.resync, liqoinformers.WithNamespace(remote))
+	}
+
 	ready := false
 	for _, reflector := range m.reflectors {
 		opts := options.NewNamespaced().
 			WithLocal(local, m.local, localFactory).WithLiqoLocal(m.localLiqo, localLiqoFactory).
 			WithRemote(remote, m.remote, remoteFactory).WithLiqoRemote(m.remoteLiqo, remoteLiqoFactory).
 			WithReadinessFunc(func() bool { return ready }).WithEventBroadcaster(m.eventBroadcaster)
+		if h, found := m.hubs[reflector]; found {
+			opts.WithRemote(remote, h.client, hubFactories[h]).WithLiqoRemote(h.liqoClient, hubLiqoFactories[h])
+		}
 		reflector.StartNamespace(opts)
 	}
 
@@ -186,11 +224,19 @@ func (m *manager) StartNamespace(local, remote string) {
 		localLiqoFactory.Start(ctx.Done())
 		remoteFactory.Start(ctx.Done())
 		remoteLiqoFactory.Start(ctx.Done())
+		for h := range hubFactories {
+			hubFactories[h].Start(ctx.Done())
+			hubLiqoFactories[h].Start(ctx.Done())
+		}
 
 		localFactory.WaitForCacheSync(ctx.Done())
 		localLiqoFactory.WaitForCacheSync(ctx.Done())
 		remoteFactory.WaitForCacheSync(ctx.Done())
 		remoteLiqoFactory.WaitForCacheSync(ctx.Done())
+		for h := range hubFactories {
+			hubFactories[h].WaitForCacheSync(ctx.Done())
+			hubLiqoFactories[h].WaitForCacheSync(ctx.Done())
+		}
 
 		// If the context was closed before the cache was ready, let abort the setup
 		select {
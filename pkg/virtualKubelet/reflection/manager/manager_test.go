@@ -103,6 +103,72 @@ var _ = Describe("Manager tests", func() {
 			})
 		})
 
+		Context("a reflector targeting a hub cluster is registered", func() {
+			var (
+				returned      Manager
+				reflector     *reflectionfake.Reflector
+				hubClient     kubernetes.Interface
+				hubLiqoClient liqoclient.Interface
+			)
+
+			BeforeEach(func() {
+				reflector = reflectionfake.NewReflector()
+				hubClient = fake.NewSimpleClientset()
+				hubLiqoClient = liqoclientfake.NewSimpleClientset()
+			})
+			JustBeforeEach(func() { returned = mgr.WithHub(reflector, hubClient, hubLiqoClient) })
+
+			It("should return the receiver manager", func() { Expect(mgr).To(BeIdenticalTo(returned)) })
+			It("should correctly add the reflector to the list", func() {
+				Expect(mgr.(*manager).reflectors).To(ConsistOf(reflector))
+				Expect(mgr.(*manager).hubs).To(HaveKey(reflector))
+			})
+
+			Context("a namespace is started", func() {
+				JustBeforeEach(func() {
+					mgr.WithNamespaceHandler(&fakeNamespaceHandler{})
+					mgr.Start(ctx)
+					mgr.StartNamespace(localNamespace, remoteNamespace)
+				})
+
+				It("should configure the reflector to target the hub cluster", func() {
+					opts := reflector.NamespaceStarted[localNamespace]
+					Expect(opts.LocalClient).To(Equal(localClient))
+					Expect(opts.RemoteNamespace).To(Equal(remoteNamespace))
+					Expect(opts.RemoteClient).To(BeIdenticalTo(hubClient))
+					Expect(opts.RemoteLiqoClient).To(BeIdenticalTo(hubLiqoClient))
+					Expect(opts.RemoteFactory).ToNot(BeNil())
+					Expect(opts.RemoteLiqoFactory).ToNot(BeNil())
+				})
+				It("should eventually mark the namespace as ready", func() {
+					Eventually(reflector.NamespaceStarted[localNamespace].Ready).Should(BeTrue())
+				})
+			})
+
+			Context("a second reflector targeting the same hub cluster is registered", func() {
+				var other *reflectionfake.Reflector
+
+				BeforeEach(func() { other = reflectionfake.NewReflector() })
+				JustBeforeEach(func() {
+					mgr.WithHub(other, hubClient, hubLiqoClient)
+					mgr.WithNamespaceHandler(&fakeNamespaceHandler{})
+					mgr.Start(ctx)
+					mgr.StartNamespace(localNamespace, remoteNamespace)
+				})
+
+				It("should configure both reflectors to target the hub cluster", func() {
+					Expect(other.NamespaceStarted[localNamespace].RemoteClient).To(BeIdenticalTo(hubClient))
+					Expect(reflector.NamespaceStarted[localNamespace].RemoteClient).To(BeIdenticalTo(hubClient))
+				})
+				It("should share the hub informer factories between the reflectors", func() {
+					Expect(other.NamespaceStarted[localNamespace].RemoteFactory).To(
+						BeIdenticalTo(reflector.NamespaceStarted[localNamespace].RemoteFactory))
+					Expect(other.NamespaceStarted[localNamespace].RemoteLiqoFactory).To(
+						BeIdenticalTo(reflector.NamespaceStarted[localNamespace].RemoteLiqoFactory))
+				})
+			})
+		})
+
 		Context("a reflector is registered", func() {
 			var (
 				returned  Manager